@@ -0,0 +1,321 @@
+package e2e
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"debug/macho"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/qntx/gox/internal/lipo"
+)
+
+func TestZigUpdate(t *testing.T) {
+	e := newEnv(t)
+	e.srv.AddZig("0.15.2", "0.15.2")
+
+	e.ok("zig", "update", "0.15.2")
+	e.cached("zig/0.15.2/zig")
+	e.ok("zig", "update", "0.15.2")
+	if n := e.srv.Hits(".tar.xz"); n != 1 {
+		t.Errorf("zig downloaded %d times, want 1", n)
+	}
+	if out := e.ok("zig", "list"); !strings.Contains(out, "0.15.2") {
+		t.Errorf("zig list does not show 0.15.2:\n%s", out)
+	}
+	e.fails(`version "0.9.0" not found`, "zig", "update", "0.9.0")
+}
+
+func TestPkgInstall(t *testing.T) {
+	files := map[string]string{
+		"ssl-1.0/include/ssl.h":  "#define SSL 1\n",
+		"ssl-1.0/lib/libssl.a":   "!<arch>\n",
+		"ssl-1.0/bin/ssl-config": "#!/bin/sh\necho ssl\n",
+	}
+	for _, asset := range []string{"ssl.tar.gz", "ssl.tar.xz", "ssl.zip"} {
+		t.Run(asset, func(t *testing.T) {
+			e := newEnv(t)
+			e.srv.AddRelease("acme", "ssl", "v1.0", asset, files)
+
+			e.ok("pkg", "install", "acme/ssl@v1.0/"+asset)
+			dir := "pkg/acme-ssl-v1.0-ssl/"
+			e.cached(dir + "include/ssl.h")
+			e.cached(dir + "lib/libssl.a")
+			e.cached(dir + "bin/ssl-config")
+			if out := e.ok("pkg", "list"); !strings.Contains(out, "acme-ssl-v1.0-ssl") {
+				t.Errorf("pkg list does not show the package:\n%s", out)
+			}
+		})
+	}
+
+	t.Run("pinned", func(t *testing.T) {
+		e := newEnv(t)
+		sum := e.srv.AddRelease("acme", "ssl", "v1.0", "ssl.tar.gz", files)
+		e.ok("pkg", "install", "acme/ssl@v1.0/ssl.tar.gz#sha256:"+sum)
+		e.fails("sha256 mismatch", "pkg", "install", "acme/ssl@v1.0/ssl.tar.gz#sha256:"+strings.Repeat("0", 64))
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		e := newEnv(t)
+		e.fails("404", "pkg", "install", "acme/ssl@v9/ssl.tar.gz")
+	})
+}
+
+func TestBuildDryRun(t *testing.T) {
+	e := newEnv(t)
+	e.srv.AddZig("master", "0.16.0-dev.1")
+	e.srv.AddRelease("acme", "ssl", "v1.0", "ssl.tar.gz", map[string]string{"ssl-1.0/include/ssl.h": ""})
+
+	out := e.ok("build", "-n", "--os", "linux", "--arch", "arm64", "--pkg", "acme/ssl@v1.0/ssl.tar.gz")
+	for _, want := range []string{"go build", "GOOS=linux", "GOARCH=arm64", "CGO_ENABLED=1", "acme-ssl-v1.0-ssl"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dry run output lacks %q:\n%s", want, out)
+		}
+	}
+	if n := e.srv.Hits(""); n != 0 {
+		t.Errorf("dry run downloaded %d files", n)
+	}
+}
+
+func TestBuildPack(t *testing.T) {
+	e := newEnv(t,
+		`[[target]]`, `name = "linux-amd64"`, `os = "linux"`, `arch = "amd64"`, `prefix = "dist/linux"`,
+		`[[target]]`, `name = "windows-amd64"`, `os = "windows"`, `arch = "amd64"`, `prefix = "dist/windows"`,
+	)
+	e.srv.AddZig("master", "0.16.0-dev.1")
+
+	e.ok("build", "--pack", "--checksum")
+
+	dist := filepath.Join(e.dir, "dist")
+	archives := map[string]string{
+		"linux-linux-amd64.tar.gz":  "linux/bin/linux",
+		"windows-windows-amd64.zip": "windows/windows.exe",
+	}
+	sums, err := os.ReadFile(filepath.Join(dist, "SHA256SUMS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, binary := range archives {
+		if names := archiveNames(t, filepath.Join(dist, name)); !slices.Contains(names, binary) {
+			t.Errorf("%s = %v, want %s", name, names, binary)
+		}
+		if !strings.Contains(string(sums), name) {
+			t.Errorf("SHA256SUMS lacks %s:\n%s", name, sums)
+		}
+	}
+}
+
+func TestBuildUniversal(t *testing.T) {
+	e := newEnv(t, `[[target]]`, `name = "mac"`, `os = "darwin"`, `arch = "universal"`, `prefix = "dist/mac"`)
+	e.srv.AddZig("master", "0.16.0-dev.1")
+
+	e.ok("build", "--pack")
+
+	archs, err := lipo.Archs(filepath.Join(e.dir, "dist", "mac", "bin", "mac"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []macho.Cpu{macho.CpuAmd64, macho.CpuArm64}; !slices.Equal(archs, want) {
+		t.Errorf("universal binary holds %v, want %v", archs, want)
+	}
+	if names := archiveNames(t, filepath.Join(e.dir, "dist", "mac-darwin-universal.tar.gz")); !slices.Contains(names, "mac/bin/mac") {
+		t.Errorf("archive = %v, want mac/bin/mac", names)
+	}
+	e.fails("darwin/universal", "run", "-t", "mac")
+}
+
+func TestBuildWindowsResources(t *testing.T) {
+	e := newEnv(t,
+		`[[target]]`, `name = "win"`, `os = "windows"`, `arch = "amd64"`, `output = "dist/app.exe"`,
+		`[windows]`, `manifest = "app.manifest"`, `product-version = "1.2.3"`, `company-name = "Acme Corp"`)
+	e.srv.AddZig("master", "0.16.0-dev.1")
+	e.write("app.manifest", `<assembly manifestVersion="1.0"/>`)
+
+	e.ok("build")
+	exe, err := os.ReadFile(filepath.Join(e.dir, "dist", "app.exe"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range [][]byte{[]byte(`<assembly manifestVersion="1.0"/>`), utf16le("Acme Corp"), utf16le("VS_VERSION_INFO")} {
+		if !bytes.Contains(exe, want) {
+			t.Errorf("app.exe lacks resource data %q", want)
+		}
+	}
+	if matches, _ := filepath.Glob(filepath.Join(e.dir, "*.syso")); len(matches) > 0 {
+		t.Errorf("resource objects left behind: %v", matches)
+	}
+}
+
+// utf16le encodes s as little-endian UTF-16, as Windows resources store text.
+func utf16le(s string) []byte {
+	var out []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(u), byte(u>>8))
+	}
+	return out
+}
+
+func TestBuildWasip1(t *testing.T) {
+	// No zig is served: wasip1 has no cgo, so gox never fetches one.
+	e := newEnv(t, `[[target]]`, `name = "wasi"`, `os = "wasip1"`, `arch = "wasm"`, `prefix = "dist/app"`)
+
+	e.ok("build")
+	wasm, err := os.ReadFile(filepath.Join(e.dir, "dist", "app", "bin", "app.wasm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(wasm, []byte("\x00asm")) {
+		t.Errorf("app.wasm starts with %q, want the wasm magic", wasm[:min(4, len(wasm))])
+	}
+}
+
+func TestVendor(t *testing.T) {
+	e := newEnv(t, `vendor = true`, `packages = ["acme/ssl@v1.0/ssl.tar.gz"]`)
+	e.srv.AddZig("master", "0.16.0-dev.1")
+	e.srv.AddRelease("acme", "ssl", "v1.0", "ssl.tar.gz", map[string]string{"ssl-1.0/include/ssl.h": "#define SSL 1\n"})
+
+	e.ok("vendor")
+	vendored := filepath.Join(e.dir, "third_party", "gox", "acme-ssl-v1.0-ssl")
+	if _, err := os.Stat(filepath.Join(vendored, "include", "ssl.h")); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := os.ReadFile(filepath.Join(e.dir, "third_party", "gox", "manifest.toml"))
+	if err != nil || !strings.Contains(string(manifest), `source = "acme/ssl@v1.0/ssl.tar.gz"`) {
+		t.Fatalf("manifest.toml = %s, %v", manifest, err)
+	}
+
+	// Builds use the vendored copy even when the cache is gone.
+	if err := os.RemoveAll(filepath.Join(e.cache, "pkg")); err != nil {
+		t.Fatal(err)
+	}
+	out := e.ok("build", "-n")
+	if !strings.Contains(out, vendored) {
+		t.Errorf("build does not use the vendored package:\n%s", out)
+	}
+	e.ok("build")
+	if n := e.srv.Hits("ssl.tar.gz"); n != 1 {
+		t.Errorf("package downloaded %d times, want 1", n)
+	}
+}
+
+func TestPlugins(t *testing.T) {
+	e := newEnv(t, `plugins = ["./tools/gox-log"]`,
+		`[[target]]`, `name = "linux-amd64"`, `os = "linux"`, `arch = "amd64"`, `prefix = "dist/linux"`, `pack = true`, `checksum = true`)
+	e.srv.AddZig("master", "0.16.0-dev.1")
+	e.write("tools/gox-log", "#!/bin/sh\n{ echo \"$1\"; cat; echo; } >> events.log\n")
+	if err := os.Chmod(filepath.Join(e.dir, "tools", "gox-log"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := e.ok("plugins"); !strings.Contains(out, "enabled") {
+		t.Errorf("plugins does not list gox-log as enabled:\n%s", out)
+	}
+	e.ok("build")
+	data, err := os.ReadFile(filepath.Join(e.dir, "events.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []string
+	for line := range strings.Lines(string(data)) {
+		if !strings.HasPrefix(line, "{") {
+			events = append(events, strings.TrimSpace(line))
+		}
+	}
+	if want := []string{"pre-build", "post-build", "pre-pack", "publish"}; !slices.Equal(events, want) {
+		t.Errorf("hooks run = %v, want %v", events, want)
+	}
+	for _, want := range []string{`"target":"linux-amd64"`, `"archive":"dist/linux-linux-amd64.tar.gz"`, `"dist/SHA256SUMS"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("events lack %s:\n%s", want, data)
+		}
+	}
+
+	e.write("tools/gox-log", "#!/bin/sh\n[ \"$1\" != pre-pack ] || { echo not today; exit 1; }\n")
+	e.fails("plugin log: pre-pack", "build")
+}
+
+func TestUpgrade(t *testing.T) {
+	e := newEnv(t, `zig-version = "0.14.0" # CI uses the same`, `packages = ["acme/ssl@v1.0.0/ssl-1.0.0.tar.gz"]`)
+	e.srv.AddZig("0.14.0", "0.14.0")
+	e.srv.AddZig("0.15.2", "0.15.2")
+	files := map[string]string{"ssl/include/ssl.h": "#define SSL 1\n"}
+	for _, tag := range []string{"v1.0.0", "v1.1.0", "v2.0.0"} {
+		e.srv.AddRelease("acme", "ssl", tag, "ssl-"+tag[1:]+".tar.gz", files)
+	}
+	config := filepath.Join(e.dir, "gox.toml")
+	before, _ := os.ReadFile(config)
+
+	out := e.ok("upgrade", "--dry-run")
+	for _, want := range []string{"zig 0.14.0 → 0.15.2", "acme/ssl@v1.1.0/ssl-1.1.0.tar.gz"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("upgrade --dry-run output lacks %q:\n%s", want, out)
+		}
+	}
+	if after, _ := os.ReadFile(config); string(after) != string(before) {
+		t.Errorf("upgrade --dry-run changed gox.toml:\n%s", after)
+	}
+
+	e.ok("upgrade")
+	after, _ := os.ReadFile(config)
+	for _, want := range []string{`zig-version = "0.15.2" # CI uses the same`, `"acme/ssl@v1.1.0/ssl-1.1.0.tar.gz"`} {
+		if !strings.Contains(string(after), want) {
+			t.Errorf("gox.toml lacks %s:\n%s", want, after)
+		}
+	}
+	lock, err := os.ReadFile(filepath.Join(e.dir, "gox.lock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(lock), "v1.1.0") || strings.Contains(string(lock), "v1.0.0") {
+		t.Errorf("gox.lock does not pin only the upgraded package:\n%s", lock)
+	}
+	e.cached("zig/0.15.2/zig")
+
+	if out := e.ok("upgrade"); !strings.Contains(out, "up to date") {
+		t.Errorf("second upgrade is not up to date:\n%s", out)
+	}
+}
+
+// archiveNames lists the files in a .tar.gz or .zip archive.
+func archiveNames(t *testing.T, path string) []string {
+	t.Helper()
+	var names []string
+	if strings.HasSuffix(path, ".zip") {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		return names
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return names
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+}
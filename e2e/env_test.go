@@ -0,0 +1,113 @@
+package e2e
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/qntx/gox/internal/fakenet"
+)
+
+// env is a project directory and an isolated home and cache for gox runs.
+type env struct {
+	t     *testing.T
+	srv   *fakenet.Server
+	dir   string // module root
+	cache string // gox cache root
+	vars  []string
+}
+
+// newEnv creates a hello-world module whose gox.toml points zig-mirror and
+// [mirrors] at a fresh fake registry, followed by extra config lines. The
+// GitHub API is pointed there too.
+func newEnv(t *testing.T, config ...string) *env {
+	t.Helper()
+	srv := fakenet.New(t)
+	home := t.TempDir()
+	e := &env{
+		t:     t,
+		srv:   srv,
+		dir:   t.TempDir(),
+		cache: filepath.Join(home, "cache", "gox"),
+		vars: slices.Concat(goEnv, []string{
+			"HOME=" + home,
+			"XDG_CACHE_HOME=" + filepath.Join(home, "cache"),
+			"GOTOOLCHAIN=local",
+			"GOFLAGS=",
+			"GOX_ZIG_MIRROR=",
+			"GOX_GITHUB_API=" + srv.GitHubAPI(),
+			"NO_COLOR=1",
+		}),
+	}
+	e.write("go.mod", "module example.com/hello\n\ngo 1.21\n")
+	e.write("main.go", "package main\n\nfunc main() { println(\"hello\") }\n")
+
+	var cfg strings.Builder
+	fmt.Fprintf(&cfg, "[default]\nzig-mirror = %q\n", srv.ZigMirror())
+	for _, line := range config {
+		fmt.Fprintln(&cfg, line)
+	}
+	fmt.Fprintln(&cfg, "\n[mirrors]")
+	for from, to := range srv.Mirrors() {
+		fmt.Fprintf(&cfg, "%q = %q\n", from, to)
+	}
+	e.write("gox.toml", cfg.String())
+	return e
+}
+
+// write creates the file name under the module root.
+func (e *env) write(name, data string) {
+	e.t.Helper()
+	p := filepath.Join(e.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		e.t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+		e.t.Fatal(err)
+	}
+}
+
+// run runs gox in the module and returns its combined output.
+func (e *env) run(args ...string) (string, error) {
+	e.t.Helper()
+	cmd := exec.Command(gox, args...)
+	cmd.Dir = e.dir
+	cmd.Env = append(os.Environ(), e.vars...)
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	err := cmd.Run()
+	return out.String(), err
+}
+
+// ok runs gox and fails the test when it fails.
+func (e *env) ok(args ...string) string {
+	e.t.Helper()
+	out, err := e.run(args...)
+	if err != nil {
+		e.t.Fatalf("gox %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return out
+}
+
+// fails runs gox and fails the test unless it fails with output containing
+// want.
+func (e *env) fails(want string, args ...string) {
+	e.t.Helper()
+	out, err := e.run(args...)
+	if err == nil || !strings.Contains(out, want) {
+		e.t.Fatalf("gox %s: err = %v, want failure with %q\n%s", strings.Join(args, " "), err, want, out)
+	}
+}
+
+// cached fails the test unless the file name exists under the gox cache.
+func (e *env) cached(name string) {
+	e.t.Helper()
+	if _, err := os.Stat(filepath.Join(e.cache, filepath.FromSlash(name))); err != nil {
+		e.t.Errorf("cache: %v", err)
+	}
+}
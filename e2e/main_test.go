@@ -0,0 +1,52 @@
+// Package e2e runs the gox binary against fake registries from
+// internal/fakenet, covering downloads, builds and packing without network
+// access.
+package e2e
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// gox is the binary under test, built once by TestMain.
+var gox string
+
+// goEnv holds the host's Go caches, shared with the gox runs so they do
+// not compile the standard library from scratch.
+var goEnv []string
+
+func TestMain(m *testing.M) {
+	if runtime.GOOS == "windows" {
+		fmt.Println("skipping e2e tests: fake zig is a shell script")
+		os.Exit(0)
+	}
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "gox-e2e-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	gox = filepath.Join(dir, "gox")
+	if out, err := exec.Command("go", "build", "-o", gox, "../cmd/gox").CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "building gox: %v\n%s", err, out)
+		return 1
+	}
+	out, err := exec.Command("go", "env", "GOCACHE", "GOMODCACHE", "GOPATH").Output()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "go env: %v\n", err)
+		return 1
+	}
+	vals := strings.Split(strings.TrimSpace(string(out)), "\n")
+	goEnv = []string{"GOCACHE=" + vals[0], "GOMODCACHE=" + vals[1], "GOPATH=" + vals[2]}
+	return m.Run()
+}
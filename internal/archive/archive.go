@@ -3,8 +3,14 @@ package archive
 import (
 	"archive/tar"
 	"archive/zip"
+	"bufio"
+	"bytes"
+	"cmp"
+	"compress/bzip2"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -12,17 +18,25 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/ulikunitz/xz"
+
+	"github.com/qntx/gox/internal/httpclient"
 )
 
 const (
 	perm         = 0o755
 	maxLinkDepth = 10
+	digestPrefix = "gox-digest:sha256:"
 )
 
 var ErrPathTraversal = errors.New("path traversal")
 
+// ErrUnsupportedFormat is returned for archives whose name has none of the
+// extensions Detect knows.
+var ErrUnsupportedFormat = errors.New("unsupported archive format (want .tar.gz, .tar.xz, .tar.bz2, .zip, .7z, .deb or .rpm)")
+
 // Format represents an archive format.
 type Format int
 
@@ -30,22 +44,38 @@ const (
 	TarGz Format = iota
 	TarXz
 	Zip
+	TarBz2   // extracted only: Go has no bzip2 writer
+	SevenZip // extracted only
+	Deb      // extracted only: the files a Debian package installs
+	RPM      // extracted only: the files an RPM package installs
+	Unknown  // a name Detect does not recognize
 )
 
+// Ext returns the extension of f, "" for Unknown.
 func (f Format) Ext() string {
-	return [...]string{".tar.gz", ".tar.xz", ".zip"}[f]
+	return [...]string{".tar.gz", ".tar.xz", ".zip", ".tar.bz2", ".7z", ".deb", ".rpm", ""}[f]
 }
 
-// Detect determines format from filename.
+// Detect determines format from filename, ignoring the query of a URL.
 func Detect(name string) Format {
-	s := strings.ToLower(name)
+	s, _, _ := strings.Cut(strings.ToLower(name), "?")
 	switch {
 	case strings.HasSuffix(s, ".zip"):
 		return Zip
+	case strings.HasSuffix(s, ".tar.gz"), strings.HasSuffix(s, ".tgz"):
+		return TarGz
 	case strings.HasSuffix(s, ".tar.xz"), strings.HasSuffix(s, ".txz"):
 		return TarXz
+	case strings.HasSuffix(s, ".tar.bz2"), strings.HasSuffix(s, ".tbz2"), strings.HasSuffix(s, ".tbz"):
+		return TarBz2
+	case strings.HasSuffix(s, ".7z"):
+		return SevenZip
+	case strings.HasSuffix(s, ".deb"):
+		return Deb
+	case strings.HasSuffix(s, ".rpm"):
+		return RPM
 	default:
-		return TarGz
+		return Unknown
 	}
 }
 
@@ -57,15 +87,33 @@ func ForOS(goos string) Format {
 	return TarGz
 }
 
-// Extract extracts archive to destDir, stripping top-level directory.
+// Extract extracts archive to destDir, stripping top-level directory. The
+// files of Debian and RPM packages are moved to the include/lib layout, see
+// distroPath.
 func Extract(src, dst string) error {
+	return extract(src, dst, true)
+}
+
+// extract is Extract, optionally marking detected executables in zip
+// entries that carry no unix modes.
+func extract(src, dst string, fixExec bool) error {
 	switch Detect(src) {
 	case Zip:
-		return unzip(src, dst)
+		return unzip(src, dst, fixExec)
 	case TarXz:
 		return untar(src, dst, xzReader)
-	default:
+	case TarBz2:
+		return untar(src, dst, bz2Reader)
+	case SevenZip:
+		return un7z(src, dst, fixExec)
+	case Deb:
+		return undeb(src, dst)
+	case RPM:
+		return unrpm(src, dst)
+	case TarGz:
 		return untar(src, dst, gzReader)
+	default:
+		return fmt.Errorf("%s: %w", filepath.Base(src), ErrUnsupportedFormat)
 	}
 }
 
@@ -77,83 +125,444 @@ func Download(ctx context.Context, url, dst string) error {
 // DownloadTo downloads with optional progress tracking.
 // If proxyReader is provided, it wraps the response body to track progress.
 func DownloadTo(ctx context.Context, url, dst string, proxyReader func(io.Reader) io.Reader) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	_, err := DownloadWith(ctx, url, dst, DownloadOptions{Proxy: proxyReader})
+	return err
+}
+
+// DownloadOptions configures DownloadWith.
+type DownloadOptions struct {
+	Proxy   func(io.Reader) io.Reader // wraps the response body, e.g. for progress
+	SHA256  string                    // expected archive digest; checked before extraction
+	Verify  func(file string) error   // extra check of the archive file before extraction
+	Resumed func(offset int64)        // called with the bytes already on disk when resuming
+	Chunks  int                       // concurrent range requests for large archives; 0 = DefaultChunks, 1 = single stream
+
+	// KeepModes extracts zip entries without unix modes as they are
+	// instead of making the executables among them executable.
+	KeepModes bool
+}
+
+// DownloadResult describes a downloaded archive.
+type DownloadResult struct {
+	SHA256 string
+	Size   int64
+}
+
+// ErrChecksumMismatch is returned when a download does not match its
+// expected SHA-256.
+var ErrChecksumMismatch = errors.New("sha256 mismatch")
+
+// DownloadWith downloads url, verifies it against opts.SHA256 when set and
+// extracts it to dst. It returns the digest and size of the archive.
+//
+// An interrupted download is kept in PartialDir and resumed with a Range
+// request by the next call for the same url, as long as the server still
+// serves the same file. Large archives are fetched as opts.Chunks concurrent
+// ranges when the server supports it.
+func DownloadWith(ctx context.Context, url, dst string, opts DownloadOptions) (*DownloadResult, error) {
+	// Fail before fetching what could not be extracted.
+	if Detect(url) == Unknown {
+		return nil, fmt.Errorf("%s: %w", url, ErrUnsupportedFormat)
+	}
+	part, err := claimPartial(url)
 	if err != nil {
+		return nil, err
+	}
+	defer part.release()
+
+	// Each retry resumes from the bytes the failed attempt left on disk.
+	var res *DownloadResult
+	err = httpclient.Retry(ctx, func() error {
+		part.refresh()
+		var err error
+		res, err = fetch(ctx, url, part, opts)
 		return err
+	})
+	if err != nil {
+		if part.validator == "" {
+			part.discard()
+		}
+		return nil, err
+	}
+	// A complete archive is never resumed: extract it or start over.
+	defer part.discard()
+
+	if opts.SHA256 != "" && !strings.EqualFold(opts.SHA256, res.SHA256) {
+		return res, fmt.Errorf("%w: got %s, want %s", ErrChecksumMismatch, res.SHA256, opts.SHA256)
+	}
+	if opts.Verify != nil {
+		if err := opts.Verify(part.path); err != nil {
+			return res, err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), perm); err != nil {
+		return nil, err
+	}
+	return res, extract(part.path, dst, !opts.KeepModes)
+}
+
+// fetch downloads url into p, continuing after the bytes already there when
+// the server honors the range, and returns the digest and size of the whole
+// archive.
+func fetch(ctx context.Context, url string, p *partial, opts DownloadOptions) (*DownloadResult, error) {
+	if n := cmp.Or(opts.Chunks, DefaultChunks); n > 1 && p.size == 0 {
+		if res, ok, err := fetchChunked(ctx, url, p, opts, n); ok {
+			return res, err
+		}
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if p.size > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", p.size))
+		req.Header.Set("If-Range", p.validator)
+	}
+
+	resp, err := httpclient.Client.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	var offset int64
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		// Fresh download, or the file changed since the partial was saved.
+	case resp.StatusCode == http.StatusPartialContent && p.size > 0 && rangeStart(resp.Header.Get("Content-Range")) == p.size:
+		offset = p.size
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && p.size > 0:
+		// The partial no longer fits the file; start over.
+		resp.Body.Close()
+		p.discard()
+		return fetch(ctx, url, p, opts)
+	case resp.StatusCode == http.StatusPartialContent:
+		// Not the range we asked for; drop the partial so a retry starts over.
+		p.discard()
+		return nil, fmt.Errorf("unexpected Content-Range %q", resp.Header.Get("Content-Range"))
+	default:
+		return nil, httpclient.NewStatusError(resp)
 	}
 
-	tmp, err := os.MkdirTemp("", "gox-*")
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset > 0 {
+		flag = os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(p.path, flag, 0o644)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if offset > 0 {
+		// The digest covers the whole archive, including resumed bytes.
+		if err := copyTo(h, p.path); err != nil {
+			return nil, err
+		}
 	}
-	defer os.RemoveAll(tmp)
+	if opts.Resumed != nil {
+		opts.Resumed(offset)
+	}
+	p.setValidator(validator(resp.Header))
 
 	// Wrap body with progress reader if provided
 	body := io.Reader(resp.Body)
-	if proxyReader != nil {
-		body = proxyReader(body)
+	if opts.Proxy != nil {
+		body = opts.Proxy(body)
 	}
+	counter := &countWriter{w: h}
+	body = io.TeeReader(body, counter)
 
-	file := filepath.Join(tmp, "archive"+Detect(url).Ext())
-	if err := fetchToReader(file, body); err != nil {
-		return err
+	if _, err := io.CopyBuffer(f, body, make([]byte, 256*1024)); err != nil {
+		return nil, err
 	}
-
-	if err := os.MkdirAll(filepath.Dir(dst), perm); err != nil {
-		return err
+	if err := f.Close(); err != nil {
+		return nil, err
 	}
-	return Extract(file, dst)
+	return &DownloadResult{SHA256: hex.EncodeToString(h.Sum(nil)), Size: offset + counter.n}, nil
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
 }
 
 // ContentLength fetches the content length of a URL without downloading.
 func ContentLength(ctx context.Context, url string) (int64, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
-	if err != nil {
-		return 0, err
+	var n int64
+	err := httpclient.Retry(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := httpclient.Client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return httpclient.NewStatusError(resp)
+		}
+		n = resp.ContentLength
+		return nil
+	})
+	return n, err
+}
+
+// CreateOptions adjusts the archives Create writes.
+type CreateOptions struct {
+	// ModTime, when set, replaces every later modification time, as
+	// SOURCE_DATE_EPOCH asks of reproducible builds.
+	ModTime time.Time
+	// Deterministic leaves nothing of the build machine in entry headers:
+	// every entry gets ModTime (default: DeterministicTime), mode 0755 if
+	// executable or a directory and 0644 otherwise, and owner root.
+	Deterministic bool
+	// Include adds files from outside the source to the archive, named
+	// relative to its top directory.
+	Include []File
+	// Exclude leaves out entries of the source whose path below it, or
+	// whose base name, matches one of these patterns (see Match).
+	Exclude []string
+	// Name, when set, replaces the file name "<src>-<goos>-<goarch>" of the
+	// archive; the extension of the format is appended to it.
+	Name string
+}
+
+// excluded reports whether o leaves out the entry at p below root.
+func (o *CreateOptions) excluded(root, p string) bool {
+	if p == root {
+		return false
 	}
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return 0, err
+	rel, err := filepath.Rel(root, p)
+	return err == nil && Excluded(filepath.ToSlash(rel), o.Exclude)
+}
+
+// skip returns what a walk function returns to leave out the entry info
+// describes, with everything below it.
+func skip(info os.FileInfo) error {
+	if info.IsDir() {
+		return filepath.SkipDir
+	}
+	return nil
+}
+
+// File is a file added to an archive.
+type File struct {
+	Src  string // file on disk
+	Name string // slash-separated path below the archive's top directory
+}
+
+// includeName returns the entry name of an included file named name in an
+// archive of src, whose directory is the archive's top directory.
+func includeName(src string, isDir bool, name string) string {
+	if isDir {
+		return filepath.Base(src) + "/" + name
+	}
+	return name
+}
+
+// DeterministicTime is the time of every entry of a deterministic archive
+// without a ModTime: the earliest a zip can record.
+var DeterministicTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// modTime returns the time an entry last modified at t is stored with.
+func (o *CreateOptions) modTime(t time.Time) time.Time {
+	switch {
+	case o.Deterministic && o.ModTime.IsZero():
+		return DeterministicTime
+	case o.Deterministic, !o.ModTime.IsZero() && t.After(o.ModTime):
+		return o.ModTime
+	}
+	return t
+}
+
+// mode returns the permissions an entry with mode m is stored with.
+func (o *CreateOptions) mode(m os.FileMode) os.FileMode {
+	switch {
+	case !o.Deterministic || m&os.ModeSymlink != 0:
+		return m
+	case m.IsDir() || m&0o111 != 0:
+		return m&^os.ModePerm | 0o755
+	}
+	return m&^os.ModePerm | 0o644
+}
+
+// tarHeader applies o to hdr.
+func (o *CreateOptions) tarHeader(hdr *tar.Header) {
+	hdr.ModTime = o.modTime(hdr.ModTime)
+	if o.Deterministic {
+		hdr.Mode = int64(o.mode(hdr.FileInfo().Mode()).Perm())
+		hdr.Uid, hdr.Gid = 0, 0
+		hdr.Uname, hdr.Gname = "", ""
 	}
-	resp.Body.Close()
-	return resp.ContentLength, nil
+}
+
+// zipHeader applies o to hdr.
+func (o *CreateOptions) zipHeader(hdr *zip.FileHeader) {
+	hdr.Modified = o.modTime(hdr.Modified)
+	if o.Deterministic {
+		hdr.SetMode(o.mode(hdr.Mode()))
+	}
+}
+
+// tag returns what Create appends to the digest of an archive written with
+// o, so changing the options rewrites an archive of unchanged content.
+func (o *CreateOptions) tag() string {
+	var tag string
+	if !o.ModTime.IsZero() {
+		tag += fmt.Sprintf(";mtime=%d", o.ModTime.Unix())
+	}
+	if o.Deterministic {
+		tag += ";deterministic"
+	}
+	return tag
 }
 
 // Create creates archive from src for OS/arch.
 func Create(src, goos, goarch string) (string, error) {
-	info, err := os.Stat(src)
+	digest, err := Digest(src)
 	if err != nil {
 		return "", err
 	}
+	dst := Path(src, goos, goarch)
+	return dst, create(src, dst, ForOS(goos), digest, &CreateOptions{})
+}
+
+// CreateIfChanged creates archive from src unless an archive at the same path
+// was already built from identical content and options, in which case it is
+// reused.
+func CreateIfChanged(src, goos, goarch string, opts CreateOptions) (path string, created bool, err error) {
+	digest, err := treeDigest(src, &opts)
+	if err != nil {
+		return "", false, err
+	}
+	dst := Path(src, goos, goarch)
+	if opts.Name != "" {
+		dst = filepath.Join(filepath.Dir(src), opts.Name+ForOS(goos).Ext())
+	}
+	if ReadDigest(dst) == digest {
+		return dst, false, nil
+	}
+	return dst, true, create(src, dst, ForOS(goos), digest, &opts)
+}
 
-	f := ForOS(goos)
-	dst := filepath.Join(
+// Path returns the archive path Create produces for src and OS/arch.
+func Path(src, goos, goarch string) string {
+	return filepath.Join(
 		filepath.Dir(src),
-		fmt.Sprintf("%s-%s-%s%s", filepath.Base(src), goos, goarch, f.Ext()),
+		fmt.Sprintf("%s-%s-%s%s", filepath.Base(src), goos, goarch, ForOS(goos).Ext()),
 	)
+}
+
+// Digest returns a content hash of src covering relative paths, file modes,
+// symlink targets and file contents.
+func Digest(src string) (string, error) {
+	return treeDigest(src, &CreateOptions{})
+}
+
+// treeDigest returns the digest of src as an archive written with opts records
+// it: with the modes it stores and tagged with the options.
+func treeDigest(src string, opts *CreateOptions) (string, error) {
+	h := sha256.New()
+	base := filepath.Dir(src)
+	err := filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if opts.excluded(src, p) {
+			return skip(info)
+		}
+		info = resolveExternal(src, p, info)
+		rel, err := filepath.Rel(base, p)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%o\x00", filepath.ToSlash(rel), opts.mode(info.Mode()))
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			l, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			io.WriteString(h, l)
+		case info.Mode().IsRegular():
+			if err := copyTo(h, p); err != nil {
+				return err
+			}
+		}
+		h.Write([]byte{0})
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, f := range opts.Include {
+		info, err := os.Stat(f.Src)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "+%s\x00%o\x00", f.Name, opts.mode(info.Mode()))
+		if err := copyTo(h, f.Src); err != nil {
+			return "", err
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)) + opts.tag(), nil
+}
+
+// ReadDigest returns the content digest recorded in an archive by Create,
+// or "" if the archive is missing or carries none.
+func ReadDigest(path string) string {
+	var comment string
+	switch Detect(path) {
+	case Zip:
+		r, err := zip.OpenReader(path)
+		if err != nil {
+			return ""
+		}
+		comment = r.Comment
+		r.Close()
+	case TarGz:
+		f, err := os.Open(path)
+		if err != nil {
+			return ""
+		}
+		defer f.Close()
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return ""
+		}
+		comment = gr.Comment
+	}
+	return strings.TrimPrefix(comment, digestPrefix)
+}
 
+func create(src, dst string, f Format, digest string, opts *CreateOptions) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
 	if f == Zip {
-		err = mkzip(src, dst, info.IsDir())
-	} else {
-		err = mktgz(src, dst, info.IsDir())
+		return mkzip(src, dst, info.IsDir(), digest, opts)
 	}
-	return dst, err
+	return mktgz(src, dst, info.IsDir(), digest, opts)
 }
 
-func gzReader(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }
-func xzReader(r io.Reader) (io.Reader, error) { return xz.NewReader(r) }
+func gzReader(r io.Reader) (io.Reader, error)  { return gzip.NewReader(r) }
+func xzReader(r io.Reader) (io.Reader, error)  { return xz.NewReader(r) }
+func bz2Reader(r io.Reader) (io.Reader, error) { return bzip2.NewReader(r), nil }
 
-func unzip(src, dst string) error {
+func unzip(src, dst string, fixExec bool) error {
 	r, err := zip.OpenReader(src)
 	if err != nil {
 		return err
@@ -161,8 +570,16 @@ func unzip(src, dst string) error {
 	defer r.Close()
 
 	strip := zipPrefix(r.File)
+	names := newCaseNames(dst)
 	for _, f := range r.File {
-		if err := unzipEntry(f, dst, strip); err != nil {
+		if name := strings.TrimPrefix(f.Name, strip); name != "" && !f.FileInfo().IsDir() {
+			if err := names.add(name); err != nil {
+				return err
+			}
+		}
+	}
+	for _, f := range r.File {
+		if err := unzipEntry(f, dst, strip, fixExec); err != nil {
 			return err
 		}
 	}
@@ -189,7 +606,7 @@ func zipPrefix(files []*zip.File) string {
 	return prefix
 }
 
-func unzipEntry(f *zip.File, dst, strip string) error {
+func unzipEntry(f *zip.File, dst, strip string, fixExec bool) error {
 	name := strings.TrimPrefix(f.Name, strip)
 	if name == "" {
 		return nil
@@ -201,7 +618,7 @@ func unzipEntry(f *zip.File, dst, strip string) error {
 	}
 
 	if f.FileInfo().IsDir() {
-		return os.MkdirAll(p, perm)
+		return os.MkdirAll(p, dirMode())
 	}
 
 	rc, err := f.Open()
@@ -209,7 +626,36 @@ func unzipEntry(f *zip.File, dst, strip string) error {
 		return err
 	}
 	defer rc.Close()
-	return write(p, rc, f.Mode())
+
+	if f.Mode()&os.ModeSymlink != 0 {
+		target, err := io.ReadAll(rc)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(p), dirMode()); err != nil {
+			return err
+		}
+		if err := linkInside(dst, p, string(target)); err != nil {
+			return err
+		}
+		return mklink(string(target), p)
+	}
+	mode, err := fileMode(f.Name, f.Mode())
+	if err != nil {
+		return err
+	}
+	var r io.Reader = rc
+	if fixExec && !hasUnixMode(f) && mode&0o111 == 0 {
+		br := bufio.NewReader(rc)
+		if head, _ := br.Peek(8); isExecutable(head) {
+			mode |= (mode & 0o444) >> 2
+		}
+		r = br
+	}
+	if err := os.MkdirAll(filepath.Dir(p), dirMode()); err != nil {
+		return err
+	}
+	return streamToFile(r, p, mode)
 }
 
 func untar(src, dst string, decomp func(io.Reader) (io.Reader, error)) error {
@@ -244,6 +690,7 @@ func untarSinglePass(tr *tar.Reader, dst string) error {
 		links     []link
 		buffered  []bufferedEntry
 		dirCache  = make(map[string]struct{}, 64) // Cache created directories
+		names     = newCaseNames(dst)
 	)
 
 	const (
@@ -259,6 +706,9 @@ func untarSinglePass(tr *tar.Reader, dst string) error {
 		if err != nil {
 			return err
 		}
+		if !entryName(hdr) {
+			continue
+		}
 
 		// Phase 1: Buffer first few entries to detect prefix
 		if !confirmed {
@@ -271,7 +721,7 @@ func untarSinglePass(tr *tar.Reader, dst string) error {
 					prefix = ""
 					confirmed = true
 					for _, b := range buffered {
-						if err := extractBuffered(&b, dst, "", &links, dirCache); err != nil {
+						if err := extractBuffered(&b, dst, "", &links, dirCache, names); err != nil {
 							return err
 						}
 					}
@@ -294,7 +744,7 @@ func untarSinglePass(tr *tar.Reader, dst string) error {
 					// Confirm prefix and flush buffer
 					confirmed = true
 					for _, b := range buffered {
-						if err := extractBuffered(&b, dst, prefix, &links, dirCache); err != nil {
+						if err := extractBuffered(&b, dst, prefix, &links, dirCache, names); err != nil {
 							return err
 						}
 					}
@@ -306,7 +756,7 @@ func untarSinglePass(tr *tar.Reader, dst string) error {
 			// Large file encountered - flush buffer and confirm
 			confirmed = true
 			for _, b := range buffered {
-				if err := extractBuffered(&b, dst, prefix, &links, dirCache); err != nil {
+				if err := extractBuffered(&b, dst, prefix, &links, dirCache, names); err != nil {
 					return err
 				}
 			}
@@ -314,14 +764,14 @@ func untarSinglePass(tr *tar.Reader, dst string) error {
 		}
 
 		// Phase 2: Stream extract directly
-		if err := streamExtract(tr, hdr, dst, prefix, &links, dirCache); err != nil {
+		if err := streamExtract(tr, hdr, dst, prefix, &links, dirCache, names); err != nil {
 			return err
 		}
 	}
 
 	// Flush remaining buffered entries
 	for _, b := range buffered {
-		if err := extractBuffered(&b, dst, prefix, &links, dirCache); err != nil {
+		if err := extractBuffered(&b, dst, prefix, &links, dirCache, names); err != nil {
 			return err
 		}
 	}
@@ -329,11 +779,36 @@ func untarSinglePass(tr *tar.Reader, dst string) error {
 	return resolveLinks(links)
 }
 
-func extractBuffered(entry *bufferedEntry, dst, strip string, links *[]link, dirCache map[string]struct{}) error {
+// entryName normalizes the name of hdr and reports whether it is an entry
+// to extract. archive/tar folds GNU long names and per-file PAX records into
+// the following header, but returns global PAX headers (git archive writes
+// one holding the commit id) as entries of their own; those, and a "./"
+// root, would otherwise be taken for the top-level directory and defeat
+// prefix stripping. Leading "./" components are dropped.
+func entryName(hdr *tar.Header) bool {
+	switch hdr.Typeflag {
+	case tar.TypeXGlobalHeader, tar.TypeXHeader, tar.TypeGNULongName, tar.TypeGNULongLink:
+		return false
+	}
+	name := hdr.Name
+	for strings.HasPrefix(name, "./") {
+		name = strings.TrimLeft(name[2:], "/")
+	}
+	if name == "." || name == "" {
+		return false
+	}
+	hdr.Name = name
+	return true
+}
+
+func extractBuffered(entry *bufferedEntry, dst, strip string, links *[]link, dirCache map[string]struct{}, names *caseNames) error {
 	name := strings.TrimPrefix(entry.hdr.Name, strip)
 	if name == "" {
 		return nil
 	}
+	if err := addName(names, name, entry.hdr.Typeflag); err != nil {
+		return err
+	}
 
 	p, err := safe(dst, name)
 	if err != nil {
@@ -343,12 +818,16 @@ func extractBuffered(entry *bufferedEntry, dst, strip string, links *[]link, dir
 	switch entry.hdr.Typeflag {
 	case tar.TypeDir:
 		dirCache[p] = struct{}{}
-		return os.MkdirAll(p, perm)
+		return os.MkdirAll(p, dirMode())
 	case tar.TypeReg:
+		mode, err := fileMode(entry.hdr.Name, entry.hdr.FileInfo().Mode())
+		if err != nil {
+			return err
+		}
 		if err := mkdirCached(filepath.Dir(p), dirCache); err != nil {
 			return err
 		}
-		return os.WriteFile(p, entry.data, os.FileMode(entry.hdr.Mode))
+		return writeFile(p, entry.data, mode)
 	case tar.TypeSymlink:
 		if err := mklink(entry.hdr.Linkname, p); err != nil {
 			*links = append(*links, link{entry.hdr.Linkname, p})
@@ -358,11 +837,14 @@ func extractBuffered(entry *bufferedEntry, dst, strip string, links *[]link, dir
 }
 
 // streamExtract writes file directly to disk without buffering in memory.
-func streamExtract(tr *tar.Reader, hdr *tar.Header, dst, strip string, links *[]link, dirCache map[string]struct{}) error {
+func streamExtract(tr *tar.Reader, hdr *tar.Header, dst, strip string, links *[]link, dirCache map[string]struct{}, names *caseNames) error {
 	name := strings.TrimPrefix(hdr.Name, strip)
 	if name == "" {
 		return nil
 	}
+	if err := addName(names, name, hdr.Typeflag); err != nil {
+		return err
+	}
 
 	p, err := safe(dst, name)
 	if err != nil {
@@ -372,13 +854,17 @@ func streamExtract(tr *tar.Reader, hdr *tar.Header, dst, strip string, links *[]
 	switch hdr.Typeflag {
 	case tar.TypeDir:
 		dirCache[p] = struct{}{}
-		return os.MkdirAll(p, perm)
+		return os.MkdirAll(p, dirMode())
 
 	case tar.TypeReg:
+		mode, err := fileMode(hdr.Name, hdr.FileInfo().Mode())
+		if err != nil {
+			return err
+		}
 		if err := mkdirCached(filepath.Dir(p), dirCache); err != nil {
 			return err
 		}
-		return streamToFile(tr, p, os.FileMode(hdr.Mode))
+		return streamToFile(tr, p, mode)
 
 	case tar.TypeSymlink:
 		if err := mklink(hdr.Linkname, p); err != nil {
@@ -388,6 +874,14 @@ func streamExtract(tr *tar.Reader, hdr *tar.Header, dst, strip string, links *[]
 	return nil
 }
 
+// addName records the files and symlinks of a tar in names.
+func addName(names *caseNames, name string, typ byte) error {
+	if typ != tar.TypeReg && typ != tar.TypeSymlink {
+		return nil
+	}
+	return names.add(name)
+}
+
 // streamToFile streams data directly to file with buffered I/O.
 func streamToFile(r io.Reader, path string, mode os.FileMode) error {
 	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
@@ -401,7 +895,17 @@ func streamToFile(r io.Reader, path string, mode os.FileMode) error {
 	if e := f.Close(); err == nil {
 		err = e
 	}
-	return err
+	if err != nil {
+		return err
+	}
+	// OpenFile keeps the mode of an existing file and is subject to the
+	// process umask; apply the policy's mode exactly.
+	return os.Chmod(path, mode)
+}
+
+// writeFile writes data to path with exactly mode.
+func writeFile(path string, data []byte, mode os.FileMode) error {
+	return streamToFile(bytes.NewReader(data), path, mode)
 }
 
 // mkdirCached creates directory only if not already cached, reducing syscalls.
@@ -409,13 +913,54 @@ func mkdirCached(dir string, cache map[string]struct{}) error {
 	if _, ok := cache[dir]; ok {
 		return nil
 	}
-	if err := os.MkdirAll(dir, perm); err != nil {
+	if err := os.MkdirAll(dir, dirMode()); err != nil {
 		return err
 	}
 	cache[dir] = struct{}{}
 	return nil
 }
 
+// linkInside checks that a symlink at path, a path below dst, to target
+// stays inside dst. The target is followed from the directory path really
+// is in, through the links extracted before it, and may only climb with
+// leading ".." elements: one after a name could climb out of a directory
+// that is itself a link, or becomes one later.
+func linkInside(dst, path, target string) error {
+	bad := fmt.Errorf("%w: %s -> %s", ErrPathTraversal, path, target)
+	if filepath.IsAbs(target) || strings.HasPrefix(target, "/") || strings.HasPrefix(target, `\`) {
+		return bad
+	}
+	root, err := filepath.EvalSymlinks(dst)
+	if err != nil {
+		return err
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	named := false
+	for _, elem := range strings.FieldsFunc(target, func(r rune) bool { return r == '/' || r == '\\' }) {
+		switch {
+		case elem == ".":
+		case elem != "..":
+			named = true
+		case named:
+			return bad
+		default:
+			dir = filepath.Dir(dir)
+			if !under(root, dir) {
+				return bad
+			}
+		}
+	}
+	return nil
+}
+
+// under reports whether p is root or below it.
+func under(root, p string) bool {
+	return p == root || strings.HasPrefix(p, root+string(os.PathSeparator))
+}
+
 func mklink(target, path string) error {
 	_ = os.Remove(path)
 	return os.Symlink(target, path)
@@ -453,7 +998,7 @@ func resolve(base, name string, m map[string]string) string {
 	return t
 }
 
-func mktgz(src, dst string, isDir bool) error {
+func mktgz(src, dst string, isDir bool, digest string, opts *CreateOptions) error {
 	f, err := os.Create(dst)
 	if err != nil {
 		return err
@@ -461,23 +1006,39 @@ func mktgz(src, dst string, isDir bool) error {
 	defer f.Close()
 
 	gw := gzip.NewWriter(f)
+	gw.Comment = digestPrefix + digest
 	defer gw.Close()
 
 	tw := tar.NewWriter(gw)
 	defer tw.Close()
 
 	if isDir {
-		return tarWalk(tw, src)
+		err = tarWalk(tw, src, opts)
+	} else {
+		err = tarAdd(tw, src, filepath.Base(src), opts)
+	}
+	for _, inc := range opts.Include {
+		if err != nil {
+			break
+		}
+		err = tarAdd(tw, inc.Src, includeName(src, isDir, inc.Name), opts)
 	}
-	return tarAdd(tw, src, filepath.Base(src))
+	return err
 }
 
-func tarWalk(tw *tar.Writer, root string) error {
+// tarWalk adds root and everything below it. filepath.Walk visits entries in
+// lexical order, so archives of the same tree always list them in the same
+// order, whatever order the file system returns them in.
+func tarWalk(tw *tar.Writer, root string, opts *CreateOptions) error {
 	base := filepath.Dir(root)
 	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if opts.excluded(root, p) {
+			return skip(info)
+		}
+		info = resolveExternal(root, p, info)
 
 		rel, err := filepath.Rel(base, p)
 		if err != nil {
@@ -489,6 +1050,7 @@ func tarWalk(tw *tar.Writer, root string) error {
 			return err
 		}
 		hdr.Name = filepath.ToSlash(rel)
+		opts.tarHeader(hdr)
 
 		if info.IsDir() {
 			hdr.Name += "/"
@@ -511,7 +1073,26 @@ func tarWalk(tw *tar.Writer, root string) error {
 	})
 }
 
-func tarAdd(tw *tar.Writer, src, name string) error {
+// resolveExternal returns the target's info for symlinks that are absolute or
+// escape root (e.g. libs linked from the package cache), so archives carry
+// real files instead of links that dangle on other machines.
+func resolveExternal(root, p string, info os.FileInfo) os.FileInfo {
+	if info.Mode()&os.ModeSymlink == 0 {
+		return info
+	}
+	if l, err := os.Readlink(p); err == nil && !filepath.IsAbs(l) {
+		rel, err := filepath.Rel(root, filepath.Join(filepath.Dir(p), l))
+		if err == nil && filepath.IsLocal(rel) {
+			return info
+		}
+	}
+	if fi, err := os.Stat(p); err == nil && fi.Mode().IsRegular() {
+		return fi
+	}
+	return info
+}
+
+func tarAdd(tw *tar.Writer, src, name string, opts *CreateOptions) error {
 	info, err := os.Stat(src)
 	if err != nil {
 		return err
@@ -522,6 +1103,7 @@ func tarAdd(tw *tar.Writer, src, name string) error {
 		return err
 	}
 	hdr.Name = name
+	opts.tarHeader(hdr)
 
 	if err := tw.WriteHeader(hdr); err != nil {
 		return err
@@ -529,7 +1111,7 @@ func tarAdd(tw *tar.Writer, src, name string) error {
 	return copyTo(tw, src)
 }
 
-func mkzip(src, dst string, isDir bool) error {
+func mkzip(src, dst string, isDir bool, digest string, opts *CreateOptions) error {
 	f, err := os.Create(dst)
 	if err != nil {
 		return err
@@ -537,20 +1119,36 @@ func mkzip(src, dst string, isDir bool) error {
 	defer f.Close()
 
 	zw := zip.NewWriter(f)
+	if err := zw.SetComment(digestPrefix + digest); err != nil {
+		return err
+	}
 	defer zw.Close()
 
 	if isDir {
-		return zipWalk(zw, src)
+		err = zipWalk(zw, src, opts)
+	} else {
+		err = zipAdd(zw, src, filepath.Base(src), opts)
+	}
+	for _, inc := range opts.Include {
+		if err != nil {
+			break
+		}
+		err = zipAdd(zw, inc.Src, includeName(src, isDir, inc.Name), opts)
 	}
-	return zipAdd(zw, src, filepath.Base(src))
+	return err
 }
 
-func zipWalk(zw *zip.Writer, root string) error {
+// zipWalk adds root and everything below it, in lexical order like tarWalk.
+func zipWalk(zw *zip.Writer, root string, opts *CreateOptions) error {
 	base := filepath.Dir(root)
 	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if opts.excluded(root, p) {
+			return skip(info)
+		}
+		info = resolveExternal(root, p, info)
 
 		rel, err := filepath.Rel(base, p)
 		if err != nil {
@@ -569,16 +1167,25 @@ func zipWalk(zw *zip.Writer, root string) error {
 		}
 		hdr.Name = rel
 		hdr.Method = zip.Deflate
+		opts.zipHeader(hdr)
 
 		w, err := zw.CreateHeader(hdr)
 		if err != nil {
 			return err
 		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			l, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(w, l)
+			return err
+		}
 		return copyTo(w, p)
 	})
 }
 
-func zipAdd(zw *zip.Writer, src, name string) error {
+func zipAdd(zw *zip.Writer, src, name string, opts *CreateOptions) error {
 	info, err := os.Stat(src)
 	if err != nil {
 		return err
@@ -590,6 +1197,7 @@ func zipAdd(zw *zip.Writer, src, name string) error {
 	}
 	hdr.Name = name
 	hdr.Method = zip.Deflate
+	opts.zipHeader(hdr)
 
 	w, err := zw.CreateHeader(hdr)
 	if err != nil {
@@ -645,15 +1253,3 @@ func copyTo(w io.Writer, path string) error {
 	_, err = io.CopyBuffer(w, f, make([]byte, 256*1024))
 	return err
 }
-
-func fetchToReader(path string, r io.Reader) error {
-	f, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	_, err = io.CopyBuffer(f, r, make([]byte, 256*1024))
-	if e := f.Close(); err == nil {
-		err = e
-	}
-	return err
-}
@@ -4,10 +4,18 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"compress/gzip"
+	"context"
+	"errors"
 	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDetect(t *testing.T) {
@@ -22,8 +30,16 @@ func TestDetect(t *testing.T) {
 		{"file.txz", TarXz},
 		{"file.zip", Zip},
 		{"file.ZIP", Zip},
-		{"file", TarGz},
-		{"file.unknown", TarGz},
+		{"file.tar.bz2", TarBz2},
+		{"file.tbz2", TarBz2},
+		{"file.tbz", TarBz2},
+		{"file.7z", SevenZip},
+		{"libssl-dev_3.0.13-1_amd64.deb", Deb},
+		{"openssl-devel-3.0.7-27.el9.x86_64.RPM", RPM},
+		{"https://host/file.tar.xz?token=x", TarXz},
+		{"file", Unknown},
+		{"file.unknown", Unknown},
+		{"file.tar.zst", Unknown},
 	}
 
 	for _, tt := range tests {
@@ -43,6 +59,11 @@ func TestFormat_Ext(t *testing.T) {
 		{TarGz, ".tar.gz"},
 		{TarXz, ".tar.xz"},
 		{Zip, ".zip"},
+		{TarBz2, ".tar.bz2"},
+		{SevenZip, ".7z"},
+		{Deb, ".deb"},
+		{RPM, ".rpm"},
+		{Unknown, ""},
 	}
 
 	for _, tt := range tests {
@@ -139,6 +160,56 @@ func TestExtract_Zip(t *testing.T) {
 	assertFileContent(t, filepath.Join(dstDir, "subdir", "file2.txt"), "content2")
 }
 
+func TestExtract_ZipSymlinks(t *testing.T) {
+	type entry struct{ name, target string } // target "" for a regular file
+	tests := []struct {
+		name    string
+		entries []entry
+		wantErr bool
+	}{
+		{"relative chain", []entry{{"lib/libfoo.so.1", ""}, {"lib/libfoo.so", "libfoo.so.1"}, {"bin/foo", "../lib/libfoo.so"}}, false},
+		{"absolute", []entry{{"lib/x", ""}, {"lib/etc", "/etc"}}, true},
+		{"above dst", []entry{{"lib/x", ""}, {"lib/up", "../.."}}, true},
+		{"climb after a name", []entry{{"lib/x", ""}, {"a/l", ".."}, {"b", "a/l/../.."}}, true},
+		{"through an earlier link", []entry{{"lib/x", ""}, {"a/l", ".."}, {"a/l/m", "../.."}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zipPath := filepath.Join(t.TempDir(), "links.zip")
+			f, err := os.Create(zipPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			zw := zip.NewWriter(f)
+			for _, e := range tt.entries {
+				hdr := &zip.FileHeader{Name: e.name, Method: zip.Store}
+				hdr.SetMode(0o644)
+				content := "content"
+				if e.target != "" {
+					hdr.SetMode(os.ModeSymlink | 0o777)
+					content = e.target
+				}
+				w, err := zw.CreateHeader(hdr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				io.WriteString(w, content)
+			}
+			zw.Close()
+			f.Close()
+
+			dst := filepath.Join(t.TempDir(), "pkg")
+			err = Extract(zipPath, dst)
+			if tt.wantErr != errors.Is(err, ErrPathTraversal) || !tt.wantErr && err != nil {
+				t.Fatalf("Extract() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				assertFileContent(t, filepath.Join(dst, "bin", "foo"), "content")
+			}
+		})
+	}
+}
+
 func TestExtract_NoStrip(t *testing.T) {
 	// Create tar.gz with multiple top-level directories
 	srcDir := t.TempDir()
@@ -159,6 +230,49 @@ func TestExtract_NoStrip(t *testing.T) {
 	assertFileContent(t, filepath.Join(dstDir, "dir2", "file2.txt"), "content2")
 }
 
+// The fixtures come from real tools: GNU tar with names and link targets
+// past 100 bytes, git archive (a global PAX header first), a tar of
+// "./sdk-1.0" and GNU tar piped through bzip2.
+func TestExtract_TarFixtures(t *testing.T) {
+	long := "include/" + strings.Repeat("very_long_directory_name_", 5) + "/" + strings.Repeat("nested_", 8) + "header.h"
+	tests := []struct {
+		file  string
+		files map[string]string
+	}{
+		{"gnu-longname.tar.gz", map[string]string{long: "long\n", "lib/libfoo.so.1": "lib\n", "lib/long-link.h": "long\n"}},
+		{"dot-prefix.tar.gz", map[string]string{long: "long\n", "lib/long-link.h": "long\n"}},
+		{"pax-global.tar.gz", map[string]string{"include/x.h": "#define X 1\n", "src/x.c": "int x;\n"}},
+		{"bzip2.tar.bz2", map[string]string{"include/bar.h": "#define BAR 1\n", "lib/libbar.a": "lib\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			dst := t.TempDir()
+			if err := Extract(filepath.Join("testdata", tt.file), dst); err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			for name, want := range tt.files {
+				assertFileContent(t, filepath.Join(dst, filepath.FromSlash(name)), want)
+			}
+			if _, err := os.Lstat(filepath.Join(dst, "pax_global_header")); !os.IsNotExist(err) {
+				t.Error("global PAX header extracted as a file")
+			}
+		})
+	}
+}
+
+func TestExtract_Unsupported(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "pkg.tar.zst")
+	if err := os.WriteFile(src, []byte("(\xb5/\xfd"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Extract(src, t.TempDir()); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Extract() error = %v, want %v", err, ErrUnsupportedFormat)
+	}
+	if _, err := DownloadWith(context.Background(), "http://127.0.0.1:0/pkg.rar", t.TempDir(), DownloadOptions{}); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("DownloadWith() error = %v, want %v", err, ErrUnsupportedFormat)
+	}
+}
+
 func TestCreate_TarGz(t *testing.T) {
 	// Create source directory
 	srcDir := t.TempDir()
@@ -209,8 +323,346 @@ func TestCreate_Zip(t *testing.T) {
 	}
 }
 
+func TestCreate_PreservesSymlinks(t *testing.T) {
+	for _, goos := range []string{"linux", "windows"} {
+		t.Run(goos, func(t *testing.T) {
+			srcDir := t.TempDir()
+			lib := filepath.Join(srcDir, "myapp", "lib")
+			if err := os.MkdirAll(lib, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(lib, "libfoo.so.1"), []byte("elf"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Symlink("libfoo.so.1", filepath.Join(lib, "libfoo.so")); err != nil {
+				t.Skipf("symlinks unsupported: %v", err)
+			}
+
+			path, err := Create(filepath.Join(srcDir, "myapp"), goos, "amd64")
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			dst := t.TempDir()
+			if err := Extract(path, dst); err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			got, err := os.Readlink(filepath.Join(dst, "lib", "libfoo.so"))
+			if err != nil {
+				t.Fatalf("libfoo.so not a symlink: %v", err)
+			}
+			if got != "libfoo.so.1" {
+				t.Errorf("libfoo.so -> %q, want libfoo.so.1", got)
+			}
+		})
+	}
+}
+
+func TestCreate_MaterializesExternalLinks(t *testing.T) {
+	cache := filepath.Join(t.TempDir(), "libfoo.so")
+	if err := os.WriteFile(cache, []byte("elf"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	srcDir := t.TempDir()
+	lib := filepath.Join(srcDir, "myapp", "lib")
+	if err := os.MkdirAll(lib, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(cache, filepath.Join(lib, "libfoo.so")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	path, err := Create(filepath.Join(srcDir, "myapp"), "linux", "amd64")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	dst := t.TempDir()
+	if err := Extract(path, dst); err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+
+	p := filepath.Join(dst, "lib", "libfoo.so")
+	info, err := os.Lstat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Mode().IsRegular() {
+		t.Errorf("libfoo.so mode = %v, want regular file", info.Mode())
+	}
+	assertFileContent(t, p, "elf")
+}
+
+func TestCreateIfChanged(t *testing.T) {
+	for _, goos := range []string{"linux", "windows"} {
+		t.Run(goos, func(t *testing.T) {
+			srcDir := t.TempDir()
+			testDir := filepath.Join(srcDir, "myapp")
+			bin := filepath.Join(testDir, "app")
+			if err := os.MkdirAll(testDir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(bin, []byte("v1"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			path, created, err := CreateIfChanged(testDir, goos, "amd64", CreateOptions{})
+			if err != nil {
+				t.Fatalf("CreateIfChanged() error = %v", err)
+			}
+			if !created {
+				t.Error("first call should create archive")
+			}
+
+			_, created, err = CreateIfChanged(testDir, goos, "amd64", CreateOptions{})
+			if err != nil {
+				t.Fatalf("CreateIfChanged() error = %v", err)
+			}
+			if created {
+				t.Error("unchanged content should reuse archive")
+			}
+
+			if err := os.WriteFile(bin, []byte("v2"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			_, created, err = CreateIfChanged(testDir, goos, "amd64", CreateOptions{})
+			if err != nil {
+				t.Fatalf("CreateIfChanged() error = %v", err)
+			}
+			if !created {
+				t.Error("changed content should recreate archive")
+			}
+
+			want, _ := Digest(testDir)
+			if got := ReadDigest(path); got != want {
+				t.Errorf("ReadDigest() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCreateIfChanged_ModTime(t *testing.T) {
+	epoch := time.Unix(1700000000, 0)
+	for _, goos := range []string{"linux", "windows"} {
+		t.Run(goos, func(t *testing.T) {
+			testDir := filepath.Join(t.TempDir(), "myapp")
+			if err := os.MkdirAll(filepath.Join(testDir, "lib"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range []string{"app", "lib/libfoo.so"} {
+				if err := os.WriteFile(filepath.Join(testDir, name), []byte(name), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var archives [][]byte
+			for i := range 2 {
+				// Touch the tree so only the clamped times can agree.
+				now := time.Now().Add(time.Duration(i) * time.Hour)
+				if err := os.Chtimes(filepath.Join(testDir, "app"), now, now); err != nil {
+					t.Fatal(err)
+				}
+				path, created, err := CreateIfChanged(testDir, goos, "amd64", CreateOptions{ModTime: epoch})
+				if err != nil {
+					t.Fatalf("CreateIfChanged() error = %v", err)
+				}
+				if i == 0 && !created {
+					t.Error("first call should create archive")
+				}
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				archives = append(archives, data)
+				if err := os.Remove(path); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if string(archives[0]) != string(archives[1]) {
+				t.Error("archives of the same tree differ")
+			}
+
+			if _, _, err := CreateIfChanged(testDir, goos, "amd64", CreateOptions{ModTime: epoch}); err != nil {
+				t.Fatal(err)
+			}
+			_, created, err := CreateIfChanged(testDir, goos, "amd64", CreateOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !created {
+				t.Error("changed options should recreate archive")
+			}
+		})
+	}
+}
+
+func TestCreateIfChanged_Deterministic(t *testing.T) {
+	// tree writes the same files with the given exec mode and time.
+	tree := func(exec os.FileMode, mtime time.Time) string {
+		dir := filepath.Join(t.TempDir(), "myapp")
+		files := []struct {
+			name string
+			mode os.FileMode
+		}{{"app", exec}, {"README", 0o600}, {"lib/libfoo.so", 0o640}}
+		for _, f := range files {
+			p := filepath.Join(dir, f.name)
+			if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(p, []byte(f.name), f.mode); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(p, f.mode); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(p, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+	opts := CreateOptions{Deterministic: true}
+	for _, goos := range []string{"linux", "windows"} {
+		t.Run(goos, func(t *testing.T) {
+			var archives [][]byte
+			for _, dir := range []string{tree(0o700, time.Now()), tree(0o755, time.Unix(1500000000, 0))} {
+				path, _, err := CreateIfChanged(dir, goos, "amd64", opts)
+				if err != nil {
+					t.Fatalf("CreateIfChanged() error = %v", err)
+				}
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				archives = append(archives, data)
+			}
+			if string(archives[0]) != string(archives[1]) {
+				t.Fatal("archives of the same files differ")
+			}
+
+			want := map[string]os.FileMode{
+				"myapp/":              os.ModeDir | 0o755,
+				"myapp/README":        0o644,
+				"myapp/app":           0o755,
+				"myapp/lib/":          os.ModeDir | 0o755,
+				"myapp/lib/libfoo.so": 0o644,
+			}
+			if goos == "windows" {
+				zr, err := zip.NewReader(strings.NewReader(string(archives[0])), int64(len(archives[0])))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, f := range zr.File {
+					if strings.HasSuffix(f.Name, "/") {
+						continue
+					}
+					if f.Mode() != want[f.Name] {
+						t.Errorf("%s mode = %v, want %v", f.Name, f.Mode(), want[f.Name])
+					}
+					if !f.Modified.Equal(DeterministicTime) {
+						t.Errorf("%s time = %v, want %v", f.Name, f.Modified, DeterministicTime)
+					}
+				}
+				return
+			}
+			gr, err := gzip.NewReader(strings.NewReader(string(archives[0])))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr := tar.NewReader(gr)
+			var names []string
+			for {
+				hdr, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				names = append(names, hdr.Name)
+				if got := hdr.FileInfo().Mode(); got != want[hdr.Name] {
+					t.Errorf("%s mode = %v, want %v", hdr.Name, got, want[hdr.Name])
+				}
+				if hdr.Uid != 0 || hdr.Gid != 0 || hdr.Uname != "" || hdr.Gname != "" {
+					t.Errorf("%s owner = %d:%d (%s:%s), want root", hdr.Name, hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname)
+				}
+				if !hdr.ModTime.Equal(DeterministicTime) {
+					t.Errorf("%s time = %v, want %v", hdr.Name, hdr.ModTime, DeterministicTime)
+				}
+			}
+			if !slices.IsSorted(names) {
+				t.Errorf("entries %q are not sorted", names)
+			}
+		})
+	}
+}
+
+func TestReadDigest_Missing(t *testing.T) {
+	if got := ReadDigest(filepath.Join(t.TempDir(), "none.tar.gz")); got != "" {
+		t.Errorf("ReadDigest() = %q, want empty", got)
+	}
+}
+
 // Helper functions
 
+func TestDownloadWith(t *testing.T) {
+	tarPath := filepath.Join(t.TempDir(), "test.tar.gz")
+	createTestTarGz(t, tarPath, map[string]string{"root/file.txt": "content"})
+	want, err := FileSHA256(tarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, tarPath)
+	}))
+	defer srv.Close()
+
+	t.Run("records digest", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "out")
+		res, err := DownloadWith(context.Background(), srv.URL+"/test.tar.gz", dst, DownloadOptions{})
+		if err != nil {
+			t.Fatalf("DownloadWith() error = %v", err)
+		}
+		if res.SHA256 != want {
+			t.Errorf("SHA256 = %q, want %q", res.SHA256, want)
+		}
+		info, _ := os.Stat(tarPath)
+		if res.Size != info.Size() {
+			t.Errorf("Size = %d, want %d", res.Size, info.Size())
+		}
+		assertFileContent(t, filepath.Join(dst, "file.txt"), "content")
+	})
+
+	t.Run("rejects mismatch", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "out")
+		_, err := DownloadWith(context.Background(), srv.URL+"/test.tar.gz", dst, DownloadOptions{SHA256: strings.Repeat("0", 64)})
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Fatalf("DownloadWith() error = %v, want ErrChecksumMismatch", err)
+		}
+		if _, err := os.Stat(dst); !os.IsNotExist(err) {
+			t.Error("mismatched archive should not be extracted")
+		}
+	})
+
+	t.Run("rejects failed verify", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "out")
+		errBad := errors.New("bad")
+		_, err := DownloadWith(context.Background(), srv.URL+"/test.tar.gz", dst, DownloadOptions{
+			Verify: func(file string) error {
+				if got, _ := FileSHA256(file); got != want {
+					t.Errorf("Verify(file) digest = %q, want %q", got, want)
+				}
+				return errBad
+			},
+		})
+		if !errors.Is(err, errBad) {
+			t.Fatalf("DownloadWith() error = %v, want Verify error", err)
+		}
+		if _, err := os.Stat(dst); !os.IsNotExist(err) {
+			t.Error("unverified archive should not be extracted")
+		}
+	})
+}
+
 func createTestTarGz(t *testing.T, path string, files map[string]string) {
 	t.Helper()
 
@@ -276,3 +728,109 @@ func assertFileContent(t *testing.T, path, want string) {
 		t.Errorf("file %q content = %q, want %q", path, string(data), want)
 	}
 }
+
+func TestCreateIfChanged_Include(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "myapp")
+	license := filepath.Join(dir, "LICENSE")
+	for path, data := range map[string]string{filepath.Join(src, "app"): "app", license: "MIT"} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := CreateOptions{Include: []File{{Src: license, Name: "docs/LICENSE"}}}
+	for _, goos := range []string{"linux", "windows"} {
+		t.Run(goos, func(t *testing.T) {
+			path, created, err := CreateIfChanged(src, goos, "amd64", opts)
+			if err != nil || !created {
+				t.Fatalf("CreateIfChanged() = %v, %v", created, err)
+			}
+			dst := t.TempDir()
+			if err := Extract(path, dst); err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range map[string]string{"app": "app", "docs/LICENSE": "MIT"} {
+				if got, err := os.ReadFile(filepath.Join(dst, name)); err != nil || string(got) != want {
+					t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+				}
+			}
+
+			if _, created, _ := CreateIfChanged(src, goos, "amd64", opts); created {
+				t.Error("unchanged archive rewritten")
+			}
+			if err := os.WriteFile(license, []byte("Apache-2.0"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { os.WriteFile(license, []byte("MIT"), 0o644) })
+			if _, created, _ := CreateIfChanged(src, goos, "amd64", opts); !created {
+				t.Error("archive kept after an included file changed")
+			}
+		})
+	}
+}
+
+func TestCreateIfChanged_Exclude(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "myapp")
+	for _, name := range []string{"bin/app", "bin/app.pdb", "lib/obj/foo.o", "lib/libfoo.so", "share/testdata/in.txt", "share/doc/README"} {
+		p := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := CreateOptions{Exclude: []string{"*.pdb", "obj", "**/testdata/**"}}
+	for _, goos := range []string{"linux", "windows"} {
+		t.Run(goos, func(t *testing.T) {
+			path, _, err := CreateIfChanged(src, goos, "amd64", opts)
+			if err != nil {
+				t.Fatalf("CreateIfChanged() error = %v", err)
+			}
+			dst := t.TempDir()
+			if err := Extract(path, dst); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			filepath.WalkDir(dst, func(p string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					rel, _ := filepath.Rel(dst, p)
+					got = append(got, filepath.ToSlash(rel))
+				}
+				return err
+			})
+			if want := []string{"bin/app", "lib/libfoo.so", "share/doc/README"}; !slices.Equal(got, want) {
+				t.Errorf("archive holds %q, want %q", got, want)
+			}
+
+			// Excluded files do not count towards the digest.
+			if err := os.WriteFile(filepath.Join(src, "bin", "app.pdb"), []byte(goos), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, created, _ := CreateIfChanged(src, goos, "amd64", opts); created {
+				t.Error("archive rewritten after an excluded file changed")
+			}
+		})
+	}
+}
+
+func TestCreateIfChanged_Name(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "myapp")
+	if err := os.WriteFile(src, []byte("binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path, created, err := CreateIfChanged(src, "linux", "amd64", CreateOptions{Name: "myapp_1.0_linux_x86_64"})
+	if err != nil {
+		t.Fatalf("CreateIfChanged() error = %v", err)
+	}
+	if want := filepath.Join(dir, "myapp_1.0_linux_x86_64.tar.gz"); path != want || !created {
+		t.Errorf("CreateIfChanged() = %q, %v, want %q, true", path, created, want)
+	}
+	if _, err := os.Stat(Path(src, "linux", "amd64")); !os.IsNotExist(err) {
+		t.Errorf("default archive path written: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrCaseCollision is returned when an archive holds paths differing only
+// in case and is extracted onto a case-insensitive filesystem, where one
+// would silently overwrite the other. Archives built on Linux, such as
+// SDKs shipping both Foo.h and foo.h, hit this on macOS and Windows.
+var ErrCaseCollision = errors.New("paths differ only in case")
+
+// foldsCase is CaseInsensitive, replaced by tests.
+var foldsCase = probeCase
+
+// CaseInsensitive reports whether the filesystem holding dir, which must
+// exist, folds case, as APFS and NTFS do by default.
+func CaseInsensitive(dir string) bool {
+	return foldsCase(dir)
+}
+
+// probeCase creates a mixed-case file in dir and looks it up upper-cased.
+func probeCase(dir string) bool {
+	f, err := os.CreateTemp(dir, ".gox-case-*")
+	if err != nil {
+		return false
+	}
+	name := f.Name()
+	f.Close()
+	defer os.Remove(name)
+	_, err = os.Lstat(filepath.Join(dir, strings.ToUpper(filepath.Base(name))))
+	return err == nil
+}
+
+// Collisions returns the pairs of paths that differ only in case, each
+// with the first path seen.
+func Collisions(paths []string) [][2]string {
+	var out [][2]string
+	seen := make(map[string]string, len(paths))
+	for _, p := range paths {
+		key := strings.ToLower(filepath.ToSlash(p))
+		if first, ok := seen[key]; ok && first != p {
+			out = append(out, [2]string{first, p})
+			continue
+		}
+		seen[key] = p
+	}
+	return out
+}
+
+// caseNames tracks the files an extraction writes into dst by their folded
+// path, failing on the first collision when dst folds case. The filesystem
+// is only probed once a collision shows up.
+type caseNames struct {
+	dst   string
+	seen  map[string]string
+	folds *bool
+}
+
+func newCaseNames(dst string) *caseNames {
+	return &caseNames{dst: dst, seen: make(map[string]string)}
+}
+
+// add records name, a file or symlink relative to dst.
+func (c *caseNames) add(name string) error {
+	key := strings.ToLower(name)
+	first, ok := c.seen[key]
+	if !ok {
+		c.seen[key] = name
+		return nil
+	}
+	if first == name {
+		return nil
+	}
+	if c.folds == nil {
+		folds := os.MkdirAll(c.dst, dirMode()) == nil && CaseInsensitive(c.dst)
+		c.folds = &folds
+	}
+	if *c.folds {
+		return fmt.Errorf("%w: %s and %s on the case-insensitive filesystem of %s", ErrCaseCollision, first, name, c.dst)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package archive
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCollisions(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  [][2]string
+	}{
+		{"none", []string{"lib/libfoo.so", "lib/libbar.so"}, nil},
+		{"same path", []string{"include/foo.h", "include/foo.h"}, nil},
+		{"file", []string{"include/Foo.h", "include/foo.h"}, [][2]string{{"include/Foo.h", "include/foo.h"}}},
+		{"directory", []string{"Include/foo.h", "include/foo.h", "include/FOO.h"}, [][2]string{{"Include/foo.h", "include/foo.h"}, {"Include/foo.h", "include/FOO.h"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Collisions(tt.paths); !slices.Equal(got, tt.want) {
+				t.Errorf("Collisions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	dir := t.TempDir()
+	got := CaseInsensitive(dir)
+	_, err := os.Stat(filepath.Join(dir, "X"))
+	if f, cerr := os.Create(filepath.Join(dir, "x")); cerr == nil {
+		f.Close()
+		_, err = os.Stat(filepath.Join(dir, "X"))
+	}
+	if want := err == nil; got != want {
+		t.Errorf("CaseInsensitive() = %v, want %v", got, want)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("CaseInsensitive() left files behind: %v", entries)
+	}
+}
+
+func TestExtract_CaseCollision(t *testing.T) {
+	files := map[string]string{
+		"sdk/include/Foo.h": "upper",
+		"sdk/include/foo.h": "lower",
+		"sdk/lib/libfoo.a":  "lib",
+	}
+	src := t.TempDir()
+	tgz, zp := filepath.Join(src, "sdk.tar.gz"), filepath.Join(src, "sdk.zip")
+	createTestTarGz(t, tgz, files)
+	createTestZip(t, zp, files)
+
+	for _, folds := range []bool{false, true} {
+		foldsCase = func(string) bool { return folds }
+		t.Cleanup(func() { foldsCase = probeCase })
+		for _, archive := range []string{tgz, zp} {
+			dst := t.TempDir()
+			err := Extract(archive, dst)
+			if folds {
+				if !errors.Is(err, ErrCaseCollision) {
+					t.Errorf("Extract(%s) onto a case-insensitive dir = %v, want ErrCaseCollision", filepath.Base(archive), err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("Extract(%s) = %v", filepath.Base(archive), err)
+			}
+			assertFileContent(t, filepath.Join(dst, "include", "Foo.h"), "upper")
+			assertFileContent(t, filepath.Join(dst, "include", "foo.h"), "lower")
+		}
+	}
+}
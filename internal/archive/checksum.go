@@ -0,0 +1,56 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SumsFile is the combined checksum file written across packed targets.
+const SumsFile = "SHA256SUMS"
+
+// FileSHA256 returns the hex SHA-256 of the file at path.
+func FileSHA256(path string) (string, error) {
+	h := sha256.New()
+	if err := copyTo(h, path); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// WriteChecksum writes path.sha256 in sha256sum format and returns the sum.
+func WriteChecksum(path string) (string, error) {
+	sum, err := FileSHA256(path)
+	if err != nil {
+		return "", err
+	}
+	line := fmt.Sprintf("%s  %s\n", sum, filepath.Base(path))
+	return sum, os.WriteFile(path+".sha256", []byte(line), 0o644)
+}
+
+// WriteSums writes a sha256sum-format file at dst listing files by their
+// path relative to dst's directory, sorted for stable output.
+func WriteSums(dst string, files []string) error {
+	dir := filepath.Dir(dst)
+	lines := make([]string, 0, len(files))
+	for _, f := range files {
+		sum, err := FileSHA256(f)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, f)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, fmt.Sprintf("%s  %s\n", sum, filepath.ToSlash(rel)))
+	}
+	slices.SortFunc(lines, func(a, b string) int {
+		return strings.Compare(a[sha256.Size*2:], b[sha256.Size*2:])
+	})
+	lines = slices.Compact(lines)
+	return os.WriteFile(dst, []byte(strings.Join(lines, "")), 0o644)
+}
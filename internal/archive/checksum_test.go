@@ -0,0 +1,65 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sha256 of "hello\n"
+const helloSum = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+
+func TestWriteChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app-linux-amd64.tar.gz")
+	if err := os.WriteFile(path, []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sum, err := WriteChecksum(path)
+	if err != nil {
+		t.Fatalf("WriteChecksum() error = %v", err)
+	}
+	if sum != helloSum {
+		t.Errorf("sum = %q, want %q", sum, helloSum)
+	}
+	data, err := os.ReadFile(path + ".sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := helloSum + "  app-linux-amd64.tar.gz\n"; string(data) != want {
+		t.Errorf(".sha256 = %q, want %q", data, want)
+	}
+}
+
+func TestWriteSums(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		filepath.Join(dir, "b", "app-windows-amd64.zip"),
+		filepath.Join(dir, "a", "app-linux-amd64.tar.gz"),
+	}
+	for _, f := range files {
+		if err := os.MkdirAll(filepath.Dir(f), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(f, []byte("hello\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dst := filepath.Join(dir, SumsFile)
+	if err := WriteSums(dst, append(files, files[0])); err != nil {
+		t.Fatalf("WriteSums() error = %v", err)
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := helloSum + "  a/app-linux-amd64.tar.gz\n" + helloSum + "  b/app-windows-amd64.zip\n"
+	if string(data) != want {
+		t.Errorf("SHA256SUMS =\n%s\nwant\n%s", data, want)
+	}
+	if strings.Count(string(data), "\n") != 2 {
+		t.Error("duplicate archives should be listed once")
+	}
+}
@@ -0,0 +1,205 @@
+package archive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/qntx/gox/internal/httpclient"
+)
+
+// DefaultChunks is the number of concurrent range requests used for large
+// downloads when DownloadOptions.Chunks is zero.
+const DefaultChunks = 4
+
+// chunkThreshold is the smallest archive split into chunks.
+var chunkThreshold int64 = 32 << 20
+
+// errRangeIgnored means the server answered a range request with something
+// other than the requested range.
+var errRangeIgnored = errors.New("range request not honored")
+
+// chunk is one byte range of a chunked download, stored in its own file
+// so it resumes independently.
+type chunk struct {
+	start, end int64 // inclusive
+	path       string
+}
+
+func (c chunk) len() int64 { return c.end - c.start + 1 }
+
+// fetchChunked downloads url with n concurrent range requests when the
+// server supports them and the archive is large enough. ok is false when
+// the caller should fall back to a single stream.
+func fetchChunked(ctx context.Context, url string, p *partial, opts DownloadOptions, n int) (res *DownloadResult, ok bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	resp, err := httpclient.Client.Do(req)
+	if err != nil {
+		return nil, false, nil
+	}
+	resp.Body.Close()
+
+	v := validator(resp.Header)
+	size := resp.ContentLength
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Accept-Ranges") != "bytes" || v == "" || size < chunkThreshold {
+		return nil, false, nil
+	}
+	if v != p.validator {
+		// The file changed since the chunks on disk were written.
+		p.discard()
+	}
+	p.setValidator(v)
+
+	chunks := planChunks(p.path, size, n)
+	var resumed int64
+	for _, c := range chunks {
+		if info, err := os.Stat(c.path); err == nil {
+			if info.Size() > c.len() {
+				os.Remove(c.path)
+				continue
+			}
+			resumed += info.Size()
+		}
+	}
+	if opts.Resumed != nil {
+		opts.Resumed(resumed)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	var wg sync.WaitGroup
+	for _, c := range chunks {
+		wg.Go(func() {
+			if err := fetchChunk(ctx, url, v, c, opts); err != nil {
+				cancel(err)
+			}
+		})
+	}
+	wg.Wait()
+	if err := context.Cause(ctx); err != nil {
+		if errors.Is(err, errRangeIgnored) {
+			p.discard()
+			return nil, false, nil
+		}
+		return nil, true, err
+	}
+
+	sum, err := joinChunks(p.path, chunks)
+	if err != nil {
+		return nil, true, err
+	}
+	return &DownloadResult{SHA256: sum, Size: size}, true, nil
+}
+
+// planChunks splits size bytes into n ranges stored next to path.
+func planChunks(path string, size int64, n int) []chunk {
+	step := (size + int64(n) - 1) / int64(n)
+	var chunks []chunk
+	for start := int64(0); start < size; start += step {
+		end := min(start+step, size) - 1
+		chunks = append(chunks, chunk{start: start, end: end, path: fmt.Sprintf("%s.%d-%d", path, start, end)})
+	}
+	return chunks
+}
+
+// fetchChunk appends the missing tail of c to its file.
+func fetchChunk(ctx context.Context, url, validator string, c chunk, opts DownloadOptions) error {
+	var have int64
+	if info, err := os.Stat(c.path); err == nil {
+		have = info.Size()
+	}
+	if have == c.len() {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", c.start+have, c.end))
+	req.Header.Set("If-Range", validator)
+
+	resp, err := httpclient.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusOK,
+		resp.StatusCode == http.StatusPartialContent && rangeStart(resp.Header.Get("Content-Range")) != c.start+have:
+		return fmt.Errorf("chunk %d-%d: %w", c.start, c.end, errRangeIgnored)
+	case resp.StatusCode != http.StatusPartialContent:
+		return fmt.Errorf("chunk %d-%d: %w", c.start, c.end, httpclient.NewStatusError(resp))
+	}
+
+	f, err := os.OpenFile(c.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	body := io.Reader(resp.Body)
+	if opts.Proxy != nil {
+		body = opts.Proxy(body)
+	}
+	n, err := io.CopyBuffer(f, io.LimitReader(body, c.len()-have), make([]byte, 256*1024))
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil && n < c.len()-have {
+		// A short 206 body: keep what arrived so the retry resumes it.
+		err = fmt.Errorf("chunk %d-%d: %w", c.start, c.end, io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+// joinChunks concatenates the chunks into path, removes them and returns
+// the digest of the result. A chunk of the wrong size fails the join and is
+// left for the next attempt to resume or refetch.
+func joinChunks(path string, chunks []chunk) (string, error) {
+	for _, c := range chunks {
+		info, err := os.Stat(c.path)
+		if err != nil {
+			return "", err
+		}
+		if info.Size() != c.len() {
+			return "", fmt.Errorf("chunk %d-%d: have %d of %d bytes: %w", c.start, c.end, info.Size(), c.len(), io.ErrUnexpectedEOF)
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	w := io.MultiWriter(f, h)
+	for _, c := range chunks {
+		if err := copyTo(w, c.path); err != nil {
+			return "", err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	for _, c := range chunks {
+		os.Remove(c.path)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// removeChunks deletes chunk files of the archive at path.
+func removeChunks(path string) {
+	matches, _ := filepath.Glob(path + ".*-*")
+	for _, m := range matches {
+		os.Remove(m)
+	}
+}
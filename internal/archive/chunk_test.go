@@ -0,0 +1,167 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/qntx/gox/internal/httpclient"
+)
+
+func TestPlanChunks(t *testing.T) {
+	tests := []struct {
+		size int64
+		n    int
+		want [][2]int64
+	}{
+		{100, 4, [][2]int64{{0, 24}, {25, 49}, {50, 74}, {75, 99}}},
+		{10, 3, [][2]int64{{0, 3}, {4, 7}, {8, 9}}},
+		{2, 4, [][2]int64{{0, 0}, {1, 1}}},
+	}
+	for _, tt := range tests {
+		chunks := planChunks("a.tar.gz", tt.size, tt.n)
+		if len(chunks) != len(tt.want) {
+			t.Fatalf("planChunks(%d, %d) = %d chunks, want %d", tt.size, tt.n, len(chunks), len(tt.want))
+		}
+		for i, c := range chunks {
+			if c.start != tt.want[i][0] || c.end != tt.want[i][1] {
+				t.Errorf("planChunks(%d, %d)[%d] = %d-%d, want %d-%d", tt.size, tt.n, i, c.start, c.end, tt.want[i][0], tt.want[i][1])
+			}
+		}
+	}
+}
+
+func TestDownloadWith_Chunked(t *testing.T) {
+	old := chunkThreshold
+	chunkThreshold = 1
+	t.Cleanup(func() { chunkThreshold = old })
+
+	tarPath := filepath.Join(t.TempDir(), "test.tar.gz")
+	noise := make([]byte, 32<<10)
+	_, _ = rand.Read(noise)
+	createTestTarGz(t, tarPath, map[string]string{"root/file.txt": "content", "root/noise.bin": string(noise)})
+	data, _ := os.ReadFile(tarPath)
+	want, _ := FileSHA256(tarPath)
+
+	var ranges atomic.Int32
+	var failOnce, shortOnce atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/norange/") {
+			_, _ = w.Write(data)
+			return
+		}
+		// Answer one chunk with only half of the requested range.
+		var start, end int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err == nil && start > 0 && shortOnce.CompareAndSwap(true, false) {
+			half := (end - start + 1) / 2
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+half-1, len(data)))
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(data[start : start+half])
+			return
+		}
+		if rg := r.Header.Get("Range"); rg != "" {
+			ranges.Add(1)
+			// Fail the last chunk once to leave resumable chunks behind.
+			if failOnce.CompareAndSwap(true, false) && !strings.HasPrefix(rg, "bytes=0-") {
+				http.Error(w, "flaky", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "test.tar.gz", time.Time{}, bytes.NewReader(data))
+	}))
+	defer srv.Close()
+
+	t.Run("splits into ranges", func(t *testing.T) {
+		ranges.Store(0)
+		dst := filepath.Join(t.TempDir(), "out")
+		res, err := DownloadWith(context.Background(), srv.URL+"/a/test.tar.gz", dst, DownloadOptions{Chunks: 4})
+		if err != nil {
+			t.Fatalf("DownloadWith() error = %v", err)
+		}
+		if res.SHA256 != want || res.Size != int64(len(data)) {
+			t.Errorf("DownloadWith() = %+v, want sha %s size %d", res, want, len(data))
+		}
+		if n := ranges.Load(); n != 4 {
+			t.Errorf("range requests = %d, want 4", n)
+		}
+		assertFileContent(t, filepath.Join(dst, "file.txt"), "content")
+	})
+
+	t.Run("resumes failed chunks", func(t *testing.T) {
+		url := srv.URL + "/b/test.tar.gz"
+		failOnce.Store(true)
+		old := httpclient.CurrentPolicy()
+		httpclient.SetPolicy(httpclient.Policy{Attempts: 1})
+		defer httpclient.SetPolicy(old)
+		dst := filepath.Join(t.TempDir(), "out")
+		if _, err := DownloadWith(context.Background(), url, dst, DownloadOptions{Chunks: 4}); err == nil {
+			t.Fatal("DownloadWith() error = nil, want flaky chunk error")
+		}
+
+		httpclient.SetPolicy(old)
+		var resumed int64
+		res, err := DownloadWith(context.Background(), url, dst, DownloadOptions{
+			Chunks:  4,
+			Resumed: func(n int64) { resumed = n },
+		})
+		if err != nil {
+			t.Fatalf("DownloadWith() retry error = %v", err)
+		}
+		if res.SHA256 != want {
+			t.Errorf("SHA256 = %s, want %s", res.SHA256, want)
+		}
+		if resumed == 0 {
+			t.Error("retry did not reuse completed chunks")
+		}
+	})
+
+	t.Run("rejects short chunks", func(t *testing.T) {
+		url := srv.URL + "/c/test.tar.gz"
+		shortOnce.Store(true)
+		old := httpclient.CurrentPolicy()
+		httpclient.SetPolicy(httpclient.Policy{Attempts: 1})
+		defer httpclient.SetPolicy(old)
+		dst := filepath.Join(t.TempDir(), "out")
+		// The short chunk must fail the download, not extraction of a
+		// truncated archive.
+		_, err := DownloadWith(context.Background(), url, dst, DownloadOptions{Chunks: 4})
+		if !errors.Is(err, io.ErrUnexpectedEOF) || !strings.HasPrefix(err.Error(), "chunk ") {
+			t.Fatalf("DownloadWith() error = %v, want short chunk error", err)
+		}
+
+		httpclient.SetPolicy(old)
+		res, err := DownloadWith(context.Background(), url, dst, DownloadOptions{Chunks: 4})
+		if err != nil {
+			t.Fatalf("DownloadWith() retry error = %v", err)
+		}
+		if res.SHA256 != want {
+			t.Errorf("SHA256 = %s, want %s", res.SHA256, want)
+		}
+	})
+
+	t.Run("falls back without range support", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "out")
+		res, err := DownloadWith(context.Background(), srv.URL+"/norange/test.tar.gz", dst, DownloadOptions{Chunks: 4})
+		if err != nil {
+			t.Fatalf("DownloadWith() error = %v", err)
+		}
+		sum := sha256.Sum256(data)
+		if res.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("SHA256 = %s, want %x", res.SHA256, sum)
+		}
+	})
+}
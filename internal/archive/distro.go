@@ -0,0 +1,423 @@
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ulikunitz/xz/lzma"
+
+	"github.com/qntx/gox/internal/zstd"
+)
+
+// Debian and RPM packages hold the files they install below the root of
+// the system. Extracting one keeps the include/lib/bin layout of other
+// packages: /usr is dropped, and multiarch directories such as
+// lib/x86_64-linux-gnu and include/x86_64-linux-gnu, as well as lib64, are
+// merged into lib and include.
+
+// multiarchRE matches Debian multiarch tuples, e.g. x86_64-linux-gnu or
+// arm-linux-gnueabihf.
+var multiarchRE = regexp.MustCompile(`^[a-z0-9_]+-linux-[a-z0-9_]+$`)
+
+// distroPath returns where the installed path name goes in the package
+// layout, "" for the root.
+func distroPath(name string) string {
+	elems := strings.Split(strings.TrimPrefix(path.Clean("/"+name), "/"), "/")
+	if elems[0] == "usr" {
+		elems = elems[1:]
+	}
+	if len(elems) > 0 && elems[0] == "lib64" {
+		elems[0] = "lib"
+	}
+	if len(elems) > 1 && (elems[0] == "lib" || elems[0] == "include") && multiarchRE.MatchString(elems[1]) {
+		elems = append(elems[:1], elems[2:]...)
+	}
+	return path.Join(elems...)
+}
+
+// distroLink returns the target of the symlink name as it points in the
+// package layout: resolved against the installed system, moved like the
+// files and made relative.
+func distroLink(name, target string) string {
+	if !path.IsAbs(target) {
+		target = path.Join("/", path.Dir(path.Clean("/"+name)), target)
+	}
+	rel, err := filepath.Rel(filepath.FromSlash(path.Dir("/"+distroPath(name))), filepath.FromSlash("/"+distroPath(target)))
+	if err != nil {
+		return target
+	}
+	return rel
+}
+
+// distroExtractor writes the files of a Debian or RPM package below dst.
+type distroExtractor struct {
+	dst   string
+	names *caseNames
+	dirs  map[string]struct{}
+	links []link
+}
+
+func newDistroExtractor(dst string) *distroExtractor {
+	return &distroExtractor{dst: dst, names: newCaseNames(dst), dirs: map[string]struct{}{}}
+}
+
+// path returns where the installed path name is extracted, "" for the root.
+func (x *distroExtractor) path(name string) (string, error) {
+	rel := distroPath(name)
+	if rel == "" {
+		return "", nil
+	}
+	return safe(x.dst, rel)
+}
+
+// add extracts an entry of mode m; r holds the contents of a regular file
+// and linkname the target of a symlink. Devices and fifos are skipped.
+func (x *distroExtractor) add(name string, m os.FileMode, linkname string, r io.Reader) error {
+	p, err := x.path(name)
+	if err != nil || p == "" {
+		return err
+	}
+	switch {
+	case m.IsDir():
+		return mkdirCached(p, x.dirs)
+	case m&os.ModeType == 0, m&os.ModeSymlink != 0:
+		if err := x.names.add(distroPath(name)); err != nil {
+			return err
+		}
+		if err := mkdirCached(filepath.Dir(p), x.dirs); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+	if m&os.ModeSymlink != 0 {
+		target, err := x.linkTarget(name, linkname, p)
+		if err != nil {
+			return err
+		}
+		if err := mklink(target, p); err != nil {
+			x.links = append(x.links, link{target, p})
+		}
+		return nil
+	}
+	mode, err := fileMode(name, m)
+	if err != nil {
+		return err
+	}
+	return streamToFile(r, p, mode)
+}
+
+// linkTarget returns the target of the symlink name, extracted to p, made
+// relative to the directory p really is in: a directory symlink extracted
+// earlier may have put it elsewhere than its name says.
+func (x *distroExtractor) linkTarget(name, linkname, p string) (string, error) {
+	root, err := filepath.EvalSymlinks(x.dst)
+	if err != nil {
+		return "", err
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(p))
+	if err != nil {
+		return "", err
+	}
+	if !under(root, dir) {
+		return "", fmt.Errorf("%w: %s", ErrPathTraversal, name)
+	}
+	abs := filepath.Join(root, filepath.Dir(filepath.FromSlash(distroPath(name))), distroLink(name, linkname))
+	target, err := filepath.Rel(dir, abs)
+	if err != nil {
+		return "", err
+	}
+	return target, linkInside(x.dst, p, target)
+}
+
+// hardlink extracts name as a copy of the already extracted target.
+func (x *distroExtractor) hardlink(name, target string) error {
+	p, err := x.path(name)
+	if err != nil || p == "" {
+		return err
+	}
+	src, err := x.path(target)
+	if err != nil {
+		return err
+	}
+	if err := x.names.add(distroPath(name)); err != nil {
+		return err
+	}
+	return cp(src, p)
+}
+
+// close copies symlinks the filesystem could not create.
+func (x *distroExtractor) close() error {
+	return resolveLinks(x.links)
+}
+
+// payloadReader returns the decompressor of a package payload, named by
+// its file extension or RPM compressor: gz, xz, zst, bz2, lzma or none.
+func payloadReader(kind string) (func(io.Reader) (io.Reader, error), error) {
+	switch kind {
+	case "", "none":
+		return func(r io.Reader) (io.Reader, error) { return r, nil }, nil
+	case "gz", "gzip":
+		return gzReader, nil
+	case "xz":
+		return xzReader, nil
+	case "zst", "zstd":
+		return func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r), nil }, nil
+	case "bz2", "bzip2":
+		return bz2Reader, nil
+	case "lzma":
+		return func(r io.Reader) (io.Reader, error) { return lzma.NewReader(r) }, nil
+	}
+	return nil, fmt.Errorf("unsupported payload compression %q", kind)
+}
+
+// undeb extracts the data.tar member of the Debian package src to dst.
+func undeb(src, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	magic := make([]byte, 8)
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != "!<arch>\n" {
+		return errors.New("deb: not an ar archive")
+	}
+	for {
+		var hdr [60]byte
+		if _, err := io.ReadFull(r, hdr[:]); err == io.EOF {
+			return errors.New("deb: no data.tar member")
+		} else if err != nil {
+			return fmt.Errorf("deb: %w", err)
+		}
+		name := strings.TrimSuffix(strings.TrimSpace(string(hdr[:16])), "/")
+		size, err := strconv.ParseInt(strings.TrimSpace(string(hdr[48:58])), 10, 64)
+		if err != nil || size < 0 || string(hdr[58:]) != "`\n" {
+			return errors.New("deb: corrupt member header")
+		}
+		if ext, ok := strings.CutPrefix(name, "data.tar"); ok {
+			decomp, err := payloadReader(strings.TrimPrefix(ext, "."))
+			if err != nil {
+				return fmt.Errorf("deb: %w", err)
+			}
+			dr, err := decomp(io.LimitReader(r, size))
+			if err != nil {
+				return fmt.Errorf("deb: %w", err)
+			}
+			return untarDistro(tar.NewReader(dr), newDistroExtractor(dst))
+		}
+		// Members are padded to an even size.
+		if _, err := r.Discard(int(size + size%2)); err != nil {
+			return fmt.Errorf("deb: %w", err)
+		}
+	}
+}
+
+func untarDistro(tr *tar.Reader, x *distroExtractor) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return x.close()
+		}
+		if err != nil {
+			return err
+		}
+		if !entryName(hdr) {
+			continue
+		}
+		if hdr.Typeflag == tar.TypeLink {
+			err = x.hardlink(hdr.Name, hdr.Linkname)
+		} else {
+			err = x.add(hdr.Name, hdr.FileInfo().Mode(), hdr.Linkname, tr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// RPM header tags of the payload.
+const (
+	rpmTagPayloadFormat     = 1124
+	rpmTagPayloadCompressor = 1125
+)
+
+// unrpm extracts the cpio payload of the RPM package src to dst.
+func unrpm(src, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	lead := make([]byte, 96)
+	if _, err := io.ReadFull(r, lead); err != nil || !bytes.Equal(lead[:4], []byte{0xed, 0xab, 0xee, 0xdb}) {
+		return errors.New("rpm: not an RPM package")
+	}
+	if _, err := readRPMHeader(r, true); err != nil {
+		return fmt.Errorf("rpm: signature: %w", err)
+	}
+	tags, err := readRPMHeader(r, false)
+	if err != nil {
+		return fmt.Errorf("rpm: %w", err)
+	}
+	if format := tags[rpmTagPayloadFormat]; format != "" && format != "cpio" {
+		return fmt.Errorf("rpm: unsupported payload format %q", format)
+	}
+	comp := tags[rpmTagPayloadCompressor]
+	if comp == "" {
+		comp = "gzip"
+	}
+	decomp, err := payloadReader(comp)
+	if err != nil {
+		return fmt.Errorf("rpm: %w", err)
+	}
+	pr, err := decomp(r)
+	if err != nil {
+		return fmt.Errorf("rpm: %w", err)
+	}
+	if err := uncpio(bufio.NewReader(pr), newDistroExtractor(dst)); err != nil {
+		return fmt.Errorf("rpm: %w", err)
+	}
+	return nil
+}
+
+// readRPMHeader reads a header structure, the signature padded to 8 bytes,
+// and returns its string tags.
+func readRPMHeader(r io.Reader, pad bool) (map[uint32]string, error) {
+	var intro [16]byte
+	if _, err := io.ReadFull(r, intro[:]); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(intro[:4], []byte{0x8e, 0xad, 0xe8, 0x01}) {
+		return nil, errors.New("bad header magic")
+	}
+	n, size := binary.BigEndian.Uint32(intro[8:]), binary.BigEndian.Uint32(intro[12:])
+	if n > 1<<16 || size > 256<<20 {
+		return nil, errors.New("header too large")
+	}
+	data := make([]byte, 16*n+size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	if pad {
+		if _, err := io.CopyN(io.Discard, r, int64(-size&7)); err != nil {
+			return nil, err
+		}
+	}
+	index, store := data[:16*n], data[16*n:]
+	tags := map[uint32]string{}
+	for e := range slices.Chunk(index, 16) {
+		const typeString = 6
+		tag, typ, off := binary.BigEndian.Uint32(e), binary.BigEndian.Uint32(e[4:]), binary.BigEndian.Uint32(e[8:])
+		if typ != typeString || off >= size {
+			continue
+		}
+		if s, _, ok := bytes.Cut(store[off:], []byte{0}); ok {
+			tags[tag] = string(s)
+		}
+	}
+	return tags, nil
+}
+
+// uncpio extracts a cpio archive in the newc format RPM payloads use.
+func uncpio(r *bufio.Reader, x *distroExtractor) error {
+	// Hard links share an inode; newc stores the contents with the last.
+	pending := map[uint64][]string{}
+	for {
+		var hdr [110]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return fmt.Errorf("cpio: %w", err)
+		}
+		if magic := string(hdr[:6]); magic != "070701" && magic != "070702" {
+			return errors.New("cpio: not a newc archive")
+		}
+		var fields [13]uint64
+		for i := range fields {
+			v, err := strconv.ParseUint(string(hdr[6+8*i:14+8*i]), 16, 32)
+			if err != nil {
+				return errors.New("cpio: corrupt header")
+			}
+			fields[i] = v
+		}
+		ino, mode, nlink, size, namesize := fields[0], fields[1], fields[4], int64(fields[6]), fields[11]
+		name := make([]byte, namesize)
+		if _, err := io.ReadFull(r, name); err != nil {
+			return fmt.Errorf("cpio: %w", err)
+		}
+		// The name and the contents are padded to 4 bytes.
+		if _, err := r.Discard(int(-(110 + namesize) & 3)); err != nil {
+			return fmt.Errorf("cpio: %w", err)
+		}
+		n := strings.TrimRight(string(name), "\x00")
+		if n == "TRAILER!!!" {
+			for _, names := range pending {
+				for _, n := range names {
+					if err := x.add(n, 0o644, "", bytes.NewReader(nil)); err != nil {
+						return err
+					}
+				}
+			}
+			return x.close()
+		}
+
+		m := os.FileMode(mode & 0o777)
+		if mode&0o4000 != 0 {
+			m |= os.ModeSetuid
+		}
+		if mode&0o2000 != 0 {
+			m |= os.ModeSetgid
+		}
+		if mode&0o1000 != 0 {
+			m |= os.ModeSticky
+		}
+		data := io.LimitReader(r, size)
+		var err error
+		switch mode & 0o170000 {
+		case 0o040000:
+			err = x.add(n, m|os.ModeDir, "", nil)
+		case 0o120000:
+			var target []byte
+			if target, err = io.ReadAll(data); err == nil {
+				err = x.add(n, m|os.ModeSymlink, string(target), nil)
+			}
+		case 0o100000:
+			if nlink > 1 && size == 0 {
+				pending[ino] = append(pending[ino], n)
+				break
+			}
+			if err = x.add(n, m, "", data); err != nil {
+				break
+			}
+			for _, other := range pending[ino] {
+				if err = x.hardlink(other, n); err != nil {
+					break
+				}
+			}
+			delete(pending, ino)
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(io.Discard, data); err != nil {
+			return fmt.Errorf("cpio: %w", err)
+		}
+		if _, err := r.Discard(int(-size & 3)); err != nil {
+			return fmt.Errorf("cpio: %w", err)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/qntx/gox/internal/linuxpkg"
+)
+
+func TestDistroPath(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"./usr/include/foo.h", "include/foo.h"},
+		{"usr/include/x86_64-linux-gnu/openssl/conf.h", "include/openssl/conf.h"},
+		{"usr/lib/x86_64-linux-gnu/libssl.a", "lib/libssl.a"},
+		{"usr/lib/arm-linux-gnueabihf/pkgconfig/ssl.pc", "lib/pkgconfig/ssl.pc"},
+		{"/usr/lib64/libssl.so", "lib/libssl.so"},
+		{"lib64/libc.so.6", "lib/libc.so.6"},
+		{"usr/bin/openssl", "bin/openssl"},
+		{"usr/share/doc/x86_64-linux-gnu", "share/doc/x86_64-linux-gnu"},
+		{"etc/ssl/openssl.cnf", "etc/ssl/openssl.cnf"},
+		{"./usr/", ""},
+		{"../../etc/passwd", "etc/passwd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := distroPath(tt.input); got != tt.want {
+				t.Errorf("distroPath(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDistroLink(t *testing.T) {
+	tests := []struct {
+		name, target string
+		want         string
+	}{
+		{"usr/lib/x86_64-linux-gnu/libfoo.so", "libfoo.so.1", "libfoo.so.1"},
+		{"usr/lib/x86_64-linux-gnu/libfoo.so", "/usr/lib/x86_64-linux-gnu/libfoo.so.1", "libfoo.so.1"},
+		{"usr/lib/x86_64-linux-gnu/libfoo.so", "/lib/x86_64-linux-gnu/libfoo.so.1", "libfoo.so.1"},
+		{"usr/lib64/libfoo.so", "../../lib64/libfoo.so.1", "libfoo.so.1"},
+		{"usr/bin/foo", "../lib/foo/foo", filepath.Join("..", "lib", "foo", "foo")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+"->"+tt.target, func(t *testing.T) {
+			if got := distroLink(tt.name, tt.target); got != tt.want {
+				t.Errorf("distroLink(%q, %q) = %q, want %q", tt.name, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+// The libfoo-dev fixtures were built with dpkg-deb -Zxz and -Zzstd from a
+// multiarch tree: headers in usr/include and usr/include/x86_64-linux-gnu,
+// libs in usr/lib/x86_64-linux-gnu with a relative and an absolute symlink.
+func TestExtract_Deb(t *testing.T) {
+	for _, comp := range []string{"xz", "zstd"} {
+		t.Run(comp, func(t *testing.T) {
+			dst := t.TempDir()
+			if err := Extract(filepath.Join("testdata", "libfoo-dev-"+comp+".deb"), dst); err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			assertFileContent(t, filepath.Join(dst, "include", "foo.h"), "#include <foo/conf.h>\n")
+			assertFileContent(t, filepath.Join(dst, "include", "foo", "conf.h"), "#define FOO_ARCH 1\n")
+			assertFileContent(t, filepath.Join(dst, "lib", "libfoo.a"), "archive\n")
+			assertFileContent(t, filepath.Join(dst, "lib", "libfoo.so"), "shared\n")
+			assertFileContent(t, filepath.Join(dst, "lib", "pkgconfig", "foo.pc"), "prefix=/usr\nName: foo\n")
+			assertFileContent(t, filepath.Join(dst, "share", "doc", "libfoo-dev", "copyright"), "copyright\n")
+			for name, want := range map[string]string{"libfoo.so": "libfoo.so.1", "libfoo.so.1": "libfoo.so.1.2"} {
+				if target, err := os.Readlink(filepath.Join(dst, "lib", name)); err != nil || target != want {
+					t.Errorf("Readlink(lib/%s) = %q, %v, want %q", name, target, err, want)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dst, "usr")); !os.IsNotExist(err) {
+				t.Errorf("usr/ was extracted: %v", err)
+			}
+		})
+	}
+}
+
+func TestExtract_RPM(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{"ssl.h": "#define SSL 3\n", "libssl.so.3": "shared\n", "openssl": "#!/bin/sh\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := &linuxpkg.Package{
+		Name: "openssl-devel", Version: "3.0.7", Arch: "amd64", License: "Apache-2.0",
+		ModTime: time.Unix(1700000000, 0),
+		Files: []linuxpkg.File{
+			{Src: filepath.Join(dir, "ssl.h"), Dst: "/usr/include/openssl/ssl.h", Mode: 0o644},
+			{Src: filepath.Join(dir, "libssl.so.3"), Dst: "/usr/lib64/libssl.so.3", Mode: 0o755},
+			{Dst: "/usr/lib64/libssl.so", Mode: os.ModeSymlink | 0o777, Link: "libssl.so.3"},
+			{Src: filepath.Join(dir, "openssl"), Dst: "/usr/bin/openssl", Mode: 0o755},
+		},
+	}
+	src := filepath.Join(t.TempDir(), "openssl-devel-3.0.7-1.x86_64.rpm")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.WriteRPM(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := t.TempDir()
+	if err := Extract(src, dst); err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	assertFileContent(t, filepath.Join(dst, "include", "openssl", "ssl.h"), "#define SSL 3\n")
+	assertFileContent(t, filepath.Join(dst, "lib", "libssl.so"), "shared\n")
+	if target, err := os.Readlink(filepath.Join(dst, "lib", "libssl.so")); err != nil || target != "libssl.so.3" {
+		t.Errorf("Readlink(lib/libssl.so) = %q, %v, want libssl.so.3", target, err)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "bin", "openssl")); err != nil || info.Mode().Perm() != 0o755 {
+		t.Errorf("bin/openssl mode = %v, %v, want 0755", info.Mode(), err)
+	}
+}
+
+// A symlink below an earlier directory symlink is made relative to where it
+// really lands, so it cannot point above dst for later entries to write
+// through.
+func TestExtract_RPMNestedLinks(t *testing.T) {
+	dir := t.TempDir()
+	evil := filepath.Join(dir, "evil")
+	if err := os.WriteFile(evil, []byte("evil\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := &linuxpkg.Package{
+		Name: "links", Version: "1.0", Arch: "amd64", License: "MIT",
+		ModTime: time.Unix(1700000000, 0),
+		Files: []linuxpkg.File{
+			{Dst: "/a/b/c", Mode: os.ModeSymlink | 0o777, Link: "/"},
+			{Dst: "/a/b/c/d/e/l", Mode: os.ModeSymlink | 0o777, Link: "/"},
+			{Src: evil, Dst: "/a/b/c/d/e/l/evil", Mode: 0o644},
+		},
+	}
+	src := filepath.Join(t.TempDir(), "links-1.0-1.x86_64.rpm")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.WriteRPM(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	parent := t.TempDir()
+	dst := filepath.Join(parent, "x", "y", "pkg")
+	if err := Extract(src, dst); err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if target, err := os.Readlink(filepath.Join(dst, "d", "e", "l")); err != nil || target != filepath.Join("..", "..") {
+		t.Errorf("Readlink(d/e/l) = %q, %v, want ../..", target, err)
+	}
+	assertFileContent(t, filepath.Join(dst, "evil"), "evil\n")
+	for _, d := range []string{parent, filepath.Join(parent, "x"), filepath.Join(parent, "x", "y")} {
+		if _, err := os.Lstat(filepath.Join(d, "evil")); err == nil {
+			t.Errorf("Extract() wrote %s outside dst", filepath.Join(d, "evil"))
+		}
+	}
+}
+
+// newc stores the contents of hard linked files with the last link only.
+func TestUncpio_Hardlinks(t *testing.T) {
+	var buf bytes.Buffer
+	entry := func(ino, mode, nlink int, name, data string) {
+		fmt.Fprintf(&buf, "070701%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x",
+			ino, mode, 0, 0, nlink, 0, len(data), 0, 0, 0, 0, len(name)+1, 0)
+		buf.WriteString(name + "\x00")
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+		buf.WriteString(data)
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+	}
+	entry(1, 0o040755, 2, "./usr/lib64", "")
+	entry(2, 0o100644, 2, "./usr/lib64/libz.so.1", "")
+	entry(2, 0o100644, 2, "./usr/lib64/libz.so.1.3", "zlib\n")
+	entry(3, 0o100644, 1, "./usr/include/zlib.h", "#define ZLIB 1\n")
+	entry(0, 0, 1, "TRAILER!!!", "")
+
+	dst := t.TempDir()
+	if err := uncpio(bufio.NewReader(&buf), newDistroExtractor(dst)); err != nil {
+		t.Fatalf("uncpio() error = %v", err)
+	}
+	assertFileContent(t, filepath.Join(dst, "lib", "libz.so.1"), "zlib\n")
+	assertFileContent(t, filepath.Join(dst, "lib", "libz.so.1.3"), "zlib\n")
+	assertFileContent(t, filepath.Join(dst, "include", "zlib.h"), "#define ZLIB 1\n")
+}
+
+func TestExtract_DistroCorrupt(t *testing.T) {
+	deb, err := os.ReadFile(filepath.Join("testdata", "libfoo-dev-xz.deb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"pkg.deb", []byte("not an ar archive")},
+		{"pkg.deb", deb[:len(deb)/2]},
+		{"pkg.deb", []byte("!<arch>\n")},
+		{"pkg.rpm", []byte("not an rpm")},
+		{"pkg.rpm", append([]byte{0xed, 0xab, 0xee, 0xdb}, make([]byte, 200)...)},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			src := filepath.Join(t.TempDir(), tt.name)
+			if err := os.WriteFile(src, tt.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := Extract(src, t.TempDir()); err == nil {
+				t.Error("Extract() succeeded")
+			}
+		})
+	}
+}
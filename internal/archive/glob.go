@@ -0,0 +1,45 @@
+package archive
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether the slash-separated name matches pattern, whose
+// elements are path.Match patterns except "**", which matches any number
+// of elements.
+func Match(pattern, name string) bool {
+	return matchElems(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// Excluded reports whether the slash-separated name, or its base name,
+// matches any of patterns.
+func Excluded(name string, patterns []string) bool {
+	for _, pat := range patterns {
+		if Match(pat, name) || Match(pat, path.Base(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchElems(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := range len(name) + 1 {
+				if matchElems(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,28 @@
+package archive
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"LICENSE", "LICENSE", true},
+		{"*.md", "README.md", true},
+		{"*.md", "docs/README.md", false},
+		{"configs/**", "configs/app.yaml", true},
+		{"configs/**", "configs/prod/app.yaml", true},
+		{"configs/**", "configs", true},
+		{"configs/**", "other/app.yaml", false},
+		{"**/*.pdb", "app.pdb", true},
+		{"**/*.pdb", "bin/x64/app.pdb", true},
+		{"**/testdata/**", "pkg/testdata/in.txt", true},
+		{"docs/*/index.html", "docs/api/index.html", true},
+		{"docs/*/index.html", "docs/api/v1/index.html", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
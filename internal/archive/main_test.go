@@ -0,0 +1,28 @@
+package archive
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/qntx/gox/internal/cache"
+	"github.com/qntx/gox/internal/httpclient"
+	"github.com/qntx/gox/internal/workspace"
+)
+
+// TestMain keeps partial downloads in a throwaway cache, makes retries
+// fast, and removes the run workspace that download tests create.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "gox-archive-test-*")
+	if err != nil {
+		panic(err)
+	}
+	if err := cache.Use(cache.ScopeProject, dir); err != nil {
+		panic(err)
+	}
+	httpclient.SetPolicy(httpclient.Policy{Attempts: 2, Backoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	code := m.Run()
+	_ = workspace.Cleanup()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
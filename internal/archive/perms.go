@@ -0,0 +1,94 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Perms is the permission policy of extracted files and directories.
+type Perms struct {
+	Umask    os.FileMode // bits cleared from archive modes, as by a umask
+	Faithful bool        // apply archive modes unchanged, setuid/setgid included
+}
+
+// DefaultPerms is used unless gox.toml configures extract-umask or
+// extract-faithful.
+var DefaultPerms = Perms{Umask: 0o022}
+
+var perms = DefaultPerms
+
+// ErrUnsafeMode is returned for setuid or setgid entries, which are only
+// extracted with a faithful policy.
+var ErrUnsafeMode = errors.New("unsafe file mode")
+
+// SetPerms replaces the extraction permission policy for the rest of the
+// process.
+func SetPerms(p Perms) {
+	p.Umask &= os.ModePerm
+	perms = p
+}
+
+// fileMode returns the mode to give the extracted file name whose archive
+// mode is m. Unless faithful, setuid and setgid entries are rejected, the
+// sticky bit and world write are dropped, the umask is applied, and the
+// owner can always read and write.
+func fileMode(name string, m os.FileMode) (os.FileMode, error) {
+	special := m & (os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	if perms.Faithful {
+		return m.Perm() | special, nil
+	}
+	if special&(os.ModeSetuid|os.ModeSetgid) != 0 {
+		return 0, fmt.Errorf("%w: %s has setuid/setgid bits (%v)", ErrUnsafeMode, name, m)
+	}
+	return (m.Perm()&^perms.Umask | 0o600) &^ 0o002, nil
+}
+
+// dirMode returns the mode of extracted directories: 0755 under the umask,
+// always traversable and writable by the owner.
+func dirMode() os.FileMode {
+	if perms.Faithful {
+		return perm
+	}
+	return perm&^perms.Umask | 0o700
+}
+
+// Zip creator systems whose entries carry unix mode bits.
+const (
+	creatorUnix   = 3
+	creatorMacOSX = 19
+)
+
+// hasUnixMode reports whether the zip entry f records unix permissions.
+// Zips made on Windows only hold DOS attributes, so every file comes out
+// non-executable.
+func hasUnixMode(f *zip.File) bool {
+	switch f.CreatorVersion >> 8 {
+	case creatorUnix, creatorMacOSX:
+		return true
+	}
+	return false
+}
+
+// isExecutable reports whether a file starting with head is an ELF or
+// Mach-O binary or a script with a shebang line.
+func isExecutable(head []byte) bool {
+	if bytes.HasPrefix(head, []byte("\x7fELF")) || bytes.HasPrefix(head, []byte("#!")) {
+		return true
+	}
+	if len(head) < 8 {
+		return false
+	}
+	switch binary.BigEndian.Uint32(head) {
+	case 0xfeedface, 0xfeedfacf, 0xcefaedfe, 0xcffaedfe:
+		return true
+	case 0xcafebabe:
+		// Fat Mach-O, unless a Java class file: its version follows the
+		// magic where a universal binary holds its small architecture count.
+		return binary.BigEndian.Uint32(head[4:]) < 45
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFileMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Perms
+		mode    os.FileMode
+		want    os.FileMode
+		wantErr bool
+	}{
+		{"default exec", DefaultPerms, 0o755, 0o755, false},
+		{"world writable", DefaultPerms, 0o777, 0o755, false},
+		{"unreadable", DefaultPerms, 0o000, 0o600, false},
+		{"sticky dropped", DefaultPerms, 0o644 | os.ModeSticky, 0o644, false},
+		{"setuid rejected", DefaultPerms, 0o755 | os.ModeSetuid, 0, true},
+		{"setgid rejected", DefaultPerms, 0o755 | os.ModeSetgid, 0, true},
+		{"umask 027", Perms{Umask: 0o027}, 0o775, 0o750, false},
+		{"umask 0 still drops world write", Perms{}, 0o777, 0o775, false},
+		{"faithful", Perms{Faithful: true}, 0o777 | os.ModeSetuid, 0o777 | os.ModeSetuid, false},
+	}
+	defer SetPerms(DefaultPerms)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetPerms(tt.policy)
+			got, err := fileMode("f", tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fileMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrUnsafeMode) {
+				t.Errorf("fileMode() error = %v, want ErrUnsafeMode", err)
+			}
+			if got != tt.want {
+				t.Errorf("fileMode(%v) = %v, want %v", tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtract_Perms(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions")
+	}
+	src := filepath.Join(t.TempDir(), "modes.tar.gz")
+	writeModeTar(t, src, map[string]int64{
+		"pkg/bin/tool":     0o777,
+		"pkg/lib/libx.so":  0o664,
+		"pkg/include/x.h":  0o400,
+		"pkg/share/doc.md": 0o644,
+	})
+	defer SetPerms(DefaultPerms)
+
+	t.Run("default", func(t *testing.T) {
+		SetPerms(DefaultPerms)
+		dst := t.TempDir()
+		if err := Extract(src, dst); err != nil {
+			t.Fatal(err)
+		}
+		assertMode(t, filepath.Join(dst, "bin", "tool"), 0o755)
+		assertMode(t, filepath.Join(dst, "lib", "libx.so"), 0o644)
+		assertMode(t, filepath.Join(dst, "include", "x.h"), 0o600)
+	})
+
+	t.Run("faithful", func(t *testing.T) {
+		SetPerms(Perms{Faithful: true})
+		dst := t.TempDir()
+		if err := Extract(src, dst); err != nil {
+			t.Fatal(err)
+		}
+		assertMode(t, filepath.Join(dst, "bin", "tool"), 0o777)
+		assertMode(t, filepath.Join(dst, "lib", "libx.so"), 0o664)
+	})
+
+	t.Run("setuid rejected", func(t *testing.T) {
+		SetPerms(DefaultPerms)
+		suid := filepath.Join(t.TempDir(), "suid.tar.gz")
+		writeModeTar(t, suid, map[string]int64{"pkg/bin/su": 0o4755, "pkg/a": 0o644})
+		if err := Extract(suid, t.TempDir()); !errors.Is(err, ErrUnsafeMode) {
+			t.Errorf("Extract() error = %v, want ErrUnsafeMode", err)
+		}
+	})
+}
+
+func TestIsExecutable(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want bool
+	}{
+		{"elf", "\x7fELF\x02\x01\x01\x00", true},
+		{"mach-o 64", "\xcf\xfa\xed\xfe\x07\x00\x00\x01", true},
+		{"mach-o fat", "\xca\xfe\xba\xbe\x00\x00\x00\x02", true},
+		{"java class", "\xca\xfe\xba\xbe\x00\x00\x00\x34", false},
+		{"shebang", "#!/bin/sh", true},
+		{"text", "hello, w", false},
+		{"pe", "MZ\x90\x00\x03\x00\x00\x00", false},
+		{"short", "\xcf\xfa", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isExecutable([]byte(tt.head)); got != tt.want {
+				t.Errorf("isExecutable(%q) = %v, want %v", tt.head, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtract_ZipExecBits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no unix modes on windows")
+	}
+	src := filepath.Join(t.TempDir(), "tools.zip")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, data := range map[string]string{
+		"tools/bin/tool":   "\x7fELF\x02\x01\x01\x00rest",
+		"tools/bin/run.sh": "#!/bin/sh\necho hi\n",
+		"tools/README":     "readme",
+	} {
+		// A zero creator byte means MS-DOS: no unix modes, as from Windows.
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An explicit unix mode is respected even for a binary.
+	hdr := &zip.FileHeader{Name: "tools/bin/data.elf", Method: zip.Deflate}
+	hdr.SetMode(0o644)
+	w, err := zw.CreateHeader(hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("\x7fELF\x02\x01\x01\x00")); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	t.Run("fixed", func(t *testing.T) {
+		dst := t.TempDir()
+		if err := Extract(src, dst); err != nil {
+			t.Fatal(err)
+		}
+		assertMode(t, filepath.Join(dst, "bin", "tool"), 0o755)
+		assertMode(t, filepath.Join(dst, "bin", "run.sh"), 0o755)
+		assertMode(t, filepath.Join(dst, "README"), 0o644)
+		assertMode(t, filepath.Join(dst, "bin", "data.elf"), 0o644)
+	})
+	t.Run("keep modes", func(t *testing.T) {
+		dst := t.TempDir()
+		if err := extract(src, dst, false); err != nil {
+			t.Fatal(err)
+		}
+		assertMode(t, filepath.Join(dst, "bin", "tool"), 0o644)
+		assertMode(t, filepath.Join(dst, "bin", "run.sh"), 0o644)
+	})
+}
+
+// writeModeTar writes a tar.gz of empty-content files with the given modes.
+func writeModeTar(t *testing.T, path string, files map[string]int64) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, mode := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: mode, Size: 1, Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gz.Close()
+}
+
+func assertMode(t *testing.T, path string, want os.FileMode) {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid); got != want {
+		t.Errorf("%s mode = %v, want %v", filepath.Base(path), got, want)
+	}
+}
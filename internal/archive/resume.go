@@ -0,0 +1,141 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/qntx/gox/internal/cache"
+	"github.com/qntx/gox/internal/workspace"
+)
+
+// partial is an archive being downloaded. Claimed partials live in the cache
+// and outlive failed or interrupted runs, so the next attempt resumes them
+// with a Range request.
+type partial struct {
+	path      string // archive bytes received so far
+	meta      string // file holding validator; empty for private downloads
+	lock      string // file holding the PID of the downloading process
+	validator string // ETag or Last-Modified the bytes in path belong to
+	size      int64  // resumable bytes in path
+}
+
+// PartialDir returns the directory holding interrupted downloads.
+func PartialDir() string {
+	return cache.Dir("partial")
+}
+
+// claimPartial returns the partial download of url. If another live process
+// is already downloading url, it returns a private partial in the run
+// workspace that is not resumable.
+func claimPartial(url string) (*partial, error) {
+	if err := os.MkdirAll(PartialDir(), perm); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(url))
+	base := filepath.Join(PartialDir(), hex.EncodeToString(sum[:8]))
+	p := &partial{path: base + Detect(url).Ext(), meta: base + ".etag", lock: base + ".lock"}
+
+	if !p.acquire() {
+		tmp, err := workspace.MkdirTemp("download-*")
+		if err != nil {
+			return nil, err
+		}
+		return &partial{path: filepath.Join(tmp, "archive"+Detect(url).Ext())}, nil
+	}
+
+	if data, err := os.ReadFile(p.meta); err == nil {
+		p.validator = strings.TrimSpace(string(data))
+	}
+	p.refresh()
+	return p, nil
+}
+
+// acquire takes the lock of p, breaking locks left by exited processes.
+func (p *partial) acquire() bool {
+	for range 2 {
+		f, err := os.OpenFile(p.lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			fmt.Fprint(f, os.Getpid())
+			f.Close()
+			return true
+		}
+		data, _ := os.ReadFile(p.lock)
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && workspace.Alive(pid) {
+			return false
+		}
+		os.Remove(p.lock)
+	}
+	return false
+}
+
+// refresh re-reads how many resumable bytes are on disk, e.g. after a
+// failed attempt.
+func (p *partial) refresh() {
+	p.size = 0
+	if p.validator == "" {
+		return
+	}
+	if info, err := os.Stat(p.path); err == nil {
+		p.size = info.Size()
+	}
+}
+
+// setValidator records the validator of the bytes being written to p.
+// Without one the download cannot be resumed safely.
+func (p *partial) setValidator(v string) {
+	p.validator = v
+	if p.meta == "" {
+		return
+	}
+	if v == "" {
+		os.Remove(p.meta)
+		return
+	}
+	_ = os.WriteFile(p.meta, []byte(v+"\n"), 0o644)
+}
+
+// release unlocks p, keeping its bytes for a later resume.
+func (p *partial) release() {
+	if p.lock != "" {
+		os.Remove(p.lock)
+	}
+}
+
+// discard deletes the bytes of p, including any chunks.
+func (p *partial) discard() {
+	os.Remove(p.path)
+	removeChunks(p.path)
+	if p.meta != "" {
+		os.Remove(p.meta)
+	}
+	p.size, p.validator = 0, ""
+}
+
+// validator returns the strong ETag of a response, or its Last-Modified.
+// Weak ETags cannot be used with If-Range.
+func validator(h interface{ Get(string) string }) string {
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return h.Get("Last-Modified")
+}
+
+// rangeStart parses the first byte position of a Content-Range header
+// ("bytes 100-199/200"), or -1.
+func rangeStart(s string) int64 {
+	s, ok := strings.CutPrefix(s, "bytes ")
+	if !ok {
+		return -1
+	}
+	start, _, _ := strings.Cut(s, "-")
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
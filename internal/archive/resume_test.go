@@ -0,0 +1,191 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// seedPartial stores the first n bytes of data as an interrupted download
+// of url with the given validator.
+func seedPartial(t *testing.T, url string, data []byte, n int, etag string) *partial {
+	t.Helper()
+	p, err := claimPartial(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.release()
+	if err := os.WriteFile(p.path, data[:n], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p.setValidator(etag)
+	return p
+}
+
+func TestDownloadWith_Resume(t *testing.T) {
+	tarPath := filepath.Join(t.TempDir(), "test.tar.gz")
+	createTestTarGz(t, tarPath, map[string]string{"root/file.txt": "content"})
+	data, err := os.ReadFile(tarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := FileSHA256(tarPath)
+
+	var etag atomic.Value
+	etag.Store(`"v1"`)
+	var gotRange atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRange.Store(r.Header.Get("Range"))
+		w.Header().Set("ETag", etag.Load().(string))
+		http.ServeContent(w, r, "test.tar.gz", time.Time{}, bytes.NewReader(data))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		seedETag   string
+		serverETag string
+		wantResume bool
+	}{
+		{"resumes matching file", `"v1"`, `"v1"`, true},
+		{"restarts changed file", `"v1"`, `"v2"`, false},
+		{"restarts without validator", "", `"v1"`, false},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := srv.URL + "/" + string(rune('a'+i)) + "/test.tar.gz"
+			half := len(data) / 2
+			p := seedPartial(t, url, data, half, tt.seedETag)
+			etag.Store(tt.serverETag)
+
+			var resumed int64
+			dst := filepath.Join(t.TempDir(), "out")
+			res, err := DownloadWith(context.Background(), url, dst, DownloadOptions{
+				Resumed: func(n int64) { resumed = n },
+			})
+			if err != nil {
+				t.Fatalf("DownloadWith() error = %v", err)
+			}
+			if res.SHA256 != want || res.Size != int64(len(data)) {
+				t.Errorf("DownloadWith() = %+v, want sha %s size %d", res, want, len(data))
+			}
+			if tt.wantResume && resumed != int64(half) {
+				t.Errorf("Resumed(%d), want %d", resumed, half)
+			}
+			if !tt.wantResume && resumed != 0 {
+				t.Errorf("Resumed(%d), want no resume", resumed)
+			}
+			if r := gotRange.Load().(string); tt.wantResume && r == "" {
+				t.Error("request had no Range header")
+			}
+			assertFileContent(t, filepath.Join(dst, "file.txt"), "content")
+			if _, err := os.Stat(p.path); !os.IsNotExist(err) {
+				t.Error("partial kept after a complete download")
+			}
+		})
+	}
+}
+
+func TestDownloadWith_KeepsPartialOnError(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 64<<10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Length", "131072")
+		_, _ = w.Write(data) // then drop the connection short of Content-Length
+	}))
+	defer srv.Close()
+
+	url := srv.URL + "/big.tar.gz"
+	_, err := DownloadWith(context.Background(), url, filepath.Join(t.TempDir(), "out"), DownloadOptions{})
+	if err == nil {
+		t.Fatal("DownloadWith() error = nil, want truncated body error")
+	}
+
+	p, err := claimPartial(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.release()
+	defer p.discard()
+	if p.size != int64(len(data)) || p.validator != `"v1"` {
+		t.Errorf("partial = %d bytes, validator %q; want %d, \"v1\"", p.size, p.validator, len(data))
+	}
+}
+
+func TestClaimPartial_Busy(t *testing.T) {
+	url := "https://example.com/busy.tar.gz"
+	p, err := claimPartial(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.release()
+
+	other, err := claimPartial(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.lock != "" || other.meta != "" {
+		t.Errorf("claimPartial() while held = %+v, want private workspace partial", other)
+	}
+}
+
+func TestRangeStart(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"bytes 100-199/200", 100},
+		{"bytes 0-9/*", 0},
+		{"", -1},
+		{"items 1-2/3", -1},
+	}
+	for _, tt := range tests {
+		if got := rangeStart(tt.in); got != tt.want {
+			t.Errorf("rangeStart(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadWith_RetriesAndResumes(t *testing.T) {
+	tarPath := filepath.Join(t.TempDir(), "test.tar.gz")
+	createTestTarGz(t, tarPath, map[string]string{"root/file.txt": "content"})
+	data, _ := os.ReadFile(tarPath)
+	want, _ := FileSHA256(tarPath)
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Method != http.MethodGet {
+			return
+		}
+		if calls.Add(1) == 1 {
+			// Send half the archive, then drop the connection.
+			w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+			_, _ = w.Write(data[:len(data)/2])
+			return
+		}
+		http.ServeContent(w, r, "test.tar.gz", time.Time{}, bytes.NewReader(data))
+	}))
+	defer srv.Close()
+
+	var resumed int64
+	res, err := DownloadWith(context.Background(), srv.URL+"/retry/test.tar.gz", filepath.Join(t.TempDir(), "out"), DownloadOptions{
+		Resumed: func(n int64) { resumed = n },
+	})
+	if err != nil {
+		t.Fatalf("DownloadWith() error = %v", err)
+	}
+	if res.SHA256 != want {
+		t.Errorf("SHA256 = %s, want %s", res.SHA256, want)
+	}
+	if calls.Load() != 2 || resumed != int64(len(data)/2) {
+		t.Errorf("calls = %d, resumed = %d; want 2 calls resuming at %d", calls.Load(), resumed, len(data)/2)
+	}
+}
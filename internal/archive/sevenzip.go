@@ -0,0 +1,966 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/ulikunitz/xz/lzma"
+)
+
+// 7z reading covers what packages are published with: the Copy, LZMA,
+// LZMA2, Deflate and BZip2 methods, the x86 BCJ filter 7-Zip applies to
+// executables, and compressed headers. Encrypted archives and BCJ2, the
+// four-stream filter of 7-Zip's ultra preset, are rejected.
+
+var sevenZipMagic = []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}
+
+// 7z property IDs.
+const (
+	szEnd = iota
+	szHeader
+	szArchiveProperties
+	szAdditionalStreamsInfo
+	szMainStreamsInfo
+	szFilesInfo
+	szPackInfo
+	szUnpackInfo
+	szSubStreamsInfo
+	szSize
+	szCRC
+	szFolders
+	szCodersUnpackSize
+	szNumUnpackStream
+	szEmptyStream
+	szEmptyFile
+	szAnti
+	szName
+	szCTime
+	szATime
+	szMTime
+	szWinAttributes
+	szComment
+	szEncodedHeader
+)
+
+// Windows file attributes; 7-Zip and p7zip store a unix mode in the high
+// 16 bits when szUnixExtension is set.
+const (
+	szAttrReadOnly  = 0x01
+	szAttrDirectory = 0x10
+	szUnixExtension = 0x8000
+)
+
+// szCoder is one decoding step of a folder, with a single input and output.
+type szCoder struct {
+	method string // hex method ID
+	props  []byte
+}
+
+// szFolder is a chain of coders decoding packed streams into one unpacked
+// stream, which holds the contents of one or more files back to back.
+type szFolder struct {
+	coders    []szCoder
+	bindPairs [][2]int // {input, output}: the output of one coder feeding another
+	packed    []int    // coder inputs read from packed streams, in order
+	sizes     []uint64 // unpacked size of each coder's output
+	crc       uint32
+	hasCRC    bool
+	firstPack int // index of the folder's first packed stream
+
+	// Files in the folder: their sizes and, when known, CRCs.
+	subSizes  []uint64
+	subCRCs   []uint32
+	subHasCRC []bool
+}
+
+// size returns the size of the folder's final output.
+func (f *szFolder) size() uint64 {
+	if i := f.mainCoder(); i >= 0 {
+		return f.sizes[i]
+	}
+	return 0
+}
+
+// mainCoder returns the coder whose output no other coder reads.
+func (f *szFolder) mainCoder() int {
+	for i := range f.coders {
+		bound := false
+		for _, bp := range f.bindPairs {
+			bound = bound || bp[1] == i
+		}
+		if !bound {
+			return i
+		}
+	}
+	return -1
+}
+
+type szStreams struct {
+	packPos   uint64
+	packSizes []uint64
+	folders   []*szFolder
+}
+
+type szFile struct {
+	name      string
+	hasStream bool
+	dir       bool
+	anti      bool
+	attrib    uint32
+	hasAttrib bool
+	size      uint64
+	crc       uint32
+	hasCRC    bool
+}
+
+// mode returns the file's mode from its unix mode, or else its Windows
+// attributes.
+func (f *szFile) mode() os.FileMode {
+	if f.hasAttrib && f.attrib&szUnixExtension != 0 {
+		u := f.attrib >> 16
+		m := os.FileMode(u & 0o777)
+		if u&0o4000 != 0 {
+			m |= os.ModeSetuid
+		}
+		if u&0o2000 != 0 {
+			m |= os.ModeSetgid
+		}
+		if u&0o1000 != 0 {
+			m |= os.ModeSticky
+		}
+		switch u & 0o170000 {
+		case 0o120000:
+			m |= os.ModeSymlink
+		case 0o040000:
+			m |= os.ModeDir
+		}
+		return m
+	}
+	if f.hasAttrib && f.attrib&szAttrReadOnly != 0 {
+		return 0o444
+	}
+	return 0o666
+}
+
+// path returns the name of f below the top-level directory strip, "" for
+// that directory itself.
+func (f *szFile) path(strip string) string {
+	if f.name+"/" == strip {
+		return ""
+	}
+	return strings.TrimPrefix(f.name, strip)
+}
+
+// hasUnixMode reports whether the file records its unix mode.
+func (f *szFile) hasUnixMode() bool {
+	return f.hasAttrib && f.attrib&szUnixExtension != 0
+}
+
+// sevenZip is an open 7z archive.
+type sevenZip struct {
+	r       io.ReaderAt
+	streams szStreams
+	files   []*szFile
+}
+
+func openSevenZip(r io.ReaderAt, size int64) (*sevenZip, error) {
+	var sig [32]byte
+	if _, err := r.ReadAt(sig[:], 0); err != nil {
+		return nil, fmt.Errorf("7z: %w", err)
+	}
+	if !bytes.Equal(sig[:6], sevenZipMagic) {
+		return nil, errors.New("7z: not a 7z archive")
+	}
+	if crc32.ChecksumIEEE(sig[12:32]) != binary.LittleEndian.Uint32(sig[8:]) {
+		return nil, errors.New("7z: corrupt start header")
+	}
+	off, n := binary.LittleEndian.Uint64(sig[12:]), binary.LittleEndian.Uint64(sig[20:])
+	if off > uint64(size) || n > uint64(size)-off || 32+off+n > uint64(size) {
+		return nil, errors.New("7z: truncated archive")
+	}
+	header := make([]byte, n)
+	if _, err := r.ReadAt(header, int64(32+off)); err != nil {
+		return nil, fmt.Errorf("7z: %w", err)
+	}
+	if crc32.ChecksumIEEE(header) != binary.LittleEndian.Uint32(sig[28:]) {
+		return nil, errors.New("7z: corrupt header")
+	}
+
+	a := &sevenZip{r: r}
+	// Archives with no files end after the start header.
+	for len(header) > 0 {
+		h := &szReader{b: header}
+		switch id := h.byte(); id {
+		case szHeader:
+			if err := a.readHeader(h); err != nil {
+				return nil, err
+			}
+			return a, nil
+		case szEncodedHeader:
+			var s szStreams
+			h.streamsInfo(&s)
+			if h.err != nil {
+				return nil, fmt.Errorf("7z: header: %w", h.err)
+			}
+			if len(s.folders) == 0 {
+				return nil, errors.New("7z: empty encoded header")
+			}
+			a.streams = s
+			rd, err := a.folderReader(s.folders[0])
+			if err != nil {
+				return nil, err
+			}
+			if header, err = io.ReadAll(io.LimitReader(rd, int64(s.folders[0].size()))); err != nil {
+				return nil, fmt.Errorf("7z: header: %w", err)
+			}
+			if f := s.folders[0]; f.hasCRC && crc32.ChecksumIEEE(header) != f.crc {
+				return nil, errors.New("7z: corrupt header")
+			}
+		default:
+			return nil, fmt.Errorf("7z: unexpected header type %#x", id)
+		}
+	}
+	return a, nil
+}
+
+func (a *sevenZip) readHeader(h *szReader) error {
+	a.streams = szStreams{}
+	for h.err == nil {
+		switch id := h.number(); id {
+		case szEnd:
+			return a.assign()
+		case szArchiveProperties:
+			for h.err == nil && h.number() != szEnd {
+				h.skip(h.number())
+			}
+		case szAdditionalStreamsInfo:
+			return errors.New("7z: additional streams are not supported")
+		case szMainStreamsInfo:
+			h.streamsInfo(&a.streams)
+		case szFilesInfo:
+			a.files = h.filesInfo()
+		default:
+			return fmt.Errorf("7z: unexpected property %#x in header", id)
+		}
+	}
+	return fmt.Errorf("7z: header: %w", h.err)
+}
+
+// assign gives each file with contents its stream: the next file of the
+// next folder holding any.
+func (a *sevenZip) assign() error {
+	folder, sub := 0, 0
+	for _, f := range a.files {
+		if !f.hasStream {
+			continue
+		}
+		for folder < len(a.streams.folders) && sub == len(a.streams.folders[folder].subSizes) {
+			folder, sub = folder+1, 0
+		}
+		if folder == len(a.streams.folders) {
+			return errors.New("7z: more files than streams")
+		}
+		fo := a.streams.folders[folder]
+		f.size, f.crc, f.hasCRC = fo.subSizes[sub], fo.subCRCs[sub], fo.subHasCRC[sub]
+		sub++
+	}
+	return nil
+}
+
+// folderReader returns the decoded contents of f.
+func (a *sevenZip) folderReader(f *szFolder) (io.Reader, error) {
+	main := f.mainCoder()
+	if main < 0 {
+		return nil, errors.New("7z: folder has no output")
+	}
+	return a.coderReader(f, main, 0)
+}
+
+// coderReader returns the output of coder i of f, reading its input from
+// the coder bound to it or from a packed stream.
+func (a *sevenZip) coderReader(f *szFolder, i, depth int) (io.Reader, error) {
+	if depth > len(f.coders) {
+		return nil, errors.New("7z: coder cycle")
+	}
+	var in io.Reader
+	for _, bp := range f.bindPairs {
+		if bp[0] == i {
+			r, err := a.coderReader(f, bp[1], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			in = r
+		}
+	}
+	if in == nil {
+		k := -1
+		for j, p := range f.packed {
+			if p == i {
+				k = j
+			}
+		}
+		if k < 0 || f.firstPack+k >= len(a.streams.packSizes) {
+			return nil, errors.New("7z: coder has no input")
+		}
+		off := 32 + a.streams.packPos
+		for _, s := range a.streams.packSizes[:f.firstPack+k] {
+			off += s
+		}
+		in = bufio.NewReader(io.NewSectionReader(a.r, int64(off), int64(a.streams.packSizes[f.firstPack+k])))
+	}
+	return szDecoder(f.coders[i], in, f.sizes[i])
+}
+
+// szDecoder returns the output of coder c reading in, size bytes long.
+func szDecoder(c szCoder, in io.Reader, size uint64) (io.Reader, error) {
+	switch c.method {
+	case "00": // Copy
+		return io.LimitReader(in, int64(size)), nil
+	case "030101": // LZMA: 5 property bytes, the header of an .lzma file without its size
+		if len(c.props) != 5 {
+			return nil, errors.New("7z: invalid LZMA properties")
+		}
+		hdr := append(append([]byte{}, c.props...), binary.LittleEndian.AppendUint64(nil, size)...)
+		r, err := lzma.NewReader(io.MultiReader(bytes.NewReader(hdr), in))
+		if err != nil {
+			return nil, fmt.Errorf("7z: %w", err)
+		}
+		return r, nil
+	case "21": // LZMA2: the dictionary size
+		if len(c.props) != 1 || c.props[0] > 40 {
+			return nil, errors.New("7z: invalid LZMA2 properties")
+		}
+		dict := uint64(1<<32 - 1)
+		if p := c.props[0]; p < 40 {
+			dict = uint64(2|p&1) << (p/2 + 11)
+		}
+		// No need for a dictionary larger than the output.
+		dict = max(min(dict, size), lzma.MinDictCap)
+		r, err := lzma.Reader2Config{DictCap: int(min(dict, lzma.MaxDictCap))}.NewReader2(in)
+		if err != nil {
+			return nil, fmt.Errorf("7z: %w", err)
+		}
+		return r, nil
+	case "03030103": // BCJ x86
+		return &bcjReader{r: in, buf: make([]byte, 1<<16)}, nil
+	case "040108": // Deflate
+		return flate.NewReader(in), nil
+	case "040202": // BZip2
+		return bzip2.NewReader(in), nil
+	case "06f10701":
+		return nil, errors.New("7z: encrypted archives are not supported")
+	}
+	return nil, fmt.Errorf("7z: unsupported method %s", c.method)
+}
+
+// szReader decodes 7z header structures, keeping the first error.
+type szReader struct {
+	b   []byte
+	err error
+}
+
+func (r *szReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *szReader) bytes(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > uint64(len(r.b)) {
+		r.fail(io.ErrUnexpectedEOF)
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *szReader) skip(n uint64) { r.bytes(n) }
+
+func (r *szReader) byte() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *szReader) uint32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+// number decodes a 7z NUMBER: the leading one bits of the first byte count
+// the bytes that follow, little-endian, below the first byte's other bits.
+func (r *szReader) number() uint64 {
+	first := r.byte()
+	var v uint64
+	mask := byte(0x80)
+	for i := range 8 {
+		if first&mask == 0 {
+			return v | uint64(first&(mask-1))<<(8*i)
+		}
+		v |= uint64(r.byte()) << (8 * i)
+		mask >>= 1
+	}
+	return v
+}
+
+// count reads a number of items, each taking at least one byte of what is
+// left, so corrupt counts fail instead of allocating.
+func (r *szReader) count() int {
+	n := r.number()
+	if n > uint64(len(r.b))*8 {
+		r.fail(errors.New("count out of range"))
+		return 0
+	}
+	return int(n)
+}
+
+// bits reads a bit vector of n items, most significant bit first.
+func (r *szReader) bits(n int) []bool {
+	v := make([]bool, n)
+	b := r.bytes(uint64(n+7) / 8)
+	for i := range v {
+		if b != nil {
+			v[i] = b[i/8]&(0x80>>(i%8)) != 0
+		}
+	}
+	return v
+}
+
+// defined reads a bit vector preceded by an all-defined byte.
+func (r *szReader) defined(n int) []bool {
+	if r.byte() == 0 {
+		return r.bits(n)
+	}
+	v := make([]bool, n)
+	for i := range v {
+		v[i] = true
+	}
+	return v
+}
+
+func (r *szReader) digests(n int) ([]bool, []uint32) {
+	defined := r.defined(n)
+	crcs := make([]uint32, n)
+	for i, ok := range defined {
+		if ok {
+			crcs[i] = r.uint32()
+		}
+	}
+	return defined, crcs
+}
+
+func (r *szReader) streamsInfo(s *szStreams) {
+	for r.err == nil {
+		switch id := r.number(); id {
+		case szEnd:
+			// Without substreams info each folder holds one file.
+			for _, f := range s.folders {
+				if f.subSizes == nil {
+					f.subSizes, f.subCRCs, f.subHasCRC = []uint64{f.size()}, []uint32{f.crc}, []bool{f.hasCRC}
+				}
+			}
+			return
+		case szPackInfo:
+			r.packInfo(s)
+		case szUnpackInfo:
+			r.unpackInfo(s)
+		case szSubStreamsInfo:
+			r.subStreamsInfo(s.folders)
+		default:
+			r.fail(fmt.Errorf("unexpected property %#x in streams info", id))
+		}
+	}
+}
+
+func (r *szReader) packInfo(s *szStreams) {
+	s.packPos = r.number()
+	s.packSizes = make([]uint64, r.count())
+	for r.err == nil {
+		switch id := r.number(); id {
+		case szEnd:
+			return
+		case szSize:
+			for i := range s.packSizes {
+				s.packSizes[i] = r.number()
+			}
+		case szCRC:
+			r.digests(len(s.packSizes))
+		default:
+			r.fail(fmt.Errorf("unexpected property %#x in pack info", id))
+		}
+	}
+}
+
+func (r *szReader) unpackInfo(s *szStreams) {
+	if id := r.number(); id != szFolders {
+		r.fail(fmt.Errorf("unexpected property %#x in unpack info", id))
+		return
+	}
+	s.folders = make([]*szFolder, r.count())
+	if r.byte() != 0 {
+		r.fail(errors.New("external folders are not supported"))
+		return
+	}
+	pack := 0
+	for i := range s.folders {
+		f := r.folder()
+		if f == nil {
+			return
+		}
+		f.firstPack = pack
+		pack += len(f.packed)
+		s.folders[i] = f
+	}
+	if id := r.number(); id != szCodersUnpackSize {
+		r.fail(fmt.Errorf("unexpected property %#x in unpack info", id))
+		return
+	}
+	for _, f := range s.folders {
+		for i := range f.sizes {
+			f.sizes[i] = r.number()
+		}
+	}
+	for r.err == nil {
+		switch id := r.number(); id {
+		case szEnd:
+			return
+		case szCRC:
+			defined, crcs := r.digests(len(s.folders))
+			for i, f := range s.folders {
+				f.hasCRC, f.crc = defined[i], crcs[i]
+			}
+		default:
+			r.fail(fmt.Errorf("unexpected property %#x in unpack info", id))
+		}
+	}
+}
+
+func (r *szReader) folder() *szFolder {
+	f := &szFolder{coders: make([]szCoder, r.count())}
+	if len(f.coders) == 0 && r.err == nil {
+		r.fail(errors.New("folder without coders"))
+	}
+	for i := range f.coders {
+		flags := r.byte()
+		f.coders[i].method = hex.EncodeToString(r.bytes(uint64(flags & 0x0f)))
+		if flags&0x10 != 0 {
+			// Coders with several streams: BCJ2 in practice.
+			if in, out := r.number(), r.number(); in != 1 || out != 1 {
+				r.fail(fmt.Errorf("method %s with %d inputs is not supported (BCJ2 is 0303011b)", f.coders[i].method, in))
+			}
+		}
+		if flags&0x20 != 0 {
+			f.coders[i].props = r.bytes(r.number())
+		}
+	}
+	if r.err != nil {
+		return nil
+	}
+	f.sizes = make([]uint64, len(f.coders))
+	f.bindPairs = make([][2]int, len(f.coders)-1)
+	for i := range f.bindPairs {
+		in, out := r.number(), r.number()
+		if in >= uint64(len(f.coders)) || out >= uint64(len(f.coders)) {
+			r.fail(errors.New("bind pair out of range"))
+			return nil
+		}
+		f.bindPairs[i] = [2]int{int(in), int(out)}
+	}
+	if n := len(f.coders) - len(f.bindPairs); n == 1 {
+		for i := range f.coders {
+			bound := false
+			for _, bp := range f.bindPairs {
+				bound = bound || bp[0] == i
+			}
+			if !bound {
+				f.packed = append(f.packed, i)
+			}
+		}
+	} else {
+		for range n {
+			f.packed = append(f.packed, int(r.number()))
+		}
+	}
+	return f
+}
+
+func (r *szReader) subStreamsInfo(folders []*szFolder) {
+	for _, f := range folders {
+		f.subSizes = []uint64{f.size()}
+	}
+	id := r.number()
+	if id == szNumUnpackStream {
+		for _, f := range folders {
+			n := r.count()
+			f.subSizes = make([]uint64, n)
+			if n > 0 {
+				f.subSizes[n-1] = f.size()
+			}
+		}
+		id = r.number()
+	}
+	if id == szSize {
+		for _, f := range folders {
+			if len(f.subSizes) == 0 {
+				continue
+			}
+			var sum uint64
+			for i := range len(f.subSizes) - 1 {
+				f.subSizes[i] = r.number()
+				sum += f.subSizes[i]
+			}
+			if sum > f.size() {
+				r.fail(errors.New("file sizes exceed their folder"))
+				return
+			}
+			f.subSizes[len(f.subSizes)-1] = f.size() - sum
+		}
+		id = r.number()
+	}
+
+	// CRCs are listed for the files whose folder CRC does not already cover
+	// them alone.
+	unknown := 0
+	for _, f := range folders {
+		f.subCRCs = make([]uint32, len(f.subSizes))
+		f.subHasCRC = make([]bool, len(f.subSizes))
+		if len(f.subSizes) == 1 && f.hasCRC {
+			f.subCRCs[0], f.subHasCRC[0] = f.crc, true
+		} else {
+			unknown += len(f.subSizes)
+		}
+	}
+	for r.err == nil {
+		switch id {
+		case szEnd:
+			return
+		case szCRC:
+			defined, crcs := r.digests(unknown)
+			k := 0
+			for _, f := range folders {
+				if len(f.subSizes) == 1 && f.hasCRC {
+					continue
+				}
+				for i := range f.subSizes {
+					f.subHasCRC[i], f.subCRCs[i] = defined[k], crcs[k]
+					k++
+				}
+			}
+		default:
+			r.fail(fmt.Errorf("unexpected property %#x in substreams info", id))
+		}
+		id = r.number()
+	}
+}
+
+func (r *szReader) filesInfo() []*szFile {
+	files := make([]*szFile, r.count())
+	for i := range files {
+		files[i] = &szFile{hasStream: true}
+	}
+	var emptyFile, anti []bool
+	empty := 0 // files without a stream, which emptyFile and anti number
+	for r.err == nil {
+		id := r.number()
+		if id == szEnd {
+			break
+		}
+		p := &szReader{b: r.bytes(r.number())}
+		switch id {
+		case szEmptyStream:
+			empty = 0
+			for i, e := range p.bits(len(files)) {
+				files[i].hasStream = !e
+				if e {
+					empty++
+				}
+			}
+		case szEmptyFile:
+			emptyFile = p.bits(empty)
+		case szAnti:
+			anti = p.bits(empty)
+		case szName:
+			if p.byte() != 0 {
+				p.fail(errors.New("external names are not supported"))
+			}
+			for _, f := range files {
+				var u []uint16
+				for p.err == nil {
+					c := uint16(p.byte()) | uint16(p.byte())<<8
+					if c == 0 {
+						break
+					}
+					u = append(u, c)
+				}
+				f.name = string(utf16.Decode(u))
+			}
+		case szWinAttributes:
+			defined := p.defined(len(files))
+			if p.byte() != 0 {
+				p.fail(errors.New("external attributes are not supported"))
+			}
+			for i, ok := range defined {
+				if ok {
+					files[i].attrib, files[i].hasAttrib = p.uint32(), true
+				}
+			}
+		}
+		r.fail(p.err)
+	}
+
+	k := 0
+	for _, f := range files {
+		if f.hasStream {
+			continue
+		}
+		f.dir = k >= len(emptyFile) || !emptyFile[k]
+		f.anti = k < len(anti) && anti[k]
+		k++
+	}
+	for _, f := range files {
+		f.dir = f.dir || f.hasAttrib && f.attrib&szAttrDirectory != 0
+	}
+	return files
+}
+
+// bcjReader reverses the x86 BCJ filter, which turns the relative targets
+// of CALL and JMP instructions into absolute ones for better compression.
+type bcjReader struct {
+	r   io.Reader
+	buf []byte // buf[start:conv] is decoded, buf[conv:end] awaits more input
+	err error
+
+	start, conv, end int
+	pos              uint32 // stream offset of buf[conv]
+	state            uint32 // recent E8/E9 bytes carried across buffers
+}
+
+func (b *bcjReader) Read(p []byte) (int, error) {
+	for b.start == b.conv {
+		if b.err != nil {
+			if b.err != io.EOF || b.conv == b.end {
+				return 0, b.err
+			}
+			// The last few bytes are too short for an instruction.
+			b.conv = b.end
+			break
+		}
+		b.end = copy(b.buf, b.buf[b.conv:b.end])
+		b.start, b.conv = 0, 0
+		n, err := b.r.Read(b.buf[b.end:])
+		b.end += n
+		b.err = err
+		k := bcjX86(b.buf[:b.end], b.pos, &b.state)
+		b.conv += k
+		b.pos += uint32(k)
+	}
+	n := copy(p, b.buf[b.start:b.conv])
+	b.start += n
+	return n, nil
+}
+
+// bcjX86 decodes the x86 BCJ filter in buf, which starts at stream offset
+// pos, and returns how many bytes it finished; the rest need the bytes that
+// follow. It is the decoder of the LZMA SDK and xz.
+func bcjX86(buf []byte, pos uint32, state *uint32) int {
+	if len(buf) <= 4 {
+		return 0
+	}
+	allowed := [8]bool{true, true, true, false, true, false, false, false}
+	bitNum := [8]uint32{0, 1, 2, 2, 3, 3, 3, 3}
+	msByte := func(b byte) bool { return b == 0 || b == 0xff }
+
+	mask, prev := *state, -1
+	i := 0
+	for ; i < len(buf)-4; i++ {
+		if buf[i]&0xfe != 0xe8 {
+			continue
+		}
+		if d := i - prev; d > 3 {
+			mask = 0
+		} else {
+			mask = mask << (d - 1) & 7
+			if mask != 0 && (!allowed[mask] || msByte(buf[i+4-int(bitNum[mask])])) {
+				prev = i
+				mask = mask<<1 | 1
+				continue
+			}
+		}
+		prev = i
+		if !msByte(buf[i+4]) {
+			mask = mask<<1 | 1
+			continue
+		}
+		src := binary.LittleEndian.Uint32(buf[i+1:])
+		var dst uint32
+		for {
+			dst = src - (pos + uint32(i) + 5)
+			if mask == 0 {
+				break
+			}
+			j := bitNum[mask] * 8
+			if !msByte(byte(dst >> (24 - j))) {
+				break
+			}
+			src = dst ^ (1<<(32-j) - 1)
+		}
+		dst &= 0x01ffffff
+		dst |= -(dst & 0x01000000)
+		binary.LittleEndian.PutUint32(buf[i+1:], dst)
+		i += 4
+	}
+	if d := i - prev; d > 3 {
+		*state = 0
+	} else {
+		*state = mask << (d - 1)
+	}
+	return i
+}
+
+// un7z extracts the 7z archive src to dst, stripping a top-level directory
+// shared by every entry as unzip does.
+func un7z(src, dst string, fixExec bool) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	a, err := openSevenZip(f, info.Size())
+	if err != nil {
+		return err
+	}
+
+	strip := sevenZipPrefix(a.files)
+	names := newCaseNames(dst)
+	for _, e := range a.files {
+		if name := e.path(strip); name != "" && !e.dir && !e.anti {
+			if err := names.add(name); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Files with contents are read in order, each folder as one stream.
+	folder, left := -1, 0
+	var r io.Reader
+	for _, e := range a.files {
+		var data io.Reader = bytes.NewReader(nil)
+		if e.hasStream {
+			for left == 0 {
+				if folder++; folder == len(a.streams.folders) {
+					return errors.New("7z: more files than streams")
+				}
+				fo := a.streams.folders[folder]
+				if left = len(fo.subSizes); left > 0 {
+					if r, err = a.folderReader(fo); err != nil {
+						return err
+					}
+				}
+			}
+			left--
+			data = io.LimitReader(r, int64(e.size))
+		}
+		if err := un7zEntry(e, data, dst, strip, fixExec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sevenZipPrefix returns the top-level directory shared by every entry, if
+// any.
+func sevenZipPrefix(files []*szFile) string {
+	var prefix string
+	for _, f := range files {
+		dir, _, nested := strings.Cut(f.name, "/")
+		switch {
+		case !nested && !f.dir:
+			return "" // a file at the top
+		case prefix == "":
+			prefix = dir + "/"
+		case dir+"/" != prefix:
+			return ""
+		}
+	}
+	return prefix
+}
+
+func un7zEntry(e *szFile, data io.Reader, dst, strip string, fixExec bool) error {
+	// The whole stream of a file is read so the next one starts after it.
+	h := crc32.NewIEEE()
+	data = io.TeeReader(data, h)
+	defer io.Copy(io.Discard, data)
+
+	name := e.path(strip)
+	if name == "" || e.anti {
+		return nil
+	}
+	p, err := safe(dst, name)
+	if err != nil {
+		return err
+	}
+	if e.dir {
+		return os.MkdirAll(p, dirMode())
+	}
+	if err := os.MkdirAll(filepath.Dir(p), dirMode()); err != nil {
+		return err
+	}
+
+	check := func() error {
+		if _, err := io.Copy(io.Discard, data); err != nil {
+			return err
+		}
+		if e.hasCRC && h.Sum32() != e.crc {
+			return fmt.Errorf("7z: %s: crc mismatch", e.name)
+		}
+		return nil
+	}
+	mode := e.mode()
+	if mode&os.ModeSymlink != 0 {
+		target, err := io.ReadAll(data)
+		if err != nil {
+			return err
+		}
+		if err := check(); err != nil {
+			return err
+		}
+		return mklink(string(target), p)
+	}
+	if mode, err = fileMode(e.name, mode); err != nil {
+		return err
+	}
+	if fixExec && !e.hasUnixMode() && mode&0o111 == 0 {
+		br := bufio.NewReader(data)
+		if head, _ := br.Peek(8); isExecutable(head) {
+			mode |= (mode & 0o444) >> 2
+		}
+		data = br
+	}
+	if err := streamToFile(data, p, mode); err != nil {
+		return err
+	}
+	return check()
+}
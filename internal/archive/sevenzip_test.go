@@ -0,0 +1,157 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// The bsdtar fixtures hold the same tree in each compression bsdtar writes:
+// a unix archive with modes, a symlink, an empty file and a compressed
+// header. 7zip-bcj.7z is laid out as 7-Zip on Windows writes it: attributes
+// without unix modes, the executable BCJ+LZMA2 filtered in its own folder,
+// the other files solid in LZMA, and an LZMA-compressed header.
+func TestExtract_SevenZip(t *testing.T) {
+	for _, method := range []string{"lzma2", "lzma1", "store", "deflate", "bzip2"} {
+		t.Run(method, func(t *testing.T) {
+			dst := t.TempDir()
+			if err := Extract(filepath.Join("testdata", "bsdtar-"+method+".7z"), dst); err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			assertFileContent(t, filepath.Join(dst, "include", "pkg.h"), "#define PKG 2\n")
+			assertFileContent(t, filepath.Join(dst, "lib", "libpkg.a"), "archive\n")
+			assertFileContent(t, filepath.Join(dst, "lib", "empty"), "")
+			assertFileContent(t, filepath.Join(dst, "lib", "libpkg-2.a"), "archive\n")
+			if target, err := os.Readlink(filepath.Join(dst, "lib", "libpkg-2.a")); err != nil || target != "libpkg.a" {
+				t.Errorf("Readlink() = %q, %v, want libpkg.a", target, err)
+			}
+			if info, err := os.Stat(filepath.Join(dst, "bin", "pkg-config")); err != nil || info.Mode().Perm() != 0o755 {
+				t.Errorf("bin/pkg-config mode = %v, %v, want 0755", info.Mode(), err)
+			}
+			if info, err := os.Stat(filepath.Join(dst, "lib", "libpkg.a")); err != nil || info.Mode().Perm() != 0o644 {
+				t.Errorf("lib/libpkg.a mode = %v, %v, want 0644", info.Mode(), err)
+			}
+		})
+	}
+}
+
+func TestExtract_SevenZipBCJ(t *testing.T) {
+	dst := t.TempDir()
+	if err := Extract(filepath.Join("testdata", "7zip-bcj.7z"), dst); err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	tool, err := os.ReadFile(filepath.Join(dst, "bin", "sdktool"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := sha256.Sum256(tool); hex.EncodeToString(sum[:]) != "299799ad6d07079b25e16a363ed7b7c5d53624deb93b27f576cf218153ca7bd5" {
+		t.Errorf("bin/sdktool decoded wrong: sha256 %x", sum)
+	}
+	assertFileContent(t, filepath.Join(dst, "include", "sdk.h"), "#define SDK 1\n")
+	assertFileContent(t, filepath.Join(dst, "lib", "sdk.a"), "!<arch>\n"+strings.Repeat("sdk", 100))
+	assertFileContent(t, filepath.Join(dst, "share", "empty"), "")
+
+	// Windows entries carry no unix modes: the ELF binary is made
+	// executable, the read-only header stays writable by its owner.
+	for name, want := range map[string]os.FileMode{"bin/sdktool": 0o755, "include/sdk.h": 0o644, "lib/sdk.a": 0o644} {
+		if info, err := os.Stat(filepath.Join(dst, filepath.FromSlash(name))); err != nil || info.Mode().Perm() != want {
+			t.Errorf("%s mode = %v, %v, want %v", name, info.Mode(), err, want)
+		}
+	}
+}
+
+// The BCJ decoder carries state across reads; any split of the input must
+// decode the same.
+func TestBCJReader_Chunks(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "7zip-bcj.7z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	info, _ := f.Stat()
+	a, err := openSevenZip(f, info.Size())
+	if err != nil {
+		t.Fatalf("openSevenZip() error = %v", err)
+	}
+	folder := a.streams.folders[0]
+	lz := folder.bindPairs[0][1] // the LZMA2 coder feeding BCJ
+	r, err := a.coderReader(folder, lz, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := io.ReadAll(&bcjReader{r: bytes.NewReader(filtered), buf: make([]byte, 1<<16)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{5, 6, 7, 64, 4099} {
+		got, err := io.ReadAll(&bcjReader{r: iotest.OneByteReader(bytes.NewReader(filtered)), buf: make([]byte, size)})
+		if err != nil {
+			t.Fatalf("buffer %d: %v", size, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("buffer %d decodes differently", size)
+		}
+	}
+}
+
+func TestExtract_SevenZipCorrupt(t *testing.T) {
+	orig, err := os.ReadFile(filepath.Join("testdata", "bsdtar-store.7z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		mangle func([]byte) []byte
+	}{
+		{"start header", func(b []byte) []byte { b[20]++; return b }},
+		{"file contents", func(b []byte) []byte { b[32+bytes.Index(b[32:], []byte("#define"))]++; return b }},
+		{"truncated", func(b []byte) []byte { return b[:len(b)-10] }},
+		{"not 7z", func(b []byte) []byte { b[0] = 'X'; return b }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := filepath.Join(t.TempDir(), "pkg.7z")
+			if err := os.WriteFile(src, tt.mangle(bytes.Clone(orig)), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := Extract(src, t.TempDir()); err == nil {
+				t.Error("Extract() succeeded")
+			}
+		})
+	}
+}
+
+func TestSzReader_Number(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		want uint64
+	}{
+		{[]byte{0x00}, 0},
+		{[]byte{0x7f}, 0x7f},
+		{[]byte{0x80, 0x80}, 0x80},
+		{[]byte{0xbf, 0xff}, 0x3fff},
+		{[]byte{0xc0, 0x00, 0x40}, 0x4000},
+		{[]byte{0xff, 1, 2, 3, 4, 5, 6, 7, 8}, 0x0807060504030201},
+	}
+	for _, tt := range tests {
+		r := &szReader{b: tt.in}
+		if got := r.number(); got != tt.want || r.err != nil || len(r.b) != 0 {
+			t.Errorf("number(% x) = %#x, %v, want %#x", tt.in, got, r.err, tt.want)
+		}
+	}
+	r := &szReader{b: []byte{0xc0, 0x00}}
+	if r.number(); !errors.Is(r.err, io.ErrUnexpectedEOF) {
+		t.Errorf("number() of a truncated value: err = %v", r.err)
+	}
+}
@@ -0,0 +1,116 @@
+package build
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/qntx/gox/internal/cache"
+)
+
+// Zig ships no bionic libc, so android targets compile against the sysroot
+// of an Android NDK, handed to zig cc as a libc installation file.
+
+// AndroidNDKEnv names the environment variables selecting an NDK when the
+// config names none, in the order the Android tools read them.
+var AndroidNDKEnv = []string{"ANDROID_NDK_HOME", "ANDROID_NDK_ROOT"}
+
+// MinAndroidAPI is the oldest Android API level Go runs on, and the level
+// android targets build for unless android-api says otherwise.
+const MinAndroidAPI = 21
+
+// androidTriples maps GOARCH to the NDK's directory names for its headers
+// and libraries.
+var androidTriples = map[string]string{
+	"386":   "i686-linux-android",
+	"amd64": "x86_64-linux-android",
+	"arm":   "arm-linux-androideabi",
+	"arm64": "aarch64-linux-android",
+}
+
+// androidAPI returns the API level an android target builds for.
+func (o *Options) androidAPI() int {
+	if o.AndroidAPI == 0 {
+		return MinAndroidAPI
+	}
+	return o.AndroidAPI
+}
+
+// androidNDK returns the NDK directory of the target, or "" when neither the
+// config nor the environment names one.
+func (o *Options) androidNDK() string {
+	if o.AndroidNDK != "" {
+		return o.AndroidNDK
+	}
+	for _, key := range AndroidNDKEnv {
+		if dir := os.Getenv(key); dir != "" {
+			return dir
+		}
+	}
+	return ""
+}
+
+// ndkSysroot returns the sysroot of the NDK at ndk, which holds one per
+// host platform under toolchains/llvm/prebuilt.
+func ndkSysroot(ndk string) (string, error) {
+	if ndk == "" {
+		return "", errors.New("no Android NDK: set android-ndk or $ANDROID_NDK_HOME")
+	}
+	matches, _ := filepath.Glob(filepath.Join(ndk, "toolchains", "llvm", "prebuilt", "*", "sysroot"))
+	if len(matches) == 0 {
+		return "", fmt.Errorf("%s is not an Android NDK: no toolchains/llvm/prebuilt/*/sysroot", ndk)
+	}
+	return matches[0], nil
+}
+
+// androidCRTDir returns the directory of the sysroot holding the C runtime
+// and system libraries (libc, liblog, libandroid, ...) of the target's API
+// level.
+func (b *Builder) androidCRTDir() string {
+	triple := androidTriples[b.opts.GOARCH]
+	return filepath.Join(b.ndk, "usr", "lib", triple, strconv.Itoa(b.opts.androidAPI()))
+}
+
+// androidLibc returns the libc installation file pointing zig cc at the
+// NDK's bionic, named after its contents so targets share it.
+func (b *Builder) androidLibc() (path string, data []byte) {
+	triple := androidTriples[b.opts.GOARCH]
+	include := filepath.Join(b.ndk, "usr", "include")
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "include_dir=%s\n", include)
+	fmt.Fprintf(&buf, "sys_include_dir=%s\n", filepath.Join(include, triple))
+	fmt.Fprintf(&buf, "crt_dir=%s\n", b.androidCRTDir())
+	buf.WriteString("msvc_lib_dir=\nkernel32_lib_dir=\ngcc_dir=\n")
+	sum := sha256.Sum256(buf.Bytes())
+	return cache.Dir("android", triple+"-"+hex.EncodeToString(sum[:6])+".libc"), buf.Bytes()
+}
+
+// writeAndroidLibc writes the libc installation file of the target unless
+// it exists.
+func (b *Builder) writeAndroidLibc() error {
+	path, data := b.androidLibc()
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
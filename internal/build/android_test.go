@@ -0,0 +1,87 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeNDK returns an NDK directory holding an empty sysroot.
+func fakeNDK(t *testing.T) (ndk, sysroot string) {
+	t.Helper()
+	ndk = t.TempDir()
+	sysroot = filepath.Join(ndk, "toolchains", "llvm", "prebuilt", "linux-x86_64", "sysroot")
+	if err := os.MkdirAll(sysroot, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return ndk, sysroot
+}
+
+func TestOptions_BuildableAndroid(t *testing.T) {
+	for _, key := range AndroidNDKEnv {
+		t.Setenv(key, "")
+	}
+	ndk, _ := fakeNDK(t)
+	tests := []struct {
+		name string
+		opts Options
+		env  string
+		want string
+	}{
+		{"no ndk", Options{GOOS: "android", GOARCH: "arm64"}, "", "requires the Android NDK"},
+		{"not an ndk", Options{GOOS: "android", GOARCH: "arm64", AndroidNDK: t.TempDir()}, "", "is not an Android NDK"},
+		{"config", Options{GOOS: "android", GOARCH: "arm64", AndroidNDK: ndk}, "", ""},
+		{"env", Options{GOOS: "android", GOARCH: "amd64"}, ndk, ""},
+		{"arch", Options{GOOS: "android", GOARCH: "riscv64", AndroidNDK: ndk}, "", "not supported by the Android NDK"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(AndroidNDKEnv[0], tt.env)
+			err := tt.opts.Buildable()
+			if tt.want == "" && err != nil {
+				t.Errorf("Buildable() = %v, want nil", err)
+			}
+			if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+				t.Errorf("Buildable() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_AndroidEnv(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	ndk, sysroot := fakeNDK(t)
+	b := New("", &Options{GOOS: "android", GOARCH: "arm64", AndroidNDK: ndk, AndroidAPI: 29, LinkMode: LinkAuto})
+	if err := b.setup(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	crt := filepath.Join(sysroot, "usr", "lib", "aarch64-linux-android", "29")
+	libc, _ := b.androidLibc()
+	data, err := os.ReadFile(libc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"include_dir=" + filepath.Join(sysroot, "usr", "include") + "\n",
+		"sys_include_dir=" + filepath.Join(sysroot, "usr", "include", "aarch64-linux-android") + "\n",
+		"crt_dir=" + crt + "\n",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("libc file lacks %q:\n%s", want, data)
+		}
+	}
+
+	env := b.buildEnv()
+	if !slices.Contains(env, "ZIG_LIBC="+libc) {
+		t.Errorf("buildEnv() = %q, want ZIG_LIBC=%s", env, libc)
+	}
+	if !strings.Contains(b.cgoLDFlags(), "-L"+crt) {
+		t.Errorf("cgoLDFlags() = %q, want -L%s", b.cgoLDFlags(), crt)
+	}
+	if got := b.opts.ZigTarget(); got != "aarch64-linux-android.29" {
+		t.Errorf("ZigTarget() = %q", got)
+	}
+}
@@ -1,6 +1,7 @@
 package build
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"io"
@@ -8,10 +9,16 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/qntx/gox/internal/archive"
+	"github.com/qntx/gox/internal/cache"
+	"github.com/qntx/gox/internal/hosttool"
+	"github.com/qntx/gox/internal/plugin"
+	"github.com/qntx/gox/internal/sdk"
+	"github.com/qntx/gox/internal/telemetry"
 	"github.com/qntx/gox/internal/ui"
 )
 
@@ -19,13 +26,17 @@ import (
 type Builder struct {
 	zig    string
 	opts   *Options
+	pkgs   []*Package
+	libs   CopyStats // libraries copyLibs placed in the prefix
+	sdk    string    // macOS SDK root for darwin targets, if any
+	ndk    string    // Android NDK sysroot for android targets, if any
 	stdout io.Writer
 	stderr io.Writer
 }
 
 // New creates a Builder with default stdout/stderr.
 func New(zigPath string, opts *Options) *Builder {
-	return &Builder{zig: zigPath, opts: opts, stdout: os.Stdout, stderr: os.Stderr}
+	return &Builder{zig: zigPath, opts: opts, stdout: ui.Stdout, stderr: ui.Stderr}
 }
 
 // NewWithOutput creates a Builder with custom output writers.
@@ -33,22 +44,54 @@ func NewWithOutput(zigPath string, opts *Options, stdout, stderr io.Writer) *Bui
 	return &Builder{zig: zigPath, opts: opts, stdout: stdout, stderr: stderr}
 }
 
+// CopiedLibs returns the shared libraries the last Run copied into the
+// prefix.
+func (b *Builder) CopiedLibs() CopyStats {
+	return b.libs
+}
+
 // Run executes the full build pipeline.
 func (b *Builder) Run(ctx context.Context, pkgs []string) error {
-	if err := b.setupPackages(ctx); err != nil {
-		return fmt.Errorf("packages: %w", err)
+	if err := b.opts.ExpandPaths(pkgs); err != nil {
+		return fmt.Errorf("output template: %w", err)
+	}
+	compile := b.compile
+	if b.opts.IsUniversal() {
+		compile = b.compileUniversal
+	} else if err := b.setup(ctx); err != nil {
+		return err
 	}
 	if err := b.setupDirs(); err != nil {
 		return fmt.Errorf("dirs: %w", err)
 	}
-	if err := b.compile(ctx, pkgs); err != nil {
+	if err := b.hook(ctx, plugin.PreBuild); err != nil {
+		return err
+	}
+	if err := telemetry.Phase(ctx, "compile", func(ctx context.Context) error { return compile(ctx, pkgs) }); err != nil {
 		return err
 	}
-	if err := b.copyLibs(); err != nil {
+	if err := b.installLib(ctx); err != nil {
+		return fmt.Errorf("%s: %w", b.opts.BuildMode, err)
+	}
+	if err := telemetry.Phase(ctx, "sign", func(ctx context.Context) error { return b.sign(ctx, pkgs) }); err != nil {
+		return fmt.Errorf("sign: %w", err)
+	}
+	if err := telemetry.Phase(ctx, "copy-libs", func(context.Context) error { return b.copyLibs() }); err != nil {
 		return fmt.Errorf("libs: %w", err)
 	}
+	if b.opts.DepsReport {
+		if err := b.writeDepsReport(pkgs); err != nil {
+			return fmt.Errorf("deps report: %w", err)
+		}
+	}
+	if err := b.hook(ctx, plugin.PostBuild); err != nil {
+		return err
+	}
 	if b.opts.Pack {
-		if err := b.createArchive(); err != nil {
+		if err := b.hook(ctx, plugin.PrePack); err != nil {
+			return err
+		}
+		if err := telemetry.Phase(ctx, "pack", func(context.Context) error { return b.pack() }); err != nil {
 			return fmt.Errorf("pack: %w", err)
 		}
 	}
@@ -58,8 +101,8 @@ func (b *Builder) Run(ctx context.Context, pkgs []string) error {
 // GoRun compiles and runs packages using `go run` with Zig as the C toolchain.
 // This leverages Go's build cache for faster repeated runs.
 func (b *Builder) GoRun(ctx context.Context, pkgs []string, progArgs []string) error {
-	if err := b.setupPackages(ctx); err != nil {
-		return fmt.Errorf("packages: %w", err)
+	if err := b.setup(ctx); err != nil {
+		return err
 	}
 
 	env := b.buildEnv()
@@ -69,7 +112,7 @@ func (b *Builder) GoRun(ctx context.Context, pkgs []string, progArgs []string) e
 		b.logBuild(env, args)
 	}
 
-	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd := hosttool.Command(ctx, "go", args...)
 	cmd.Env = append(os.Environ(), env...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = b.stdout
@@ -87,8 +130,8 @@ func (b *Builder) GoRun(ctx context.Context, pkgs []string, progArgs []string) e
 // GoTest runs tests using `go test` with Zig as the C toolchain.
 // This leverages Go's build cache for faster repeated test runs.
 func (b *Builder) GoTest(ctx context.Context, pkgs []string, testArgs []string) error {
-	if err := b.setupPackages(ctx); err != nil {
-		return fmt.Errorf("packages: %w", err)
+	if err := b.setup(ctx); err != nil {
+		return err
 	}
 
 	env := b.buildEnv()
@@ -98,8 +141,12 @@ func (b *Builder) GoTest(ctx context.Context, pkgs []string, testArgs []string)
 		b.logBuild(env, args)
 	}
 
-	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd := hosttool.Command(ctx, "go", args...)
 	cmd.Env = append(os.Environ(), env...)
+	if b.opts.GOOS == "windows" && runtime.GOOS != "windows" && len(b.opts.BinDirs) > 0 {
+		// go test passes its environment on to wine through -exec.
+		cmd.Env = append(cmd.Env, "WINEPATH="+winePath(b.opts.BinDirs))
+	}
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = b.stdout
 	cmd.Stderr = b.stderr
@@ -115,8 +162,8 @@ func (b *Builder) GoTest(ctx context.Context, pkgs []string, testArgs []string)
 
 // GoInstall compiles and installs packages using `go install` with Zig as the C toolchain.
 func (b *Builder) GoInstall(ctx context.Context, pkgs []string) error {
-	if err := b.setupPackages(ctx); err != nil {
-		return fmt.Errorf("packages: %w", err)
+	if err := b.setup(ctx); err != nil {
+		return err
 	}
 
 	env := b.buildEnv()
@@ -126,7 +173,7 @@ func (b *Builder) GoInstall(ctx context.Context, pkgs []string) error {
 		b.logBuild(env, args)
 	}
 
-	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd := hosttool.Command(ctx, "go", args...)
 	cmd.Env = append(os.Environ(), env...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = b.stdout
@@ -141,14 +188,63 @@ func (b *Builder) GoInstall(ctx context.Context, pkgs []string) error {
 	return nil
 }
 
+// Env fetches the target's packages (and macOS SDK) and returns the
+// environment gox gives the go command (GOOS, GOARCH, CC, CGO_* and so on),
+// for tools like gopls that run go themselves.
+func (b *Builder) Env(ctx context.Context) ([]string, error) {
+	if err := b.setup(ctx); err != nil {
+		return nil, err
+	}
+	return b.buildEnv(), nil
+}
+
+// setup fetches the packages and the macOS SDK of the target.
+func (b *Builder) setup(ctx context.Context) error {
+	if b.opts.IsUniversal() {
+		return ErrUniversal
+	}
+	if err := b.setupPackages(ctx); err != nil {
+		return fmt.Errorf("packages: %w", err)
+	}
+	if b.opts.GOOS == "darwin" {
+		root, err := sdk.Resolve(ctx, b.opts.MacOSSDK)
+		if err != nil {
+			return fmt.Errorf("macos-sdk: %w", err)
+		}
+		b.sdk = root
+	}
+	if b.opts.GOOS == "ios" {
+		root, err := resolveIOSSDK(ctx, b.opts.IOSSDK)
+		if err != nil {
+			return fmt.Errorf("ios-sdk: %w", err)
+		}
+		b.sdk = root
+	}
+	if b.opts.GOOS == "android" {
+		root, err := ndkSysroot(b.opts.androidNDK())
+		if err != nil {
+			return fmt.Errorf("android-ndk: %w", err)
+		}
+		b.ndk = root
+		if err := b.writeAndroidLibc(); err != nil {
+			return fmt.Errorf("android-ndk: %w", err)
+		}
+	}
+	return nil
+}
+
 func (b *Builder) setupPackages(ctx context.Context) error {
 	if len(b.opts.Packages) == 0 {
 		return nil
 	}
+	if err := b.opts.ResolvePackages(); err != nil {
+		return err
+	}
 	pkgs, err := EnsureAll(ctx, b.opts.Packages)
 	if err != nil {
 		return err
 	}
+	b.pkgs = pkgs
 	inc, lib, bin := CollectPaths(pkgs)
 	b.opts.IncludeDirs = append(inc, b.opts.IncludeDirs...)
 	b.opts.LibDirs = append(lib, b.opts.LibDirs...)
@@ -167,7 +263,7 @@ func (b *Builder) setupDirs() error {
 		}
 	}
 	if b.opts.Prefix != "" && b.opts.GOOS != "windows" {
-		return os.MkdirAll(filepath.Join(b.opts.Prefix, "lib"), 0o755)
+		return os.MkdirAll(filepath.Join(b.opts.Prefix, b.opts.Layout.LibDir(b.opts.GOOS)), 0o755)
 	}
 	return nil
 }
@@ -181,8 +277,16 @@ func (b *Builder) compile(ctx context.Context, pkgs []string) error {
 		b.logBuild(env, args)
 	}
 
+	cleanup, err := b.writeSyso(ctx, pkgs)
+	if err != nil {
+		return fmt.Errorf("windows resources: %w", err)
+	}
+	if cleanup != nil {
+		defer cleanup()
+	}
+
 	start := time.Now()
-	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd := hosttool.Command(ctx, "go", args...)
 	cmd.Env = append(os.Environ(), env...)
 	cmd.Stdout, cmd.Stderr = b.stdout, b.stderr
 
@@ -190,6 +294,9 @@ func (b *Builder) compile(ctx context.Context, pkgs []string) error {
 		ui.BuildFailed()
 		return err
 	}
+	if peak := peakRSS(cmd.ProcessState); peak > 0 {
+		_ = recordMemory(b.opts, peak)
+	}
 
 	ui.Built(b.outputPath(), time.Since(start))
 	return nil
@@ -200,62 +307,141 @@ func (b *Builder) copyLibs() error {
 		return nil
 	}
 
+	layout := b.opts.Layout
+	srcs, dst := b.opts.LibDirs, filepath.Join(b.opts.Prefix, layout.LibDir(b.opts.GOOS))
 	if b.opts.GOOS == "windows" {
-		if len(b.opts.BinDirs) == 0 {
-			return nil
-		}
-		for _, src := range b.opts.BinDirs {
-			if err := copyDir(src, b.opts.Prefix); err != nil {
-				return fmt.Errorf("%s: %w", src, err)
-			}
-		}
-		if b.opts.Verbose {
-			fmt.Fprintf(os.Stderr, "dlls: %s\n", b.opts.Prefix)
-		}
-		return nil
+		srcs, dst = b.opts.BinDirs, filepath.Join(b.opts.Prefix, layout.BinDir(b.opts.GOOS))
 	}
-
-	if len(b.opts.LibDirs) == 0 {
+	if len(srcs) == 0 {
 		return nil
 	}
-	dst := filepath.Join(b.opts.Prefix, "lib")
-	for _, src := range b.opts.LibDirs {
-		if err := copyDir(src, dst); err != nil {
+
+	var jobs []copyJob
+	for _, src := range srcs {
+		j, err := planCopy(src, dst, b.opts.LibExclude)
+		if err != nil {
 			return fmt.Errorf("%s: %w", src, err)
 		}
+		jobs = append(jobs, j...)
 	}
-	if b.opts.Verbose {
-		fmt.Fprintf(os.Stderr, "libs: %s\n", dst)
+	if err := b.checkCase(jobs, dst); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	stats, err := copyAll(jobs, b.opts.LibCopy)
+	if err != nil {
+		return err
+	}
+	b.libs = stats
+	if stats.Files > 0 {
+		ui.Copied(dst, stats.Files, stats.Bytes, time.Since(start))
 	}
 	return nil
 }
 
-func (b *Builder) createArchive() error {
-	src := b.opts.Prefix
-	if src == "" {
-		src = b.opts.Output
+// checkCase looks for libs whose prefix paths differ only in case. On a
+// case-insensitive host one would overwrite the other, so the copy fails;
+// otherwise a warning flags targets whose users extract the prefix onto a
+// case-insensitive filesystem by default.
+func (b *Builder) checkCase(jobs []copyJob, dst string) error {
+	paths := make([]string, len(jobs))
+	for i, j := range jobs {
+		paths[i] = j.dst
+	}
+	pairs := archive.Collisions(paths)
+	if len(pairs) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return err
 	}
+	if archive.CaseInsensitive(dst) {
+		return fmt.Errorf("%w: %s and %s on the case-insensitive filesystem of %s", archive.ErrCaseCollision, pairs[0][0], pairs[0][1], dst)
+	}
+	if b.opts.GOOS == "darwin" || b.opts.GOOS == "windows" {
+		for _, p := range pairs {
+			ui.Warn("%s and %s differ only in case and collide on %s filesystems", p[0], p[1], b.opts.GOOS)
+		}
+	}
+	return nil
+}
+
+// pack writes the target's archive or package, and its checksum when asked
+// to.
+func (b *Builder) pack() error {
+	src := cmp.Or(b.opts.Prefix, b.opts.Output)
 	if src == "" {
 		return fmt.Errorf("--pack requires --output or --prefix")
 	}
-	path, err := archive.Create(src, b.opts.GOOS, b.opts.GOARCH)
+	var path string
+	var created bool
+	var err error
+	if b.opts.PackFormat.IsLinuxPackage() {
+		path, err = b.createPackage()
+		created = true
+	} else {
+		opts := b.archiveOptions()
+		opts.Exclude, opts.Name = b.opts.PackExclude, b.opts.PackName
+		if opts.Include, err = b.packIncludes(); err != nil {
+			return err
+		}
+		path, created, err = archive.CreateIfChanged(src, b.opts.GOOS, b.opts.GOARCH, opts)
+	}
 	if err != nil {
 		return err
 	}
+	if b.opts.Checksum {
+		sum, err := archive.WriteChecksum(path)
+		if err != nil {
+			return fmt.Errorf("checksum: %w", err)
+		}
+		if b.opts.Verbose {
+			fmt.Fprintf(ui.Stderr, "sha256: %s\n", sum)
+		}
+	}
 	if b.opts.Verbose {
-		fmt.Fprintf(os.Stderr, "pack: %s\n", path)
+		if created {
+			fmt.Fprintf(ui.Stderr, "pack: %s\n", path)
+		} else {
+			fmt.Fprintf(ui.Stderr, "pack: %s (unchanged)\n", path)
+		}
 	}
 	return nil
 }
 
 func (b *Builder) buildEnv() []string {
-	target := b.opts.ZigTarget()
+	cgo := b.opts.HasCgo()
 	env := []string{
 		"CGO_ENABLED=1",
 		"GOOS=" + b.opts.GOOS,
 		"GOARCH=" + b.opts.GOARCH,
-		"CC=" + b.zigCC("cc", target),
-		"CXX=" + b.zigCC("c++", target),
+	}
+	if cgo {
+		target := b.opts.ZigTarget()
+		env = append(env, "CC="+b.zigCC("cc", target), "CXX="+b.zigCC("c++", target))
+	} else {
+		env[0] = "CGO_ENABLED=0"
+	}
+	if tc := b.opts.GoToolchain(); tc != "" {
+		env = append(env, "GOTOOLCHAIN="+tc)
+	}
+	if b.opts.IsolateCache {
+		env = append(env, "GOCACHE="+GoCacheDir(b.opts.GOOS, b.opts.GOARCH))
+	}
+	if b.opts.Reproducible && os.Getenv("SOURCE_DATE_EPOCH") == "" {
+		if t, ok := sourceDateEpoch(); ok {
+			// Pass the commit time on to cgo and tools go build runs.
+			env = append(env, "SOURCE_DATE_EPOCH="+strconv.FormatInt(t.Unix(), 10))
+		}
+	}
+	if !cgo {
+		return env // zig plays no part
+	}
+	env = append(env, zigCacheEnv()...)
+	if b.ndk != "" {
+		libc, _ := b.androidLibc()
+		env = append(env, "ZIG_LIBC="+libc)
 	}
 	if flags := b.cgoFlags(); flags != "" {
 		env = append(env, "CGO_CFLAGS="+flags)
@@ -263,14 +449,50 @@ func (b *Builder) buildEnv() []string {
 	if flags := b.cgoLDFlags(); flags != "" {
 		env = append(env, "CGO_LDFLAGS="+flags)
 	}
+	return append(env, pkgConfigEnv(b.pkgs)...)
+}
+
+// ZigCacheDir returns the directory zig cc caches compiled objects and libc
+// artifacts in, instead of the user's home or the project.
+func ZigCacheDir() string {
+	return cache.Dir("zig-cache")
+}
+
+// zigCacheEnv points Zig's global and local caches at ZigCacheDir, unless
+// the user set them explicitly.
+func zigCacheEnv() []string {
+	var env []string
+	for _, key := range []string{"ZIG_GLOBAL_CACHE_DIR", "ZIG_LOCAL_CACHE_DIR"} {
+		if os.Getenv(key) == "" {
+			env = append(env, key+"="+ZigCacheDir())
+		}
+	}
 	return env
 }
 
+// GoCacheRoot returns the directory holding the isolated GOCACHE of every
+// target.
+func GoCacheRoot() string {
+	return cache.Dir("gocache")
+}
+
+// GoCacheDir returns the isolated GOCACHE of goos/goarch builds, so
+// parallel cross builds keep their own warm cache.
+func GoCacheDir(goos, goarch string) string {
+	return filepath.Join(GoCacheRoot(), goos+"-"+goarch)
+}
+
 func (b *Builder) buildArgs(pkgs []string) []string {
 	args := []string{"build"}
 	if out := b.outputPath(); out != "" {
 		args = append(args, "-o", out)
 	}
+	if b.opts.BuildMode.IsLib() {
+		args = append(args, "-buildmode="+string(b.opts.BuildMode))
+	}
+	if b.opts.Reproducible {
+		args = append(args, reproducibleFlags...)
+	}
 	if flags := b.goLDFlags(); flags != "" {
 		args = append(args, "-ldflags="+flags)
 	}
@@ -304,6 +526,11 @@ func (b *Builder) testArgs(pkgs []string, testArgs []string) []string {
 		args = append(args, "-ldflags="+flags)
 	}
 	args = append(args, b.opts.BuildFlags...)
+	if b.opts.InSysroot {
+		args = append(args, "-exec", quoteExec(sysrootOf(b.opts).ExecArgs()))
+	} else if b.opts.TestExec != "" {
+		args = append(args, "-exec", b.opts.TestExec)
+	}
 	if len(pkgs) == 0 {
 		args = append(args, ".")
 	} else {
@@ -317,6 +544,9 @@ func (b *Builder) testArgs(pkgs []string, testArgs []string) []string {
 
 func (b *Builder) installArgs(pkgs []string) []string {
 	args := []string{"install"}
+	if b.opts.Reproducible {
+		args = append(args, reproducibleFlags...)
+	}
 	if flags := b.goLDFlags(); flags != "" {
 		args = append(args, "-ldflags="+flags)
 	}
@@ -330,11 +560,16 @@ func (b *Builder) installArgs(pkgs []string) []string {
 }
 
 func (b *Builder) zigCC(mode, target string) string {
+	return fmt.Sprintf("%s %s -target %s", b.zigBin(), mode, target)
+}
+
+// zigBin returns the path of the zig executable.
+func (b *Builder) zigBin() string {
 	bin := filepath.Join(b.zig, "zig")
 	if runtime.GOOS == "windows" {
 		bin += ".exe"
 	}
-	return fmt.Sprintf("%s %s -target %s", bin, mode, target)
+	return bin
 }
 
 func (b *Builder) cgoFlags() string {
@@ -342,11 +577,20 @@ func (b *Builder) cgoFlags() string {
 	for _, d := range b.opts.IncludeDirs {
 		flags = append(flags, "-I"+d)
 	}
+	if b.sdk != "" {
+		flags = append(flags, "-isysroot", b.sdk, "-F"+sdk.Frameworks(b.sdk))
+	}
 	return strings.Join(flags, " ")
 }
 
 func (b *Builder) cgoLDFlags() string {
 	var flags []string
+	if b.sdk != "" {
+		flags = append(flags, "--sysroot="+b.sdk, "-F"+sdk.Frameworks(b.sdk), "-L"+filepath.Join(b.sdk, "usr", "lib"))
+	}
+	if b.ndk != "" {
+		flags = append(flags, "-L"+b.androidCRTDir()) // liblog, libandroid, ...
+	}
 	for _, d := range b.opts.LibDirs {
 		flags = append(flags, "-L"+d)
 	}
@@ -366,15 +610,24 @@ func (b *Builder) goLDFlags() string {
 	var flags []string
 	if b.opts.Strip {
 		flags = append(flags, "-s", "-w")
-	} else if b.opts.GOOS == "darwin" && runtime.GOOS != "darwin" {
+	} else if isApple(b.opts.GOOS) && runtime.GOOS != "darwin" {
 		flags = append(flags, "-w")
 	}
+	if b.opts.Reproducible {
+		flags = append(flags, "-buildid=")
+	}
+	flags = append(flags, b.opts.Version.ldflags(b.buildDate())...)
 	switch b.opts.LinkMode {
 	case LinkStatic:
 		flags = append(flags, "-linkmode=external", `-extldflags "-static"`)
 	case LinkDynamic:
 		flags = append(flags, "-linkmode=external")
 	}
+	if out := b.outputPath(); b.opts.BuildMode == BuildCShared && b.opts.GOOS == "darwin" && out != "" {
+		// Let consumers find the dylib through their rpath rather than at
+		// the path it was built at.
+		flags = append(flags, `-extldflags "-Wl,-install_name,@rpath/`+filepath.Base(out)+`"`)
+	}
 	return strings.Join(flags, " ")
 }
 
@@ -382,11 +635,20 @@ func (b *Builder) rpath() string {
 	if b.opts.Prefix == "" || b.opts.NoRpath || b.opts.LinkMode.IsStatic() {
 		return ""
 	}
+	rel := b.libRelPath()
+	switch {
+	case b.opts.BuildMode == BuildCArchive:
+		return "" // archives are not linked
+	case b.opts.BuildMode.IsLib() && b.opts.GOOS == "darwin":
+		return "-Wl,-rpath,@loader_path"
+	case b.opts.BuildMode.IsLib():
+		return "-Wl,-rpath,$ORIGIN" // the library sits in the lib directory
+	}
 	switch b.opts.GOOS {
 	case "linux", "freebsd", "netbsd":
-		return "-Wl,-rpath,$ORIGIN/../lib"
+		return "-Wl,-rpath,$ORIGIN" + rel
 	case "darwin":
-		return "-Wl,-rpath,@executable_path/../lib"
+		return "-Wl,-rpath,@executable_path" + rel
 	}
 	return ""
 }
@@ -399,85 +661,33 @@ func (b *Builder) outputPath() string {
 		return ""
 	}
 	name := filepath.Base(b.opts.Prefix)
-	if b.opts.GOOS == "windows" {
-		return filepath.Join(b.opts.Prefix, name+".exe")
-	}
-	return filepath.Join(b.opts.Prefix, "bin", name)
-}
-
-func (b *Builder) logBuild(env, args []string) {
-	if out := b.outputPath(); out != "" {
-		fmt.Fprintf(os.Stderr, "out: %s\n", out)
-	}
-	fmt.Fprintf(os.Stderr, "env: %v\ngo %s\n", env, strings.Join(args, " "))
-}
-
-func copyDir(src, dst string) error {
-	entries, err := os.ReadDir(src)
-	if err != nil {
-		return err
-	}
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
-		}
-		srcPath := filepath.Join(src, e.Name())
-		dstPath := filepath.Join(dst, e.Name())
-
-		info, err := e.Info()
-		if err != nil {
-			return err
-		}
-		if info.Mode()&os.ModeSymlink != 0 {
-			if err := copySymlink(srcPath, dstPath); err != nil {
-				return err
-			}
-		} else {
-			if err := copyFile(srcPath, dstPath, info.Mode()); err != nil {
-				return err
-			}
+	if b.opts.BuildMode.IsLib() {
+		dir := b.opts.Layout.LibDir(b.opts.GOOS)
+		if b.opts.BuildMode == BuildCShared && b.opts.GOOS == "windows" {
+			dir = b.opts.Layout.BinDir(b.opts.GOOS) // DLLs are found next to executables
 		}
+		return filepath.Join(b.opts.Prefix, dir, b.opts.BuildMode.libFile(name, b.opts.GOOS))
 	}
-	return nil
+	return filepath.Join(b.opts.Prefix, b.opts.Layout.BinDir(b.opts.GOOS), name+b.opts.BuildMode.ext(b.opts.GOOS))
 }
 
-func copySymlink(src, dst string) error {
-	target, err := os.Readlink(src)
-	if err != nil {
-		return copyFile(src, dst, 0)
-	}
-	_ = os.Remove(dst)
-	if os.Symlink(target, dst) == nil {
-		return nil
-	}
-	if !filepath.IsAbs(target) {
-		target = filepath.Join(filepath.Dir(src), target)
+// libRelPath returns the lib directory relative to the bin directory as a
+// slash-prefixed suffix for rpath origins (e.g. "/../lib"), or "" when equal.
+func (b *Builder) libRelPath() string {
+	layout := b.opts.Layout
+	rel, err := filepath.Rel(
+		filepath.Join("/", layout.BinDir(b.opts.GOOS)),
+		filepath.Join("/", layout.LibDir(b.opts.GOOS)),
+	)
+	if err != nil || rel == "." {
+		return ""
 	}
-	return copyFile(target, dst, 0)
+	return "/" + filepath.ToSlash(rel)
 }
 
-func copyFile(src, dst string, mode os.FileMode) error {
-	in, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer in.Close()
-
-	if mode == 0 {
-		if fi, err := in.Stat(); err == nil {
-			mode = fi.Mode()
-		} else {
-			mode = 0o644
-		}
-	}
-
-	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
-	if err != nil {
-		return err
-	}
-	_, err = io.Copy(out, in)
-	if e := out.Close(); err == nil {
-		err = e
+func (b *Builder) logBuild(env, args []string) {
+	if out := b.outputPath(); out != "" {
+		fmt.Fprintf(ui.Stderr, "out: %s\n", out)
 	}
-	return err
+	fmt.Fprintf(ui.Stderr, "env: %v\ngo %s\n", env, strings.Join(args, " "))
 }
@@ -0,0 +1,183 @@
+package build
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/qntx/gox/internal/archive"
+)
+
+func TestBuilder_OutputPath(t *testing.T) {
+	flat := true
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"no output", Options{GOOS: "linux"}, ""},
+		{"explicit output", Options{GOOS: "linux", Output: "bin/app"}, "bin/app"},
+		{"unix prefix", Options{GOOS: "linux", Prefix: "dist/app"}, "dist/app/bin/app"},
+		{"windows prefix", Options{GOOS: "windows", Prefix: "dist/app"}, "dist/app/app.exe"},
+		{"custom bin", Options{GOOS: "linux", Prefix: "dist/app", Layout: Layout{Bin: "sbin"}}, "dist/app/sbin/app"},
+		{"flat unix", Options{GOOS: "linux", Prefix: "dist/app", Layout: Layout{Flat: &flat}}, "dist/app/app"},
+		{"c-shared linux", Options{GOOS: "linux", Prefix: "dist/app", BuildMode: BuildCShared}, "dist/app/lib/libapp.so"},
+		{"c-shared lib name", Options{GOOS: "linux", Prefix: "dist/libapp", BuildMode: BuildCShared}, "dist/libapp/lib/libapp.so"},
+		{"c-shared darwin", Options{GOOS: "darwin", Prefix: "dist/app", BuildMode: BuildCShared}, "dist/app/lib/libapp.dylib"},
+		{"c-shared windows", Options{GOOS: "windows", Prefix: "dist/app", BuildMode: BuildCShared}, "dist/app/app.dll"},
+		{"c-archive linux", Options{GOOS: "linux", Prefix: "dist/app", BuildMode: BuildCArchive}, "dist/app/lib/libapp.a"},
+		{"c-archive windows", Options{GOOS: "windows", Prefix: "dist/app", BuildMode: BuildCArchive}, "dist/app/libapp.a"},
+		{"c-shared windows bin", Options{GOOS: "windows", Prefix: "dist/app", BuildMode: BuildCShared, Layout: Layout{Flat: new(bool)}}, "dist/app/bin/app.dll"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New("", &tt.opts)
+			if got := b.outputPath(); got != filepath.FromSlash(tt.want) {
+				t.Errorf("outputPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_Rpath(t *testing.T) {
+	flat := true
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"no prefix", Options{GOOS: "linux"}, ""},
+		{"linux", Options{GOOS: "linux", Prefix: "dist"}, "-Wl,-rpath,$ORIGIN/../lib"},
+		{"darwin", Options{GOOS: "darwin", Prefix: "dist"}, "-Wl,-rpath,@executable_path/../lib"},
+		{"lib64", Options{GOOS: "linux", Prefix: "dist", Layout: Layout{Lib: "lib64"}}, "-Wl,-rpath,$ORIGIN/../lib64"},
+		{"flat", Options{GOOS: "linux", Prefix: "dist", Layout: Layout{Flat: &flat}}, "-Wl,-rpath,$ORIGIN"},
+		{"windows", Options{GOOS: "windows", Prefix: "dist"}, ""},
+		{"static", Options{GOOS: "linux", Prefix: "dist", LinkMode: LinkStatic}, ""},
+		{"no-rpath", Options{GOOS: "linux", Prefix: "dist", NoRpath: true}, ""},
+		{"c-shared linux", Options{GOOS: "linux", Prefix: "dist", BuildMode: BuildCShared}, "-Wl,-rpath,$ORIGIN"},
+		{"c-shared darwin", Options{GOOS: "darwin", Prefix: "dist", BuildMode: BuildCShared}, "-Wl,-rpath,@loader_path"},
+		{"c-archive", Options{GOOS: "linux", Prefix: "dist", BuildMode: BuildCArchive}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New("", &tt.opts)
+			if got := b.rpath(); got != tt.want {
+				t.Errorf("rpath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_IsolatedGoCache(t *testing.T) {
+	for _, isolate := range []bool{false, true} {
+		opts := &Options{GOOS: "linux", GOARCH: "arm64", IsolateCache: isolate}
+		var got string
+		for _, kv := range New("", opts).buildEnv() {
+			if v, ok := strings.CutPrefix(kv, "GOCACHE="); ok {
+				got = v
+			}
+		}
+		want := ""
+		if isolate {
+			want = filepath.Join(GoCacheRoot(), "linux-arm64")
+		}
+		if got != want {
+			t.Errorf("IsolateCache=%v: GOCACHE = %q, want %q", isolate, got, want)
+		}
+	}
+}
+
+func TestBuilder_ZigCacheEnv(t *testing.T) {
+	t.Setenv("ZIG_GLOBAL_CACHE_DIR", "")
+	t.Setenv("ZIG_LOCAL_CACHE_DIR", "/tmp/own")
+
+	env := New("", &Options{GOOS: "linux", GOARCH: "amd64"}).buildEnv()
+	if want := "ZIG_GLOBAL_CACHE_DIR=" + ZigCacheDir(); !slices.Contains(env, want) {
+		t.Errorf("buildEnv() = %q, want %q", env, want)
+	}
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "ZIG_LOCAL_CACHE_DIR=") {
+			t.Errorf("buildEnv() overrides the user's ZIG_LOCAL_CACHE_DIR: %q", kv)
+		}
+	}
+}
+
+func TestBuilder_BuildEnvNoCgo(t *testing.T) {
+	env := New("", &Options{GOOS: "wasip1", GOARCH: "wasm", GoVersion: "1.25"}).buildEnv()
+	want := []string{"CGO_ENABLED=0", "GOOS=wasip1", "GOARCH=wasm", "GOTOOLCHAIN=go1.25.0"}
+	if !slices.Equal(env, want) {
+		t.Errorf("buildEnv() = %q, want %q", env, want)
+	}
+}
+
+func TestBuilder_MacOSSDKFlags(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "MacOSX14.5.sdk")
+	frameworks := filepath.Join(root, "System", "Library", "Frameworks")
+
+	b := New("", &Options{GOOS: "darwin", GOARCH: "arm64", LibDirs: []string{"/pkg/lib"}})
+	if got := b.cgoFlags(); strings.Contains(got, "-isysroot") {
+		t.Errorf("cgoFlags() without an SDK = %q", got)
+	}
+	b.sdk = root
+	if got, want := b.cgoFlags(), "-isysroot "+root+" -F"+frameworks; !strings.HasSuffix(got, want) {
+		t.Errorf("cgoFlags() = %q, want suffix %q", got, want)
+	}
+	want := "--sysroot=" + root + " -F" + frameworks + " -L" + filepath.Join(root, "usr", "lib") + " -L/pkg/lib"
+	if got := b.cgoLDFlags(); !strings.HasPrefix(got, want) {
+		t.Errorf("cgoLDFlags() = %q, want prefix %q", got, want)
+	}
+}
+
+func TestBuilder_CheckCase(t *testing.T) {
+	dst := t.TempDir()
+	jobs := []copyJob{{dst: filepath.Join(dst, "libFoo.dylib")}, {dst: filepath.Join(dst, "libfoo.dylib")}}
+	err := New("", &Options{GOOS: "darwin"}).checkCase(jobs, dst)
+	if archive.CaseInsensitive(dst) {
+		if !errors.Is(err, archive.ErrCaseCollision) {
+			t.Errorf("checkCase() on a case-insensitive host = %v, want ErrCaseCollision", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Errorf("checkCase() on a case-sensitive host = %v, want a warning only", err)
+	}
+}
+
+// A copy-mode build after a link-mode one replaces the prefix links rather
+// than writing through them into the package cache.
+func TestBuilder_CopyLibsAfterLinks(t *testing.T) {
+	for _, mode := range []CopyMode{CopyHardlink, CopySymlink} {
+		t.Run(string(mode), func(t *testing.T) {
+			cached := filepath.Join(t.TempDir(), "lib")
+			if err := os.MkdirAll(cached, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			lib := filepath.Join(cached, "libfoo.so")
+			if err := os.WriteFile(lib, []byte("elf"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			prefix := t.TempDir()
+			for _, m := range []CopyMode{mode, CopyFiles} {
+				b := New("", &Options{GOOS: "linux", GOARCH: "amd64", Prefix: prefix, LinkMode: LinkAuto, LibDirs: []string{cached}, LibCopy: m})
+				if err := b.copyLibs(); err != nil {
+					t.Fatalf("copyLibs(%s) error = %v", m, err)
+				}
+			}
+			if data, err := os.ReadFile(lib); err != nil || string(data) != "elf" {
+				t.Errorf("cached lib = %q, %v, want it untouched", data, err)
+			}
+			copied := filepath.Join(prefix, "lib", "libfoo.so")
+			if data, err := os.ReadFile(copied); err != nil || string(data) != "elf" {
+				t.Errorf("prefix lib = %q, %v", data, err)
+			}
+			if info, err := os.Lstat(copied); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				t.Error("prefix lib is still a symlink after a copy-mode build")
+			}
+		})
+	}
+}
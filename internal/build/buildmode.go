@@ -0,0 +1,185 @@
+package build
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/qntx/gox/internal/ui"
+)
+
+// BuildMode selects what go build produces for a target.
+type BuildMode string
+
+const (
+	BuildExe      BuildMode = "exe"       // an executable (default)
+	BuildCShared  BuildMode = "c-shared"  // a shared library and its C header
+	BuildCArchive BuildMode = "c-archive" // a static library and its C header
+)
+
+// BuildModes lists the accepted build modes.
+var BuildModes = []BuildMode{BuildExe, BuildCShared, BuildCArchive}
+
+func (m BuildMode) Valid() bool {
+	return m == "" || slices.Contains(BuildModes, m)
+}
+
+// IsLib reports whether m builds a C library rather than an executable.
+func (m BuildMode) IsLib() bool {
+	return m == BuildCShared || m == BuildCArchive
+}
+
+// ext returns the file extension of a binary built in mode m for goos.
+func (m BuildMode) ext(goos string) string {
+	switch {
+	case m == BuildCArchive:
+		return ".a"
+	case m == BuildCShared && goos == "windows":
+		return ".dll"
+	case m == BuildCShared && goos == "darwin":
+		return ".dylib"
+	case m == BuildCShared:
+		return ".so"
+	case goos == "windows":
+		return ".exe"
+	case goos == "wasip1":
+		return ".wasm"
+	}
+	return ""
+}
+
+// libFile returns the file name of the library name for goos: libname.so,
+// libname.dylib and libname.a, or name.dll on windows.
+func (m BuildMode) libFile(name, goos string) string {
+	if m.libPrefixed(goos) && !strings.HasPrefix(name, "lib") {
+		name = "lib" + name
+	}
+	return name + m.ext(goos)
+}
+
+// libPrefixed reports whether library files of mode m carry a "lib" prefix
+// on goos; only DLLs go without.
+func (m BuildMode) libPrefixed(goos string) bool {
+	return m == BuildCArchive || goos != "windows"
+}
+
+// headerPath returns the C header go build writes next to a library.
+func headerPath(lib string) string {
+	return strings.TrimSuffix(lib, filepath.Ext(lib)) + ".h"
+}
+
+// installLib moves the header of a library built into a prefix to its
+// include directory, writes its pkg-config file when asked to and gives
+// windows DLLs a module-definition file and an import library for linkers
+// that cannot link against the DLL itself.
+func (b *Builder) installLib(ctx context.Context) error {
+	out := b.outputPath()
+	if !b.opts.BuildMode.IsLib() || out == "" {
+		return nil
+	}
+	header := headerPath(out)
+	if b.opts.Prefix != "" {
+		dst := filepath.Join(b.opts.Prefix, b.opts.Layout.IncludeDir(), filepath.Base(header))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		if err := os.Rename(header, dst); err != nil {
+			return err
+		}
+		header = dst
+	}
+	if b.opts.Verbose {
+		ui.Label("header", header)
+	}
+	if b.opts.PkgConfig {
+		if err := b.writePkgConfig(); err != nil {
+			return fmt.Errorf("pkg-config: %w", err)
+		}
+	}
+	if b.opts.BuildMode != BuildCShared || b.opts.GOOS != "windows" {
+		return nil
+	}
+
+	src, err := os.ReadFile(header)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(out)
+	if b.opts.Prefix != "" {
+		dir = filepath.Join(b.opts.Prefix, b.opts.Layout.LibDir(b.opts.GOOS))
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	stem := strings.TrimSuffix(filepath.Base(out), filepath.Ext(out))
+	def := filepath.Join(dir, stem+".def")
+	if err := os.WriteFile(def, moduleDef(filepath.Base(out), dllExports(src)), 0o644); err != nil {
+		return err
+	}
+	return b.importLib(ctx, def, filepath.Join(dir, stem+".lib"), filepath.Base(out))
+}
+
+// dllExportRe matches the functions a cgo header exports from a DLL, e.g.
+// "extern __declspec(dllexport) GoInt Add(GoInt a, GoInt b);".
+var dllExportRe = regexp.MustCompile(`(?m)^extern __declspec\(dllexport\)[^(;]*?\b(\w+)\s*\(`)
+
+// dllExports returns the names of the functions header exports.
+func dllExports(header []byte) []string {
+	var names []string
+	for _, m := range dllExportRe.FindAllSubmatch(header, -1) {
+		names = append(names, string(m[1]))
+	}
+	return names
+}
+
+// moduleDef returns the module-definition (.def) file of dll exporting
+// names.
+func moduleDef(dll string, names []string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "LIBRARY %s\nEXPORTS\n", dll)
+	for _, n := range names {
+		fmt.Fprintf(&buf, "    %s\n", n)
+	}
+	return buf.Bytes()
+}
+
+// dlltoolMachines maps GOARCH to the machine names of zig dlltool.
+var dlltoolMachines = map[string]string{
+	"386":   "i386",
+	"amd64": "i386:x86-64",
+	"arm64": "arm64",
+}
+
+// importLib writes the import library lib for dll from the .def file def
+// with zig dlltool.
+func (b *Builder) importLib(ctx context.Context, def, lib, dll string) error {
+	machine, ok := dlltoolMachines[b.opts.GOARCH]
+	if !ok {
+		return fmt.Errorf("no import library for windows/%s", b.opts.GOARCH)
+	}
+	start := time.Now()
+	cmd := exec.CommandContext(ctx, b.zigBin(), "dlltool", "-m", machine, "-d", def, "-D", dll, "-l", lib)
+	cmd.Env = append(os.Environ(), zigCacheEnv()...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("zig dlltool: %w\n%s", err, strings.TrimSpace(string(out)))
+	}
+	if b.opts.Verbose {
+		ui.Label("implib", fmt.Sprintf("%s (%s)", lib, ui.FormatDuration(time.Since(start))))
+	}
+	return nil
+}
+
+func buildModeNames() []string {
+	names := make([]string, len(BuildModes))
+	for i, m := range BuildModes {
+		names[i] = string(m)
+	}
+	return names
+}
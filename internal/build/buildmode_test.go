@@ -0,0 +1,182 @@
+package build
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBuilder_BuildModeArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      Options
+		wantArgs  []string
+		wantFlags string
+	}{
+		{"exe", Options{GOOS: "linux", Output: "app"}, []string{"build", "-o", "app", "."}, ""},
+		{"c-shared", Options{GOOS: "linux", Output: "libapp.so", BuildMode: BuildCShared}, []string{"build", "-o", "libapp.so", "-buildmode=c-shared", "."}, ""},
+		{
+			"c-shared darwin", Options{GOOS: "darwin", Prefix: "dist/app", BuildMode: BuildCShared, Strip: true},
+			[]string{"build", "-o", filepath.FromSlash("dist/app/lib/libapp.dylib"), "-buildmode=c-shared", `-ldflags=-s -w -extldflags "-Wl,-install_name,@rpath/libapp.dylib"`, "."},
+			`-s -w -extldflags "-Wl,-install_name,@rpath/libapp.dylib"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New("", &tt.opts)
+			if got := b.buildArgs(nil); !slices.Equal(got, tt.wantArgs) {
+				t.Errorf("buildArgs() = %q, want %q", got, tt.wantArgs)
+			}
+			if got := b.goLDFlags(); got != tt.wantFlags {
+				t.Errorf("goLDFlags() = %q, want %q", got, tt.wantFlags)
+			}
+		})
+	}
+}
+
+func TestOptions_PathDataExt(t *testing.T) {
+	tests := []struct {
+		opts Options
+		want string
+	}{
+		{Options{GOOS: "linux"}, ""},
+		{Options{GOOS: "windows"}, ".exe"},
+		{Options{GOOS: "linux", BuildMode: BuildCShared}, ".so"},
+		{Options{GOOS: "darwin", BuildMode: BuildCShared}, ".dylib"},
+		{Options{GOOS: "windows", BuildMode: BuildCShared}, ".dll"},
+		{Options{GOOS: "windows", BuildMode: BuildCArchive}, ".a"},
+		{Options{GOOS: "wasip1"}, ".wasm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.opts.GOOS+"/"+string(tt.opts.BuildMode), func(t *testing.T) {
+			if got := tt.opts.pathData(nil, false).Ext; got != tt.want {
+				t.Errorf("Ext = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDLLExports(t *testing.T) {
+	header := `/* Code generated by cmd/cgo; DO NOT EDIT. */
+typedef long long GoInt64;
+extern __declspec(dllexport) GoInt64 Add(GoInt64 a, GoInt64 b);
+extern __declspec(dllexport) void Hello(void);
+extern __declspec(dllexport) char* Name(GoString s);
+extern void notExported(void);
+`
+	got := dllExports([]byte(header))
+	if want := []string{"Add", "Hello", "Name"}; !slices.Equal(got, want) {
+		t.Errorf("dllExports() = %q, want %q", got, want)
+	}
+	def := string(moduleDef("app.dll", got))
+	if want := "LIBRARY app.dll\nEXPORTS\n    Add\n    Hello\n    Name\n"; def != want {
+		t.Errorf("moduleDef() = %q, want %q", def, want)
+	}
+}
+
+func TestBuilder_InstallLib(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake zig is a shell script")
+	}
+	zig := t.TempDir()
+	log := filepath.Join(zig, "log")
+	if err := os.WriteFile(filepath.Join(zig, "zig"), []byte("#!/bin/sh\necho \"$@\" > "+log+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		opts  Options
+		files []string // relative to the prefix
+	}{
+		{"linux", Options{GOOS: "linux", BuildMode: BuildCShared}, []string{"lib/libapp.so", "include/libapp.h"}},
+		{"windows", Options{GOOS: "windows", GOARCH: "amd64", BuildMode: BuildCShared}, []string{"app.dll", "include/app.h", "app.def"}},
+		{"c-archive", Options{GOOS: "darwin", BuildMode: BuildCArchive, PkgConfig: true}, []string{"lib/libapp.a", "include/libapp.h", "lib/pkgconfig/app.pc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix := filepath.Join(t.TempDir(), "app")
+			tt.opts.Prefix = prefix
+			b := New(zig, &tt.opts)
+			out := b.outputPath()
+			if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range []string{out, headerPath(out)} {
+				if err := os.WriteFile(f, []byte("extern __declspec(dllexport) void Hello(void);\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := b.installLib(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range tt.files {
+				if _, err := os.Stat(filepath.Join(prefix, f)); err != nil {
+					t.Error(err)
+				}
+			}
+			if _, err := os.Stat(headerPath(out)); err == nil {
+				t.Error("header left next to the library")
+			}
+			if tt.opts.GOOS == "windows" {
+				args, _ := os.ReadFile(log)
+				if !strings.Contains(string(args), "dlltool -m i386:x86-64 -d "+filepath.Join(prefix, "app.def")) {
+					t.Errorf("zig %s, want dlltool run on app.def", args)
+				}
+			}
+		})
+	}
+}
+
+func TestBuilder_PkgConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		wantPath string
+		want     []string
+		notWant  string
+	}{
+		{
+			name:     "c-archive",
+			opts:     Options{GOOS: "linux", Prefix: "dist/app", BuildMode: BuildCArchive, Libs: []string{"ssl"}, LibDirs: []string{"/cache/lib"}},
+			wantPath: "dist/app/lib/pkgconfig/app.pc",
+			want: []string{
+				"prefix=${pcfiledir}/../..\n", "libdir=${prefix}/lib\n", "includedir=${prefix}/include\n",
+				"Libs: -L${libdir} -lapp\n", "Libs.private: -lssl -lpthread\n", "Cflags: -I${includedir}\n",
+			},
+			notWant: "/cache/lib",
+		},
+		{
+			name:     "c-shared windows",
+			opts:     Options{GOOS: "windows", Prefix: "dist/app", BuildMode: BuildCShared},
+			wantPath: "dist/app/pkgconfig/app.pc",
+			want:     []string{"prefix=${pcfiledir}/..\n", "libdir=${prefix}\n", "Libs: -L${libdir} -lapp\n"},
+			notWant:  "Libs.private",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New("", &tt.opts)
+			path := b.pkgConfigPath()
+			if path != filepath.FromSlash(tt.wantPath) {
+				t.Errorf("pkgConfigPath() = %q, want %q", path, tt.wantPath)
+			}
+			rel, err := filepath.Rel(filepath.Dir(path), tt.opts.Prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pc := string(b.pkgConfig(filepath.ToSlash(rel)))
+			for _, w := range tt.want {
+				if !strings.Contains(pc, w) {
+					t.Errorf("pkgConfig() lacks %q:\n%s", w, pc)
+				}
+			}
+			if strings.Contains(pc, tt.notWant) {
+				t.Errorf("pkgConfig() contains %q:\n%s", tt.notWant, pc)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package build
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"path/filepath"
+	"strings"
+)
+
+// CgoFlags are the #cgo directives one target needs to build with a plain
+// go build once its packages are vendored next to the Go sources.
+type CgoFlags struct {
+	GOOS    string
+	GOARCH  string
+	CFLAGS  []string
+	LDFLAGS []string
+	// Unportable lists absolute paths outside dir that were kept as is.
+	Unportable []string
+}
+
+// GenCgoFlags fetches the packages of opts and derives its directives.
+// Paths are rewritten relative to dir as ${SRCDIR}/...; cached packages are
+// expected under dir/vendorDir/<name>, mirroring the package cache layout.
+func GenCgoFlags(ctx context.Context, opts *Options, dir, vendorDir string) (*CgoFlags, error) {
+	b := New("", opts)
+	if err := b.setupPackages(ctx); err != nil {
+		return nil, fmt.Errorf("packages: %w", err)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	f := &CgoFlags{GOOS: opts.GOOS, GOARCH: opts.GOARCH}
+	rel := func(p string) string {
+		s, ok := srcdirPath(p, dir, vendorDir)
+		if !ok {
+			f.Unportable = append(f.Unportable, p)
+		}
+		return s
+	}
+	for _, d := range opts.IncludeDirs {
+		f.CFLAGS = append(f.CFLAGS, "-I"+rel(d))
+	}
+	for _, d := range opts.LibDirs {
+		f.LDFLAGS = append(f.LDFLAGS, "-L"+rel(d))
+	}
+	for _, l := range opts.Libs {
+		f.LDFLAGS = append(f.LDFLAGS, "-l"+l)
+	}
+	return f, nil
+}
+
+// srcdirPath maps p to a ${SRCDIR}-relative path for a package in dir. It
+// reports false for absolute paths outside both dir and the package cache.
+func srcdirPath(p, dir, vendorDir string) (string, bool) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p, false
+	}
+	if r, err := filepath.Rel(cacheDir(), abs); err == nil && filepath.IsLocal(r) {
+		return "${SRCDIR}/" + filepath.ToSlash(filepath.Join(vendorDir, r)), true
+	}
+	r, err := filepath.Rel(dir, abs)
+	if err != nil || (filepath.IsAbs(p) && !filepath.IsLocal(r)) {
+		return filepath.ToSlash(p), false
+	}
+	return "${SRCDIR}/" + filepath.ToSlash(r), true
+}
+
+// FileName returns zz_cgoflags_<os>_<arch>.go; the suffix alone restricts
+// the file to its target.
+func (f *CgoFlags) FileName() string {
+	return fmt.Sprintf("zz_cgoflags_%s_%s.go", f.GOOS, f.GOARCH)
+}
+
+// Render returns the generated Go file for package pkg.
+func (f *CgoFlags) Render(pkg string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gox gen cgoflags. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "//go:build cgo && %s && %s\n\n", f.GOOS, f.GOARCH)
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	if len(f.CFLAGS) > 0 {
+		fmt.Fprintf(&buf, "// #cgo CFLAGS: %s\n", strings.Join(f.CFLAGS, " "))
+	}
+	if len(f.LDFLAGS) > 0 {
+		fmt.Fprintf(&buf, "// #cgo LDFLAGS: %s\n", strings.Join(f.LDFLAGS, " "))
+	}
+	buf.WriteString("import \"C\"\n")
+	return format.Source(buf.Bytes())
+}
@@ -0,0 +1,75 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSrcdirPath(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := filepath.Join(t.TempDir(), "mod", "cuda")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(filepath.Dir(dir))
+
+	tests := []struct {
+		name   string
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{"cached package", filepath.Join(cacheDir(), "gocnn-lib-cudart-v1", "include"), "${SRCDIR}/third_party/gocnn-lib-cudart-v1/include", true},
+		{"inside dir", filepath.Join(dir, "include"), "${SRCDIR}/include", true},
+		{"relative sibling", "libs/include", "${SRCDIR}/../libs/include", true},
+		{"absolute outside", filepath.Join(string(filepath.Separator), "opt", "cuda", "include"), "/opt/cuda/include", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := srcdirPath(tt.path, dir, "third_party")
+			if ok != tt.wantOK || (ok && got != tt.want) {
+				t.Errorf("srcdirPath() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenCgoFlags(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "include"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	opts := &Options{
+		GOOS:        "windows",
+		GOARCH:      "amd64",
+		IncludeDirs: []string{filepath.Join(dir, "include")},
+		LibDirs:     []string{filepath.Join(dir, "lib")},
+		Libs:        []string{"cuda", "cublas"},
+	}
+	f, err := GenCgoFlags(t.Context(), opts, dir, "third_party")
+	if err != nil {
+		t.Fatalf("GenCgoFlags() error = %v", err)
+	}
+	if f.FileName() != "zz_cgoflags_windows_amd64.go" {
+		t.Errorf("FileName() = %q", f.FileName())
+	}
+	src, err := f.Render("cuda")
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	for _, want := range []string{
+		"// Code generated by gox gen cgoflags. DO NOT EDIT.",
+		"//go:build cgo && windows && amd64",
+		"package cuda",
+		"// #cgo CFLAGS: -I${SRCDIR}/include",
+		"// #cgo LDFLAGS: -L${SRCDIR}/lib -lcuda -lcublas",
+		`import "C"`,
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("Render() missing %q:\n%s", want, src)
+		}
+	}
+}
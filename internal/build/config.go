@@ -1,51 +1,132 @@
 package build
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
+
+	"github.com/qntx/gox/internal/archive"
+	"github.com/qntx/gox/internal/cache"
+	"github.com/qntx/gox/internal/httpclient"
+	"github.com/qntx/gox/internal/lock"
+	"github.com/qntx/gox/internal/ui"
 )
 
 // Config represents gox.toml structure.
 type Config struct {
-	Default ConfigDefault  `toml:"default"`
-	Targets []ConfigTarget `toml:"target"`
+	Extends string                     `toml:"extends,omitempty"`
+	Default ConfigDefault              `toml:"default,omitempty"`
+	Mirrors map[string]string          `toml:"mirrors,omitempty"`   // package URL prefix rewrites
+	Tools   map[string]string          `toml:"tools,omitempty"`     // host tool paths, e.g. go = "/opt/go/bin/go"
+	Windows Windows                    `toml:"windows,omitempty"`   // resources linked into windows binaries
+	Version Version                    `toml:"version,omitempty"`   // variables set with -ldflags -X
+	Package Packaging                  `toml:"package,omitempty"`   // metadata of Linux packages
+	Scoop   Scoop                      `toml:"scoop,omitempty"`     // manifest of the windows archives
+	Modules map[string]PkgConfigModule `toml:"pkgconfig,omitempty"` // .pc files written into packages, by name
+	Targets []ConfigTarget             `toml:"target,omitempty"`
+
+	dir string // directory containing the loaded config file
 }
 
 // ConfigDefault holds values inherited by all targets.
 type ConfigDefault struct {
-	ZigVersion string   `toml:"zig-version"`
-	LinkMode   string   `toml:"linkmode"`
-	Include    []string `toml:"include"`
-	Lib        []string `toml:"lib"`
-	Link       []string `toml:"link"`
-	Packages   []string `toml:"packages"`
-	Flags      []string `toml:"flags"`
-	Strip      bool     `toml:"strip"`
-	Verbose    bool     `toml:"verbose"`
+	ZigVersion      string   `toml:"zig-version,omitempty"`
+	GoVersion       string   `toml:"go-version,omitempty"`
+	CacheScope      string   `toml:"cache-scope,omitempty"`
+	ZigMinisign     bool     `toml:"zig-minisign,omitempty"`
+	Theme           string   `toml:"theme,omitempty"`
+	ZigMirror       string   `toml:"zig-mirror,omitempty"`
+	ZigPath         string   `toml:"zig-path,omitempty"`
+	ConfirmDownload string   `toml:"confirm-download,omitempty"` // e.g. "4G"
+	Parallel        *int     `toml:"parallel,omitempty"`         // targets built at once, 0 for auto
+	ExtractUmask    string   `toml:"extract-umask,omitempty"`    // octal, e.g. "027"
+	ExtractFaithful bool     `toml:"extract-faithful,omitempty"`
+	WatchIgnore     []string `toml:"watch-ignore,omitempty"` // glob patterns skipped by --watch
+	Vendor          bool     `toml:"vendor,omitempty"`       // resolve packages from third_party/gox first
+	Plugins         []string `toml:"plugins,omitempty"`      // gox-<name> executables run at build hooks
+	LinkMode        string   `toml:"linkmode,omitempty"`
+	BuildMode       string   `toml:"buildmode,omitempty"`
+	Include         []string `toml:"include,omitempty"`
+	Lib             []string `toml:"lib,omitempty"`
+	Link            []string `toml:"link,omitempty"`
+	LibExclude      []string `toml:"lib-exclude,omitempty"`
+	Packages        []string `toml:"packages,omitempty"`
+	Flags           []string `toml:"flags,omitempty"`
+	Layout          Layout   `toml:"layout,omitempty"`
+	Sysroot         string   `toml:"sysroot,omitempty"`
+	MacOSSDK        string   `toml:"macos-sdk,omitempty"`
+	IOSSDK          string   `toml:"ios-sdk,omitempty"`
+	AndroidNDK      string   `toml:"android-ndk,omitempty"`
+	AndroidAPI      int      `toml:"android-api,omitempty,omitzero"`
+	Sign            Sign     `toml:"sign,omitempty"`
+	Retry           Retry    `toml:"retry,omitempty"`
+	DepsReport      bool     `toml:"deps-report,omitempty"`
+	PkgConfig       bool     `toml:"pkg-config,omitempty"`
+	Checksum        bool     `toml:"checksum,omitempty"`
+	IsolateGoCache  bool     `toml:"isolate-gocache,omitempty"`
+	Reproducible    bool     `toml:"reproducible,omitempty"`
+	Strip           bool     `toml:"strip,omitempty"`
+	Verbose         bool     `toml:"verbose,omitempty"`
+}
+
+// Retry configures how failed downloads are retried.
+type Retry struct {
+	Attempts   int    `toml:"attempts,omitempty"`
+	Backoff    string `toml:"backoff,omitempty"`
+	MaxBackoff string `toml:"max-backoff,omitempty"`
+}
+
+// Merge fills unset fields of r from base.
+func (r Retry) Merge(base Retry) Retry {
+	r.Attempts = cmp.Or(r.Attempts, base.Attempts)
+	r.Backoff = cmp.Or(r.Backoff, base.Backoff)
+	r.MaxBackoff = cmp.Or(r.MaxBackoff, base.MaxBackoff)
+	return r
 }
 
 // ConfigTarget defines a platform-specific build configuration.
 type ConfigTarget struct {
-	Name       string   `toml:"name"`
-	OS         string   `toml:"os"`
-	Arch       string   `toml:"arch"`
-	Output     string   `toml:"output"`
-	Prefix     string   `toml:"prefix"`
-	ZigVersion string   `toml:"zig-version"`
-	LinkMode   string   `toml:"linkmode"`
-	Include    []string `toml:"include"`
-	Lib        []string `toml:"lib"`
-	Link       []string `toml:"link"`
-	Packages   []string `toml:"packages"`
-	Flags      []string `toml:"flags"`
-	NoRpath    bool     `toml:"no-rpath"`
-	Pack       bool     `toml:"pack"`
-	Strip      bool     `toml:"strip"`
-	Verbose    bool     `toml:"verbose"`
+	Name           string   `toml:"name,omitempty"`
+	OS             string   `toml:"os,omitempty"`
+	Arch           string   `toml:"arch,omitempty"`
+	Output         string   `toml:"output,omitempty"`
+	Prefix         string   `toml:"prefix,omitempty"`
+	ZigVersion     string   `toml:"zig-version,omitempty"`
+	GoVersion      string   `toml:"go-version,omitempty"`
+	LinkMode       string   `toml:"linkmode,omitempty"`
+	BuildMode      string   `toml:"buildmode,omitempty"`
+	Include        []string `toml:"include,omitempty"`
+	Lib            []string `toml:"lib,omitempty"`
+	Link           []string `toml:"link,omitempty"`
+	LibExclude     []string `toml:"lib-exclude,omitempty"`
+	Packages       []string `toml:"packages,omitempty"`
+	Flags          []string `toml:"flags,omitempty"`
+	Layout         Layout   `toml:"layout,omitempty"`
+	Sysroot        string   `toml:"sysroot,omitempty"`
+	MacOSSDK       string   `toml:"macos-sdk,omitempty"`
+	IOSSDK         string   `toml:"ios-sdk,omitempty"`
+	Simulator      bool     `toml:"simulator,omitempty"`
+	AndroidAPI     int      `toml:"android-api,omitempty,omitzero"`
+	Sign           Sign     `toml:"sign,omitempty"`
+	NoRpath        bool     `toml:"no-rpath,omitempty"`
+	Pack           Pack     `toml:"pack,omitempty"`
+	DepsReport     bool     `toml:"deps-report,omitempty"`
+	PkgConfig      bool     `toml:"pkg-config,omitempty"`
+	Checksum       bool     `toml:"checksum,omitempty"`
+	IsolateGoCache bool     `toml:"isolate-gocache,omitempty"`
+	Reproducible   bool     `toml:"reproducible,omitempty"`
+	Strip          bool     `toml:"strip,omitempty"`
+	Verbose        bool     `toml:"verbose,omitempty"`
+
+	Variants []ConfigVariant `toml:"variant,omitempty"`
 }
 
 const ConfigFile = "gox.toml"
@@ -67,63 +148,241 @@ func LoadConfig(path string) (*Config, error) {
 		}
 		return nil, err
 	}
-	var cfg Config
-	return &cfg, toml.Unmarshal(data, &cfg)
+	cfg := Config{dir: filepath.Dir(path)}
+	if err := toml.Unmarshal(data, &cfg); err != nil {
+		return &cfg, err
+	}
+	if err := cfg.resolveExtends(cfg.dir, 0); err != nil {
+		return nil, fmt.Errorf("extends: %w", err)
+	}
+	return &cfg, nil
+}
+
+// UseCache applies the configured cache-scope for the rest of the process.
+// Project-scoped caches live in .gox/ next to the config file.
+func (c *Config) UseCache() error {
+	scope := cache.Scope(c.Default.CacheScope)
+	if !scope.Valid() {
+		return fmt.Errorf("invalid cache-scope: %s (use user or project)", scope)
+	}
+	return cache.Use(scope, c.dir)
+}
+
+// UseRetry applies the configured [default.retry] policy to every download
+// for the rest of the process.
+func (c *Config) UseRetry() error {
+	r := c.Default.Retry
+	if r.Attempts < 0 {
+		return fmt.Errorf("invalid retry.attempts: %d", r.Attempts)
+	}
+	p := httpclient.Policy{Attempts: r.Attempts}
+	for _, d := range []struct {
+		key string
+		val string
+		dst *time.Duration
+	}{
+		{"backoff", r.Backoff, &p.Backoff},
+		{"max-backoff", r.MaxBackoff, &p.MaxBackoff},
+	} {
+		if d.val == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.val)
+		if err != nil || v <= 0 {
+			return fmt.Errorf("invalid retry.%s: %q", d.key, d.val)
+		}
+		*d.dst = v
+	}
+	httpclient.SetPolicy(p)
+	return nil
+}
+
+// UseConfirm applies confirm-download, the package download size above
+// which interactive sessions ask before fetching.
+func (c *Config) UseConfirm() error {
+	ConfirmAbove = DefaultConfirmAbove
+	if c.Default.ConfirmDownload == "" {
+		return nil
+	}
+	n, err := ui.ParseSize(c.Default.ConfirmDownload)
+	if err != nil {
+		return fmt.Errorf("confirm-download: %w", err)
+	}
+	ConfirmAbove = n
+	return nil
+}
+
+// UseExtract applies extract-umask and extract-faithful, the permission
+// policy of extracted packages and toolchains.
+func (c *Config) UseExtract() error {
+	p := archive.DefaultPerms
+	if s := c.Default.ExtractUmask; s != "" {
+		n, err := strconv.ParseUint(s, 8, 32)
+		if err != nil || n > 0o777 {
+			return fmt.Errorf("invalid extract-umask: %q", s)
+		}
+		p.Umask = os.FileMode(n)
+	}
+	p.Faithful = c.Default.ExtractFaithful
+	archive.SetPerms(p)
+	return nil
+}
+
+// ZigPath returns zig-path, resolved against the config directory when it
+// is a relative path rather than a bare command name.
+func (c *Config) ZigPath() string {
+	p := c.Default.ZigPath
+	if p == "" || filepath.IsAbs(p) || !strings.ContainsAny(p, `/\`) {
+		return p
+	}
+	return filepath.Join(c.dir, p)
+}
+
+// Dir returns the directory containing the loaded config file.
+func (c *Config) Dir() string {
+	return c.dir
+}
+
+// LockPath returns the gox.lock path next to the config file.
+func (c *Config) LockPath() string {
+	return filepath.Join(c.dir, lock.File)
+}
+
+// Encode writes c as TOML, omitting unset fields.
+func (c *Config) Encode(w io.Writer) error {
+	enc := toml.NewEncoder(w)
+	enc.Indent = ""
+	return enc.Encode(c)
 }
 
-// ToOptions converts targets to Options slice.
+// ToOptions converts targets to Options slice. A target with variants
+// expands into one Options per variant.
 func (c *Config) ToOptions(names []string) ([]*Options, error) {
 	targets, err := c.selectTargets(names)
 	if err != nil {
 		return nil, err
 	}
 	if len(targets) == 0 {
-		return []*Options{c.defaultOptions()}, nil
+		return []*Options{c.DefaultOptions()}, nil
 	}
-	out := make([]*Options, len(targets))
-	for i, t := range targets {
-		out[i] = c.mergeOptions(t)
+	out := make([]*Options, 0, len(targets))
+	for _, s := range targets {
+		if err := s.target.validateVariants(); err != nil {
+			return nil, err
+		}
+		variants := s.variants
+		if variants == nil {
+			if len(s.target.Variants) == 0 {
+				out = append(out, c.mergeOptions(s.target))
+				continue
+			}
+			variants = make([]*ConfigVariant, len(s.target.Variants))
+			for i := range s.target.Variants {
+				variants[i] = &s.target.Variants[i]
+			}
+		}
+		for _, v := range variants {
+			o := c.mergeOptions(s.target)
+			if err := o.applyVariant(v); err != nil {
+				return nil, fmt.Errorf("target %s: %w", s.target.Name, err)
+			}
+			out = append(out, o)
+		}
 	}
 	return out, nil
 }
 
-func (c *Config) selectTargets(names []string) ([]*ConfigTarget, error) {
+// TargetNames returns the names ToOptions accepts: every target and, for
+// targets with variants, each "<target>-<variant>".
+func (c *Config) TargetNames() []string {
+	var names []string
+	for _, t := range c.Targets {
+		if t.Name == "" {
+			continue
+		}
+		names = append(names, t.Name)
+		for _, v := range t.Variants {
+			names = append(names, t.Name+"-"+v.Name)
+		}
+	}
+	return names
+}
+
+// selection is a target picked by name, restricted to variants when the
+// name addressed a single "<target>-<variant>".
+type selection struct {
+	target   *ConfigTarget
+	variants []*ConfigVariant
+}
+
+func (c *Config) selectTargets(names []string) ([]selection, error) {
 	if len(names) == 0 {
-		out := make([]*ConfigTarget, len(c.Targets))
+		out := make([]selection, len(c.Targets))
 		for i := range c.Targets {
-			out[i] = &c.Targets[i]
+			out[i] = selection{target: &c.Targets[i]}
 		}
 		return out, nil
 	}
-	out := make([]*ConfigTarget, 0, len(names))
+	out := make([]selection, 0, len(names))
 	for _, name := range names {
-		found := false
-		for i := range c.Targets {
-			if c.Targets[i].Name == name {
-				out = append(out, &c.Targets[i])
-				found = true
-				break
-			}
-		}
-		if !found {
+		s, ok := c.findTarget(name)
+		if !ok {
 			return nil, fmt.Errorf("target %q not found", name)
 		}
+		out = append(out, s)
 	}
 	return out, nil
 }
 
-func (c *Config) defaultOptions() *Options {
+func (c *Config) findTarget(name string) (selection, bool) {
+	for i := range c.Targets {
+		if c.Targets[i].Name == name {
+			return selection{target: &c.Targets[i]}, true
+		}
+	}
+	for i := range c.Targets {
+		t := &c.Targets[i]
+		for j := range t.Variants {
+			if t.Name+"-"+t.Variants[j].Name == name {
+				return selection{target: t, variants: []*ConfigVariant{&t.Variants[j]}}, true
+			}
+		}
+	}
+	return selection{}, false
+}
+
+// DefaultOptions returns options built from [default] only, ignoring targets.
+func (c *Config) DefaultOptions() *Options {
 	d := &c.Default
 	return &Options{
-		ZigVersion:  d.ZigVersion,
-		LinkMode:    LinkMode(d.LinkMode),
-		IncludeDirs: append([]string(nil), d.Include...),
-		LibDirs:     append([]string(nil), d.Lib...),
-		Libs:        append([]string(nil), d.Link...),
-		Packages:    append([]string(nil), d.Packages...),
-		BuildFlags:  append([]string(nil), d.Flags...),
-		Strip:       d.Strip,
-		Verbose:     d.Verbose,
+		ZigVersion:   d.ZigVersion,
+		GoVersion:    d.GoVersion,
+		LinkMode:     LinkMode(d.LinkMode),
+		BuildMode:    BuildMode(d.BuildMode),
+		IncludeDirs:  append([]string(nil), d.Include...),
+		LibDirs:      append([]string(nil), d.Lib...),
+		Libs:         append([]string(nil), d.Link...),
+		LibExclude:   append([]string(nil), d.LibExclude...),
+		Packages:     append([]string(nil), d.Packages...),
+		BuildFlags:   append([]string(nil), d.Flags...),
+		Layout:       d.Layout,
+		Sysroot:      d.Sysroot,
+		MacOSSDK:     d.MacOSSDK,
+		IOSSDK:       d.IOSSDK,
+		AndroidNDK:   d.AndroidNDK,
+		AndroidAPI:   d.AndroidAPI,
+		Sign:         d.Sign,
+		Windows:      c.windows(),
+		Version:      c.Version,
+		Packaging:    c.packaging(),
+		Scoop:        c.scoop(),
+		DepsReport:   d.DepsReport,
+		PkgConfig:    d.PkgConfig,
+		Checksum:     d.Checksum,
+		IsolateCache: d.IsolateGoCache,
+		Reproducible: d.Reproducible,
+		Strip:        d.Strip,
+		Verbose:      d.Verbose,
 	}
 }
 
@@ -137,21 +396,46 @@ func (c *Config) mergeOptions(t *ConfigTarget) *Options {
 		linkMode = d.LinkMode
 	}
 	return &Options{
-		GOOS:        t.OS,
-		GOARCH:      t.Arch,
-		Output:      t.Output,
-		Prefix:      t.Prefix,
-		ZigVersion:  zigVer,
-		LinkMode:    LinkMode(linkMode),
-		IncludeDirs: mergeSlices(d.Include, t.Include),
-		LibDirs:     mergeSlices(d.Lib, t.Lib),
-		Libs:        mergeSlices(d.Link, t.Link),
-		Packages:    mergeSlices(d.Packages, t.Packages),
-		BuildFlags:  mergeSlices(d.Flags, t.Flags),
-		NoRpath:     t.NoRpath,
-		Pack:        t.Pack,
-		Strip:       d.Strip || t.Strip,
-		Verbose:     d.Verbose || t.Verbose,
+		Target:       t.Name,
+		GOOS:         t.OS,
+		GOARCH:       t.Arch,
+		Output:       t.Output,
+		Prefix:       t.Prefix,
+		ZigVersion:   zigVer,
+		GoVersion:    cmp.Or(t.GoVersion, d.GoVersion),
+		LinkMode:     LinkMode(linkMode),
+		BuildMode:    BuildMode(cmp.Or(t.BuildMode, d.BuildMode)),
+		IncludeDirs:  mergeSlices(d.Include, t.Include),
+		LibDirs:      mergeSlices(d.Lib, t.Lib),
+		Libs:         mergeSlices(d.Link, t.Link),
+		LibExclude:   mergeSlices(d.LibExclude, t.LibExclude),
+		Packages:     mergeSlices(d.Packages, t.Packages),
+		BuildFlags:   mergeSlices(d.Flags, t.Flags),
+		Layout:       t.Layout.Merge(d.Layout),
+		Sysroot:      cmp.Or(t.Sysroot, d.Sysroot),
+		MacOSSDK:     cmp.Or(t.MacOSSDK, d.MacOSSDK),
+		IOSSDK:       cmp.Or(t.IOSSDK, d.IOSSDK),
+		Simulator:    t.Simulator,
+		AndroidNDK:   d.AndroidNDK,
+		AndroidAPI:   cmp.Or(t.AndroidAPI, d.AndroidAPI),
+		Sign:         t.Sign.Merge(d.Sign),
+		Windows:      c.windows(),
+		Version:      c.Version,
+		Packaging:    c.packaging(),
+		Scoop:        c.scoop(),
+		NoRpath:      t.NoRpath,
+		Pack:         t.Pack.Enabled(),
+		PackFormat:   t.Pack.Format,
+		PackInclude:  t.Pack.Include,
+		PackExclude:  t.Pack.Exclude,
+		PackName:     t.Pack.Name,
+		DepsReport:   d.DepsReport || t.DepsReport,
+		PkgConfig:    d.PkgConfig || t.PkgConfig,
+		Checksum:     d.Checksum || t.Checksum,
+		IsolateCache: d.IsolateGoCache || t.IsolateGoCache,
+		Reproducible: d.Reproducible || t.Reproducible,
+		Strip:        d.Strip || t.Strip,
+		Verbose:      d.Verbose || t.Verbose,
 	}
 }
 
@@ -4,6 +4,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/qntx/gox/internal/archive"
+	"github.com/qntx/gox/internal/cache"
+	"github.com/qntx/gox/internal/httpclient"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -56,7 +61,7 @@ pack = true
 		if cfg.Targets[0].Name != "linux-amd64" {
 			t.Errorf("Targets[0].Name = %q, want linux-amd64", cfg.Targets[0].Name)
 		}
-		if cfg.Targets[1].Pack != true {
+		if !cfg.Targets[1].Pack.Enabled() {
 			t.Error("Targets[1].Pack = false, want true")
 		}
 	})
@@ -79,6 +84,7 @@ func TestConfig_ToOptions(t *testing.T) {
 	cfg := &Config{
 		Default: ConfigDefault{
 			ZigVersion: "0.15.0",
+			GoVersion:  "1.24.3",
 			Include:    []string{"/usr/include"},
 			Strip:      true,
 		},
@@ -95,7 +101,10 @@ func TestConfig_ToOptions(t *testing.T) {
 				OS:         "windows",
 				Arch:       "amd64",
 				ZigVersion: "0.14.0",
-				Pack:       true,
+				GoVersion:  "1.25.0",
+				Pack:       Pack{Format: PackArchive},
+
+				IsolateGoCache: true,
 			},
 		},
 	}
@@ -116,6 +125,9 @@ func TestConfig_ToOptions(t *testing.T) {
 		if opts[0].ZigVersion != "0.15.0" {
 			t.Errorf("opts[0].ZigVersion = %q, want 0.15.0", opts[0].ZigVersion)
 		}
+		if opts[0].GoVersion != "1.24.3" {
+			t.Errorf("opts[0].GoVersion = %q, want 1.24.3", opts[0].GoVersion)
+		}
 		if len(opts[0].IncludeDirs) != 2 {
 			t.Errorf("len(opts[0].IncludeDirs) = %d, want 2", len(opts[0].IncludeDirs))
 		}
@@ -127,9 +139,15 @@ func TestConfig_ToOptions(t *testing.T) {
 		if opts[1].ZigVersion != "0.14.0" {
 			t.Errorf("opts[1].ZigVersion = %q, want 0.14.0", opts[1].ZigVersion)
 		}
+		if opts[1].GoVersion != "1.25.0" {
+			t.Errorf("opts[1].GoVersion = %q, want 1.25.0", opts[1].GoVersion)
+		}
 		if !opts[1].Pack {
 			t.Error("opts[1].Pack = false, want true")
 		}
+		if opts[0].IsolateCache || !opts[1].IsolateCache {
+			t.Errorf("IsolateCache = %v, %v, want false, true", opts[0].IsolateCache, opts[1].IsolateCache)
+		}
 	})
 
 	t.Run("specific target", func(t *testing.T) {
@@ -169,6 +187,33 @@ func TestConfig_ToOptions(t *testing.T) {
 	})
 }
 
+func TestConfig_UseCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Cleanup(func() { _ = cache.Use(cache.ScopeUser, "") })
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gox.toml")
+	if err := os.WriteFile(path, []byte("[default]\ncache-scope = \"project\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if err := cfg.UseCache(); err != nil {
+		t.Fatalf("UseCache() error = %v", err)
+	}
+	want := filepath.Join(dir, cache.ProjectDir, "pkg")
+	if got := cacheDir(); got != want {
+		t.Errorf("cacheDir() = %q, want %q", got, want)
+	}
+
+	cfg.Default.CacheScope = "global"
+	if err := cfg.UseCache(); err == nil {
+		t.Error("UseCache() should reject unknown scope")
+	}
+}
+
 func TestMergeSlices(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -220,3 +265,104 @@ func TestFindConfig(t *testing.T) {
 		t.Errorf("findConfig() = %q, want %q", found, configPath)
 	}
 }
+
+func TestConfig_UseRetry(t *testing.T) {
+	old := httpclient.CurrentPolicy()
+	t.Cleanup(func() { httpclient.SetPolicy(old) })
+
+	tests := []struct {
+		name    string
+		retry   Retry
+		want    httpclient.Policy
+		wantErr bool
+	}{
+		{"defaults", Retry{}, httpclient.DefaultPolicy, false},
+		{"custom", Retry{Attempts: 6, Backoff: "1s", MaxBackoff: "1m"}, httpclient.Policy{Attempts: 6, Backoff: time.Second, MaxBackoff: time.Minute}, false},
+		{"disabled", Retry{Attempts: 1}, httpclient.Policy{Attempts: 1, Backoff: httpclient.DefaultPolicy.Backoff, MaxBackoff: httpclient.DefaultPolicy.MaxBackoff}, false},
+		{"bad duration", Retry{Backoff: "soon"}, httpclient.Policy{}, true},
+		{"negative attempts", Retry{Attempts: -1}, httpclient.Policy{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Default: ConfigDefault{Retry: tt.retry}}
+			err := cfg.UseRetry()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UseRetry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && httpclient.CurrentPolicy() != tt.want {
+				t.Errorf("CurrentPolicy() = %+v, want %+v", httpclient.CurrentPolicy(), tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_ZigPath(t *testing.T) {
+	dir := filepath.Join(string(filepath.Separator), "proj")
+	tests := []struct {
+		name, path, want string
+	}{
+		{"unset", "", ""},
+		{"command name", "zig", "zig"},
+		{"absolute", filepath.Join(dir, "bin", "zig"), filepath.Join(dir, "bin", "zig")},
+		{"relative to config", filepath.Join("tools", "zig"), filepath.Join(dir, "tools", "zig")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Default: ConfigDefault{ZigPath: tt.path}, dir: dir}
+			if got := c.ZigPath(); got != tt.want {
+				t.Errorf("ZigPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_UseConfirm(t *testing.T) {
+	t.Cleanup(func() { ConfirmAbove = DefaultConfirmAbove })
+
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{"", DefaultConfirmAbove, false},
+		{"4G", 4 << 30, false},
+		{"lots", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			c := &Config{Default: ConfigDefault{ConfirmDownload: tt.value}}
+			err := c.UseConfirm()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UseConfirm() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && ConfirmAbove != tt.want {
+				t.Errorf("ConfirmAbove = %d, want %d", ConfirmAbove, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_UseExtract(t *testing.T) {
+	t.Cleanup(func() { archive.SetPerms(archive.DefaultPerms) })
+
+	tests := []struct {
+		umask   string
+		wantErr bool
+	}{
+		{"", false},
+		{"027", false},
+		{"0", false},
+		{"0777", false},
+		{"1000", true},
+		{"9", true},
+		{"rwx", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.umask, func(t *testing.T) {
+			c := &Config{Default: ConfigDefault{ExtractUmask: tt.umask}}
+			if err := c.UseExtract(); (err != nil) != tt.wantErr {
+				t.Errorf("UseExtract() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package build
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/qntx/gox/internal/ui"
+)
+
+// DefaultConfirmAbove is the download size above which gox asks before
+// fetching packages unless confirm-download says otherwise.
+const DefaultConfirmAbove = 1 << 30
+
+var (
+	// ConfirmAbove is the projected package download size above which an
+	// interactive session is asked for confirmation.
+	ConfirmAbove int64 = DefaultConfirmAbove
+
+	// AssumeYes skips the confirmation, as with --yes.
+	AssumeYes bool
+)
+
+// ErrDownloadDeclined is returned when a large download is not confirmed.
+var ErrDownloadDeclined = errors.New("download declined")
+
+// approved holds the URLs of packages whose download was already reported
+// and confirmed, so a build checked up front is not asked again per target.
+var approved = struct {
+	sync.Mutex
+	urls map[string]bool
+}{urls: map[string]bool{}}
+
+// ConfirmDownloads reports the packages a whole build still has to fetch,
+// and asks for confirmation when they exceed ConfirmAbove, before any
+// target starts.
+func ConfirmDownloads(ctx context.Context, sources []string) error {
+	sources = slices.Clone(sources)
+	slices.Sort(sources)
+	_, toDownload, err := pending(slices.Compact(sources))
+	if err != nil || len(toDownload) == 0 {
+		return err
+	}
+	return confirmDownload(toDownload, downloadSizes(ctx, toDownload))
+}
+
+// confirmDownload prints the projected download size of pkgs and the space
+// left in the cache, fails early when they cannot fit, and asks before
+// downloads larger than ConfirmAbove. Sessions without a terminal are never
+// asked.
+func confirmDownload(pkgs []*Package, sizes map[string]int64) error {
+	approved.Lock()
+	defer approved.Unlock()
+
+	var (
+		total int64
+		urls  []string
+	)
+	for _, p := range pkgs {
+		if p.URL != "" && !approved.urls[p.URL] {
+			total += sizes[p.URL]
+			urls = append(urls, p.URL)
+		}
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+
+	if total > 0 {
+		dir := cacheDir()
+		if free, ok := freeSpace(existingDir(dir)); ok {
+			ui.Info("Downloading %s in %d package(s), %s free in %s", ui.FormatSize(total), len(urls), ui.FormatSize(free), dir)
+			if free < total {
+				return fmt.Errorf("not enough disk space in %s: %s to download, %s free", dir, ui.FormatSize(total), ui.FormatSize(free))
+			}
+		} else {
+			ui.Info("Downloading %s in %d package(s)", ui.FormatSize(total), len(urls))
+		}
+	}
+	if total > ConfirmAbove && !AssumeYes && ui.Interactive() {
+		if !ui.Confirm("Download %s of packages? (--yes skips this)", ui.FormatSize(total)) {
+			return ErrDownloadDeclined
+		}
+	}
+	for _, u := range urls {
+		approved.urls[u] = true
+	}
+	return nil
+}
+
+// existingDir returns dir or its nearest existing parent.
+func existingDir(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,44 @@
+package build
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConfirmDownload(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	t.Run("approved once", func(t *testing.T) {
+		pkgs := []*Package{{URL: "https://example.com/confirm-a.tar.gz"}}
+		sizes := map[string]int64{pkgs[0].URL: 1 << 20}
+		if err := confirmDownload(pkgs, sizes); err != nil {
+			t.Fatalf("confirmDownload() error = %v", err)
+		}
+		if !approved.urls[pkgs[0].URL] {
+			t.Error("package not recorded as approved")
+		}
+		// Even an impossible size passes once approved.
+		sizes[pkgs[0].URL] = 1 << 62
+		if err := confirmDownload(pkgs, sizes); err != nil {
+			t.Errorf("confirmDownload() again error = %v", err)
+		}
+	})
+
+	t.Run("not enough space", func(t *testing.T) {
+		if _, ok := freeSpace(t.TempDir()); !ok {
+			t.Skip("free space unknown on this platform")
+		}
+		pkgs := []*Package{{URL: "https://example.com/confirm-b.tar.gz"}}
+		err := confirmDownload(pkgs, map[string]int64{pkgs[0].URL: 1 << 62})
+		if err == nil || errors.Is(err, ErrDownloadDeclined) {
+			t.Errorf("confirmDownload() error = %v, want disk space error", err)
+		}
+	})
+}
+
+func TestExistingDir(t *testing.T) {
+	dir := t.TempDir()
+	if got := existingDir(dir + "/a/b/c"); got != dir {
+		t.Errorf("existingDir() = %q, want %q", got, dir)
+	}
+}
@@ -0,0 +1,255 @@
+package build
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/qntx/gox/internal/archive"
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/uiutil"
+)
+
+// copyJob is a single file or symlink scheduled for copying into the prefix.
+type copyJob struct {
+	src  string
+	dst  string
+	mode os.FileMode
+	size int64
+	link string // preserved symlink target, empty to materialize
+}
+
+// CopyStats summarizes a completed copy.
+type CopyStats struct {
+	Files int
+	Bytes int64
+}
+
+// dstLocks serializes copies to the same destination path, which parallel
+// targets sharing a prefix would otherwise write concurrently.
+var dstLocks sync.Map // absolute dst -> *sync.Mutex
+
+func lockDst(dst string) func() {
+	if abs, err := filepath.Abs(dst); err == nil {
+		dst = abs
+	}
+	v, _ := dstLocks.LoadOrStore(dst, new(sync.Mutex))
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+const (
+	// copyProgressThreshold is the total size above which copies show a progress bar.
+	copyProgressThreshold = 64 << 20
+	maxCopyWorkers        = 8
+)
+
+// planCopy lists the files under src to be copied into dst, recursing into
+// subdirectories and skipping entries matching any exclude pattern.
+func planCopy(src, dst string, exclude []string) ([]copyJob, error) {
+	var jobs []copyJob
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		if excluded(rel, exclude) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		job := copyJob{
+			src:  p,
+			dst:  filepath.Join(dst, rel),
+			mode: info.Mode(),
+			size: info.Size(),
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			job.link = localLink(src, p)
+		}
+		jobs = append(jobs, job)
+		return nil
+	})
+	return jobs, err
+}
+
+// excluded reports whether rel (or its base name) matches any pattern.
+func excluded(rel string, patterns []string) bool {
+	return archive.Excluded(filepath.ToSlash(rel), patterns)
+}
+
+// localLink returns the target of symlink p if it is relative and resolves
+// inside root (e.g. libfoo.so -> libfoo.so.1), so it can be preserved as a
+// link instead of duplicating the file. Otherwise it returns "".
+func localLink(root, p string) string {
+	target, err := os.Readlink(p)
+	if err != nil || filepath.IsAbs(target) {
+		return ""
+	}
+	rel, err := filepath.Rel(root, filepath.Join(filepath.Dir(p), target))
+	if err != nil || !filepath.IsLocal(rel) {
+		return ""
+	}
+	return target
+}
+
+// copyAll executes jobs concurrently, showing progress for large copies.
+// With CopyHardlink or CopySymlink, files are linked to their cached source
+// and fall back to a real copy when linking fails. Of jobs sharing a
+// destination only the first runs, as the first lib dir wins when linking.
+func copyAll(jobs []copyJob, mode CopyMode) (CopyStats, error) {
+	seen := make(map[string]bool, len(jobs))
+	jobs = slices.DeleteFunc(slices.Clone(jobs), func(j copyJob) bool {
+		dup := seen[j.dst]
+		seen[j.dst] = true
+		return dup
+	})
+	var stats CopyStats
+	for _, j := range jobs {
+		stats.Files++
+		if j.mode.IsRegular() {
+			stats.Bytes += j.size
+		}
+	}
+	if len(jobs) == 0 {
+		return stats, nil
+	}
+
+	var (
+		progress *ui.Progress
+		proxy    func(io.Reader) io.Reader
+		bar      uiutil.Bar
+	)
+	if stats.Bytes > copyProgressThreshold && (mode == "" || mode == CopyFiles) {
+		progress = ui.NewProgress()
+		bar = progress.AddBar("libs", stats.Bytes)
+		proxy = bar.ProxyReader
+	}
+
+	ch := make(chan copyJob)
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	for range min(runtime.NumCPU(), maxCopyWorkers, len(jobs)) {
+		wg.Go(func() {
+			for j := range ch {
+				if err := j.run(mode, proxy); err != nil {
+					once.Do(func() { first = err })
+				}
+			}
+		})
+	}
+	for _, j := range jobs {
+		ch <- j
+	}
+	close(ch)
+	wg.Wait()
+
+	if progress != nil {
+		if first != nil {
+			bar.Abort(true)
+		} else {
+			bar.Complete()
+		}
+		progress.Wait()
+	}
+	return stats, first
+}
+
+func (j copyJob) run(mode CopyMode, proxy func(io.Reader) io.Reader) error {
+	defer lockDst(j.dst)()
+	if err := os.MkdirAll(filepath.Dir(j.dst), 0o755); err != nil {
+		return err
+	}
+	if j.link != "" {
+		return copySymlink(j.src, j.dst, j.link)
+	}
+	if mode == CopyHardlink || mode == CopySymlink {
+		if linkFile(j.src, j.dst, mode) == nil {
+			return nil
+		}
+	}
+	if j.mode&os.ModeSymlink != 0 {
+		return copySymlink(j.src, j.dst, "")
+	}
+	return copyFile(j.src, j.dst, j.mode, proxy)
+}
+
+// linkFile links dst to the resolved file behind src.
+func linkFile(src, dst string, mode CopyMode) error {
+	target, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return err
+	}
+	_ = os.Remove(dst)
+	if mode == CopyHardlink {
+		return os.Link(target, dst)
+	}
+	if target, err = filepath.Abs(target); err != nil {
+		return err
+	}
+	return os.Symlink(target, dst)
+}
+
+// copySymlink recreates src at dst as a link to target when target is set
+// and symlinks are supported; otherwise the resolved file is materialized.
+func copySymlink(src, dst, target string) error {
+	_ = os.Remove(dst)
+	if target != "" && os.Symlink(target, dst) == nil {
+		return nil
+	}
+	return copyFile(src, dst, 0, nil)
+}
+
+func copyFile(src, dst string, mode os.FileMode, proxy func(io.Reader) io.Reader) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if mode == 0 {
+		if fi, err := in.Stat(); err == nil {
+			mode = fi.Mode()
+		} else {
+			mode = 0o644
+		}
+	}
+
+	// dst may be a link into the package cache left by an earlier build in
+	// a link mode; writing through it would truncate the cached file.
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	r := io.Reader(in)
+	if proxy != nil {
+		r = proxy(r)
+	}
+	_, err = io.Copy(out, r)
+	if e := out.Close(); err == nil {
+		err = e
+	}
+	return err
+}
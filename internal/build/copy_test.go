@@ -0,0 +1,261 @@
+package build
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestPlanCopy(t *testing.T) {
+	src := t.TempDir()
+	for _, name := range []string{"libfoo.so", "x64/libbar.so", "plugins/a/libplug.so", "libfoo.pdb", "cmake/foo.cmake"} {
+		p := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("elf"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jobs, err := planCopy(src, "/dst", []string{"*.pdb", "cmake"})
+	if err != nil {
+		t.Fatalf("planCopy() error = %v", err)
+	}
+
+	got := make(map[string]bool)
+	for _, j := range jobs {
+		got[j.dst] = true
+		if j.size != 3 {
+			t.Errorf("%s size = %d, want 3", j.dst, j.size)
+		}
+	}
+	want := []string{"libfoo.so", "x64/libbar.so", "plugins/a/libplug.so"}
+	if len(jobs) != len(want) {
+		t.Errorf("len(jobs) = %d, want %d", len(jobs), len(want))
+	}
+	for _, w := range want {
+		if !got[filepath.Join("/dst", filepath.FromSlash(w))] {
+			t.Errorf("missing job for %s", w)
+		}
+	}
+}
+
+func TestExcluded(t *testing.T) {
+	tests := []struct {
+		rel      string
+		patterns []string
+		want     bool
+	}{
+		{"libfoo.pdb", []string{"*.pdb"}, true},
+		{"x64/libfoo.pdb", []string{"*.pdb"}, true},
+		{"cmake", []string{"cmake"}, true},
+		{"pkgconfig/foo.pc", []string{"pkgconfig/*"}, true},
+		{"libfoo.so", []string{"*.pdb"}, false},
+		{"libfoo.so", nil, false},
+	}
+	for _, tt := range tests {
+		if got := excluded(tt.rel, tt.patterns); got != tt.want {
+			t.Errorf("excluded(%q, %v) = %v, want %v", tt.rel, tt.patterns, got, tt.want)
+		}
+	}
+}
+
+func TestCopyAll(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	files := map[string]string{"a.so": "aaaa", "b.so": "bb", "c.dll": "c"}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jobs, err := planCopy(src, dst, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := copyAll(jobs, CopyFiles)
+	if err != nil {
+		t.Fatalf("copyAll() error = %v", err)
+	}
+	if stats.Files != 3 {
+		t.Errorf("Files = %d, want 3", stats.Files)
+	}
+	if stats.Bytes != 7 {
+		t.Errorf("Bytes = %d, want 7", stats.Bytes)
+	}
+	for name, content := range files {
+		data, err := os.ReadFile(filepath.Join(dst, name))
+		if err != nil {
+			t.Errorf("read %s: %v", name, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", name, data, content)
+		}
+	}
+}
+
+func TestCopyAll_SameDestination(t *testing.T) {
+	dst := t.TempDir()
+	var srcs []string
+	for _, content := range []string{"first", "second"} {
+		src := t.TempDir()
+		if err := os.WriteFile(filepath.Join(src, "libfoo.so"), bytes.Repeat([]byte(content), 1<<16), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		srcs = append(srcs, src)
+	}
+	var jobs []copyJob
+	for _, src := range srcs {
+		j, err := planCopy(src, dst, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jobs = append(jobs, j...)
+	}
+
+	// The first lib dir wins within one copy.
+	stats, err := copyAll(jobs, CopyFiles)
+	if err != nil {
+		t.Fatalf("copyAll() error = %v", err)
+	}
+	if stats.Files != 1 {
+		t.Errorf("Files = %d, want 1", stats.Files)
+	}
+	data, err := os.ReadFile(filepath.Join(dst, "libfoo.so"))
+	if err != nil || !bytes.Equal(data, bytes.Repeat([]byte("first"), 1<<16)) {
+		t.Errorf("libfoo.so = %d bytes, %v, want the first lib dir's copy", len(data), err)
+	}
+
+	// Parallel targets sharing a prefix leave one whole copy behind.
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			if _, err := copyAll(jobs[i%2:i%2+1], CopyFiles); err != nil {
+				t.Errorf("copyAll() error = %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	data, err = os.ReadFile(filepath.Join(dst, "libfoo.so"))
+	if err != nil || len(data) != 5<<16 && len(data) != 6<<16 {
+		t.Errorf("libfoo.so = %d bytes, %v, want one whole copy", len(data), err)
+	}
+}
+
+func TestCopyAll_Empty(t *testing.T) {
+	stats, err := copyAll(nil, CopyFiles)
+	if err != nil {
+		t.Fatalf("copyAll() error = %v", err)
+	}
+	if stats.Files != 0 || stats.Bytes != 0 {
+		t.Errorf("stats = %+v, want zero", stats)
+	}
+}
+
+func TestCopyAll_PreservesSymlinkChain(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "libfoo.so.1.2.3"), []byte("elf"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("libfoo.so.1.2.3", filepath.Join(src, "libfoo.so.1")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	if err := os.Symlink("libfoo.so.1", filepath.Join(src, "libfoo.so")); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(t.TempDir(), "libbar.so")
+	if err := os.WriteFile(outside, []byte("bar"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(src, "libbar.so")); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err := planCopy(src, dst, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := copyAll(jobs, CopyFiles); err != nil {
+		t.Fatalf("copyAll() error = %v", err)
+	}
+
+	for name, want := range map[string]string{"libfoo.so": "libfoo.so.1", "libfoo.so.1": "libfoo.so.1.2.3"} {
+		got, err := os.Readlink(filepath.Join(dst, name))
+		if err != nil {
+			t.Errorf("%s: not a symlink: %v", name, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s -> %q, want %q", name, got, want)
+		}
+	}
+
+	info, err := os.Lstat(filepath.Join(dst, "libbar.so"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Mode().IsRegular() {
+		t.Errorf("absolute link should be materialized, got mode %v", info.Mode())
+	}
+}
+
+func TestLocalLink(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		target string
+		want   string
+	}{
+		{"libfoo.so", "libfoo.so.1", "libfoo.so.1"},
+		{"sub/libfoo.so", "../libfoo.so.1", "../libfoo.so.1"},
+		{"libbar.so", "../outside.so", ""},
+		{"libabs.so", "/usr/lib/libabs.so", ""},
+	}
+	for _, tt := range tests {
+		p := filepath.Join(root, tt.name)
+		if err := os.Symlink(tt.target, p); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+		if got := localLink(root, p); got != tt.want {
+			t.Errorf("localLink(%q -> %q) = %q, want %q", tt.name, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestCopyAll_LinkModes(t *testing.T) {
+	for _, mode := range []CopyMode{CopyHardlink, CopySymlink} {
+		t.Run(string(mode), func(t *testing.T) {
+			src, dst := t.TempDir(), t.TempDir()
+			if err := os.WriteFile(filepath.Join(src, "libfoo.so"), []byte("elf"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			jobs, err := planCopy(src, dst, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := copyAll(jobs, mode); err != nil {
+				t.Fatalf("copyAll() error = %v", err)
+			}
+
+			srcInfo, err := os.Stat(filepath.Join(src, "libfoo.so"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			dstInfo, err := os.Stat(filepath.Join(dst, "libfoo.so"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !os.SameFile(srcInfo, dstInfo) {
+				t.Error("dst should refer to the cached source file")
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !(linux || darwin || freebsd || windows)
+
+package build
+
+// freeSpace is not available on this platform.
+func freeSpace(string) (int64, bool) {
+	return 0, false
+}
@@ -0,0 +1,143 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/qntx/gox/internal/hosttool"
+)
+
+// binfmtDir holds the kernel's binfmt_misc registrations.
+var binfmtDir = "/proc/sys/fs/binfmt_misc"
+
+// Emulator runs binaries the host cannot execute itself: linux binaries of
+// another architecture through qemu-user, invoked directly or through the
+// binfmt_misc handler the kernel starts for them, and windows binaries
+// through wine.
+type Emulator struct {
+	Exec    []string // program the binary is passed to; empty with binfmt_misc
+	Sysroot string   // root filesystem providing the loader and libc, if any
+	DLLDirs []string // directories wine searches for DLLs, passed as WINEPATH
+}
+
+// NewEmulator returns the emulator for running opts' binaries on this
+// host. For linux, qemu-<arch> in PATH or [tools] is preferred over
+// binfmt_misc.
+func NewEmulator(opts *Options) (*Emulator, error) {
+	switch {
+	case opts.GOOS == "windows" && runtime.GOOS != "windows":
+		return newWine(opts)
+	case opts.GOOS != "linux" || runtime.GOOS != "linux":
+		return nil, fmt.Errorf("no emulator for %s binaries on %s", opts.GOOS, runtime.GOOS)
+	}
+	arch, ok := qemuArch[opts.GOARCH]
+	if !ok {
+		return nil, fmt.Errorf("emulation does not support %s", opts.GOARCH)
+	}
+	e := &Emulator{Sysroot: opts.Sysroot}
+	qemu := "qemu-" + arch
+	if path, err := hosttool.Path(qemu); err == nil {
+		e.Exec = []string{path}
+		return e, nil
+	}
+	if binfmtEnabled(qemu) {
+		return e, nil
+	}
+	return nil, fmt.Errorf("install %s (qemu-user) or register it with binfmt_misc", qemu)
+}
+
+// newWine returns wine as the emulator of windows binaries, which it runs
+// for x86 only.
+func newWine(opts *Options) (*Emulator, error) {
+	if runtime.GOARCH != "amd64" || (opts.GOARCH != "amd64" && opts.GOARCH != "386") {
+		return nil, fmt.Errorf("wine runs windows/%s binaries only on amd64 hosts", opts.GOARCH)
+	}
+	path, err := hosttool.Path("wine")
+	if err != nil {
+		return nil, err
+	}
+	return &Emulator{Exec: []string{path}}, nil
+}
+
+// binfmtEnabled reports whether the kernel has an enabled binfmt_misc
+// handler named name.
+func binfmtEnabled(name string) bool {
+	data, err := os.ReadFile(filepath.Join(binfmtDir, name))
+	return err == nil && strings.HasPrefix(string(data), "enabled")
+}
+
+// Check fails early for a dynamically linked ELF binary whose loader exists
+// neither in the sysroot nor on the host, which qemu would only report as
+// a missing file. Other binaries pass.
+func (e *Emulator) Check(bin string) error {
+	interp, _, err := elfDeps(bin)
+	if err != nil || interp == "" {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(e.Sysroot, interp)); err != nil {
+		if e.Sysroot == "" {
+			return fmt.Errorf("loader %s not found: set sysroot to a root filesystem of the target, or use --linkmode static", interp)
+		}
+		return fmt.Errorf("loader %s not found in sysroot %s", interp, e.Sysroot)
+	}
+	return nil
+}
+
+// ExecFlag returns the go test -exec value running test binaries under the
+// emulator, or "" when the kernel runs them itself.
+func (e *Emulator) ExecFlag() string {
+	args := e.Exec
+	if env := e.env(); len(env) > 0 {
+		args = slices.Concat([]string{"env"}, env, args)
+	}
+	if len(args) == 0 {
+		return ""
+	}
+	return quoteExec(args)
+}
+
+// Command returns the command running bin with args under the emulator.
+// A sysroot is passed as QEMU_LD_PREFIX, which binfmt_misc handlers honor
+// too, and DLL directories as WINEPATH.
+func (e *Emulator) Command(ctx context.Context, bin string, args []string) *exec.Cmd {
+	argv := append(slices.Clone(e.Exec), bin)
+	cmd := exec.CommandContext(ctx, argv[0], append(argv[1:], args...)...)
+	if env := e.env(); len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	return cmd
+}
+
+// env returns the variables the emulator is run with.
+func (e *Emulator) env() []string {
+	var env []string
+	if e.Sysroot != "" {
+		env = append(env, "QEMU_LD_PREFIX="+e.Sysroot)
+	}
+	if len(e.DLLDirs) > 0 {
+		env = append(env, "WINEPATH="+winePath(e.DLLDirs))
+	}
+	return env
+}
+
+// winePath returns dirs as a WINEPATH value: windows paths on the Z: drive,
+// which wine maps to the host root, followed by any WINEPATH already set.
+func winePath(dirs []string) string {
+	parts := make([]string, 0, len(dirs)+1)
+	for _, d := range dirs {
+		if abs, err := filepath.Abs(d); err == nil {
+			d = abs
+		}
+		parts = append(parts, "Z:"+strings.ReplaceAll(filepath.ToSlash(d), "/", `\`))
+	}
+	if old := os.Getenv("WINEPATH"); old != "" {
+		parts = append(parts, old)
+	}
+	return strings.Join(parts, ";")
+}
@@ -0,0 +1,155 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/qntx/gox/internal/hosttool"
+)
+
+func TestNewEmulator(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("emulation needs a linux host")
+	}
+	t.Cleanup(func() { _ = hosttool.Use(nil) })
+	bin := t.TempDir()
+	qemu, wine := filepath.Join(bin, "qemu-riscv64"), filepath.Join(bin, "wine")
+	for _, p := range []string{qemu, wine} {
+		if err := os.WriteFile(p, []byte("#!/bin/sh\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := hosttool.Use(map[string]string{"qemu-riscv64": qemu, "wine": wine}); err != nil {
+		t.Fatal(err)
+	}
+	binfmt := t.TempDir()
+	old := binfmtDir
+	binfmtDir = binfmt
+	t.Cleanup(func() { binfmtDir = old })
+	for name, state := range map[string]string{"qemu-s390x": "enabled\ninterpreter /usr/bin/qemu-s390x\n", "qemu-ppc64le": "disabled\n"} {
+		if err := os.WriteFile(filepath.Join(binfmt, name), []byte(state), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", t.TempDir())
+
+	wantWine, wineErr := []string{wine}, ""
+	if runtime.GOARCH != "amd64" {
+		wantWine, wineErr = nil, "only on amd64 hosts"
+	}
+	tests := []struct {
+		name     string
+		opts     Options
+		wantExec []string
+		wantErr  string
+	}{
+		{"qemu", Options{GOOS: "linux", GOARCH: "riscv64"}, []string{qemu}, ""},
+		{"binfmt", Options{GOOS: "linux", GOARCH: "s390x"}, nil, ""},
+		{"binfmt disabled", Options{GOOS: "linux", GOARCH: "ppc64le"}, nil, "install qemu-ppc64le"},
+		{"missing", Options{GOOS: "linux", GOARCH: "loong64"}, nil, "install qemu-loongarch64"},
+		{"wine", Options{GOOS: "windows", GOARCH: "386"}, wantWine, wineErr},
+		{"wine arm64", Options{GOOS: "windows", GOARCH: "arm64"}, nil, "only on amd64 hosts"},
+		{"darwin", Options{GOOS: "darwin", GOARCH: "arm64"}, nil, "no emulator for darwin"},
+		{"unknown arch", Options{GOOS: "linux", GOARCH: "mips"}, nil, "does not support mips"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emu, err := NewEmulator(&tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewEmulator() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(emu.Exec, tt.wantExec) {
+				t.Errorf("Exec = %v, want %v", emu.Exec, tt.wantExec)
+			}
+		})
+	}
+}
+
+func TestEmulator_Command(t *testing.T) {
+	e := &Emulator{Exec: []string{"/usr/bin/qemu-aarch64"}, Sysroot: "/srv/arm64"}
+	cmd := e.Command(t.Context(), "/tmp/app", []string{"-v"})
+	if want := []string{"/usr/bin/qemu-aarch64", "/tmp/app", "-v"}; !slices.Equal(cmd.Args, want) {
+		t.Errorf("Args = %v, want %v", cmd.Args, want)
+	}
+	if !slices.Contains(cmd.Env, "QEMU_LD_PREFIX=/srv/arm64") {
+		t.Error("Env lacks QEMU_LD_PREFIX")
+	}
+
+	cmd = (&Emulator{}).Command(t.Context(), "/tmp/app", nil)
+	if want := []string{"/tmp/app"}; !slices.Equal(cmd.Args, want) || cmd.Env != nil {
+		t.Errorf("binfmt command = %v (env %v), want %v", cmd.Args, cmd.Env, want)
+	}
+}
+
+func TestEmulator_ExecFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		emu  Emulator
+		want string
+	}{
+		{"qemu", Emulator{Exec: []string{"/usr/bin/qemu-aarch64"}}, "/usr/bin/qemu-aarch64"},
+		{"qemu sysroot", Emulator{Exec: []string{"/usr/bin/qemu-aarch64"}, Sysroot: "/srv/my root"}, "env 'QEMU_LD_PREFIX=/srv/my root' /usr/bin/qemu-aarch64"},
+		{"binfmt", Emulator{}, ""},
+		{"binfmt sysroot", Emulator{Sysroot: "/srv/arm64"}, "env QEMU_LD_PREFIX=/srv/arm64"},
+		{"wine dlls", Emulator{Exec: []string{"/usr/bin/wine"}, DLLDirs: []string{"/pkg/bin"}}, `env WINEPATH=Z:\pkg\bin /usr/bin/wine`},
+	}
+	t.Setenv("WINEPATH", "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.emu.DLLDirs != nil && runtime.GOOS == "windows" {
+				t.Skip("wine paths are built on unix hosts")
+			}
+			if got := tt.emu.ExecFlag(); got != tt.want {
+				t.Errorf("ExecFlag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWinePath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("wine paths are built on unix hosts")
+	}
+	t.Setenv("WINEPATH", "")
+	if got, want := winePath([]string{"/cache/sqlite/bin", "/opt/x/bin"}), `Z:\cache\sqlite\bin;Z:\opt\x\bin`; got != want {
+		t.Errorf("winePath() = %q, want %q", got, want)
+	}
+	t.Setenv("WINEPATH", `C:\tools`)
+	if got, want := winePath([]string{"/opt/x/bin"}), `Z:\opt\x\bin;C:\tools`; got != want {
+		t.Errorf("winePath() with WINEPATH = %q, want %q", got, want)
+	}
+}
+
+func TestEmulator_Check(t *testing.T) {
+	bin := "/bin/ls"
+	interp, _, err := elfDeps(bin)
+	if err != nil || interp == "" {
+		t.Skipf("%s is not a dynamic ELF binary", bin)
+	}
+	if err := (&Emulator{}).Check(bin); err != nil {
+		t.Errorf("Check() with host loader = %v", err)
+	}
+	err = (&Emulator{Sysroot: t.TempDir()}).Check(bin)
+	if err == nil || !strings.Contains(err.Error(), "not found in sysroot") {
+		t.Errorf("Check() with empty sysroot = %v", err)
+	}
+}
+
+func TestBuilder_TestArgsExec(t *testing.T) {
+	opts := &Options{GOOS: "linux", GOARCH: "arm64", TestExec: "qemu-aarch64 -L /srv/arm64"}
+	args := New("", opts).testArgs([]string{"./..."}, []string{"-v"})
+	want := []string{"test", "-exec", "qemu-aarch64 -L /srv/arm64", "./...", "-v"}
+	if !slices.Equal(args, want) {
+		t.Errorf("testArgs() = %q, want %q", args, want)
+	}
+}
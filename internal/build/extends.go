@@ -0,0 +1,263 @@
+package build
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/qntx/gox/internal/cache"
+	"github.com/qntx/gox/internal/httpclient"
+	"github.com/qntx/gox/internal/ui"
+)
+
+const (
+	maxExtendsDepth = 5
+	extendsTimeout  = 30 * time.Second
+)
+
+// resolveExtends loads the config named by c.Extends and merges it beneath
+// c. from is where c was loaded from: the directory of a file, which a
+// relative extends path is joined to, or a URL, which it is resolved
+// against. A "#sha256:<hex>" suffix pins the expected content digest.
+func (c *Config) resolveExtends(from string, depth int) error {
+	if c.Extends == "" {
+		return nil
+	}
+	if depth >= maxExtendsDepth {
+		return errors.New("too many nested extends")
+	}
+
+	src, digest, _ := strings.Cut(c.Extends, "#sha256:")
+	if isURL(from) && !isURL(src) {
+		// A remote config only reaches other remote files, never local ones.
+		parent, err := url.Parse(from)
+		if err != nil {
+			return err
+		}
+		ref, err := url.Parse(filepath.ToSlash(src))
+		if err != nil {
+			return fmt.Errorf("%s: %w", src, err)
+		}
+		src = parent.ResolveReference(ref).String()
+	}
+	data, err := readExtends(src, from, digest)
+	if err != nil {
+		return fmt.Errorf("%s: %w", src, err)
+	}
+
+	var base Config
+	if err := toml.Unmarshal(data, &base); err != nil {
+		return fmt.Errorf("%s: %w", src, err)
+	}
+	next := src
+	if !isURL(src) {
+		next = filepath.Dir(filepath.Join(from, src))
+	} else if err := base.checkRemote(digest != ""); err != nil {
+		return fmt.Errorf("%s: %w", src, err)
+	}
+	if err := base.resolveExtends(next, depth+1); err != nil {
+		return err
+	}
+	c.inherit(&base)
+	return nil
+}
+
+// checkRemote rejects what a config fetched from a URL may not set: paths
+// to local files, which would be read from the directory of the config
+// extending it, and, unless its content is pinned, the keys that run
+// programs on the build host.
+func (c *Config) checkRemote(pinned bool) error {
+	for _, p := range c.Default.Plugins {
+		if strings.ContainsAny(p, `/\`) {
+			return fmt.Errorf("plugin %s is a local path, which a remote config cannot name", p)
+		}
+	}
+	if c.Windows.Icon != "" || c.Windows.Manifest != "" {
+		return errors.New("windows icon and manifest are local files, which a remote config cannot name")
+	}
+	if pinned {
+		return nil
+	}
+	var keys []string
+	if c.Default.Sign.Command != "" || slices.ContainsFunc(c.Targets, func(t ConfigTarget) bool { return t.Sign.Command != "" }) {
+		keys = append(keys, "sign.command")
+	}
+	if len(c.Default.Plugins) > 0 {
+		keys = append(keys, "plugins")
+	}
+	if c.Default.ZigPath != "" {
+		keys = append(keys, "zig-path")
+	}
+	if len(c.Tools) > 0 {
+		keys = append(keys, "[tools]")
+	}
+	if len(keys) > 0 {
+		return fmt.Errorf("sets %s, which run programs on this machine: pin its content with #sha256:<digest>", strings.Join(keys, ", "))
+	}
+	return nil
+}
+
+// inherit fills c from base: scalar defaults apply where c leaves them unset,
+// lists are concatenated (base first), mirrors, tools, [windows], [version],
+// [package] and [scoop] are merged with c winning, and base targets not
+// redefined in c are kept ahead of c's own targets.
+func (c *Config) inherit(base *Config) {
+	d, b := &c.Default, &base.Default
+	d.ZigVersion = cmp.Or(d.ZigVersion, b.ZigVersion)
+	d.GoVersion = cmp.Or(d.GoVersion, b.GoVersion)
+	d.CacheScope = cmp.Or(d.CacheScope, b.CacheScope)
+	d.Theme = cmp.Or(d.Theme, b.Theme)
+	d.ZigMirror = cmp.Or(d.ZigMirror, b.ZigMirror)
+	d.ZigPath = cmp.Or(d.ZigPath, b.ZigPath)
+	d.ConfirmDownload = cmp.Or(d.ConfirmDownload, b.ConfirmDownload)
+	if d.Parallel == nil {
+		d.Parallel = b.Parallel
+	}
+	d.LinkMode = cmp.Or(d.LinkMode, b.LinkMode)
+	d.BuildMode = cmp.Or(d.BuildMode, b.BuildMode)
+	d.Include = mergeSlices(b.Include, d.Include)
+	d.Lib = mergeSlices(b.Lib, d.Lib)
+	d.Link = mergeSlices(b.Link, d.Link)
+	d.LibExclude = mergeSlices(b.LibExclude, d.LibExclude)
+	d.Packages = mergeSlices(b.Packages, d.Packages)
+	d.Flags = mergeSlices(b.Flags, d.Flags)
+	d.Layout = d.Layout.Merge(b.Layout)
+	d.Retry = d.Retry.Merge(b.Retry)
+	d.Sysroot = cmp.Or(d.Sysroot, b.Sysroot)
+	d.MacOSSDK = cmp.Or(d.MacOSSDK, b.MacOSSDK)
+	d.IOSSDK = cmp.Or(d.IOSSDK, b.IOSSDK)
+	d.AndroidNDK = cmp.Or(d.AndroidNDK, b.AndroidNDK)
+	d.AndroidAPI = cmp.Or(d.AndroidAPI, b.AndroidAPI)
+	d.Sign = d.Sign.Merge(b.Sign)
+	d.DepsReport = d.DepsReport || b.DepsReport
+	d.PkgConfig = d.PkgConfig || b.PkgConfig
+	d.Checksum = d.Checksum || b.Checksum
+	d.IsolateGoCache = d.IsolateGoCache || b.IsolateGoCache
+	d.Reproducible = d.Reproducible || b.Reproducible
+	d.Strip = d.Strip || b.Strip
+	d.Verbose = d.Verbose || b.Verbose
+	d.ZigMinisign = d.ZigMinisign || b.ZigMinisign
+	d.ExtractUmask = cmp.Or(d.ExtractUmask, b.ExtractUmask)
+	d.ExtractFaithful = d.ExtractFaithful || b.ExtractFaithful
+	d.WatchIgnore = mergeSlices(b.WatchIgnore, d.WatchIgnore)
+	d.Vendor = d.Vendor || b.Vendor
+	d.Plugins = mergeSlices(b.Plugins, d.Plugins)
+	c.Mirrors = mergeMirrors(c.Mirrors, base.Mirrors)
+	c.Tools = mergeTools(c.Tools, base.Tools)
+	c.Modules = mergeModules(c.Modules, base.Modules)
+	c.Windows = c.Windows.Merge(base.Windows)
+	c.Version = c.Version.Merge(base.Version)
+	c.Package = c.Package.Merge(base.Package)
+	c.Scoop = c.Scoop.Merge(base.Scoop)
+
+	var targets []ConfigTarget
+	for _, t := range base.Targets {
+		if !c.hasTarget(t.Name) {
+			targets = append(targets, t)
+		}
+	}
+	c.Targets = append(targets, c.Targets...)
+}
+
+func (c *Config) hasTarget(name string) bool {
+	for _, t := range c.Targets {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// readExtends returns the content of an extends source. Remote sources are
+// cached; a cached copy matching the pinned digest is used without network
+// access, and any cached copy is used as a fallback when fetching fails.
+func readExtends(src, dir, digest string) ([]byte, error) {
+	if !isURL(src) {
+		data, err := os.ReadFile(filepath.Join(dir, src))
+		if err != nil {
+			return nil, err
+		}
+		return data, verifyDigest(data, digest)
+	}
+
+	cached := filepath.Join(configCacheDir(), urlHash(src)+".toml")
+	if digest != "" {
+		if data, err := os.ReadFile(cached); err == nil && verifyDigest(data, digest) == nil {
+			return data, nil
+		}
+	}
+
+	data, err := fetchExtends(src)
+	if err != nil {
+		stale, rerr := os.ReadFile(cached)
+		if rerr != nil || verifyDigest(stale, digest) != nil {
+			return nil, err
+		}
+		ui.Warn("Using cached %s: %v", src, err)
+		return stale, nil
+	}
+	if err := verifyDigest(data, digest); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(cached), 0o755); err == nil {
+		_ = os.WriteFile(cached, data, 0o644)
+	}
+	return data, nil
+}
+
+func fetchExtends(url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), extendsTimeout)
+	defer cancel()
+
+	var data []byte
+	err := httpclient.Retry(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := httpclient.Client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return httpclient.NewStatusError(resp)
+		}
+		data, err = io.ReadAll(resp.Body)
+		return err
+	})
+	return data, err
+}
+
+func verifyDigest(data []byte, want string) error {
+	if want == "" {
+		return nil
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, want) {
+		return fmt.Errorf("sha256 mismatch: got %s, want %s", got, want)
+	}
+	return nil
+}
+
+func configCacheDir() string {
+	return cache.Dir("config")
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
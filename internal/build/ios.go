@@ -0,0 +1,45 @@
+package build
+
+import (
+	"context"
+	"errors"
+
+	"github.com/qntx/gox/internal/sdk"
+)
+
+// errNoIOSSDK is returned for ios targets without ios-sdk. Unlike darwin
+// targets they never fall back to $SDKROOT or a registered SDK, which hold
+// macOS SDKs.
+var errNoIOSSDK = errors.New("no iOS SDK: set ios-sdk to an iPhoneOS.sdk, or an iPhoneSimulator.sdk for simulator targets")
+
+// iosArchs are the GOARCHes of ios targets; amd64 only runs in the
+// simulator.
+var iosArchs = []string{"amd64", "arm64"}
+
+// IsSimulator reports whether an ios target builds for the iOS simulator
+// rather than devices.
+func (o *Options) IsSimulator() bool {
+	return o.GOOS == "ios" && (o.Simulator || o.GOARCH == "amd64")
+}
+
+// isApple reports whether goos is built against an Apple SDK.
+func isApple(goos string) bool {
+	return goos == "darwin" || goos == "ios"
+}
+
+// locateIOSSDK returns the iOS SDK directory spec selects like sdk.Locate.
+func locateIOSSDK(spec string) (string, error) {
+	if spec == "" {
+		return "", errNoIOSSDK
+	}
+	return sdk.Locate(spec)
+}
+
+// resolveIOSSDK returns the iOS SDK directory spec selects like
+// sdk.Resolve, downloading an archive URL on first use.
+func resolveIOSSDK(ctx context.Context, spec string) (string, error) {
+	if spec == "" {
+		return "", errNoIOSSDK
+	}
+	return sdk.Resolve(ctx, spec)
+}
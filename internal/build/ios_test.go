@@ -0,0 +1,72 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOptions_ZigTargetIOS(t *testing.T) {
+	tests := []struct {
+		opts Options
+		want string
+	}{
+		{Options{GOOS: "ios", GOARCH: "arm64"}, "aarch64-ios"},
+		{Options{GOOS: "ios", GOARCH: "arm64", Simulator: true}, "aarch64-ios-simulator"},
+		{Options{GOOS: "ios", GOARCH: "amd64"}, "x86_64-ios-simulator"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.opts.ZigTarget(); got != tt.want {
+				t.Errorf("ZigTarget() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptions_BuildableIOS(t *testing.T) {
+	iosSDK := t.TempDir()
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"no sdk", Options{GOOS: "ios", GOARCH: "arm64"}, "requires an iOS SDK"},
+		{"sdk", Options{GOOS: "ios", GOARCH: "arm64", IOSSDK: iosSDK}, ""},
+		{"simulator", Options{GOOS: "ios", GOARCH: "amd64", IOSSDK: iosSDK}, ""},
+		{"arch", Options{GOOS: "ios", GOARCH: "386", IOSSDK: iosSDK}, "not supported on ios"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Buildable()
+			if tt.want == "" && err != nil {
+				t.Errorf("Buildable() = %v, want nil", err)
+			}
+			if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+				t.Errorf("Buildable() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_SetupIOS(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "iPhoneOS.sdk")
+	for _, d := range []string{filepath.Join(root, "usr", "include"), filepath.Join(root, "System", "Library", "Frameworks")} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b := New("", &Options{GOOS: "ios", GOARCH: "arm64", IOSSDK: root, BuildMode: BuildCArchive, LinkMode: LinkAuto})
+	if err := b.setup(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.cgoFlags(), "-isysroot "+root) {
+		t.Errorf("cgoFlags() = %q, want -isysroot %s", b.cgoFlags(), root)
+	}
+
+	b = New("", &Options{GOOS: "ios", GOARCH: "arm64", LinkMode: LinkAuto})
+	if err := b.setup(t.Context()); err == nil || !strings.Contains(err.Error(), "ios-sdk") {
+		t.Errorf("setup() without ios-sdk = %v, want ios-sdk error", err)
+	}
+}
@@ -0,0 +1,232 @@
+package build
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/qntx/gox/internal/archive"
+	"github.com/qntx/gox/internal/linuxpkg"
+)
+
+// Packaging is the [package] section: the metadata and extra files of the
+// Linux packages pack = "deb", "rpm" and "apk" write. Name and version are templates
+// expanded like output paths.
+type Packaging struct {
+	Name        string   `toml:"name,omitempty"`        // default: the output's base name
+	Version     string   `toml:"version,omitempty"`     // default: {{.Version}}
+	Maintainer  string   `toml:"maintainer,omitempty"`  // "Name <email>"
+	Description string   `toml:"description,omitempty"` // summary line, then details
+	Homepage    string   `toml:"homepage,omitempty"`
+	License     string   `toml:"license,omitempty"`     // SPDX expression
+	Depends     []string `toml:"depends,omitempty"`     // required packages, in the format's syntax
+	Systemd     []string `toml:"systemd,omitempty"`     // unit files, relative to gox.toml
+	PreInstall  string   `toml:"preinstall,omitempty"`  // script, relative to gox.toml
+	PostInstall string   `toml:"postinstall,omitempty"` // script, relative to gox.toml
+	PreRemove   string   `toml:"preremove,omitempty"`   // script, relative to gox.toml
+	PostRemove  string   `toml:"postremove,omitempty"`  // script, relative to gox.toml
+}
+
+// Merge fills unset fields of p from base; lists are concatenated, base
+// first.
+func (p Packaging) Merge(base Packaging) Packaging {
+	p.Name = cmp.Or(p.Name, base.Name)
+	p.Version = cmp.Or(p.Version, base.Version)
+	p.Maintainer = cmp.Or(p.Maintainer, base.Maintainer)
+	p.Description = cmp.Or(p.Description, base.Description)
+	p.Homepage = cmp.Or(p.Homepage, base.Homepage)
+	p.License = cmp.Or(p.License, base.License)
+	p.Depends = mergeSlices(base.Depends, p.Depends)
+	p.Systemd = mergeSlices(base.Systemd, p.Systemd)
+	p.PreInstall = cmp.Or(p.PreInstall, base.PreInstall)
+	p.PostInstall = cmp.Or(p.PostInstall, base.PostInstall)
+	p.PreRemove = cmp.Or(p.PreRemove, base.PreRemove)
+	p.PostRemove = cmp.Or(p.PostRemove, base.PostRemove)
+	return p
+}
+
+// packaging returns the [package] section with relative unit and script
+// paths resolved against the config directory.
+func (c *Config) packaging() Packaging {
+	p := c.Package
+	p.Systemd = append([]string(nil), p.Systemd...)
+	paths := []*string{&p.PreInstall, &p.PostInstall, &p.PreRemove, &p.PostRemove}
+	for i := range p.Systemd {
+		paths = append(paths, &p.Systemd[i])
+	}
+	for _, s := range paths {
+		if *s != "" && !filepath.IsAbs(*s) {
+			*s = filepath.Join(c.dir, *s)
+		}
+	}
+	return p
+}
+
+// linuxPackage returns the package describing the target's output, without
+// its files.
+func (o *Options) linuxPackage() (*linuxpkg.Package, error) {
+	p := o.Packaging
+	src := cmp.Or(o.Prefix, o.Output)
+	data := o.pathData(nil, usesGit(p.Name+cmp.Or(p.Version, "{{.Version}}")))
+	name, err := expandPath("package.name", p.Name, data)
+	if err != nil {
+		return nil, err
+	}
+	version, err := expandPath("package.version", cmp.Or(p.Version, "{{.Version}}"), data)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		base := filepath.Base(src)
+		name = strings.ToLower(strings.TrimSuffix(base, filepath.Ext(base)))
+	}
+	return &linuxpkg.Package{
+		Name:        name,
+		Version:     version,
+		Arch:        o.GOARCH,
+		Maintainer:  p.Maintainer,
+		Description: cmp.Or(p.Description, name+" built with gox"),
+		Homepage:    p.Homepage,
+		License:     p.License,
+		Depends:     p.Depends,
+	}, nil
+}
+
+// packagePath returns the path of the Linux package the target packs into,
+// next to its output, or "" when it has none.
+func (o *Options) packagePath() string {
+	src := cmp.Or(o.Prefix, o.Output)
+	p, err := o.linuxPackage()
+	if src == "" || err != nil {
+		return ""
+	}
+	name := p.DebFile()
+	switch o.PackFormat {
+	case PackRPM:
+		name = p.RPMFile()
+	case PackAPK:
+		name = p.APKFile()
+	}
+	return filepath.Join(filepath.Dir(src), name)
+}
+
+// packageFiles returns the files the target's Linux package installs: a
+// prefix's contents under /usr, or /opt/<name> for a flat layout, less
+// pack.exclude, a lone output as /usr/bin/<name>, and the [package] systemd
+// units.
+func (b *Builder) packageFiles(name string) ([]linuxpkg.File, error) {
+	var files []linuxpkg.File
+	if b.opts.Prefix == "" {
+		out := b.outputPath()
+		files = append(files, linuxpkg.File{Src: out, Dst: "/usr/bin/" + filepath.Base(out), Mode: 0o755})
+	} else {
+		root := "/usr"
+		if b.opts.Layout.IsFlat(b.opts.GOOS) {
+			root = "/opt/" + name
+		}
+		err := filepath.WalkDir(b.opts.Prefix, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(b.opts.Prefix, p)
+			if err != nil {
+				return err
+			}
+			switch {
+			case rel != "." && excluded(rel, b.opts.PackExclude):
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			case d.IsDir():
+				return nil
+			}
+			info, err := os.Stat(p) // files linked from the package cache are copied
+			if err != nil {
+				return err
+			}
+			f := linuxpkg.File{Src: p, Dst: path.Join(root, filepath.ToSlash(rel)), Mode: 0o644}
+			if l, err := os.Readlink(p); err == nil && !filepath.IsAbs(l) && filepath.IsLocal(filepath.Join(filepath.Dir(rel), l)) {
+				f.Mode, f.Link = fs.ModeSymlink|0o777, l
+			} else if info.Mode()&0o111 != 0 {
+				f.Mode = 0o755
+			}
+			files = append(files, f)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, unit := range b.opts.Packaging.Systemd {
+		files = append(files, linuxpkg.File{Src: unit, Dst: "/usr/lib/systemd/system/" + filepath.Base(unit), Mode: 0o644})
+	}
+	return files, nil
+}
+
+// createPackage writes the target's Linux package next to its output and
+// returns its path.
+func (b *Builder) createPackage() (string, error) {
+	p, err := b.opts.linuxPackage()
+	if err != nil {
+		return "", err
+	}
+	if p.Files, err = b.packageFiles(p.Name); err != nil {
+		return "", err
+	}
+	for _, unit := range b.opts.Packaging.Systemd {
+		p.Units = append(p.Units, filepath.Base(unit))
+	}
+	pk := b.opts.Packaging
+	for _, s := range []struct {
+		key, path string
+		data      *[]byte
+	}{
+		{"preinstall", pk.PreInstall, &p.Scripts.PreInstall},
+		{"postinstall", pk.PostInstall, &p.Scripts.PostInstall},
+		{"preremove", pk.PreRemove, &p.Scripts.PreRemove},
+		{"postremove", pk.PostRemove, &p.Scripts.PostRemove},
+	} {
+		if s.path == "" {
+			continue
+		}
+		if *s.data, err = os.ReadFile(s.path); err != nil {
+			return "", fmt.Errorf("package.%s: %w", s.key, err)
+		}
+	}
+	p.ModTime = time.Now()
+	if b.opts.Reproducible {
+		p.ModTime = cmp.Or(b.archiveOptions().ModTime, archive.DeterministicTime)
+	}
+
+	dst := b.opts.packagePath()
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+"-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	write := p.WriteDeb
+	switch b.opts.PackFormat {
+	case PackRPM:
+		write = p.WriteRPM
+	case PackAPK:
+		write = p.WriteAPK
+	}
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	return dst, os.Rename(tmp.Name(), dst)
+}
@@ -0,0 +1,145 @@
+package build
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/qntx/gox/internal/linuxpkg"
+)
+
+func TestBuilder_PackageFiles(t *testing.T) {
+	dir := t.TempDir()
+	prefix := filepath.Join(dir, "dist", "app")
+	outside := filepath.Join(dir, "libbar.so")
+	unit := filepath.Join(dir, "app.service")
+	for path, mode := range map[string]os.FileMode{
+		filepath.Join(prefix, "bin", "app"):           0o700,
+		filepath.Join(prefix, "lib", "libfoo.so.1"):   0o600,
+		filepath.Join(prefix, "include", "app.h"):     0o600,
+		filepath.Join(prefix, "share", "app", "conf"): 0o664,
+		outside: 0o644,
+		unit:    0o644,
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("libfoo.so.1", filepath.Join(prefix, "lib", "libfoo.so")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(prefix, "lib", "libbar.so")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want []linuxpkg.File
+	}{
+		{
+			"prefix", Options{GOOS: "linux", GOARCH: "amd64", Prefix: prefix, Packaging: Packaging{Systemd: []string{unit}}},
+			[]linuxpkg.File{
+				{Src: filepath.Join(prefix, "bin", "app"), Dst: "/usr/bin/app", Mode: 0o755},
+				{Src: filepath.Join(prefix, "include", "app.h"), Dst: "/usr/include/app.h", Mode: 0o644},
+				{Src: filepath.Join(prefix, "lib", "libbar.so"), Dst: "/usr/lib/libbar.so", Mode: 0o644},
+				{Src: filepath.Join(prefix, "lib", "libfoo.so"), Dst: "/usr/lib/libfoo.so", Mode: fs.ModeSymlink | 0o777, Link: "libfoo.so.1"},
+				{Src: filepath.Join(prefix, "lib", "libfoo.so.1"), Dst: "/usr/lib/libfoo.so.1", Mode: 0o644},
+				{Src: filepath.Join(prefix, "share", "app", "conf"), Dst: "/usr/share/app/conf", Mode: 0o644},
+				{Src: unit, Dst: "/usr/lib/systemd/system/app.service", Mode: 0o644},
+			},
+		},
+		{
+			"exclude", Options{GOOS: "linux", GOARCH: "amd64", Prefix: prefix, PackExclude: []string{"include", "libbar.so", "share/**"}},
+			[]linuxpkg.File{
+				{Src: filepath.Join(prefix, "bin", "app"), Dst: "/usr/bin/app", Mode: 0o755},
+				{Src: filepath.Join(prefix, "lib", "libfoo.so"), Dst: "/usr/lib/libfoo.so", Mode: fs.ModeSymlink | 0o777, Link: "libfoo.so.1"},
+				{Src: filepath.Join(prefix, "lib", "libfoo.so.1"), Dst: "/usr/lib/libfoo.so.1", Mode: 0o644},
+			},
+		},
+		{
+			"output", Options{GOOS: "linux", GOARCH: "amd64", Output: filepath.Join(prefix, "bin", "app")},
+			[]linuxpkg.File{{Src: filepath.Join(prefix, "bin", "app"), Dst: "/usr/bin/app", Mode: 0o755}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New("", &tt.opts).packageFiles("app")
+			if err != nil {
+				t.Fatalf("packageFiles() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("packageFiles() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+
+	flat := true
+	opts := Options{GOOS: "linux", GOARCH: "amd64", Prefix: prefix, Layout: Layout{Flat: &flat}}
+	files, err := New("", &opts).packageFiles("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(files[0].Dst, "/opt/app/") {
+		t.Errorf("flat layout installs to %s, want /opt/app", files[0].Dst)
+	}
+}
+
+func TestBuilder_CreatePackage(t *testing.T) {
+	git := gitInfo
+	t.Cleanup(func() { gitInfo = git })
+	gitInfo = func() (string, string) { return "v1.4.0", "abc1234" }
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "App")
+	if err := os.WriteFile(out, []byte("binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{
+		GOOS: "linux", GOARCH: "arm64", Output: out, Pack: true, PackFormat: PackDeb,
+		Packaging: Packaging{Maintainer: "Jane Doe <jane@example.com>"},
+	}
+	want := filepath.Join(dir, "app_1.4.0_arm64.deb")
+	if got := opts.ArchivePath(); got != want {
+		t.Errorf("ArchivePath() = %q, want %q", got, want)
+	}
+	if err := New("", &opts).pack(); err != nil {
+		t.Fatalf("pack() error = %v", err)
+	}
+	data, err := os.ReadFile(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "!<arch>\ndebian-binary") {
+		t.Errorf("%s is not a .deb", want)
+	}
+
+	opts.PackFormat, opts.Packaging.License = PackRPM, "MIT"
+	if err := New("", &opts).pack(); err != nil {
+		t.Fatalf("pack() rpm error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app-1.4.0-1.aarch64.rpm")); err != nil {
+		t.Error(err)
+	}
+
+	opts.PackFormat, opts.LinkMode = PackAPK, LinkStatic
+	if err := New("", &opts).pack(); err != nil {
+		t.Fatalf("pack() apk error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app-1.4.0-r0.apk")); err != nil {
+		t.Error(err)
+	}
+
+	opts.PackFormat = PackDeb
+	opts.Packaging = Packaging{Name: "{{.Target}}", Version: "2.0"}
+	opts.Target = "server"
+	if got, want := filepath.Base(opts.ArchivePath()), "server_2.0_arm64.deb"; got != want {
+		t.Errorf("ArchivePath() with [package] = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,15 @@
+package build
+
+import (
+	"os"
+	"testing"
+
+	"github.com/qntx/gox/internal/workspace"
+)
+
+// TestMain removes the run workspace that download tests create.
+func TestMain(m *testing.M) {
+	code := m.Run()
+	_ = workspace.Cleanup()
+	os.Exit(code)
+}
@@ -0,0 +1,64 @@
+package build
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/qntx/gox/internal/cache"
+)
+
+// DefaultMemoryEstimate is assumed for targets with no recorded build.
+const DefaultMemoryEstimate int64 = 1 << 30
+
+// memoryMu serializes updates to the stats file across parallel builds.
+var memoryMu sync.Mutex
+
+// EstimateMemory returns the peak memory of the last build of o's target,
+// or DefaultMemoryEstimate when none is recorded.
+func EstimateMemory(o *Options) int64 {
+	memoryMu.Lock()
+	defer memoryMu.Unlock()
+	if peak := readMemoryStats()[memoryKey(o)]; peak > 0 {
+		return peak
+	}
+	return DefaultMemoryEstimate
+}
+
+// recordMemory stores the peak memory of a finished build of o's target.
+func recordMemory(o *Options, peak int64) error {
+	memoryMu.Lock()
+	defer memoryMu.Unlock()
+	stats := readMemoryStats()
+	stats[memoryKey(o)] = peak
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := memoryStatsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func readMemoryStats() map[string]int64 {
+	stats := make(map[string]int64)
+	if data, err := os.ReadFile(memoryStatsPath()); err == nil {
+		_ = json.Unmarshal(data, &stats)
+	}
+	return stats
+}
+
+func memoryKey(o *Options) string {
+	key := o.GOOS + "/" + o.GOARCH
+	if o.Target != "" {
+		key += "/" + o.Target
+	}
+	return key
+}
+
+func memoryStatsPath() string {
+	return cache.Dir("stats", "memory.json")
+}
@@ -0,0 +1,24 @@
+package build
+
+import "testing"
+
+func TestEstimateMemory(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	o := &Options{GOOS: "linux", GOARCH: "amd64", Target: "server"}
+	if got := EstimateMemory(o); got != DefaultMemoryEstimate {
+		t.Errorf("EstimateMemory() = %d, want default %d", got, DefaultMemoryEstimate)
+	}
+
+	if err := recordMemory(o, 300<<20); err != nil {
+		t.Fatalf("recordMemory() error = %v", err)
+	}
+	if got := EstimateMemory(o); got != 300<<20 {
+		t.Errorf("EstimateMemory() = %d, want %d", got, 300<<20)
+	}
+
+	other := &Options{GOOS: "linux", GOARCH: "amd64"}
+	if got := EstimateMemory(other); got != DefaultMemoryEstimate {
+		t.Errorf("EstimateMemory(other target) = %d, want default", got)
+	}
+}
@@ -0,0 +1,54 @@
+package build
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// mirror rewrites URLs starting with From to start with To instead.
+type mirror struct {
+	From, To string
+}
+
+// mirrors holds the active [mirrors] rules, longest prefix first.
+var mirrors []mirror
+
+// UseMirrors applies the [mirrors] rewrite rules to package downloads for
+// the rest of the process.
+func (c *Config) UseMirrors() error {
+	rules := make([]mirror, 0, len(c.Mirrors))
+	for from, to := range c.Mirrors {
+		if !isURL(from) || !isURL(to) {
+			return fmt.Errorf("invalid mirror %q = %q: both sides must be http(s) URLs", from, to)
+		}
+		rules = append(rules, mirror{From: from, To: to})
+	}
+	slices.SortFunc(rules, func(a, b mirror) int {
+		return cmp.Or(cmp.Compare(len(b.From), len(a.From)), strings.Compare(a.From, b.From))
+	})
+	mirrors = rules
+	return nil
+}
+
+// mirrorURL applies the longest matching [mirrors] prefix to url.
+func mirrorURL(url string) string {
+	for _, m := range mirrors {
+		if rest, ok := strings.CutPrefix(url, m.From); ok {
+			return m.To + rest
+		}
+	}
+	return url
+}
+
+// mergeMirrors returns base overlaid with c; c wins on the same prefix.
+func mergeMirrors(c, base map[string]string) map[string]string {
+	if len(base) == 0 {
+		return c
+	}
+	out := maps.Clone(base)
+	maps.Copy(out, c)
+	return out
+}
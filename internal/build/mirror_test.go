@@ -0,0 +1,116 @@
+package build
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qntx/gox/internal/archive"
+	"github.com/qntx/gox/internal/lock"
+)
+
+func useMirrors(t *testing.T, m map[string]string) error {
+	t.Helper()
+	t.Cleanup(func() { mirrors = nil })
+	return (&Config{Mirrors: m}).UseMirrors()
+}
+
+func TestMirrorURL(t *testing.T) {
+	if err := useMirrors(t, map[string]string{
+		"https://github.com/":           "https://ghproxy.internal/",
+		"https://github.com/gocnn-lib/": "https://artifacts.internal/gocnn/",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, url, want string
+	}{
+		{"no match", "https://example.com/a.tar.gz", "https://example.com/a.tar.gz"},
+		{"prefix", "https://github.com/foo/bar/releases/download/v1/a.tar.gz", "https://ghproxy.internal/foo/bar/releases/download/v1/a.tar.gz"},
+		{"longest prefix wins", "https://github.com/gocnn-lib/cudart/releases/download/v1/a.tar.xz", "https://artifacts.internal/gocnn/cudart/releases/download/v1/a.tar.xz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mirrorURL(tt.url); got != tt.want {
+				t.Errorf("mirrorURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUseMirrors_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		m    map[string]string
+	}{
+		{"relative target", map[string]string{"https://github.com/": "ghproxy/"}},
+		{"bare host", map[string]string{"github.com/": "https://ghproxy.internal/"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := useMirrors(t, tt.m); err == nil {
+				t.Error("UseMirrors() should reject non-URL rules")
+			}
+		})
+	}
+}
+
+func TestInherit_Mirrors(t *testing.T) {
+	c := &Config{Mirrors: map[string]string{"https://github.com/": "https://local/"}}
+	c.inherit(&Config{Mirrors: map[string]string{
+		"https://github.com/":  "https://base/",
+		"https://example.com/": "https://base-example/",
+	}})
+	if got := c.Mirrors["https://github.com/"]; got != "https://local/" {
+		t.Errorf("local mirror = %q, want override kept", got)
+	}
+	if got := c.Mirrors["https://example.com/"]; got != "https://base-example/" {
+		t.Errorf("inherited mirror = %q, want base entry", got)
+	}
+}
+
+func TestEnsureAll_Mirror(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Cleanup(func() { _ = lock.Use("") })
+
+	src := filepath.Join(t.TempDir(), "pkg")
+	if err := os.MkdirAll(filepath.Join(src, "include"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	tarPath, err := archive.Create(src, "linux", "amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/proxy/") {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, tarPath)
+	}))
+	defer srv.Close()
+
+	// The upstream host does not resolve; only the mirror serves the asset.
+	const upstream = "https://upstream.invalid/"
+	if err := useMirrors(t, map[string]string{upstream: srv.URL + "/proxy/"}); err != nil {
+		t.Fatal(err)
+	}
+	lockPath := filepath.Join(t.TempDir(), lock.File)
+	if err := lock.Use(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EnsureAll(context.Background(), []string{upstream + "pkg.tar.gz"}); err != nil {
+		t.Fatalf("EnsureAll() error = %v", err)
+	}
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), upstream+"pkg.tar.gz") || strings.Contains(string(data), srv.URL) {
+		t.Errorf("lockfile = %q, want upstream URL only", data)
+	}
+}
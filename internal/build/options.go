@@ -1,39 +1,94 @@
 package build
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/qntx/gox/internal/archive"
+	"github.com/qntx/gox/internal/linuxpkg"
+	"github.com/qntx/gox/internal/sdk"
 )
 
 // LinkMode specifies binary linking strategy.
 type LinkMode string
 
+// CopyMode specifies how cached libraries are placed into a prefix.
+type CopyMode string
+
 // Options configures a build operation.
 type Options struct {
-	GOOS        string
-	GOARCH      string
-	Output      string
-	Prefix      string
-	ZigVersion  string
-	LinkMode    LinkMode
-	IncludeDirs []string
-	LibDirs     []string
-	BinDirs     []string
-	Libs        []string
-	Packages    []string
-	BuildFlags  []string
-	NoRpath     bool
-	Pack        bool
-	Strip       bool
-	Verbose     bool
+	Target       string // config target name, used by path templates
+	Variant      string // config variant name, empty for plain targets
+	GOOS         string
+	GOARCH       string
+	Output       string
+	Prefix       string
+	ZigVersion   string
+	GoVersion    string
+	LinkMode     LinkMode
+	BuildMode    BuildMode
+	LibCopy      CopyMode
+	IncludeDirs  []string
+	LibDirs      []string
+	BinDirs      []string
+	Libs         []string
+	LibExclude   []string
+	Packages     []string
+	BuildFlags   []string
+	Layout       Layout
+	Sysroot      string // root filesystem providing libc for sysroot runs
+	MacOSSDK     string // macOS SDK directory, registered version or URL
+	IOSSDK       string // iOS SDK directory, registered version or URL
+	AndroidNDK   string // Android NDK directory for android targets
+	AndroidAPI   int    // Android API level, MinAndroidAPI when zero
+	Sign         Sign   // command signing the binary before it is packed
+	Windows      Windows
+	Version      Version   // variables set to the version, commit and build date
+	Packaging    Packaging // metadata of Linux packages
+	Scoop        Scoop     // manifest written for the windows archives
+	TestExec     string    // go test -exec program for test binaries
+	NoRpath      bool
+	Pack         bool
+	PackFormat   PackFormat // what Pack writes, an archive when empty
+	PackInclude  []string   // extra files for the archive, "pattern[=dst]"
+	PackExclude  []string   // patterns of prefix files left out when packing
+	PackName     string     // archive file name without extension, a template
+	Checksum     bool
+	DepsReport   bool
+	PkgConfig    bool // write <prefix>/lib/pkgconfig/<name>.pc for a library
+	IsolateCache bool // per-GOOS/GOARCH GOCACHE under the gox cache
+	Reproducible bool // trimmed paths, no build ID or VCS stamp, deterministic archives
+	InSysroot    bool // run test binaries inside an assembled sysroot
+	Simulator    bool // build an ios target for the iOS simulator
+	Strip        bool
+	Verbose      bool
+}
+
+// Layout names the directories of a --prefix install. Empty fields use the
+// defaults bin/, lib/ and include/. A flat layout places binaries and shared
+// libraries directly in the prefix root, which is the default for windows.
+type Layout struct {
+	Bin     string `toml:"bin,omitempty"`
+	Lib     string `toml:"lib,omitempty"`
+	Include string `toml:"include,omitempty"`
+	Flat    *bool  `toml:"flat,omitempty"`
 }
 
 const (
 	LinkAuto    LinkMode = "auto"
 	LinkStatic  LinkMode = "static"
 	LinkDynamic LinkMode = "dynamic"
+
+	CopyFiles    CopyMode = "copy"
+	CopyHardlink CopyMode = "hardlink"
+	CopySymlink  CopyMode = "symlink"
 )
 
 var (
@@ -46,14 +101,28 @@ var (
 		"ppc64le": "powerpc64le",
 		"riscv64": "riscv64",
 		"s390x":   "s390x",
+		"wasm":    "wasm32",
 	}
 	zigOS = map[string]string{
+		"android": "linux-android",
 		"darwin":  "macos",
 		"freebsd": "freebsd",
+		"ios":     "ios",
 		"linux":   "linux-gnu",
 		"netbsd":  "netbsd",
+		"wasip1":  "wasi",
 		"windows": "windows-gnu",
 	}
+	// goVersionRe matches Go release versions such as 1.24, 1.24.3 or
+	// 1.25rc1; the minor version is submatch 1.
+	goVersionRe = regexp.MustCompile(`^1\.(\d+)(\.\d+|rc\d+)?$`)
+	// unbuildable lists os/arch pairs the Zig toolchain cannot produce without
+	// SDKs that gox does not ship.
+	unbuildable = map[string]string{
+		"darwin/arm64":  "requires the macOS SDK (CoreFoundation): set macos-sdk or run gox sdk add",
+		"freebsd/arm":   "requires ld.bfd",
+		"freebsd/arm64": "requires ld.bfd",
+	}
 )
 
 func (m LinkMode) Valid() bool {
@@ -64,6 +133,50 @@ func (m LinkMode) IsStatic() bool {
 	return m == LinkStatic
 }
 
+func (m CopyMode) Valid() bool {
+	return m == CopyFiles || m == CopyHardlink || m == CopySymlink
+}
+
+// IsFlat reports whether binaries and libraries share the prefix root.
+func (l Layout) IsFlat(goos string) bool {
+	if l.Flat != nil {
+		return *l.Flat
+	}
+	return goos == "windows"
+}
+
+// BinDir returns the binary directory relative to the prefix.
+func (l Layout) BinDir(goos string) string {
+	if l.IsFlat(goos) {
+		return ""
+	}
+	return cmp.Or(l.Bin, "bin")
+}
+
+// LibDir returns the shared library directory relative to the prefix.
+func (l Layout) LibDir(goos string) string {
+	if l.IsFlat(goos) {
+		return ""
+	}
+	return cmp.Or(l.Lib, "lib")
+}
+
+// IncludeDir returns the header directory relative to the prefix.
+func (l Layout) IncludeDir() string {
+	return cmp.Or(l.Include, "include")
+}
+
+// Merge returns l with empty fields filled from base.
+func (l Layout) Merge(base Layout) Layout {
+	l.Bin = cmp.Or(l.Bin, base.Bin)
+	l.Lib = cmp.Or(l.Lib, base.Lib)
+	l.Include = cmp.Or(l.Include, base.Include)
+	if l.Flat == nil {
+		l.Flat = base.Flat
+	}
+	return l
+}
+
 // Normalize applies defaults for unset fields.
 func (o *Options) Normalize() {
 	if o.GOOS == "" {
@@ -75,6 +188,9 @@ func (o *Options) Normalize() {
 	if o.LinkMode == "" {
 		o.LinkMode = LinkAuto
 	}
+	if o.LibCopy == "" {
+		o.LibCopy = CopyFiles
+	}
 	if o.Prefix != "" {
 		o.Prefix = filepath.Clean(o.Prefix)
 	}
@@ -85,6 +201,32 @@ func (o *Options) Validate() error {
 	if !o.LinkMode.Valid() {
 		return fmt.Errorf("invalid linkmode: %q", o.LinkMode)
 	}
+	if o.LibCopy != "" && !o.LibCopy.Valid() {
+		return fmt.Errorf("invalid lib-copy: %q", o.LibCopy)
+	}
+	if !o.BuildMode.Valid() {
+		return fmt.Errorf("invalid buildmode: %q", o.BuildMode)
+	}
+	if !o.HasCgo() {
+		switch {
+		case o.LinkMode != LinkAuto:
+			return fmt.Errorf("%s has no cgo; linkmode %s does not apply", o.GOOS, o.LinkMode)
+		case o.BuildMode.IsLib():
+			return fmt.Errorf("buildmode %s is not supported on %s", o.BuildMode, o.GOOS)
+		}
+	}
+	if o.Simulator && o.GOOS != "ios" {
+		return errors.New("simulator requires os ios")
+	}
+	if o.GOOS == "ios" && o.BuildMode == BuildCShared {
+		return errors.New("buildmode c-shared is not supported on ios; use c-archive")
+	}
+	if o.AndroidAPI != 0 && o.AndroidAPI < MinAndroidAPI {
+		return fmt.Errorf("android-api %d is below the minimum Go supports (%d)", o.AndroidAPI, MinAndroidAPI)
+	}
+	if o.GoVersion != "" && !validGoVersion(o.GoVersion) {
+		return fmt.Errorf("invalid go-version: %q (want a release GOTOOLCHAIN can fetch, 1.21 or later, e.g. 1.24.3 or 1.25rc1)", o.GoVersion)
+	}
 	if o.Output != "" && o.Prefix != "" {
 		return errors.New("--output and --prefix are mutually exclusive")
 	}
@@ -94,15 +236,174 @@ func (o *Options) Validate() error {
 	if o.Pack && o.Output == "" && o.Prefix == "" {
 		return errors.New("--pack requires --output or --prefix")
 	}
+	if o.Checksum && !o.Pack {
+		return errors.New("--checksum requires --pack")
+	}
+	if !o.PackFormat.Valid() {
+		return fmt.Errorf("invalid pack format: %q", o.PackFormat)
+	}
+	if strings.ContainsAny(o.PackName, `/\`) {
+		return fmt.Errorf("pack.name %q is a file name, not a path: the archive goes next to the output or prefix", o.PackName)
+	}
+	for _, d := range []struct{ key, dir string }{{"bin", o.Layout.Bin}, {"lib", o.Layout.Lib}, {"include", o.Layout.Include}} {
+		if d.dir != "" && !filepath.IsLocal(d.dir) {
+			return fmt.Errorf("layout.%s %q must be a relative path inside the prefix", d.key, d.dir)
+		}
+	}
+	if o.PackFormat.IsLinuxPackage() {
+		_, deb := linuxpkg.DebArch(o.GOARCH)
+		_, rpm := linuxpkg.RPMArch(o.GOARCH)
+		_, apk := linuxpkg.APKArch(o.GOARCH)
+		switch {
+		case o.GOOS != "linux" || o.PackFormat == PackDeb && !deb || o.PackFormat == PackRPM && !rpm || o.PackFormat == PackAPK && !apk:
+			return fmt.Errorf("pack format %s does not support %s/%s", o.PackFormat, o.GOOS, o.GOARCH)
+		case len(o.PackInclude) > 0:
+			return fmt.Errorf("pack.include adds files to archives, not %s packages", o.PackFormat)
+		case o.PackName != "":
+			return fmt.Errorf("pack.name names archives, not %s packages", o.PackFormat)
+		case o.PackFormat == PackAPK && !o.LinkMode.IsStatic():
+			return errors.New("pack format apk requires --linkmode static: Alpine has musl, not glibc")
+		case o.PackFormat == PackDeb && o.Packaging.Maintainer == "":
+			return errors.New("pack format deb requires [package] maintainer")
+		case o.PackFormat == PackRPM && o.Packaging.License == "":
+			return errors.New("pack format rpm requires [package] license")
+		}
+	}
+	if o.IsUniversal() && o.Output == "" && o.Prefix == "" {
+		return errors.New("darwin/universal requires --output or --prefix")
+	}
+	if o.BuildMode.IsLib() {
+		switch {
+		case o.LinkMode.IsStatic():
+			return fmt.Errorf("buildmode %s cannot link statically", o.BuildMode)
+		case o.IsUniversal():
+			return fmt.Errorf("buildmode %s does not support darwin/universal", o.BuildMode)
+		case o.Pack && o.Prefix == "":
+			return fmt.Errorf("--pack with buildmode %s requires --prefix to hold the header", o.BuildMode)
+		case o.PkgConfig && o.Prefix == "":
+			return errors.New("--pkg-config requires --prefix")
+		}
+	} else if o.PkgConfig {
+		return errors.New("--pkg-config requires buildmode c-shared or c-archive")
+	}
+	return nil
+}
+
+// Buildable reports whether the host toolchain can produce this target.
+// It returns nil when buildable, or an error describing what is missing.
+func (o *Options) Buildable() error {
+	if o.IsUniversal() {
+		for _, s := range o.Slices() {
+			if err := s.Buildable(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	target := o.GOOS + "/" + o.GOARCH
+	if _, ok := zigOS[o.GOOS]; !ok {
+		return fmt.Errorf("%s: os %q not supported by zig toolchain", target, o.GOOS)
+	}
+	if _, ok := zigArch[o.GOARCH]; !ok {
+		return fmt.Errorf("%s: arch %q not supported by zig toolchain", target, o.GOARCH)
+	}
+	if reason, ok := unbuildable[target]; ok && !o.hasSDK() {
+		return fmt.Errorf("%s: %s", target, reason)
+	}
+	if (o.GOOS == "wasip1") != (o.GOARCH == "wasm") {
+		return fmt.Errorf("%s: wasm builds only as wasip1/wasm", target)
+	}
+	if o.GOOS == "ios" {
+		if !slices.Contains(iosArchs, o.GOARCH) {
+			return fmt.Errorf("%s: arch %q not supported on ios", target, o.GOARCH)
+		}
+		if root, err := locateIOSSDK(o.IOSSDK); err != nil || root == "" {
+			return fmt.Errorf("%s: requires an iOS SDK: %w", target, cmp.Or(err, errNoIOSSDK))
+		}
+	}
+	if o.GOOS == "android" {
+		if _, ok := androidTriples[o.GOARCH]; !ok {
+			return fmt.Errorf("%s: arch %q not supported by the Android NDK", target, o.GOARCH)
+		}
+		if _, err := ndkSysroot(o.androidNDK()); err != nil {
+			return fmt.Errorf("%s: requires the Android NDK (bionic libc): %w", target, err)
+		}
+	}
 	return nil
 }
 
+// hasSDK reports whether a darwin target has a macOS SDK to build against.
+func (o *Options) hasSDK() bool {
+	if o.GOOS != "darwin" {
+		return false
+	}
+	root, err := sdk.Locate(o.MacOSSDK)
+	return err == nil && root != ""
+}
+
+// ArchivePath returns the path --pack writes, or "" without an output.
+func (o *Options) ArchivePath() string {
+	src := cmp.Or(o.Prefix, o.Output)
+	switch {
+	case src == "":
+		return ""
+	case o.PackFormat.IsLinuxPackage():
+		return o.packagePath()
+	case o.PackName != "":
+		return filepath.Join(filepath.Dir(src), o.PackName+archive.ForOS(o.GOOS).Ext())
+	}
+	return archive.Path(src, o.GOOS, o.GOARCH)
+}
+
+// GoToolchain returns the GOTOOLCHAIN value pinning GoVersion, or "" when
+// the local toolchain should be used. The go command downloads and caches
+// the requested release on first use.
+func (o *Options) GoToolchain() string {
+	if o.GoVersion == "" {
+		return ""
+	}
+	v := strings.TrimPrefix(o.GoVersion, "go")
+	// Go 1.21 and later name their first release 1.N.0, not 1.N.
+	if m := goVersionRe.FindStringSubmatch(v); m != nil && m[2] == "" {
+		v += ".0"
+	}
+	return "go" + v
+}
+
+// validGoVersion reports whether v names a Go release the toolchain
+// download of GOTOOLCHAIN serves: 1.21 and later, which it has for every
+// platform Go supports.
+func validGoVersion(v string) bool {
+	m := goVersionRe.FindStringSubmatch(strings.TrimPrefix(v, "go"))
+	if m == nil {
+		return false
+	}
+	minor, err := strconv.Atoi(m[1])
+	return err == nil && minor >= 21
+}
+
+// HasCgo reports whether the target can use cgo. Go has none on wasip1, so
+// those targets build without zig.
+func (o *Options) HasCgo() bool {
+	return o.GOOS != "wasip1"
+}
+
 // ZigTarget returns the Zig cross-compilation target triple.
 func (o *Options) ZigTarget() string {
 	arch := zigArch[o.GOARCH]
 	os := zigOS[o.GOOS]
-	if o.GOOS == "linux" {
+	switch o.GOOS {
+	case "linux":
 		os = o.linuxABI()
+	case "ios":
+		if o.IsSimulator() {
+			os = "ios-simulator"
+		}
+	case "android":
+		if o.GOARCH == "arm" {
+			os = "linux-androideabi"
+		}
+		os += "." + strconv.Itoa(o.androidAPI()) // the API level
 	}
 	return arch + "-" + os
 }
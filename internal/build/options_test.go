@@ -1,8 +1,11 @@
 package build
 
 import (
+	"path/filepath"
 	"runtime"
 	"testing"
+
+	"github.com/qntx/gox/internal/sdk"
 )
 
 func TestLinkMode_Valid(t *testing.T) {
@@ -107,11 +110,41 @@ func TestOptions_Validate(t *testing.T) {
 			opts:    Options{LinkMode: "invalid"},
 			wantErr: true,
 		},
+		{
+			name:    "go-version ok",
+			opts:    Options{GoVersion: "go1.24.3", LinkMode: LinkAuto},
+			wantErr: false,
+		},
+		{
+			name:    "invalid go-version",
+			opts:    Options{GoVersion: "latest", LinkMode: LinkAuto},
+			wantErr: true,
+		},
 		{
 			name:    "output and prefix exclusive",
 			opts:    Options{Output: "bin", Prefix: "dist", LinkMode: LinkAuto},
 			wantErr: true,
 		},
+		{
+			name:    "layout dirs inside prefix ok",
+			opts:    Options{Prefix: "dist", Layout: Layout{Bin: "usr/bin", Lib: "lib64", Include: "include"}, LinkMode: LinkAuto},
+			wantErr: false,
+		},
+		{
+			name:    "layout lib escapes prefix",
+			opts:    Options{Prefix: "dist", Layout: Layout{Lib: "../lib"}, LinkMode: LinkAuto},
+			wantErr: true,
+		},
+		{
+			name:    "layout bin absolute",
+			opts:    Options{Prefix: "dist", Layout: Layout{Bin: "/usr/bin"}, LinkMode: LinkAuto},
+			wantErr: true,
+		},
+		{
+			name:    "layout include escapes prefix",
+			opts:    Options{Prefix: "dist", Layout: Layout{Include: "include/../.."}, LinkMode: LinkAuto},
+			wantErr: true,
+		},
 		{
 			name:    "no-rpath requires prefix",
 			opts:    Options{NoRpath: true, LinkMode: LinkAuto},
@@ -132,11 +165,136 @@ func TestOptions_Validate(t *testing.T) {
 			opts:    Options{Pack: true, Output: "bin", LinkMode: LinkAuto},
 			wantErr: false,
 		},
+		{
+			name:    "checksum requires pack",
+			opts:    Options{Checksum: true, Output: "bin", LinkMode: LinkAuto},
+			wantErr: true,
+		},
+		{
+			name:    "invalid buildmode",
+			opts:    Options{BuildMode: "plugin", LinkMode: LinkAuto},
+			wantErr: true,
+		},
+		{
+			name:    "wasip1 static",
+			opts:    Options{GOOS: "wasip1", GOARCH: "wasm", LinkMode: LinkStatic},
+			wantErr: true,
+		},
+		{
+			name:    "wasip1 c-archive",
+			opts:    Options{GOOS: "wasip1", GOARCH: "wasm", BuildMode: BuildCArchive, Prefix: "dist", LinkMode: LinkAuto},
+			wantErr: true,
+		},
+		{
+			name:    "wasip1 ok",
+			opts:    Options{GOOS: "wasip1", GOARCH: "wasm", Prefix: "dist", LinkMode: LinkAuto},
+			wantErr: false,
+		},
+		{
+			name:    "simulator requires ios",
+			opts:    Options{GOOS: "linux", Simulator: true, LinkMode: LinkAuto},
+			wantErr: true,
+		},
+		{
+			name:    "ios c-shared",
+			opts:    Options{GOOS: "ios", BuildMode: BuildCShared, Prefix: "dist", LinkMode: LinkAuto},
+			wantErr: true,
+		},
+		{
+			name:    "android-api below minimum",
+			opts:    Options{GOOS: "android", AndroidAPI: 19, LinkMode: LinkAuto},
+			wantErr: true,
+		},
+		{
+			name:    "c-shared static",
+			opts:    Options{BuildMode: BuildCShared, LinkMode: LinkStatic},
+			wantErr: true,
+		},
+		{
+			name:    "c-shared pack requires prefix",
+			opts:    Options{BuildMode: BuildCShared, Pack: true, Output: "libapp.so", LinkMode: LinkAuto},
+			wantErr: true,
+		},
+		{
+			name:    "pkg-config requires library",
+			opts:    Options{PkgConfig: true, Prefix: "dist", LinkMode: LinkAuto},
+			wantErr: true,
+		},
+		{
+			name:    "pkg-config requires prefix",
+			opts:    Options{BuildMode: BuildCArchive, PkgConfig: true, Output: "libapp.a", LinkMode: LinkAuto},
+			wantErr: true,
+		},
+		{
+			name:    "c-archive pkg-config ok",
+			opts:    Options{BuildMode: BuildCArchive, PkgConfig: true, Prefix: "dist", LinkMode: LinkAuto},
+			wantErr: false,
+		},
+		{
+			name:    "c-shared pack with prefix ok",
+			opts:    Options{BuildMode: BuildCShared, Pack: true, Prefix: "dist", LinkMode: LinkAuto},
+			wantErr: false,
+		},
+		{
+			name:    "checksum with pack ok",
+			opts:    Options{Checksum: true, Pack: true, Output: "bin", LinkMode: LinkAuto},
+			wantErr: false,
+		},
 		{
 			name:    "pack with prefix ok",
 			opts:    Options{Pack: true, Prefix: "dist", LinkMode: LinkAuto},
 			wantErr: false,
 		},
+		{
+			name:    "deb requires linux",
+			opts:    Options{GOOS: "darwin", GOARCH: "arm64", Pack: true, PackFormat: PackDeb, Prefix: "dist", Packaging: Packaging{Maintainer: "a <a@b.c>"}, LinkMode: LinkAuto},
+			wantErr: true,
+		},
+		{
+			name:    "deb requires maintainer",
+			opts:    Options{GOOS: "linux", GOARCH: "amd64", Pack: true, PackFormat: PackDeb, Prefix: "dist", LinkMode: LinkAuto},
+			wantErr: true,
+		},
+		{
+			name:    "rpm requires license",
+			opts:    Options{GOOS: "linux", GOARCH: "amd64", Pack: true, PackFormat: PackRPM, Prefix: "dist", LinkMode: LinkAuto},
+			wantErr: true,
+		},
+		{
+			name:    "rpm unknown arch",
+			opts:    Options{GOOS: "linux", GOARCH: "mips", Pack: true, PackFormat: PackRPM, Prefix: "dist", Packaging: Packaging{License: "MIT"}, LinkMode: LinkAuto},
+			wantErr: true,
+		},
+		{
+			name:    "apk requires static linkmode",
+			opts:    Options{GOOS: "linux", GOARCH: "amd64", Pack: true, PackFormat: PackAPK, Prefix: "dist", LinkMode: LinkAuto},
+			wantErr: true,
+		},
+		{
+			name:    "apk static ok",
+			opts:    Options{GOOS: "linux", GOARCH: "amd64", Pack: true, PackFormat: PackAPK, Prefix: "dist", LinkMode: LinkStatic},
+			wantErr: false,
+		},
+		{
+			name:    "rpm with license ok",
+			opts:    Options{GOOS: "linux", GOARCH: "amd64", Pack: true, PackFormat: PackRPM, Prefix: "dist", Packaging: Packaging{License: "MIT"}, LinkMode: LinkAuto},
+			wantErr: false,
+		},
+		{
+			name:    "pack name",
+			opts:    Options{GOOS: "linux", GOARCH: "amd64", Pack: true, PackName: "{{.Name}}_{{.Version}}", Prefix: "dist", LinkMode: LinkAuto},
+			wantErr: false,
+		},
+		{
+			name:    "pack name with directory",
+			opts:    Options{GOOS: "linux", GOARCH: "amd64", Pack: true, PackName: "release/{{.Name}}", Prefix: "dist", LinkMode: LinkAuto},
+			wantErr: true,
+		},
+		{
+			name:    "pack name on deb",
+			opts:    Options{GOOS: "linux", GOARCH: "amd64", Pack: true, PackFormat: PackDeb, PackName: "app", Prefix: "dist", Packaging: Packaging{Maintainer: "me"}, LinkMode: LinkAuto},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -149,6 +307,53 @@ func TestOptions_Validate(t *testing.T) {
 	}
 }
 
+func TestValidGoVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"1.24.3", true},
+		{"go1.24.3", true},
+		{"1.24", true},
+		{"1.21.0", true},
+		{"1.25rc1", true},
+		{"go1.21rc2", true},
+		{"1.20.14", false},
+		{"1.19", false},
+		{"1.9.7", false},
+		{"1.24.3rc1", false},
+		{"1.24beta1", false},
+		{"2.0.0", false},
+		{"latest", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			if got := validGoVersion(tt.version); got != tt.want {
+				t.Errorf("validGoVersion(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptions_GoToolchain(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"", ""},
+		{"1.24.3", "go1.24.3"},
+		{"go1.25rc1", "go1.25rc1"},
+		{"1.24", "go1.24.0"},
+	}
+	for _, tt := range tests {
+		o := Options{GoVersion: tt.version}
+		if got := o.GoToolchain(); got != tt.want {
+			t.Errorf("GoToolchain(%q) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
 func TestOptions_ZigTarget(t *testing.T) {
 	tests := []struct {
 		goos, goarch string
@@ -170,6 +375,10 @@ func TestOptions_ZigTarget(t *testing.T) {
 		{"linux", "loong64", LinkAuto, "loongarch64-linux-gnu"},
 		{"linux", "ppc64le", LinkAuto, "powerpc64le-linux-gnu"},
 		{"linux", "s390x", LinkAuto, "s390x-linux-gnu"},
+		{"android", "arm64", LinkAuto, "aarch64-linux-android.21"},
+		{"android", "amd64", LinkAuto, "x86_64-linux-android.21"},
+		{"android", "arm", LinkAuto, "arm-linux-androideabi.21"},
+		{"wasip1", "wasm", LinkAuto, "wasm32-wasi"},
 	}
 
 	for _, tt := range tests {
@@ -185,3 +394,92 @@ func TestOptions_ZigTarget(t *testing.T) {
 		})
 	}
 }
+
+func TestOptions_Buildable(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv(sdk.Env, "")
+	for _, key := range AndroidNDKEnv {
+		t.Setenv(key, "")
+	}
+	macSDK := t.TempDir()
+
+	tests := []struct {
+		goos, goarch string
+		sdk          string
+		want         bool
+	}{
+		{"linux", "amd64", "", true},
+		{"windows", "arm64", "", true},
+		{"darwin", "amd64", "", true},
+		{"darwin", "arm64", "", false},
+		{"darwin", "arm64", macSDK, true},
+		{"darwin", "universal", "", false},
+		{"darwin", "universal", macSDK, true},
+		{"linux", "universal", "", false},
+		{"freebsd", "arm64", "", false},
+		{"freebsd", "arm64", macSDK, false},
+		{"ios", "arm64", "", false},
+		{"android", "arm64", "", false},
+		{"linux", "mips", "", false},
+		{"wasip1", "wasm", "", true},
+		{"linux", "wasm", "", false},
+		{"wasip1", "amd64", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.goos+"/"+tt.goarch+"/"+filepath.Base(tt.sdk), func(t *testing.T) {
+			o := &Options{GOOS: tt.goos, GOARCH: tt.goarch, MacOSSDK: tt.sdk}
+			if got := o.Buildable() == nil; got != tt.want {
+				t.Errorf("Buildable() = %v, want buildable %v", o.Buildable(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLayout(t *testing.T) {
+	flat, nested := true, false
+	tests := []struct {
+		name        string
+		layout      Layout
+		goos        string
+		bin, lib    string
+		wantInclude string
+	}{
+		{"default unix", Layout{}, "linux", "bin", "lib", "include"},
+		{"default windows", Layout{}, "windows", "", "", "include"},
+		{"lib64", Layout{Lib: "lib64"}, "linux", "bin", "lib64", "include"},
+		{"flat unix", Layout{Flat: &flat}, "linux", "", "", "include"},
+		{"nested windows", Layout{Flat: &nested}, "windows", "bin", "lib", "include"},
+		{"custom include", Layout{Include: "inc"}, "linux", "bin", "lib", "inc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.layout.BinDir(tt.goos); got != tt.bin {
+				t.Errorf("BinDir() = %q, want %q", got, tt.bin)
+			}
+			if got := tt.layout.LibDir(tt.goos); got != tt.lib {
+				t.Errorf("LibDir() = %q, want %q", got, tt.lib)
+			}
+			if got := tt.layout.IncludeDir(); got != tt.wantInclude {
+				t.Errorf("IncludeDir() = %q, want %q", got, tt.wantInclude)
+			}
+		})
+	}
+}
+
+func TestLayout_Merge(t *testing.T) {
+	flat := true
+	base := Layout{Bin: "sbin", Lib: "lib64", Flat: &flat}
+	got := Layout{Lib: "lib32"}.Merge(base)
+
+	if got.Bin != "sbin" {
+		t.Errorf("Bin = %q, want sbin", got.Bin)
+	}
+	if got.Lib != "lib32" {
+		t.Errorf("Lib = %q, want lib32", got.Lib)
+	}
+	if got.Flat == nil || !*got.Flat {
+		t.Error("Flat should be inherited from base")
+	}
+}
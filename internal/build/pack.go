@@ -0,0 +1,203 @@
+package build
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/qntx/gox/internal/archive"
+)
+
+// PackFormat selects what packing a target produces.
+type PackFormat string
+
+const (
+	PackArchive PackFormat = "archive" // tar.gz, or zip for windows (default)
+	PackDeb     PackFormat = "deb"     // Debian package of a linux target
+	PackRPM     PackFormat = "rpm"     // RPM package of a linux target
+	PackAPK     PackFormat = "apk"     // Alpine package of a static linux target
+)
+
+// PackFormats lists the accepted pack formats.
+var PackFormats = []PackFormat{PackArchive, PackDeb, PackRPM, PackAPK}
+
+func (f PackFormat) Valid() bool {
+	return f == "" || slices.Contains(PackFormats, f)
+}
+
+// IsLinuxPackage reports whether f is a Linux distribution package, built
+// from the [package] section.
+func (f PackFormat) IsLinuxPackage() bool {
+	return f == PackDeb || f == PackRPM || f == PackAPK
+}
+
+// Pack is the pack setting of a target or variant. In gox.toml it is true
+// for an archive, the name of a format, or a table.
+type Pack struct {
+	Format  PackFormat `toml:"format,omitempty"`
+	Include []string   `toml:"include,omitempty"` // extra files, "pattern[=dst]"
+	Exclude []string   `toml:"exclude,omitempty"` // patterns of files left out
+	Name    string     `toml:"name,omitempty"`    // archive file name without extension, a template
+}
+
+// packTable is Pack without its TOML methods, for decoding the table form.
+type packTable Pack
+
+// Enabled reports whether the target is packed.
+func (p Pack) Enabled() bool {
+	return p.Format != ""
+}
+
+// UnmarshalTOML decodes pack = true, pack = "<format>" or a [pack] table.
+func (p *Pack) UnmarshalTOML(v any) error {
+	switch v := v.(type) {
+	case bool:
+		*p = Pack{}
+		if v {
+			p.Format = PackArchive
+		}
+	case string:
+		*p = Pack{Format: PackFormat(v)}
+	case map[string]any:
+		data, err := toml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var t packTable
+		if err := toml.Unmarshal(data, &t); err != nil {
+			return fmt.Errorf("pack: %w", err)
+		}
+		*p = Pack(t)
+		if p.Format == "" {
+			p.Format = PackArchive
+		}
+	default:
+		return fmt.Errorf("pack: expected boolean, string or table, got %T", v)
+	}
+	return nil
+}
+
+// MarshalTOML encodes p in its shortest form: true or the format alone,
+// else an inline table.
+func (p Pack) MarshalTOML() ([]byte, error) {
+	switch {
+	case len(p.Include) > 0 || len(p.Exclude) > 0 || p.Name != "":
+	case p.Format == PackArchive:
+		return []byte("true"), nil
+	default:
+		return []byte(strconv.Quote(string(p.Format))), nil
+	}
+	if p.Format == PackArchive {
+		p.Format = "" // the default of a table
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(packTable(p)); err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	return []byte("{ " + strings.Join(lines, ", ") + " }"), nil
+}
+
+// packFormatNames returns the accepted pack formats as strings, for the
+// schema.
+func packFormatNames() []string {
+	names := make([]string, len(PackFormats))
+	for i, f := range PackFormats {
+		names[i] = string(f)
+	}
+	return names
+}
+
+// packIncludes expands the pack.include patterns of the target into the
+// files they add to its archive. A pattern is a path relative to the working
+// directory, with "*", "?", "[...]" and "**" wildcards, and an optional
+// "=dst" naming where its files go in the archive: below dst for a pattern
+// with wildcards (or a directory), otherwise at dst itself, or in it when dst
+// ends in "/". Without dst, files keep their path when it lies below the
+// working directory and go to the archive's root otherwise.
+func (b *Builder) packIncludes() ([]archive.File, error) {
+	var files []archive.File
+	for _, inc := range b.opts.PackInclude {
+		pattern, dst, remap := strings.Cut(inc, "=")
+		pattern = filepath.ToSlash(filepath.Clean(pattern))
+		root := globRoot(pattern)
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, fmt.Errorf("pack.include: %w", err)
+		}
+		if !remap {
+			dst = root
+			if !filepath.IsLocal(root) {
+				dst = path.Base(root)
+			}
+		}
+		if !info.IsDir() {
+			if strings.HasSuffix(dst, "/") {
+				dst += path.Base(root)
+			}
+			files = append(files, archive.File{Src: root, Name: path.Clean(dst)})
+			continue
+		}
+		if root == pattern {
+			pattern += "/**" // a directory stands for everything in it
+		}
+		n := len(files)
+		err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !archive.Match(pattern, filepath.ToSlash(p)) {
+				return err
+			}
+			if info, err := os.Stat(p); err != nil || info.IsDir() {
+				return err // a link to a directory adds nothing
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, archive.File{Src: p, Name: path.Join(dst, filepath.ToSlash(rel))})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("pack.include: %w", err)
+		}
+		if len(files) == n {
+			return nil, fmt.Errorf("pack.include: %q matches no files", inc)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, f := range files {
+		var taken bool
+		if b.opts.Prefix != "" {
+			_, err := os.Lstat(filepath.Join(b.opts.Prefix, filepath.FromSlash(f.Name)))
+			taken = err == nil
+		} else {
+			taken = f.Name == filepath.Base(b.opts.Output)
+		}
+		if taken || seen[f.Name] {
+			return nil, fmt.Errorf("pack.include: %s is already in the archive", f.Name)
+		}
+		seen[f.Name] = true
+	}
+	return files, nil
+}
+
+// globRoot returns the leading elements of pattern free of wildcards: the
+// directory every match lies below, or the pattern itself when it has none.
+func globRoot(pattern string) string {
+	elems := strings.Split(pattern, "/")
+	for i, e := range elems {
+		if strings.ContainsAny(e, `*?[`) {
+			return cmp.Or(strings.Join(elems[:i], "/"), ".")
+		}
+	}
+	return pattern
+}
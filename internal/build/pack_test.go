@@ -0,0 +1,128 @@
+package build
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestPack_TOML(t *testing.T) {
+	tests := []struct {
+		name, toml string
+		want       Pack
+		encoded    string
+	}{
+		{"true", "pack = true", Pack{Format: PackArchive}, "pack = true"},
+		{"false", "pack = false", Pack{}, ""},
+		{"format", `pack = "deb"`, Pack{Format: PackDeb}, `pack = "deb"`},
+		{"table", "[pack]\nformat = \"deb\"", Pack{Format: PackDeb}, `pack = "deb"`},
+		{"empty table", "[pack]", Pack{Format: PackArchive}, "pack = true"},
+		{
+			"include", "pack = { include = [\"LICENSE\", \"configs/**=etc\"] }",
+			Pack{Format: PackArchive, Include: []string{"LICENSE", "configs/**=etc"}},
+			`pack = { include = ["LICENSE", "configs/**=etc"] }`,
+		},
+		{
+			"format and include", "[pack]\nformat = \"deb\"\ninclude = [\"LICENSE\"]",
+			Pack{Format: PackDeb, Include: []string{"LICENSE"}},
+			`pack = { format = "deb", include = ["LICENSE"] }`,
+		},
+		{
+			"exclude", "pack = { exclude = [\"*.pdb\"] }",
+			Pack{Format: PackArchive, Exclude: []string{"*.pdb"}},
+			`pack = { exclude = ["*.pdb"] }`,
+		},
+		{
+			"name", "[pack]\nname = \"{{.Name}}_{{.OS}}_{{.Arch}}\"",
+			Pack{Format: PackArchive, Name: "{{.Name}}_{{.OS}}_{{.Arch}}"},
+			`pack = { name = "{{.Name}}_{{.OS}}_{{.Arch}}" }`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var target ConfigTarget
+			if _, err := toml.Decode(tt.toml, &target); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(target.Pack, tt.want) {
+				t.Errorf("Pack = %+v, want %+v", target.Pack, tt.want)
+			}
+			var buf bytes.Buffer
+			if err := toml.NewEncoder(&buf).Encode(target); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if got := strings.TrimSpace(buf.String()); got != tt.encoded {
+				t.Errorf("Encode() = %q, want %q", got, tt.encoded)
+			}
+		})
+	}
+
+	var target ConfigTarget
+	if _, err := toml.Decode("pack = 1", &target); err == nil {
+		t.Error("Decode() of pack = 1 succeeded")
+	}
+}
+
+func TestBuilder_PackIncludes(t *testing.T) {
+	dir := t.TempDir()
+	work := filepath.Join(dir, "work")
+	for _, name := range []string{
+		"work/LICENSE", "work/README.md", "work/CHANGELOG.md", "work/docs/guide.md",
+		"work/configs/app.yaml", "work/configs/prod/app.yaml", "NOTICE", "work/dist/app/bin/app",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(work)
+
+	tests := []struct {
+		name    string
+		include []string
+		want    []string // archive names
+		wantErr bool
+	}{
+		{"file", []string{"LICENSE"}, []string{"LICENSE"}, false},
+		{"outside", []string{"../NOTICE"}, []string{"NOTICE"}, false},
+		{"glob", []string{"*.md"}, []string{"CHANGELOG.md", "README.md"}, false},
+		{"recursive", []string{"configs/**"}, []string{"configs/app.yaml", "configs/prod/app.yaml"}, false},
+		{"directory", []string{"docs"}, []string{"docs/guide.md"}, false},
+		{"remap glob", []string{"configs/**=etc"}, []string{"etc/app.yaml", "etc/prod/app.yaml"}, false},
+		{"remap file", []string{"LICENSE=COPYING"}, []string{"COPYING"}, false},
+		{"remap into dir", []string{"LICENSE=share/doc/"}, []string{"share/doc/LICENSE"}, false},
+		{"no match", []string{"*.txt"}, nil, true},
+		{"missing", []string{"COPYING"}, nil, true},
+		{"collides with prefix", []string{"dist/app/bin/app=bin/app"}, nil, true},
+		{"twice", []string{"LICENSE", "LICENSE"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Prefix: "dist/app", PackInclude: tt.include}
+			files, err := New("", &opts).packIncludes()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("packIncludes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var names []string
+			for _, f := range files {
+				names = append(names, f.Name)
+				if data, err := os.ReadFile(f.Src); err != nil || !strings.HasSuffix(string(data), path.Base(f.Src)) {
+					t.Errorf("%s: source %s unreadable", f.Name, f.Src)
+				}
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("packIncludes() = %q, want %q", names, tt.want)
+			}
+		})
+	}
+}
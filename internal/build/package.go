@@ -4,8 +4,8 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -13,18 +13,30 @@ import (
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
 	"github.com/qntx/gox/internal/archive"
+	"github.com/qntx/gox/internal/cache"
+	"github.com/qntx/gox/internal/lock"
+	"github.com/qntx/gox/internal/telemetry"
 	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/uiutil"
 )
 
 // Package represents a dependency archive with include/lib/bin directories.
 type Package struct {
-	Source  string
-	URL     string
-	Dir     string
-	Include string
-	Lib     string
-	Bin     string
+	Source    string
+	URL       string
+	FetchURL  string // URL after [mirrors] rewrites; URL stays in gox.lock
+	SHA256    string // pinned archive digest from a "#sha256:<hex>" suffix
+	KeepModes bool   // "#keep-modes": no executable bits for zip entries without unix modes
+	Vcpkg     string // "port:triplet" of a "vcpkg:" source, copied from vcpkg instead of downloaded
+	Dir       string
+	Include   string
+	Lib       string
+	Bin       string
+
+	fetched string // sha256 of the archive downloaded by this run
 }
 
 // CacheEntry represents a cached package with metadata.
@@ -36,42 +48,39 @@ type CacheEntry struct {
 	LibCount     int
 }
 
+// latestTag is the release tag of floating package sources, such as
+// owner/repo@latest/asset, which follow the newest release until gox pkg
+// update locks them again.
+const latestTag = "latest"
+
 var (
 	ghReleaseRE = regexp.MustCompile(`^([^/]+)/([^@]+)@([^/]+)/(.+)$`)
-	archiveExts = []string{".tar.gz", ".tgz", ".tar.xz", ".txz", ".zip"}
+	sha256RE    = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+	archiveExts = []string{".tar.gz", ".tgz", ".tar.xz", ".txz", ".tar.bz2", ".tbz2", ".tbz", ".zip", ".7z", ".deb", ".rpm"}
 )
 
 // EnsureAll parses and downloads packages in parallel with progress.
-func EnsureAll(ctx context.Context, sources []string) ([]*Package, error) {
+func EnsureAll(ctx context.Context, sources []string) (_ []*Package, err error) {
 	if len(sources) == 0 {
 		return nil, nil
 	}
+	ctx, span := telemetry.Start(ctx, "packages.ensure", attribute.Int("gox.packages", len(sources)))
+	defer func() { telemetry.End(span, err) }()
 
-	pkgs := make([]*Package, len(sources))
-	for i, s := range sources {
-		p, err := parsePackage(s)
-		if err != nil {
-			return nil, err
-		}
-		p.resolvePaths()
-		pkgs[i] = p
-	}
-
-	var toDownload []*Package
-	for _, p := range pkgs {
-		if !p.isCached() {
-			toDownload = append(toDownload, p)
-		}
+	pkgs, toDownload, err := pending(sources)
+	if err != nil {
+		return nil, err
 	}
 	if len(toDownload) == 0 {
+		if err := writeModules(pkgs); err != nil {
+			return nil, err
+		}
 		return pkgs, nil
 	}
 
-	sizes := make(map[string]int64)
-	for _, p := range toDownload {
-		if size, err := archive.ContentLength(ctx, p.URL); err == nil && size > 0 {
-			sizes[p.URL] = size
-		}
+	sizes := downloadSizes(ctx, toDownload)
+	if err := confirmDownload(toDownload, sizes); err != nil {
+		return nil, err
 	}
 
 	progress := ui.NewProgress()
@@ -86,7 +95,13 @@ func EnsureAll(ctx context.Context, sources []string) ([]*Package, error) {
 		bar := progress.AddBar(p.Dir, sizes[p.URL])
 		wg.Go(func() {
 			p.resolvePaths()
-			if e := p.download(ctx, bar); e != nil {
+			e := telemetry.Phase(ctx, "package.download", func(ctx context.Context) error {
+				if err := p.download(ctx, bar); err != nil {
+					return err
+				}
+				return p.stampDigest()
+			}, attribute.String("gox.package", p.Source), attribute.String("url.full", p.FetchURL))
+			if e != nil {
 				mu.Lock()
 				errs = append(errs, fmt.Errorf("%s: %w", p.Source, e))
 				mu.Unlock()
@@ -101,9 +116,60 @@ func EnsureAll(ctx context.Context, sources []string) ([]*Package, error) {
 		return nil, errs[0]
 	}
 	ui.Success("Downloaded %d package(s) in %s", len(toDownload), ui.FormatDuration(time.Since(start)))
+	if err := writeModules(pkgs); err != nil {
+		return nil, err
+	}
 	return pkgs, nil
 }
 
+// pending parses sources and returns them along with the packages that
+// still have to be downloaded.
+func pending(sources []string) (pkgs, toDownload []*Package, err error) {
+	pkgs = make([]*Package, len(sources))
+	for i, s := range sources {
+		p, err := parsePackage(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		p.resolvePaths()
+		pkgs[i] = p
+	}
+	warnUnvendored(pkgs)
+	for _, p := range pkgs {
+		if p.vendored() != "" {
+			continue
+		}
+		if p.Vcpkg != "" {
+			if !p.isCached() || p.vcpkgChanged() {
+				toDownload = append(toDownload, p)
+			}
+			continue
+		}
+		locked, err := p.checkLock()
+		if err != nil {
+			return nil, nil, err
+		}
+		// A cached package missing from the lock is fetched again so its
+		// archive digest can be recorded.
+		if !p.isCached() || (lock.Active() != nil && !locked) {
+			toDownload = append(toDownload, p)
+		}
+	}
+	return pkgs, toDownload, nil
+}
+
+// downloadSizes returns the advertised archive sizes of pkgs by URL;
+// servers that do not report one are left out.
+func downloadSizes(ctx context.Context, pkgs []*Package) map[string]int64 {
+	sizes := make(map[string]int64)
+	for _, p := range pkgs {
+		if size, err := archive.ContentLength(ctx, p.FetchURL); err == nil && size > 0 {
+			sizes[p.URL] = size
+		}
+	}
+	return sizes
+}
+
 // CollectPaths returns include, lib, and bin directories from packages.
 func CollectPaths(pkgs []*Package) (inc, lib, bin []string) {
 	for _, p := range pkgs {
@@ -163,55 +229,161 @@ func CacheDir() string {
 	return cacheDir()
 }
 
+// resolvePaths points p at its vendored copy when there is one, else at its
+// cache entry.
 func (p *Package) resolvePaths() {
-	dir := filepath.Join(cacheDir(), p.Dir)
+	dir := p.vendored()
+	if dir == "" {
+		dir = filepath.Join(cacheDir(), p.Dir)
+	}
 	p.Include = filepath.Join(dir, "include")
 	p.Lib = filepath.Join(dir, "lib")
 	p.Bin = filepath.Join(dir, "bin")
 }
 
+// archiveSHA256 returns the sha256 of the package archive: the pinned digest,
+// the gox.lock entry, or the digest of this run's download. Returns "" when
+// none is known, such as for vcpkg ports or unlocked cached packages.
+func (p *Package) archiveSHA256() string {
+	if p.SHA256 != "" {
+		return p.SHA256
+	}
+	if l := lock.Active(); l != nil {
+		if locked, ok := l.Package(p.Source); ok && locked.SHA256 != "" {
+			return locked.SHA256
+		}
+	}
+	return p.fetched
+}
+
 func (p *Package) isCached() bool {
 	return isDir(filepath.Join(cacheDir(), p.Dir))
 }
 
-func (p *Package) download(ctx context.Context, bar *ui.Bar) error {
+func (p *Package) download(ctx context.Context, bar uiutil.Bar) error {
+	if p.Vcpkg != "" {
+		err := p.installVcpkg(ctx)
+		if bar != nil {
+			if err != nil {
+				bar.Abort(true)
+			} else {
+				bar.Complete()
+			}
+		}
+		return err
+	}
 	dir := filepath.Join(cacheDir(), p.Dir)
+	os.RemoveAll(dir)
 
-	var proxy func(io.Reader) io.Reader
+	opts := archive.DownloadOptions{KeepModes: p.KeepModes}
 	if bar != nil {
-		proxy = bar.ProxyReader
+		opts.Proxy = bar.ProxyReader
+		opts.Resumed = bar.SetCurrent
+	}
+	l := lock.Active()
+	locked, isLocked := lock.Package{}, false
+	if l != nil {
+		locked, isLocked = l.Package(p.Source)
+		opts.SHA256 = locked.SHA256
+	}
+	if p.SHA256 != "" {
+		opts.SHA256 = p.SHA256
 	}
 
-	if err := archive.DownloadTo(ctx, p.URL, dir, proxy); err != nil {
+	res, err := archive.DownloadWith(ctx, p.FetchURL, dir, opts)
+	if err != nil {
 		os.RemoveAll(dir)
 		if bar != nil {
 			bar.Abort(true)
 		}
+		if errors.Is(err, archive.ErrChecksumMismatch) {
+			if p.SHA256 != "" {
+				return fmt.Errorf("%s: %w", p.Source, err)
+			}
+			return fmt.Errorf("%s: %w (upstream asset changed?)", lock.File, err)
+		}
 		return err
 	}
 	if bar != nil {
 		bar.Complete()
 	}
+	p.fetched = res.SHA256
 
 	if !isDir(p.Include) && !isDir(p.Lib) {
 		return fmt.Errorf("%s: missing include/ and lib/", p.Source)
 	}
+	if l != nil && !isLocked {
+		return l.SetPackage(lock.Package{Source: p.Source, URL: p.URL, Size: res.Size, SHA256: res.SHA256})
+	}
 	return nil
 }
 
+// checkLock reports whether p is in the active lock and rejects entries
+// whose URL no longer matches the source.
+func (p *Package) checkLock() (bool, error) {
+	l := lock.Active()
+	if l == nil {
+		return false, nil
+	}
+	locked, ok := l.Package(p.Source)
+	if ok && locked.URL != p.URL {
+		return false, fmt.Errorf("%s: %s resolves to %s, locked to %s", lock.File, p.Source, p.URL, locked.URL)
+	}
+	return ok, nil
+}
+
 func parsePackage(source string) (*Package, error) {
 	p := &Package{Source: source}
+	spec, options, _ := strings.Cut(source, "#")
+	for opt := range strings.SplitSeq(options, "#") {
+		switch digest, pinned := strings.CutPrefix(opt, "sha256:"); {
+		case opt == "":
+		case pinned:
+			if !sha256RE.MatchString(digest) {
+				return nil, fmt.Errorf("invalid sha256 in package: %s", source)
+			}
+			p.SHA256 = strings.ToLower(digest)
+		case opt == "keep-modes":
+			p.KeepModes = true
+		default:
+			return nil, fmt.Errorf("unknown option #%s in package: %s", opt, source)
+		}
+	}
 	switch {
-	case strings.HasPrefix(source, "http://"), strings.HasPrefix(source, "https://"):
-		p.URL = source
-		p.Dir = urlHash(source)
-	case ghReleaseRE.MatchString(source):
-		m := ghReleaseRE.FindStringSubmatch(source)
+	case strings.HasPrefix(spec, "vcpkg:"):
+		if options != "" {
+			return nil, fmt.Errorf("vcpkg packages take no #options: %s", source)
+		}
+		key, err := parseVcpkg(strings.TrimPrefix(spec, "vcpkg:"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid package %s: %w", source, err)
+		}
+		p.Vcpkg = key
+		p.Dir = "vcpkg-" + strings.Replace(key, ":", "-", 1)
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		p.URL = spec
+		p.Dir = urlHash(spec)
+	case ghReleaseRE.MatchString(spec):
+		m := ghReleaseRE.FindStringSubmatch(spec)
 		p.URL = fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s", m[1], m[2], m[3], m[4])
+		if m[3] == latestTag {
+			p.URL = fmt.Sprintf("https://github.com/%s/%s/releases/latest/download/%s", m[1], m[2], m[4])
+		}
 		p.Dir = fmt.Sprintf("%s-%s-%s-%s", m[1], m[2], m[3], trimArchiveExt(m[4]))
+	case registryRE.MatchString(spec):
+		return nil, fmt.Errorf("package %s names the registry and needs a target to resolve against", source)
 	default:
 		return nil, fmt.Errorf("invalid package: %s", source)
 	}
+	p.FetchURL = mirrorURL(p.URL)
+	// Pinned packages get their own cache entry so an unverified copy of
+	// the same URL is never used in their place.
+	if p.SHA256 != "" {
+		p.Dir += "-" + p.SHA256[:12]
+	}
+	if p.KeepModes {
+		p.Dir += "-keep-modes"
+	}
 	return p, nil
 }
 
@@ -225,10 +397,7 @@ func resolveLibDir(libDir string) string {
 }
 
 func cacheDir() string {
-	if dir, err := os.UserCacheDir(); err == nil {
-		return filepath.Join(dir, "gox", "pkg")
-	}
-	return filepath.Join(os.TempDir(), "gox", "pkg")
+	return cache.Dir("pkg")
 }
 
 func urlHash(url string) string {
@@ -1,7 +1,17 @@
 package build
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/qntx/gox/internal/archive"
+	"github.com/qntx/gox/internal/lock"
 )
 
 func TestParsePackage(t *testing.T) {
@@ -24,6 +34,12 @@ func TestParsePackage(t *testing.T) {
 			wantURL: "https://github.com/my-org/my-repo/releases/download/v2.1.0/lib.tar.xz",
 			wantDir: "my-org-my-repo-v2.1.0-lib",
 		},
+		{
+			name:    "github latest release",
+			source:  "owner/repo@latest/lib.tar.gz",
+			wantURL: "https://github.com/owner/repo/releases/latest/download/lib.tar.gz",
+			wantDir: "owner-repo-latest-lib",
+		},
 		{
 			name:    "https url",
 			source:  "https://example.com/lib-1.0.tar.gz",
@@ -34,6 +50,59 @@ func TestParsePackage(t *testing.T) {
 			source:  "http://example.com/lib.zip",
 			wantURL: "http://example.com/lib.zip",
 		},
+		{
+			name:    "pinned github release",
+			source:  "owner/repo@v1/lib.tar.gz#sha256:" + strings.Repeat("AB", 32),
+			wantURL: "https://github.com/owner/repo/releases/download/v1/lib.tar.gz",
+			wantDir: "owner-repo-v1-lib-abababababab",
+		},
+		{
+			name:    "pinned url",
+			source:  "https://example.com/lib.zip#sha256:" + strings.Repeat("0", 64),
+			wantURL: "https://example.com/lib.zip",
+		},
+		{
+			name:    "keep modes",
+			source:  "owner/repo@v1/tools.zip#keep-modes",
+			wantURL: "https://github.com/owner/repo/releases/download/v1/tools.zip",
+			wantDir: "owner-repo-v1-tools-keep-modes",
+		},
+		{
+			name:    "pinned with keep modes",
+			source:  "owner/repo@v1/tools.zip#keep-modes#sha256:" + strings.Repeat("ab", 32),
+			wantURL: "https://github.com/owner/repo/releases/download/v1/tools.zip",
+			wantDir: "owner-repo-v1-tools-abababababab-keep-modes",
+		},
+		{
+			name:    "unknown option",
+			source:  "owner/repo@v1/lib.tar.gz#strip",
+			wantErr: true,
+		},
+		{
+			name:    "malformed digest",
+			source:  "owner/repo@v1/lib.tar.gz#sha256:abcd",
+			wantErr: true,
+		},
+		{
+			name:    "vcpkg",
+			source:  "vcpkg:openssl:x64-linux",
+			wantDir: "vcpkg-openssl-x64-linux",
+		},
+		{
+			name:    "vcpkg without triplet",
+			source:  "vcpkg:openssl",
+			wantErr: true,
+		},
+		{
+			name:    "vcpkg with options",
+			source:  "vcpkg:openssl:x64-linux#keep-modes",
+			wantErr: true,
+		},
+		{
+			name:    "unresolved registry name",
+			source:  "cudart@12.9",
+			wantErr: true,
+		},
 		{
 			name:    "invalid source",
 			source:  "invalid-source",
@@ -75,7 +144,12 @@ func TestTrimArchiveExt(t *testing.T) {
 		{"lib.tgz", "lib"},
 		{"lib.tar.xz", "lib"},
 		{"lib.txz", "lib"},
+		{"lib.tar.bz2", "lib"},
+		{"lib.tbz2", "lib"},
 		{"lib.zip", "lib"},
+		{"lib.7z", "lib"},
+		{"libssl-dev_3.0.13-1_amd64.deb", "libssl-dev_3.0.13-1_amd64"},
+		{"openssl-devel-3.0.7-27.el9.x86_64.rpm", "openssl-devel-3.0.7-27.el9.x86_64"},
 		{"lib", "lib"},
 		{"lib.so", "lib.so"},
 		{"my-lib-1.0.0.tar.gz", "my-lib-1.0.0"},
@@ -197,3 +271,95 @@ func matchGlob(name, pattern string) bool {
 	}
 	return name == pattern
 }
+
+func TestEnsureAll_Pinned(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	src := filepath.Join(t.TempDir(), "pkg")
+	if err := os.MkdirAll(filepath.Join(src, "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "lib", "libx.a"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tarPath, err := archive.Create(src, "linux", "amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := archive.FileSHA256(tarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, tarPath)
+	}))
+	defer srv.Close()
+
+	bad := srv.URL + "/pkg.tar.gz#sha256:" + strings.Repeat("0", 64)
+	if _, err := EnsureAll(context.Background(), []string{bad}); !errors.Is(err, archive.ErrChecksumMismatch) {
+		t.Errorf("EnsureAll(wrong pin) error = %v, want ErrChecksumMismatch", err)
+	}
+
+	good := srv.URL + "/pkg.tar.gz#sha256:" + sum
+	pkgs, err := EnsureAll(context.Background(), []string{good})
+	if err != nil {
+		t.Fatalf("EnsureAll(pin) error = %v", err)
+	}
+	if !isDir(pkgs[0].Lib) {
+		t.Errorf("lib dir %s not extracted", pkgs[0].Lib)
+	}
+}
+
+func TestEnsureAll_Lock(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Cleanup(func() { _ = lock.Use("") })
+
+	// Serve a package archive whose content can change upstream.
+	src := filepath.Join(t.TempDir(), "pkg")
+	if err := os.MkdirAll(filepath.Join(src, "include"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeHeader := func(content string) string {
+		if err := os.WriteFile(filepath.Join(src, "include", "a.h"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		path, err := archive.Create(src, "linux", "amd64")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	tarPath := writeHeader("v1")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, tarPath)
+	}))
+	defer srv.Close()
+	source := srv.URL + "/pkg.tar.gz"
+
+	lockPath := filepath.Join(t.TempDir(), lock.File)
+	if err := lock.Use(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EnsureAll(context.Background(), []string{source}); err != nil {
+		t.Fatalf("EnsureAll() error = %v", err)
+	}
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatalf("lockfile not written: %v", err)
+	}
+	if !strings.Contains(string(data), "sha256 = ") {
+		t.Errorf("lockfile = %q, want sha256 entry", data)
+	}
+
+	// The upstream asset changes; a clean cache must refuse it.
+	tarPath = writeHeader("v2")
+	if err := RemoveAllCached(); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Use(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EnsureAll(context.Background(), []string{source}); err == nil {
+		t.Fatal("EnsureAll() should fail when the archive no longer matches gox.lock")
+	}
+}
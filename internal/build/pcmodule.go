@@ -0,0 +1,179 @@
+package build
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/qntx/gox/internal/hosttool"
+)
+
+// PkgConfigModule is a pkg-config file gox writes into the packages whose
+// source matches Package, for libraries shipped without one. Go packages
+// using "#cgo pkg-config: <name>" then build against them.
+type PkgConfigModule struct {
+	Package string   `toml:"package"` // package sources it applies to; * matches any text
+	Version string   `toml:"version,omitempty"`
+	Cflags  []string `toml:"cflags,omitempty"`
+	Libs    []string `toml:"libs,omitempty"`
+}
+
+// pcModule is a [pkgconfig] entry ready to match package sources.
+type pcModule struct {
+	name string
+	re   *regexp.Regexp
+	PkgConfigModule
+}
+
+// pcModules holds the active [pkgconfig] entries, by name.
+var pcModules []pcModule
+
+// pcModuleRE matches the module names pkg-config looks files up by.
+var pcModuleRE = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]*$`)
+
+// pcMarker starts the files gox writes, telling them apart from the .pc
+// files a package ships.
+const pcMarker = "# Written by gox from [pkgconfig."
+
+// UsePkgConfig applies the [pkgconfig] modules to the packages ensured for
+// the rest of the process.
+func (c *Config) UsePkgConfig() error {
+	mods := make([]pcModule, 0, len(c.Modules))
+	for name, m := range c.Modules {
+		if !pcModuleRE.MatchString(name) {
+			return fmt.Errorf("invalid pkgconfig module name %q", name)
+		}
+		if m.Package == "" {
+			return fmt.Errorf("pkgconfig.%s: package is required", name)
+		}
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(m.Package), `\*`, ".*")
+		mods = append(mods, pcModule{name: name, re: regexp.MustCompile("^" + pattern + "$"), PkgConfigModule: m})
+	}
+	slices.SortFunc(mods, func(a, b pcModule) int { return strings.Compare(a.name, b.name) })
+	pcModules = mods
+	return nil
+}
+
+// writeModules writes the [pkgconfig] modules matching each cached package
+// into its lib/pkgconfig and removes ones gox wrote before that no longer
+// match. Files the package ships are left alone, and vendored packages
+// keep the modules they were vendored with.
+func writeModules(pkgs []*Package) error {
+	for _, p := range pkgs {
+		if p.vendored() != "" || !isDir(filepath.Dir(p.Lib)) {
+			continue
+		}
+		dir := filepath.Join(p.Lib, "pkgconfig")
+		want := map[string][]byte{}
+		for _, m := range pcModules {
+			if m.re.MatchString(p.Source) {
+				want[m.name+".pc"] = m.file(p)
+			}
+		}
+		old, _ := filepath.Glob(filepath.Join(dir, "*.pc"))
+		for _, path := range old {
+			if _, ok := want[filepath.Base(path)]; !ok && writtenByGox(path) {
+				if err := os.Remove(path); err != nil {
+					return err
+				}
+			}
+		}
+		for name, data := range want {
+			path := filepath.Join(dir, name)
+			cur, err := os.ReadFile(path)
+			if err == nil && (bytes.Equal(cur, data) || !bytes.HasPrefix(cur, []byte(pcMarker))) {
+				continue
+			}
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergeModules returns base overlaid with c; c wins on the same name.
+func mergeModules(c, base map[string]PkgConfigModule) map[string]PkgConfigModule {
+	if len(base) == 0 {
+		return c
+	}
+	out := maps.Clone(base)
+	maps.Copy(out, c)
+	return out
+}
+
+func writtenByGox(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && bytes.HasPrefix(data, []byte(pcMarker))
+}
+
+// file returns the .pc file of m for p, with paths relative to the file
+// like the ones gox writes for libraries.
+func (m *pcModule) file(p *Package) []byte {
+	libdir := "${prefix}/lib"
+	if rel, err := filepath.Rel(p.Lib, resolveLibDir(p.Lib)); err == nil && rel != "." {
+		libdir += "/" + filepath.ToSlash(rel)
+	}
+	cflags := m.Cflags
+	if isDir(p.Include) {
+		cflags = append([]string{"-I${includedir}"}, cflags...)
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s%s] in gox.toml\n", pcMarker, m.name)
+	fmt.Fprintf(&buf, "prefix=${pcfiledir}/../..\n")
+	fmt.Fprintf(&buf, "libdir=%s\n", libdir)
+	fmt.Fprintf(&buf, "includedir=${prefix}/include\n\n")
+	fmt.Fprintf(&buf, "Name: %s\n", m.name)
+	fmt.Fprintf(&buf, "Description: %s from %s\n", m.name, p.Source)
+	fmt.Fprintf(&buf, "Version: %s\n", cmp.Or(m.Version, "0"))
+	fmt.Fprintf(&buf, "Libs: %s\n", strings.Join(append([]string{"-L${libdir}"}, m.Libs...), " "))
+	fmt.Fprintf(&buf, "Cflags: %s\n", strings.Join(cflags, " "))
+	return buf.Bytes()
+}
+
+// pkgConfigDirs returns the directories holding the .pc files of pkgs.
+func pkgConfigDirs(pkgs []*Package) []string {
+	var dirs []string
+	for _, p := range pkgs {
+		for _, dir := range []string{
+			filepath.Join(resolveLibDir(p.Lib), "pkgconfig"),
+			filepath.Join(p.Lib, "pkgconfig"),
+			filepath.Join(filepath.Dir(p.Lib), "share", "pkgconfig"),
+		} {
+			if isDir(dir) && !slices.Contains(dirs, dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return dirs
+}
+
+// pkgConfigEnv points pkg-config at the .pc files of pkgs, leaving out the
+// host's own unless PKG_CONFIG_LIBDIR is set, and at the [tools] pkg-config.
+func pkgConfigEnv(pkgs []*Package) []string {
+	dirs := pkgConfigDirs(pkgs)
+	if len(dirs) == 0 {
+		return nil
+	}
+	path := strings.Join(dirs, string(os.PathListSeparator))
+	env := []string{"PKG_CONFIG_PATH=" + path}
+	if cur := os.Getenv("PKG_CONFIG_PATH"); cur != "" {
+		env[0] += string(os.PathListSeparator) + cur
+	}
+	if os.Getenv("PKG_CONFIG_LIBDIR") == "" {
+		env = append(env, "PKG_CONFIG_LIBDIR="+path)
+	}
+	if tool := hosttool.Configured("pkg-config"); tool != "" {
+		env = append(env, "PKG_CONFIG="+tool)
+	}
+	return env
+}
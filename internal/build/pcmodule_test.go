@@ -0,0 +1,173 @@
+package build
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func usePkgConfig(t *testing.T, m map[string]PkgConfigModule) error {
+	t.Helper()
+	t.Cleanup(func() { pcModules = nil })
+	return (&Config{Modules: m}).UsePkgConfig()
+}
+
+func TestUsePkgConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		m    map[string]PkgConfigModule
+	}{
+		{"no package", map[string]PkgConfigModule{"sqlite3": {Libs: []string{"-lsqlite3"}}}},
+		{"path as name", map[string]PkgConfigModule{"lib/sqlite3": {Package: "*"}}},
+		{"empty name", map[string]PkgConfigModule{"": {Package: "*"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := usePkgConfig(t, tt.m); err == nil {
+				t.Error("UsePkgConfig() succeeded")
+			}
+		})
+	}
+}
+
+// testCachedPackage returns a package with include/ and lib/ in a fresh
+// directory.
+func testCachedPackage(t *testing.T, source string) *Package {
+	t.Helper()
+	root := t.TempDir()
+	p := &Package{Source: source, Include: filepath.Join(root, "include"), Lib: filepath.Join(root, "lib"), Bin: filepath.Join(root, "bin")}
+	for name, data := range map[string]string{"include/sqlite3.h": "#define SQLITE 3\n", "lib/libsqlite3.a": "lib\n"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return p
+}
+
+func TestWriteModules(t *testing.T) {
+	p := testCachedPackage(t, "acme/sqlite@v3.46.0/sqlite-linux-amd64.tar.gz")
+	other := testCachedPackage(t, "acme/zlib@v1.3/zlib-linux-amd64.tar.gz")
+	shipped := filepath.Join(p.Lib, "pkgconfig", "shipped.pc")
+	if err := os.MkdirAll(filepath.Dir(shipped), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(shipped, []byte("Name: shipped\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := usePkgConfig(t, map[string]PkgConfigModule{
+		"sqlite3": {Package: "acme/sqlite@*", Version: "3.46.0", Cflags: []string{"-DSQLITE_THREADSAFE=1"}, Libs: []string{"-lsqlite3", "-lm"}},
+		"shipped": {Package: "*", Libs: []string{"-lshipped"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeModules([]*Package{p, other}); err != nil {
+		t.Fatalf("writeModules() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(p.Lib, "pkgconfig", "sqlite3.pc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"prefix=${pcfiledir}/../..\n",
+		"Version: 3.46.0\n",
+		"Libs: -L${libdir} -lsqlite3 -lm\n",
+		"Cflags: -I${includedir} -DSQLITE_THREADSAFE=1\n",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("sqlite3.pc = %q, want %q in it", data, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(other.Lib, "pkgconfig", "sqlite3.pc")); !os.IsNotExist(err) {
+		t.Errorf("sqlite3.pc written for a package not matching: %v", err)
+	}
+	if data, _ := os.ReadFile(shipped); string(data) != "Name: shipped\n" {
+		t.Errorf("shipped .pc overwritten: %q", data)
+	}
+	if got := pkgConfigDirs([]*Package{p, other}); !slices.Equal(got, []string{filepath.Join(p.Lib, "pkgconfig"), filepath.Join(other.Lib, "pkgconfig")}) {
+		t.Errorf("pkgConfigDirs() = %v", got)
+	}
+
+	// pkg-config reads the file back.
+	if pc, err := exec.LookPath("pkg-config"); err == nil {
+		cmd := exec.Command(pc, "--cflags", "--libs", "sqlite3")
+		cmd.Env = append(os.Environ(), "PKG_CONFIG_LIBDIR="+filepath.Join(p.Lib, "pkgconfig"), "PKG_CONFIG_PATH=")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("pkg-config: %v", err)
+		}
+		dir := filepath.Join(p.Lib, "pkgconfig")
+		want := "-DSQLITE_THREADSAFE=1 -I" + dir + "/../../include -L" + dir + "/../../lib -lsqlite3 -lm"
+		if got := strings.Join(strings.Fields(string(out)), " "); !sameFlags(got, want) {
+			t.Errorf("pkg-config output = %q, want the flags of %q", got, want)
+		}
+	}
+
+	// Modules dropped from the config are removed again.
+	if err := usePkgConfig(t, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeModules([]*Package{p}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(p.Lib, "pkgconfig", "sqlite3.pc")); !os.IsNotExist(err) {
+		t.Errorf("sqlite3.pc kept after its module was removed: %v", err)
+	}
+	if _, err := os.Stat(shipped); err != nil {
+		t.Errorf("shipped .pc removed: %v", err)
+	}
+}
+
+// sameFlags reports whether a and b hold the same space-separated flags,
+// as pkg-config versions order -I and other cflags differently.
+func sameFlags(a, b string) bool {
+	x, y := strings.Fields(a), strings.Fields(b)
+	slices.Sort(x)
+	slices.Sort(y)
+	return slices.Equal(x, y)
+}
+
+func TestPkgConfigEnv(t *testing.T) {
+	t.Setenv("PKG_CONFIG_PATH", "")
+	t.Setenv("PKG_CONFIG_LIBDIR", "")
+	p := testCachedPackage(t, "acme/sqlite@v3/sqlite.tar.gz")
+	if env := pkgConfigEnv([]*Package{p}); env != nil {
+		t.Errorf("pkgConfigEnv() without .pc files = %v, want none", env)
+	}
+	dir := filepath.Join(p.Lib, "pkgconfig")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	env := pkgConfigEnv([]*Package{p})
+	if want := []string{"PKG_CONFIG_PATH=" + dir, "PKG_CONFIG_LIBDIR=" + dir}; !slices.Equal(env, want) {
+		t.Errorf("pkgConfigEnv() = %v, want %v", env, want)
+	}
+
+	// The user's own search path is kept.
+	t.Setenv("PKG_CONFIG_LIBDIR", "/opt/sysroot/lib/pkgconfig")
+	env = pkgConfigEnv([]*Package{p})
+	if want := []string{"PKG_CONFIG_PATH=" + dir}; !slices.Equal(env, want) {
+		t.Errorf("pkgConfigEnv() with PKG_CONFIG_LIBDIR = %v, want %v", env, want)
+	}
+}
+
+func TestInherit_Modules(t *testing.T) {
+	c := &Config{Modules: map[string]PkgConfigModule{"sqlite3": {Package: "local/*"}}}
+	c.inherit(&Config{Modules: map[string]PkgConfigModule{
+		"sqlite3": {Package: "base/*"},
+		"zlib":    {Package: "base/zlib@*"},
+	}})
+	if got := c.Modules["sqlite3"].Package; got != "local/*" {
+		t.Errorf("sqlite3 package = %q, want the local module kept", got)
+	}
+	if got := c.Modules["zlib"].Package; got != "base/zlib@*" {
+		t.Errorf("zlib package = %q, want it inherited", got)
+	}
+}
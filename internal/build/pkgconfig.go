@@ -0,0 +1,95 @@
+package build
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/qntx/gox/internal/ui"
+)
+
+// archiveSystemLibs are the system libraries the Go runtime in a c-archive
+// needs from the program linking it, by GOOS.
+var archiveSystemLibs = map[string][]string{
+	"android": {"-llog"},
+	"linux":   {"-lpthread"},
+	"freebsd": {"-lpthread"},
+	"netbsd":  {"-lpthread"},
+	"darwin":  {"-framework", "CoreFoundation", "-framework", "Security", "-lresolv"},
+	"ios":     {"-framework", "CoreFoundation", "-framework", "Security", "-lresolv"},
+	"windows": {"-lws2_32", "-lwinmm", "-lntdll"},
+}
+
+// pkgConfigPath returns where the pkg-config file of a library built into a
+// prefix goes: <prefix>/<lib>/pkgconfig/<name>.pc.
+func (b *Builder) pkgConfigPath() string {
+	return filepath.Join(b.opts.Prefix, b.opts.Layout.LibDir(b.opts.GOOS), "pkgconfig", b.libName()+".pc")
+}
+
+// libName returns the name C linkers know the library by: its file name
+// without the "lib" prefix and extension, as in -l<name>.
+func (b *Builder) libName() string {
+	name := strings.TrimSuffix(filepath.Base(b.outputPath()), b.opts.BuildMode.ext(b.opts.GOOS))
+	if b.opts.BuildMode.libPrefixed(b.opts.GOOS) {
+		name = strings.TrimPrefix(name, "lib")
+	}
+	return name
+}
+
+// writePkgConfig writes the pkg-config file of the library. Its paths are
+// relative to the file itself, so the prefix stays relocatable.
+func (b *Builder) writePkgConfig() error {
+	path := b.pkgConfigPath()
+	rel, err := filepath.Rel(filepath.Dir(path), b.opts.Prefix)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, b.pkgConfig(filepath.ToSlash(rel)), 0o644); err != nil {
+		return err
+	}
+	if b.opts.Verbose {
+		ui.Label("pkg-config", path)
+	}
+	return nil
+}
+
+// pkgConfig returns the .pc file of the library for a prefix at rel from
+// the file's directory.
+func (b *Builder) pkgConfig(rel string) []byte {
+	name := b.libName()
+	version, _ := gitInfo()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "prefix=${pcfiledir}/%s\n", rel)
+	fmt.Fprintf(&buf, "libdir=%s\n", pcDir(b.opts.Layout.LibDir(b.opts.GOOS)))
+	fmt.Fprintf(&buf, "includedir=%s\n\n", pcDir(b.opts.Layout.IncludeDir()))
+	fmt.Fprintf(&buf, "Name: %s\n", name)
+	fmt.Fprintf(&buf, "Description: %s built with gox\n", name)
+	fmt.Fprintf(&buf, "Version: %s\n", cmp.Or(strings.TrimPrefix(version, "v"), "0"))
+	fmt.Fprintf(&buf, "Libs: -L${libdir} -l%s\n", name)
+	if b.opts.BuildMode == BuildCArchive {
+		// A static archive leaves its own dependencies to the final link.
+		// Library directories are build machine paths and stay out.
+		var private []string
+		for _, l := range b.opts.Libs {
+			private = append(private, "-l"+l)
+		}
+		private = append(private, archiveSystemLibs[b.opts.GOOS]...)
+		fmt.Fprintf(&buf, "Libs.private: %s\n", strings.Join(private, " "))
+	}
+	fmt.Fprintf(&buf, "Cflags: -I${includedir}\n")
+	return buf.Bytes()
+}
+
+// pcDir returns a .pc variable for dir relative to ${prefix}.
+func pcDir(dir string) string {
+	if dir == "" {
+		return "${prefix}"
+	}
+	return "${prefix}/" + filepath.ToSlash(dir)
+}
@@ -0,0 +1,90 @@
+package build
+
+import (
+	"fmt"
+
+	"github.com/qntx/gox/internal/sdk"
+)
+
+// Invocation is a go command and the environment gox runs it with.
+type Invocation struct {
+	Env  []string // variables added to the inherited environment
+	Args []string // arguments after "go"
+}
+
+// Plan returns the go invocation for verb ("build", "run", "test" or
+// "install") without downloading packages, creating directories or running
+// anything. extra holds program or test arguments for run and test.
+func (b *Builder) Plan(verb string, pkgs, extra []string) (*Invocation, error) {
+	if verb == "build" {
+		if err := b.opts.ExpandPaths(pkgs); err != nil {
+			return nil, fmt.Errorf("output template: %w", err)
+		}
+	}
+	if err := b.planPackages(); err != nil {
+		return nil, fmt.Errorf("packages: %w", err)
+	}
+	if b.opts.GOOS == "darwin" {
+		root, err := sdk.Locate(b.opts.MacOSSDK)
+		if err != nil {
+			return nil, fmt.Errorf("macos-sdk: %w", err)
+		}
+		b.sdk = root
+	}
+	if b.opts.GOOS == "ios" {
+		root, err := locateIOSSDK(b.opts.IOSSDK)
+		if err != nil {
+			return nil, fmt.Errorf("ios-sdk: %w", err)
+		}
+		b.sdk = root
+	}
+	if b.opts.GOOS == "android" {
+		root, err := ndkSysroot(b.opts.androidNDK())
+		if err != nil {
+			return nil, fmt.Errorf("android-ndk: %w", err)
+		}
+		b.ndk = root
+	}
+
+	var args []string
+	switch verb {
+	case "build":
+		args = b.buildArgs(pkgs)
+	case "run":
+		args = b.runArgs(pkgs, extra)
+	case "test":
+		args = b.testArgs(pkgs, extra)
+	case "install":
+		args = b.installArgs(pkgs)
+	default:
+		return nil, fmt.Errorf("unknown go command %q", verb)
+	}
+	return &Invocation{Env: b.buildEnv(), Args: args}, nil
+}
+
+// planPackages adds package directories like setupPackages does, without
+// downloading: packages not cached yet contribute the paths they will have
+// once fetched.
+func (b *Builder) planPackages() error {
+	if err := b.opts.ResolvePackages(); err != nil {
+		return err
+	}
+	var inc, lib, bin []string
+	for _, s := range b.opts.Packages {
+		p, err := parsePackage(s)
+		if err != nil {
+			return err
+		}
+		p.resolvePaths()
+		if p.vendored() != "" || p.isCached() {
+			i, l, bn := CollectPaths([]*Package{p})
+			inc, lib, bin = append(inc, i...), append(lib, l...), append(bin, bn...)
+			continue
+		}
+		inc, lib, bin = append(inc, p.Include), append(lib, p.Lib), append(bin, p.Bin)
+	}
+	b.opts.IncludeDirs = append(inc, b.opts.IncludeDirs...)
+	b.opts.LibDirs = append(lib, b.opts.LibDirs...)
+	b.opts.BinDirs = append(bin, b.opts.BinDirs...)
+	return nil
+}
@@ -0,0 +1,59 @@
+package build
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBuilder_Plan(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	tests := []struct {
+		name     string
+		verb     string
+		opts     Options
+		extra    []string
+		wantArgs []string
+		wantErr  bool
+	}{
+		{"build", "build", Options{GOOS: "linux", GOARCH: "amd64", Output: "dist/{{.OS}}/app"}, nil, []string{"build", "-o", "dist/linux/app", "."}, false},
+		{"test", "test", Options{GOOS: "linux", GOARCH: "amd64", BuildFlags: []string{"-race"}}, []string{"-run", "X"}, []string{"test", "-race", ".", "-run", "X"}, false},
+		{"install", "install", Options{GOOS: "linux", GOARCH: "amd64", Strip: true}, nil, []string{"install", "-ldflags=-s -w", "."}, false},
+		{"unknown", "vet", Options{GOOS: "linux", GOARCH: "amd64"}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv, err := New("/opt/zig", &tt.opts).Plan(tt.verb, nil, tt.extra)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Plan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !slices.Equal(inv.Args, tt.wantArgs) {
+				t.Errorf("Args = %q, want %q", inv.Args, tt.wantArgs)
+			}
+			if !slices.Contains(inv.Env, "GOOS=linux") {
+				t.Errorf("Env = %q, missing GOOS", inv.Env)
+			}
+		})
+	}
+
+	t.Run("uncached package", func(t *testing.T) {
+		opts := &Options{GOOS: "linux", GOARCH: "amd64", Packages: []string{"https://example.com/libfoo.tar.gz"}}
+		inv, err := New("/opt/zig", opts).Plan("build", nil, nil)
+		if err != nil {
+			t.Fatalf("Plan() error = %v", err)
+		}
+		var cflags string
+		for _, kv := range inv.Env {
+			if v, ok := strings.CutPrefix(kv, "CGO_CFLAGS="); ok {
+				cflags = v
+			}
+		}
+		if !strings.Contains(cflags, "-I") || !strings.Contains(cflags, "include") {
+			t.Errorf("CGO_CFLAGS = %q, want the package include dir", cflags)
+		}
+	})
+}
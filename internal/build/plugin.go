@@ -0,0 +1,44 @@
+package build
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/qntx/gox/internal/plugin"
+)
+
+// UsePlugins enables the plugins of [default] for the rest of the process.
+func (c *Config) UsePlugins() error {
+	return plugin.Use(c.PluginNames())
+}
+
+// PluginNames returns the plugins of [default] with relative paths
+// containing a separator resolved against the config directory; bare names
+// are looked up in PATH as gox-<name>.
+func (c *Config) PluginNames() []string {
+	names := make([]string, len(c.Default.Plugins))
+	for i, p := range c.Default.Plugins {
+		if !filepath.IsAbs(p) && strings.ContainsAny(p, `/\`) {
+			p = filepath.Join(c.dir, p)
+		}
+		names[i] = p
+	}
+	return names
+}
+
+// hook runs the enabled plugins at the target hook event.
+func (b *Builder) hook(ctx context.Context, event string) error {
+	e := plugin.Event{
+		Event:  event,
+		Target: b.opts.Target,
+		OS:     b.opts.GOOS,
+		Arch:   b.opts.GOARCH,
+		Output: b.outputPath(),
+		Prefix: b.opts.Prefix,
+	}
+	if event == plugin.PrePack {
+		e.Archive = b.opts.ArchivePath()
+	}
+	return plugin.Run(ctx, e, b.stderr)
+}
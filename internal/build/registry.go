@@ -0,0 +1,145 @@
+package build
+
+import (
+	"cmp"
+	_ "embed"
+	"fmt"
+	"maps"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+)
+
+// The built-in registry maps "<name>@<version>" package sources, such as
+// zlib@1.3, to a prebuilt package per platform, so configs need not spell
+// out the URL of each.
+
+//go:embed registry.toml
+var registryTOML string
+
+// registryRE matches the package sources looked up in the registry.
+var registryRE = regexp.MustCompile(`^([a-z0-9][a-z0-9._+-]*)@([0-9][0-9A-Za-z._+-]*|latest)$`)
+
+// registryPlatformRE matches the "<os>-<arch>" keys of registry entries.
+var registryPlatformRE = regexp.MustCompile(`^[a-z0-9]+-[a-z0-9]+$`)
+
+// registry returns the entries of registry.toml: name, version and
+// "<os>-<arch>" to a package source.
+var registry = sync.OnceValue(func() map[string]map[string]map[string]string {
+	r, err := parseRegistry(registryTOML)
+	if err != nil {
+		panic(fmt.Sprintf("registry.toml: %v", err))
+	}
+	return r
+})
+
+// parseRegistry decodes a registry and checks that every entry is a valid
+// package source for an "<os>-<arch>" platform, pinned by #sha256 so a
+// name always resolves to the same archive.
+func parseRegistry(text string) (map[string]map[string]map[string]string, error) {
+	var r map[string]map[string]map[string]string
+	if _, err := toml.Decode(text, &r); err != nil {
+		return nil, err
+	}
+	for name, versions := range r {
+		for version, builds := range versions {
+			if !registryRE.MatchString(name + "@" + version) {
+				return nil, fmt.Errorf("%s@%s: not a registry name", name, version)
+			}
+			for platform, source := range builds {
+				if !registryPlatformRE.MatchString(platform) {
+					return nil, fmt.Errorf("%s@%s: invalid platform %q", name, version, platform)
+				}
+				p, err := parsePackage(source)
+				if err != nil {
+					return nil, fmt.Errorf("%s@%s %s: %w", name, version, platform, err)
+				}
+				if p.SHA256 == "" {
+					return nil, fmt.Errorf("%s@%s %s: %s is not pinned with #sha256", name, version, platform, source)
+				}
+			}
+		}
+	}
+	return r, nil
+}
+
+// ResolvePackages returns sources with the registry names replaced by the
+// package built for goos/goarch, the host when empty. Options after the
+// name, e.g. #keep-modes, carry over; other sources are kept as given.
+// "<name>@latest" takes the newest version in the registry.
+func ResolvePackages(sources []string, goos, goarch string) ([]string, error) {
+	platform := cmp.Or(goos, runtime.GOOS) + "-" + cmp.Or(goarch, runtime.GOARCH)
+	out := slices.Clone(sources)
+	for i, source := range out {
+		spec, options, hasOptions := strings.Cut(source, "#")
+		m := registryRE.FindStringSubmatch(spec)
+		if m == nil {
+			continue
+		}
+		versions, ok := registry()[m[1]]
+		if !ok {
+			names := registryNames()
+			if len(names) == 0 {
+				return nil, fmt.Errorf("%s is not in the package registry, which is empty", spec)
+			}
+			return nil, fmt.Errorf("%s is not in the package registry, which has %s", spec, strings.Join(names, ", "))
+		}
+		version := m[2]
+		if version == latestTag {
+			version = latestVersion(versions)
+		}
+		builds, ok := versions[version]
+		if !ok {
+			return nil, fmt.Errorf("%s: the package registry has versions %s", spec, strings.Join(slices.Sorted(maps.Keys(versions)), ", "))
+		}
+		resolved, ok := builds[platform]
+		if !ok {
+			return nil, fmt.Errorf("%s has no build for %s in the package registry, only %s", spec, platform, strings.Join(slices.Sorted(maps.Keys(builds)), ", "))
+		}
+		if hasOptions {
+			resolved += "#" + options
+		}
+		out[i] = resolved
+	}
+	return out, nil
+}
+
+// ResolvePackages replaces the registry names in o.Packages with the
+// packages built for its target.
+func (o *Options) ResolvePackages() error {
+	pkgs, err := ResolvePackages(o.Packages, o.GOOS, o.GOARCH)
+	if err != nil {
+		return fmt.Errorf("packages: %w", err)
+	}
+	o.Packages = pkgs
+	return nil
+}
+
+// registryNames returns the "<name>@<version>" of every registry entry.
+func registryNames() []string {
+	var names []string
+	for name, versions := range registry() {
+		for v := range versions {
+			names = append(names, name+"@"+v)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// latestVersion returns the highest of the registry versions.
+func latestVersion(versions map[string]map[string]string) string {
+	var best string
+	var bestV []int
+	for v := range versions {
+		n, ok := parseTag(v)
+		if ok && (best == "" || slices.Compare(n, bestV) > 0) {
+			best, bestV = v, n
+		}
+	}
+	return best
+}
@@ -0,0 +1,170 @@
+package build
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testRegistry stands in for registry.toml in the tests of registry names.
+const testRegistry = `
+[cublas."12.9"]
+linux-amd64 = "https://example.com/libcublas-linux-x86_64-12.9.1.4-archive.tar.xz#sha256:1111111111111111111111111111111111111111111111111111111111111111"
+windows-amd64 = "https://example.com/libcublas-windows-x86_64-12.9.1.4-archive.zip#sha256:2222222222222222222222222222222222222222222222222222222222222222"
+
+[cudart."12.9"]
+linux-amd64 = "gocnn-lib/cudart@v12.9.79/linux-amd64.tar.xz#sha256:3333333333333333333333333333333333333333333333333333333333333333"
+windows-amd64 = "gocnn-lib/cudart@v12.9.79/windows-amd64.zip#sha256:4444444444444444444444444444444444444444444444444444444444444444"
+`
+
+// useTestRegistry makes testRegistry the registry until the test ends.
+func useTestRegistry(t *testing.T) {
+	t.Helper()
+	r, err := parseRegistry(testRegistry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := registry
+	registry = func() map[string]map[string]map[string]string { return r }
+	t.Cleanup(func() { registry = saved })
+}
+
+// TestRegistry checks every entry of registry.toml is a valid package
+// source for a known platform, pinned by #sha256.
+func TestRegistry(t *testing.T) {
+	if _, err := parseRegistry(registryTOML); err != nil {
+		t.Fatalf("registry.toml: %v", err)
+	}
+}
+
+func TestParseRegistry(t *testing.T) {
+	const pin = "#sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	tests := []struct {
+		name    string
+		text    string
+		wantErr string
+	}{
+		{"pinned", `zlib."1.3".linux-amd64 = "https://example.com/zlib.tar.gz` + pin + `"`, ""},
+		{"empty", "", ""},
+		{"unpinned", `zlib."1.3".linux-amd64 = "https://example.com/zlib.tar.gz"`, "not pinned with #sha256"},
+		{"unpinned with options", `zlib."1.3".linux-amd64 = "https://example.com/zlib.zip#keep-modes"`, "not pinned with #sha256"},
+		{"invalid source", `zlib."1.3".linux-amd64 = "zlib.tar.gz` + pin + `"`, "zlib@1.3 linux-amd64"},
+		{"invalid platform", `zlib."1.3".linux = "https://example.com/zlib.tar.gz` + pin + `"`, `invalid platform "linux"`},
+		{"invalid name", `Zlib."1.3".linux-amd64 = "https://example.com/zlib.tar.gz` + pin + `"`, "not a registry name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseRegistry(tt.text)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("parseRegistry() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseRegistry() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolvePackages(t *testing.T) {
+	useTestRegistry(t)
+	tests := []struct {
+		name    string
+		sources []string
+		goos    string
+		goarch  string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "registry name",
+			sources: []string{"cudart@12.9"},
+			goos:    "linux",
+			goarch:  "amd64",
+			want:    []string{"gocnn-lib/cudart@v12.9.79/linux-amd64.tar.xz#sha256:3333333333333333333333333333333333333333333333333333333333333333"},
+		},
+		{
+			name:    "latest version",
+			sources: []string{"cublas@latest"},
+			goos:    "linux",
+			goarch:  "amd64",
+			want:    []string{"https://example.com/libcublas-linux-x86_64-12.9.1.4-archive.tar.xz#sha256:1111111111111111111111111111111111111111111111111111111111111111"},
+		},
+		{
+			name:    "options carry over",
+			sources: []string{"cudart@12.9#keep-modes"},
+			goos:    "windows",
+			goarch:  "amd64",
+			want:    []string{"gocnn-lib/cudart@v12.9.79/windows-amd64.zip#sha256:4444444444444444444444444444444444444444444444444444444444444444#keep-modes"},
+		},
+		{
+			name:    "other sources kept",
+			sources: []string{"owner/repo@v1/lib.tar.gz", "https://example.com/lib.zip", "vcpkg:zlib:x64-linux"},
+			goos:    "linux",
+			goarch:  "amd64",
+			want:    []string{"owner/repo@v1/lib.tar.gz", "https://example.com/lib.zip", "vcpkg:zlib:x64-linux"},
+		},
+		{
+			name:    "unknown name",
+			sources: []string{"nosuchlib@1.0"},
+			goos:    "linux",
+			goarch:  "amd64",
+			wantErr: "not in the package registry, which has cublas@12.9, cudart@12.9",
+		},
+		{
+			name:    "unknown version",
+			sources: []string{"cudart@11.8"},
+			goos:    "linux",
+			goarch:  "amd64",
+			wantErr: "the package registry has versions 12.9",
+		},
+		{
+			name:    "no build for platform",
+			sources: []string{"cudart@12.9"},
+			goos:    "darwin",
+			goarch:  "arm64",
+			wantErr: "no build for darwin-arm64 in the package registry, only linux-amd64, windows-amd64",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolvePackages(tt.sources, tt.goos, tt.goarch)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ResolvePackages() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolvePackages() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ResolvePackages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionsResolvePackages(t *testing.T) {
+	useTestRegistry(t)
+	sources := []string{"cublas@12.9"}
+	o := &Options{GOOS: "linux", GOARCH: "amd64", Packages: sources}
+	if err := o.ResolvePackages(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(o.Packages[0], "https://example.com/libcublas-linux-x86_64-12.9.1.4-archive.tar.xz#") {
+		t.Errorf("Packages = %v, want the linux-amd64 cublas build", o.Packages)
+	}
+	if sources[0] != "cublas@12.9" {
+		t.Errorf("ResolvePackages() changed the caller's slice: %v", sources)
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	versions := map[string]map[string]string{"3.9": nil, "3.10": nil, "3.2": nil, "nightly": nil}
+	if got := latestVersion(versions); got != "3.10" {
+		t.Errorf("latestVersion() = %q, want 3.10", got)
+	}
+}
@@ -0,0 +1,79 @@
+package build
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/qntx/gox/internal/zig"
+)
+
+// DepsReportFile is the report name written into a prefix.
+const DepsReportFile = "dependencies.json"
+
+// DepsReport records the toolchain and packages used to produce an artifact.
+type DepsReport struct {
+	Target     string        `json:"target"`
+	ZigVersion string        `json:"zig_version"`
+	ZigPath    string        `json:"zig_path"`
+	Packages   []DepsPackage `json:"packages"`
+}
+
+// DepsPackage describes one package contributing to a build.
+type DepsPackage struct {
+	Source  string `json:"source"`
+	URL     string `json:"url"`
+	Digest  string `json:"digest,omitempty"`
+	Include string `json:"include,omitempty"`
+	Lib     string `json:"lib,omitempty"`
+	Bin     string `json:"bin,omitempty"`
+}
+
+// depsReportPath returns where the report is written: inside the prefix, or
+// beside the output binary, which without -o is the go command's default
+// output in the working directory.
+func (b *Builder) depsReportPath(pkgs []string) string {
+	if b.opts.Prefix != "" {
+		return filepath.Join(b.opts.Prefix, DepsReportFile)
+	}
+	out := b.opts.Output
+	if out == "" {
+		out = binaryName(pkgs) + b.opts.BuildMode.ext(b.opts.GOOS)
+	}
+	return out + "." + DepsReportFile
+}
+
+func (b *Builder) depsReport() *DepsReport {
+	r := &DepsReport{
+		Target:     b.opts.GOOS + "/" + b.opts.GOARCH,
+		ZigVersion: zig.ResolveVersion(b.opts.ZigVersion),
+		ZigPath:    b.zig,
+		Packages:   []DepsPackage{},
+	}
+	for _, p := range b.pkgs {
+		dp := DepsPackage{Source: p.Source, URL: p.URL}
+		if sum := p.archiveSHA256(); sum != "" {
+			dp.Digest = "sha256:" + sum
+		}
+		if isDir(p.Include) {
+			dp.Include = p.Include
+		}
+		if isDir(p.Lib) {
+			dp.Lib = resolveLibDir(p.Lib)
+		}
+		if isDir(p.Bin) {
+			dp.Bin = p.Bin
+		}
+		r.Packages = append(r.Packages, dp)
+	}
+	return r
+}
+
+func (b *Builder) writeDepsReport(pkgs []string) error {
+	path := b.depsReportPath(pkgs)
+	data, err := json.MarshalIndent(b.depsReport(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
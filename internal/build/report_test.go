@@ -0,0 +1,121 @@
+package build
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qntx/gox/internal/archive"
+	"github.com/qntx/gox/internal/zig"
+)
+
+func TestBuilder_DepsReportPath(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"prefix", Options{Prefix: "dist"}, filepath.Join("dist", DepsReportFile)},
+		{"output", Options{Output: "bin/app"}, "bin/app." + DepsReportFile},
+		{"default output", Options{GOOS: "linux"}, "app." + DepsReportFile},
+		{"default windows output", Options{GOOS: "windows"}, "app.exe." + DepsReportFile},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New("", &tt.opts).depsReportPath([]string{"./cmd/app"}); got != tt.want {
+				t.Errorf("depsReportPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_WriteDepsReport(t *testing.T) {
+	prefix := t.TempDir()
+	b := New("/opt/zig", &Options{GOOS: "linux", GOARCH: "amd64", Prefix: prefix})
+	b.pkgs = []*Package{{Source: "owner/repo@v1/lib.tar.gz", URL: "https://example.com/lib.tar.gz", Dir: "missing"}}
+
+	if err := b.writeDepsReport(nil); err != nil {
+		t.Fatalf("writeDepsReport() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(prefix, DepsReportFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r DepsReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if r.Target != "linux/amd64" {
+		t.Errorf("Target = %q, want linux/amd64", r.Target)
+	}
+	if r.ZigVersion != "master" {
+		t.Errorf("ZigVersion = %q, want master", r.ZigVersion)
+	}
+	if len(r.Packages) != 1 || r.Packages[0].URL != "https://example.com/lib.tar.gz" {
+		t.Errorf("Packages = %+v", r.Packages)
+	}
+}
+
+func TestBuilder_DepsReportArchiveDigest(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	src := filepath.Join(t.TempDir(), "pkg")
+	if err := os.MkdirAll(filepath.Join(src, "include"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "include", "a.h"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tarPath, err := archive.Create(src, "linux", "amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(tarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, tarPath)
+	}))
+	defer srv.Close()
+
+	b := New("", &Options{})
+	if b.pkgs, err = EnsureAll(context.Background(), []string{srv.URL + "/pkg.tar.gz"}); err != nil {
+		t.Fatalf("EnsureAll() error = %v", err)
+	}
+	if got := b.depsReport().Packages[0].Digest; got != want {
+		t.Errorf("Digest = %q, want the archive's %q", got, want)
+	}
+
+	// A pinned package reports its pin without being downloaded again.
+	b.pkgs = []*Package{{Source: "owner/repo@v1/lib.tar.gz", SHA256: "abc123"}}
+	if got := b.depsReport().Packages[0].Digest; got != "sha256:abc123" {
+		t.Errorf("Digest = %q, want sha256:abc123", got)
+	}
+}
+
+func TestBuilder_DepsReportZigVersionFile(t *testing.T) {
+	proj := t.TempDir()
+	if err := os.WriteFile(filepath.Join(proj, "go.mod"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(proj, zig.VersionFile), []byte("0.14.1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(proj)
+
+	if got := New("", &Options{}).depsReport().ZigVersion; got != "0.14.1" {
+		t.Errorf("ZigVersion = %q, want 0.14.1 from %s", got, zig.VersionFile)
+	}
+	if got := New("", &Options{ZigVersion: "0.15.2"}).depsReport().ZigVersion; got != "0.15.2" {
+		t.Errorf("ZigVersion = %q, want the configured 0.15.2", got)
+	}
+}
@@ -0,0 +1,52 @@
+package build
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/qntx/gox/internal/archive"
+)
+
+// sourceDateEpoch returns the time reproducible builds stamp into archives
+// and the build date: $SOURCE_DATE_EPOCH, else the commit time of HEAD. ok
+// is false outside a git repository without the variable.
+var sourceDateEpoch = sync.OnceValues(func() (time.Time, bool) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		epoch = git("log", "-1", "--format=%ct")
+	}
+	sec, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0).UTC(), true
+})
+
+// reproducibleFlags are the go flags --reproducible adds: no absolute paths
+// and no VCS stamp, which would differ between checkouts.
+var reproducibleFlags = []string{"-trimpath", "-buildvcs=false"}
+
+// buildDate returns the build date the [version] section records, the
+// source date for reproducible builds.
+func (b *Builder) buildDate() string {
+	if t, ok := sourceDateEpoch(); ok && b.opts.Reproducible {
+		return t.Format(time.RFC3339)
+	}
+	return buildDate()
+}
+
+// archiveOptions returns how the target's archive is written: for
+// reproducible builds, deterministically with the source date as every
+// entry's time.
+func (b *Builder) archiveOptions() archive.CreateOptions {
+	if !b.opts.Reproducible {
+		return archive.CreateOptions{}
+	}
+	opts := archive.CreateOptions{Deterministic: true}
+	if t, ok := sourceDateEpoch(); ok {
+		opts.ModTime = t
+	}
+	return opts
+}
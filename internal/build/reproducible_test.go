@@ -0,0 +1,51 @@
+package build
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/qntx/gox/internal/archive"
+)
+
+func TestBuilder_Reproducible(t *testing.T) {
+	epoch, git := sourceDateEpoch, gitInfo
+	t.Cleanup(func() { sourceDateEpoch, gitInfo = epoch, git })
+	sourceDateEpoch = func() (time.Time, bool) { return time.Unix(1700000000, 0).UTC(), true }
+	gitInfo = func() (string, string) { return "v1.2.3", "abc1234" }
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+
+	opts := Options{
+		GOOS: "wasip1", GOARCH: "wasm", Output: "app.wasm", Reproducible: true,
+		Version: Version{Date: "Date"},
+	}
+	b := New("", &opts)
+
+	wantFlags := "-buildid= -X main.Date=2023-11-14T22:13:20Z"
+	if got := b.goLDFlags(); got != wantFlags {
+		t.Errorf("goLDFlags() = %q, want %q", got, wantFlags)
+	}
+	wantArgs := []string{"build", "-o", "app.wasm", "-trimpath", "-buildvcs=false", "-ldflags=" + wantFlags, "."}
+	if got := b.buildArgs(nil); !slices.Equal(got, wantArgs) {
+		t.Errorf("buildArgs() = %q, want %q", got, wantArgs)
+	}
+	wantArgs = []string{"install", "-trimpath", "-buildvcs=false", "-ldflags=" + wantFlags, "."}
+	if got := b.installArgs(nil); !slices.Equal(got, wantArgs) {
+		t.Errorf("installArgs() = %q, want %q", got, wantArgs)
+	}
+	if env := b.buildEnv(); !slices.Contains(env, "SOURCE_DATE_EPOCH=1700000000") {
+		t.Errorf("buildEnv() = %q, want SOURCE_DATE_EPOCH=1700000000", env)
+	}
+	if got := b.archiveOptions(); got.ModTime.Unix() != 1700000000 || !got.Deterministic {
+		t.Errorf("archiveOptions() = %+v, want deterministic at the source date", got)
+	}
+
+	opts.Reproducible = false
+	if got := b.buildArgs(nil); slices.Contains(got, "-trimpath") {
+		t.Errorf("buildArgs() = %q, want no -trimpath", got)
+	}
+	if got := b.archiveOptions(); !reflect.DeepEqual(got, archive.CreateOptions{}) {
+		t.Errorf("archiveOptions() = %+v, want defaults", got)
+	}
+}
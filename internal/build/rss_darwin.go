@@ -0,0 +1,15 @@
+package build
+
+import (
+	"os"
+	"syscall"
+)
+
+// peakRSS returns the peak resident memory of a finished process tree in
+// bytes. macOS reports ru_maxrss in bytes.
+func peakRSS(ps *os.ProcessState) int64 {
+	if ru, ok := ps.SysUsage().(*syscall.Rusage); ok {
+		return int64(ru.Maxrss)
+	}
+	return 0
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package build
+
+import "os"
+
+// peakRSS is not available on this platform.
+func peakRSS(*os.ProcessState) int64 {
+	return 0
+}
@@ -0,0 +1,17 @@
+//go:build unix && !darwin
+
+package build
+
+import (
+	"os"
+	"syscall"
+)
+
+// peakRSS returns the peak resident memory of a finished process tree in
+// bytes. Linux and the BSDs report ru_maxrss in kilobytes.
+func peakRSS(ps *os.ProcessState) int64 {
+	if ru, ok := ps.SysUsage().(*syscall.Rusage); ok {
+		return int64(ru.Maxrss) * 1024
+	}
+	return 0
+}
@@ -0,0 +1,376 @@
+package build
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/qntx/gox/internal/cache"
+	"github.com/qntx/gox/internal/ui"
+)
+
+//go:generate go run ../../cmd/gox config schema -o ../../gox.schema.json
+
+// SchemaURL is where the gox.toml JSON Schema is published, for editors
+// that support a "#:schema" directive or a schema store.
+const SchemaURL = "https://raw.githubusercontent.com/qntx/gox/main/gox.schema.json"
+
+// schema is the subset of JSON Schema gox.toml needs.
+type schema struct {
+	Schema      string             `json:"$schema,omitempty"`
+	ID          string             `json:"$id,omitempty"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	AnyOf       []*schema          `json:"anyOf,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Items       *schema            `json:"items,omitempty"`
+	Properties  map[string]*schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	// Additional is false for structs and the value schema for maps.
+	Additional any `json:"additionalProperties,omitempty"`
+}
+
+// schemaDocs describes config keys, by "<parent>.<key>" where a key means
+// different things in different tables and by plain key otherwise.
+var schemaDocs = map[string]string{
+	"extends":           "Config file path or URL to inherit from, optionally pinned with #sha256:<digest>",
+	"default":           "Global defaults applied to all targets",
+	"mirrors":           "Package URL prefix rewrites, e.g. \"https://github.com/\" = \"https://ghproxy.internal/\"",
+	"tools":             "Paths of host tools: go, git, docker, podman",
+	"pkgconfig":         "pkg-config files written into packages that ship none, by module name",
+	"pkgconfig.package": "Package sources the module is written for; * matches any text, e.g. \"*/sqlite@*\"",
+	"pkgconfig.version": "Module version (default: 0)",
+	"pkgconfig.cflags":  "Compiler flags besides -I for the package's include/",
+	"pkgconfig.libs":    "Linker flags besides -L for the package's lib/, e.g. [\"-lsqlite3\"]",
+	"windows":           "Resources linked into windows binaries: icon, manifest and version information (strings support templates)",
+	"icon":              ".ico file shown for the binary, relative to gox.toml",
+	"manifest":          "Application manifest, relative to gox.toml",
+	"file-version":      "File version, e.g. {{.Version}} (default: product-version)",
+	"product-version":   "Product version (default: file-version)",
+	"company-name":      "CompanyName string",
+	"file-description":  "FileDescription string",
+	"product-name":      "ProductName string",
+	"copyright":         "LegalCopyright string",
+	"original-filename": "OriginalFilename string (default: the binary's name)",
+	"internal-name":     "InternalName string (default: the binary's name without .exe)",
+	"comments":          "Comments string",
+	"version":           "Variables set with -ldflags -X to the version, commit and build date",
+	"version.package":   "Import path of the package holding the version variables (default: main)",
+	"version.version":   "Variable set to git describe --tags --always --dirty, e.g. Version",
+	"commit":            "Variable set to the short commit hash",
+	"date":              "Variable set to the build date (RFC 3339, UTC; $SOURCE_DATE_EPOCH when set)",
+	"package":           "Metadata and extra files of the Linux packages pack = \"deb\", \"rpm\" and \"apk\" write",
+	"package.name":      "Package name (supports templates; default: the output's base name)",
+	"package.version":   "Package version (supports templates; default: {{.Version}})",
+	"maintainer":        "Package maintainer, e.g. \"Jane Doe <jane@example.com>\" (required for deb)",
+	"description":       "Package description: a summary line, then details",
+	"homepage":          "Project homepage URL",
+	"license":           "License of the package as an SPDX expression, e.g. \"MIT\" (required for rpm)",
+	"depends":           "Packages the package requires, e.g. \"libc6 (>= 2.31)\", \"glibc >= 2.28\" or \"musl>=1.2\"",
+	"systemd":           "systemd unit files installed to /usr/lib/systemd/system and enabled on install, relative to gox.toml",
+	"preinstall":        "Script run before installation, relative to gox.toml",
+	"postinstall":       "Script run after installation, relative to gox.toml (default: enable the systemd units)",
+	"preremove":         "Script run before removal, relative to gox.toml (default: stop the systemd units)",
+	"postremove":        "Script run after removal, relative to gox.toml",
+	"scoop":             "Scoop manifest written for the packed windows zips, once url is set",
+	"scoop.url":         "Base URL the archives are published under, e.g. https://github.com/acme/app/releases/download/{{.Version}} (supports templates)",
+	"scoop.name":        "Manifest name, written as <name>.json (default: the output's base name)",
+	"scoop.description": "App description (default: the [package] summary line)",
+	"scoop.homepage":    "App homepage URL (default: the [package] homepage)",
+	"scoop.license":     "App license (default: the [package] license)",
+	"scoop.output":      "Manifest path, relative to gox.toml (default: <name>.json next to the archives)",
+	"target":            "Build target definitions",
+	"variant":           "Named flavors of a target, built as <target>-<variant>",
+	"name":              "Target identifier for --target",
+	"variant.name":      "Variant name, appended to the target name",
+	"os":                "Target operating system (GOOS)",
+	"arch":              "Target architecture (GOARCH), or universal for a darwin fat binary",
+	"output":            "Output binary path (supports templates)",
+	"prefix":            "Output prefix directory (supports templates)",
+	"zig-version":       "Zig compiler version (default: .zig-version, else master)",
+	"go-version":        "Go toolchain version, fetched via GOTOOLCHAIN",
+	"cache-scope":       "user (shared ~/.cache/gox) or project (.gox/ next to gox.toml)",
+	"zig-minisign":      "Verify Zig tarballs against their ziglang.org minisign signature",
+	"theme":             "Terminal theme; GOX_THEME overrides it",
+	"zig-mirror":        "Base URL serving Zig tarballs by file name instead of ziglang.org",
+	"zig-path":          "Installed Zig (binary, directory or command name) to use instead of downloading one",
+	"confirm-download":  "Package download size above which interactive sessions ask first, e.g. 4G",
+	"extract-umask":     "Octal bits cleared from the modes of extracted package and Zig files (default: 022)",
+	"extract-faithful":  "Apply archive modes unchanged when extracting, including setuid/setgid",
+	"parallel":          "Targets gox build runs at once; 0 picks one per two CPUs (default: 1)",
+	"watch-ignore":      "Glob patterns of paths or file names --watch does not watch, relative to the module root",
+	"vendor":            "Resolve packages from third_party/gox (written by gox vendor) before the cache",
+	"plugins":           "Plugins run at the pre-build, post-build, pre-pack and publish hooks: names of gox-<name> executables on PATH, or paths relative to gox.toml",
+	"linkmode":          "Link mode",
+	"buildmode":         "What go build produces: exe, c-shared for a shared library or c-archive for a static library, each with its C header",
+	"include":           "C header include directories",
+	"lib":               "Library search directories",
+	"link":              "Libraries to link",
+	"lib-exclude":       "Glob patterns skipped when copying libs to prefix",
+	"packages":          "Pre-built packages to download",
+	"flags":             "Additional go build flags",
+	"layout":            "Directory names of a --prefix install",
+	"layout.bin":        "Binary directory (default: bin)",
+	"layout.lib":        "Library directory (default: lib)",
+	"layout.include":    "Header directory (default: include)",
+	"layout.flat":       "Place binaries and shared libraries in the prefix root (default on windows)",
+	"sysroot":           "Root filesystem providing the loader and libc for --sysroot and qemu-user runs (default: / for the host architecture)",
+	"macos-sdk":         "macOS SDK for darwin targets: a MacOSX.sdk directory, a version registered with gox sdk add, or an archive URL (default: $SDKROOT, then the newest registered SDK)",
+	"ios-sdk":           "iOS SDK for ios targets: an iPhoneOS.sdk or iPhoneSimulator.sdk directory, a version registered with gox sdk add, or an archive URL",
+	"simulator":         "Build an ios target for the iOS simulator (implied by ios/amd64)",
+	"android-ndk":       "Android NDK for android targets, whose sysroot provides bionic libc (default: $ANDROID_NDK_HOME, then $ANDROID_NDK_ROOT)",
+	"android-api":       "Android API level android targets build for (default and minimum: 21)",
+	"sign":              "Signing of the binary after it is compiled and before it is packed",
+	"sign.command":      "Command template signing {{.File}}, e.g. codesign or signtool; $VAR is read from the environment and an empty expansion skips the target",
+	"retry":             "Retry policy for downloads",
+	"retry.attempts":    "Total tries including the first (default: 4)",
+	"retry.backoff":     "Delay before the first retry, doubled each time (default: 500ms)",
+	"retry.max-backoff": "Cap on a single delay (default: 15s)",
+	"no-rpath":          "Disable rpath",
+	"pack":              "Pack the build: true for an archive, the name of a format, or a table",
+	"pack.include":      "Extra files added to the archive, \"pattern[=dst]\": globs (with **) relative to the working directory, optionally placed under dst",
+	"pack.exclude":      "Patterns of prefix files left out of the archive or package, matched against the path in the prefix and the base name, e.g. \"*.pdb\" or \"**/testdata/**\"",
+	"pack.name":         "Archive file name without extension, an output template (default: <output or prefix>-<os>-<arch>)",
+	"format":            "What pack writes: archive (tar.gz, or zip for windows), deb, rpm or apk (static builds)",
+	"deps-report":       "Write dependencies.json next to the artifact",
+	"pkg-config":        "Write lib/pkgconfig/<name>.pc for a c-shared or c-archive library in a prefix",
+	"reproducible":      "Build byte-identical binaries and archives: -trimpath, no build ID or VCS stamp, deterministic archives dated SOURCE_DATE_EPOCH or the commit",
+	"checksum":          "Write <archive>.sha256 and SHA256SUMS when packing",
+	"isolate-gocache":   "Use a separate GOCACHE per GOOS/GOARCH under the gox cache",
+	"strip":             "Strip symbols (-ldflags=\"-s -w\")",
+	"verbose":           "Verbose output",
+}
+
+// schemaEnums lists the accepted values of keys with a fixed set.
+var schemaEnums = map[string][]string{
+	"linkmode":    {string(LinkAuto), string(LinkStatic), string(LinkDynamic)},
+	"buildmode":   buildModeNames(),
+	"format":      packFormatNames(),
+	"cache-scope": {string(cache.ScopeUser), string(cache.ScopeProject)},
+	"theme":       ui.Themes(),
+	"os":          slices.Sorted(maps.Keys(zigOS)),
+	"arch":        append(slices.Sorted(maps.Keys(zigArch)), Universal),
+}
+
+// Schema returns the JSON Schema of gox.toml, derived from Config so it
+// follows every field added there.
+func Schema() ([]byte, error) {
+	s := typeSchema(reflect.TypeFor[Config](), "")
+	s.Schema = "https://json-schema.org/draft/2020-12/schema"
+	s.ID = SchemaURL
+	s.Title = ConfigFile
+	s.Description = "gox cross-compilation config"
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// typeSchema maps a config type to its schema. parent is the TOML key
+// holding the type, used to look up descriptions of its fields.
+func typeSchema(t reflect.Type, parent string) *schema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem(), parent)
+	case reflect.String:
+		return &schema{Type: "string"}
+	case reflect.Bool:
+		return &schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &schema{Type: "integer"}
+	case reflect.Slice:
+		return &schema{Type: "array", Items: typeSchema(t.Elem(), parent)}
+	case reflect.Map:
+		return &schema{Type: "object", Additional: typeSchema(t.Elem(), parent)}
+	case reflect.Struct:
+		s := &schema{Type: "object", Properties: map[string]*schema{}, Additional: false}
+		for i := range t.NumField() {
+			f := t.Field(i)
+			tag := f.Tag.Get("toml")
+			key, opts, _ := strings.Cut(tag, ",")
+			if !f.IsExported() || key == "" || key == "-" {
+				continue
+			}
+			p := typeSchema(f.Type, key)
+			doc, ok := schemaDocs[parent+"."+key]
+			if !ok {
+				doc = schemaDocs[key]
+			}
+			p.Description = doc
+			if p.Type == "string" {
+				p.Enum = schemaEnums[key]
+			}
+			s.Properties[key] = p
+			if !strings.Contains(opts, "omitempty") {
+				s.Required = append(s.Required, key)
+			}
+		}
+		if t == reflect.TypeFor[Pack]() {
+			// pack = true and pack = "<format>" stand for the table.
+			return &schema{AnyOf: []*schema{{Type: "boolean"}, {Type: "string", Enum: schemaEnums["format"]}, s}}
+		}
+		return s
+	}
+	panic(fmt.Sprintf("schema: unsupported config type %s", t))
+}
+
+// validate checks a decoded TOML value against s and returns one problem
+// per mismatch, prefixed with its key path.
+func (s *schema) validate(path string, v any) []string {
+	at := func(format string, args ...any) []string {
+		return []string{fmt.Sprintf("%s: %s", displayPath(path), fmt.Sprintf(format, args...))}
+	}
+	if len(s.AnyOf) > 0 {
+		var types []string
+		for _, alt := range s.AnyOf {
+			t := cmp.Or(map[string]string{"object": "table"}[alt.Type], alt.Type)
+			if t == tomlType(v) {
+				return alt.validate(path, v)
+			}
+			types = append(types, t)
+		}
+		return at("expected %s, got %s", strings.Join(types, ", "), tomlType(v))
+	}
+	switch s.Type {
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return at("expected string, got %s", tomlType(v))
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			return at("%q is not one of %s", str, strings.Join(s.Enum, ", "))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return at("expected boolean, got %s", tomlType(v))
+		}
+	case "integer":
+		if _, ok := v.(int64); !ok {
+			return at("expected integer, got %s", tomlType(v))
+		}
+	case "array":
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice {
+			return at("expected array, got %s", tomlType(v))
+		}
+		var problems []string
+		for i := range rv.Len() {
+			problems = append(problems, s.Items.validate(fmt.Sprintf("%s[%d]", path, i), rv.Index(i).Interface())...)
+		}
+		return problems
+	case "object":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return at("expected table, got %s", tomlType(v))
+		}
+		var problems []string
+		for _, key := range s.Required {
+			if _, ok := m[key]; !ok {
+				problems = append(problems, at("missing required key %q", key)...)
+			}
+		}
+		for _, key := range slices.Sorted(maps.Keys(m)) {
+			sub := joinPath(path, key)
+			if p, ok := s.Properties[key]; ok {
+				problems = append(problems, p.validate(sub, m[key])...)
+			} else if add, ok := s.Additional.(*schema); ok {
+				problems = append(problems, add.validate(sub, m[key])...)
+			} else {
+				problems = append(problems, fmt.Sprintf("%s: unknown key", sub))
+			}
+		}
+		return problems
+	}
+	return nil
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "config"
+	}
+	return path
+}
+
+// tomlType names the TOML type of a decoded value.
+func tomlType(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int64:
+		return "integer"
+	case float64:
+		return "float"
+	case map[string]any:
+		return "table"
+	}
+	if reflect.ValueOf(v).Kind() == reflect.Slice {
+		return "array"
+	}
+	return "datetime"
+}
+
+// ValidateConfig checks the config at path, or the nearest gox.toml, against
+// the schema and then checks every target's options. It returns the file
+// checked and the problems found; err is only set when the file cannot be
+// read or parsed.
+func ValidateConfig(path string) (string, []string, error) {
+	if path == "" {
+		if path = findConfig(); path == "" {
+			return "", nil, ErrConfigNotFound
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return path, nil, err
+	}
+	var doc map[string]any
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return path, nil, err
+	}
+	problems := typeSchema(reflect.TypeFor[Config](), "").validate("", doc)
+	if len(problems) > 0 {
+		return path, problems, nil
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return path, []string{err.Error()}, nil
+	}
+	opts, err := cfg.ToOptions(nil)
+	if err != nil {
+		return path, []string{err.Error()}, nil
+	}
+	for _, o := range opts {
+		o.Normalize()
+		err := o.Validate()
+		if err == nil {
+			err = o.ResolvePackages()
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("target %s: %v", cmp.Or(o.Target, o.GOOS+"/"+o.GOARCH), err))
+		}
+	}
+	return path, problems, nil
+}
@@ -0,0 +1,131 @@
+package build
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchema_PublishedFile(t *testing.T) {
+	want, err := Schema()
+	if err != nil {
+		t.Fatalf("Schema() error = %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join("..", "..", "gox.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("gox.schema.json is out of date: run go generate ./internal/build")
+	}
+}
+
+func TestSchema_Documented(t *testing.T) {
+	var walk func(path string, s *schema)
+	walk = func(path string, s *schema) {
+		for key, p := range s.Properties {
+			if p.Description == "" {
+				t.Errorf("%s has no description in schemaDocs", joinPath(path, key))
+			}
+			walk(joinPath(path, key), p)
+		}
+		if s.Items != nil {
+			walk(path, s.Items)
+		}
+		for _, alt := range s.AnyOf {
+			walk(path, alt)
+		}
+	}
+	walk("", typeSchema(reflect.TypeFor[Config](), ""))
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{"valid", `
+[default]
+linkmode = "static"
+[default.layout]
+flat = true
+[mirrors]
+"https://github.com/" = "https://proxy.example.com/"
+[[target]]
+name = "linux-amd64"
+os = "linux"
+arch = "amd64"
+prefix = "dist/app"
+pack = true
+[[target.variant]]
+name = "server"
+pack = { format = "deb" }
+[package]
+maintainer = "Jane Doe <jane@example.com>"
+`, nil},
+		{"schema", `
+extends = 3
+[default]
+linkmode = "staic"
+zig-versoin = "0.14.0"
+[default.retry]
+attempts = "3"
+[tools]
+go = 1
+[[target]]
+os = "plan9"
+arch = "amd64"
+flags = "-race"
+pack = "zip"
+[[target.variant]]
+output = "x"
+pack = 1
+`, []string{
+			`default.linkmode: "staic" is not one of auto, static, dynamic`,
+			"default.retry.attempts: expected integer, got string",
+			"default.zig-versoin: unknown key",
+			"extends: expected string, got integer",
+			"target[0].flags: expected array, got string",
+			`target[0].os: "plan9" is not one of android, darwin, freebsd, ios, linux, netbsd, wasip1, windows`,
+			`target[0].pack: "zip" is not one of archive, deb, rpm, apk`,
+			`target[0].variant[0]: missing required key "name"`,
+			"target[0].variant[0].pack: expected boolean, string, table, got integer",
+			"tools.go: expected string, got integer",
+		}},
+		{"options", `
+[[target]]
+name = "bad-go"
+os = "linux"
+arch = "amd64"
+go-version = "latest"
+`, []string{"target bad-go: "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ConfigFile)
+			if err := os.WriteFile(path, []byte(tt.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, got, err := ValidateConfig(path)
+			if err != nil {
+				t.Fatalf("ValidateConfig() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ValidateConfig() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if !strings.HasPrefix(got[i], tt.want[i]) {
+					t.Errorf("problem %d = %q, want prefix %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, _, err := ValidateConfig(filepath.Join(t.TempDir(), "missing.toml")); err == nil {
+		t.Error("ValidateConfig() of a missing file succeeded")
+	}
+}
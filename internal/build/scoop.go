@@ -0,0 +1,143 @@
+package build
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/qntx/gox/internal/archive"
+)
+
+// Scoop is the [scoop] section: a Scoop manifest written for the packed
+// windows archives of a build, once URL says where they are published.
+type Scoop struct {
+	URL         string `toml:"url,omitempty"`         // base URL of the archives, a template
+	Name        string `toml:"name,omitempty"`        // default: the output's base name
+	Description string `toml:"description,omitempty"` // default: the [package] summary
+	Homepage    string `toml:"homepage,omitempty"`    // default: the [package] homepage
+	License     string `toml:"license,omitempty"`     // default: the [package] license
+	Output      string `toml:"output,omitempty"`      // manifest path, relative to gox.toml
+}
+
+// Merge fills unset fields of s from base.
+func (s Scoop) Merge(base Scoop) Scoop {
+	s.URL = cmp.Or(s.URL, base.URL)
+	s.Name = cmp.Or(s.Name, base.Name)
+	s.Description = cmp.Or(s.Description, base.Description)
+	s.Homepage = cmp.Or(s.Homepage, base.Homepage)
+	s.License = cmp.Or(s.License, base.License)
+	s.Output = cmp.Or(s.Output, base.Output)
+	return s
+}
+
+// scoop returns the [scoop] section with a relative output resolved against
+// the config directory and metadata it leaves unset taken from [package].
+func (c *Config) scoop() Scoop {
+	s := c.Scoop
+	if s.Output != "" && !filepath.IsAbs(s.Output) {
+		s.Output = filepath.Join(c.dir, s.Output)
+	}
+	summary, _, _ := strings.Cut(strings.TrimSpace(c.Package.Description), "\n")
+	s.Description = cmp.Or(s.Description, summary)
+	s.Homepage = cmp.Or(s.Homepage, c.Package.Homepage)
+	s.License = cmp.Or(s.License, c.Package.License)
+	return s
+}
+
+// scoopArchs maps GOARCH to the architectures of a Scoop manifest.
+var scoopArchs = map[string]string{
+	"386":   "32bit",
+	"amd64": "64bit",
+	"arm64": "arm64",
+}
+
+// scoopManifest is a Scoop app manifest.
+type scoopManifest struct {
+	Version      string                `json:"version"`
+	Description  string                `json:"description,omitempty"`
+	Homepage     string                `json:"homepage,omitempty"`
+	License      string                `json:"license,omitempty"`
+	Architecture map[string]*scoopArch `json:"architecture"`
+}
+
+// scoopArch is the download of one architecture.
+type scoopArch struct {
+	URL        string   `json:"url"`
+	Hash       string   `json:"hash"`
+	ExtractDir string   `json:"extract_dir,omitempty"`
+	Bin        []string `json:"bin,omitempty"`
+}
+
+// WriteScoopManifest writes the Scoop manifest of the packed windows zips
+// among opts, with dir holding it unless [scoop] output says otherwise, and
+// returns its path. It writes nothing and returns "" when [scoop] has no url
+// or no target packs a windows zip.
+func WriteScoopManifest(opts []*Options, dir string) (string, error) {
+	var s Scoop
+	var name string
+	m := &scoopManifest{Architecture: map[string]*scoopArch{}}
+	targets := map[string]string{}
+	for _, o := range opts {
+		arch, ok := scoopArchs[o.GOARCH]
+		if o.Scoop.URL == "" || o.GOOS != "windows" || !o.Pack || cmp.Or(o.PackFormat, PackArchive) != PackArchive || !ok {
+			continue
+		}
+		target := cmp.Or(o.Target, o.GOOS+"-"+o.GOARCH)
+		if prev, dup := targets[arch]; dup {
+			return "", fmt.Errorf("scoop: %s and %s both pack windows/%s", prev, target, o.GOARCH)
+		}
+		targets[arch] = target
+
+		path := o.ArchivePath()
+		hash, err := archive.FileSHA256(path)
+		if err != nil {
+			return "", fmt.Errorf("scoop: %w", err)
+		}
+		data := o.pathData(nil, true)
+		base, err := expandPath("scoop.url", o.Scoop.URL, data)
+		if err != nil {
+			return "", err
+		}
+		a := &scoopArch{URL: strings.TrimSuffix(base, "/") + "/" + filepath.Base(path), Hash: hash}
+		src, bin := cmp.Or(o.Prefix, o.Output), filepath.Base(o.Output)
+		if o.Prefix != "" {
+			// Archives hold the prefix directory.
+			a.ExtractDir = filepath.Base(o.Prefix)
+			if bin, err = filepath.Rel(o.Prefix, New("", o).outputPath()); err != nil {
+				return "", err
+			}
+		}
+		if !o.BuildMode.IsLib() {
+			a.Bin = []string{strings.ReplaceAll(filepath.ToSlash(bin), "/", `\`)}
+		}
+		m.Architecture[arch] = a
+
+		if s.URL == "" {
+			s = o.Scoop
+			m.Version = strings.TrimPrefix(data.Version, "v")
+			base := filepath.Base(src)
+			name = cmp.Or(s.Name, strings.ToLower(strings.TrimSuffix(base, filepath.Ext(base))))
+		}
+	}
+	if s.URL == "" {
+		return "", nil
+	}
+	if m.Version == "" {
+		return "", errors.New("scoop: no version: tag the commit so git describe finds one")
+	}
+	m.Description, m.Homepage, m.License = s.Description, s.Homepage, s.License
+
+	out, err := json.MarshalIndent(m, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	dst := cmp.Or(s.Output, filepath.Join(dir, name+".json"))
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return "", err
+	}
+	return dst, os.WriteFile(dst, append(out, '\n'), 0o644)
+}
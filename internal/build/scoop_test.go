@@ -0,0 +1,95 @@
+package build
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qntx/gox/internal/archive"
+)
+
+func TestWriteScoopManifest(t *testing.T) {
+	git := gitInfo
+	t.Cleanup(func() { gitInfo = git })
+	gitInfo = func() (string, string) { return "v1.4.0", "abc1234" }
+
+	dir := t.TempDir()
+	scoop := Scoop{URL: "https://example.com/app/releases/download/{{.Version}}/", Description: "An app", License: "MIT"}
+	flat := true
+	opts := []*Options{
+		{GOOS: "windows", GOARCH: "amd64", Prefix: filepath.Join(dir, "App"), Layout: Layout{Flat: &flat}, Pack: true, Scoop: scoop},
+		{GOOS: "windows", GOARCH: "arm64", Output: filepath.Join(dir, "arm64", "app.exe"), Pack: true, Scoop: scoop},
+		{GOOS: "linux", GOARCH: "amd64", Prefix: filepath.Join(dir, "App"), Pack: true, Scoop: scoop},
+		{GOOS: "windows", GOARCH: "386", Output: filepath.Join(dir, "app.exe"), Scoop: scoop},
+	}
+	for _, o := range opts[:3] {
+		if err := os.MkdirAll(filepath.Dir(o.ArchivePath()), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(o.ArchivePath(), []byte(o.GOARCH), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path, err := WriteScoopManifest(opts, dir)
+	if err != nil {
+		t.Fatalf("WriteScoopManifest() error = %v", err)
+	}
+	if want := filepath.Join(dir, "app.json"); path != want {
+		t.Errorf("path = %q, want %q", path, want)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m scoopManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != "1.4.0" || m.Description != "An app" || m.License != "MIT" {
+		t.Errorf("manifest = %+v", m)
+	}
+	if len(m.Architecture) != 2 {
+		t.Fatalf("architectures = %v, want 64bit and arm64", m.Architecture)
+	}
+	amd64, arm64 := m.Architecture["64bit"], m.Architecture["arm64"]
+	sum, _ := archive.FileSHA256(opts[0].ArchivePath())
+	if want := "https://example.com/app/releases/download/v1.4.0/App-windows-amd64.zip"; amd64.URL != want {
+		t.Errorf("64bit url = %q, want %q", amd64.URL, want)
+	}
+	if amd64.Hash != sum || amd64.ExtractDir != "App" || len(amd64.Bin) != 1 || amd64.Bin[0] != "App.exe" {
+		t.Errorf("64bit = %+v", amd64)
+	}
+	if arm64.ExtractDir != "" || len(arm64.Bin) != 1 || arm64.Bin[0] != "app.exe" {
+		t.Errorf("arm64 = %+v", arm64)
+	}
+
+	opts[1].GOARCH = "amd64"
+	if _, err := WriteScoopManifest(opts, dir); err == nil {
+		t.Error("WriteScoopManifest() with two windows/amd64 archives succeeded")
+	}
+
+	for _, o := range opts {
+		o.Scoop.URL = ""
+	}
+	if path, err := WriteScoopManifest(opts, dir); path != "" || err != nil {
+		t.Errorf("WriteScoopManifest() without url = %q, %v", path, err)
+	}
+}
+
+func TestConfig_Scoop(t *testing.T) {
+	c := &Config{
+		Package: Packaging{Description: "An app\nthat does things", Homepage: "https://example.com", License: "MIT"},
+		Scoop:   Scoop{URL: "https://example.com/dl", License: "Apache-2.0", Output: "bucket/app.json"},
+		dir:     "/src",
+	}
+	got := c.scoop()
+	want := Scoop{
+		URL: "https://example.com/dl", Description: "An app", Homepage: "https://example.com",
+		License: "Apache-2.0", Output: filepath.Join("/src", "bucket", "app.json"),
+	}
+	if got != want {
+		t.Errorf("scoop() = %+v, want %+v", got, want)
+	}
+}
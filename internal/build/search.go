@@ -0,0 +1,141 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// RegistryEntry is a package of the built-in registry.
+type RegistryEntry struct {
+	Name      string   // <name>@<version>, as given to --pkg
+	Platforms []string // <os>-<arch> it has builds for
+}
+
+// SearchRegistry returns the registry entries whose name contains query,
+// ignoring case, sorted by name. An empty query returns them all.
+func SearchRegistry(query string) []RegistryEntry {
+	query = strings.ToLower(query)
+	var out []RegistryEntry
+	for name, versions := range registry() {
+		for version, builds := range versions {
+			e := RegistryEntry{Name: name + "@" + version, Platforms: slices.Sorted(maps.Keys(builds))}
+			if strings.Contains(e.Name, query) {
+				out = append(out, e)
+			}
+		}
+	}
+	slices.SortFunc(out, func(a, b RegistryEntry) int { return strings.Compare(a.Name, b.Name) })
+	return out
+}
+
+// ReleaseAsset is an archive published with a GitHub release.
+type ReleaseAsset struct {
+	Source     string // owner/repo@tag/asset, as given to --pkg
+	Platform   string // <os>-<arch> read from the asset name, or just <os>; "" when it names neither
+	Prerelease bool
+}
+
+// SearchReleases returns the archives published with the recent releases
+// of repo ("owner/repo") whose name contains query, ignoring case, newest
+// release first. Drafts and assets gox cannot extract are left out.
+func SearchReleases(ctx context.Context, repo, query string) ([]ReleaseAsset, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("invalid repository %q: want owner/repo", repo)
+	}
+	releases, err := fetchReleases(ctx, owner, name)
+	if err != nil {
+		return nil, fmt.Errorf("%s releases: %w", repo, err)
+	}
+	query = strings.ToLower(query)
+	var out []ReleaseAsset
+	for _, r := range releases {
+		if r.Draft {
+			continue
+		}
+		for _, a := range r.Assets {
+			if trimArchiveExt(a.Name) == a.Name || !strings.Contains(strings.ToLower(a.Name), query) {
+				continue
+			}
+			out = append(out, ReleaseAsset{
+				Source:     fmt.Sprintf("%s@%s/%s", repo, r.Tag, a.Name),
+				Platform:   assetPlatform(a.Name),
+				Prerelease: r.Prerelease,
+			})
+		}
+	}
+	return out, nil
+}
+
+// assetWordRE splits asset names into words; x86_64 and x86-64 are taken
+// as one.
+var assetWordRE = regexp.MustCompile(`x86[-_]64|[a-z0-9]+`)
+
+// assetOS and assetArch map the words release assets commonly name
+// platforms with to GOOS and GOARCH.
+var (
+	assetOS = map[string]string{
+		"linux":   "linux",
+		"windows": "windows",
+		"win":     "windows",
+		"win32":   "windows",
+		"win64":   "windows",
+		"mingw":   "windows",
+		"darwin":  "darwin",
+		"macos":   "darwin",
+		"osx":     "darwin",
+		"apple":   "darwin",
+		"android": "android",
+		"ios":     "ios",
+		"freebsd": "freebsd",
+		"wasi":    "wasip1",
+	}
+	assetArch = map[string]string{
+		"amd64":       "amd64",
+		"x64":         "amd64",
+		"x86_64":      "amd64",
+		"x86-64":      "amd64",
+		"win64":       "amd64",
+		"arm64":       "arm64",
+		"aarch64":     "arm64",
+		"386":         "386",
+		"i386":        "386",
+		"i686":        "386",
+		"x86":         "386",
+		"arm":         "arm",
+		"armv7":       "arm",
+		"armhf":       "arm",
+		"riscv64":     "riscv64",
+		"loong64":     "loong64",
+		"loongarch64": "loong64",
+		"ppc64le":     "ppc64le",
+		"s390x":       "s390x",
+		"wasm":        "wasm",
+		"wasm32":      "wasm",
+	}
+)
+
+// assetPlatform returns the <os>-<arch> an asset name mentions, just the
+// os when it names no arch, or "" when it names no os.
+func assetPlatform(name string) string {
+	var goos, goarch string
+	for _, w := range assetWordRE.FindAllString(strings.ToLower(trimArchiveExt(name)), -1) {
+		if v, ok := assetOS[w]; ok && goos == "" {
+			goos = v
+		}
+		if v, ok := assetArch[w]; ok && goarch == "" {
+			goarch = v
+		}
+	}
+	switch {
+	case goos == "":
+		return ""
+	case goarch == "":
+		return goos
+	}
+	return goos + "-" + goarch
+}
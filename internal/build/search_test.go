@@ -0,0 +1,111 @@
+package build
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSearchRegistry(t *testing.T) {
+	useTestRegistry(t)
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"cublas@12.9", "cudart@12.9"}},
+		{"CUDART", []string{"cudart@12.9"}},
+		{"@12.9", []string{"cublas@12.9", "cudart@12.9"}},
+		{"openssl", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var got []string
+			for _, e := range SearchRegistry(tt.query) {
+				got = append(got, e.Name)
+				if !slices.Equal(e.Platforms, []string{"linux-amd64", "windows-amd64"}) {
+					t.Errorf("%s platforms = %v", e.Name, e.Platforms)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SearchRegistry(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchReleases(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/lib/releases" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`[
+			{"tag_name": "v2.0.0-rc1", "prerelease": true, "assets": [{"name": "lib-linux-arm64.tar.xz"}]},
+			{"tag_name": "v1.1.0", "draft": true, "assets": [{"name": "lib-linux-amd64.tar.gz"}]},
+			{"tag_name": "v1.0.0", "assets": [
+				{"name": "lib-linux-amd64.tar.gz"},
+				{"name": "lib-windows-x64.zip"},
+				{"name": "checksums.txt"}
+			]}
+		]`))
+	}))
+	defer srv.Close()
+	old := GitHubAPI
+	GitHubAPI = srv.URL
+	t.Cleanup(func() { GitHubAPI = old })
+
+	got, err := SearchReleases(t.Context(), "acme/lib", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ReleaseAsset{
+		{Source: "acme/lib@v2.0.0-rc1/lib-linux-arm64.tar.xz", Platform: "linux-arm64", Prerelease: true},
+		{Source: "acme/lib@v1.0.0/lib-linux-amd64.tar.gz", Platform: "linux-amd64"},
+		{Source: "acme/lib@v1.0.0/lib-windows-x64.zip", Platform: "windows-amd64"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("SearchReleases() = %v, want %v", got, want)
+	}
+
+	got, err = SearchReleases(t.Context(), "acme/lib", "Windows")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Platform != "windows-amd64" {
+		t.Errorf("SearchReleases(Windows) = %v, want the windows zip", got)
+	}
+
+	for _, repo := range []string{"acme", "acme/lib/x", "/lib"} {
+		if _, err := SearchReleases(t.Context(), repo, ""); err == nil || !strings.Contains(err.Error(), "want owner/repo") {
+			t.Errorf("SearchReleases(%q) error = %v", repo, err)
+		}
+	}
+	if _, err := SearchReleases(t.Context(), "acme/gone", ""); err == nil || !strings.Contains(err.Error(), "acme/gone releases") {
+		t.Errorf("SearchReleases(unknown repo) error = %v", err)
+	}
+}
+
+func TestAssetPlatform(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"linux-amd64.tar.xz", "linux-amd64"},
+		{"libcublas-linux-x86_64-12.9.1.4-archive.tar.xz", "linux-amd64"},
+		{"libcublas-windows-x86_64-12.9.1.4-archive.zip", "windows-amd64"},
+		{"sqlite-win32-x64.zip", "windows-amd64"},
+		{"lib-macos-aarch64.tar.gz", "darwin-arm64"},
+		{"lib_Linux_i686.tar.gz", "linux-386"},
+		{"lib-android.zip", "android"},
+		{"headers-1.2.0.tar.gz", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := assetPlatform(tt.name); got != tt.want {
+				t.Errorf("assetPlatform(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package build
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/qntx/gox/internal/hosttool"
+	"github.com/qntx/gox/internal/ui"
+)
+
+// Sign configures the command that signs a target's binary after it is
+// compiled and before it is packed.
+type Sign struct {
+	// Command is a template expanded with SignData, e.g.
+	// "codesign --force --sign $APPLE_IDENTITY {{.File}}". $VAR references
+	// are expanded from the environment after the template, so their values
+	// are neither template text nor printed with --verbose. The result is
+	// split into words on unquoted white space. A command that expands to
+	// nothing leaves the target unsigned.
+	Command string `toml:"command,omitempty"`
+}
+
+// Merge fills unset fields of s from base.
+func (s Sign) Merge(base Sign) Sign {
+	s.Command = cmp.Or(s.Command, base.Command)
+	return s
+}
+
+// SignData holds the values available to sign.command: those of output
+// templates plus the file to sign.
+type SignData struct {
+	PathData
+	File string // absolute path of the binary
+}
+
+// sign runs the target's sign.command on its output binary.
+func (b *Builder) sign(ctx context.Context, pkgs []string) error {
+	out := b.outputPath()
+	if b.opts.Sign.Command == "" || out == "" {
+		return nil
+	}
+	file, err := filepath.Abs(out)
+	if err != nil {
+		return err
+	}
+	args, shown, err := signArgs(b.opts.Sign.Command, &SignData{PathData: *b.opts.pathData(pkgs, usesGit(b.opts.Sign.Command)), File: file})
+	if err != nil || len(args) == 0 {
+		return err
+	}
+	if b.opts.Verbose {
+		ui.Label("sign", shown)
+	}
+
+	start := time.Now()
+	cmd := hosttool.Command(ctx, args[0], args[1:]...)
+	cmd.Env = os.Environ()
+	cmd.Stdout = b.stdout
+	cmd.Stderr = b.stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	ui.Success("Signed %s in %s", out, ui.FormatDuration(time.Since(start)))
+	return nil
+}
+
+// envRefRE matches the markers signArgs puts in place of $VAR references
+// while the template runs.
+var envRefRE = regexp.MustCompile("\x00([0-9]+)\x00")
+
+// signArgs expands data in command, splits the result into words and then
+// expands the environment within each word, so values with spaces or quotes
+// stay one argument. An unquoted reference that expands to nothing drops its
+// word, as in a shell. shown is the command with data expanded but the $VAR
+// references left as they are, safe to print.
+func signArgs(command string, data *SignData) (args []string, shown string, err error) {
+	var names []string
+	marked := os.Expand(command, func(name string) string {
+		names = append(names, name)
+		return fmt.Sprintf("\x00%d\x00", len(names)-1)
+	})
+	s, err := expandPath("sign.command", marked, data)
+	if err != nil {
+		return nil, "", err
+	}
+	refs := func(s string, value func(name string) string) string {
+		return envRefRE.ReplaceAllStringFunc(s, func(m string) string {
+			i, _ := strconv.Atoi(envRefRE.FindStringSubmatch(m)[1])
+			return value(names[i])
+		})
+	}
+	words, quoted, err := splitWords(s)
+	if err != nil {
+		return nil, "", fmt.Errorf("sign.command: %w", err)
+	}
+	for i, w := range words {
+		if arg := refs(w, os.Getenv); arg != "" || quoted[i] {
+			args = append(args, arg)
+		}
+	}
+	shown = strings.TrimSpace(refs(s, func(name string) string { return "$" + name }))
+	return args, shown, nil
+}
+
+// splitWords splits s on white space outside single or double quotes,
+// which group words and are not part of them. quoted reports which words
+// contained quotes.
+func splitWords(s string) (words []string, quoted []bool, err error) {
+	var (
+		word  strings.Builder
+		quote rune
+		in    bool // inside a word, which may be empty: ""
+		q     bool // the current word contains quotes
+	)
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, in, q = r, true, true
+		case unicode.IsSpace(r):
+			if in {
+				words, quoted = append(words, word.String()), append(quoted, q)
+				word.Reset()
+				in, q = false, false
+			}
+		default:
+			word.WriteRune(r)
+			in = true
+		}
+	}
+	if quote != 0 {
+		return nil, nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if in {
+		words, quoted = append(words, word.String()), append(quoted, q)
+	}
+	return words, quoted, nil
+}
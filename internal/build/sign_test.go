@@ -0,0 +1,117 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{"codesign -s - app", []string{"codesign", "-s", "-", "app"}, false},
+		{"  signtool  sign\t/a  ", []string{"signtool", "sign", "/a"}, false},
+		{`sign "/my dir/app" '' x`, []string{"sign", "/my dir/app", "", "x"}, false},
+		{`a"b c"d 'e "f"'`, []string{"ab cd", `e "f"`}, false},
+		{"", nil, false},
+		{`sign "app`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, _, err := splitWords(tt.in)
+			if (err != nil) != tt.wantErr || !slices.Equal(got, tt.want) {
+				t.Errorf("splitWords() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignArgs(t *testing.T) {
+	t.Setenv("GOX_TEST_IDENTITY", "Developer ID")
+	t.Setenv("GOX_TEST_PASSWORD", "{{.Missing}} s3cret")
+	t.Setenv("GOX_TEST_FLAGS", "--force --timestamp")
+	t.Setenv("GOX_TEST_QUOTED", `it's "one" arg`)
+	t.Setenv("GOX_TEST_EMPTY", "")
+	data := &SignData{PathData: PathData{Name: "app", OS: "darwin", Arch: "arm64"}, File: "/out dir/$HOME/app"}
+	tests := []struct {
+		command   string
+		want      []string
+		wantShown string
+	}{
+		{
+			`codesign --sign "$GOX_TEST_IDENTITY" "{{.File}}"`,
+			[]string{"codesign", "--sign", "Developer ID", "/out dir/$HOME/app"},
+			`codesign --sign "$GOX_TEST_IDENTITY" "/out dir/$HOME/app"`,
+		},
+		{
+			`rcodesign sign --p12-password "${GOX_TEST_PASSWORD}" {{.Name}}`,
+			[]string{"rcodesign", "sign", "--p12-password", "{{.Missing}} s3cret", "app"},
+			`rcodesign sign --p12-password "$GOX_TEST_PASSWORD" app`,
+		},
+		{
+			`codesign $GOX_TEST_FLAGS $GOX_TEST_UNSET {{.Name}}`,
+			[]string{"codesign", "--force --timestamp", "app"},
+			`codesign $GOX_TEST_FLAGS $GOX_TEST_UNSET app`,
+		},
+		{
+			`signtool --password $GOX_TEST_QUOTED --pin "$GOX_TEST_EMPTY" {{.Name}}`,
+			[]string{"signtool", "--password", `it's "one" arg`, "--pin", "", "app"},
+			`signtool --password $GOX_TEST_QUOTED --pin "$GOX_TEST_EMPTY" app`,
+		},
+		{
+			`{{if eq .OS "darwin"}}rcodesign sign {{.Name}}-{{.Arch}}{{end}}`,
+			[]string{"rcodesign", "sign", "app-arm64"},
+			`rcodesign sign app-arm64`,
+		},
+		{`{{if eq .OS "windows"}}signtool sign {{.File}}{{end}}`, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			got, shown, err := signArgs(tt.command, data)
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Errorf("signArgs() = %q, %v, want %q", got, err, tt.want)
+			}
+			if shown != tt.wantShown {
+				t.Errorf("signArgs() shown = %q, want %q", shown, tt.wantShown)
+			}
+		})
+	}
+	if _, _, err := signArgs("sign {{.Missing}}", data); err == nil {
+		t.Error("signArgs() accepted an unknown template field")
+	}
+}
+
+func TestBuilder_Sign(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake signer is a shell script")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "signed")
+	signer := filepath.Join(dir, "signer")
+	if err := os.WriteFile(signer, []byte("#!/bin/sh\necho \"$@\" > "+log+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "app")
+	opts := &Options{GOOS: "darwin", GOARCH: "arm64", Output: out, Sign: Sign{Command: signer + " --sign - {{.File}}"}}
+	if err := New("", opts).sign(t.Context(), nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "--sign - " + out; strings.TrimSpace(string(got)) != want {
+		t.Errorf("signer got %q, want %q", got, want)
+	}
+
+	opts.Sign.Command = "false {{.File}}"
+	if err := New("", opts).sign(t.Context(), nil); err == nil {
+		t.Error("sign() ignored a failing command")
+	}
+}
@@ -0,0 +1,257 @@
+package build
+
+import (
+	"cmp"
+	"context"
+	"debug/elf"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/qntx/gox/internal/hosttool"
+)
+
+// qemuArch maps GOARCH to the qemu-user binary suffix.
+var qemuArch = map[string]string{
+	"386":     "i386",
+	"amd64":   "x86_64",
+	"arm":     "arm",
+	"arm64":   "aarch64",
+	"loong64": "loongarch64",
+	"ppc64le": "ppc64le",
+	"riscv64": "riscv64",
+	"s390x":   "s390x",
+}
+
+// sysrootLibDir is where libraries are placed inside a sysroot; the runner
+// puts it on LD_LIBRARY_PATH so the layout of the libc root does not matter.
+const sysrootLibDir = "/usr/lib"
+
+// Sysroot runs linux binaries in a minimal root filesystem holding only the
+// binary, the shared libraries it needs from packages and the loader and
+// libc of a chosen root, so a library missing from the packages or a libc
+// too old for the binary fails the run instead of a customer's machine.
+type Sysroot struct {
+	GOARCH  string
+	Libc    string   // root filesystem providing the loader and libc
+	LibDirs []string // package library directories, searched first
+}
+
+// NewSysroot returns the sysroot for running opts' binaries. Without a
+// configured sysroot, binaries for the host architecture use the host's libc.
+func NewSysroot(opts *Options) (*Sysroot, error) {
+	if runtime.GOOS != "linux" {
+		return nil, errors.New("sysroot runs need a linux host")
+	}
+	if opts.GOOS != "linux" {
+		return nil, fmt.Errorf("sysroot runs only support linux targets, not %s", opts.GOOS)
+	}
+	if _, ok := qemuArch[opts.GOARCH]; !ok {
+		return nil, fmt.Errorf("sysroot runs do not support %s", opts.GOARCH)
+	}
+	if opts.Sysroot == "" && opts.GOARCH != runtime.GOARCH && !opts.LinkMode.IsStatic() {
+		return nil, fmt.Errorf("set sysroot to a linux/%s root filesystem (e.g. from debootstrap) to provide its libc", opts.GOARCH)
+	}
+	return sysrootOf(opts), nil
+}
+
+func sysrootOf(opts *Options) *Sysroot {
+	return &Sysroot{
+		GOARCH:  opts.GOARCH,
+		Libc:    cmp.Or(opts.Sysroot, "/"),
+		LibDirs: opts.LibDirs,
+	}
+}
+
+// ExecArgs returns the command go test -exec runs test binaries with: a gox
+// sysroot-exec invocation carrying s.
+func (s *Sysroot) ExecArgs() []string {
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "gox"
+	}
+	args := []string{exe, "sysroot-exec", "--arch", s.GOARCH, "--libc", s.Libc}
+	for _, d := range s.LibDirs {
+		args = append(args, "--lib", d)
+	}
+	return args
+}
+
+// Assemble copies binary into dir/bin together with its loader and every
+// shared library it needs, transitively. Libraries found neither in the
+// package directories nor in the libc root are reported together.
+func (s *Sysroot) Assemble(binary, dir string) (string, error) {
+	bin := filepath.Join(dir, "bin", filepath.Base(binary))
+	if err := copyInto(binary, bin); err != nil {
+		return "", err
+	}
+
+	interp, needed, err := elfDeps(binary)
+	if err != nil {
+		return "", err
+	}
+	if interp != "" {
+		if err := copyInto(filepath.Join(s.Libc, interp), filepath.Join(dir, interp)); err != nil {
+			return "", fmt.Errorf("loader: %w", err)
+		}
+	}
+
+	var missing []string
+	seen := map[string]bool{}
+	for len(needed) > 0 {
+		lib := needed[0]
+		needed = needed[1:]
+		if seen[lib] {
+			continue
+		}
+		seen[lib] = true
+		src := s.find(lib)
+		if src == "" {
+			missing = append(missing, lib)
+			continue
+		}
+		if err := copyInto(src, filepath.Join(dir, sysrootLibDir, lib)); err != nil {
+			return "", err
+		}
+		_, deps, err := elfDeps(src)
+		if err != nil {
+			return "", err
+		}
+		needed = append(needed, deps...)
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return "", fmt.Errorf("%s needs %s, found neither in packages nor in %s",
+			filepath.Base(binary), strings.Join(missing, ", "), s.Libc)
+	}
+	return bin, nil
+}
+
+// find returns the path of lib in the package directories or the usual
+// library directories of the libc root.
+func (s *Sysroot) find(lib string) string {
+	dirs := slices.Clone(s.LibDirs)
+	triple := multiarch[s.GOARCH]
+	for _, d := range []string{"lib/" + triple, "usr/lib/" + triple, "lib64", "usr/lib64", "lib", "usr/lib"} {
+		dirs = append(dirs, filepath.Join(s.Libc, d))
+	}
+	for _, d := range dirs {
+		p := filepath.Join(d, lib)
+		if fi, err := os.Stat(p); err == nil && fi.Mode().IsRegular() {
+			return p
+		}
+	}
+	return ""
+}
+
+// multiarch holds the Debian multiarch directory names of each GOARCH.
+var multiarch = map[string]string{
+	"386":     "i386-linux-gnu",
+	"amd64":   "x86_64-linux-gnu",
+	"arm":     "arm-linux-gnueabihf",
+	"arm64":   "aarch64-linux-gnu",
+	"loong64": "loongarch64-linux-gnu",
+	"ppc64le": "powerpc64le-linux-gnu",
+	"riscv64": "riscv64-linux-gnu",
+	"s390x":   "s390x-linux-gnu",
+}
+
+// Command returns the command running bin, assembled in dir, inside the
+// sysroot: bwrap for the host architecture, qemu-user otherwise.
+func (s *Sysroot) Command(ctx context.Context, dir, bin string, args []string) (*exec.Cmd, error) {
+	inner := "/bin/" + filepath.Base(bin)
+	if s.GOARCH == runtime.GOARCH {
+		if _, err := hosttool.Path("bwrap"); err == nil {
+			wd, err := os.Getwd()
+			if err != nil {
+				return nil, err
+			}
+			bargs := []string{
+				"--bind", dir, "/",
+				"--dev", "/dev",
+				"--proc", "/proc",
+				"--tmpfs", "/tmp",
+				"--bind", wd, wd,
+				"--chdir", wd,
+				"--setenv", "LD_LIBRARY_PATH", sysrootLibDir,
+				"--die-with-parent",
+				inner,
+			}
+			return hosttool.Command(ctx, "bwrap", append(bargs, args...)...), nil
+		}
+	}
+	qemu := "qemu-" + qemuArch[s.GOARCH]
+	if _, err := hosttool.Path(qemu); err != nil {
+		if s.GOARCH == runtime.GOARCH {
+			return nil, fmt.Errorf("sysroot runs need bwrap or %s: %w", qemu, err)
+		}
+		return nil, err
+	}
+	qargs := append([]string{"-L", dir, "-E", "LD_LIBRARY_PATH=" + sysrootLibDir, bin}, args...)
+	return hosttool.Command(ctx, qemu, qargs...), nil
+}
+
+// elfDeps returns the program interpreter and DT_NEEDED libraries of an ELF
+// file. Static binaries have neither.
+func elfDeps(path string) (string, []string, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	var interp string
+	for _, p := range f.Progs {
+		if p.Type != elf.PT_INTERP {
+			continue
+		}
+		data := make([]byte, p.Filesz)
+		if _, err := p.ReadAt(data, 0); err != nil {
+			return "", nil, fmt.Errorf("%s: interpreter: %w", path, err)
+		}
+		interp = strings.TrimRight(string(data), "\x00")
+	}
+	needed, err := f.ImportedLibraries()
+	if err != nil && !errors.Is(err, elf.ErrNoSymbols) {
+		return "", nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return interp, needed, nil
+}
+
+// copyInto copies the file at src, following symlinks, to dst, keeping its
+// mode and creating parent directories.
+func copyInto(src, dst string) error {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, fi.Mode().Perm())
+}
+
+// quoteExec joins args into a go -exec value. The go command splits it on
+// spaces and strips one level of single or double quotes, without escapes.
+func quoteExec(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		switch {
+		case a != "" && !strings.ContainsAny(a, " \t\n\r'\""):
+			quoted[i] = a
+		case !strings.Contains(a, "'"):
+			quoted[i] = "'" + a + "'"
+		default:
+			quoted[i] = `"` + a + `"`
+		}
+	}
+	return strings.Join(quoted, " ")
+}
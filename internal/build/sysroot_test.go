@@ -0,0 +1,131 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNewSysroot(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sysroot runs need a linux host")
+	}
+	other := "arm64"
+	if runtime.GOARCH == other {
+		other = "amd64"
+	}
+	tests := []struct {
+		name     string
+		opts     Options
+		wantLibc string
+		wantErr  string
+	}{
+		{"host arch", Options{GOOS: "linux", GOARCH: runtime.GOARCH}, "/", ""},
+		{"configured", Options{GOOS: "linux", GOARCH: other, Sysroot: "/srv/root"}, "/srv/root", ""},
+		{"cross static", Options{GOOS: "linux", GOARCH: other, LinkMode: LinkStatic}, "/", ""},
+		{"cross dynamic", Options{GOOS: "linux", GOARCH: other}, "", "set sysroot"},
+		{"windows", Options{GOOS: "windows", GOARCH: "amd64"}, "", "only support linux"},
+		{"unknown arch", Options{GOOS: "linux", GOARCH: "mips"}, "", "do not support mips"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sr, err := NewSysroot(&tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewSysroot() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sr.Libc != tt.wantLibc {
+				t.Errorf("Libc = %q, want %q", sr.Libc, tt.wantLibc)
+			}
+		})
+	}
+}
+
+func TestSysroot_Assemble(t *testing.T) {
+	bin := "/bin/ls"
+	interp, needed, err := elfDeps(bin)
+	if err != nil || interp == "" || len(needed) == 0 {
+		t.Skipf("%s is not a dynamic ELF binary", bin)
+	}
+
+	t.Run("host libc", func(t *testing.T) {
+		dir := t.TempDir()
+		sr := &Sysroot{GOARCH: runtime.GOARCH, Libc: "/"}
+		got, err := sr.Assemble(bin, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := filepath.Join(dir, "bin", "ls"); got != want {
+			t.Errorf("Assemble() = %q, want %q", got, want)
+		}
+		for _, p := range append([]string{interp}, needed...) {
+			if !strings.HasPrefix(p, "/") {
+				p = filepath.Join(sysrootLibDir, p)
+			}
+			if _, err := os.Stat(filepath.Join(dir, p)); err != nil {
+				t.Errorf("sysroot lacks %s: %v", p, err)
+			}
+		}
+	})
+
+	t.Run("package libs first", func(t *testing.T) {
+		lib := t.TempDir()
+		src := (&Sysroot{GOARCH: runtime.GOARCH, Libc: "/"}).find(needed[0])
+		data, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(lib, needed[0]), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		sr := &Sysroot{GOARCH: runtime.GOARCH, Libc: "/", LibDirs: []string{lib}}
+		if got := sr.find(needed[0]); got != filepath.Join(lib, needed[0]) {
+			t.Errorf("find() = %q, want the package copy", got)
+		}
+	})
+
+	t.Run("missing libc", func(t *testing.T) {
+		empty := t.TempDir()
+		sr := &Sysroot{GOARCH: runtime.GOARCH, Libc: empty}
+		_, err := sr.Assemble(bin, t.TempDir())
+		if err == nil {
+			t.Fatal("Assemble() succeeded without a libc")
+		}
+	})
+}
+
+func TestQuoteExec(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"/usr/bin/gox", "sysroot-exec"}, "/usr/bin/gox sysroot-exec"},
+		{[]string{"/opt/my tools/gox", "--lib", ""}, "'/opt/my tools/gox' --lib ''"},
+		{[]string{"/tmp/it's"}, `"/tmp/it's"`},
+	}
+	for _, tt := range tests {
+		if got := quoteExec(tt.args); got != tt.want {
+			t.Errorf("quoteExec(%q) = %s, want %s", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestBuilder_TestArgsSysroot(t *testing.T) {
+	opts := &Options{GOOS: "linux", GOARCH: "arm64", Sysroot: "/srv/arm64", LibDirs: []string{"/pkg/lib"}, InSysroot: true}
+	args := New("", opts).testArgs([]string{"./..."}, nil)
+	i := slices.Index(args, "-exec")
+	if i < 0 {
+		t.Fatalf("testArgs() = %q, want -exec", args)
+	}
+	if !strings.HasSuffix(args[i+1], "sysroot-exec --arch arm64 --libc /srv/arm64 --lib /pkg/lib") {
+		t.Errorf("-exec = %q", args[i+1])
+	}
+}
@@ -0,0 +1,109 @@
+package build
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/qntx/gox/internal/hosttool"
+)
+
+// PathData holds the values available to output and prefix templates,
+// e.g. output = "dist/{{.Name}}-{{.OS}}-{{.Arch}}{{.Ext}}".
+type PathData struct {
+	Name    string // binary name: last element of the main package path
+	Target  string // config target name
+	Variant string // config variant name
+	OS      string
+	Arch    string
+	Ext     string // ".exe" on windows, ".wasm" on wasip1, empty elsewhere; the library extension for c-shared and c-archive
+	Version string // git describe --tags --always --dirty
+	Commit  string // short commit hash
+}
+
+// gitInfo runs git once per process; templates without .Version or
+// .Commit never invoke it.
+var gitInfo = sync.OnceValues(func() (version, commit string) {
+	return git("describe", "--tags", "--always", "--dirty"), git("rev-parse", "--short", "HEAD")
+})
+
+// ExpandPaths resolves templates in Output, Prefix and PackName for
+// building pkgs.
+func (o *Options) ExpandPaths(pkgs []string) error {
+	if !strings.Contains(o.Output+o.Prefix+o.PackName, "{{") {
+		return nil
+	}
+	data := o.pathData(pkgs, usesGit(o.Output+o.Prefix+o.PackName))
+
+	var err error
+	if o.Output, err = expandPath("output", o.Output, data); err != nil {
+		return err
+	}
+	if o.Prefix, err = expandPath("prefix", o.Prefix, data); err != nil {
+		return err
+	}
+	if o.PackName, err = expandPath("pack.name", o.PackName, data); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (o *Options) pathData(pkgs []string, withGit bool) *PathData {
+	d := &PathData{
+		Name:    binaryName(pkgs),
+		Target:  o.Target,
+		Variant: o.Variant,
+		OS:      o.GOOS,
+		Arch:    o.GOARCH,
+	}
+	if d.Target == "" {
+		d.Target = o.GOOS + "-" + o.GOARCH
+	}
+	d.Ext = o.BuildMode.ext(o.GOOS)
+	if withGit {
+		d.Version, d.Commit = gitInfo()
+	}
+	return d
+}
+
+func expandPath(name, text string, data any) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// binaryName mirrors the go command's default output name: the last element
+// of the first package path, or the working directory for ".".
+func binaryName(pkgs []string) string {
+	pkg := "."
+	if len(pkgs) > 0 {
+		pkg = strings.TrimSuffix(pkgs[0], "/...")
+	}
+	if pkg == "." || pkg == "" {
+		if wd, err := os.Getwd(); err == nil {
+			return filepath.Base(wd)
+		}
+	}
+	return filepath.Base(filepath.FromSlash(pkg))
+}
+
+func git(args ...string) string {
+	out, err := hosttool.Command(context.Background(), "git", args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
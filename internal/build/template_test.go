@@ -0,0 +1,103 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOptions_ExpandPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		pkgs    []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "plain path untouched",
+			opts: Options{GOOS: "linux", GOARCH: "amd64", Output: "bin/app"},
+			want: "bin/app",
+		},
+		{
+			name: "name os arch ext",
+			opts: Options{GOOS: "windows", GOARCH: "arm64", Output: "dist/{{.Name}}-{{.OS}}-{{.Arch}}{{.Ext}}"},
+			pkgs: []string{"./cmd/tool"},
+			want: "dist/tool-windows-arm64.exe",
+		},
+		{
+			name: "target defaults to os-arch",
+			opts: Options{GOOS: "linux", GOARCH: "arm64", Output: "dist/{{.Target}}/app"},
+			want: "dist/linux-arm64/app",
+		},
+		{
+			name: "config target name",
+			opts: Options{Target: "pi", GOOS: "linux", GOARCH: "arm", Output: "dist/{{.Target}}/app"},
+			want: "dist/pi/app",
+		},
+		{
+			name:    "unknown field",
+			opts:    Options{GOOS: "linux", GOARCH: "amd64", Output: "{{.Bogus}}"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.ExpandPaths(tt.pkgs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandPaths() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tt.opts.Output != tt.want {
+				t.Errorf("Output = %q, want %q", tt.opts.Output, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptions_ExpandPaths_Prefix(t *testing.T) {
+	o := Options{GOOS: "darwin", GOARCH: "amd64", Prefix: "dist/{{.OS}}_{{.Arch}}"}
+	if err := o.ExpandPaths(nil); err != nil {
+		t.Fatalf("ExpandPaths() error = %v", err)
+	}
+	if o.Prefix != "dist/darwin_amd64" {
+		t.Errorf("Prefix = %q, want %q", o.Prefix, "dist/darwin_amd64")
+	}
+}
+
+func TestOptions_ExpandPaths_PackName(t *testing.T) {
+	o := Options{GOOS: "windows", GOARCH: "arm64", Prefix: "dist/app", Pack: true, PackName: "{{.Name}}_{{.OS}}_{{.Arch}}"}
+	if err := o.ExpandPaths([]string{"./cmd/tool"}); err != nil {
+		t.Fatalf("ExpandPaths() error = %v", err)
+	}
+	if o.PackName != "tool_windows_arm64" {
+		t.Errorf("PackName = %q, want %q", o.PackName, "tool_windows_arm64")
+	}
+	if got, want := o.ArchivePath(), filepath.Join("dist", "tool_windows_arm64.zip"); got != want {
+		t.Errorf("ArchivePath() = %q, want %q", got, want)
+	}
+}
+
+func TestBinaryName(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "myproj")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	tests := []struct {
+		pkgs []string
+		want string
+	}{
+		{nil, "myproj"},
+		{[]string{"."}, "myproj"},
+		{[]string{"./cmd/server"}, "server"},
+		{[]string{"example.com/x/cmd/cli"}, "cli"},
+		{[]string{"./tools/..."}, "tools"},
+	}
+	for _, tt := range tests {
+		if got := binaryName(tt.pkgs); got != tt.want {
+			t.Errorf("binaryName(%v) = %q, want %q", tt.pkgs, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package build
+
+import (
+	"maps"
+	"path/filepath"
+	"strings"
+
+	"github.com/qntx/gox/internal/hosttool"
+)
+
+// UseTools applies the [tools] paths for the rest of the process. Relative
+// paths containing a separator are resolved against the config directory;
+// bare names are looked up in PATH.
+func (c *Config) UseTools() error {
+	paths := make(map[string]string, len(c.Tools))
+	for name, p := range c.Tools {
+		if p != "" && !filepath.IsAbs(p) && strings.ContainsAny(p, `/\`) {
+			p = filepath.Join(c.dir, p)
+		}
+		paths[name] = p
+	}
+	return hosttool.Use(paths)
+}
+
+// mergeTools returns base overlaid with c; c wins for the same tool.
+func mergeTools(c, base map[string]string) map[string]string {
+	if len(base) == 0 {
+		return c
+	}
+	out := maps.Clone(base)
+	maps.Copy(out, c)
+	return out
+}
+
+// RequiredTools returns the host tools building opts needs: go, and git
+// when an output, prefix, pack.name, sign.command or [windows] template uses
+// .Version or .Commit.
+func RequiredTools(opts []*Options) []string {
+	tools := []string{"go"}
+	for _, o := range opts {
+		text := o.Output + o.Prefix + o.PackName + o.Sign.Command
+		if o.GOOS == "windows" {
+			text += o.Windows.templates()
+		}
+		if usesGit(text) {
+			return append(tools, "git")
+		}
+	}
+	return tools
+}
+
+func usesGit(text string) bool {
+	return strings.Contains(text, "{{") && (strings.Contains(text, ".Version") || strings.Contains(text, ".Commit"))
+}
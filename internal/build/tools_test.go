@@ -0,0 +1,60 @@
+package build
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/qntx/gox/internal/hosttool"
+)
+
+func TestRequiredTools(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []*Options
+		want []string
+	}{
+		{"plain", []*Options{{Output: "bin/app"}}, []string{"go"}},
+		{"name template", []*Options{{Output: "dist/{{.Name}}{{.Ext}}"}}, []string{"go"}},
+		{"version template", []*Options{{}, {Output: "dist/{{.Name}}-{{.Version}}"}}, []string{"go", "git"}},
+		{"version pack name", []*Options{{Output: "app", PackName: "app_{{.Version}}"}}, []string{"go", "git"}},
+		{"commit prefix", []*Options{{Prefix: "dist/{{.Commit}}"}}, []string{"go", "git"}},
+		{"windows version", []*Options{{GOOS: "windows", Windows: Windows{ProductVersion: "{{.Version}}"}}}, []string{"go", "git"}},
+		{"windows version on linux", []*Options{{GOOS: "linux", Windows: Windows{ProductVersion: "{{.Version}}"}}}, []string{"go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RequiredTools(tt.opts); !slices.Equal(got, tt.want) {
+				t.Errorf("RequiredTools() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_UseTools(t *testing.T) {
+	t.Cleanup(func() { _ = hosttool.Use(nil) })
+	dir, goBin := t.TempDir(), filepath.Join(t.TempDir(), "go")
+	c := &Config{dir: dir, Tools: map[string]string{
+		"go":     goBin,
+		"git":    "git",
+		"docker": "tools/docker",
+	}}
+	if err := c.UseTools(); err != nil {
+		t.Fatalf("UseTools() error = %v", err)
+	}
+	want := map[string]string{
+		"go":     goBin,
+		"git":    "git",
+		"docker": filepath.Join(dir, "tools/docker"),
+	}
+	for name, p := range want {
+		if got := hosttool.Configured(name); got != p {
+			t.Errorf("Configured(%s) = %q, want %q", name, got, p)
+		}
+	}
+
+	c.Tools = map[string]string{"cmake": "cmake"}
+	if err := c.UseTools(); err == nil {
+		t.Error("UseTools() accepted an unknown tool")
+	}
+}
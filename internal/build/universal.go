@@ -0,0 +1,87 @@
+package build
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/qntx/gox/internal/lipo"
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/workspace"
+)
+
+// Universal is the GOARCH of the darwin/universal pseudo-target, which
+// builds every arch in UniversalArchs and merges them into one fat binary.
+const Universal = "universal"
+
+// UniversalArchs are the darwin archs a universal binary holds.
+var UniversalArchs = []string{"amd64", "arm64"}
+
+// ErrUniversal is returned by the go commands other than build, which have
+// no single arch to run a universal target as.
+var ErrUniversal = errors.New("darwin/universal is only supported by gox build; use darwin/amd64 or darwin/arm64")
+
+// IsUniversal reports whether o is the darwin/universal pseudo-target.
+func (o *Options) IsUniversal() bool {
+	return o.GOOS == "darwin" && o.GOARCH == Universal
+}
+
+// Slices returns the options of each arch a universal target builds, with
+// no output of their own, or o itself for any other target.
+func (o *Options) Slices() []*Options {
+	if !o.IsUniversal() {
+		return []*Options{o}
+	}
+	out := make([]*Options, len(UniversalArchs))
+	for i, arch := range UniversalArchs {
+		s := *o
+		s.GOARCH = arch
+		s.Output, s.Prefix = "", ""
+		s.Pack, s.PackFormat, s.Checksum, s.DepsReport = false, "", false, false
+		out[i] = &s
+	}
+	return out
+}
+
+// compileUniversal builds each arch of a universal target into the run
+// workspace and merges the binaries into the output with lipo.
+func (b *Builder) compileUniversal(ctx context.Context, pkgs []string) error {
+	out := b.outputPath()
+	tmp, err := workspace.MkdirTemp("universal-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	archs := b.opts.Slices()
+	// The prefix gets the libs of the packages like any other target.
+	if err := b.setupPackages(ctx); err != nil {
+		return fmt.Errorf("packages: %w", err)
+	}
+
+	var thin []string
+	for _, o := range archs {
+		o.Output = filepath.Join(tmp, o.GOARCH, filepath.Base(out))
+		s := NewWithOutput(b.zig, o, b.stdout, b.stderr)
+		if err := s.setup(ctx); err != nil {
+			return fmt.Errorf("darwin/%s: %w", o.GOARCH, err)
+		}
+		if err := s.setupDirs(); err != nil {
+			return fmt.Errorf("dirs: %w", err)
+		}
+		if err := s.compile(ctx, pkgs); err != nil {
+			return err
+		}
+		thin = append(thin, o.Output)
+	}
+
+	start := time.Now()
+	if err := lipo.Create(out, thin...); err != nil {
+		return err
+	}
+	ui.Built(out, time.Since(start))
+	return nil
+}
@@ -0,0 +1,43 @@
+package build
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestOptions_Slices(t *testing.T) {
+	o := &Options{GOOS: "darwin", GOARCH: Universal, Prefix: "dist/app", Pack: true, Checksum: true, Libs: []string{"z"}}
+	got := o.Slices()
+	var archs []string
+	for _, s := range got {
+		archs = append(archs, s.GOARCH)
+		if s.Prefix != "" || s.Pack || s.Checksum {
+			t.Errorf("%s slice keeps the universal output: %+v", s.GOARCH, s)
+		}
+		if !slices.Equal(s.Libs, o.Libs) {
+			t.Errorf("%s slice Libs = %v, want %v", s.GOARCH, s.Libs, o.Libs)
+		}
+	}
+	if !slices.Equal(archs, UniversalArchs) {
+		t.Errorf("Slices() archs = %v, want %v", archs, UniversalArchs)
+	}
+
+	thin := &Options{GOOS: "darwin", GOARCH: "arm64"}
+	if got := thin.Slices(); len(got) != 1 || got[0] != thin {
+		t.Errorf("Slices() of a thin target = %v, want itself", got)
+	}
+}
+
+func TestOptions_ValidateUniversal(t *testing.T) {
+	o := &Options{GOOS: "darwin", GOARCH: Universal, LinkMode: LinkAuto}
+	if err := o.Validate(); err == nil {
+		t.Error("Validate() accepted darwin/universal without an output")
+	}
+	o.Output = "app"
+	if err := o.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	if err := New("", o).setup(t.Context()); err != ErrUniversal {
+		t.Errorf("setup() = %v, want ErrUniversal", err)
+	}
+}
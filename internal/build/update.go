@@ -0,0 +1,100 @@
+package build
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/qntx/gox/internal/lock"
+)
+
+// PackageUpdate is what UpdatePackages did with one package.
+type PackageUpdate struct {
+	Source  string
+	Old     lock.Package // locked entry before the update; zero when there was none
+	New     lock.Package // locked entry after it; zero without a gox.lock
+	Skipped string       // why the package was left alone, if it was
+}
+
+// Changed reports whether the package now resolves to another archive.
+func (u PackageUpdate) Changed() bool {
+	return u.Skipped == "" && u.New.SHA256 != "" && u.Old.SHA256 != u.New.SHA256
+}
+
+// UpdatePackages downloads sources again, dropping their cached copies and
+// gox.lock entries first, so floating ones such as owner/repo@latest/asset
+// or a URL to a nightly build pick up what they point at now. Packages
+// pinned by #sha256, vendored ones and vcpkg ports are skipped. When a
+// download fails, the other packages keep their new entries and the failed
+// ones their old.
+func UpdatePackages(ctx context.Context, sources []string) ([]PackageUpdate, error) {
+	pkgs, err := parsePackages(sources)
+	if err != nil {
+		return nil, err
+	}
+	l := lock.Active()
+	updates := make([]PackageUpdate, len(pkgs))
+	var refresh []string
+	for i, p := range pkgs {
+		u := &updates[i]
+		u.Source = p.Source
+		switch {
+		case p.SHA256 != "":
+			u.Skipped = "pinned by sha256"
+		case p.Vcpkg != "":
+			u.Skipped = "vcpkg port, copied again when vcpkg rebuilds it"
+		case p.vendored() != "":
+			u.Skipped = "vendored, run gox vendor to update it"
+		default:
+			if l != nil {
+				u.Old, _ = l.Package(p.Source)
+				if err := l.RemovePackage(p.Source); err != nil {
+					return nil, err
+				}
+			}
+			if err := os.RemoveAll(filepath.Join(cacheDir(), p.Dir)); err != nil {
+				return nil, err
+			}
+			refresh = append(refresh, p.Source)
+		}
+	}
+	if len(refresh) == 0 {
+		return updates, nil
+	}
+
+	_, err = EnsureAll(ctx, refresh)
+	if l == nil {
+		return updates, err
+	}
+	for i := range updates {
+		u := &updates[i]
+		if u.Skipped != "" {
+			continue
+		}
+		var ok bool
+		if u.New, ok = l.Package(u.Source); !ok && u.Old.Source != "" {
+			if e := l.SetPackage(u.Old); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return updates, err
+}
+
+// parsePackages parses sources, dropping repeated ones.
+func parsePackages(sources []string) ([]*Package, error) {
+	var pkgs []*Package
+	seen := map[string]bool{}
+	for _, s := range sources {
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		p, err := parsePackage(s)
+		if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil
+}
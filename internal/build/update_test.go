@@ -0,0 +1,100 @@
+package build
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qntx/gox/internal/archive"
+	"github.com/qntx/gox/internal/lock"
+)
+
+func TestUpdatePackages(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Cleanup(func() { _ = lock.Use("") })
+
+	// Serve a nightly archive whose content changes upstream.
+	src := filepath.Join(t.TempDir(), "pkg")
+	if err := os.MkdirAll(filepath.Join(src, "include"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeHeader := func(content string) string {
+		if err := os.WriteFile(filepath.Join(src, "include", "a.h"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		path, err := archive.Create(src, "linux", "amd64")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	tarPath := writeHeader("v1")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, tarPath)
+	}))
+	defer srv.Close()
+	nightly := srv.URL + "/nightly.tar.gz"
+	stable := srv.URL + "/stable.tar.gz"
+	pinned := srv.URL + "/pinned.tar.gz#sha256:" + strings.Repeat("0", 64)
+
+	lockPath := filepath.Join(t.TempDir(), lock.File)
+	if err := lock.Use(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EnsureAll(context.Background(), []string{nightly, stable}); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := lock.Active().Package(nightly)
+
+	tarPath = writeHeader("v2")
+	updates, err := UpdatePackages(context.Background(), []string{nightly, pinned, "vcpkg:zlib:x64-linux", nightly})
+	if err != nil {
+		t.Fatalf("UpdatePackages() error = %v", err)
+	}
+	if len(updates) != 3 {
+		t.Fatalf("UpdatePackages() = %+v, want 3 updates", updates)
+	}
+	if u := updates[0]; !u.Changed() || u.Old.SHA256 != before.SHA256 || u.New.SHA256 == before.SHA256 {
+		t.Errorf("nightly update = %+v, want a new digest", u)
+	}
+	if locked, _ := lock.Active().Package(nightly); locked.SHA256 != updates[0].New.SHA256 {
+		t.Errorf("gox.lock has %s, want the new digest", locked.SHA256)
+	}
+	if data, _ := os.ReadFile(filepath.Join(cacheDir(), urlHash(nightly), "include", "a.h")); string(data) != "v2" {
+		t.Errorf("cached a.h = %q, want the new archive", data)
+	}
+	for _, u := range updates[1:] {
+		if u.Skipped == "" || u.Changed() {
+			t.Errorf("update of %s = %+v, want it skipped", u.Source, u)
+		}
+	}
+
+	// The same archive again is unchanged.
+	updates, err = UpdatePackages(context.Background(), []string{nightly})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updates[0].Changed() {
+		t.Errorf("unchanged archive reported as changed: %+v", updates[0])
+	}
+
+	// A failed download keeps the old entry.
+	gone := srv.URL + "/gone.tar.gz"
+	if err := lock.Active().SetPackage(lock.Package{Source: gone, URL: gone, SHA256: "ab"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UpdatePackages(context.Background(), []string{gone}); err == nil {
+		t.Fatal("UpdatePackages() succeeded for a missing archive")
+	}
+	if locked, ok := lock.Active().Package(gone); !ok || locked.SHA256 != "ab" {
+		t.Errorf("after a failed update gox.lock has %+v, %v, want the old entry", locked, ok)
+	}
+}
@@ -0,0 +1,160 @@
+package build
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/qntx/gox/internal/httpclient"
+)
+
+// GitHubAPIEnv names the environment variable that overrides GitHubAPI,
+// e.g. for GitHub Enterprise.
+const GitHubAPIEnv = "GOX_GITHUB_API"
+
+// GitHubAPI is the GitHub REST endpoint asked for the releases of package
+// repositories.
+var GitHubAPI = "https://api.github.com"
+
+// ErrPinned is returned by NewerRelease for packages pinned by digest,
+// which cannot move to another release without a new digest.
+var ErrPinned = errors.New("pinned by sha256")
+
+// release is the part of a GitHub release NewerRelease looks at.
+type release struct {
+	Tag        string `json:"tag_name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+	Assets     []struct {
+		Name string `json:"name"`
+	} `json:"assets"`
+}
+
+// NewerRelease returns source moved to the newest compatible release of its
+// GitHub repository, or "" when there is none. A release is compatible when
+// its tag keeps the major version (the minor one for 0.x) and it publishes
+// the same asset, with the version in its name replaced. Sources that are
+// not GitHub releases with version tags are left alone.
+func NewerRelease(ctx context.Context, source string) (string, error) {
+	spec, options, _ := strings.Cut(source, "#")
+	if strings.Contains(options, "sha256:") {
+		return "", ErrPinned
+	}
+	m := ghReleaseRE.FindStringSubmatch(spec)
+	if m == nil {
+		return "", nil
+	}
+	owner, repo, tag, asset := m[1], m[2], m[3], m[4]
+	cur, ok := parseTag(tag)
+	if !ok {
+		return "", nil
+	}
+	releases, err := fetchReleases(ctx, owner, repo)
+	if err != nil {
+		return "", fmt.Errorf("%s/%s releases: %w", owner, repo, err)
+	}
+
+	best, bestTag, bestAsset := cur, "", ""
+	for _, r := range releases {
+		v, ok := parseTag(r.Tag)
+		if r.Draft || r.Prerelease || !ok || !compatible(cur, v) || slices.Compare(v, best) <= 0 {
+			continue
+		}
+		if name := renameAsset(asset, tag, r.Tag); r.hasAsset(name) {
+			best, bestTag, bestAsset = v, r.Tag, name
+		}
+	}
+	if bestTag == "" {
+		return "", nil
+	}
+	out := fmt.Sprintf("%s/%s@%s/%s", owner, repo, bestTag, bestAsset)
+	if options != "" {
+		out += "#" + options
+	}
+	return out, nil
+}
+
+// hasAsset reports whether r publishes an asset named name.
+func (r *release) hasAsset(name string) bool {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchReleases lists the most recent releases of owner/repo, authenticated
+// with GITHUB_TOKEN when it is set.
+func fetchReleases(ctx context.Context, owner, repo string) ([]release, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/releases?per_page=100", strings.TrimSuffix(cmp.Or(os.Getenv(GitHubAPIEnv), GitHubAPI), "/"), owner, repo)
+	var releases []release
+	err := httpclient.Retry(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := httpclient.Client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return httpclient.NewStatusError(resp)
+		}
+		releases = nil
+		return json.NewDecoder(resp.Body).Decode(&releases)
+	})
+	return releases, err
+}
+
+// parseTag parses a version tag such as v1.2.3 or 3.46.0 into its numbers.
+func parseTag(tag string) ([]int, bool) {
+	s := strings.TrimPrefix(tag, "v")
+	if s == "" {
+		return nil, false
+	}
+	var v []int
+	for part := range strings.SplitSeq(s, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+		v = append(v, n)
+	}
+	return v, true
+}
+
+// compatible reports whether v keeps the API of cur under semver: the same
+// major version, and the same minor version while the major one is 0.
+func compatible(cur, v []int) bool {
+	if v[0] != cur[0] {
+		return false
+	}
+	if cur[0] == 0 {
+		return len(cur) > 1 && len(v) > 1 && v[1] == cur[1]
+	}
+	return true
+}
+
+// renameAsset returns the name asset has in release tag to, replacing the
+// version of tag from in it, with or without its v prefix. A bare version
+// must be dotted so single digits elsewhere in the name are kept.
+func renameAsset(asset, from, to string) string {
+	pairs := []string{from, to}
+	if bare := strings.TrimPrefix(from, "v"); strings.Contains(bare, ".") {
+		pairs = append(pairs, bare, strings.TrimPrefix(to, "v"))
+	}
+	return strings.NewReplacer(pairs...).Replace(asset)
+}
@@ -0,0 +1,84 @@
+package build
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewerRelease(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/lib/releases":
+			_, _ = w.Write([]byte(`[
+				{"tag_name": "v2.0.0", "assets": [{"name": "lib-2.0.0-linux.tar.gz"}]},
+				{"tag_name": "v1.5.0-rc1", "prerelease": true, "assets": [{"name": "lib-1.5.0-rc1-linux.tar.gz"}]},
+				{"tag_name": "v1.4.0", "assets": [{"name": "lib-1.4.0-windows.zip"}]},
+				{"tag_name": "v1.3.2", "assets": [{"name": "lib-1.3.2-linux.tar.gz"}, {"name": "lib-1.3.2-windows.zip"}]},
+				{"tag_name": "v1.3.0", "assets": [{"name": "lib-1.3.0-linux.tar.gz"}]},
+				{"tag_name": "v1.2.0", "assets": [{"name": "lib-1.2.0-linux.tar.gz"}]}
+			]`))
+		case "/repos/acme/zero/releases":
+			_, _ = w.Write([]byte(`[
+				{"tag_name": "0.5.0", "assets": [{"name": "zero.zip"}]},
+				{"tag_name": "0.4.7", "assets": [{"name": "zero.zip"}]}
+			]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	old := GitHubAPI
+	GitHubAPI = srv.URL
+	t.Cleanup(func() { GitHubAPI = old })
+
+	tests := []struct {
+		name, source, want, wantErr string
+	}{
+		{"newest compatible", "acme/lib@v1.2.0/lib-1.2.0-linux.tar.gz", "acme/lib@v1.3.2/lib-1.3.2-linux.tar.gz", ""},
+		{"asset missing in newer", "acme/lib@v1.2.0/lib-1.2.0-windows.zip", "acme/lib@v1.4.0/lib-1.4.0-windows.zip", ""},
+		{"options kept", "acme/lib@v1.3.0/lib-1.3.0-linux.tar.gz#keep-modes", "acme/lib@v1.3.2/lib-1.3.2-linux.tar.gz#keep-modes", ""},
+		{"current", "acme/lib@v1.3.2/lib-1.3.2-linux.tar.gz", "", ""},
+		{"major zero keeps minor", "acme/zero@0.4.1/zero.zip", "acme/zero@0.4.7/zero.zip", ""},
+		{"not versioned", "acme/lib@nightly/lib.tar.gz", "", ""},
+		{"url", "https://example.com/lib-1.0.0.tar.gz", "", ""},
+		{"pinned", "acme/lib@v1.2.0/lib-1.2.0-linux.tar.gz#sha256:" + strings.Repeat("a", 64), "", "pinned"},
+		{"unknown repo", "acme/gone@v1.0.0/gone.zip", "", "acme/gone releases"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewerRelease(t.Context(), tt.source)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewerRelease() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("NewerRelease() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenameAsset(t *testing.T) {
+	tests := []struct {
+		asset, from, to, want string
+	}{
+		{"sqlite-3.46.0-linux.tar.gz", "3.46.0", "3.47.1", "sqlite-3.47.1-linux.tar.gz"},
+		{"lib-1.2.0-x64.zip", "v1.2.0", "v1.3.0", "lib-1.3.0-x64.zip"},
+		{"lib-v1.2.0.zip", "v1.2.0", "v1.3.0", "lib-v1.3.0.zip"},
+		{"lib-win64.zip", "v1", "v2", "lib-win64.zip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.asset, func(t *testing.T) {
+			if got := renameAsset(tt.asset, tt.from, tt.to); got != tt.want {
+				t.Errorf("renameAsset() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package build
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigVariant is a named flavor of a target, declared as
+// [[target.variant]]. It shares the target's os/arch and settings; scalar
+// fields override the target's and lists are appended to them.
+type ConfigVariant struct {
+	Name       string   `toml:"name"`
+	Output     string   `toml:"output,omitempty"`
+	Prefix     string   `toml:"prefix,omitempty"`
+	LinkMode   string   `toml:"linkmode,omitempty"`
+	Include    []string `toml:"include,omitempty"`
+	Lib        []string `toml:"lib,omitempty"`
+	Link       []string `toml:"link,omitempty"`
+	LibExclude []string `toml:"lib-exclude,omitempty"`
+	Packages   []string `toml:"packages,omitempty"`
+	Flags      []string `toml:"flags,omitempty"`
+	Pack       Pack     `toml:"pack,omitempty"`
+	Strip      bool     `toml:"strip,omitempty"`
+}
+
+// variantOutput is the output used when neither the target nor the variant
+// names one, so variants never overwrite each other's binary.
+const variantOutput = "{{.Name}}-{{.Variant}}{{.Ext}}"
+
+func (t *ConfigTarget) validateVariants() error {
+	seen := make(map[string]bool, len(t.Variants))
+	for _, v := range t.Variants {
+		switch {
+		case v.Name == "":
+			return fmt.Errorf("target %s: variant without a name", t.Name)
+		case seen[v.Name]:
+			return fmt.Errorf("target %s: duplicate variant %q", t.Name, v.Name)
+		}
+		seen[v.Name] = true
+	}
+	return nil
+}
+
+// applyVariant layers v over options merged from its target. Paths
+// inherited from the target get a "-<variant>" suffix so each variant
+// produces its own binary, prefix and archive.
+func (o *Options) applyVariant(v *ConfigVariant) error {
+	if o.Target != "" {
+		o.Target += "-" + v.Name
+	}
+	o.Variant = v.Name
+
+	switch {
+	case v.Output != "" || v.Prefix != "":
+		o.Output, o.Prefix = v.Output, v.Prefix
+	case o.Output == "" && o.Prefix == "":
+		o.Output = variantOutput
+	default:
+		var err error
+		if o.Output, err = variantPath("output", o.Output, v.Name, true); err != nil {
+			return err
+		}
+		if o.Prefix, err = variantPath("prefix", o.Prefix, v.Name, false); err != nil {
+			return err
+		}
+	}
+
+	if v.LinkMode != "" {
+		o.LinkMode = LinkMode(v.LinkMode)
+	}
+	o.IncludeDirs = mergeSlices(o.IncludeDirs, v.Include)
+	o.LibDirs = mergeSlices(o.LibDirs, v.Lib)
+	o.Libs = mergeSlices(o.Libs, v.Link)
+	o.LibExclude = mergeSlices(o.LibExclude, v.LibExclude)
+	o.Packages = mergeSlices(o.Packages, v.Packages)
+	o.BuildFlags = mergeSlices(o.BuildFlags, v.Flags)
+	if v.Pack.Enabled() {
+		o.Pack, o.PackFormat = true, v.Pack.Format
+	}
+	o.PackInclude = mergeSlices(o.PackInclude, v.Pack.Include)
+	o.PackExclude = mergeSlices(o.PackExclude, v.Pack.Exclude)
+	if v.Pack.Name != "" {
+		o.PackName = v.Pack.Name
+	} else {
+		var err error
+		if o.PackName, err = variantPath("pack.name", o.PackName, v.Name, false); err != nil {
+			return err
+		}
+	}
+	o.Strip = o.Strip || v.Strip
+	return nil
+}
+
+// variantPath derives a variant's path from the target's. A templated path
+// must already tell variants apart through .Variant or .Target; a plain one
+// gets "-<variant>" appended, before the extension for output files.
+func variantPath(key, path, variant string, file bool) (string, error) {
+	switch {
+	case path == "":
+		return "", nil
+	case strings.Contains(path, "{{"):
+		if strings.Contains(path, ".Variant") || strings.Contains(path, ".Target") {
+			return path, nil
+		}
+		return "", fmt.Errorf("variant %s: templated %s %q must use {{.Variant}} or {{.Target}}", variant, key, path)
+	}
+	trimmed := strings.TrimRight(path, `/\`)
+	sep := path[len(trimmed):] // a trailing separator marks a directory
+	ext := ""
+	if file && sep == "" {
+		ext = filepath.Ext(trimmed)
+	}
+	return strings.TrimSuffix(trimmed, ext) + "-" + variant + ext + sep, nil
+}
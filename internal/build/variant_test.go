@@ -0,0 +1,158 @@
+package build
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestConfig_ToOptionsVariants(t *testing.T) {
+	cfg := &Config{
+		Default: ConfigDefault{Flags: []string{"-trimpath"}},
+		Targets: []ConfigTarget{
+			{
+				Name:     "linux-amd64",
+				OS:       "linux",
+				Arch:     "amd64",
+				Prefix:   "./dist/linux",
+				LinkMode: "static",
+				Pack:     Pack{Format: PackArchive},
+				Variants: []ConfigVariant{
+					{Name: "server", Flags: []string{"-tags=server"}},
+					{Name: "desktop", LinkMode: "dynamic", Link: []string{"gtk-3"}},
+				},
+			},
+			{Name: "darwin-arm64", OS: "darwin", Arch: "arm64"},
+		},
+	}
+
+	t.Run("expand", func(t *testing.T) {
+		opts, err := cfg.ToOptions(nil)
+		if err != nil {
+			t.Fatalf("ToOptions() error = %v", err)
+		}
+		if len(opts) != 3 {
+			t.Fatalf("len(opts) = %d, want 3", len(opts))
+		}
+		server, desktop := opts[0], opts[1]
+		if server.Target != "linux-amd64-server" || server.Variant != "server" {
+			t.Errorf("server = %q/%q", server.Target, server.Variant)
+		}
+		if server.Prefix != "./dist/linux-server" || desktop.Prefix != "./dist/linux-desktop" {
+			t.Errorf("prefixes = %q, %q", server.Prefix, desktop.Prefix)
+		}
+		if server.LinkMode != LinkStatic || desktop.LinkMode != LinkDynamic {
+			t.Errorf("linkmodes = %q, %q", server.LinkMode, desktop.LinkMode)
+		}
+		if want := []string{"-trimpath", "-tags=server"}; !slices.Equal(server.BuildFlags, want) {
+			t.Errorf("server.BuildFlags = %v, want %v", server.BuildFlags, want)
+		}
+		if !slices.Equal(desktop.Libs, []string{"gtk-3"}) || len(server.Libs) != 0 {
+			t.Errorf("libs = %v, %v", server.Libs, desktop.Libs)
+		}
+		if !server.Pack || !desktop.Pack {
+			t.Error("variants should inherit pack")
+		}
+		if opts[2].Target != "darwin-arm64" || opts[2].Variant != "" {
+			t.Errorf("opts[2] = %q/%q", opts[2].Target, opts[2].Variant)
+		}
+	})
+
+	t.Run("select", func(t *testing.T) {
+		for _, tt := range []struct {
+			name string
+			want []string
+		}{
+			{"linux-amd64", []string{"linux-amd64-server", "linux-amd64-desktop"}},
+			{"linux-amd64-desktop", []string{"linux-amd64-desktop"}},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				opts, err := cfg.ToOptions([]string{tt.name})
+				if err != nil {
+					t.Fatalf("ToOptions() error = %v", err)
+				}
+				var got []string
+				for _, o := range opts {
+					got = append(got, o.Target)
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("targets = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, variants := range [][]ConfigVariant{
+			{{Name: ""}},
+			{{Name: "a"}, {Name: "a"}},
+		} {
+			c := &Config{Targets: []ConfigTarget{{Name: "x", Variants: variants}}}
+			if _, err := c.ToOptions(nil); err == nil {
+				t.Errorf("ToOptions(%v) should fail", variants)
+			}
+		}
+	})
+}
+
+func TestApplyVariantPackName(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		variant ConfigVariant
+		want    string
+		wantErr bool
+	}{
+		{"unset", "", ConfigVariant{Name: "gui"}, "", false},
+		{"plain", "app", ConfigVariant{Name: "gui"}, "app-gui", false},
+		{"templated", "{{.Name}}_{{.Variant}}_{{.OS}}", ConfigVariant{Name: "gui"}, "{{.Name}}_{{.Variant}}_{{.OS}}", false},
+		{"templated without variant", "{{.Name}}_{{.OS}}", ConfigVariant{Name: "gui"}, "", true},
+		{"override", "{{.Name}}_{{.OS}}", ConfigVariant{Name: "gui", Pack: Pack{Name: "app-desktop"}}, "app-desktop", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Options{Target: "t", Output: "dist/app", PackName: tt.target}
+			err := o.applyVariant(&tt.variant)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyVariant() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && o.PackName != tt.want {
+				t.Errorf("PackName = %q, want %q", o.PackName, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyVariantPaths(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     string
+		prefix     string
+		variant    ConfigVariant
+		wantOutput string
+		wantPrefix string
+		wantErr    bool
+	}{
+		{"default output", "", "", ConfigVariant{Name: "gui"}, variantOutput, "", false},
+		{"output ext", "dist/app.exe", "", ConfigVariant{Name: "gui"}, "dist/app-gui.exe", "", false},
+		{"output dir", "dist/", "", ConfigVariant{Name: "gui"}, "dist-gui/", "", false},
+		{"prefix", "", "./out/", ConfigVariant{Name: "gui"}, "", "./out-gui/", false},
+		{"templated", "dist/{{.Target}}/app", "", ConfigVariant{Name: "gui"}, "dist/{{.Target}}/app", "", false},
+		{"templated without variant", "dist/{{.OS}}/app", "", ConfigVariant{Name: "gui"}, "", "", true},
+		{"override", "dist/app", "", ConfigVariant{Name: "gui", Prefix: "gui"}, "", "gui", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Options{Target: "t", Output: tt.output, Prefix: tt.prefix}
+			err := o.applyVariant(&tt.variant)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyVariant() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if o.Output != tt.wantOutput || o.Prefix != tt.wantPrefix {
+				t.Errorf("paths = %q, %q, want %q, %q", o.Output, o.Prefix, tt.wantOutput, tt.wantPrefix)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package build
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/qntx/gox/internal/hosttool"
+)
+
+// A "vcpkg:<port>:<triplet>" package is taken from a vcpkg installed tree
+// instead of downloaded: the port and the ports it depends on are copied
+// into the cache in the include/lib/bin layout.
+
+// vcpkgNameRE matches vcpkg port and triplet names.
+var vcpkgNameRE = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// vcpkgStamp is the file in a vcpkg package's cache entry recording the
+// ABI hashes of the ports copied into it.
+const vcpkgStamp = ".vcpkg-abi"
+
+// parseVcpkg returns the "port:triplet" of the spec after "vcpkg:".
+func parseVcpkg(spec string) (string, error) {
+	port, triplet, ok := strings.Cut(spec, ":")
+	if !ok || !vcpkgNameRE.MatchString(port) || !vcpkgNameRE.MatchString(triplet) {
+		return "", errors.New("want vcpkg:<port>:<triplet>, e.g. vcpkg:openssl:x64-linux")
+	}
+	return port + ":" + triplet, nil
+}
+
+// vcpkgTree is a vcpkg installed tree: vcpkg_installed/ of a manifest
+// project, or installed/ of a classic vcpkg root or a raw export.
+type vcpkgTree struct {
+	dir     string // holds <triplet>/ and vcpkg/
+	classic bool   // vcpkg install can add ports to it
+	ports   map[string]*vcpkgPort
+}
+
+// vcpkgPort is an installed port, keyed by "port:triplet".
+type vcpkgPort struct {
+	version string
+	abi     string
+	depends []string // "port:triplet" of the ports it links against
+}
+
+// openVcpkgTree finds the installed tree: vcpkg_installed/ in the working
+// directory, else installed/ under $VCPKG_ROOT or next to vcpkg in PATH.
+func openVcpkgTree() (*vcpkgTree, error) {
+	t := &vcpkgTree{}
+	if dir, err := filepath.Abs("vcpkg_installed"); err == nil && isDir(dir) {
+		t.dir = dir
+	} else {
+		root := os.Getenv("VCPKG_ROOT")
+		if root == "" {
+			if exe, err := hosttool.Path("vcpkg"); err == nil {
+				if exe, err = filepath.EvalSymlinks(exe); err == nil {
+					root = filepath.Dir(exe)
+				}
+			}
+		}
+		if root == "" {
+			return nil, errors.New("vcpkg not found: set VCPKG_ROOT to a vcpkg root or raw export, or add vcpkg to PATH")
+		}
+		t.dir, t.classic = filepath.Join(root, "installed"), true
+	}
+	return t, t.readStatus()
+}
+
+// readStatus reads the installed ports from the status database and the
+// updates appended to it since vcpkg last compacted it.
+func (t *vcpkgTree) readStatus() error {
+	t.ports = map[string]*vcpkgPort{}
+	files := []string{filepath.Join(t.dir, "vcpkg", "status")}
+	updates, _ := filepath.Glob(filepath.Join(t.dir, "vcpkg", "updates", "*"))
+	slices.Sort(updates)
+	for _, name := range append(files, updates...) {
+		f, err := os.Open(name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = readControl(f, t.addStatus)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// addStatus applies one paragraph of the status database: a port, or a
+// feature of one adding dependencies.
+func (t *vcpkgTree) addStatus(fields map[string]string) {
+	triplet := fields["Architecture"]
+	key := fields["Package"] + ":" + triplet
+	installed := strings.HasSuffix(fields["Status"], " installed")
+	if !installed {
+		if fields["Feature"] == "" {
+			delete(t.ports, key)
+		}
+		return
+	}
+	p := t.ports[key]
+	if p == nil {
+		p = &vcpkgPort{}
+		t.ports[key] = p
+	}
+	if fields["Feature"] == "" {
+		p.version, p.abi = fields["Version"], fields["Abi"]
+	}
+	for dep := range strings.SplitSeq(fields["Depends"], ",") {
+		// e.g. "zlib", "openssl[core]:x64-linux" or "vcpkg-cmake:x64-linux"
+		dep, _, _ = strings.Cut(strings.TrimSpace(dep), " ")
+		name, depTriplet, _ := strings.Cut(dep, ":")
+		name, _, _ = strings.Cut(name, "[")
+		if name == "" || (depTriplet != "" && depTriplet != triplet) {
+			continue // host tools only run during the build
+		}
+		if dep := name + ":" + triplet; !slices.Contains(p.depends, dep) {
+			p.depends = append(p.depends, dep)
+		}
+	}
+}
+
+// readControl calls fn with the fields of each paragraph of a Debian-style
+// control file, the format of the vcpkg status database.
+func readControl(r io.Reader, fn func(map[string]string)) error {
+	fields := map[string]string{}
+	last := ""
+	flush := func() {
+		if len(fields) > 0 {
+			fn(fields)
+		}
+		fields, last = map[string]string{}, ""
+	}
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		line := s.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+		case line[0] == ' ' || line[0] == '\t':
+			if last != "" {
+				fields[last] += "\n" + strings.TrimSpace(line)
+			}
+		default:
+			k, v, ok := strings.Cut(line, ":")
+			if !ok {
+				return fmt.Errorf("malformed line %q", line)
+			}
+			last = k
+			fields[k] = strings.TrimSpace(v)
+		}
+	}
+	flush()
+	return s.Err()
+}
+
+// closure returns key and the ports it depends on, directly or not, that
+// are installed, in a stable order.
+func (t *vcpkgTree) closure(key string) []string {
+	seen := map[string]bool{key: true}
+	out := []string{key}
+	for i := 0; i < len(out); i++ {
+		for _, dep := range t.ports[out[i]].depends {
+			if !seen[dep] && t.ports[dep] != nil {
+				seen[dep] = true
+				out = append(out, dep)
+			}
+		}
+	}
+	slices.Sort(out[1:])
+	return out
+}
+
+// abi returns the stamp recording the builds of the ports keys.
+func (t *vcpkgTree) abi(keys []string) string {
+	var b strings.Builder
+	for _, key := range keys {
+		p := t.ports[key]
+		fmt.Fprintf(&b, "%s %s %s\n", key, p.version, p.abi)
+	}
+	return b.String()
+}
+
+// files returns the paths the port key installed, relative to its triplet
+// directory, from its list file.
+func (t *vcpkgTree) files(key string) ([]string, error) {
+	port, triplet, _ := strings.Cut(key, ":")
+	lists, _ := filepath.Glob(filepath.Join(t.dir, "vcpkg", "info", port+"_*_"+triplet+".list"))
+	if len(lists) == 0 {
+		return nil, fmt.Errorf("vcpkg: no file list for %s in %s", key, filepath.Join(t.dir, "vcpkg", "info"))
+	}
+	// Prefer the list of the installed version over leftovers.
+	list := lists[len(lists)-1]
+	for _, l := range lists {
+		if strings.HasPrefix(filepath.Base(l), port+"_"+t.ports[key].version+"_") {
+			list = l
+		}
+	}
+	data, err := os.ReadFile(list)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimSpace(line)
+		if rel, ok := strings.CutPrefix(line, triplet+"/"); ok && rel != "" && !strings.HasSuffix(rel, "/") {
+			files = append(files, rel)
+		}
+	}
+	return files, nil
+}
+
+// vcpkgLayout maps a file a port installed, relative to its triplet
+// directory, into the package layout: release headers, libs and binaries,
+// with tools/<port>/ merged into bin. Debug builds and share/ are left out.
+func vcpkgLayout(rel string) (string, bool) {
+	first, rest, _ := strings.Cut(rel, "/")
+	switch first {
+	case "include", "lib", "bin":
+		return rel, true
+	case "tools":
+		if _, file, ok := strings.Cut(rest, "/"); ok {
+			return "bin/" + file, true
+		}
+	}
+	return "", false
+}
+
+// vcpkgChanged reports whether the ports in p's cache entry were rebuilt or
+// removed since it was copied. A tree that cannot be found keeps the cached
+// copy usable.
+func (p *Package) vcpkgChanged() bool {
+	t, err := openVcpkgTree()
+	if err != nil {
+		return false
+	}
+	if t.ports[p.Vcpkg] == nil {
+		return true
+	}
+	stamp, err := os.ReadFile(filepath.Join(cacheDir(), p.Dir, vcpkgStamp))
+	return err != nil || string(stamp) != t.abi(t.closure(p.Vcpkg))
+}
+
+// installVcpkg copies the port of p and its dependencies into the cache,
+// running vcpkg install first when a classic root lacks it.
+func (p *Package) installVcpkg(ctx context.Context) error {
+	t, err := openVcpkgTree()
+	if err != nil {
+		return err
+	}
+	if t.ports[p.Vcpkg] == nil {
+		if !t.classic {
+			return fmt.Errorf("%s is not in %s: add it to vcpkg.json and run vcpkg install", p.Vcpkg, t.dir)
+		}
+		if _, err := hosttool.Path("vcpkg"); err != nil {
+			return fmt.Errorf("%s is not installed in %s: %w", p.Vcpkg, t.dir, err)
+		}
+		cmd := hosttool.Command(ctx, "vcpkg", "install", p.Vcpkg)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("vcpkg install %s: %w\n%s", p.Vcpkg, err, strings.TrimSpace(string(out)))
+		}
+		if err := t.readStatus(); err != nil {
+			return err
+		}
+		if t.ports[p.Vcpkg] == nil {
+			return fmt.Errorf("vcpkg install %s did not install it in %s", p.Vcpkg, t.dir)
+		}
+	}
+
+	dir := filepath.Join(cacheDir(), p.Dir)
+	keys := t.closure(p.Vcpkg)
+	var jobs []copyJob
+	for _, key := range keys {
+		_, triplet, _ := strings.Cut(key, ":")
+		root := filepath.Join(t.dir, triplet)
+		files, err := t.files(key)
+		if err != nil {
+			return err
+		}
+		for _, rel := range files {
+			dst, ok := vcpkgLayout(rel)
+			if !ok {
+				continue
+			}
+			src := filepath.Join(root, filepath.FromSlash(rel))
+			info, err := os.Lstat(src)
+			if err != nil {
+				return fmt.Errorf("vcpkg: %s: %w", key, err)
+			}
+			job := copyJob{src: src, dst: filepath.Join(dir, filepath.FromSlash(dst)), mode: info.Mode(), size: info.Size()}
+			// Links are kept where the file stays, e.g. libfoo.so -> libfoo.so.1.
+			if info.Mode()&os.ModeSymlink != 0 && dst == rel {
+				job.link = localLink(root, src)
+			}
+			jobs = append(jobs, job)
+		}
+	}
+
+	os.RemoveAll(dir)
+	if err = os.MkdirAll(dir, 0o755); err == nil {
+		_, err = copyAll(jobs, CopyFiles)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, vcpkgStamp), []byte(t.abi(keys)), 0o644)
+	}
+	if err == nil && !isDir(p.Include) && !isDir(p.Lib) {
+		err = fmt.Errorf("%s: missing include/ and lib/", p.Source)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+	}
+	return err
+}
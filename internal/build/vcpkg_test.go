@@ -0,0 +1,183 @@
+package build
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeVcpkgTree writes a classic vcpkg root with openssl, which depends on
+// zlib and the host-only vcpkg-cmake, and returns its installed/ directory.
+func writeVcpkgTree(t *testing.T, root string) string {
+	t.Helper()
+	installed := filepath.Join(root, "installed")
+	files := map[string]string{
+		"x64-linux/include/openssl/ssl.h":     "#define SSL 3\n",
+		"x64-linux/lib/libssl.a":              "ssl\n",
+		"x64-linux/lib/pkgconfig/openssl.pc":  "prefix=${pcfiledir}/../..\n",
+		"x64-linux/debug/lib/libssl.a":        "debug ssl\n",
+		"x64-linux/share/openssl/copyright":   "license\n",
+		"x64-linux/tools/openssl/openssl":     "#!/bin/sh\n",
+		"x64-linux/include/zlib.h":            "#define ZLIB 1\n",
+		"x64-linux/lib/libz.so.1":             "z\n",
+		"x64-linux/lib/libcurl.a":             "not a dependency\n",
+		"x64-linux/share/vcpkg-cmake/x.cmake": "\n",
+		"vcpkg/info/openssl_3.3.2_x64-linux.list": "x64-linux/\nx64-linux/include/\nx64-linux/include/openssl/ssl.h\n" +
+			"x64-linux/lib/libssl.a\nx64-linux/lib/pkgconfig/openssl.pc\nx64-linux/debug/lib/libssl.a\n" +
+			"x64-linux/share/openssl/copyright\nx64-linux/tools/openssl/openssl\n",
+		"vcpkg/info/vcpkg-cmake_2024-04-23_x64-linux.list": "x64-linux/share/vcpkg-cmake/x.cmake\n",
+		"vcpkg/info/zlib_1.3.1_x64-linux.list":             "x64-linux/include/zlib.h\nx64-linux/lib/libz.so.1\nx64-linux/lib/libz.so\n",
+		"vcpkg/status": `Package: vcpkg-cmake
+Version: 2024-04-23
+Architecture: x64-linux
+Multi-Arch: same
+Abi: c1
+Status: install ok installed
+
+Package: zlib
+Version: 1.3.1
+Depends: vcpkg-cmake:x64-linux
+Architecture: x64-linux
+Multi-Arch: same
+Abi: z1
+Description: A compression library
+Status: install ok installed
+
+Package: openssl
+Version: 3.3.2
+Depends: vcpkg-cmake:x64-linux, vcpkg-cmake-config:x64-linux
+Architecture: x64-linux
+Multi-Arch: same
+Abi: a1
+Description: TLS/SSL and crypto library
+  with a long description
+Status: install ok installed
+
+Package: openssl
+Feature: zlib
+Depends: zlib
+Architecture: x64-linux
+Multi-Arch: same
+Description: zlib compression
+Status: install ok installed
+
+Package: curl
+Version: 8.9.1
+Architecture: x64-linux
+Multi-Arch: same
+Abi: u1
+Status: purge ok not-installed
+`,
+	}
+	for name, data := range files {
+		p := filepath.Join(installed, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("libz.so.1", filepath.Join(installed, "x64-linux", "lib", "libz.so")); err != nil {
+		t.Fatal(err)
+	}
+	return installed
+}
+
+func TestEnsureAll_Vcpkg(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	root := t.TempDir()
+	t.Setenv("VCPKG_ROOT", root)
+	installed := writeVcpkgTree(t, root)
+
+	pkgs, err := EnsureAll(context.Background(), []string{"vcpkg:openssl:x64-linux"})
+	if err != nil {
+		t.Fatalf("EnsureAll() error = %v", err)
+	}
+	p := pkgs[0]
+	for name, want := range map[string]string{
+		"include/openssl/ssl.h":    "#define SSL 3\n",
+		"lib/libssl.a":             "ssl\n",
+		"lib/pkgconfig/openssl.pc": "prefix=${pcfiledir}/../..\n",
+		"bin/openssl":              "#!/bin/sh\n",
+		"include/zlib.h":           "#define ZLIB 1\n",
+		"lib/libz.so":              "z\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(p.Lib), filepath.FromSlash(name)))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v, want %q", name, data, err, want)
+		}
+	}
+	if target, err := os.Readlink(filepath.Join(p.Lib, "libz.so")); err != nil || target != "libz.so.1" {
+		t.Errorf("Readlink(lib/libz.so) = %q, %v, want libz.so.1", target, err)
+	}
+	for _, name := range []string{"debug", "share", "tools", "lib/libcurl.a"} {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(p.Lib), name)); !os.IsNotExist(err) {
+			t.Errorf("%s copied: %v", name, err)
+		}
+	}
+
+	// A rebuilt port is copied again; an unchanged one is left alone.
+	if err := os.WriteFile(filepath.Join(installed, "x64-linux", "lib", "libssl.a"), []byte("ssl v2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EnsureAll(context.Background(), []string{"vcpkg:openssl:x64-linux"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(p.Lib, "libssl.a")); string(data) != "ssl\n" {
+		t.Errorf("unchanged port copied again: libssl.a = %q", data)
+	}
+	status := filepath.Join(installed, "vcpkg", "status")
+	data, err := os.ReadFile(status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(status, []byte(strings.Replace(string(data), "Abi: a1", "Abi: a2", 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EnsureAll(context.Background(), []string{"vcpkg:openssl:x64-linux"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(p.Lib, "libssl.a")); string(data) != "ssl v2\n" {
+		t.Errorf("rebuilt port not copied again: libssl.a = %q", data)
+	}
+}
+
+func TestEnsureAll_VcpkgMissing(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	root := t.TempDir()
+	t.Setenv("VCPKG_ROOT", root)
+	t.Setenv("PATH", t.TempDir())
+	writeVcpkgTree(t, root)
+
+	_, err := EnsureAll(context.Background(), []string{"vcpkg:curl:x64-linux"})
+	if err == nil || !strings.Contains(err.Error(), "curl:x64-linux is not installed") {
+		t.Errorf("EnsureAll(uninstalled port) error = %v", err)
+	}
+}
+
+func TestVcpkgLayout(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{"include/openssl/ssl.h", "include/openssl/ssl.h", true},
+		{"lib/libssl.a", "lib/libssl.a", true},
+		{"bin/libssl-3-x64.dll", "bin/libssl-3-x64.dll", true},
+		{"tools/openssl/openssl", "bin/openssl", true},
+		{"tools/protobuf/protoc", "bin/protoc", true},
+		{"debug/lib/libssl.a", "", false},
+		{"share/openssl/copyright", "", false},
+		{"tools/README", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got, ok := vcpkgLayout(tt.input); got != tt.want || ok != tt.ok {
+				t.Errorf("vcpkgLayout(%q) = %q, %v, want %q, %v", tt.input, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package build
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/qntx/gox/internal/archive"
+	"github.com/qntx/gox/internal/lock"
+	"github.com/qntx/gox/internal/ui"
+)
+
+// VendorDir is where gox vendor copies packages, relative to gox.toml.
+const VendorDir = "third_party/gox"
+
+// VendorManifest is the file in VendorDir listing the vendored packages.
+const VendorManifest = "manifest.toml"
+
+const vendorHeader = "# This file is generated by gox vendor. Do not edit.\n\n"
+
+// Vendored describes a package copied into VendorDir.
+type Vendored struct {
+	Source string `toml:"source"`
+	URL    string `toml:"url"`
+	SHA256 string `toml:"sha256,omitempty"` // archive digest from gox.lock or the source pin
+	Dir    string `toml:"dir"`              // directory under VendorDir, named as in the cache
+	Digest string `toml:"digest"`           // content digest of the vendored directory
+}
+
+// vendorRoot is the VendorDir packages resolve from first, or "" when
+// vendoring is off.
+var vendorRoot string
+
+// UseVendor makes packages resolve from the project's VendorDir before the
+// cache for the rest of the process when vendor is set.
+func (c *Config) UseVendor() {
+	vendorRoot = ""
+	if c.Default.Vendor {
+		vendorRoot = filepath.Join(c.dir, VendorDir)
+	}
+}
+
+// vendored returns the vendored copy of p, or "" when there is none.
+func (p *Package) vendored() string {
+	if vendorRoot == "" {
+		return ""
+	}
+	if dir := filepath.Join(vendorRoot, p.Dir); isDir(dir) {
+		return dir
+	}
+	return ""
+}
+
+// Vendor replaces dir with copies of the packages sources name, taken from
+// the cache after downloading any that are missing, and a manifest listing
+// them.
+func Vendor(ctx context.Context, sources []string, dir string) ([]Vendored, error) {
+	saved := vendorRoot
+	vendorRoot = ""
+	defer func() { vendorRoot = saved }()
+	pkgs, err := EnsureAll(ctx, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), "."+filepath.Base(dir)+"-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	var out []Vendored
+	for _, p := range pkgs {
+		if slices.ContainsFunc(out, func(v Vendored) bool { return v.Dir == p.Dir }) {
+			continue
+		}
+		dst := filepath.Join(tmp, p.Dir)
+		jobs, err := planCopy(filepath.Join(cacheDir(), p.Dir), dst, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p.Source, err)
+		}
+		if _, err := copyAll(jobs, CopyFiles); err != nil {
+			return nil, fmt.Errorf("%s: %w", p.Source, err)
+		}
+		digest, err := archive.Digest(dst)
+		if err != nil {
+			return nil, err
+		}
+		v := Vendored{Source: p.Source, URL: p.URL, SHA256: p.SHA256, Dir: p.Dir, Digest: digest}
+		if l := lock.Active(); l != nil && v.SHA256 == "" {
+			locked, _ := l.Package(p.Source)
+			v.SHA256 = locked.SHA256
+		}
+		out = append(out, v)
+	}
+	if err := writeVendorManifest(filepath.Join(tmp, VendorManifest), out); err != nil {
+		return nil, err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReadVendorManifest returns the packages listed in dir's manifest.
+func ReadVendorManifest(dir string) ([]Vendored, error) {
+	var m struct {
+		Packages []Vendored `toml:"package"`
+	}
+	if _, err := toml.DecodeFile(filepath.Join(dir, VendorManifest), &m); err != nil {
+		return nil, err
+	}
+	return m.Packages, nil
+}
+
+func writeVendorManifest(path string, pkgs []Vendored) error {
+	var buf bytes.Buffer
+	buf.WriteString(vendorHeader)
+	enc := toml.NewEncoder(&buf)
+	enc.Indent = ""
+	if err := enc.Encode(struct {
+		Packages []Vendored `toml:"package"`
+	}{pkgs}); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// warnUnvendored reports packages a vendoring project resolves from the
+// cache or network because VendorDir lacks them.
+func warnUnvendored(pkgs []*Package) {
+	if vendorRoot == "" {
+		return
+	}
+	for _, p := range pkgs {
+		if p.vendored() == "" {
+			ui.Warn("%s is not vendored in %s (run gox vendor)", p.Source, VendorDir)
+		}
+	}
+}
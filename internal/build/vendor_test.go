@@ -0,0 +1,59 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVendor(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Cleanup(func() { vendorRoot = "" })
+	for _, name := range []string{"acme-ssl-v1-ssl", "acme-zlib-v1-zlib"} {
+		inc := filepath.Join(cacheDir(), name, "include")
+		if err := os.MkdirAll(inc, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(inc, name+".h"), []byte("#pragma once\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := &Config{dir: t.TempDir(), Default: ConfigDefault{Vendor: true}}
+	dir := filepath.Join(cfg.dir, VendorDir)
+	ssl, zlib := "acme/ssl@v1/ssl.tar.gz", "acme/zlib@v1/zlib.tar.gz"
+
+	got, err := Vendor(t.Context(), []string{ssl, zlib, ssl}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Dir != "acme-ssl-v1-ssl" || got[0].Digest == "" {
+		t.Fatalf("Vendor() = %+v", got)
+	}
+	manifest, err := ReadVendorManifest(dir)
+	if err != nil || len(manifest) != 2 || manifest[1].Source != zlib {
+		t.Fatalf("ReadVendorManifest() = %+v, %v", manifest, err)
+	}
+
+	// Builds resolve vendored packages without the cache.
+	cfg.UseVendor()
+	if err := os.RemoveAll(cacheDir()); err != nil {
+		t.Fatal(err)
+	}
+	pkgs, err := EnsureAll(t.Context(), []string{ssl})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "acme-ssl-v1-ssl", "include"); pkgs[0].Include != want {
+		t.Errorf("Include = %s, want %s", pkgs[0].Include, want)
+	}
+
+	// Vendoring again drops packages no longer used.
+	cfg.Default.Vendor = false
+	cfg.UseVendor()
+	if _, err := Vendor(t.Context(), nil, dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "acme-zlib-v1-zlib")); !os.IsNotExist(err) {
+		t.Errorf("unused package kept: %v", err)
+	}
+}
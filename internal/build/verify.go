@@ -0,0 +1,144 @@
+package build
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// digestStamp is the file in a package's cache entry recording the digest
+// of its contents when it was downloaded, and its source, in the format of
+// sha256sum: "<hex>  <source>".
+const digestStamp = ".gox-digest"
+
+// Statuses of a cached package VerifyCached reports.
+const (
+	VerifyOK         = "ok"
+	VerifyModified   = "modified"   // contents differ from the recorded digest
+	VerifyUnrecorded = "unrecorded" // cached before digests were recorded
+)
+
+// CacheCheck is the result of verifying one cached package.
+type CacheCheck struct {
+	Name   string // cache entry, as in ListCached
+	Source string // package source; "" when unrecorded
+	Status string
+}
+
+// stampDigest records the digest of p's cache entry.
+func (p *Package) stampDigest() error {
+	dir := filepath.Join(cacheDir(), p.Dir)
+	digest, err := contentDigest(dir)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, digestStamp), []byte(digest+"  "+p.Source+"\n"), 0o644)
+}
+
+// VerifyCached hashes every cached package again and compares it with the
+// digest recorded when it was downloaded.
+func VerifyCached() ([]CacheCheck, error) {
+	entries, err := ListCached()
+	if err != nil {
+		return nil, err
+	}
+	checks := make([]CacheCheck, 0, len(entries))
+	for _, e := range entries {
+		c := CacheCheck{Name: e.Name, Status: VerifyUnrecorded}
+		data, err := os.ReadFile(filepath.Join(e.Path, digestStamp))
+		if err == nil {
+			want, source, _ := strings.Cut(strings.TrimSpace(string(data)), "  ")
+			c.Source = source
+			got, err := contentDigest(e.Path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", e.Name, err)
+			}
+			c.Status = VerifyOK
+			if got != want {
+				c.Status = VerifyModified
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		checks = append(checks, c)
+	}
+	return checks, nil
+}
+
+// RepairCached downloads the modified packages of checks again, verified
+// against gox.lock when there is one.
+func RepairCached(ctx context.Context, checks []CacheCheck) error {
+	var sources []string
+	for _, c := range checks {
+		if c.Status != VerifyModified {
+			continue
+		}
+		if err := RemoveCached(c.Name); err != nil {
+			return err
+		}
+		sources = append(sources, c.Source)
+	}
+	// Repair the cache even where a vendored copy would be used instead.
+	saved := vendorRoot
+	vendorRoot = ""
+	defer func() { vendorRoot = saved }()
+	_, err := EnsureAll(ctx, sources)
+	return err
+}
+
+// contentDigest hashes the names, permissions and contents of the files
+// and symlinks below dir. Directories, the stamps gox keeps in cache
+// entries and the .pc files it writes from [pkgconfig] are left out, so
+// the digest holds as long as the package's own files do.
+func contentDigest(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == digestStamp || rel == vcpkgStamp || strings.HasSuffix(rel, ".pc") && writtenByGox(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%o\x00", rel, info.Mode()&(fs.ModeType|fs.ModePerm))
+		if d.Type()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			io.WriteString(h, target)
+		} else if err := hashFile(h, path); err != nil {
+			return err
+		}
+		h.Write([]byte{0})
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,100 @@
+package build
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qntx/gox/internal/archive"
+)
+
+func TestVerifyCached(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	src := filepath.Join(t.TempDir(), "pkg")
+	for name, data := range map[string]string{"include/a.h": "#define A 1\n", "lib/liba.a": "a\n"} {
+		path := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tarPath, err := archive.Create(src, "linux", "amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, tarPath)
+	}))
+	defer srv.Close()
+	source := srv.URL + "/a.tar.gz"
+
+	pkgs, err := EnsureAll(context.Background(), []string{source})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := pkgs[0]
+	if err := os.MkdirAll(filepath.Join(cacheDir(), "legacy", "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Modules gox writes later do not count as changes.
+	if err := usePkgConfig(t, map[string]PkgConfigModule{"a": {Package: "*", Libs: []string{"-la"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeModules([]*Package{p}); err != nil {
+		t.Fatal(err)
+	}
+	checks, err := VerifyCached()
+	if err != nil {
+		t.Fatalf("VerifyCached() error = %v", err)
+	}
+	want := map[string]CacheCheck{
+		p.Dir:    {Name: p.Dir, Source: source, Status: VerifyOK},
+		"legacy": {Name: "legacy", Status: VerifyUnrecorded},
+	}
+	if len(checks) != len(want) {
+		t.Fatalf("VerifyCached() = %+v", checks)
+	}
+	for _, c := range checks {
+		if c != want[c.Name] {
+			t.Errorf("VerifyCached() %s = %+v, want %+v", c.Name, c, want[c.Name])
+		}
+	}
+
+	// A changed header or mode is found, and --fix restores it.
+	header := filepath.Join(p.Include, "a.h")
+	for _, tamper := range []func() error{
+		func() error { return os.WriteFile(header, []byte("#define A 2\n"), 0o644) },
+		func() error { return os.Chmod(header, 0o755) },
+		func() error { return os.Remove(header) },
+	} {
+		if err := tamper(); err != nil {
+			t.Fatal(err)
+		}
+		checks, err := VerifyCached()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got *CacheCheck
+		for i := range checks {
+			if checks[i].Name == p.Dir {
+				got = &checks[i]
+			}
+		}
+		if got == nil || got.Status != VerifyModified {
+			t.Fatalf("VerifyCached() after tampering = %+v, want %s modified", checks, p.Dir)
+		}
+		if err := RepairCached(context.Background(), checks); err != nil {
+			t.Fatalf("RepairCached() error = %v", err)
+		}
+		if data, err := os.ReadFile(header); err != nil || string(data) != "#define A 1\n" {
+			t.Errorf("a.h after RepairCached() = %q, %v", data, err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package build
+
+import (
+	"cmp"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Version names the string variables gox sets with -ldflags -X to the
+// version, commit and build date, sparing projects the Makefile that does
+// it. Unset variables are left alone.
+type Version struct {
+	Package string `toml:"package,omitempty"` // import path holding the variables (default: main)
+	Version string `toml:"version,omitempty"` // set to git describe --tags --always --dirty
+	Commit  string `toml:"commit,omitempty"`  // set to the short commit hash
+	Date    string `toml:"date,omitempty"`    // set to the build date, RFC 3339 in UTC
+}
+
+// Merge fills unset fields of v from base.
+func (v Version) Merge(base Version) Version {
+	v.Package = cmp.Or(v.Package, base.Package)
+	v.Version = cmp.Or(v.Version, base.Version)
+	v.Commit = cmp.Or(v.Commit, base.Commit)
+	v.Date = cmp.Or(v.Date, base.Date)
+	return v
+}
+
+// buildDate is the date every target of a run records: $SOURCE_DATE_EPOCH
+// when set, so reproducible builds agree, else the time of the first use.
+var buildDate = sync.OnceValue(func() string {
+	t := time.Now()
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		t = time.Unix(epoch, 0)
+	}
+	return t.UTC().Format(time.RFC3339)
+})
+
+// ldflags returns the -X flags setting v's variables, with date as the
+// build date, leaving out values that are unknown, such as the version
+// outside a git repository.
+func (v *Version) ldflags(date string) []string {
+	if v.Version == "" && v.Commit == "" && v.Date == "" {
+		return nil
+	}
+	pkg := cmp.Or(v.Package, "main")
+	version, commit := gitInfo()
+	var flags []string
+	for _, x := range []struct{ name, value string }{
+		{v.Version, version},
+		{v.Commit, commit},
+		{v.Date, date},
+	} {
+		if x.name != "" && x.value != "" {
+			flags = append(flags, "-X", pkg+"."+x.name+"="+x.value)
+		}
+	}
+	return flags
+}
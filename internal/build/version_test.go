@@ -0,0 +1,60 @@
+package build
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestVersion_LDFlags(t *testing.T) {
+	git, now := gitInfo, buildDate
+	t.Cleanup(func() { gitInfo, buildDate = git, now })
+	buildDate = func() string { return "2026-01-02T03:04:05Z" }
+	date := buildDate()
+
+	tests := []struct {
+		name    string
+		v       Version
+		version string
+		want    []string
+	}{
+		{"unset", Version{}, "v1.2.3", nil},
+		{
+			"main", Version{Version: "Version", Commit: "Commit", Date: "Date"}, "v1.2.3",
+			[]string{"-X", "main.Version=v1.2.3", "-X", "main.Commit=abc1234", "-X", "main.Date=2026-01-02T03:04:05Z"},
+		},
+		{
+			"package", Version{Package: "example.com/app/internal/buildinfo", Version: "version"}, "v1.2.3-1-gabc1234-dirty",
+			[]string{"-X", "example.com/app/internal/buildinfo.version=v1.2.3-1-gabc1234-dirty"},
+		},
+		{"no git", Version{Version: "Version", Date: "Date"}, "", []string{"-X", "main.Date=2026-01-02T03:04:05Z"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commit := "abc1234"
+			if tt.version == "" {
+				commit = ""
+			}
+			gitInfo = func() (string, string) { return tt.version, commit }
+			if got := tt.v.ldflags(date); !slices.Equal(got, tt.want) {
+				t.Errorf("ldflags() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	gitInfo = func() (string, string) { return "v2.0.0", "" }
+	b := New("", &Options{GOOS: "linux", Strip: true, Version: Version{Version: "Version"}})
+	if got, want := b.goLDFlags(), "-s -w -X main.Version=v2.0.0"; got != want {
+		t.Errorf("goLDFlags() = %q, want %q", got, want)
+	}
+}
+
+func TestConfig_Version(t *testing.T) {
+	cfg := Config{Version: Version{Package: "example.com/app/version", Commit: "Commit"}, Targets: []ConfigTarget{{Name: "a", OS: "linux", Arch: "amd64"}}}
+	opts, err := cfg.ToOptions(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts[0].Version != cfg.Version {
+		t.Errorf("Version = %+v, want %+v", opts[0].Version, cfg.Version)
+	}
+}
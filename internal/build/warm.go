@@ -0,0 +1,70 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/workspace"
+)
+
+// warmSources are minimal programs whose link makes Zig build the target's
+// C runtime, and for C++ also libc++.
+var warmSources = []struct {
+	mode, file, src string
+	cxx             bool
+}{
+	{"cc", "warm.c", "int main(void) { return 0; }\n", false},
+	{"c++", "warm.cc", "#include <string>\nint main() { return std::string(\"gox\").size() == 3 ? 0 : 1; }\n", true},
+}
+
+// WarmLibc compiles and links small programs for the target so Zig builds
+// its libc, CRT and compiler-rt artifacts into ZigCacheDir ahead of the
+// first CGO build. With cxx, libc++ is built as well.
+func (b *Builder) WarmLibc(ctx context.Context, cxx bool) error {
+	if b.opts.GOOS == "android" || !b.opts.HasCgo() {
+		return nil // bionic comes prebuilt with the NDK; wasip1 has no cgo
+	}
+	dir, err := workspace.MkdirTemp("warm-*")
+	if err != nil {
+		return fmt.Errorf("temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, s := range warmSources {
+		if s.cxx && !cxx {
+			continue
+		}
+		src := filepath.Join(dir, s.file)
+		if err := os.WriteFile(src, []byte(s.src), 0o644); err != nil {
+			return err
+		}
+		args := b.warmArgs(s.mode, src, filepath.Join(dir, "warm"))
+		if b.opts.Verbose {
+			fmt.Fprintf(ui.Stderr, "zig %s\n", strings.Join(args, " "))
+		}
+		cmd := exec.CommandContext(ctx, b.zigBin(), args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), zigCacheEnv()...)
+		cmd.Stdout = b.stdout
+		cmd.Stderr = b.stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("zig %s: %w", s.mode, err)
+		}
+	}
+	return nil
+}
+
+// warmArgs returns the zig arguments linking src for the target, with the
+// optimization cgo compiles with by default so the cached artifacts match.
+func (b *Builder) warmArgs(mode, src, out string) []string {
+	args := []string{mode, "-target", b.opts.ZigTarget(), "-O2", "-g"}
+	if b.opts.LinkMode.IsStatic() {
+		args = append(args, "-static")
+	}
+	return append(args, "-o", out, src)
+}
@@ -0,0 +1,30 @@
+package build
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBuilder_WarmArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		mode string
+		want []string
+	}{
+		{"glibc", Options{GOOS: "linux", GOARCH: "amd64"}, "cc",
+			[]string{"cc", "-target", "x86_64-linux-gnu", "-O2", "-g", "-o", "out", "in.c"}},
+		{"static musl", Options{GOOS: "linux", GOARCH: "arm64", LinkMode: LinkStatic}, "cc",
+			[]string{"cc", "-target", "aarch64-linux-musl", "-O2", "-g", "-static", "-o", "out", "in.c"}},
+		{"windows c++", Options{GOOS: "windows", GOARCH: "amd64"}, "c++",
+			[]string{"c++", "-target", "x86_64-windows-gnu", "-O2", "-g", "-o", "out", "in.c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New("", &tt.opts).warmArgs(tt.mode, "in.c", "out")
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("warmArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package build
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/qntx/gox/internal/hosttool"
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/winres"
+)
+
+// Windows configures the resources linked into windows binaries: an icon,
+// an application manifest and the version information Explorer shows.
+// Version fields and strings are templates expanded like output paths.
+type Windows struct {
+	Icon             string `toml:"icon,omitempty"`     // .ico file
+	Manifest         string `toml:"manifest,omitempty"` // application manifest
+	FileVersion      string `toml:"file-version,omitempty"`
+	ProductVersion   string `toml:"product-version,omitempty"`
+	CompanyName      string `toml:"company-name,omitempty"`
+	FileDescription  string `toml:"file-description,omitempty"`
+	ProductName      string `toml:"product-name,omitempty"`
+	Copyright        string `toml:"copyright,omitempty"`
+	OriginalFilename string `toml:"original-filename,omitempty"` // default: the binary's name
+	InternalName     string `toml:"internal-name,omitempty"`     // default: the binary's name without .exe
+	Comments         string `toml:"comments,omitempty"`
+}
+
+// Merge fills unset fields of w from base.
+func (w Windows) Merge(base Windows) Windows {
+	w.Icon = cmp.Or(w.Icon, base.Icon)
+	w.Manifest = cmp.Or(w.Manifest, base.Manifest)
+	w.FileVersion = cmp.Or(w.FileVersion, base.FileVersion)
+	w.ProductVersion = cmp.Or(w.ProductVersion, base.ProductVersion)
+	w.CompanyName = cmp.Or(w.CompanyName, base.CompanyName)
+	w.FileDescription = cmp.Or(w.FileDescription, base.FileDescription)
+	w.ProductName = cmp.Or(w.ProductName, base.ProductName)
+	w.Copyright = cmp.Or(w.Copyright, base.Copyright)
+	w.OriginalFilename = cmp.Or(w.OriginalFilename, base.OriginalFilename)
+	w.InternalName = cmp.Or(w.InternalName, base.InternalName)
+	w.Comments = cmp.Or(w.Comments, base.Comments)
+	return w
+}
+
+// windows returns the [windows] section with relative icon and manifest
+// paths resolved against the config directory.
+func (c *Config) windows() Windows {
+	w := c.Windows
+	for _, p := range []*string{&w.Icon, &w.Manifest} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(c.dir, *p)
+		}
+	}
+	return w
+}
+
+// hasVersion reports whether w sets any version information.
+func (w *Windows) hasVersion() bool {
+	v := *w
+	v.Icon, v.Manifest = "", ""
+	return v != Windows{}
+}
+
+// templates returns the template text of w's version fields, for usesGit.
+func (w *Windows) templates() string {
+	v := *w
+	v.Icon, v.Manifest = "", ""
+	return fmt.Sprint(v)
+}
+
+// sysoName is the resource object gox writes into each main package of a
+// windows build. Its _windows_<arch> suffix keeps other targets from
+// linking it.
+func sysoName(goarch string) string {
+	return "gox_resources_windows_" + goarch + ".syso"
+}
+
+// sysoLocks serializes builds sharing a resource object path, such as two
+// variants of one windows target.
+var sysoLocks sync.Map // path -> *sync.Mutex
+
+// writeSyso writes the [windows] resources into the main packages of pkgs
+// and returns a function removing them again, or nil when the target needs
+// none.
+func (b *Builder) writeSyso(ctx context.Context, pkgs []string) (func(), error) {
+	w := b.opts.Windows
+	if b.opts.GOOS != "windows" || w == (Windows{}) {
+		return nil, nil
+	}
+	res, err := b.resources(pkgs)
+	if err != nil {
+		return nil, err
+	}
+	data, err := res.Syso(b.opts.GOARCH)
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := b.mainDirs(ctx, pkgs)
+	if err != nil {
+		return nil, err
+	}
+
+	var written []string
+	var locked []*sync.Mutex
+	cleanup := func() {
+		for _, p := range written {
+			_ = os.Remove(p)
+		}
+		for _, mu := range locked {
+			mu.Unlock()
+		}
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, sysoName(b.opts.GOARCH))
+		mu, _ := sysoLocks.LoadOrStore(path, new(sync.Mutex))
+		mu.(*sync.Mutex).Lock()
+		locked = append(locked, mu.(*sync.Mutex))
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			cleanup()
+			return nil, err
+		}
+		written = append(written, path)
+		if b.opts.Verbose {
+			ui.Label("resources", path)
+		}
+	}
+	return cleanup, nil
+}
+
+// resources reads the icon and manifest and expands the version fields of
+// the [windows] section.
+func (b *Builder) resources(pkgs []string) (*winres.Resources, error) {
+	w := b.opts.Windows
+	var r winres.Resources
+	var err error
+	if w.Icon != "" {
+		if r.Icon, err = os.ReadFile(w.Icon); err != nil {
+			return nil, fmt.Errorf("windows.icon: %w", err)
+		}
+	}
+	if w.Manifest != "" {
+		if r.Manifest, err = os.ReadFile(w.Manifest); err != nil {
+			return nil, fmt.Errorf("windows.manifest: %w", err)
+		}
+	}
+	if !w.hasVersion() {
+		return &r, nil
+	}
+
+	fields := []struct{ key, name, value string }{
+		{"file-version", "FileVersion", w.FileVersion},
+		{"product-version", "ProductVersion", w.ProductVersion},
+		{"company-name", "CompanyName", w.CompanyName},
+		{"file-description", "FileDescription", w.FileDescription},
+		{"product-name", "ProductName", w.ProductName},
+		{"copyright", "LegalCopyright", w.Copyright},
+		{"original-filename", "OriginalFilename", w.OriginalFilename},
+		{"internal-name", "InternalName", w.InternalName},
+		{"comments", "Comments", w.Comments},
+	}
+	data := b.opts.pathData(pkgs, usesGit(w.templates()))
+	strs := map[string]string{}
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		s, err := expandPath("windows."+f.key, f.value, data)
+		if err != nil {
+			return nil, err
+		}
+		strs[f.name] = s
+	}
+	strs["FileVersion"] = cmp.Or(strs["FileVersion"], strs["ProductVersion"])
+	strs["ProductVersion"] = cmp.Or(strs["ProductVersion"], strs["FileVersion"])
+	if name := filepath.Base(b.outputPath()); b.outputPath() != "" {
+		strs["OriginalFilename"] = cmp.Or(strs["OriginalFilename"], name)
+		strs["InternalName"] = cmp.Or(strs["InternalName"], strings.TrimSuffix(name, ".exe"))
+	}
+	for k, v := range strs {
+		if v == "" {
+			delete(strs, k)
+		}
+	}
+	r.Version = &winres.VersionInfo{
+		FileVersion:    winres.ParseVersion(strs["FileVersion"]),
+		ProductVersion: winres.ParseVersion(strs["ProductVersion"]),
+		Strings:        strs,
+	}
+	return &r, nil
+}
+
+// mainDirs returns the directories of the main packages among pkgs, as the
+// target sees them.
+func (b *Builder) mainDirs(ctx context.Context, pkgs []string) ([]string, error) {
+	args := append([]string{"list", "-f", `{{if eq .Name "main"}}{{.Dir}}{{end}}`}, pkgs...)
+	cmd := hosttool.Command(ctx, "go", args...)
+	cmd.Env = append(os.Environ(), b.buildEnv()...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %w\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+	var dirs []string
+	for line := range strings.Lines(string(out)) {
+		if dir := strings.TrimSpace(line); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	slices.Sort(dirs) // lock order
+	return dirs, nil
+}
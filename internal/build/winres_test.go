@@ -0,0 +1,89 @@
+package build
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuilder_Resources(t *testing.T) {
+	tests := []struct {
+		name      string
+		windows   Windows
+		output    string
+		want      map[string]string
+		version   [4]uint16
+		noVersion bool
+	}{
+		{
+			name:    "defaults",
+			windows: Windows{ProductVersion: "v1.4.2", CompanyName: "Acme"},
+			output:  "dist/app.exe",
+			want: map[string]string{
+				"FileVersion": "v1.4.2", "ProductVersion": "v1.4.2", "CompanyName": "Acme",
+				"OriginalFilename": "app.exe", "InternalName": "app",
+			},
+			version: [4]uint16{1, 4, 2, 0},
+		},
+		{
+			name:    "templates",
+			windows: Windows{FileVersion: "2.0.{{.Arch}}", FileDescription: "{{.Name}} for {{.OS}}", InternalName: "tool"},
+			want: map[string]string{
+				"FileVersion": "2.0.amd64", "ProductVersion": "2.0.amd64", "FileDescription": "app for windows",
+				"InternalName": "tool",
+			},
+			version: [4]uint16{2, 0, 0, 0},
+		},
+		{
+			name:      "manifest only",
+			windows:   Windows{Manifest: filepath.Join(t.TempDir(), "app.manifest")},
+			noVersion: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.windows.Manifest != "" {
+				if err := os.WriteFile(tt.windows.Manifest, []byte("<assembly/>"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			b := New("", &Options{GOOS: "windows", GOARCH: "amd64", Output: tt.output, Windows: tt.windows})
+			r, err := b.resources([]string{"./cmd/app"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.noVersion {
+				if r.Version != nil || string(r.Manifest) != "<assembly/>" {
+					t.Errorf("resources() = %+v, want the manifest only", r)
+				}
+				return
+			}
+			if !maps.Equal(r.Version.Strings, tt.want) {
+				t.Errorf("strings = %v, want %v", r.Version.Strings, tt.want)
+			}
+			if r.Version.FileVersion != tt.version || r.Version.ProductVersion != tt.version {
+				t.Errorf("versions = %v, %v, want %v", r.Version.FileVersion, r.Version.ProductVersion, tt.version)
+			}
+		})
+	}
+
+	b := New("", &Options{GOOS: "windows", GOARCH: "amd64", Windows: Windows{Icon: filepath.Join(t.TempDir(), "missing.ico")}})
+	if _, err := b.resources(nil); err == nil || !strings.Contains(err.Error(), "windows.icon") {
+		t.Errorf("resources() with a missing icon = %v, want windows.icon error", err)
+	}
+}
+
+func TestConfig_Windows(t *testing.T) {
+	dir, manifest := t.TempDir(), filepath.Join(t.TempDir(), "app.manifest")
+	cfg := Config{dir: dir, Windows: Windows{Icon: "assets/app.ico", Manifest: manifest, ProductName: "App"}}
+	opts, err := cfg.ToOptions(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Windows{Icon: filepath.Join(dir, "assets", "app.ico"), Manifest: manifest, ProductName: "App"}
+	if got := opts[0].Windows; got != want {
+		t.Errorf("Windows = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,74 @@
+// Package cache locates the directory gox uses for downloaded toolchains,
+// packages and other cached state.
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Scope selects where cached state is stored.
+type Scope string
+
+const (
+	ScopeUser    Scope = "user"    // shared per-user cache (default)
+	ScopeProject Scope = "project" // .gox/ next to the project config
+)
+
+// ProjectDir is the directory name used for project-scoped caches.
+const ProjectDir = ".gox"
+
+var root string
+
+// Valid reports whether s is a known scope. Empty means ScopeUser.
+func (s Scope) Valid() bool {
+	switch s {
+	case "", ScopeUser, ScopeProject:
+		return true
+	}
+	return false
+}
+
+// Use selects the cache scope for the rest of the process. projectDir is the
+// directory holding the project config and is only used for ScopeProject.
+func Use(scope Scope, projectDir string) error {
+	switch scope {
+	case "", ScopeUser:
+		root = ""
+		return nil
+	case ScopeProject:
+		abs, err := filepath.Abs(filepath.Join(projectDir, ProjectDir))
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(abs, 0o755); err != nil {
+			return err
+		}
+		// Keep the cache out of version control without touching the
+		// project's own .gitignore.
+		ignore := filepath.Join(abs, ".gitignore")
+		if _, err := os.Stat(ignore); os.IsNotExist(err) {
+			_ = os.WriteFile(ignore, []byte("*\n"), 0o644)
+		}
+		root = abs
+		return nil
+	}
+	return fmt.Errorf("invalid cache scope: %s", scope)
+}
+
+// Root returns the cache root directory.
+func Root() string {
+	if root != "" {
+		return root
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "gox")
+	}
+	return filepath.Join(os.TempDir(), "gox")
+}
+
+// Dir returns a subdirectory of the cache root.
+func Dir(elem ...string) string {
+	return filepath.Join(append([]string{Root()}, elem...)...)
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScope_Valid(t *testing.T) {
+	tests := []struct {
+		scope Scope
+		want  bool
+	}{
+		{"", true},
+		{ScopeUser, true},
+		{ScopeProject, true},
+		{"global", false},
+	}
+	for _, tt := range tests {
+		if got := tt.scope.Valid(); got != tt.want {
+			t.Errorf("Scope(%q).Valid() = %v, want %v", tt.scope, got, tt.want)
+		}
+	}
+}
+
+func TestUse(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Cleanup(func() { root = "" })
+
+	userRoot := Root()
+
+	project := t.TempDir()
+	if err := Use(ScopeProject, project); err != nil {
+		t.Fatalf("Use(project) error = %v", err)
+	}
+	want := filepath.Join(project, ProjectDir)
+	if got := Root(); got != want {
+		t.Errorf("Root() = %q, want %q", got, want)
+	}
+	if got := Dir("zig"); got != filepath.Join(want, "zig") {
+		t.Errorf("Dir(zig) = %q, want %q", got, filepath.Join(want, "zig"))
+	}
+	if _, err := os.Stat(filepath.Join(want, ".gitignore")); err != nil {
+		t.Errorf(".gitignore not created: %v", err)
+	}
+
+	if err := Use(ScopeUser, project); err != nil {
+		t.Fatalf("Use(user) error = %v", err)
+	}
+	if got := Root(); got != userRoot {
+		t.Errorf("Root() = %q, want %q", got, userRoot)
+	}
+
+	if err := Use("global", project); err == nil {
+		t.Error("Use() should reject unknown scope")
+	}
+}
@@ -5,21 +5,46 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/qntx/gox/internal/archive"
 	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/daemon"
+	"github.com/qntx/gox/internal/hosttool"
+	"github.com/qntx/gox/internal/plugin"
+	"github.com/qntx/gox/internal/telemetry"
 	"github.com/qntx/gox/internal/ui"
 	"github.com/qntx/gox/internal/zig"
 )
 
 type buildFlags struct {
-	config   string
-	targets  []string
-	linkMode string
-	parallel bool
-	opts     build.Options
+	config     string
+	targets    []string
+	linkMode   string
+	buildMode  string
+	packFormat string
+	libCopy    string
+	parallel   int
+	buildable  bool
+	container  string
+	remote     string
+	workers    []string
+	maxMemory  string
+	dryRun     bool
+	watch      bool
+	ignore     []string
+	opts       build.Options
 }
 
 var (
@@ -33,7 +58,20 @@ Configuration can be loaded from gox.toml in the current or parent directories.
 CLI flags override config file values.
 
 When --target is not specified and gox.toml exists, all targets are built.
-Use --target to build specific targets (comma-separated or repeated).`,
+Use --target to build specific targets (comma-separated or repeated).
+
+Passing --os or --arch without --target builds a single ad-hoc target from
+the [default] section instead of overriding every configured target.
+
+Use --container[=image] to run the whole build inside docker or podman with
+the module and gox cache mounted, e.g. on hosts without Go installed.
+Use --remote[=addr] to queue the build on a running gox daemon.
+Use --workers host1,host2 to build the targets on remote 'gox worker'
+machines (experimental); the files they write are copied back.
+
+Use --watch to rebuild whenever .go, C/C++ sources or headers, go.mod or
+gox.toml change in the module; --watch-ignore and the watch-ignore config
+key skip paths by glob pattern.`,
 		RunE: runBuild,
 	}
 )
@@ -43,53 +81,233 @@ func init() {
 
 	f.StringVarP(&flags.config, "config", "c", "", "config file path (default: gox.toml)")
 	f.StringSliceVarP(&flags.targets, "target", "t", nil, "build targets")
+	_ = buildCmd.RegisterFlagCompletionFunc("target", completeTargets)
 	f.StringVar(&flags.opts.GOOS, "os", "", "target operating system")
 	f.StringVar(&flags.opts.GOARCH, "arch", "", "target architecture")
 	f.StringVarP(&flags.opts.Output, "output", "o", "", "output file path")
 	f.StringVar(&flags.opts.Prefix, "prefix", "", "output prefix directory")
 	f.StringVar(&flags.opts.ZigVersion, "zig-version", "", "zig compiler version")
+	f.StringVar(&flags.opts.GoVersion, "go-version", "", "go toolchain version (via GOTOOLCHAIN)")
 	f.StringVar(&flags.linkMode, "linkmode", "", "link mode: static|dynamic|auto")
+	f.StringVar(&flags.buildMode, "buildmode", "", "build mode: exe|c-shared|c-archive")
 	f.StringSliceVarP(&flags.opts.IncludeDirs, "include", "I", nil, "include directories")
 	f.StringSliceVarP(&flags.opts.LibDirs, "lib", "L", nil, "library directories")
 	f.StringSliceVarP(&flags.opts.Libs, "link", "l", nil, "libraries to link")
+	f.StringSliceVar(&flags.opts.LibExclude, "lib-exclude", nil, "glob patterns excluded when copying libs to prefix")
+	f.StringVar(&flags.libCopy, "lib-copy", "", "how libs are placed in prefix: copy|hardlink|symlink")
 	f.StringSliceVar(&flags.opts.Packages, "pkg", nil, "packages to download")
 	f.StringSliceVar(&flags.opts.BuildFlags, "flags", nil, "additional build flags")
 	f.BoolVar(&flags.opts.NoRpath, "no-rpath", false, "disable rpath")
 	f.BoolVar(&flags.opts.Pack, "pack", false, "create archive")
+	f.StringVar(&flags.packFormat, "pack-format", "", "pack format: archive|deb|rpm|apk (implies --pack)")
+	f.BoolVar(&flags.opts.DepsReport, "deps-report", false, "write dependencies.json next to the artifact")
+	f.BoolVar(&flags.opts.PkgConfig, "pkg-config", false, "write a pkg-config file for a c-shared or c-archive library")
+	f.BoolVar(&flags.opts.Checksum, "checksum", false, "write <archive>.sha256 and SHA256SUMS for packed archives")
+	f.BoolVar(&flags.opts.Reproducible, "reproducible", false, "build reproducibly: -trimpath, no build ID or VCS stamp, deterministic archives")
+	f.BoolVar(&flags.opts.IsolateCache, "isolate-gocache", false, "use a separate GOCACHE per GOOS/GOARCH under the gox cache")
 	f.BoolVarP(&flags.opts.Strip, "strip", "s", false, "strip symbols (-ldflags=\"-s -w\")")
 	f.BoolVarP(&flags.opts.Verbose, "verbose", "v", false, "verbose output")
-	f.BoolVarP(&flags.parallel, "parallel", "j", false, "parallel builds")
+	f.BoolVarP(&flags.dryRun, "dry-run", "n", false, "print the go commands and environment without running them")
+	f.IntVarP(&flags.parallel, "parallel", "j", 1, "targets to build at once (0 or bare -j: one per two CPUs)")
+	f.Lookup("parallel").NoOptDefVal = "0"
+	f.StringVar(&flags.maxMemory, "max-memory", "", "limit estimated memory of parallel builds (e.g. 4G)")
+	f.BoolVar(&flags.buildable, "only-buildable", false, "skip targets the host toolchain cannot build")
+	f.StringVar(&flags.container, "container", "", "run the build inside a docker/podman container (default image: "+defaultContainerImage+")")
+	f.Lookup("container").NoOptDefVal = defaultContainerImage
+	f.StringVar(&flags.remote, "remote", "", "send the build to a gox daemon (default address: "+daemon.DefaultAddr+")")
+	f.Lookup("remote").NoOptDefVal = daemon.DefaultAddr
+	f.StringSliceVar(&flags.workers, "workers", nil, "build targets on these gox workers (host[:port], experimental)")
+	f.BoolVarP(&flags.watch, "watch", "w", false, "rebuild when source files change")
+	f.StringSliceVar(&flags.ignore, "watch-ignore", nil, "glob patterns of paths not watched")
+	buildCmd.MarkFlagsMutuallyExclusive("workers", "remote", "container", "watch")
 
 	rootCmd.AddCommand(buildCmd)
 }
 
 func runBuild(cmd *cobra.Command, args []string) error {
+	args = parallelArg(cmd, os.Args[1:], args)
+	if flags.remote != "" {
+		return runRemote(cmd, flags.remote)
+	}
+	if flags.watch && !flags.dryRun {
+		return watchBuild(cmd, args)
+	}
+	return buildTargets(cmd, args)
+}
+
+// parallelArg takes the count of `-j N` and `--parallel N` out of args:
+// since -j may be given bare, pflag leaves N as a positional argument. raw
+// is the command line without the program name; only a number right after a
+// bare -j or --parallel in it is taken, so numeric package arguments stay.
+func parallelArg(cmd *cobra.Command, raw, args []string) []string {
+	if !cmd.Flags().Changed("parallel") || flags.parallel != 0 {
+		return args
+	}
+	// raw starts with the command path, which pflag does not count.
+	pos, at, n := -strings.Count(cmd.CommandPath(), " "), -1, 0
+	for i := 0; i < len(raw); i++ {
+		switch a := raw[i]; {
+		case a == "--":
+			i = len(raw)
+		case a == "-j" || a == "--parallel":
+			at = -1
+			if i+1 < len(raw) && raw[i+1] != "" && strings.Trim(raw[i+1], "0123456789") == "" {
+				if v, err := strconv.Atoi(raw[i+1]); err == nil {
+					at, n = pos, v
+					pos++
+					i++
+				}
+			}
+		case strings.HasPrefix(a, "-") && a != "-":
+			if takesValue(cmd, a) {
+				i++
+			}
+		default:
+			pos++
+		}
+	}
+	if at < 0 || at >= len(args) {
+		return args
+	}
+	flags.parallel = n
+	return slices.Delete(slices.Clone(args), at, at+1)
+}
+
+// takesValue reports whether the flag token a consumes the next token as
+// its value, as pflag parses it.
+func takesValue(cmd *cobra.Command, a string) bool {
+	fs := cmd.Flags()
+	if strings.Contains(a, "=") {
+		return false
+	}
+	if name, ok := strings.CutPrefix(a, "--"); ok {
+		f := fs.Lookup(name)
+		return f != nil && f.NoOptDefVal == ""
+	}
+	// Of combined shorthands such as -vo, the first one taking a value
+	// takes the rest of the token, or the next one when nothing is left.
+	for i := 1; i < len(a); i++ {
+		f := fs.ShorthandLookup(a[i : i+1])
+		if f == nil {
+			return false
+		}
+		if f.NoOptDefVal == "" {
+			return i == len(a)-1
+		}
+	}
+	return false
+}
+
+// buildTargets builds every selected target once.
+func buildTargets(cmd *cobra.Command, args []string) error {
 	opts, err := loadBuildOptions(cmd)
 	if err != nil {
 		return err
 	}
-	if flags.parallel && len(opts) > 1 {
-		return runParallel(cmd, args, opts)
+	if flags.dryRun {
+		return dryRun(opts, "build", args, nil)
+	}
+	if flags.container != "" {
+		return runInContainer(cmd.Context(), flags.container)
+	}
+	if len(flags.workers) > 0 {
+		return runDistributed(cmd, opts, flags.workers)
+	}
+	if flags.buildable {
+		if opts, err = filterBuildable(opts); err != nil {
+			return err
+		}
 	}
-	return runSequential(cmd, args, opts)
+	if err := hosttool.Require(build.RequiredTools(opts)...); err != nil {
+		return err
+	}
+
+	// Report and confirm the packages of every target before the first starts.
+	var sources []string
+	for _, o := range opts {
+		sources = append(sources, o.Packages...)
+	}
+	if err := build.ConfirmDownloads(cmd.Context(), sources); err != nil {
+		return fmt.Errorf("packages: %w", err)
+	}
+
+	// Cancel builds on Ctrl-C so the summary can report what completed.
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	cmd.SetContext(ctx)
+
+	var summary *buildSummary
+	if flags.parallel != 1 && len(opts) > 1 {
+		summary, err = runParallel(cmd, args, opts)
+	} else {
+		summary, err = runSequential(cmd, args, opts)
+	}
+	if ctx.Err() != nil && summary != nil {
+		summary.cancel()
+		if len(opts) > 1 {
+			summary.render(true)
+		}
+		return errInterrupted
+	}
+	if summary != nil && len(opts) > 1 {
+		summary.render(false)
+	}
+	if err != nil {
+		return err
+	}
+	sums, err := writeSums(opts)
+	if err != nil {
+		return err
+	}
+	scoop, err := writeScoop(opts)
+	if err != nil {
+		return err
+	}
+	return publish(ctx, opts, sums, scoop)
 }
 
-func runSequential(cmd *cobra.Command, args []string, opts []*build.Options) error {
+// errInterrupted is returned when a build is stopped by a signal.
+var errInterrupted = errors.New("interrupted")
+
+func runSequential(cmd *cobra.Command, args []string, opts []*build.Options) (*buildSummary, error) {
+	summary := newBuildSummary(opts)
 	for i, o := range opts {
-		if err := executeBuild(cmd, args, o, i, len(opts)); err != nil {
-			return err
+		summary.start(i)
+		libs, err := executeBuild(cmd, args, o, i, len(opts))
+		summary.copied(i, libs)
+		summary.finish(cmd.Context(), i, err)
+		if err != nil {
+			return summary, err
 		}
 	}
-	return nil
+	return summary, nil
 }
 
-func runParallel(cmd *cobra.Command, args []string, opts []*build.Options) error {
+func runParallel(cmd *cobra.Command, args []string, opts []*build.Options) (*buildSummary, error) {
 	ui.Header(fmt.Sprintf("Building %d targets", len(opts)))
 
-	if err := preloadPackages(cmd.Context(), opts); err != nil {
-		return err
+	var limit int64
+	if flags.maxMemory != "" {
+		var err error
+		if limit, err = ui.ParseSize(flags.maxMemory); err != nil {
+			return nil, fmt.Errorf("--max-memory: %w", err)
+		}
+	}
+
+	opts, dups := dedupeOptions(opts)
+	summary := newBuildSummary(opts)
+	plan := newFetchPlan(opts)
+	slots := buildSlots(len(opts), flags.parallel)
+	mem := newMemBudget(limit)
+	logQueue(len(opts), dups, plan, slots)
+	if limit > 0 {
+		ui.Info("Memory budget: %s (targets without history assume %s)", ui.FormatSize(limit), ui.FormatSize(build.DefaultMemoryEstimate))
 	}
 
+	ctx := cmd.Context()
+	go func() { _ = plan.run(ctx) }()
+	sem := make(chan struct{}, slots)
+
 	type result struct {
 		target string
 		output string
@@ -99,10 +317,21 @@ func runParallel(cmd *cobra.Command, args []string, opts []*build.Options) error
 	results := make(chan result, len(opts))
 	var wg sync.WaitGroup
 
-	for _, o := range opts {
+	for i, o := range opts {
 		wg.Go(func() {
 			var buf bytes.Buffer
-			err := executeBuildBuffered(cmd, args, o, &buf)
+			err := plan.wait(ctx, o)
+			if err == nil {
+				sem <- struct{}{}
+				n := mem.acquire(build.EstimateMemory(o))
+				summary.start(i)
+				var libs build.CopyStats
+				libs, err = executeBuildBuffered(cmd, args, o, &buf)
+				summary.copied(i, libs)
+				summary.finish(ctx, i, err)
+				mem.release(n)
+				<-sem
+			}
 			results <- result{
 				target: fmt.Sprintf("%s/%s", o.GOOS, o.GOARCH),
 				output: buf.String(),
@@ -126,71 +355,141 @@ func runParallel(cmd *cobra.Command, args []string, opts []*build.Options) error
 		}
 	}
 
+	if ctx.Err() != nil {
+		return summary, ctx.Err()
+	}
 	if len(errs) == 0 {
 		ui.Success("All %d targets built", len(opts))
-		return nil
+		return summary, nil
 	}
 	if len(errs) == 1 {
-		return errs[0]
+		return summary, errs[0]
 	}
-	return fmt.Errorf("%d targets failed", len(errs))
+	return summary, fmt.Errorf("%d targets failed", len(errs))
 }
 
-func executeBuild(cmd *cobra.Command, args []string, opts *build.Options, idx, total int) error {
+// executeBuild builds one target and returns the libraries it copied.
+func executeBuild(cmd *cobra.Command, args []string, opts *build.Options, idx, total int) (libs build.CopyStats, err error) {
 	opts.Normalize()
 	if err := opts.Validate(); err != nil {
-		return err
+		return libs, err
 	}
+	ctx, span := startTarget(cmd.Context(), opts)
+	defer func() { telemetry.End(span, err) }()
+	emitTargetStart(opts)
 
-	zigPath, err := zig.Ensure(cmd.Context(), opts.ZigVersion)
+	zigPath, err := ensureZig(ctx, opts)
 	if err != nil {
-		return fmt.Errorf("zig: %w", err)
+		return libs, err
 	}
 
 	ui.Target(idx, total, opts.GOOS, opts.GOARCH)
-	if opts.Verbose {
+	if opts.Verbose && zigPath != "" {
 		ui.Label("zig", zigPath)
 	}
 
-	return build.New(zigPath, opts).Run(cmd.Context(), args)
+	b := build.New(zigPath, opts)
+	err = b.Run(ctx, args)
+	return b.CopiedLibs(), err
 }
 
-func executeBuildBuffered(cmd *cobra.Command, args []string, opts *build.Options, buf *bytes.Buffer) error {
+func executeBuildBuffered(cmd *cobra.Command, args []string, opts *build.Options, buf *bytes.Buffer) (libs build.CopyStats, err error) {
 	opts.Normalize()
 	if err := opts.Validate(); err != nil {
-		return err
+		return libs, err
 	}
+	ctx, span := startTarget(cmd.Context(), opts)
+	defer func() { telemetry.End(span, err) }()
+	emitTargetStart(opts)
 
-	zigPath, err := zig.Ensure(cmd.Context(), opts.ZigVersion)
+	zigPath, err := ensureZig(ctx, opts)
 	if err != nil {
-		return fmt.Errorf("zig: %w", err)
+		return libs, err
 	}
 
-	return build.NewWithOutput(zigPath, opts, buf, buf).Run(cmd.Context(), args)
+	b := build.NewWithOutput(zigPath, opts, buf, buf)
+	err = b.Run(ctx, args)
+	return b.CopiedLibs(), err
+}
+
+// ensureZig returns the zig compiler of a target, or "" for targets built
+// without cgo, which need none.
+func ensureZig(ctx context.Context, opts *build.Options) (string, error) {
+	if !opts.HasCgo() {
+		return "", nil
+	}
+	path, err := zig.Ensure(ctx, opts.ZigVersion)
+	if err != nil {
+		return "", fmt.Errorf("zig: %w", err)
+	}
+	return path, nil
+}
+
+// startTarget opens the span covering one target's toolchain and build.
+func startTarget(ctx context.Context, o *build.Options) (context.Context, trace.Span) {
+	return telemetry.Start(ctx, "target",
+		attribute.String("gox.target", targetLabel(o)),
+		attribute.String("gox.goos", o.GOOS),
+		attribute.String("gox.goarch", o.GOARCH))
 }
 
 func loadBuildOptions(cmd *cobra.Command) ([]*build.Options, error) {
-	cfg, err := build.LoadConfig(flags.config)
-	if err != nil && !errors.Is(err, build.ErrConfigNotFound) {
-		return nil, fmt.Errorf("config: %w", err)
+	var cfg *build.Config
+	err := telemetry.Phase(cmd.Context(), "config.load", func(context.Context) error {
+		var err error
+		cfg, err = build.LoadConfig(flags.config)
+		if err != nil && !errors.Is(err, build.ErrConfigNotFound) {
+			return fmt.Errorf("config: %w", err)
+		}
+		if err := useProject(cfg); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cfg != nil && cfg.Default.Parallel != nil && !cmd.Flags().Changed("parallel") {
+		flags.parallel = *cfg.Default.Parallel
+	}
+	if flags.parallel < 0 {
+		return nil, fmt.Errorf("parallel must be 0 (auto) or a number of targets, got %d", flags.parallel)
 	}
 
 	var opts []*build.Options
-	if cfg != nil {
+	if cfg != nil && isAdHocTarget(cmd) {
+		if len(cfg.Targets) > 0 {
+			ui.Warn("--os/--arch given without --target: ignoring %d config target(s)", len(cfg.Targets))
+		}
+		opts = []*build.Options{cfg.DefaultOptions()}
+	} else if cfg != nil {
 		opts, err = cfg.ToOptions(flags.targets)
 		if err != nil {
 			return nil, fmt.Errorf("config: %w", err)
 		}
+		if len(opts) > 1 && (cmd.Flags().Changed("os") || cmd.Flags().Changed("arch")) {
+			ui.Warn("--os/--arch override all %d selected targets", len(opts))
+		}
 	} else {
 		opts = []*build.Options{{}}
 	}
 
 	for _, o := range opts {
 		applyFlagOverrides(cmd, o)
+		if err := o.ResolvePackages(); err != nil {
+			return nil, err
+		}
 	}
 	return opts, nil
 }
 
+// isAdHocTarget reports whether --os/--arch were given without --target,
+// which requests a single build instead of the configured target list.
+func isAdHocTarget(cmd *cobra.Command) bool {
+	changed := cmd.Flags().Changed
+	return (changed("os") || changed("arch")) && !changed("target")
+}
+
 func applyFlagOverrides(cmd *cobra.Command, o *build.Options) {
 	changed := cmd.Flags().Changed
 
@@ -209,9 +508,15 @@ func applyFlagOverrides(cmd *cobra.Command, o *build.Options) {
 	if changed("zig-version") {
 		o.ZigVersion = flags.opts.ZigVersion
 	}
+	if changed("go-version") {
+		o.GoVersion = flags.opts.GoVersion
+	}
 	if changed("linkmode") {
 		o.LinkMode = build.LinkMode(flags.linkMode)
 	}
+	if changed("buildmode") {
+		o.BuildMode = build.BuildMode(flags.buildMode)
+	}
 	if changed("include") {
 		o.IncludeDirs = flags.opts.IncludeDirs
 	}
@@ -221,6 +526,12 @@ func applyFlagOverrides(cmd *cobra.Command, o *build.Options) {
 	if changed("link") {
 		o.Libs = flags.opts.Libs
 	}
+	if changed("lib-copy") {
+		o.LibCopy = build.CopyMode(flags.libCopy)
+	}
+	if changed("lib-exclude") {
+		o.LibExclude = flags.opts.LibExclude
+	}
 	if changed("pkg") {
 		o.Packages = flags.opts.Packages
 	}
@@ -233,6 +544,24 @@ func applyFlagOverrides(cmd *cobra.Command, o *build.Options) {
 	if changed("pack") {
 		o.Pack = flags.opts.Pack
 	}
+	if changed("pack-format") {
+		o.Pack, o.PackFormat = true, build.PackFormat(flags.packFormat)
+	}
+	if changed("deps-report") {
+		o.DepsReport = flags.opts.DepsReport
+	}
+	if changed("pkg-config") {
+		o.PkgConfig = flags.opts.PkgConfig
+	}
+	if changed("checksum") {
+		o.Checksum = flags.opts.Checksum
+	}
+	if changed("isolate-gocache") {
+		o.IsolateCache = flags.opts.IsolateCache
+	}
+	if changed("reproducible") {
+		o.Reproducible = flags.opts.Reproducible
+	}
 	if changed("strip") {
 		o.Strip = flags.opts.Strip
 	}
@@ -241,20 +570,107 @@ func applyFlagOverrides(cmd *cobra.Command, o *build.Options) {
 	}
 }
 
-func preloadPackages(ctx context.Context, opts []*build.Options) error {
-	seen := make(map[string]bool)
-	var pkgs []string
+// filterBuildable drops targets the host cannot produce, warning for each.
+func filterBuildable(opts []*build.Options) ([]*build.Options, error) {
+	out := opts[:0]
+	for _, o := range opts {
+		o.Normalize()
+		if err := o.Buildable(); err != nil {
+			ui.Warn("Skipping %v", err)
+			continue
+		}
+		out = append(out, o)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no buildable targets")
+	}
+	return out, nil
+}
+
+// writeSums writes SHA256SUMS covering every checksummed archive, placed in
+// the deepest directory shared by all of them, and returns its path, or ""
+// when no archive is checksummed.
+func writeSums(opts []*build.Options) (string, error) {
+	var files []string
 	for _, o := range opts {
-		for _, pkg := range o.Packages {
-			if !seen[pkg] {
-				seen[pkg] = true
-				pkgs = append(pkgs, pkg)
+		if o.Pack && o.Checksum {
+			if path := o.ArchivePath(); path != "" {
+				files = append(files, path)
 			}
 		}
 	}
-	if len(pkgs) == 0 {
-		return nil
+	if len(files) == 0 {
+		return "", nil
+	}
+	dir := filepath.Dir(files[0])
+	for _, f := range files[1:] {
+		dir = commonDir(dir, filepath.Dir(f))
+	}
+	dst := filepath.Join(dir, archive.SumsFile)
+	if err := archive.WriteSums(dst, files); err != nil {
+		return "", fmt.Errorf("checksum: %w", err)
+	}
+	ui.Success("Wrote %s (%d archive(s))", dst, len(files))
+	return dst, nil
+}
+
+// writeScoop writes the Scoop manifest of the packed windows archives, placed
+// in the deepest directory shared by them unless [scoop] output is set, and
+// returns its path, or "" when none is written.
+func writeScoop(opts []*build.Options) (string, error) {
+	var dirs []string
+	for _, o := range opts {
+		if o.Pack && o.GOOS == "windows" {
+			if path := o.ArchivePath(); path != "" {
+				dirs = append(dirs, filepath.Dir(path))
+			}
+		}
+	}
+	if len(dirs) == 0 {
+		return "", nil
+	}
+	dir := dirs[0]
+	for _, d := range dirs[1:] {
+		dir = commonDir(dir, d)
+	}
+	path, err := build.WriteScoopManifest(opts, dir)
+	if path != "" && err == nil {
+		ui.Success("Wrote %s", path)
+	}
+	return path, err
+}
+
+// publish runs the plugins' publish hook with the artifacts of every target
+// and the extra files written across them (SHA256SUMS, the Scoop manifest),
+// skipping empty paths.
+func publish(ctx context.Context, opts []*build.Options, extra ...string) error {
+	var files []string
+	for _, o := range opts {
+		for _, f := range artifacts(o) {
+			if !slices.Contains(files, f) {
+				files = append(files, f)
+			}
+		}
+	}
+	for _, f := range extra {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return plugin.Run(ctx, plugin.Event{Event: plugin.Publish, Artifacts: files}, os.Stderr)
+}
+
+// commonDir returns the deepest directory containing both a and b.
+func commonDir(a, b string) string {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	for {
+		if rel, err := filepath.Rel(a, b); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return a
+		}
+		parent := filepath.Dir(a)
+		if parent == a {
+			return a
+		}
+		a = parent
 	}
-	_, err := build.EnsureAll(ctx, pkgs)
-	return err
 }
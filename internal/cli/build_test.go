@@ -1,6 +1,10 @@
 package cli
 
 import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/spf13/cobra"
@@ -95,6 +99,16 @@ func TestApplyFlagOverrides(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:     "buildmode override",
+			flagName: "buildmode",
+			setup:    func(f *buildFlags) { f.buildMode = "c-shared" },
+			check: func(t *testing.T, o *build.Options) {
+				if o.BuildMode != build.BuildCShared {
+					t.Errorf("BuildMode = %q, want c-shared", o.BuildMode)
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -107,6 +121,7 @@ func TestApplyFlagOverrides(t *testing.T) {
 			cmd.Flags().String("prefix", "", "")
 			cmd.Flags().String("zig-version", "", "")
 			cmd.Flags().String("linkmode", "", "")
+			cmd.Flags().String("buildmode", "", "")
 			cmd.Flags().StringSlice("include", nil, "")
 			cmd.Flags().StringSlice("lib", nil, "")
 			cmd.Flags().StringSlice("link", nil, "")
@@ -131,6 +146,8 @@ func TestApplyFlagOverrides(t *testing.T) {
 					cmd.Flags().Set(tt.flagName, "./dist")
 				case "linkmode":
 					cmd.Flags().Set(tt.flagName, "static")
+				case "buildmode":
+					cmd.Flags().Set(tt.flagName, "c-shared")
 				}
 			}
 
@@ -156,6 +173,8 @@ func TestBuildCmd_Flags(t *testing.T) {
 		"config", "target", "os", "arch", "output", "prefix",
 		"zig-version", "linkmode", "include", "lib", "link",
 		"pkg", "flags", "no-rpath", "pack", "strip", "verbose", "parallel",
+		"only-buildable", "lib-exclude", "lib-copy", "deps-report", "go-version", "max-memory",
+		"container", "remote", "checksum", "buildmode", "pkg-config", "reproducible", "pack-format",
 	}
 
 	for _, name := range expectedFlags {
@@ -192,3 +211,252 @@ func TestBuildCmd_ShortFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadBuildOptions_AdHocTarget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gox.toml")
+	content := `
+[default]
+strip = true
+
+[[target]]
+name = "linux-amd64"
+os = "linux"
+arch = "amd64"
+
+[[target]]
+name = "windows-amd64"
+os = "windows"
+arch = "amd64"
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	newCmd := func() *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.SetContext(t.Context())
+		cmd.Flags().StringSlice("target", nil, "")
+		cmd.Flags().String("os", "", "")
+		cmd.Flags().String("arch", "", "")
+		return cmd
+	}
+
+	oldFlags := flags
+	defer func() { flags = oldFlags }()
+
+	t.Run("os without target builds single target", func(t *testing.T) {
+		flags = buildFlags{config: path}
+		flags.opts.GOOS = "darwin"
+		cmd := newCmd()
+		cmd.Flags().Set("os", "darwin")
+
+		opts, err := loadBuildOptions(cmd)
+		if err != nil {
+			t.Fatalf("loadBuildOptions() error = %v", err)
+		}
+		if len(opts) != 1 {
+			t.Fatalf("len(opts) = %d, want 1", len(opts))
+		}
+		if opts[0].GOOS != "darwin" {
+			t.Errorf("GOOS = %q, want darwin", opts[0].GOOS)
+		}
+		if !opts[0].Strip {
+			t.Error("Strip = false, want true (inherited from default)")
+		}
+	})
+
+	t.Run("os with target overrides selected target", func(t *testing.T) {
+		flags = buildFlags{config: path, targets: []string{"linux-amd64"}}
+		flags.opts.GOARCH = "arm64"
+		cmd := newCmd()
+		cmd.Flags().Set("target", "linux-amd64")
+		cmd.Flags().Set("arch", "arm64")
+
+		opts, err := loadBuildOptions(cmd)
+		if err != nil {
+			t.Fatalf("loadBuildOptions() error = %v", err)
+		}
+		if len(opts) != 1 {
+			t.Fatalf("len(opts) = %d, want 1", len(opts))
+		}
+		if opts[0].GOOS != "linux" || opts[0].GOARCH != "arm64" {
+			t.Errorf("target = %s/%s, want linux/arm64", opts[0].GOOS, opts[0].GOARCH)
+		}
+	})
+
+	t.Run("no overrides builds all targets", func(t *testing.T) {
+		flags = buildFlags{config: path}
+		opts, err := loadBuildOptions(newCmd())
+		if err != nil {
+			t.Fatalf("loadBuildOptions() error = %v", err)
+		}
+		if len(opts) != 2 {
+			t.Errorf("len(opts) = %d, want 2", len(opts))
+		}
+	})
+}
+
+func TestParallelArg(t *testing.T) {
+	tests := []struct {
+		args     []string
+		want     int
+		wantArgs []string
+	}{
+		{nil, 1, []string{}},
+		{[]string{"-j"}, 0, []string{}},
+		{[]string{"-j", "./cmd"}, 0, []string{"./cmd"}},
+		{[]string{"-j", "4"}, 4, []string{}},
+		{[]string{"-j=4"}, 4, []string{}},
+		{[]string{"-j", "0"}, 0, []string{}},
+		{[]string{"--parallel", "3", "./cmd"}, 3, []string{"./cmd"}},
+		{[]string{"./cmd", "-j", "2"}, 2, []string{"./cmd"}},
+		{[]string{"-j", "./cmd", "--", "8"}, 0, []string{"./cmd", "8"}},
+		{[]string{"-j", "+4"}, 0, []string{"+4"}},
+		{[]string{"-j=0", "./x", "7"}, 0, []string{"./x", "7"}},
+		{[]string{"-j", "./x", "7"}, 0, []string{"./x", "7"}},
+		{[]string{"7", "-j", "7"}, 7, []string{"7"}},
+		{[]string{"-o", "5", "-j", "2", "5"}, 2, []string{"5"}},
+		{[]string{"-vo", "5", "-j", "./x", "5"}, 0, []string{"./x", "5"}},
+	}
+	oldFlags := flags
+	defer func() { flags = oldFlags }()
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			flags = buildFlags{}
+			cmd := &cobra.Command{Use: "build"}
+			cmd.Flags().IntVarP(&flags.parallel, "parallel", "j", 1, "")
+			cmd.Flags().Lookup("parallel").NoOptDefVal = "0"
+			cmd.Flags().StringP("output", "o", "", "")
+			cmd.Flags().BoolP("verbose", "v", false, "")
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatalf("ParseFlags(%q) error = %v", tt.args, err)
+			}
+			args := parallelArg(cmd, tt.args, cmd.Flags().Args())
+			if flags.parallel != tt.want {
+				t.Errorf("parallel = %d, want %d", flags.parallel, tt.want)
+			}
+			if !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("args = %q, want %q", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestLoadBuildOptions_Parallel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gox.toml")
+	content := `
+[default]
+parallel = 3
+
+[[target]]
+name = "linux-amd64"
+os = "linux"
+arch = "amd64"
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	oldFlags := flags
+	defer func() { flags = oldFlags }()
+
+	tests := []struct {
+		name    string
+		flag    string
+		want    int
+		wantErr bool
+	}{
+		{"config", "", 3, false},
+		{"flag overrides config", "2", 2, false},
+		{"auto", "0", 0, false},
+		{"negative", "-1", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags = buildFlags{config: path}
+			cmd := &cobra.Command{}
+			cmd.SetContext(t.Context())
+			cmd.Flags().IntVarP(&flags.parallel, "parallel", "j", 1, "")
+			if tt.flag != "" {
+				cmd.Flags().Set("parallel", tt.flag)
+			}
+			_, err := loadBuildOptions(cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadBuildOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && flags.parallel != tt.want {
+				t.Errorf("parallel = %d, want %d", flags.parallel, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterBuildable(t *testing.T) {
+	t.Run("drops unbuildable targets", func(t *testing.T) {
+		opts := []*build.Options{
+			{GOOS: "linux", GOARCH: "amd64"},
+			{GOOS: "ios", GOARCH: "arm64"},
+			{GOOS: "windows", GOARCH: "amd64"},
+		}
+		got, err := filterBuildable(opts)
+		if err != nil {
+			t.Fatalf("filterBuildable() error = %v", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("len = %d, want 2", len(got))
+		}
+		if got[1].GOOS != "windows" {
+			t.Errorf("got[1].GOOS = %q, want windows", got[1].GOOS)
+		}
+	})
+
+	t.Run("errors when nothing buildable", func(t *testing.T) {
+		_, err := filterBuildable([]*build.Options{{GOOS: "ios", GOARCH: "arm64"}})
+		if err == nil {
+			t.Error("filterBuildable() should fail when no target is buildable")
+		}
+	})
+}
+
+func TestCommonDir(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"dist/linux", "dist/linux", "dist/linux"},
+		{"dist/linux", "dist/windows", "dist"},
+		{"dist", "dist/windows", "dist"},
+		{"dist/a/b", "out/c", "."},
+	}
+	for _, tt := range tests {
+		if got := commonDir(tt.a, tt.b); got != filepath.FromSlash(tt.want) {
+			t.Errorf("commonDir(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestWriteSums(t *testing.T) {
+	dir := t.TempDir()
+	var opts []*build.Options
+	for _, goos := range []string{"linux", "windows"} {
+		o := &build.Options{GOOS: goos, GOARCH: "amd64", Prefix: filepath.Join(dir, goos, "app"), Pack: true, Checksum: true}
+		if err := os.MkdirAll(filepath.Dir(o.ArchivePath()), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(o.ArchivePath(), []byte(goos), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		opts = append(opts, o)
+	}
+	opts = append(opts, &build.Options{GOOS: "darwin", GOARCH: "amd64", Output: filepath.Join(dir, "x"), Pack: true})
+
+	if _, err := writeSums(opts); err != nil {
+		t.Fatalf("writeSums() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "SHA256SUMS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(data), "\n"); got != 2 {
+		t.Errorf("SHA256SUMS has %d lines, want 2:\n%s", got, data)
+	}
+}
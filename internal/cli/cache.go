@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/workspace"
+)
+
+var (
+	cacheCmd = &cobra.Command{
+		Use:   "cache",
+		Short: "Manage gox caches and temporary files",
+	}
+
+	cacheTmpCleanCmd = &cobra.Command{
+		Use:   "tmp-clean",
+		Short: "Remove temp workspaces left by crashed runs",
+		Long: `Remove run workspaces left behind by gox processes that no longer exist.
+
+Every gox invocation keeps its temporary files (downloads, run binaries,
+scripts) in one workspace under the system temp directory and removes it
+on exit. A killed or crashed run cannot clean up; this command lists and
+removes those leftovers. Workspaces of running processes are kept.`,
+		Args: cobra.NoArgs,
+		RunE: runCacheTmpClean,
+	}
+)
+
+func init() {
+	cacheCmd.AddCommand(cacheTmpCleanCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+func runCacheTmpClean(_ *cobra.Command, _ []string) error {
+	removed, err := workspace.Clean()
+	if len(removed) > 0 {
+		tbl := ui.NewTable("WORKSPACE", "PID", "SIZE", "AGE")
+		var total int64
+		for _, l := range removed {
+			tbl.AddCells(l.Path, l.PID, ui.Bytes(l.Size), time.Since(l.ModTime).Truncate(time.Second))
+			total += l.Size
+		}
+		tbl.Render()
+		fmt.Fprintln(ui.Stderr)
+		ui.Success("Removed %d workspaces (%s)", len(removed), ui.FormatSize(total))
+	}
+	if err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		ui.Info("No leftover workspaces")
+	}
+	ui.Label("path", workspace.Root())
+	return nil
+}
@@ -0,0 +1,15 @@
+package cli
+
+import "testing"
+
+func TestCacheCmd(t *testing.T) {
+	found := false
+	for _, cmd := range cacheCmd.Commands() {
+		if cmd.Name() == "tmp-clean" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("missing 'cache tmp-clean' subcommand")
+	}
+}
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/archive"
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/cache"
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/workspace"
+	"github.com/qntx/gox/internal/zig"
+)
+
+// cleanCategory is a kind of cached state gox clean removes.
+type cleanCategory struct {
+	name  string
+	paths func() ([]string, error)
+}
+
+var cleanCategories = []cleanCategory{
+	{"zig", func() ([]string, error) {
+		return []string{zig.Path("")}, nil
+	}},
+	{"zig-cache", func() ([]string, error) {
+		return []string{build.ZigCacheDir()}, nil
+	}},
+	{"pkg", func() ([]string, error) {
+		return []string{cache.Dir("pkg")}, nil
+	}},
+	{"gocache", func() ([]string, error) {
+		return []string{build.GoCacheRoot(), cache.Dir("container")}, nil
+	}},
+	{"tmp", func() ([]string, error) {
+		leftovers, err := workspace.Leftovers()
+		paths := []string{archive.PartialDir()}
+		for _, l := range leftovers {
+			paths = append(paths, l.Path)
+		}
+		return paths, err
+	}},
+}
+
+var (
+	cleanDryRun bool
+	cleanCmd    = &cobra.Command{
+		Use:   "clean [zig|zig-cache|pkg|gocache|tmp]...",
+		Short: "Remove cached Zig installs, packages, build caches and temp files",
+		Long: `Clean removes gox's cached state, all categories unless some are named:
+
+  zig        Zig installations
+  zig-cache  Zig's compilation cache (libc, compiler-rt, C objects)
+  pkg        downloaded packages
+  gocache    Go build and module caches of gox builds
+  tmp        partial downloads and workspaces of crashed runs
+
+Use --dry-run to see the space each category would reclaim. Workspaces of
+running gox processes are kept.`,
+		Example: `  gox clean --dry-run
+  gox clean pkg tmp`,
+		ValidArgs:         cleanNames(),
+		Args:              cobra.OnlyValidArgs,
+		PersistentPreRunE: useConfigCache,
+		RunE:              runClean,
+	}
+)
+
+func init() {
+	cleanCmd.Flags().BoolVarP(&cleanDryRun, "dry-run", "n", false, "show reclaimable space without removing anything")
+	rootCmd.AddCommand(cleanCmd)
+}
+
+func cleanNames() []string {
+	names := make([]string, len(cleanCategories))
+	for i, c := range cleanCategories {
+		names[i] = c.name
+	}
+	return names
+}
+
+func runClean(_ *cobra.Command, args []string) error {
+	tbl := ui.NewTable("CATEGORY", "SIZE", "PATH")
+	var (
+		total int64
+		errs  []error
+	)
+	for _, c := range cleanCategories {
+		if len(args) > 0 && !slices.Contains(args, c.name) {
+			continue
+		}
+		paths, err := c.paths()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+		for _, p := range paths {
+			size, ok := diskUsage(p)
+			if !ok {
+				continue
+			}
+			if !cleanDryRun {
+				if err := removeAllForce(p); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+					continue
+				}
+			}
+			tbl.AddCells(c.name, ui.Bytes(size), p)
+			total += size
+		}
+	}
+
+	switch {
+	case total == 0 && len(errs) == 0:
+		ui.Info("Nothing to clean")
+	case cleanDryRun:
+		tbl.Render()
+		fmt.Fprintln(ui.Stderr)
+		ui.Info("Would reclaim %s", ui.FormatSize(total))
+	default:
+		tbl.Render()
+		fmt.Fprintln(ui.Stderr)
+		ui.Success("Reclaimed %s", ui.FormatSize(total))
+	}
+	return errors.Join(errs...)
+}
+
+// diskUsage returns the size of the files under path, and false when path
+// does not exist.
+func diskUsage(path string) (int64, bool) {
+	if _, err := os.Lstat(path); err != nil {
+		return 0, false
+	}
+	var size int64
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size, true
+}
+
+// removeAllForce removes path like os.RemoveAll, first making directories
+// writable when needed: the Go module cache is read-only.
+func removeAllForce(path string) error {
+	if err := os.RemoveAll(path); err == nil {
+		return nil
+	}
+	_ = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			_ = os.Chmod(p, 0o755)
+		}
+		return nil
+	})
+	return os.RemoveAll(path)
+}
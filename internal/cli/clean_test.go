@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/qntx/gox/internal/cache"
+)
+
+func TestRunClean(t *testing.T) {
+	if err := cache.Use(cache.ScopeProject, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cache.Use(cache.ScopeUser, ""); cleanDryRun = false })
+
+	write := func(elem ...string) string {
+		path := cache.Dir(elem...)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	zigFile := write("zig", "0.14.1", "zig")
+	pkgFile := write("pkg", "url-1234-lib", "lib", "libfoo.a")
+	modDir := filepath.Dir(write("container", "gomod", "example.com", "m@v1", "go.mod"))
+	// The module cache is read-only, like the one go writes.
+	if err := os.Chmod(modDir, 0o555); err != nil {
+		t.Fatal(err)
+	}
+
+	cleanDryRun = true
+	if err := runClean(cleanCmd, nil); err != nil {
+		t.Fatalf("runClean(--dry-run) error = %v", err)
+	}
+	for _, p := range []string{zigFile, pkgFile} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("dry run removed %s", p)
+		}
+	}
+
+	cleanDryRun = false
+	if err := runClean(cleanCmd, []string{"pkg", "gocache"}); err != nil {
+		t.Fatalf("runClean(pkg, gocache) error = %v", err)
+	}
+	if _, err := os.Stat(pkgFile); !os.IsNotExist(err) {
+		t.Errorf("package not removed: %v", err)
+	}
+	if _, err := os.Stat(cache.Dir("container")); !os.IsNotExist(err) {
+		t.Errorf("read-only module cache not removed: %v", err)
+	}
+	if _, err := os.Stat(zigFile); err != nil {
+		t.Errorf("zig removed although not selected: %v", err)
+	}
+}
+
+func TestDiskUsage(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a"), make([]byte, 100), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "b"), make([]byte, 23), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" {
+		if err := os.Symlink("a", filepath.Join(dir, "link")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if size, ok := diskUsage(dir); !ok || size != 123 {
+		t.Errorf("diskUsage() = %d, %v; want 123, true", size, ok)
+	}
+	if _, ok := diskUsage(filepath.Join(dir, "missing")); ok {
+		t.Error("diskUsage() of a missing path reported ok")
+	}
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/zig"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish|powershell",
+	Short: "Generate shell completion scripts",
+	Long: `Completion prints a completion script for the given shell. Besides
+commands and flags it completes --target with the targets in gox.toml,
+package names for 'gox pkg clean' and 'gox pkg info', and installed Zig
+versions for 'gox zig clean'.
+
+  bash        source <(gox completion bash)
+  zsh         gox completion zsh > "${fpath[1]}/_gox"
+  fish        gox completion fish > ~/.config/fish/completions/gox.fish
+  powershell  gox completion powershell | Out-String | Invoke-Expression`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE:                  runCompletion,
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+func runCompletion(_ *cobra.Command, args []string) error {
+	switch args[0] {
+	case "bash":
+		return rootCmd.GenBashCompletionV2(ui.Stdout, true)
+	case "zsh":
+		return rootCmd.GenZshCompletion(ui.Stdout)
+	case "fish":
+		return rootCmd.GenFishCompletion(ui.Stdout, true)
+	case "powershell":
+		return rootCmd.GenPowerShellCompletionWithDesc(ui.Stdout)
+	}
+	return fmt.Errorf("unsupported shell: %s", args[0])
+}
+
+// completeTargets offers the target names of the config selected by the
+// command's --config flag, or the nearest gox.toml.
+func completeTargets(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	path, _ := cmd.Flags().GetString("config")
+	cfg, err := build.LoadConfig(path)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	// --target is a list on some commands: complete the last element.
+	done, prefix := "", toComplete
+	if i := strings.LastIndexByte(toComplete, ','); i >= 0 {
+		done, prefix = toComplete[:i+1], toComplete[i+1:]
+	}
+	var out []string
+	for _, name := range cfg.TargetNames() {
+		if strings.HasPrefix(name, prefix) {
+			out = append(out, done+name)
+		}
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeCachedPackages offers cached package names as the first argument.
+func completeCachedPackages(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	useCompletionCache()
+	pkgs, err := build.ListCached()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names := make([]string, len(pkgs))
+	for i, p := range pkgs {
+		names[i] = p.Name
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeZigVersions offers installed Zig versions as the first argument.
+func completeZigVersions(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	useCompletionCache()
+	versions, err := zig.Installed()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return versions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// useCompletionCache applies the nearest config's cache-scope, since
+// completions run without the pkg and zig pre-run hooks. Errors leave the
+// default cache in place.
+func useCompletionCache() {
+	if cfg, err := build.LoadConfig(""); err == nil {
+		_ = cfg.UseCache()
+	}
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/ui"
+)
+
+func TestCompleteTargets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gox.toml")
+	config := `
+[[target]]
+name = "linux-amd64"
+os = "linux"
+arch = "amd64"
+
+[[target.variant]]
+name = "server"
+
+[[target]]
+name = "windows-amd64"
+os = "windows"
+arch = "amd64"
+`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := &cobra.Command{}
+	cmd.Flags().String("config", path, "")
+
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", []string{"linux-amd64", "linux-amd64-server", "windows-amd64"}},
+		{"lin", []string{"linux-amd64", "linux-amd64-server"}},
+		{"linux-amd64,win", []string{"linux-amd64,windows-amd64"}},
+		{"darwin", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, dir := completeTargets(cmd, nil, tt.in)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("completeTargets(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+			if dir != cobra.ShellCompDirectiveNoFileComp {
+				t.Errorf("directive = %v", dir)
+			}
+		})
+	}
+}
+
+func TestRunCompletion(t *testing.T) {
+	var buf bytes.Buffer
+	old := ui.Stdout
+	ui.Stdout = &buf
+	t.Cleanup(func() { ui.Stdout = old })
+
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		buf.Reset()
+		if err := runCompletion(completionCmd, []string{shell}); err != nil {
+			t.Fatalf("runCompletion(%s) error = %v", shell, err)
+		}
+		if !strings.Contains(buf.String(), "gox") {
+			t.Errorf("runCompletion(%s) script does not mention gox", shell)
+		}
+	}
+}
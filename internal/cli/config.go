@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/ui"
+)
+
+var (
+	schemaOutput string
+
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Inspect and validate gox.toml",
+	}
+
+	configSchemaCmd = &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of gox.toml",
+		Long: `Schema prints the JSON Schema of gox.toml, generated from the config
+structs of this gox version. Point an editor at it for completion and
+validation, e.g. with a "#:schema" comment on the first line of gox.toml for
+editors using taplo (Even Better TOML), or via the published copy:
+
+  ` + build.SchemaURL,
+		Args: cobra.NoArgs,
+		RunE: runConfigSchema,
+	}
+
+	configValidateCmd = &cobra.Command{
+		Use:   "validate [file]",
+		Short: "Check gox.toml against the schema and settings",
+		Long: `Validate checks a config (default: the nearest gox.toml) for unknown keys,
+wrong value types and invalid enum values, then resolves extends and checks
+every target and setting the way a build would. It exits non-zero when a
+problem is found, so it can gate CI.`,
+		Args:         cobra.MaximumNArgs(1),
+		RunE:         runConfigValidate,
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	configSchemaCmd.Flags().StringVarP(&schemaOutput, "output", "o", "", "write the schema to a file instead of stdout")
+
+	configCmd.AddCommand(configSchemaCmd, configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigSchema(_ *cobra.Command, _ []string) error {
+	data, err := build.Schema()
+	if err != nil {
+		return err
+	}
+	if schemaOutput != "" {
+		return os.WriteFile(schemaOutput, data, 0o644)
+	}
+	_, err = ui.Stdout.Write(data)
+	return err
+}
+
+func runConfigValidate(_ *cobra.Command, args []string) error {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	}
+	path, problems, err := build.ValidateConfig(path)
+	if errors.Is(err, build.ErrConfigNotFound) {
+		return fmt.Errorf("no %s found", build.ConfigFile)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(problems) == 0 {
+		// Settings applied per process (cache, mirrors, retry, tools).
+		cfg, err := build.LoadConfig(path)
+		if err == nil {
+			err = useProject(cfg)
+		}
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	for _, p := range problems {
+		ui.Error("%s", p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problem(s) in %s", len(problems), path)
+	}
+	ui.Success("%s is valid", path)
+	return nil
+}
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/qntx/gox/internal/cache"
+	"github.com/qntx/gox/internal/hosttool"
+	"github.com/qntx/gox/internal/ui"
+)
+
+const (
+	defaultContainerImage = "golang:latest"
+	containerSrc          = "/src"
+	containerCache        = "/cache/gox"
+	containerGox          = "/usr/local/bin/gox"
+)
+
+// container describes a containerized gox invocation.
+type container struct {
+	runtime string // docker or podman
+	image   string
+	root    string // host module root, mounted at containerSrc
+	wd      string // host working directory inside root
+	cache   string // host gox cache root
+	exe     string // host gox binary to mount, or "" to use the image's gox
+	user    string // uid:gid to run as, or ""
+}
+
+// runInContainer re-runs the current gox command inside image, mounting the
+// module and the gox cache so artifacts and downloads land on the host.
+func runInContainer(ctx context.Context, image string) error {
+	rt, err := containerRuntime()
+	if err != nil {
+		return err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	c := &container{
+		runtime: rt,
+		image:   image,
+		root:    moduleRoot(wd),
+		wd:      wd,
+		cache:   cache.Root(),
+	}
+	// The host binary only runs in the container when both are linux on
+	// the same architecture; otherwise the image must provide gox.
+	if runtime.GOOS == "linux" {
+		if exe, err := os.Executable(); err == nil {
+			c.exe = exe
+		}
+		c.user = fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())
+	}
+	if err := os.MkdirAll(c.cache, 0o755); err != nil {
+		return err
+	}
+
+	args := c.args(stripFlag(os.Args[1:], "container"))
+	ui.Info("Running in %s container %s", rt, image)
+
+	cmd := hosttool.Command(ctx, rt, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = ui.Stdout
+	cmd.Stderr = ui.Stderr
+	// Let the runtime proxy the signal and stop the container instead of
+	// killing the client and leaking it.
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = 10 * time.Second
+	return cmd.Run()
+}
+
+// args returns the runtime arguments for running gox with goxArgs.
+func (c *container) args(goxArgs []string) []string {
+	rel, err := filepath.Rel(c.root, c.wd)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = "."
+	}
+	workdir := path.Join(containerSrc, filepath.ToSlash(rel))
+
+	// A project-scoped cache already lives under the module mount.
+	cacheDir := containerCache
+	if r, err := filepath.Rel(c.root, c.cache); err == nil && !strings.HasPrefix(r, "..") {
+		cacheDir = path.Join(containerSrc, filepath.ToSlash(r))
+	}
+
+	args := []string{"run", "--rm", "-i",
+		"-v", c.root + ":" + containerSrc,
+		"-w", workdir,
+		"-e", "HOME=/tmp",
+		"-e", "XDG_CACHE_HOME=" + path.Dir(containerCache),
+		"-e", "GOMODCACHE=" + path.Join(cacheDir, "container", "gomod"),
+		"-e", "GOCACHE=" + path.Join(cacheDir, "container", "go-build"),
+	}
+	if cacheDir == containerCache {
+		args = append(args, "-v", c.cache+":"+containerCache)
+	}
+	if c.user != "" {
+		args = append(args, "--user", c.user)
+	}
+	gox := "gox"
+	if c.exe != "" {
+		args = append(args, "-v", c.exe+":"+containerGox+":ro")
+		gox = containerGox
+	}
+	args = append(args, c.image, gox)
+	return append(args, goxArgs...)
+}
+
+// containerRuntime returns the first available container runtime.
+func containerRuntime() (string, error) {
+	for _, rt := range []string{"docker", "podman"} {
+		if _, err := hosttool.Path(rt); err == nil {
+			return rt, nil
+		}
+	}
+	return "", errors.New("--container requires docker or podman in PATH or [tools]")
+}
+
+// stripFlag removes --name and --name=value from args, e.g. so a gox re-run
+// inside a container or daemon does not recurse.
+func stripFlag(args []string, name string) []string {
+	out := make([]string, 0, len(args))
+	for i, a := range args {
+		if a == "--" {
+			return append(out, args[i:]...)
+		}
+		if a == "--"+name || strings.HasPrefix(a, "--"+name+"=") {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// moduleRoot returns the nearest directory at or above dir containing
+// go.mod, or dir itself when none is found.
+func moduleRoot(dir string) string {
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir
+		}
+		d = parent
+	}
+}
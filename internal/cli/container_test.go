@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestStripFlag(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"build", "--container", "-t", "linux"}, []string{"build", "-t", "linux"}},
+		{[]string{"build", "--container=golang:1.24", "."}, []string{"build", "."}},
+		{[]string{"build", "--", "--container"}, []string{"build", "--", "--container"}},
+	}
+	for _, tt := range tests {
+		if got := stripFlag(tt.args, "container"); !slices.Equal(got, tt.want) {
+			t.Errorf("stripFlag(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestContainer_Args(t *testing.T) {
+	c := &container{
+		runtime: "docker",
+		image:   "golang:1.24",
+		root:    "/home/u/proj",
+		wd:      "/home/u/proj/cmd/app",
+		cache:   "/home/u/.cache/gox",
+		exe:     "/usr/bin/gox",
+		user:    "1000:1000",
+	}
+	args := c.args([]string{"build", "-t", "linux"})
+
+	for _, want := range [][]string{
+		{"-v", "/home/u/proj:/src"},
+		{"-w", "/src/cmd/app"},
+		{"-v", "/home/u/.cache/gox:/cache/gox"},
+		{"-v", "/usr/bin/gox:/usr/local/bin/gox:ro"},
+		{"--user", "1000:1000"},
+		{"golang:1.24", "/usr/local/bin/gox", "build", "-t", "linux"},
+	} {
+		if !containsSeq(args, want) {
+			t.Errorf("args = %v, missing %v", args, want)
+		}
+	}
+
+	t.Run("project cache", func(t *testing.T) {
+		c := *c
+		c.cache = "/home/u/proj/.gox"
+		c.exe = ""
+		args := c.args(nil)
+		if containsSeq(args, []string{"-v", "/home/u/proj/.gox:/cache/gox"}) {
+			t.Errorf("args = %v, project cache should not be mounted separately", args)
+		}
+		if !containsSeq(args, []string{"-e", "GOCACHE=/src/.gox/container/go-build"}) {
+			t.Errorf("args = %v, want GOCACHE under /src/.gox", args)
+		}
+		if args[len(args)-1] != "gox" {
+			t.Errorf("command = %q, want gox from image", args[len(args)-1])
+		}
+	})
+}
+
+func TestModuleRoot(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := moduleRoot(sub); got != root {
+		t.Errorf("moduleRoot() = %q, want %q", got, root)
+	}
+}
+
+func containsSeq(s, seq []string) bool {
+	for i := 0; i+len(seq) <= len(s); i++ {
+		if slices.Equal(s[i:i+len(seq)], seq) {
+			return true
+		}
+	}
+	return false
+}
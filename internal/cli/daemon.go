@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/daemon"
+	"github.com/qntx/gox/internal/ui"
+)
+
+var (
+	daemonAddr string
+	daemonRoot string
+
+	daemonCmd = &cobra.Command{
+		Use:   "daemon",
+		Short: "Run a local build server",
+		Long: `Daemon serves a local HTTP API so IDE plugins and repeated invocations
+share one build queue. Each build runs in a fresh gox process.
+
+Endpoints:
+  POST /v1/build   {"dir": "...", "args": [...]}  run gox build, stream NDJSON output
+  POST /v1/fetch   {"zig": "...", "packages": [...]}  fetch the toolchain and packages
+  GET  /v1/status  queue and cache state
+
+Builds from all clients run one at a time, only in directories below --root.
+Clients present a bearer token: ` + daemon.TokenEnv + ` when set, else one the
+daemon writes to a file only the user can read at startup. Requests from web
+pages (with an Origin header) and POSTs of other content than JSON are
+refused. Use gox build --remote to send a build to the daemon.`,
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: useConfigCache,
+		RunE:              runDaemon,
+	}
+
+	daemonStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Show daemon queue and cache state",
+		Args:  cobra.NoArgs,
+		RunE:  runDaemonStatus,
+	}
+)
+
+func init() {
+	daemonCmd.PersistentFlags().StringVar(&daemonAddr, "addr", daemon.DefaultAddr, "listen/dial address")
+	daemonCmd.Flags().StringVar(&daemonRoot, "root", "", "directory builds must run in (default: current directory)")
+	daemonCmd.AddCommand(daemonStatusCmd)
+	rootCmd.AddCommand(daemonCmd)
+}
+
+func runDaemon(cmd *cobra.Command, _ []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	root, err := filepath.Abs(daemonRoot)
+	if err != nil {
+		return err
+	}
+	token, err := daemon.NewToken()
+	if err != nil {
+		return fmt.Errorf("daemon token: %w", err)
+	}
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	ui.Info("gox daemon listening on %s, building in %s", daemonAddr, root)
+	return daemon.NewServer(exe, token, root).ListenAndServe(ctx, daemonAddr)
+}
+
+func runDaemonStatus(cmd *cobra.Command, _ []string) error {
+	st, err := daemon.NewClient(daemonAddr).Status(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	ui.Header("Daemon")
+	ui.Label("pid", fmt.Sprint(st.PID))
+	ui.Label("uptime", ui.FormatDuration(time.Since(st.Started)))
+	ui.Label("built", fmt.Sprint(st.Built))
+	if len(st.Zig) > 0 {
+		ui.Label("zig", strings.Join(st.Zig, ", "))
+	}
+	if len(st.Jobs) == 0 {
+		ui.Info("Queue is empty")
+		return nil
+	}
+
+	t := ui.NewTable("ID", "State", "Dir", "Args")
+	for _, j := range st.Jobs {
+		t.AddRow(fmt.Sprint(j.ID), j.State, j.Dir, strings.Join(j.Args, " "))
+	}
+	t.Render()
+	return nil
+}
+
+// runRemote sends the current build to the daemon at addr.
+func runRemote(cmd *cobra.Command, addr string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	args := stripFlag(os.Args[1:], "remote")
+	if len(args) > 0 && args[0] == cmd.Name() {
+		args = args[1:]
+	}
+	req := daemon.BuildRequest{Dir: wd, Args: args}
+	return daemon.NewClient(addr).Build(cmd.Context(), req, ui.Stderr)
+}
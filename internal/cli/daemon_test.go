@@ -0,0 +1,12 @@
+package cli
+
+import "testing"
+
+func TestDaemonCmd_Flags(t *testing.T) {
+	if daemonCmd.PersistentFlags().Lookup("addr") == nil {
+		t.Error("missing flag: addr")
+	}
+	if _, _, err := daemonCmd.Find([]string{"status"}); err != nil {
+		t.Errorf("missing subcommand status: %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/worker"
+	"github.com/qntx/gox/internal/workspace"
+)
+
+// runDistributed ships the module to the workers and builds each target on
+// the next free one, unpacking what it wrote into the module. A target
+// whose worker becomes unreachable is retried on the remaining workers.
+func runDistributed(cmd *cobra.Command, opts []*build.Options, workers []string) error {
+	// Cancel remote builds on Ctrl-C so the summary can report what completed.
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	root := moduleRoot(wd)
+
+	files, err := worker.SourceFiles(ctx, root)
+	if err != nil {
+		return fmt.Errorf("sources: %w", err)
+	}
+	tmp, err := workspace.MkdirTemp("dist-*")
+	if err != nil {
+		return fmt.Errorf("temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	source, size, err := packSources(root, files, tmp)
+	if err != nil {
+		return fmt.Errorf("sources: %w", err)
+	}
+	ui.Header(fmt.Sprintf("Building %d target(s) on %d worker(s)", len(opts), len(workers)))
+	ui.Info("Shipping %d file(s), %s, from %s", len(files), ui.FormatSize(size), root)
+
+	base := goxArgs(cmd)
+	token := os.Getenv(worker.TokenEnv)
+	summary := newBuildSummary(opts)
+
+	queue := make(chan int, len(opts))
+	for i := range opts {
+		queue <- i
+	}
+	var (
+		mu      sync.Mutex
+		pending = len(opts)
+		errs    = make([]error, len(opts))
+		wg      sync.WaitGroup
+	)
+	done := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if pending--; pending == 0 {
+			close(queue)
+		}
+	}
+
+	for _, addr := range workers {
+		client := worker.NewClient(addr, token)
+		wg.Go(func() {
+			for i := range queue {
+				o := opts[i]
+				job := worker.Job{Dir: worker.RelDir(root, wd), Args: targetArgs(base, o)}
+				var out bytes.Buffer
+				summary.start(i)
+				paths, err := client.Run(ctx, job, source, &out, root)
+				if errors.Is(err, worker.ErrUnreachable) && ctx.Err() == nil {
+					// Hand the target to another worker and retire this one.
+					ui.Warn("%s: %v", targetLabel(o), err)
+					queue <- i
+					return
+				}
+				summary.finish(ctx, i, err)
+				printRemoteResult(o, addr, out.String(), paths, err)
+				errs[i] = err
+				done()
+			}
+		})
+	}
+	wg.Wait()
+
+	var failed []error
+	for i, o := range opts {
+		err := errs[i]
+		if err == nil && summary.results[i].state != stateBuilt {
+			err = errors.New("no reachable worker left")
+		}
+		if err != nil {
+			failed = append(failed, fmt.Errorf("%s: %w", targetLabel(o), err))
+		}
+	}
+	if ctx.Err() != nil {
+		summary.cancel()
+		summary.render(true)
+		return errInterrupted
+	}
+	switch len(failed) {
+	case 0:
+		ui.Success("All %d targets built", len(opts))
+		return nil
+	case 1:
+		return failed[0]
+	}
+	return fmt.Errorf("%d targets failed: %w", len(failed), errors.Join(failed...))
+}
+
+// packSources writes files under root to a tar.gz in dir.
+func packSources(root string, files []string, dir string) (string, int64, error) {
+	path := dir + string(os.PathSeparator) + "source.tar.gz"
+	f, err := os.Create(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	if err := worker.Pack(f, root, files); err != nil {
+		return "", 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+	return path, info.Size(), f.Close()
+}
+
+// goxArgs returns the arguments of the current command without the ones
+// that only make sense on the coordinator.
+func goxArgs(cmd *cobra.Command) []string {
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == cmd.Name() {
+		args = args[1:]
+	}
+	args = stripValueFlag(args, "workers", "")
+	args = stripValueFlag(args, "target", "t")
+	args = stripValueFlag(args, "parallel", "j")
+	return stripValueFlag(args, "max-memory", "")
+}
+
+// targetArgs selects o's config target for a worker run.
+func targetArgs(base []string, o *build.Options) []string {
+	if o.Target == "" {
+		return base
+	}
+	return append([]string{"--target", o.Target}, base...)
+}
+
+// stripValueFlag removes --name VALUE, --name=VALUE, -s VALUE and -sVALUE
+// from args, stopping at "--".
+func stripValueFlag(args []string, name, short string) []string {
+	out := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--":
+			return append(out, args[i:]...)
+		case a == "--"+name || (short != "" && a == "-"+short):
+			i++
+		case strings.HasPrefix(a, "--"+name+"="):
+		case short != "" && strings.HasPrefix(a, "-"+short) && !strings.HasPrefix(a, "--"):
+		default:
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func printRemoteResult(o *build.Options, addr, output string, paths []string, err error) {
+	if output != "" {
+		fmt.Fprint(ui.Stderr, output)
+	}
+	if err != nil {
+		ui.Error("%s failed on %s", targetLabel(o), addr)
+		return
+	}
+	ui.Success("%s built on %s (%d file(s))", targetLabel(o), addr, len(paths))
+}
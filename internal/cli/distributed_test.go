@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/worker"
+)
+
+func TestStripValueFlag(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"--workers", "a,b", "-s"}, []string{"-s"}},
+		{[]string{"--workers=a", "-t", "linux", "."}, []string{"."}},
+		{[]string{"-tlinux", "--target=win", "--pack"}, []string{"--pack"}},
+		{[]string{".", "--", "-t", "x"}, []string{".", "--", "-t", "x"}},
+	}
+	for _, tt := range tests {
+		got := stripValueFlag(stripValueFlag(tt.args, "workers", ""), "target", "t")
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("stripValueFlag(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestTargetArgs(t *testing.T) {
+	base := []string{"--pack", "./cmd/app"}
+	tests := []struct {
+		name string
+		opts build.Options
+		want []string
+	}{
+		{"config target", build.Options{Target: "linux-arm64"}, []string{"--target", "linux-arm64", "--pack", "./cmd/app"}},
+		{"variant", build.Options{Target: "linux-arm64-musl", Variant: "musl"}, []string{"--target", "linux-arm64-musl", "--pack", "./cmd/app"}},
+		{"ad hoc", build.Options{GOOS: "linux", GOARCH: "arm64"}, base},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := targetArgs(base, &tt.opts); !slices.Equal(got, tt.want) {
+				t.Errorf("targetArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunWorker_RequiresTokenOffLoopback(t *testing.T) {
+	t.Setenv(worker.TokenEnv, "")
+	saved := wkFlags
+	defer func() { wkFlags = saved }()
+	wkFlags.listen = ":" + worker.DefaultPort
+
+	err := runWorker(workerCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), worker.TokenEnv) {
+		t.Errorf("runWorker() error = %v, want %s required", err, worker.TokenEnv)
+	}
+}
+
+func TestLoopback(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"127.0.0.1:7078", true},
+		{"[::1]:7078", true},
+		{"localhost:7078", true},
+		{":7078", false},
+		{"0.0.0.0:7078", false},
+		{"build1:7078", false},
+		{"7078", false},
+	}
+	for _, tt := range tests {
+		if got := loopback(tt.addr); got != tt.want {
+			t.Errorf("loopback(%q) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/cache"
+	"github.com/qntx/gox/internal/hosttool"
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/zig"
+)
+
+// doctorTimeout bounds the network check.
+const doctorTimeout = 10 * time.Second
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the gox environment",
+	Long: `Doctor checks the config, Go installation, CGO, optional host tools (git,
+docker, podman), installed Zig versions and the size of Zig's compilation
+cache, cache directory permissions and access to ziglang.org (or the
+configured mirror), and prints a fix for every problem found. It exits
+non-zero when a check fails.`,
+	Args:         cobra.NoArgs,
+	RunE:         runDoctor,
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// checkResult is the outcome of one doctor check.
+type checkResult struct {
+	status ui.Status
+	detail string
+	fix    string
+}
+
+func checkOK(format string, args ...any) checkResult {
+	return checkResult{status: ui.StatusOK, detail: fmt.Sprintf(format, args...)}
+}
+
+func checkWarn(detail, fix string) checkResult {
+	return checkResult{status: ui.StatusWarn, detail: detail, fix: fix}
+}
+
+func checkFail(detail, fix string) checkResult {
+	return checkResult{status: ui.StatusFailed, detail: detail, fix: fix}
+}
+
+// doctorCheck is one named diagnostic. Checks run in order, so the config
+// check can apply cache-scope, zig-path and mirrors for the later ones.
+type doctorCheck struct {
+	name string
+	run  func(ctx context.Context) checkResult
+}
+
+var doctorChecks = []doctorCheck{
+	{"config", checkConfig},
+	{"go", checkGo},
+	{"cgo", checkCgo},
+	{"tools", checkTools},
+	{"zig", checkZig},
+	{"zig-cache", checkZigCache},
+	{"cache", checkCache},
+	{"network", checkNetwork},
+}
+
+func runDoctor(cmd *cobra.Command, _ []string) error {
+	ui.Header("gox doctor")
+	tbl := ui.NewTable("CHECK", "STATUS", "DETAIL")
+	var fixes []string
+	failed := 0
+	for _, c := range doctorChecks {
+		r := c.run(cmd.Context())
+		tbl.AddStatusRow(r.status, c.name, statusText(r.status), r.detail)
+		if r.fix != "" {
+			fixes = append(fixes, fmt.Sprintf("%s: %s", c.name, r.fix))
+		}
+		if r.status == ui.StatusFailed {
+			failed++
+		}
+	}
+	tbl.Render()
+
+	if len(fixes) > 0 {
+		fmt.Fprintln(ui.Stderr)
+		ui.Info("Suggested fixes:")
+		for _, f := range fixes {
+			fmt.Fprintf(ui.Stderr, "  %s\n", f)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	ui.Success("No problems found")
+	return nil
+}
+
+func statusText(s ui.Status) string {
+	switch s {
+	case ui.StatusOK:
+		return "ok"
+	case ui.StatusWarn:
+		return "warn"
+	case ui.StatusFailed:
+		return "fail"
+	}
+	return "-"
+}
+
+func checkConfig(context.Context) checkResult {
+	cfg, err := build.LoadConfig("")
+	if errors.Is(err, build.ErrConfigNotFound) {
+		return checkOK("no %s, using defaults", build.ConfigFile)
+	}
+	if err != nil {
+		return checkFail(err.Error(), "fix the error above in "+build.ConfigFile)
+	}
+	if err := useProject(cfg); err != nil {
+		return checkFail(err.Error(), "fix the setting above in "+build.ConfigFile)
+	}
+	return checkOK("%s in %s, %d target(s)", build.ConfigFile, cfg.Dir(), len(cfg.Targets))
+}
+
+func checkGo(ctx context.Context) checkResult {
+	v, err := hosttool.Version(ctx, "go")
+	if err != nil {
+		if hosttool.Configured("go") != "" {
+			return checkFail(err.Error(), "fix [tools] go in "+build.ConfigFile)
+		}
+		return checkFail("go not found in PATH", "install Go from https://go.dev/dl and add it to PATH")
+	}
+	return checkOK("%s", strings.TrimPrefix(v, "go version "))
+}
+
+func checkCgo(ctx context.Context) checkResult {
+	out, err := hosttool.Command(ctx, "go", "env", "CGO_ENABLED").Output()
+	if err != nil {
+		return checkFail("cannot run go env", "fix the Go installation first")
+	}
+	if strings.TrimSpace(string(out)) != "1" {
+		return checkWarn("CGO_ENABLED=0 in your environment",
+			"gox builds enable cgo themselves; unset CGO_ENABLED (or go env -u CGO_ENABLED) for plain go commands")
+	}
+	return checkOK("enabled")
+}
+
+// checkTools reports the optional host tools. Only a configured path that
+// does not work is a failure; tools missing from PATH just disable the
+// features that use them.
+func checkTools(ctx context.Context) checkResult {
+	var found, missing []string
+	for _, t := range hosttool.Known {
+		if !t.Optional {
+			continue
+		}
+		if _, err := hosttool.Version(ctx, t.Name); err != nil {
+			if hosttool.Configured(t.Name) != "" {
+				return checkFail(err.Error(), fmt.Sprintf("fix [tools] %s in %s", t.Name, build.ConfigFile))
+			}
+			missing = append(missing, t.Name)
+			continue
+		}
+		found = append(found, t.Name)
+	}
+	detail := "found " + cmp.Or(strings.Join(found, ", "), "none")
+	if len(missing) > 0 {
+		detail += "; not found " + strings.Join(missing, ", ")
+	}
+	return checkOK("%s", detail)
+}
+
+func checkZig(ctx context.Context) checkResult {
+	if zig.System != "" {
+		path, err := zig.Ensure(ctx, "")
+		if err != nil {
+			return checkFail(err.Error(), "point --zig-path or zig-path at a working zig binary")
+		}
+		return checkOK("system zig in %s", path)
+	}
+	versions, err := zig.Installed()
+	if err != nil && !os.IsNotExist(err) {
+		return checkFail(err.Error(), "check permissions of "+zig.Path(""))
+	}
+	if len(versions) == 0 {
+		return checkWarn("none installed", "run 'gox zig update' (or the first build downloads one)")
+	}
+	slices.Sort(versions)
+	return checkOK("%s", strings.Join(versions, ", "))
+}
+
+// checkZigCache reports the size of the Zig compilation cache gox builds
+// use, which grows with every target and Zig version.
+func checkZigCache(context.Context) checkResult {
+	dir := build.ZigCacheDir()
+	for _, key := range []string{"ZIG_GLOBAL_CACHE_DIR", "ZIG_LOCAL_CACHE_DIR"} {
+		if v := os.Getenv(key); v != "" {
+			return checkOK("%s=%s set by the environment", key, v)
+		}
+	}
+	size, ok := diskUsage(dir)
+	if !ok {
+		return checkOK("empty (%s)", dir)
+	}
+	return checkOK("%s in %s (gox clean zig-cache removes it)", ui.FormatSize(size), dir)
+}
+
+func checkCache(context.Context) checkResult {
+	root := cache.Root()
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return checkFail(err.Error(), "make "+root+" writable, or set XDG_CACHE_HOME or cache-scope = \"project\"")
+	}
+	f, err := os.CreateTemp(root, ".doctor-*")
+	if err != nil {
+		return checkFail(err.Error(), "make "+root+" writable, or set XDG_CACHE_HOME or cache-scope = \"project\"")
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return checkOK("%s is writable", root)
+}
+
+func checkNetwork(ctx context.Context) checkResult {
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+	url, err := zig.Reachable(ctx)
+	if err == nil {
+		return checkOK("%s reachable", url)
+	}
+	detail := err.Error()
+	if !strings.Contains(detail, url) {
+		detail = url + ": " + detail
+	}
+	// Only Zig downloads need the network; an installed Zig keeps working.
+	if versions, _ := zig.Installed(); zig.System != "" || len(versions) > 0 {
+		return checkWarn(detail, "new Zig versions cannot be downloaded; set zig-mirror / "+zig.MirrorEnv+" if ziglang.org is blocked")
+	}
+	return checkFail(detail, "check your connection or HTTPS_PROXY, or set zig-mirror / "+zig.MirrorEnv+" if ziglang.org is blocked")
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/zig"
+)
+
+func TestCheckCache(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T) string
+		want  ui.Status
+	}{
+		{"writable", func(t *testing.T) string { return t.TempDir() }, ui.StatusOK},
+		{"blocked by file", func(t *testing.T) string {
+			path := filepath.Join(t.TempDir(), "file")
+			if err := os.WriteFile(path, nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			return path
+		}, ui.StatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CACHE_HOME", tt.setup(t))
+			r := checkCache(t.Context())
+			if r.status != tt.want {
+				t.Errorf("checkCache() = %+v, want status %v", r, tt.want)
+			}
+			if r.status != ui.StatusOK && r.fix == "" {
+				t.Error("failed check has no fix")
+			}
+		})
+	}
+}
+
+func TestCheckZig(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Cleanup(func() { zig.System = "" })
+
+	if r := checkZig(t.Context()); r.status != ui.StatusWarn {
+		t.Errorf("checkZig() without zig = %+v, want warn", r)
+	}
+
+	zig.System = filepath.Join(t.TempDir(), "zig")
+	if r := checkZig(t.Context()); r.status != ui.StatusFailed || r.fix == "" {
+		t.Errorf("checkZig() with missing zig-path = %+v, want fail with fix", r)
+	}
+}
+
+func TestCheckNetwork(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	tests := []struct {
+		name string
+		code int
+		want ui.Status
+	}{
+		{"mirror up", http.StatusOK, ui.StatusOK},
+		{"mirror without index", http.StatusNotFound, ui.StatusOK},
+		{"mirror down", http.StatusBadGateway, ui.StatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.code)
+			}))
+			defer srv.Close()
+			t.Setenv(zig.MirrorEnv, srv.URL)
+
+			if r := checkNetwork(t.Context()); r.status != tt.want {
+				t.Errorf("checkNetwork() = %+v, want status %v", r, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckConfig_None(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if r := checkConfig(t.Context()); r.status != ui.StatusOK {
+		t.Errorf("checkConfig() = %+v, want ok", r)
+	}
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/zig"
+)
+
+// dryRun prints the go invocation each of opts would run for verb, without
+// fetching Zig or packages. Zig is shown where Ensure would install it.
+func dryRun(opts []*build.Options, verb string, pkgs, extra []string) error {
+	for i, o := range opts {
+		o.Normalize()
+		if err := o.Validate(); err != nil {
+			return err
+		}
+		if o.IsUniversal() && verb != "build" {
+			return build.ErrUniversal
+		}
+		// A universal target runs go build per arch, then merges the binaries.
+		for j, s := range o.Slices() {
+			inv, err := build.New(zig.Locate(o.ZigVersion), s).Plan(verb, pkgs, extra)
+			if err != nil {
+				return err
+			}
+			if i > 0 || j > 0 {
+				fmt.Fprintln(ui.Stdout)
+			}
+			label := targetLabel(o)
+			if s != o {
+				label += " (" + s.GOARCH + ", merged by lipo)"
+			}
+			writePlan(ui.Stdout, label, inv)
+		}
+	}
+	return nil
+}
+
+// writePlan renders inv as a shell command: a comment naming the target,
+// one KEY=value line per variable, then the go command.
+func writePlan(w io.Writer, label string, inv *build.Invocation) {
+	fmt.Fprintf(w, "# %s\n", label)
+	for _, kv := range inv.Env {
+		k, v, _ := strings.Cut(kv, "=")
+		fmt.Fprintf(w, "%s=%s \\\n", k, shellQuote(v))
+	}
+	args := make([]string, len(inv.Args))
+	for i, a := range inv.Args {
+		args[i] = shellQuote(a)
+	}
+	fmt.Fprintf(w, "go %s\n", strings.Join(args, " "))
+}
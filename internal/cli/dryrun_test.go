@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/qntx/gox/internal/build"
+)
+
+func TestWritePlan(t *testing.T) {
+	var buf bytes.Buffer
+	writePlan(&buf, "linux-amd64", &build.Invocation{
+		Env:  []string{"GOOS=linux", "CC=/opt/zig/zig cc -target x86_64-linux-gnu"},
+		Args: []string{"build", "-ldflags=-s -w", "-o", "dist/app", "."},
+	})
+	want := `# linux-amd64
+GOOS=linux \
+CC='/opt/zig/zig cc -target x86_64-linux-gnu' \
+go build '-ldflags=-s -w' -o dist/app .
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writePlan() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/zig"
+)
+
+type envFlags struct {
+	config string
+	target string
+	goos   string
+	goarch string
+	json   bool
+	export bool
+}
+
+var (
+	evFlags envFlags
+	envCmd  = &cobra.Command{
+		Use:   "env [KEY...]",
+		Short: "Print the CGO environment of a target",
+		Long: `Env prints the environment gox builds a target with: GOOS, GOARCH,
+CGO_ENABLED, the Zig CC/CXX wrappers and CGO_CFLAGS/CGO_LDFLAGS including
+package paths. Nothing is compiled; Zig and packages are fetched if missing.
+
+With KEY arguments only those values are printed, one per line.`,
+		Example: `  gox env -t windows-amd64
+  eval "$(gox env --export --os linux --arch arm64)"
+  gox env --json -t linux-amd64
+  gox env CC CGO_LDFLAGS`,
+		RunE: runEnv,
+	}
+)
+
+func init() {
+	f := envCmd.Flags()
+
+	f.StringVarP(&evFlags.config, "config", "c", "", "config file path (default: gox.toml)")
+	f.StringVarP(&evFlags.target, "target", "t", "", "target name from config")
+	_ = envCmd.RegisterFlagCompletionFunc("target", completeTargets)
+	f.StringVar(&evFlags.goos, "os", "", "target operating system (GOOS)")
+	f.StringVar(&evFlags.goarch, "arch", "", "target architecture (GOARCH)")
+	f.BoolVar(&evFlags.json, "json", false, "print a JSON object")
+	f.BoolVar(&evFlags.export, "export", false, "print export statements for sh-compatible shells")
+	envCmd.MarkFlagsMutuallyExclusive("json", "export")
+
+	rootCmd.AddCommand(envCmd)
+}
+
+func runEnv(cmd *cobra.Command, keys []string) error {
+	opts, err := loadTargetOptions(evFlags.config, evFlags.target, evFlags.goos, evFlags.goarch)
+	if err != nil {
+		return err
+	}
+	env, err := targetEnv(cmd.Context(), opts)
+	if err != nil {
+		return err
+	}
+	data, err := formatEnv(env, keys, evFlags.json, evFlags.export)
+	if err != nil {
+		return err
+	}
+	_, err = ui.Stdout.Write(data)
+	return err
+}
+
+// formatEnv renders env as KEY='value' lines, export statements or JSON.
+// With keys, only those variables are kept, and plain output is just their
+// values so a single one can be captured by a shell.
+func formatEnv(env, keys []string, asJSON, export bool) ([]byte, error) {
+	vars := make(map[string]string, len(env))
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+		vars[k] = v
+	}
+	if len(keys) > 0 {
+		env = env[:0:0]
+		for _, k := range keys {
+			env = append(env, k+"="+vars[k])
+		}
+	}
+
+	switch {
+	case asJSON:
+		out := make(map[string]string, len(env))
+		for _, kv := range env {
+			k, v, _ := strings.Cut(kv, "=")
+			out[k] = v
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case export:
+		var buf bytes.Buffer
+		for _, kv := range env {
+			k, v, _ := strings.Cut(kv, "=")
+			fmt.Fprintf(&buf, "export %s=%s\n", k, shellQuote(v))
+		}
+		return buf.Bytes(), nil
+	case len(keys) > 0:
+		var buf bytes.Buffer
+		for _, k := range keys {
+			fmt.Fprintln(&buf, vars[k])
+		}
+		return buf.Bytes(), nil
+	}
+	return envFile(env), nil
+}
+
+// loadTargetOptions selects one target: by name, by --os/--arch over the
+// defaults, or the only target in the config.
+func loadTargetOptions(config, target, goos, goarch string) (*build.Options, error) {
+	cfg, err := build.LoadConfig(config)
+	if err != nil && !errors.Is(err, build.ErrConfigNotFound) {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if err := useProject(cfg); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	var opts *build.Options
+	switch {
+	case cfg == nil:
+		opts = &build.Options{}
+	case target != "":
+		all, err := cfg.ToOptions([]string{target})
+		if err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+		opts = all[0]
+	case goos != "" || goarch != "" || len(cfg.Targets) == 0:
+		opts = cfg.DefaultOptions()
+	default:
+		all, err := cfg.ToOptions(nil)
+		if err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+		if len(all) > 1 {
+			return nil, fmt.Errorf("%d targets in config: choose one with --target", len(all))
+		}
+		opts = all[0]
+	}
+	if goos != "" {
+		opts.GOOS = goos
+	}
+	if goarch != "" {
+		opts.GOARCH = goarch
+	}
+	return opts, nil
+}
+
+// targetEnv validates opts and returns the environment it builds with,
+// fetching Zig and packages as needed.
+func targetEnv(ctx context.Context, opts *build.Options) ([]string, error) {
+	opts.Normalize()
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	zigPath, err := zig.Ensure(ctx, opts.ZigVersion)
+	if err != nil {
+		return nil, fmt.Errorf("zig: %w", err)
+	}
+	return build.New(zigPath, opts).Env(ctx)
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatEnv(t *testing.T) {
+	env := []string{"GOOS=windows", "CC=zig cc -target x86_64-windows-gnu"}
+	tests := []struct {
+		name   string
+		keys   []string
+		json   bool
+		export bool
+		want   string
+	}{
+		{"env", nil, false, false, "GOOS=windows\nCC='zig cc -target x86_64-windows-gnu'\n"},
+		{"export", nil, false, true, "export GOOS=windows\nexport CC='zig cc -target x86_64-windows-gnu'\n"},
+		{"json", nil, true, false, "{\n  \"CC\": \"zig cc -target x86_64-windows-gnu\",\n  \"GOOS\": \"windows\"\n}\n"},
+		{"keys", []string{"CC", "MISSING"}, false, false, "zig cc -target x86_64-windows-gnu\n\n"},
+		{"keys json", []string{"GOOS"}, true, false, "{\n  \"GOOS\": \"windows\"\n}\n"},
+		{"keys export", []string{"GOOS"}, false, true, "export GOOS=windows\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatEnv(env, tt.keys, tt.json, tt.export)
+			if err != nil {
+				t.Fatalf("formatEnv() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("formatEnv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadTargetOptions(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	two := write("two.toml", `
+[[target]]
+name = "linux-amd64"
+os = "linux"
+arch = "amd64"
+
+[[target]]
+name = "windows-amd64"
+os = "windows"
+arch = "amd64"
+flags = ["-tags=gui"]
+`)
+	one := write("one.toml", `
+[[target]]
+name = "darwin-arm64"
+os = "darwin"
+arch = "arm64"
+`)
+
+	tests := []struct {
+		name     string
+		config   string
+		target   string
+		goos     string
+		goarch   string
+		wantOS   string
+		wantArch string
+		wantErr  bool
+	}{
+		{"named target", two, "windows-amd64", "", "", "windows", "amd64", false},
+		{"only target", one, "", "", "", "darwin", "arm64", false},
+		{"ad hoc", two, "", "freebsd", "arm64", "freebsd", "arm64", false},
+		{"ambiguous", two, "", "", "", "", "", true},
+		{"unknown target", two, "nope", "", "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := loadTargetOptions(tt.config, tt.target, tt.goos, tt.goarch)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadTargetOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if opts.GOOS != tt.wantOS || opts.GOARCH != tt.wantArch {
+				t.Errorf("target = %s/%s, want %s/%s", opts.GOOS, opts.GOARCH, tt.wantOS, tt.wantArch)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/zig"
+)
+
+type fetchFlags struct {
+	config   string
+	targets  []string
+	goos     string
+	goarch   string
+	warmLibc bool
+	cxx      bool
+}
+
+var (
+	fFlags   fetchFlags
+	fetchCmd = &cobra.Command{
+		Use:   "fetch",
+		Short: "Download Zig and packages for targets ahead of a build",
+		Long: `Fetch installs the Zig version and packages every selected target needs,
+so later builds start without downloads. Without --target all targets in
+gox.toml are fetched.
+
+The first CGO build for a target spends minutes while Zig builds its libc,
+CRT and compiler-rt. --warm-libc links a small C program per target to build
+them into gox's Zig cache up front (--cxx adds libc++), so CI can save the
+cache and the first real build is fast.`,
+		Example: `  gox fetch
+  gox fetch --warm-libc -t linux-arm64,windows-amd64
+  gox fetch --warm-libc --cxx --os linux --arch amd64`,
+		Args: cobra.NoArgs,
+		RunE: runFetch,
+	}
+)
+
+func init() {
+	f := fetchCmd.Flags()
+
+	f.StringVarP(&fFlags.config, "config", "c", "", "config file path (default: gox.toml)")
+	f.StringSliceVarP(&fFlags.targets, "target", "t", nil, "target names from config (default: all)")
+	_ = fetchCmd.RegisterFlagCompletionFunc("target", completeTargets)
+	f.StringVar(&fFlags.goos, "os", "", "target operating system (GOOS)")
+	f.StringVar(&fFlags.goarch, "arch", "", "target architecture (GOARCH)")
+	f.BoolVar(&fFlags.warmLibc, "warm-libc", false, "pre-build Zig's libc artifacts for each target")
+	f.BoolVar(&fFlags.cxx, "cxx", false, "with --warm-libc, also build libc++")
+
+	rootCmd.AddCommand(fetchCmd)
+}
+
+func runFetch(cmd *cobra.Command, _ []string) error {
+	if fFlags.cxx && !fFlags.warmLibc {
+		return errors.New("--cxx requires --warm-libc")
+	}
+	opts, err := loadFetchOptions(fFlags.config, fFlags.targets, fFlags.goos, fFlags.goarch)
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	var sources []string
+	for _, o := range opts {
+		sources = append(sources, o.Packages...)
+	}
+	if err := build.ConfirmDownloads(ctx, sources); err != nil {
+		return fmt.Errorf("packages: %w", err)
+	}
+
+	// Variants and link-mode twins often share a Zig target: warm it once.
+	warmed := make(map[string]bool)
+	for i, o := range opts {
+		o.Normalize()
+		if err := o.Validate(); err != nil {
+			return err
+		}
+		ui.Target(i, len(opts), o.GOOS, o.GOARCH)
+
+		zigPath, err := zig.Ensure(ctx, o.ZigVersion)
+		if err != nil {
+			return fmt.Errorf("zig: %w", err)
+		}
+		for _, s := range o.Slices() {
+			b := build.New(zigPath, s)
+			if _, err := b.Env(ctx); err != nil {
+				return fmt.Errorf("%s: %w", targetLabel(o), err)
+			}
+
+			key := zigPath + " " + s.ZigTarget()
+			if !fFlags.warmLibc || warmed[key] {
+				continue
+			}
+			warmed[key] = true
+			start := time.Now()
+			if err := b.WarmLibc(ctx, fFlags.cxx); err != nil {
+				return fmt.Errorf("%s: warm libc: %w", targetLabel(o), err)
+			}
+			ui.Success("Warmed %s in %s", s.ZigTarget(), ui.FormatDuration(time.Since(start)))
+		}
+	}
+
+	ui.Success("Fetched %d target(s)", len(opts))
+	return nil
+}
+
+// loadFetchOptions selects targets like gox build: the named ones, a single
+// ad-hoc target from [default] when only --os/--arch are given, or all.
+func loadFetchOptions(config string, targets []string, goos, goarch string) ([]*build.Options, error) {
+	cfg, err := build.LoadConfig(config)
+	if err != nil && !errors.Is(err, build.ErrConfigNotFound) {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if err := useProject(cfg); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	var opts []*build.Options
+	switch {
+	case cfg == nil:
+		opts = []*build.Options{{}}
+	case len(targets) == 0 && (goos != "" || goarch != ""):
+		opts = []*build.Options{cfg.DefaultOptions()}
+	default:
+		if opts, err = cfg.ToOptions(targets); err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+	}
+	for _, o := range opts {
+		if goos != "" {
+			o.GOOS = goos
+		}
+		if goarch != "" {
+			o.GOARCH = goarch
+		}
+		if err := o.ResolvePackages(); err != nil {
+			return nil, err
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadFetchOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gox.toml")
+	config := `
+[[target]]
+name = "linux-amd64"
+os = "linux"
+arch = "amd64"
+
+[[target]]
+name = "windows-amd64"
+os = "windows"
+arch = "amd64"
+`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		targets []string
+		goos    string
+		goarch  string
+		want    []string
+		wantErr bool
+	}{
+		{"all", nil, "", "", []string{"linux/amd64", "windows/amd64"}, false},
+		{"named", []string{"windows-amd64"}, "", "", []string{"windows/amd64"}, false},
+		{"ad hoc", nil, "darwin", "arm64", []string{"darwin/arm64"}, false},
+		{"override named", []string{"linux-amd64"}, "", "arm64", []string{"linux/arm64"}, false},
+		{"unknown", []string{"nope"}, "", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := loadFetchOptions(path, tt.targets, tt.goos, tt.goarch)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadFetchOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, o := range opts {
+				got = append(got, o.GOOS+"/"+o.GOARCH)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("targets = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
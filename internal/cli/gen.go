@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/ui"
+)
+
+type genCgoFlags struct {
+	config    string
+	targets   []string
+	dir       string
+	vendorDir string
+	pkg       string
+}
+
+var (
+	gcFlags genCgoFlags
+	genCmd  = &cobra.Command{
+		Use:   "gen",
+		Short: "Generate files from gox.toml",
+	}
+	genCgoFlagsCmd = &cobra.Command{
+		Use:   "cgoflags",
+		Short: "Write per-target #cgo directive files",
+		Long: `Cgoflags writes zz_cgoflags_<os>_<arch>.go for each target with #cgo CFLAGS
+and LDFLAGS derived from its include/lib directories, links and packages,
+so consumers of a library can use plain 'go build' once the artifacts are
+vendored.
+
+Paths become ${SRCDIR}-relative. Downloaded packages are expected under
+--vendor-dir with the same names as in the package cache (see 'gox pkg list').`,
+		Example: `  gox gen cgoflags
+  gox gen cgoflags -t linux-amd64,windows-amd64 --dir ./cuda --vendor-dir third_party`,
+		Args: cobra.NoArgs,
+		RunE: runGenCgoFlags,
+	}
+)
+
+func init() {
+	f := genCgoFlagsCmd.Flags()
+
+	f.StringVarP(&gcFlags.config, "config", "c", "", "config file path (default: gox.toml)")
+	f.StringSliceVarP(&gcFlags.targets, "target", "t", nil, "target names from config (default: all)")
+	_ = genCgoFlagsCmd.RegisterFlagCompletionFunc("target", completeTargets)
+	f.StringVar(&gcFlags.dir, "dir", ".", "Go package directory to write into")
+	f.StringVar(&gcFlags.vendorDir, "vendor-dir", "third_party", "vendored package directory, relative to --dir")
+	f.StringVar(&gcFlags.pkg, "package", "", "Go package name (default: detected from --dir)")
+
+	genCmd.AddCommand(genCgoFlagsCmd)
+	rootCmd.AddCommand(genCmd)
+}
+
+func runGenCgoFlags(cmd *cobra.Command, _ []string) error {
+	cfg, err := build.LoadConfig(gcFlags.config)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if err := useProject(cfg); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	opts, err := cfg.ToOptions(gcFlags.targets)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	pkg := gcFlags.pkg
+	if pkg == "" {
+		if pkg, err = packageName(gcFlags.dir); err != nil {
+			return err
+		}
+	}
+
+	for _, o := range opts {
+		o.Normalize()
+		if err := o.Validate(); err != nil {
+			return err
+		}
+		flags, err := build.GenCgoFlags(cmd.Context(), o, gcFlags.dir, gcFlags.vendorDir)
+		if err != nil {
+			return fmt.Errorf("%s: %w", targetLabel(o), err)
+		}
+		for _, p := range flags.Unportable {
+			ui.Warn("%s: %s is outside %s and kept absolute", targetLabel(o), p, gcFlags.dir)
+		}
+		src, err := flags.Render(pkg)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(gcFlags.dir, flags.FileName())
+		if err := os.WriteFile(path, src, 0o644); err != nil {
+			return err
+		}
+		ui.Success("Wrote %s", path)
+	}
+	return nil
+}
+
+// packageName returns the package clause shared by the non-test Go files in
+// dir, ignoring previously generated cgoflags files.
+func packageName(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	fset := token.NewFileSet()
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") ||
+			strings.HasPrefix(name, "zz_cgoflags_") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.PackageClauseOnly)
+		if err != nil {
+			continue
+		}
+		return f.Name.Name, nil
+	}
+	return "", fmt.Errorf("no Go files in %s (use --package)", dir)
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPackageName(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    string
+		wantErr bool
+	}{
+		{"package", map[string]string{"a.go": "package cuda\n"}, "cuda", false},
+		{"ignores tests and generated", map[string]string{
+			"a_test.go":                  "package cuda_test\n",
+			"zz_cgoflags_linux_amd64.go": "package old\n",
+			"b.go":                       "package cuda\n",
+		}, "cuda", false},
+		{"no go files", map[string]string{"README.md": "x"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := packageName(dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("packageName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("packageName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
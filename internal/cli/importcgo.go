@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/convert"
+	"github.com/qntx/gox/internal/ui"
+)
+
+type importCgoFlags struct {
+	output string
+	force  bool
+}
+
+var (
+	icFlags      importCgoFlags
+	importCgoCmd = &cobra.Command{
+		Use:   "import-cgo [dir]",
+		Short: "Propose gox.toml settings from the module's #cgo directives",
+		Long: `Import-cgo scans the Go files under dir (default: the current directory)
+for #cgo directives and proposes the link, include and lib settings that
+satisfy them. Unconstrained directives go to [default]; directives limited
+to an os or os/arch become targets.
+
+pkg-config modules are reported as notes, since the host pkg-config cannot
+describe cross targets: add a package providing each one.
+Output goes to stdout unless --output is given.`,
+		Example: `  gox import-cgo
+  gox import-cgo ./... -o gox.toml`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runImportCgo,
+	}
+)
+
+func init() {
+	f := importCgoCmd.Flags()
+
+	f.StringVarP(&icFlags.output, "output", "o", "", "write config to file instead of stdout")
+	f.BoolVarP(&icFlags.force, "force", "f", false, "overwrite existing output file")
+
+	rootCmd.AddCommand(importCgoCmd)
+}
+
+func runImportCgo(_ *cobra.Command, args []string) error {
+	root := "."
+	if len(args) > 0 && args[0] != "./..." {
+		root = args[0]
+	}
+	res, err := convert.FromCgo(root)
+	if err != nil {
+		return err
+	}
+	for _, n := range res.Notes {
+		ui.Warn("%s", n)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Proposed by gox import-cgo from #cgo directives; review before use.\n\n")
+	if err := res.Config.Encode(&buf); err != nil {
+		return err
+	}
+
+	if icFlags.output == "" {
+		_, err := ui.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if _, err := os.Stat(icFlags.output); err == nil && !icFlags.force {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", icFlags.output)
+	}
+	if err := os.WriteFile(icFlags.output, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	ui.Success("Wrote %s with %d target(s)", icFlags.output, len(res.Config.Targets))
+	return nil
+}
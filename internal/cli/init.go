@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/ui"
+)
+
+type initFlags struct {
+	targets    []string
+	zigVersion string
+	force      bool
+}
+
+var (
+	inFlags initFlags
+	initCmd = &cobra.Command{
+		Use:   "init",
+		Short: "Create a starter gox.toml for the current module",
+		Long: `Init inspects the current Go module, detects main packages and writes a
+starter gox.toml with one target per platform.
+
+Use --targets to choose platforms (default: common desktop/server targets).`,
+		Args: cobra.NoArgs,
+		RunE: runInit,
+	}
+
+	defaultInitTargets = []string{"linux/amd64", "linux/arm64", "windows/amd64", "darwin/amd64"}
+)
+
+func init() {
+	f := initCmd.Flags()
+
+	f.StringSliceVar(&inFlags.targets, "targets", defaultInitTargets, "os/arch pairs to generate")
+	f.StringVar(&inFlags.zigVersion, "zig-version", "", "zig compiler version to pin")
+	f.BoolVarP(&inFlags.force, "force", "f", false, "overwrite existing gox.toml")
+
+	rootCmd.AddCommand(initCmd)
+}
+
+func runInit(_ *cobra.Command, _ []string) error {
+	if _, err := os.Stat(build.ConfigFile); err == nil && !inFlags.force {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", build.ConfigFile)
+	}
+
+	mains, err := findMainPackages(".")
+	if err != nil {
+		return err
+	}
+
+	cfg, err := scaffoldConfig(binaryName(mains), inFlags.targets, inFlags.zigVersion)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by gox init. See https://github.com/qntx/gox#configuration\n")
+	if len(mains) > 1 {
+		fmt.Fprintf(&buf, "# Main packages: %s (pass one to gox build)\n", strings.Join(mains, ", "))
+	}
+	buf.WriteString("\n")
+	if err := cfg.Encode(&buf); err != nil {
+		return err
+	}
+	if err := os.WriteFile(build.ConfigFile, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+
+	ui.Success("Created %s with %d target(s)", build.ConfigFile, len(cfg.Targets))
+	if len(mains) == 0 {
+		ui.Warn("No main package found; gox build will build the current directory")
+	}
+	return nil
+}
+
+// scaffoldConfig returns a config with one target per os/arch pair.
+func scaffoldConfig(name string, targets []string, zigVersion string) (*build.Config, error) {
+	cfg := &build.Config{Default: build.ConfigDefault{ZigVersion: zigVersion}}
+	for _, t := range targets {
+		goos, goarch, ok := strings.Cut(t, "/")
+		if !ok || goos == "" || goarch == "" {
+			return nil, fmt.Errorf("invalid target %q (want os/arch)", t)
+		}
+		bin := name
+		if goos == "windows" {
+			bin += ".exe"
+		}
+		cfg.Targets = append(cfg.Targets, build.ConfigTarget{
+			Name:   goos + "-" + goarch,
+			OS:     goos,
+			Arch:   goarch,
+			Output: path.Join("dist", goos+"-"+goarch, bin),
+		})
+	}
+	return cfg, nil
+}
+
+// findMainPackages returns the directories under root (as ./rel paths) that
+// contain a non-test package main.
+func findMainPackages(root string) ([]string, error) {
+	var mains []string
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if p != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+				name == "vendor" || name == "testdata" || isModuleRoot(p)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(p, ".go") || strings.HasSuffix(p, "_test.go") {
+			return nil
+		}
+		f, err := parser.ParseFile(fset, p, nil, parser.PackageClauseOnly)
+		if err != nil || f.Name.Name != "main" {
+			return nil
+		}
+		dir := "./" + filepath.ToSlash(filepath.Dir(p))
+		if dir == "./." {
+			dir = "."
+		}
+		if !slices.Contains(mains, dir) {
+			mains = append(mains, dir)
+		}
+		return nil
+	})
+	return mains, err
+}
+
+// binaryName derives the output name from the first main package, falling
+// back to the module path's last element.
+func binaryName(mains []string) string {
+	if len(mains) > 0 && mains[0] != "." {
+		return path.Base(mains[0])
+	}
+	if mod := modulePath("go.mod"); mod != "" {
+		return path.Base(mod)
+	}
+	if wd, err := os.Getwd(); err == nil {
+		return filepath.Base(wd)
+	}
+	return "app"
+}
+
+func modulePath(gomod string) string {
+	f, err := os.Open(gomod)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if mod, ok := strings.CutPrefix(strings.TrimSpace(sc.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(mod), `"`)
+		}
+	}
+	return ""
+}
+
+// isModuleRoot reports whether dir contains its own go.mod (a nested module).
+func isModuleRoot(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "go.mod"))
+	return err == nil
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestInitCmd_Flags(t *testing.T) {
+	for _, name := range []string{"targets", "zig-version", "force"} {
+		if initCmd.Flags().Lookup(name) == nil {
+			t.Errorf("missing flag: %s", name)
+		}
+	}
+}
+
+func TestFindMainPackages(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"lib.go":               "package lib\n",
+		"cmd/app/main.go":      "package main\n",
+		"cmd/app/main_test.go": "package main_test\n",
+		"cmd/tool/tool.go":     "package main\n",
+		"testdata/x/main.go":   "package main\n",
+		"vendor/y/main.go":     "package main\n",
+		"nested/go.mod":        "module nested\n",
+		"nested/main.go":       "package main\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Chdir(root)
+	got, err := findMainPackages(".")
+	if err != nil {
+		t.Fatalf("findMainPackages() error = %v", err)
+	}
+	want := []string{"./cmd/app", "./cmd/tool"}
+	if !slices.Equal(got, want) {
+		t.Errorf("findMainPackages() = %v, want %v", got, want)
+	}
+}
+
+func TestScaffoldConfig(t *testing.T) {
+	cfg, err := scaffoldConfig("app", []string{"linux/amd64", "windows/arm64"}, "0.14.0")
+	if err != nil {
+		t.Fatalf("scaffoldConfig() error = %v", err)
+	}
+	if cfg.Default.ZigVersion != "0.14.0" {
+		t.Errorf("ZigVersion = %q, want %q", cfg.Default.ZigVersion, "0.14.0")
+	}
+	if len(cfg.Targets) != 2 {
+		t.Fatalf("len(Targets) = %d, want 2", len(cfg.Targets))
+	}
+	if got := cfg.Targets[1].Output; got != "dist/windows-arm64/app.exe" {
+		t.Errorf("Output = %q, want %q", got, "dist/windows-arm64/app.exe")
+	}
+
+	if _, err := scaffoldConfig("app", []string{"linux"}, ""); err == nil {
+		t.Error("scaffoldConfig() should reject target without arch")
+	}
+}
+
+func TestBinaryName(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/foo/bar\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	tests := []struct {
+		mains []string
+		want  string
+	}{
+		{[]string{"./cmd/tool"}, "tool"},
+		{[]string{"."}, "bar"},
+		{nil, "bar"},
+	}
+	for _, tt := range tests {
+		if got := binaryName(tt.mains); got != tt.want {
+			t.Errorf("binaryName(%v) = %q, want %q", tt.mains, got, tt.want)
+		}
+	}
+}
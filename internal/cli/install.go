@@ -8,6 +8,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/hosttool"
 	"github.com/qntx/gox/internal/ui"
 	"github.com/qntx/gox/internal/zig"
 )
@@ -16,6 +17,7 @@ type installFlags struct {
 	config   string
 	target   string
 	linkMode string
+	dryRun   bool
 	opts     build.Options
 }
 
@@ -43,7 +45,9 @@ func init() {
 
 	f.StringVarP(&iFlags.config, "config", "c", "", "config file path (default: gox.toml)")
 	f.StringVarP(&iFlags.target, "target", "t", "", "target name from config (must match current platform)")
+	_ = installCmd.RegisterFlagCompletionFunc("target", completeTargets)
 	f.StringVar(&iFlags.opts.ZigVersion, "zig-version", "", "zig compiler version")
+	f.StringVar(&iFlags.opts.GoVersion, "go-version", "", "go toolchain version (via GOTOOLCHAIN)")
 	f.StringVar(&iFlags.linkMode, "linkmode", "", "link mode: static|dynamic|auto")
 	f.StringSliceVarP(&iFlags.opts.IncludeDirs, "include", "I", nil, "include directories")
 	f.StringSliceVarP(&iFlags.opts.LibDirs, "lib", "L", nil, "library directories")
@@ -52,6 +56,7 @@ func init() {
 	f.StringSliceVar(&iFlags.opts.BuildFlags, "flags", nil, "additional build flags")
 	f.BoolVarP(&iFlags.opts.Strip, "strip", "s", false, "strip symbols (-ldflags=\"-s -w\")")
 	f.BoolVarP(&iFlags.opts.Verbose, "verbose", "v", false, "verbose output")
+	f.BoolVarP(&iFlags.dryRun, "dry-run", "n", false, "print the go command and environment without running it")
 
 	rootCmd.AddCommand(installCmd)
 }
@@ -66,6 +71,14 @@ func runInstall(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if iFlags.dryRun {
+		return dryRun([]*build.Options{opts}, "install", args, nil)
+	}
+
+	if err := hosttool.Require(build.RequiredTools([]*build.Options{opts})...); err != nil {
+		return err
+	}
+
 	opts.Normalize()
 
 	zigPath, err := zig.Ensure(cmd.Context(), opts.ZigVersion)
@@ -85,6 +98,9 @@ func loadInstallOptions(cmd *cobra.Command) (*build.Options, error) {
 	if err != nil && !errors.Is(err, build.ErrConfigNotFound) {
 		return nil, fmt.Errorf("config: %w", err)
 	}
+	if err := useProject(cfg); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
 
 	var opts *build.Options
 	if cfg != nil {
@@ -160,6 +176,9 @@ func applyInstallFlagOverrides(cmd *cobra.Command, o *build.Options) {
 	if changed("zig-version") {
 		o.ZigVersion = iFlags.opts.ZigVersion
 	}
+	if changed("go-version") {
+		o.GoVersion = iFlags.opts.GoVersion
+	}
 	if changed("linkmode") {
 		o.LinkMode = build.LinkMode(iFlags.linkMode)
 	}
@@ -187,6 +206,7 @@ func applyInstallFlagOverrides(cmd *cobra.Command, o *build.Options) {
 
 	o.Output = ""
 	o.Prefix = ""
-	o.Pack = false
+	o.Pack, o.PackFormat = false, ""
 	o.NoRpath = false
+	o.BuildMode, o.PkgConfig = "", false
 }
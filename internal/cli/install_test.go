@@ -183,7 +183,7 @@ func TestApplyInstallFlagOverrides_ClearsInvalidFields(t *testing.T) {
 
 func TestInstallCmd_Flags(t *testing.T) {
 	expectedFlags := []string{
-		"config", "target", "zig-version", "linkmode",
+		"config", "target", "zig-version", "go-version", "linkmode",
 		"include", "lib", "link", "pkg", "flags", "strip", "verbose",
 	}
 
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/ui"
+)
+
+type lspEnvFlags struct {
+	config string
+	target string
+	goos   string
+	goarch string
+	format string
+	output string
+}
+
+var (
+	leFlags   lspEnvFlags
+	lspEnvCmd = &cobra.Command{
+		Use:   "lsp-env",
+		Short: "Configure gopls for a cross-compilation target",
+		Long: `Lsp-env prints the environment a target is built with (GOOS, GOARCH, the
+Zig CC/CXX and CGO flags, package include paths) in a form editors can load,
+so gopls analyzes files behind build constraints such as //go:build windows.
+
+Formats:
+  env      KEY='value' lines, usable as an env file or sourced by a shell
+  vscode   a settings.json fragment setting gopls build.env and buildFlags
+
+With --output, the vscode format is merged into an existing settings file.`,
+		Example: `  gox lsp-env -t windows-amd64 > .gox.env
+  gox lsp-env -t windows-amd64 --format vscode -o .vscode/settings.json
+  gox lsp-env --os darwin --arch arm64`,
+		Args: cobra.NoArgs,
+		RunE: runLspEnv,
+	}
+)
+
+func init() {
+	f := lspEnvCmd.Flags()
+
+	f.StringVarP(&leFlags.config, "config", "c", "", "config file path (default: gox.toml)")
+	f.StringVarP(&leFlags.target, "target", "t", "", "target name from config")
+	_ = lspEnvCmd.RegisterFlagCompletionFunc("target", completeTargets)
+	f.StringVar(&leFlags.goos, "os", "", "target operating system (GOOS)")
+	f.StringVar(&leFlags.goarch, "arch", "", "target architecture (GOARCH)")
+	f.StringVar(&leFlags.format, "format", "env", "output format: env|vscode")
+	f.StringVarP(&leFlags.output, "output", "o", "", "write to file instead of stdout")
+
+	rootCmd.AddCommand(lspEnvCmd)
+}
+
+func runLspEnv(cmd *cobra.Command, _ []string) error {
+	if leFlags.format != "env" && leFlags.format != "vscode" {
+		return fmt.Errorf("invalid --format: %s (use env or vscode)", leFlags.format)
+	}
+	opts, err := loadTargetOptions(leFlags.config, leFlags.target, leFlags.goos, leFlags.goarch)
+	if err != nil {
+		return err
+	}
+	env, err := targetEnv(cmd.Context(), opts)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if leFlags.format == "vscode" {
+		var base []byte
+		if leFlags.output != "" {
+			if base, err = os.ReadFile(leFlags.output); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if data, err = vscodeSettings(base, env, opts.BuildFlags); err != nil {
+			return fmt.Errorf("%s: %w", leFlags.output, err)
+		}
+	} else {
+		data = envFile(env)
+	}
+
+	if leFlags.output == "" {
+		_, err := ui.Stdout.Write(data)
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(leFlags.output), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(leFlags.output, data, 0o644); err != nil {
+		return err
+	}
+	ui.Success("Wrote %s for %s/%s", leFlags.output, opts.GOOS, opts.GOARCH)
+	return nil
+}
+
+// envFile renders env as KEY='value' lines.
+func envFile(env []string) []byte {
+	var buf bytes.Buffer
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+		fmt.Fprintf(&buf, "%s=%s\n", k, shellQuote(v))
+	}
+	return buf.Bytes()
+}
+
+// shellQuote single-quotes s when it holds characters a shell or env file
+// parser would interpret.
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`#;&|<>(){}*?[]~") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// vscodeSettings sets gopls build.env and build.buildFlags in the settings
+// JSON base (which may be empty), keeping every other setting.
+func vscodeSettings(base []byte, env, buildFlags []string) ([]byte, error) {
+	settings := map[string]any{}
+	if len(bytes.TrimSpace(base)) > 0 {
+		if err := json.Unmarshal(base, &settings); err != nil {
+			return nil, fmt.Errorf("parse settings (comments are not supported): %w", err)
+		}
+	}
+	gopls, _ := settings["gopls"].(map[string]any)
+	if gopls == nil {
+		gopls = map[string]any{}
+	}
+	vars := map[string]string{}
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+		vars[k] = v
+	}
+	gopls["build.env"] = vars
+	if len(buildFlags) > 0 {
+		gopls["build.buildFlags"] = buildFlags
+	} else {
+		delete(gopls, "build.buildFlags")
+	}
+	settings["gopls"] = gopls
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
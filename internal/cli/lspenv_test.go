@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"linux", "linux"},
+		{"", "''"},
+		{"/opt/zig/zig cc -target x86_64-windows-gnu", "'/opt/zig/zig cc -target x86_64-windows-gnu'"},
+		{"-Wl,-rpath,$ORIGIN/../lib", "'-Wl,-rpath,$ORIGIN/../lib'"},
+		{"it's", `'it'\''s'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := shellQuote(tt.in); got != tt.want {
+				t.Errorf("shellQuote(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvFile(t *testing.T) {
+	got := string(envFile([]string{"GOOS=windows", "CC=zig cc -target x86_64-windows-gnu"}))
+	want := "GOOS=windows\nCC='zig cc -target x86_64-windows-gnu'\n"
+	if got != want {
+		t.Errorf("envFile() = %q, want %q", got, want)
+	}
+}
+
+func TestVscodeSettings(t *testing.T) {
+	env := []string{"GOOS=windows", "CGO_ENABLED=1"}
+	tests := []struct {
+		name      string
+		base      string
+		flags     []string
+		wantKeep  string
+		wantFlags bool
+		wantErr   bool
+	}{
+		{"empty", "", []string{"-tags=cuda"}, "", true, false},
+		{"keeps other settings", `{"editor.tabSize": 4, "gopls": {"ui.semanticTokens": true, "build.buildFlags": ["-tags=old"]}}`, nil, "editor.tabSize", false, false},
+		{"comments rejected", "// jsonc\n{}", nil, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := vscodeSettings([]byte(tt.base), env, tt.flags)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("vscodeSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var got map[string]any
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("output is not JSON: %v\n%s", err, data)
+			}
+			gopls := got["gopls"].(map[string]any)
+			if e := gopls["build.env"].(map[string]any); e["GOOS"] != "windows" || e["CGO_ENABLED"] != "1" {
+				t.Errorf("build.env = %v", e)
+			}
+			if _, ok := gopls["build.buildFlags"]; ok != tt.wantFlags {
+				t.Errorf("build.buildFlags present = %v, want %v", ok, tt.wantFlags)
+			}
+			if tt.wantKeep != "" {
+				if _, ok := got[tt.wantKeep]; !ok {
+					t.Errorf("lost setting %s", tt.wantKeep)
+				}
+				if gopls["ui.semanticTokens"] != true {
+					t.Error("lost existing gopls setting")
+				}
+			}
+		})
+	}
+}
@@ -2,21 +2,23 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
 	"slices"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/lock"
 	"github.com/qntx/gox/internal/ui"
 )
 
 var (
 	pkgCmd = &cobra.Command{
-		Use:   "pkg",
-		Short: "Manage cached dependency packages",
+		Use:               "pkg",
+		Short:             "Manage cached dependency packages",
+		PersistentPreRunE: useConfigCache,
 	}
 
 	pkgListCmd = &cobra.Command{
@@ -31,15 +33,17 @@ var (
 		Long: `Remove cached dependency packages.
 If no name is specified, removes all cached packages.
 Supports glob patterns (e.g., cuda_* to match all cuda packages).`,
-		Args: cobra.MaximumNArgs(1),
-		RunE: runPkgClean,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeCachedPackages,
+		RunE:              runPkgClean,
 	}
 
 	pkgInfoCmd = &cobra.Command{
-		Use:   "info <name>",
-		Short: "Show package details",
-		Args:  cobra.ExactArgs(1),
-		RunE:  runPkgInfo,
+		Use:               "info <name>",
+		Short:             "Show package details",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeCachedPackages,
+		RunE:              runPkgInfo,
 	}
 
 	pkgInstallCmd = &cobra.Command{
@@ -50,14 +54,61 @@ Useful for pre-warming cache in CI/CD pipelines.
 
 Sources can be:
   - Direct URL: https://example.com/archive.tar.gz
-  - GitHub release: owner/repo@version/asset.tar.gz`,
+  - GitHub release: owner/repo@version/asset.tar.gz
+  - Registry name: <name>@<version>, the build for this host`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: runPkgInstall,
 	}
+
+	pkgSearchCmd = &cobra.Command{
+		Use:   "search [query]",
+		Short: "Search available prebuilt packages",
+		Long: `List the packages of the built-in registry whose name contains query,
+with the platforms each has builds for. With --repo, list the archives
+published with the recent GitHub releases of that repository instead,
+as sources ready for --pkg.`,
+		Example: `  gox pkg search cuda
+  gox pkg search --repo gocnn-lib/cudart linux`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runPkgSearch,
+	}
+
+	pkgUpdateCmd = &cobra.Command{
+		Use:   "update [source]...",
+		Short: "Re-resolve packages and refresh gox.lock",
+		Long: `Download packages again, ignoring their cached copies and gox.lock
+entries, so floating sources such as owner/repo@latest/asset, a
+<name>@latest registry name or a URL to a nightly build pick up what they
+point at now. The new digests are written to gox.lock and every package
+whose archive changed is reported.
+
+Without arguments every package of every target in gox.toml is updated,
+and gox.lock entries no target uses any more are removed. Packages pinned
+by #sha256, vendored packages and vcpkg ports are left alone.`,
+		Example: `  gox pkg update
+  gox pkg update owner/repo@latest/lib-linux-amd64.tar.gz`,
+		RunE: runPkgUpdate,
+	}
+
+	pkgVerifyCmd = &cobra.Command{
+		Use:   "verify",
+		Short: "Check cached packages for corruption or tampering",
+		Long: `Hash every cached package again and compare it with the digest recorded
+when it was downloaded. Packages cached by older gox versions have no
+recorded digest and are reported as unrecorded. With --fix, modified
+packages are downloaded again, verified against gox.lock when there is one.`,
+		Args: cobra.NoArgs,
+		RunE: runPkgVerify,
+	}
+
+	pkgSearchRepo string
+	pkgVerifyFix  bool
 )
 
 func init() {
-	pkgCmd.AddCommand(pkgListCmd, pkgCleanCmd, pkgInfoCmd, pkgInstallCmd)
+	pkgSearchCmd.Flags().StringVar(&pkgSearchRepo, "repo", "", "search the GitHub releases of owner/repo")
+	pkgVerifyCmd.Flags().BoolVar(&pkgVerifyFix, "fix", false, "download modified packages again")
+	pkgCmd.AddCommand(pkgListCmd, pkgCleanCmd, pkgInfoCmd, pkgInstallCmd, pkgSearchCmd, pkgUpdateCmd, pkgVerifyCmd)
 	rootCmd.AddCommand(pkgCmd)
 }
 
@@ -80,12 +131,12 @@ func runPkgList(_ *cobra.Command, _ []string) error {
 	tbl := ui.NewTable("NAME", "SIZE", "INCLUDE", "LIB")
 	var total int64
 	for _, p := range pkgs {
-		tbl.AddRow(p.Name, ui.FormatSize(p.Size), fmt.Sprintf("%d", p.IncludeCount), fmt.Sprintf("%d", p.LibCount))
+		tbl.AddCells(p.Name, ui.Bytes(p.Size), p.IncludeCount, p.LibCount)
 		total += p.Size
 	}
 	tbl.Render()
 
-	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(ui.Stderr)
 	ui.Label("total", fmt.Sprintf("%d packages, %s", len(pkgs), ui.FormatSize(total)))
 	ui.Label("path", build.CacheDir())
 	return nil
@@ -125,10 +176,181 @@ func runPkgInstall(cmd *cobra.Command, args []string) error {
 		ctx = context.Background()
 	}
 
-	_, err := build.EnsureAll(ctx, args)
+	sources, err := build.ResolvePackages(args, "", "")
+	if err != nil {
+		return err
+	}
+	_, err = build.EnsureAll(ctx, sources)
 	return err
 }
 
+func runPkgSearch(cmd *cobra.Command, args []string) error {
+	var query string
+	if len(args) > 0 {
+		query = args[0]
+	}
+	if pkgSearchRepo != "" {
+		return searchReleases(cmd.Context(), pkgSearchRepo, query)
+	}
+
+	entries := build.SearchRegistry(query)
+	switch {
+	case len(entries) == 0 && query == "":
+		ui.Info("The package registry is empty")
+		return nil
+	case len(entries) == 0:
+		ui.Info("No registry packages matching %q", query)
+		return nil
+	}
+	tbl := ui.NewTable("NAME", "PLATFORMS")
+	for _, e := range entries {
+		tbl.AddRow(e.Name, strings.Join(e.Platforms, ", "))
+	}
+	tbl.Render()
+	return nil
+}
+
+func searchReleases(ctx context.Context, repo, query string) error {
+	assets, err := build.SearchReleases(ctx, repo, query)
+	if err != nil {
+		return err
+	}
+	if len(assets) == 0 {
+		ui.Info("No release archives of %s matching %q", repo, query)
+		return nil
+	}
+	tbl := ui.NewTable("SOURCE", "PLATFORM")
+	for _, a := range assets {
+		platform := a.Platform
+		if a.Prerelease {
+			platform += " (prerelease)"
+		}
+		tbl.AddRow(a.Source, strings.TrimSpace(platform))
+	}
+	tbl.Render()
+	return nil
+}
+
+func runPkgUpdate(cmd *cobra.Command, args []string) error {
+	sources, err := build.ResolvePackages(args, "", "")
+	if err != nil {
+		return err
+	}
+	prune := len(args) == 0
+	if prune {
+		cfg, err := build.LoadConfig("")
+		if errors.Is(err, build.ErrConfigNotFound) {
+			return errors.New("no gox.toml found: name the packages to update")
+		}
+		if err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		opts, err := allTargets(cfg)
+		if err != nil {
+			return err
+		}
+		for _, o := range opts {
+			if err := o.ResolvePackages(); err != nil {
+				return err
+			}
+			sources = append(sources, o.Packages...)
+		}
+		slices.Sort(sources)
+		sources = slices.Compact(sources)
+	}
+
+	updates, err := build.UpdatePackages(cmd.Context(), sources)
+	if len(updates) > 0 {
+		reportUpdates(updates)
+	}
+	if err != nil {
+		return err
+	}
+	if l := lock.Active(); prune && l != nil {
+		dropped, err := l.RetainPackages(sources)
+		if err != nil {
+			return err
+		}
+		for _, p := range dropped {
+			ui.Info("%s: no longer used, removed from %s", p.Source, lock.File)
+		}
+	}
+	return nil
+}
+
+// reportUpdates prints what became of each package gox pkg update looked at.
+func reportUpdates(updates []build.PackageUpdate) {
+	tbl := ui.NewTable("PACKAGE", "STATUS")
+	var changed int
+	for _, u := range updates {
+		switch {
+		case u.Skipped != "":
+			tbl.AddStatusRow(ui.StatusMuted, u.Source, "skipped: "+u.Skipped)
+		case u.Changed() && u.Old.SHA256 == "":
+			changed++
+			tbl.AddStatusRow(ui.StatusOK, u.Source, "locked "+shortDigest(u.New.SHA256))
+		case u.Changed():
+			changed++
+			tbl.AddStatusRow(ui.StatusWarn, u.Source, "changed "+shortDigest(u.Old.SHA256)+" → "+shortDigest(u.New.SHA256))
+		case u.New.SHA256 == "":
+			tbl.AddStatusRow(ui.StatusNone, u.Source, "refreshed")
+		default:
+			tbl.AddStatusRow(ui.StatusNone, u.Source, "unchanged")
+		}
+	}
+	tbl.Render()
+	if lock.Active() != nil {
+		ui.Success("%d of %d package(s) changed", changed, len(updates))
+	}
+}
+
+// shortDigest abbreviates a sha256 hex digest for display.
+func shortDigest(digest string) string {
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return digest
+}
+
+func runPkgVerify(cmd *cobra.Command, _ []string) error {
+	checks, err := build.VerifyCached()
+	if err != nil {
+		return err
+	}
+	if len(checks) == 0 {
+		ui.Info("No cached packages")
+		return nil
+	}
+
+	tbl := ui.NewTable("NAME", "STATUS", "SOURCE")
+	var modified int
+	for _, c := range checks {
+		status := ui.StatusOK
+		switch c.Status {
+		case build.VerifyModified:
+			status = ui.StatusFailed
+			modified++
+		case build.VerifyUnrecorded:
+			status = ui.StatusMuted
+		}
+		tbl.AddStatusRow(status, c.Name, c.Status, c.Source)
+	}
+	tbl.Render()
+
+	switch {
+	case modified == 0:
+		ui.Success("%d package(s) verified", len(checks))
+		return nil
+	case pkgVerifyFix:
+		if err := build.RepairCached(cmd.Context(), checks); err != nil {
+			return err
+		}
+		ui.Success("Downloaded %d modified package(s) again", modified)
+		return nil
+	}
+	return fmt.Errorf("%d cached package(s) modified: run gox pkg verify --fix", modified)
+}
+
 func cleanPkg(pattern string) error {
 	pkgs, err := build.ListCached()
 	if err != nil {
@@ -50,7 +50,7 @@ func TestMatchGlob(t *testing.T) {
 }
 
 func TestPkgCmd_Subcommands(t *testing.T) {
-	subcommands := []string{"list", "clean", "info", "install"}
+	subcommands := []string{"list", "clean", "info", "install", "search", "update", "verify", "vendor"}
 
 	for _, name := range subcommands {
 		t.Run(name, func(t *testing.T) {
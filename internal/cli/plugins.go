@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/plugin"
+	"github.com/qntx/gox/internal/ui"
+)
+
+var pluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "List the gox-* plugins on PATH and those gox.toml enables",
+	Long: `Plugins extend builds without changing gox. A plugin named notify is an
+executable gox-notify on PATH, enabled by plugins = ["notify"] in the
+[default] section of gox.toml; a path containing a separator, relative to
+gox.toml, names the executable directly.
+
+Enabled plugins run in order at each hook of gox build:
+
+  pre-build   before a target compiles
+  post-build  after a target's binary is built, signed and given its libs
+  pre-pack    before a target is archived (targets with pack only)
+  publish     once after every target is built and SHA256SUMS is written
+
+Each run gets the hook name as its only argument and in $GOX_EVENT, and a
+JSON event on stdin with the target, os, arch, output, prefix, archive
+(pre-pack) and artifacts (publish). Its output is shown with the build's. A
+plugin exiting non-zero fails the build at that hook.`,
+	Example: `  gox plugins
+  printf '#!/bin/sh\njq -r .artifacts[] | xargs gh release upload v1\n' > ~/bin/gox-upload`,
+	Args: cobra.NoArgs,
+	RunE: runPlugins,
+}
+
+func init() {
+	rootCmd.AddCommand(pluginsCmd)
+}
+
+func runPlugins(*cobra.Command, []string) error {
+	cfg, err := build.LoadConfig("")
+	if err != nil && !errors.Is(err, build.ErrConfigNotFound) {
+		return fmt.Errorf("config: %w", err)
+	}
+	var names []string
+	if cfg != nil {
+		names = cfg.PluginNames()
+	}
+
+	tbl := ui.NewTable("PLUGIN", "STATUS", "PATH")
+	var enabled []string
+	for _, name := range names {
+		p, err := plugin.Lookup(name)
+		if err != nil {
+			tbl.AddRow(name, "missing", "-")
+			continue
+		}
+		enabled = append(enabled, p.Path)
+		tbl.AddRow(p.Name, "enabled", p.Path)
+	}
+	found := plugin.Discover()
+	for _, p := range found {
+		if !slices.Contains(enabled, p.Path) {
+			tbl.AddRow(p.Name, "available", p.Path)
+		}
+	}
+	if len(names) == 0 && len(found) == 0 {
+		ui.Info("No plugins found (gox-<name> executables on PATH)")
+		return nil
+	}
+	ui.Header("Plugins")
+	tbl.Render()
+	return nil
+}
@@ -1,10 +1,22 @@
 package cli
 
 import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/lock"
+	"github.com/qntx/gox/internal/telemetry"
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/workspace"
+	"github.com/qntx/gox/internal/zig"
 )
 
 var (
@@ -41,9 +53,158 @@ Build for any OS/arch from any host without complex toolchain setup.
   gox pkg list                 List cached packages`,
 }
 
-// Execute runs the root command.
-func Execute() error {
+// themeEnv selects the UI theme for every project, overriding gox.toml.
+const themeEnv = "GOX_THEME"
+
+var (
+	noVerify bool
+	zigPath  string
+	logFile  string
+	yes      bool
+	jsonOut  bool
+	closeLog = func() error { return nil }
+	endTrace = func(error) {}
+)
+
+// traceFlushTimeout bounds how long exit waits on the OTLP endpoint.
+const traceFlushTimeout = 5 * time.Second
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&noVerify, "no-verify", false, "skip zig tarball checksum and signature verification")
+	rootCmd.PersistentFlags().StringVar(&zigPath, "zig-path", "", "use this zig binary (or directory) instead of downloading one")
+	rootCmd.PersistentFlags().BoolVarP(&yes, "yes", "y", false, "download large packages without asking")
+	rootCmd.PersistentFlags().BoolVar(&jsonOut, "json", false, "write machine-readable JSON events to stdout instead of styled output")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "also write unstyled, timestamped output to `file`")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := openLog(cmd, args); err != nil {
+			return err
+		}
+		startTrace(cmd)
+		return nil
+	}
+	// Run the root hook (--log-file, tracing) as well as those of zig, pkg
+	// and daemon.
+	cobra.EnableTraverseRunHooks = true
+	cobra.OnInitialize(func() {
+		zig.Verify = !noVerify
+		zig.System = zigPath
+		build.AssumeYes = yes
+		ui.SetJSON(jsonOut)
+	})
+	if name := os.Getenv(themeEnv); name != "" {
+		if err := ui.SetTheme(name); err != nil {
+			ui.Warn("%s: %v", themeEnv, err)
+		}
+	}
+}
+
+// Execute runs the root command. Traces are flushed, the run workspace is
+// removed and the --log-file closed on return, including when a command
+// panics.
+func Execute() (err error) {
+	defer workspace.Cleanup()
+	defer func() { _ = closeLog() }()
+	defer func() { endTrace(err) }()
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 	rootCmd.SetOut(os.Stderr)
+	defer func() {
+		if err != nil {
+			ui.Emit(ui.Event{Type: ui.EventError, Error: err.Error()})
+		}
+	}()
 	return rootCmd.Execute()
 }
+
+// openLog starts teeing output to --log-file, including cobra's own usage
+// and error messages.
+func openLog(cmd *cobra.Command, _ []string) error {
+	if logFile == "" {
+		return nil
+	}
+	closer, err := ui.OpenLog(logFile)
+	if err != nil {
+		return fmt.Errorf("log file: %w", err)
+	}
+	closeLog = closer
+	cmd.Root().SetOut(ui.Stderr)
+	cmd.Root().SetErr(ui.Stderr)
+	return nil
+}
+
+// startTrace exports a span for the command when an OTLP endpoint is
+// configured. Tracing problems are reported but never fail the command.
+func startTrace(cmd *cobra.Command) {
+	if !telemetry.Enabled() {
+		return
+	}
+	shutdown, err := telemetry.Setup(cmd.Context())
+	if err != nil {
+		ui.Warn("telemetry: %v", err)
+		return
+	}
+	ctx, span := telemetry.Start(cmd.Context(), cmd.CommandPath())
+	cmd.SetContext(ctx)
+	endTrace = func(err error) {
+		telemetry.End(span, err)
+		ctx, cancel := context.WithTimeout(context.Background(), traceFlushTimeout)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			ui.Warn("telemetry: %v", err)
+		}
+	}
+}
+
+// useProject applies the cache-scope, zig settings, theme, retry policy,
+// mirrors, download confirmation and gox.lock of cfg, if any. GOX_THEME takes precedence over the config theme.
+func useProject(cfg *build.Config) error {
+	if cfg == nil {
+		return nil
+	}
+	if err := cfg.UseCache(); err != nil {
+		return err
+	}
+	zig.VerifySignature = cfg.Default.ZigMinisign
+	zig.Mirror = cfg.Default.ZigMirror
+	zig.System = cmp.Or(zigPath, cfg.ZigPath())
+	if os.Getenv(themeEnv) == "" {
+		if err := ui.SetTheme(cfg.Default.Theme); err != nil {
+			return err
+		}
+	}
+	if err := cfg.UseRetry(); err != nil {
+		return err
+	}
+	if err := cfg.UseMirrors(); err != nil {
+		return err
+	}
+	if err := cfg.UseConfirm(); err != nil {
+		return err
+	}
+	if err := cfg.UseTools(); err != nil {
+		return err
+	}
+	if err := cfg.UseExtract(); err != nil {
+		return err
+	}
+	cfg.UseVendor()
+	if err := cfg.UsePkgConfig(); err != nil {
+		return err
+	}
+	if err := cfg.UsePlugins(); err != nil {
+		return err
+	}
+	return lock.Use(cfg.LockPath())
+}
+
+// useConfigCache loads the nearest config only to apply its cache-scope and
+// lockfile, so cache management commands see the same state as builds.
+func useConfigCache(*cobra.Command, []string) error {
+	cfg, err := build.LoadConfig("")
+	if err != nil && !errors.Is(err, build.ErrConfigNotFound) {
+		return fmt.Errorf("config: %w", err)
+	}
+	if err := useProject(cfg); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return nil
+}
@@ -9,6 +9,32 @@ func TestRootCmd(t *testing.T) {
 		}
 	})
 
+	t.Run("no-verify flag", func(t *testing.T) {
+		if rootCmd.PersistentFlags().Lookup("no-verify") == nil {
+			t.Error("missing persistent --no-verify flag")
+		}
+	})
+
+	t.Run("zig-path flag", func(t *testing.T) {
+		if rootCmd.PersistentFlags().Lookup("zig-path") == nil {
+			t.Error("missing persistent --zig-path flag")
+		}
+	})
+
+	t.Run("log-file flag", func(t *testing.T) {
+		if rootCmd.PersistentFlags().Lookup("log-file") == nil {
+			t.Error("missing persistent --log-file flag")
+		}
+	})
+
+	t.Run("json and yes flags", func(t *testing.T) {
+		for _, name := range []string{"json", "yes"} {
+			if rootCmd.PersistentFlags().Lookup(name) == nil {
+				t.Errorf("missing persistent --%s flag", name)
+			}
+		}
+	})
+
 	t.Run("has subcommands", func(t *testing.T) {
 		if len(rootCmd.Commands()) == 0 {
 			t.Error("rootCmd has no subcommands")
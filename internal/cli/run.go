@@ -6,13 +6,16 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"syscall"
 
 	"github.com/spf13/cobra"
 
 	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/hosttool"
 	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/workspace"
 	"github.com/qntx/gox/internal/zig"
 )
 
@@ -21,6 +24,10 @@ type runFlags struct {
 	target   string
 	linkMode string
 	exec     string
+	sysroot  bool
+	dryRun   bool
+	watch    bool
+	ignore   []string
 	opts     build.Options
 }
 
@@ -37,8 +44,16 @@ Arguments after -- are passed to the compiled program.
 Configuration can be loaded from gox.toml. When using config, only the target
 matching the current platform (or specified by --target) is used.
 
-Note: Cross-compilation is not supported for run. The target OS and architecture
-must match the current system.`,
+Linux binaries of another architecture are run through qemu-user, invoked
+as qemu-<arch> or through a binfmt_misc handler; a configured sysroot
+provides their loader and libc. Windows binaries run under wine, with
+package DLL directories on WINEPATH. Other targets must match the current
+system, except with --exec. --sysroot runs linux binaries of any
+architecture inside a minimal root filesystem holding only the binary, its
+package libraries and the libc of the configured sysroot.
+
+With --watch the program is stopped, rebuilt and started again whenever
+.go, C/C++ sources or headers, go.mod or gox.toml change in the module.`,
 		RunE:               runRun,
 		DisableFlagParsing: false,
 	}
@@ -48,9 +63,13 @@ func init() {
 	f := runCmd.Flags()
 
 	f.StringVarP(&rFlags.config, "config", "c", "", "config file path (default: gox.toml)")
-	f.StringVarP(&rFlags.target, "target", "t", "", "target name from config (must match current platform)")
+	f.StringVarP(&rFlags.target, "target", "t", "", "target name from config (current platform, linux via qemu-user or windows via wine)")
+	_ = runCmd.RegisterFlagCompletionFunc("target", completeTargets)
 	f.StringVar(&rFlags.exec, "exec", "", "execute binary using specified program")
+	f.BoolVar(&rFlags.sysroot, "sysroot", false, "run the linux binary in a sysroot of its package libraries and libc (bwrap or qemu-user)")
+	runCmd.MarkFlagsMutuallyExclusive("exec", "sysroot")
 	f.StringVar(&rFlags.opts.ZigVersion, "zig-version", "", "zig compiler version")
+	f.StringVar(&rFlags.opts.GoVersion, "go-version", "", "go toolchain version (via GOTOOLCHAIN)")
 	f.StringVar(&rFlags.linkMode, "linkmode", "", "link mode: static|dynamic|auto")
 	f.StringSliceVarP(&rFlags.opts.IncludeDirs, "include", "I", nil, "include directories")
 	f.StringSliceVarP(&rFlags.opts.LibDirs, "lib", "L", nil, "library directories")
@@ -58,6 +77,10 @@ func init() {
 	f.StringSliceVar(&rFlags.opts.Packages, "pkg", nil, "packages to download")
 	f.StringSliceVar(&rFlags.opts.BuildFlags, "flags", nil, "additional build flags")
 	f.BoolVarP(&rFlags.opts.Verbose, "verbose", "v", false, "verbose output")
+	f.BoolVarP(&rFlags.dryRun, "dry-run", "n", false, "print the go command and environment without running it")
+	f.BoolVarP(&rFlags.watch, "watch", "w", false, "rebuild and restart the program when source files change")
+	f.StringSliceVar(&rFlags.ignore, "watch-ignore", nil, "glob patterns of paths not watched")
+	runCmd.MarkFlagsMutuallyExclusive("watch", "sysroot")
 
 	rootCmd.AddCommand(runCmd)
 }
@@ -70,7 +93,24 @@ func runRun(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if err := validateRunTarget(opts); err != nil {
+	var emu *build.Emulator
+	if rFlags.sysroot {
+		opts.Normalize()
+		if _, err := build.NewSysroot(opts); err != nil {
+			return err
+		}
+	} else if emu, err = runEmulator(opts); err != nil {
+		return err
+	}
+
+	if rFlags.dryRun {
+		return dryRun([]*build.Options{opts}, "run", pkgs, progArgs)
+	}
+	if rFlags.watch {
+		return watchRun(cmd, pkgs, progArgs)
+	}
+
+	if err := hosttool.Require(build.RequiredTools([]*build.Options{opts})...); err != nil {
 		return err
 	}
 
@@ -85,22 +125,22 @@ func runRun(cmd *cobra.Command, args []string) error {
 		ui.Label("zig", zigPath)
 	}
 
-	if rFlags.exec != "" {
-		return runWithExec(cmd, pkgs, progArgs, opts, zigPath)
+	if rFlags.exec != "" || rFlags.sysroot || emu != nil {
+		return runWithExec(cmd, pkgs, progArgs, opts, zigPath, emu)
 	}
 
 	return build.New(zigPath, opts).GoRun(cmd.Context(), pkgs, progArgs)
 }
 
-func runWithExec(cmd *cobra.Command, pkgs, progArgs []string, opts *build.Options, zigPath string) error {
-	tmpDir, err := os.MkdirTemp("", "gox-run-*")
+func runWithExec(cmd *cobra.Command, pkgs, progArgs []string, opts *build.Options, zigPath string, emu *build.Emulator) error {
+	tmpDir, err := workspace.MkdirTemp("exec-*")
 	if err != nil {
 		return fmt.Errorf("temp dir: %w", err)
 	}
 	defer os.RemoveAll(tmpDir)
 
 	binName := "main"
-	if runtime.GOOS == "windows" {
+	if opts.GOOS == "windows" {
 		binName += ".exe"
 	}
 	opts.Output = tmpDir + string(os.PathSeparator) + binName
@@ -113,6 +153,20 @@ func runWithExec(cmd *cobra.Command, pkgs, progArgs []string, opts *build.Option
 		return err
 	}
 
+	if rFlags.sysroot {
+		sr, err := build.NewSysroot(opts)
+		if err != nil {
+			return err
+		}
+		return runInSysroot(cmd.Context(), sr, opts.Output, progArgs, filepath.Join(tmpDir, "root"))
+	}
+	if emu != nil {
+		if err := emu.Check(opts.Output); err != nil {
+			return err
+		}
+		emu.DLLDirs = opts.BinDirs
+		return runProgram(emu.Command(cmd.Context(), opts.Output, progArgs))
+	}
 	return executeProgram(opts.Output, progArgs, rFlags.exec, opts.Verbose)
 }
 
@@ -130,6 +184,9 @@ func loadRunOptions(cmd *cobra.Command) (*build.Options, error) {
 	if err != nil && !errors.Is(err, build.ErrConfigNotFound) {
 		return nil, fmt.Errorf("config: %w", err)
 	}
+	if err := useProject(cfg); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
 
 	var opts *build.Options
 	if cfg != nil {
@@ -199,12 +256,36 @@ func validateRunTarget(opts *build.Options) error {
 	return nil
 }
 
+// runEmulator checks that opts' binary can run on this host and returns
+// the emulator a linux target of another architecture, or a windows target
+// run under wine, needs, or nil. With --exec the given program is trusted
+// to run any target.
+func runEmulator(opts *build.Options) (*build.Emulator, error) {
+	err := validateRunTarget(opts)
+	if err == nil || rFlags.exec != "" {
+		return nil, nil
+	}
+	opts.Normalize()
+	if runtime.GOOS != "linux" || (opts.GOOS != "linux" && opts.GOOS != "windows") {
+		return nil, err
+	}
+	emu, err := build.NewEmulator(opts)
+	if err != nil {
+		return nil, fmt.Errorf("cannot run %s/%s binary on %s/%s: %w",
+			opts.GOOS, opts.GOARCH, runtime.GOOS, runtime.GOARCH, err)
+	}
+	return emu, nil
+}
+
 func applyRunFlagOverrides(cmd *cobra.Command, o *build.Options) {
 	changed := cmd.Flags().Changed
 
 	if changed("zig-version") {
 		o.ZigVersion = rFlags.opts.ZigVersion
 	}
+	if changed("go-version") {
+		o.GoVersion = rFlags.opts.GoVersion
+	}
 	if changed("linkmode") {
 		o.LinkMode = build.LinkMode(rFlags.linkMode)
 	}
@@ -229,8 +310,9 @@ func applyRunFlagOverrides(cmd *cobra.Command, o *build.Options) {
 
 	o.Output = ""
 	o.Prefix = ""
-	o.Pack = false
+	o.Pack, o.PackFormat = false, ""
 	o.NoRpath = false
+	o.BuildMode, o.PkgConfig = "", false
 }
 
 func executeProgram(binPath string, args []string, execProg string, verbose bool) error {
@@ -241,10 +323,15 @@ func executeProgram(binPath string, args []string, execProg string, verbose bool
 	} else {
 		cmd = exec.Command(binPath, args...)
 	}
+	return runProgram(cmd)
+}
 
+// runProgram runs cmd attached to the terminal, forwarding interrupts, and
+// exits with its status when it fails.
+func runProgram(cmd *exec.Cmd) error {
 	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout = ui.Stdout
+	cmd.Stderr = ui.Stderr
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
@@ -237,7 +237,7 @@ func TestApplyRunFlagOverrides_ClearsInvalidFields(t *testing.T) {
 
 func TestRunCmd_Flags(t *testing.T) {
 	expectedFlags := []string{
-		"config", "target", "exec", "zig-version", "linkmode",
+		"config", "target", "exec", "zig-version", "go-version", "linkmode",
 		"include", "lib", "link", "pkg", "flags", "verbose",
 	}
 
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/zig"
+)
+
+// fetchPlan is the download work of a parallel build. Each zig version and
+// each distinct package set is fetched once; a target waits only for its
+// own set, so targets needing nothing compile while others download.
+type fetchPlan struct {
+	zigVersions []string
+	sets        []*fetchSet
+	bySet       map[string]*fetchSet
+}
+
+type fetchSet struct {
+	key      string
+	packages []string
+	done     chan struct{}
+	err      error
+}
+
+// dedupeOptions normalizes opts and drops targets identical to an earlier
+// one. It returns the unique targets and the number dropped.
+func dedupeOptions(opts []*build.Options) ([]*build.Options, int) {
+	seen := make(map[string]bool, len(opts))
+	out := make([]*build.Options, 0, len(opts))
+	for _, o := range opts {
+		o.Normalize()
+		data, err := json.Marshal(o)
+		if err != nil {
+			out = append(out, o)
+			continue
+		}
+		if seen[string(data)] {
+			continue
+		}
+		seen[string(data)] = true
+		out = append(out, o)
+	}
+	return out, len(opts) - len(out)
+}
+
+func newFetchPlan(opts []*build.Options) *fetchPlan {
+	p := &fetchPlan{bySet: make(map[string]*fetchSet)}
+	for _, o := range opts {
+		if !slices.Contains(p.zigVersions, o.ZigVersion) {
+			p.zigVersions = append(p.zigVersions, o.ZigVersion)
+		}
+		if len(o.Packages) == 0 {
+			continue
+		}
+		pkgs := slices.Clone(o.Packages)
+		slices.Sort(pkgs)
+		pkgs = slices.Compact(pkgs)
+		key := strings.Join(pkgs, "\n")
+		if _, ok := p.bySet[key]; !ok {
+			s := &fetchSet{key: key, packages: pkgs, done: make(chan struct{})}
+			p.bySet[key] = s
+			p.sets = append(p.sets, s)
+		}
+	}
+	// Smaller sets finish first and unblock their targets sooner; larger
+	// overlapping sets then only download what is still missing.
+	slices.SortStableFunc(p.sets, func(a, b *fetchSet) int {
+		return cmp.Compare(len(a.packages), len(b.packages))
+	})
+	return p
+}
+
+// run fetches zig versions, then package sets one at a time so overlapping
+// sets never download the same package concurrently.
+func (p *fetchPlan) run(ctx context.Context) error {
+	for _, v := range p.zigVersions {
+		if _, err := zig.Ensure(ctx, v); err != nil {
+			for _, s := range p.sets {
+				s.err = err
+				close(s.done)
+			}
+			return fmt.Errorf("zig: %w", err)
+		}
+	}
+	for _, s := range p.sets {
+		_, s.err = build.EnsureAll(ctx, s.packages)
+		close(s.done)
+	}
+	return nil
+}
+
+// wait blocks until the packages of o are cached.
+func (p *fetchPlan) wait(ctx context.Context, o *build.Options) error {
+	if len(o.Packages) == 0 {
+		return nil
+	}
+	pkgs := slices.Clone(o.Packages)
+	slices.Sort(pkgs)
+	s := p.bySet[strings.Join(slices.Compact(pkgs), "\n")]
+	select {
+	case <-s.done:
+		return s.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// buildSlots returns how many of n targets may compile at once: at most
+// jobs, or with jobs 0 one per two CPUs, since each go build is itself
+// parallel and running one per CPU only oversubscribes the host.
+func buildSlots(n, jobs int) int {
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0) / 2
+	}
+	return max(1, min(n, jobs))
+}
+
+func logQueue(targets, dups int, plan *fetchPlan, slots int) {
+	msg := fmt.Sprintf("Queue: %d target(s), %d package set(s) to fetch, %d build slot(s)", targets, len(plan.sets), slots)
+	if dups > 0 {
+		msg += fmt.Sprintf(", %d duplicate(s) skipped", dups)
+	}
+	ui.Info("%s", msg)
+}
+
+// memBudget throttles compiles so their estimated peak memory stays within
+// limit. A target estimated above the limit runs alone. A nil budget never
+// blocks.
+type memBudget struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	limit   int64
+	used    int64
+	running int
+}
+
+func newMemBudget(limit int64) *memBudget {
+	if limit <= 0 {
+		return nil
+	}
+	m := &memBudget{limit: limit}
+	m.cond = sync.NewCond(&m.mu)
+	return m
+}
+
+// acquire blocks until n bytes fit and returns the amount to release.
+func (m *memBudget) acquire(n int64) int64 {
+	if m == nil {
+		return 0
+	}
+	n = min(n, m.limit)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for m.running > 0 && m.used+n > m.limit {
+		m.cond.Wait()
+	}
+	m.used += n
+	m.running++
+	return n
+}
+
+func (m *memBudget) release(n int64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.used -= n
+	m.running--
+	m.mu.Unlock()
+	m.cond.Broadcast()
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/qntx/gox/internal/build"
+)
+
+func TestDedupeOptions(t *testing.T) {
+	opts := []*build.Options{
+		{GOOS: "linux", GOARCH: "amd64", Output: "a"},
+		{GOOS: "linux", GOARCH: "amd64", Output: "a"},
+		{GOOS: "linux", GOARCH: "amd64", Output: "b"},
+		{GOOS: "linux", GOARCH: "amd64", Output: "a", LinkMode: build.LinkAuto},
+	}
+	got, dups := dedupeOptions(opts)
+	if len(got) != 2 {
+		t.Errorf("len(unique) = %d, want 2", len(got))
+	}
+	if dups != 2 {
+		t.Errorf("dups = %d, want 2", dups)
+	}
+}
+
+func TestNewFetchPlan(t *testing.T) {
+	opts := []*build.Options{
+		{ZigVersion: "0.15.2", Packages: []string{"b", "a", "c"}},
+		{ZigVersion: "0.15.2", Packages: []string{"a", "b", "c"}},
+		{ZigVersion: "master", Packages: []string{"a"}},
+		{ZigVersion: "master"},
+	}
+	plan := newFetchPlan(opts)
+
+	if len(plan.zigVersions) != 2 {
+		t.Errorf("zigVersions = %v, want 2 versions", plan.zigVersions)
+	}
+	if len(plan.sets) != 2 {
+		t.Fatalf("len(sets) = %d, want 2", len(plan.sets))
+	}
+	if len(plan.sets[0].packages) != 1 {
+		t.Errorf("first set = %v, want smallest set first", plan.sets[0].packages)
+	}
+
+	// A target without packages never waits on downloads.
+	if err := plan.wait(context.Background(), opts[3]); err != nil {
+		t.Errorf("wait() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := plan.wait(ctx, opts[0]); err == nil {
+		t.Error("wait() should return when ctx is canceled before fetch completes")
+	}
+}
+
+func TestBuildSlots(t *testing.T) {
+	tests := []struct {
+		n, jobs int
+		want    int
+	}{
+		{1, 0, 1},
+		{1, 4, 1},
+		{10, 4, 4},
+		{3, 4, 3},
+		{10, 1, 1},
+	}
+	for _, tt := range tests {
+		if got := buildSlots(tt.n, tt.jobs); got != tt.want {
+			t.Errorf("buildSlots(%d, %d) = %d, want %d", tt.n, tt.jobs, got, tt.want)
+		}
+	}
+	if got := buildSlots(1000, 0); got < 1 || got > 1000 {
+		t.Errorf("buildSlots(1000, 0) = %d, want within [1, 1000]", got)
+	}
+}
+
+func TestMemBudget(t *testing.T) {
+	var nilBudget *memBudget
+	nilBudget.release(nilBudget.acquire(1 << 40))
+
+	m := newMemBudget(100)
+	a := m.acquire(60)
+
+	// An oversized target is clamped and waits to run alone.
+	done := make(chan int64)
+	go func() { done <- m.acquire(500) }()
+	select {
+	case <-done:
+		t.Fatal("acquire() should block while the budget is in use")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	m.release(a)
+	if got := <-done; got != 100 {
+		t.Errorf("acquire(500) = %d, want clamped to 100", got)
+	}
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/sdk"
+	"github.com/qntx/gox/internal/ui"
+)
+
+var (
+	sdkCmd = &cobra.Command{
+		Use:   "sdk",
+		Short: "Manage macOS SDKs for darwin CGO targets",
+		Long: `Darwin targets whose cgo code uses Apple frameworks (CoreFoundation,
+Security, ...) need a macOS SDK, which Zig does not ship. Register a local
+MacOSX.sdk, e.g. copied from Xcode, and darwin builds use the newest
+registered SDK unless macos-sdk in gox.toml or $SDKROOT names another.`,
+		PersistentPreRunE: useConfigCache,
+	}
+
+	sdkAddCmd = &cobra.Command{
+		Use:   "add <path|url>",
+		Short: "Register a MacOSX.sdk directory or download one",
+		Long: `Register the macOS SDK at path under its version, read from
+SDKSettings.json or a MacOSX<version>.sdk name. A URL is downloaded and
+extracted into the gox cache first.`,
+		Example: `  gox sdk add ~/sdks/MacOSX14.5.sdk
+  gox sdk add https://example.com/MacOSX14.5.sdk.tar.xz`,
+		Args: cobra.ExactArgs(1),
+		RunE: runSDKAdd,
+	}
+
+	sdkListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List registered macOS SDKs",
+		Args:  cobra.NoArgs,
+		RunE:  runSDKList,
+	}
+
+	sdkRemoveCmd = &cobra.Command{
+		Use:               "remove <version>",
+		Short:             "Unregister a macOS SDK",
+		Long:              `Unregister a macOS SDK. Downloaded SDKs are deleted; registered directories are kept.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeSDKVersions,
+		RunE:              runSDKRemove,
+	}
+)
+
+func init() {
+	sdkCmd.AddCommand(sdkAddCmd, sdkListCmd, sdkRemoveCmd)
+	rootCmd.AddCommand(sdkCmd)
+}
+
+func runSDKAdd(cmd *cobra.Command, args []string) error {
+	s, err := sdk.Add(cmd.Context(), args[0])
+	if err != nil {
+		return err
+	}
+	ui.Success("Registered macOS SDK %s", s.Version)
+	ui.Label("path", s.Path)
+	return nil
+}
+
+func runSDKList(_ *cobra.Command, _ []string) error {
+	sdks, err := sdk.List()
+	if err != nil {
+		return err
+	}
+	if len(sdks) == 0 {
+		ui.Info("No macOS SDKs registered (gox sdk add <path>)")
+		return nil
+	}
+
+	ui.Header("Registered macOS SDKs")
+	tbl := ui.NewTable("VERSION", "PATH")
+	for _, s := range sdks {
+		tbl.AddRow(s.Version, s.Path)
+	}
+	tbl.Render()
+	return nil
+}
+
+func runSDKRemove(_ *cobra.Command, args []string) error {
+	if err := sdk.Remove(args[0]); err != nil {
+		return err
+	}
+	ui.Success("Removed macOS SDK %s", args[0])
+	return nil
+}
+
+// completeSDKVersions offers registered SDK versions as the first argument.
+func completeSDKVersions(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	useCompletionCache()
+	sdks, err := sdk.List()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	versions := make([]string, len(sdks))
+	for i, s := range sdks {
+		versions[i] = s.Version
+	}
+	return versions, cobra.ShellCompDirectiveNoFileComp
+}
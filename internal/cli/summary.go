@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/ui"
+)
+
+// targetState is the outcome of one target of a build.
+type targetState int
+
+const (
+	statePending targetState = iota
+	stateRunning
+	stateBuilt
+	stateFailed
+	stateCancelled
+)
+
+func (s targetState) String() string {
+	switch s {
+	case stateRunning:
+		return "running"
+	case stateBuilt:
+		return "built"
+	case stateFailed:
+		return "failed"
+	case stateCancelled:
+		return "cancelled"
+	}
+	return "not started"
+}
+
+func (s targetState) status() ui.Status {
+	switch s {
+	case stateBuilt:
+		return ui.StatusOK
+	case stateFailed:
+		return ui.StatusFailed
+	case stateCancelled:
+		return ui.StatusWarn
+	}
+	return ui.StatusMuted
+}
+
+type targetResult struct {
+	opts    *build.Options
+	state   targetState
+	started time.Time
+	removed []string        // torn artifacts deleted after cancellation
+	libs    build.CopyStats // shared libraries copied into the prefix
+}
+
+// buildSummary tracks every target of a build so the end of a run can
+// report what completed, the libraries each target ships and, when
+// interrupted, which artifacts on disk can be trusted.
+type buildSummary struct {
+	mu      sync.Mutex
+	results []*targetResult
+}
+
+func newBuildSummary(opts []*build.Options) *buildSummary {
+	s := &buildSummary{results: make([]*targetResult, len(opts))}
+	for i, o := range opts {
+		s.results[i] = &targetResult{opts: o}
+	}
+	return s
+}
+
+func (s *buildSummary) start(i int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[i].state = stateRunning
+	s.results[i].started = time.Now()
+}
+
+// copied records the libraries target i copied into its prefix.
+func (s *buildSummary) copied(i int, libs build.CopyStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[i].libs = libs
+}
+
+// emitTargetStart reports a target whose options are normalized and valid
+// as started, for --json output.
+func emitTargetStart(o *build.Options) {
+	ui.Emit(ui.Event{Type: ui.EventTargetStart, Target: targetLabel(o), OS: o.GOOS, Arch: o.GOARCH})
+}
+
+// finish records the outcome of target i. A failure caused by ctx being
+// cancelled counts as cancelled, and artifacts written since the target
+// started are removed because they may be truncated.
+func (s *buildSummary) finish(ctx context.Context, i int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.results[i]
+	switch {
+	case err == nil:
+		r.state = stateBuilt
+	case ctx.Err() != nil:
+		r.state = stateCancelled
+		r.removed = removeTorn(r.started, artifacts(r.opts)...)
+	default:
+		r.state = stateFailed
+	}
+	e := ui.Event{
+		Type:       ui.EventTargetFinish,
+		Target:     targetLabel(r.opts),
+		OS:         r.opts.GOOS,
+		Arch:       r.opts.GOARCH,
+		Status:     r.state.String(),
+		DurationMS: time.Since(r.started).Milliseconds(),
+	}
+	if r.state == stateBuilt {
+		e.Artifacts = artifacts(r.opts)
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	ui.Emit(e)
+}
+
+// cancel marks targets that never finished as cancelled.
+func (s *buildSummary) cancel() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.results {
+		if r.state == statePending || r.state == stateRunning {
+			r.state = stateCancelled
+		}
+	}
+}
+
+// render prints one row per target with the libraries it copied and, for an
+// interrupted build, the artifacts that are safe to use.
+func (s *buildSummary) render(interrupted bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if interrupted {
+		// Finish any torn progress line before the table.
+		fmt.Fprintln(ui.Stderr)
+		ui.Header("Build interrupted")
+	} else {
+		ui.Header("Build summary")
+	}
+
+	tbl := ui.NewTable("TARGET", "STATUS", "LIBS", "ARTIFACT")
+	counts := map[targetState]int{}
+	var (
+		valid []string
+		libs  build.CopyStats
+	)
+	for _, r := range s.results {
+		counts[r.state]++
+		artifact := "-"
+		switch {
+		case r.state == stateBuilt:
+			paths := artifacts(r.opts)
+			if len(paths) > 0 {
+				artifact = paths[len(paths)-1]
+			}
+			valid = append(valid, paths...)
+		case len(r.removed) > 0:
+			artifact = fmt.Sprintf("removed partial %s", r.removed[0])
+		}
+		libs.Files += r.libs.Files
+		libs.Bytes += r.libs.Bytes
+		tbl.AddStatusRow(r.state.status(), targetLabel(r.opts), r.state.String(), formatLibs(r.libs), artifact)
+	}
+	tbl.Render()
+
+	fmt.Fprintln(ui.Stderr)
+	ui.Label("built", fmt.Sprint(counts[stateBuilt]))
+	ui.Label("failed", fmt.Sprint(counts[stateFailed]))
+	ui.Label("cancelled", fmt.Sprint(counts[stateCancelled]))
+	if libs.Files > 0 {
+		ui.Label("libs", formatLibs(libs))
+	}
+	if !interrupted {
+		return
+	}
+	if len(valid) == 0 {
+		ui.Warn("No artifacts from this run are complete")
+		return
+	}
+	ui.Info("Complete artifacts from this run:")
+	for _, p := range valid {
+		fmt.Fprintf(ui.Stderr, "  %s\n", p)
+	}
+}
+
+// formatLibs renders copied libraries as "3 (12.4 MB)", or "-" for none.
+func formatLibs(libs build.CopyStats) string {
+	if libs.Files == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d (%s)", libs.Files, ui.FormatSize(libs.Bytes))
+}
+
+func targetLabel(o *build.Options) string {
+	if o.Target != "" {
+		return o.Target
+	}
+	return o.GOOS + "/" + o.GOARCH
+}
+
+// artifacts returns the files a target produces: its binary and, when
+// packed, its archive.
+func artifacts(o *build.Options) []string {
+	var out []string
+	if o.Output != "" {
+		out = append(out, o.Output)
+	}
+	if o.Pack {
+		if p := o.ArchivePath(); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// removeTorn deletes regular files among paths modified at or after since.
+func removeTorn(since time.Time, paths ...string) []string {
+	var removed []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil || !info.Mode().IsRegular() || info.ModTime().Before(since) {
+			continue
+		}
+		if os.Remove(p) == nil {
+			removed = append(removed, p)
+		}
+	}
+	return removed
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/ui"
+)
+
+func TestBuildSummary_Finish(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old")
+	torn := filepath.Join(dir, "torn")
+	for _, p := range []string{old, torn} {
+		if err := os.WriteFile(p, []byte("x"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(old, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := []*build.Options{
+		{GOOS: "linux", GOARCH: "amd64", Output: filepath.Join(dir, "built")},
+		{GOOS: "linux", GOARCH: "arm64", Output: old},
+		{GOOS: "windows", GOARCH: "amd64", Output: torn},
+		{GOOS: "darwin", GOARCH: "arm64"},
+		{GOOS: "freebsd", GOARCH: "amd64"},
+	}
+	s := newBuildSummary(opts)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.start(0)
+	s.finish(ctx, 0, nil)
+	s.start(1)
+	s.finish(ctx, 1, errors.New("compile error"))
+
+	s.start(2)
+	s.results[2].started = time.Now().Add(-time.Minute)
+	cancel()
+	s.finish(ctx, 2, context.Canceled)
+	s.start(3)
+	s.cancel()
+
+	want := []targetState{stateBuilt, stateFailed, stateCancelled, stateCancelled, stateCancelled}
+	for i, r := range s.results {
+		if r.state != want[i] {
+			t.Errorf("results[%d].state = %v, want %v", i, r.state, want[i])
+		}
+	}
+	if _, err := os.Stat(torn); !os.IsNotExist(err) {
+		t.Error("artifact written by a cancelled target was not removed")
+	}
+	if _, err := os.Stat(old); err != nil {
+		t.Error("artifact from an earlier run was removed")
+	}
+	if len(s.results[2].removed) != 1 {
+		t.Errorf("removed = %v, want [%s]", s.results[2].removed, torn)
+	}
+}
+
+func TestArtifacts(t *testing.T) {
+	o := &build.Options{GOOS: "linux", GOARCH: "amd64", Output: "dist/app", Pack: true}
+	got := artifacts(o)
+	if len(got) != 2 || got[0] != "dist/app" || got[1] != o.ArchivePath() {
+		t.Errorf("artifacts() = %v, want [dist/app %s]", got, o.ArchivePath())
+	}
+	if got := artifacts(&build.Options{}); len(got) != 0 {
+		t.Errorf("artifacts(no output) = %v, want none", got)
+	}
+}
+
+func TestBuildSummary_RenderLibs(t *testing.T) {
+	var buf bytes.Buffer
+	old := ui.Stderr
+	ui.Stderr = &buf
+	t.Cleanup(func() { ui.Stderr = old })
+
+	s := newBuildSummary([]*build.Options{
+		{GOOS: "linux", GOARCH: "amd64"},
+		{GOOS: "windows", GOARCH: "amd64"},
+	})
+	for i, libs := range []build.CopyStats{{Files: 2, Bytes: 3 << 20}, {}} {
+		s.start(i)
+		s.copied(i, libs)
+		s.finish(context.Background(), i, nil)
+	}
+	s.render(false)
+
+	out := buf.String()
+	for _, want := range []string{"Build summary", formatLibs(build.CopyStats{Files: 2, Bytes: 3 << 20})} {
+		if !strings.Contains(out, want) {
+			t.Errorf("render() output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Complete artifacts") {
+		t.Errorf("render() listed artifacts of a build that was not interrupted:\n%s", out)
+	}
+}
+
+func TestFormatLibs(t *testing.T) {
+	if got := formatLibs(build.CopyStats{}); got != "-" {
+		t.Errorf("formatLibs(none) = %q, want -", got)
+	}
+	want := "3 (" + ui.FormatSize(1024) + ")"
+	if got := formatLibs(build.CopyStats{Files: 3, Bytes: 1024}); got != want {
+		t.Errorf("formatLibs() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/workspace"
+)
+
+type sysrootExecFlags struct {
+	arch string
+	libc string
+	lib  []string
+}
+
+var (
+	sxFlags        sysrootExecFlags
+	sysrootExecCmd = &cobra.Command{
+		Use:   "sysroot-exec [flags] BINARY [ARGS...]",
+		Short: "Run a linux binary inside an assembled sysroot",
+		Long: `Sysroot-exec assembles a minimal root filesystem for BINARY from the
+given library directories and libc root, and runs it there with bwrap or
+qemu-user. 'gox test --sysroot' passes it to go test as -exec.`,
+		Args:         cobra.MinimumNArgs(1),
+		Hidden:       true,
+		SilenceUsage: true,
+		RunE:         runSysrootExec,
+	}
+)
+
+func init() {
+	f := sysrootExecCmd.Flags()
+	f.SetInterspersed(false)
+
+	f.StringVar(&sxFlags.arch, "arch", "", "architecture (GOARCH) of the binary")
+	f.StringVar(&sxFlags.libc, "libc", "/", "root filesystem providing the loader and libc")
+	f.StringArrayVar(&sxFlags.lib, "lib", nil, "package library directories")
+	_ = sysrootExecCmd.MarkFlagRequired("arch")
+
+	rootCmd.AddCommand(sysrootExecCmd)
+}
+
+func runSysrootExec(cmd *cobra.Command, args []string) error {
+	dir, err := workspace.MkdirTemp("sysroot-*")
+	if err != nil {
+		return fmt.Errorf("temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	sr := &build.Sysroot{GOARCH: sxFlags.arch, Libc: sxFlags.libc, LibDirs: sxFlags.lib}
+	return runInSysroot(cmd.Context(), sr, args[0], args[1:], dir)
+}
+
+// runInSysroot assembles binary's sysroot in dir and runs it there.
+func runInSysroot(ctx context.Context, sr *build.Sysroot, binary string, args []string, dir string) error {
+	bin, err := sr.Assemble(binary, dir)
+	if err != nil {
+		return fmt.Errorf("sysroot: %w", err)
+	}
+	c, err := sr.Command(ctx, dir, bin, args)
+	if err != nil {
+		return fmt.Errorf("sysroot: %w", err)
+	}
+	return runProgram(c)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/hosttool"
 	"github.com/qntx/gox/internal/ui"
 	"github.com/qntx/gox/internal/zig"
 )
@@ -16,6 +17,8 @@ type testFlags struct {
 	config   string
 	target   string
 	linkMode string
+	exec     string
+	dryRun   bool
 	opts     build.Options
 }
 
@@ -31,6 +34,16 @@ enabling CGO testing without manual environment setup.
 
 Arguments after -- are passed directly to the test binary.
 
+Test binaries of another GOOS/GOARCH run through an emulator when one is
+found: qemu-user (qemu-<arch> or binfmt_misc) for linux targets on linux
+hosts, wine for windows targets. --exec names any other program to run
+them with, as 'go test -exec' does.
+
+With --sysroot, linux test binaries of any architecture run inside a minimal
+root filesystem holding only the binary, its package libraries and the libc
+of the configured sysroot, via bwrap or qemu-user, so missing shared
+libraries and libc mismatches fail the test run.
+
 Configuration can be loaded from gox.toml. When using config, only the target
 matching the current platform (or specified by --target) is used.`,
 		RunE: runTest,
@@ -41,15 +54,21 @@ func init() {
 	f := testCmd.Flags()
 
 	f.StringVarP(&tFlags.config, "config", "c", "", "config file path (default: gox.toml)")
-	f.StringVarP(&tFlags.target, "target", "t", "", "target name from config (must match current platform)")
+	f.StringVarP(&tFlags.target, "target", "t", "", "target name from config (current platform, or one an emulator runs)")
+	_ = testCmd.RegisterFlagCompletionFunc("target", completeTargets)
 	f.StringVar(&tFlags.opts.ZigVersion, "zig-version", "", "zig compiler version")
+	f.StringVar(&tFlags.opts.GoVersion, "go-version", "", "go toolchain version (via GOTOOLCHAIN)")
 	f.StringVar(&tFlags.linkMode, "linkmode", "", "link mode: static|dynamic|auto")
 	f.StringSliceVarP(&tFlags.opts.IncludeDirs, "include", "I", nil, "include directories")
 	f.StringSliceVarP(&tFlags.opts.LibDirs, "lib", "L", nil, "library directories")
 	f.StringSliceVarP(&tFlags.opts.Libs, "link", "l", nil, "libraries to link")
 	f.StringSliceVar(&tFlags.opts.Packages, "pkg", nil, "packages to download")
 	f.StringSliceVar(&tFlags.opts.BuildFlags, "flags", nil, "additional build flags")
+	f.StringVar(&tFlags.exec, "exec", "", "run test binaries using this program (go test -exec)")
+	f.BoolVar(&tFlags.opts.InSysroot, "sysroot", false, "run linux test binaries in a sysroot of their package libraries and libc (bwrap or qemu-user)")
+	testCmd.MarkFlagsMutuallyExclusive("exec", "sysroot")
 	f.BoolVarP(&tFlags.opts.Verbose, "verbose", "v", false, "verbose output")
+	f.BoolVarP(&tFlags.dryRun, "dry-run", "n", false, "print the go command and environment without running it")
 
 	rootCmd.AddCommand(testCmd)
 }
@@ -62,7 +81,20 @@ func runTest(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if err := validateTestTarget(opts); err != nil {
+	if opts.InSysroot {
+		opts.Normalize()
+		if _, err := build.NewSysroot(opts); err != nil {
+			return err
+		}
+	} else if err := testEmulator(opts); err != nil {
+		return err
+	}
+
+	if tFlags.dryRun {
+		return dryRun([]*build.Options{opts}, "test", pkgs, testArgs)
+	}
+
+	if err := hosttool.Require(build.RequiredTools([]*build.Options{opts})...); err != nil {
 		return err
 	}
 
@@ -94,6 +126,9 @@ func loadTestOptions(cmd *cobra.Command) (*build.Options, error) {
 	if err != nil && !errors.Is(err, build.ErrConfigNotFound) {
 		return nil, fmt.Errorf("config: %w", err)
 	}
+	if err := useProject(cfg); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
 
 	var opts *build.Options
 	if cfg != nil {
@@ -163,12 +198,35 @@ func validateTestTarget(opts *build.Options) error {
 	return nil
 }
 
+// testEmulator sets how test binaries of a target the host cannot execute
+// are run: the --exec program, or an emulator found for the target.
+func testEmulator(opts *build.Options) error {
+	if tFlags.exec != "" {
+		opts.TestExec = tFlags.exec
+		return nil
+	}
+	err := validateTestTarget(opts)
+	if err == nil {
+		return nil
+	}
+	opts.Normalize()
+	emu, emuErr := build.NewEmulator(opts)
+	if emuErr != nil {
+		return fmt.Errorf("%w: %w", err, emuErr)
+	}
+	opts.TestExec = emu.ExecFlag()
+	return nil
+}
+
 func applyTestFlagOverrides(cmd *cobra.Command, o *build.Options) {
 	changed := cmd.Flags().Changed
 
 	if changed("zig-version") {
 		o.ZigVersion = tFlags.opts.ZigVersion
 	}
+	if changed("go-version") {
+		o.GoVersion = tFlags.opts.GoVersion
+	}
 	if changed("linkmode") {
 		o.LinkMode = build.LinkMode(tFlags.linkMode)
 	}
@@ -190,9 +248,11 @@ func applyTestFlagOverrides(cmd *cobra.Command, o *build.Options) {
 	if changed("verbose") {
 		o.Verbose = tFlags.opts.Verbose
 	}
+	o.InSysroot = tFlags.opts.InSysroot
 
 	o.Output = ""
 	o.Prefix = ""
-	o.Pack = false
+	o.Pack, o.PackFormat = false, ""
 	o.NoRpath = false
+	o.BuildMode, o.PkgConfig = "", false
 }
@@ -228,7 +228,7 @@ func TestApplyTestFlagOverrides_ClearsInvalidFields(t *testing.T) {
 
 func TestTestCmd_Flags(t *testing.T) {
 	expectedFlags := []string{
-		"config", "target", "zig-version", "linkmode",
+		"config", "target", "zig-version", "go-version", "linkmode",
 		"include", "lib", "link", "pkg", "flags", "verbose",
 	}
 
@@ -0,0 +1,315 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/hosttool"
+	"github.com/qntx/gox/internal/lock"
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/workspace"
+	"github.com/qntx/gox/internal/zig"
+)
+
+type upgradeFlags struct {
+	config  string
+	dryRun  bool
+	noBuild bool
+}
+
+var (
+	upFlags    upgradeFlags
+	upgradeCmd = &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade the pinned Zig version and packages in gox.toml",
+		Long: `Upgrade moves a project's toolchain and packages forward together:
+
+  zig        an explicit zig-version is raised to the latest stable release;
+             with master, the snapshot pinned in gox.lock is renewed
+  packages   GitHub release packages (owner/repo@tag/asset) move to the
+             newest release with the same major version (minor for 0.x)
+             that publishes the same asset
+
+gox.toml is edited in place, keeping its comments and layout, gox.lock is
+updated for the new versions, and every target is then built into a
+temporary directory, so the result is one reviewable diff that is known to
+build. Packages pinned by #sha256 and values inherited through extends are
+reported but not changed. Set GITHUB_TOKEN to raise GitHub's API rate limit.`,
+		Example: `  gox upgrade
+  gox upgrade --dry-run
+  gox upgrade --no-build && git diff gox.toml gox.lock`,
+		Args: cobra.NoArgs,
+		RunE: runUpgrade,
+	}
+)
+
+func init() {
+	f := upgradeCmd.Flags()
+
+	f.StringVarP(&upFlags.config, "config", "c", "", "config file path (default: gox.toml)")
+	f.BoolVarP(&upFlags.dryRun, "dry-run", "n", false, "print the upgrades without changing anything")
+	f.BoolVar(&upFlags.noBuild, "no-build", false, "skip building every target after upgrading")
+
+	rootCmd.AddCommand(upgradeCmd)
+}
+
+// upgrades maps old config values to their replacements.
+type upgrades struct {
+	zig      map[string]string // zig-version values
+	packages map[string]string // package sources
+	master   bool              // renew the master snapshot in gox.lock
+}
+
+func (u *upgrades) empty() bool {
+	return len(u.zig) == 0 && len(u.packages) == 0 && !u.master
+}
+
+func runUpgrade(cmd *cobra.Command, _ []string) error {
+	cfg, err := build.LoadConfig(upFlags.config)
+	if errors.Is(err, build.ErrConfigNotFound) {
+		return fmt.Errorf("no %s found (run gox init)", build.ConfigFile)
+	}
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if err := useProject(cfg); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	path := upFlags.config
+	if path == "" {
+		path = filepath.Join(cfg.Dir(), build.ConfigFile)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	up, err := findUpgrades(cmd, cfg, data)
+	if err != nil {
+		return err
+	}
+	if up.empty() {
+		ui.Success("Zig and packages are up to date")
+		return nil
+	}
+	if upFlags.dryRun {
+		return nil
+	}
+
+	if err := os.WriteFile(path, rewriteConfig(data, up), 0o644); err != nil {
+		return err
+	}
+	if len(up.zig) > 0 || len(up.packages) > 0 {
+		ui.Success("Updated %s", path)
+	}
+	if l := lock.Active(); l != nil {
+		for old := range up.packages {
+			if err := l.RemovePackage(old); err != nil {
+				return err
+			}
+		}
+	}
+	if up.master {
+		if err := zig.Unpin("master"); err != nil {
+			return err
+		}
+	}
+
+	// Reload so targets see the new values, then fetch them into gox.lock.
+	if cfg, err = build.LoadConfig(path); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	opts, err := allTargets(cfg)
+	if err != nil {
+		return err
+	}
+	if err := fetchUpgrades(cmd, opts); err != nil {
+		return err
+	}
+	if !upFlags.noBuild {
+		if err := verifyUpgrade(cmd, opts); err != nil {
+			return fmt.Errorf("%w\n%s and %s are upgraded; review them with git diff", err, build.ConfigFile, lock.File)
+		}
+	}
+	ui.Success("Upgrade complete; review it with git diff %s %s", build.ConfigFile, lock.File)
+	return nil
+}
+
+// findUpgrades reports and returns the upgrades available for the zig
+// versions and packages cfg uses. data is the config file itself; values
+// only found through extends are reported but left out.
+func findUpgrades(cmd *cobra.Command, cfg *build.Config, data []byte) (*upgrades, error) {
+	ctx := cmd.Context()
+	up := &upgrades{zig: map[string]string{}, packages: map[string]string{}}
+	opts, err := allTargets(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions, sources []string
+	for _, o := range opts {
+		versions = append(versions, zig.ResolveVersion(o.ZigVersion))
+		sources = append(sources, o.Packages...)
+	}
+	slices.Sort(versions)
+	slices.Sort(sources)
+
+	var latest string
+	for _, v := range slices.Compact(versions) {
+		switch {
+		case v == "master":
+			up.master = lock.Active() != nil
+			if up.master {
+				ui.Info("zig master: renewing the snapshot pinned in %s", lock.File)
+			}
+		case zig.IsRelease(v):
+			if latest == "" {
+				if latest, err = zig.Latest(ctx); err != nil {
+					return nil, fmt.Errorf("zig: %w", err)
+				}
+			}
+			if zig.CompareVersions(latest, v) <= 0 {
+				continue
+			}
+			if !setsZigVersion(data, v) {
+				ui.Warn("zig %s: set through extends or %s, upgrade it there to %s", v, zig.VersionFile, latest)
+				continue
+			}
+			up.zig[v] = latest
+			ui.Info("zig %s → %s", v, latest)
+		}
+	}
+
+	for _, src := range slices.Compact(sources) {
+		newer, err := build.NewerRelease(ctx, src)
+		switch {
+		case errors.Is(err, build.ErrPinned):
+			ui.Warn("%s: %v, not upgraded", src, err)
+		case err != nil:
+			return nil, err
+		case newer == "":
+		case !quoted(data, src):
+			ui.Warn("%s: set through extends, upgrade it there to %s", src, newer)
+		default:
+			up.packages[src] = newer
+			ui.Info("%s → %s", src, newer)
+		}
+	}
+	return up, nil
+}
+
+// allTargets returns the options of every target and variant in cfg,
+// or its defaults when it has no targets.
+func allTargets(cfg *build.Config) ([]*build.Options, error) {
+	if len(cfg.Targets) == 0 {
+		return []*build.Options{cfg.DefaultOptions()}, nil
+	}
+	opts, err := cfg.ToOptions(nil)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	return opts, nil
+}
+
+// fetchUpgrades installs the zig versions and packages of opts, recording
+// them in gox.lock.
+func fetchUpgrades(cmd *cobra.Command, opts []*build.Options) error {
+	ctx := cmd.Context()
+	var sources []string
+	fetched := map[string]bool{}
+	for _, o := range opts {
+		if err := o.ResolvePackages(); err != nil {
+			return err
+		}
+		sources = append(sources, o.Packages...)
+		if fetched[o.ZigVersion] {
+			continue
+		}
+		fetched[o.ZigVersion] = true
+		if _, err := zig.Ensure(ctx, o.ZigVersion); err != nil {
+			return fmt.Errorf("zig: %w", err)
+		}
+	}
+	slices.Sort(sources)
+	if _, err := build.EnsureAll(ctx, slices.Compact(sources)); err != nil {
+		return fmt.Errorf("packages: %w", err)
+	}
+	return nil
+}
+
+// verifyUpgrade builds every target the host can build into a temporary
+// directory.
+func verifyUpgrade(cmd *cobra.Command, opts []*build.Options) error {
+	opts, err := filterBuildable(opts)
+	if err != nil {
+		return err
+	}
+	if err := hosttool.Require(build.RequiredTools(opts)...); err != nil {
+		return err
+	}
+	tmpDir, err := workspace.MkdirTemp("upgrade-*")
+	if err != nil {
+		return fmt.Errorf("temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for i, o := range opts {
+		binName := "main"
+		if o.GOOS == "windows" {
+			binName += ".exe"
+		}
+		o.Output = filepath.Join(tmpDir, strconv.Itoa(i), binName)
+		o.Prefix, o.Pack, o.PackFormat, o.Checksum, o.DepsReport, o.PkgConfig = "", false, "", false, false, false
+		if _, err := executeBuild(cmd, nil, o, i, len(opts)); err != nil {
+			return fmt.Errorf("%s: %w", targetLabel(o), err)
+		}
+	}
+	ui.Success("Built %d target(s) with the upgrades", len(opts))
+	return nil
+}
+
+// zigVersionRE matches zig-version assignments, capturing the value.
+var zigVersionRE = regexp.MustCompile(`(?m)^(\s*zig-version\s*=\s*)(["'])([^"'\n]*)(["'])`)
+
+// rewriteConfig applies up to the text of a config file, so its comments
+// and layout survive.
+func rewriteConfig(data []byte, up *upgrades) []byte {
+	data = zigVersionRE.ReplaceAllFunc(data, func(m []byte) []byte {
+		sub := zigVersionRE.FindSubmatch(m)
+		newer, ok := up.zig[string(sub[3])]
+		if !ok {
+			return m
+		}
+		return []byte(string(sub[1]) + string(sub[2]) + newer + string(sub[4]))
+	})
+	s := string(data)
+	for old, newer := range up.packages {
+		s = strings.ReplaceAll(s, strconv.Quote(old), strconv.Quote(newer))
+		s = strings.ReplaceAll(s, "'"+old+"'", "'"+newer+"'")
+	}
+	return []byte(s)
+}
+
+// setsZigVersion reports whether data assigns v to zig-version.
+func setsZigVersion(data []byte, v string) bool {
+	for _, m := range zigVersionRE.FindAllSubmatch(data, -1) {
+		if string(m[3]) == v {
+			return true
+		}
+	}
+	return false
+}
+
+// quoted reports whether data holds s as a TOML string.
+func quoted(data []byte, s string) bool {
+	return strings.Contains(string(data), strconv.Quote(s)) || strings.Contains(string(data), "'"+s+"'")
+}
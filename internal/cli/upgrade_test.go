@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/lock"
+	"github.com/qntx/gox/internal/zig"
+)
+
+func TestRewriteConfig(t *testing.T) {
+	data := []byte(`# pinned toolchain
+[default]
+zig-version = "0.13.0" # keep in sync with CI
+packages = ["acme/lib@v1.2.0/lib-1.2.0.tar.gz", 'acme/tool@v2.0.0/tool.zip']
+
+[[target]]
+name = "old"
+zig-version = '0.12.0'
+packages = ["acme/other@v1.0.0/other.zip"]
+`)
+	up := &upgrades{
+		zig: map[string]string{"0.13.0": "0.14.1"},
+		packages: map[string]string{
+			"acme/lib@v1.2.0/lib-1.2.0.tar.gz": "acme/lib@v1.3.0/lib-1.3.0.tar.gz",
+			"acme/tool@v2.0.0/tool.zip":        "acme/tool@v2.1.0/tool.zip",
+		},
+	}
+	want := `# pinned toolchain
+[default]
+zig-version = "0.14.1" # keep in sync with CI
+packages = ["acme/lib@v1.3.0/lib-1.3.0.tar.gz", 'acme/tool@v2.1.0/tool.zip']
+
+[[target]]
+name = "old"
+zig-version = '0.12.0'
+packages = ["acme/other@v1.0.0/other.zip"]
+`
+	if got := string(rewriteConfig(data, up)); got != want {
+		t.Errorf("rewriteConfig() =\n%s\nwant\n%s", got, want)
+	}
+
+	if !setsZigVersion(data, "0.12.0") || setsZigVersion(data, "0.11.0") {
+		t.Error("setsZigVersion() does not match the zig-version values")
+	}
+	if !quoted(data, "acme/tool@v2.0.0/tool.zip") || quoted(data, "acme/lib") {
+		t.Error("quoted() does not match whole strings")
+	}
+}
+
+// A .zig-version release is what builds use, so upgrade must not renew the
+// master pin for a config that leaves zig-version unset.
+func TestFindUpgrades_ZigVersionFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"master": {"version": "0.16.0-dev.1"}, "0.15.2": {}, "0.14.1": {}}`))
+	}))
+	defer srv.Close()
+	t.Setenv(zig.MirrorEnv, srv.URL)
+
+	proj := t.TempDir()
+	data := []byte("[[target]]\nname = \"linux\"\nos = \"linux\"\narch = \"amd64\"\n")
+	for name, content := range map[string][]byte{"go.mod": nil, zig.VersionFile: []byte("0.15.2\n"), build.ConfigFile: data} {
+		if err := os.WriteFile(filepath.Join(proj, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(proj)
+	if err := lock.Use(filepath.Join(proj, lock.File)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = lock.Use("") })
+	cfg, err := build.LoadConfig(build.ConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &cobra.Command{}
+	cmd.SetContext(t.Context())
+	up, err := findUpgrades(cmd, cfg, data)
+	if err != nil {
+		t.Fatalf("findUpgrades() error = %v", err)
+	}
+	if up.master {
+		t.Error("findUpgrades() renews the master pin, want the .zig-version release left alone")
+	}
+	if len(up.zig) != 0 {
+		t.Errorf("zig upgrades = %v, want none", up.zig)
+	}
+}
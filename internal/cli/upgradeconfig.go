@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/convert"
+	"github.com/qntx/gox/internal/ui"
+)
+
+type upgradeConfigFlags struct {
+	from   string
+	output string
+	force  bool
+}
+
+var (
+	ucFlags          upgradeConfigFlags
+	upgradeConfigCmd = &cobra.Command{
+		Use:   "upgrade-config [file] [-- xgo flags]",
+		Short: "Generate gox.toml from another cross-build tool",
+		Long: `Translate an existing cross-build setup into an equivalent gox.toml.
+
+Supported sources:
+  goreleaser   builds section of .goreleaser.yaml
+  make         GOOS=/GOARCH= pairs in a Makefile
+  xgo          xgo command-line flags given after --
+
+The source is detected from the file name unless --from is set. Settings that
+cannot be translated are reported as warnings. Output goes to stdout unless
+--output is given.`,
+		Example: `  gox upgrade-config .goreleaser.yaml -o gox.toml
+  gox upgrade-config Makefile
+  gox upgrade-config --from xgo -- -targets=linux/amd64,windows/* -ldflags="-s -w"`,
+		RunE: runUpgradeConfig,
+	}
+)
+
+func init() {
+	f := upgradeConfigCmd.Flags()
+
+	f.StringVar(&ucFlags.from, "from", "", "source format: goreleaser|make|xgo")
+	f.StringVarP(&ucFlags.output, "output", "o", "", "write config to file instead of stdout")
+	f.BoolVarP(&ucFlags.force, "force", "f", false, "overwrite existing output file")
+
+	rootCmd.AddCommand(upgradeConfigCmd)
+}
+
+func runUpgradeConfig(cmd *cobra.Command, args []string) error {
+	res, err := importConfig(cmd, args)
+	if err != nil {
+		return err
+	}
+	for _, n := range res.Notes {
+		ui.Warn("%s", n)
+	}
+
+	var buf bytes.Buffer
+	if err := res.Config.Encode(&buf); err != nil {
+		return err
+	}
+
+	if ucFlags.output == "" {
+		_, err := ui.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if _, err := os.Stat(ucFlags.output); err == nil && !ucFlags.force {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", ucFlags.output)
+	}
+	if err := os.WriteFile(ucFlags.output, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	ui.Success("Wrote %s with %d target(s)", ucFlags.output, len(res.Config.Targets))
+	return nil
+}
+
+func importConfig(cmd *cobra.Command, args []string) (*convert.Result, error) {
+	from := convert.Source(ucFlags.from)
+	if from == convert.Xgo {
+		if at := cmd.ArgsLenAtDash(); at >= 0 {
+			args = args[at:]
+		}
+		return convert.FromXgo(args)
+	}
+
+	if len(args) != 1 {
+		return nil, errors.New("expected exactly one source file")
+	}
+	if from == "" {
+		var err error
+		if from, err = convert.DetectSource(args[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return nil, err
+	}
+	switch from {
+	case convert.GoReleaser:
+		return convert.FromGoReleaser(data)
+	case convert.Makefile:
+		return convert.FromMakefile(data)
+	}
+	return nil, fmt.Errorf("unsupported source %q", from)
+}
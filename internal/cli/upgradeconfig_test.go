@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestUpgradeConfigCmd_Flags(t *testing.T) {
+	for _, name := range []string{"from", "output", "force"} {
+		if upgradeConfigCmd.Flags().Lookup(name) == nil {
+			t.Errorf("missing flag: %s", name)
+		}
+	}
+}
+
+func TestImportConfig(t *testing.T) {
+	oldFlags := ucFlags
+	defer func() { ucFlags = oldFlags }()
+
+	t.Run("detects makefile", func(t *testing.T) {
+		ucFlags = upgradeConfigFlags{}
+		path := filepath.Join(t.TempDir(), "Makefile")
+		if err := os.WriteFile(path, []byte("all:\n\tGOOS=linux GOARCH=arm64 go build\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		res, err := importConfig(&cobra.Command{}, []string{path})
+		if err != nil {
+			t.Fatalf("importConfig() error = %v", err)
+		}
+		if len(res.Config.Targets) != 1 || res.Config.Targets[0].Arch != "arm64" {
+			t.Errorf("Targets = %+v", res.Config.Targets)
+		}
+	})
+
+	t.Run("requires file", func(t *testing.T) {
+		ucFlags = upgradeConfigFlags{from: "goreleaser"}
+		if _, err := importConfig(&cobra.Command{}, nil); err == nil {
+			t.Error("importConfig() should fail without a file")
+		}
+	})
+
+	t.Run("xgo args", func(t *testing.T) {
+		ucFlags = upgradeConfigFlags{from: "xgo"}
+		res, err := importConfig(&cobra.Command{}, []string{"-targets=darwin/amd64"})
+		if err != nil {
+			t.Fatalf("importConfig() error = %v", err)
+		}
+		if len(res.Config.Targets) != 1 || res.Config.Targets[0].OS != "darwin" {
+			t.Errorf("Targets = %+v", res.Config.Targets)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/ui"
+)
+
+var (
+	vendorConfig string
+	vendorCmd    = &cobra.Command{
+		Use:   "vendor",
+		Short: "Copy the packages of gox.toml into third_party/gox",
+		Long: `Vendor copies the exact packages every target in gox.toml uses into
+third_party/gox next to it, named as in the package cache, with a
+manifest.toml recording each package's source, URL, archive digest and the
+digest of its vendored files. Packages no target uses any more are removed.
+
+Commit the directory and set vendor = true in [default]: builds then
+resolve packages from third_party/gox before the cache, so they need no
+network access for them. Run gox vendor again after changing packages.`,
+		Example: `  gox vendor
+  git add third_party/gox`,
+		Args: cobra.NoArgs,
+		RunE: runVendor,
+	}
+
+	// pkgVendorCmd is gox vendor under gox pkg, next to the other commands
+	// managing packages.
+	pkgVendorCmd = &cobra.Command{
+		Use:     "vendor",
+		Short:   vendorCmd.Short,
+		Long:    vendorCmd.Long,
+		Example: "  gox pkg vendor\n  git add third_party/gox",
+		Args:    cobra.NoArgs,
+		RunE:    runVendor,
+	}
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{vendorCmd, pkgVendorCmd} {
+		cmd.Flags().StringVarP(&vendorConfig, "config", "c", "", "config file path (default: gox.toml)")
+	}
+	rootCmd.AddCommand(vendorCmd)
+	pkgCmd.AddCommand(pkgVendorCmd)
+}
+
+func runVendor(cmd *cobra.Command, _ []string) error {
+	cfg, err := build.LoadConfig(vendorConfig)
+	if errors.Is(err, build.ErrConfigNotFound) {
+		return fmt.Errorf("no %s found (run gox init)", build.ConfigFile)
+	}
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if err := useProject(cfg); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	opts, err := allTargets(cfg)
+	if err != nil {
+		return err
+	}
+	var sources []string
+	for _, o := range opts {
+		if err := o.ResolvePackages(); err != nil {
+			return err
+		}
+		sources = append(sources, o.Packages...)
+	}
+	slices.Sort(sources)
+	sources = slices.Compact(sources)
+
+	dir := filepath.Join(cfg.Dir(), build.VendorDir)
+	old, _ := build.ReadVendorManifest(dir)
+	vendored, err := build.Vendor(cmd.Context(), sources, dir)
+	if err != nil {
+		return fmt.Errorf("vendor: %w", err)
+	}
+	for _, o := range old {
+		if !slices.ContainsFunc(vendored, func(v build.Vendored) bool { return v.Dir == o.Dir }) {
+			ui.Info("Removed %s", o.Source)
+		}
+	}
+	ui.Success("Vendored %d package(s) in %s", len(vendored), dir)
+	if !cfg.Default.Vendor && len(vendored) > 0 {
+		ui.Info("Set vendor = true in [default] to build from them")
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/hosttool"
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/watch"
+	"github.com/qntx/gox/internal/workspace"
+	"github.com/qntx/gox/internal/zig"
+)
+
+// stopDelay is how long a watched program may take to exit after an
+// interrupt before it is killed.
+const stopDelay = 5 * time.Second
+
+// newWatcher returns a watcher of the module containing the working
+// directory that skips the config's watch-ignore patterns and ignore.
+func newWatcher(config string, ignore []string) (*watch.Watcher, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := build.LoadConfig(config)
+	if err != nil && !errors.Is(err, build.ErrConfigNotFound) {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	w := &watch.Watcher{Root: moduleRoot(wd), Ignore: ignore}
+	if cfg != nil {
+		w.Ignore = slices.Concat(cfg.Default.WatchIgnore, ignore)
+	}
+	return w, nil
+}
+
+// watchBuild builds the selected targets, then again after each change,
+// until interrupted. Build failures are reported and wait for the next
+// change.
+func watchBuild(cmd *cobra.Command, args []string) error {
+	w, err := newWatcher(flags.config, flags.ignore)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return w.Run(ctx, func(ctx context.Context) error {
+		cmd.SetContext(ctx)
+		return buildTargets(cmd, args)
+	})
+}
+
+// watchRun builds and starts the program, stopping, rebuilding and
+// restarting it after each change until interrupted. Options are reloaded
+// every time so gox.toml edits apply.
+func watchRun(cmd *cobra.Command, pkgs, progArgs []string) error {
+	w, err := newWatcher(rFlags.config, rFlags.ignore)
+	if err != nil {
+		return err
+	}
+	w.Restart = true
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return w.Run(ctx, func(ctx context.Context) error {
+		return runOnce(ctx, cmd, pkgs, progArgs)
+	})
+}
+
+// runOnce builds the program to a temporary binary and runs it until it
+// exits or ctx is canceled. Unlike a plain run, a failing program does not
+// end gox.
+func runOnce(ctx context.Context, cmd *cobra.Command, pkgs, progArgs []string) error {
+	opts, err := loadRunOptions(cmd)
+	if err != nil {
+		return err
+	}
+	emu, err := runEmulator(opts)
+	if err != nil {
+		return err
+	}
+	if err := hosttool.Require(build.RequiredTools([]*build.Options{opts})...); err != nil {
+		return err
+	}
+	opts.Normalize()
+	zigPath, err := zig.Ensure(ctx, opts.ZigVersion)
+	if err != nil {
+		return fmt.Errorf("zig: %w", err)
+	}
+
+	tmpDir, err := workspace.MkdirTemp("watch-*")
+	if err != nil {
+		return fmt.Errorf("temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	binName := "main"
+	if opts.GOOS == "windows" {
+		binName += ".exe"
+	}
+	opts.Output = filepath.Join(tmpDir, binName)
+	if err := build.New(zigPath, opts).Run(ctx, pkgs); err != nil {
+		return err
+	}
+
+	var prog *exec.Cmd
+	switch {
+	case emu != nil:
+		if err := emu.Check(opts.Output); err != nil {
+			return err
+		}
+		emu.DLLDirs = opts.BinDirs
+		prog = emu.Command(ctx, opts.Output, progArgs)
+	case rFlags.exec != "":
+		prog = exec.CommandContext(ctx, rFlags.exec, append([]string{opts.Output}, progArgs...)...)
+	default:
+		prog = exec.CommandContext(ctx, opts.Output, progArgs...)
+	}
+	prog.Stdin = os.Stdin
+	prog.Stdout = ui.Stdout
+	prog.Stderr = ui.Stderr
+	// Let the program shut down cleanly before it is restarted.
+	prog.Cancel = func() error {
+		if runtime.GOOS == "windows" {
+			return prog.Process.Kill()
+		}
+		return prog.Process.Signal(os.Interrupt)
+	}
+	prog.WaitDelay = stopDelay
+
+	err = prog.Run()
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/worker"
+)
+
+type workerFlags struct {
+	listen string
+	jobs   int
+}
+
+var (
+	wkFlags   workerFlags
+	workerCmd = &cobra.Command{
+		Use:   "worker",
+		Short: "Serve builds for distributed gox build --workers (experimental)",
+		Long: `Worker runs builds for coordinators started with 'gox build --workers'.
+Each job ships the coordinator's source tree; the worker runs gox build on
+it with its own zig toolchain and package cache and sends back the build
+output and every file the build wrote.
+
+Workers run arbitrary builds for anyone who can reach them. Set a shared
+secret in ` + worker.TokenEnv + ` on both the worker and the coordinators,
+and only listen on networks you trust. Without the secret, a worker only
+listens on a loopback address.`,
+		Example: `  GOX_WORKER_TOKEN=secret gox worker --listen :7078 --jobs 2`,
+		Args:    cobra.NoArgs,
+		RunE:    runWorker,
+	}
+)
+
+func init() {
+	f := workerCmd.Flags()
+
+	f.StringVar(&wkFlags.listen, "listen", "127.0.0.1:"+worker.DefaultPort, "listen address")
+	f.IntVar(&wkFlags.jobs, "jobs", 1, "builds to run at once")
+
+	rootCmd.AddCommand(workerCmd)
+}
+
+func runWorker(cmd *cobra.Command, _ []string) error {
+	token := os.Getenv(worker.TokenEnv)
+	if token == "" && !loopback(wkFlags.listen) {
+		return fmt.Errorf("%s is not set: refusing to let any client reaching %s run builds", worker.TokenEnv, wkFlags.listen)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	ui.Info("gox worker listening on %s (%d job(s) at a time)", wkFlags.listen, max(1, wkFlags.jobs))
+	return worker.NewServer(exe, token, wkFlags.jobs).ListenAndServe(ctx, wkFlags.listen)
+}
+
+// loopback reports whether addr only accepts local connections.
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -12,16 +12,20 @@ import (
 
 var (
 	zigCmd = &cobra.Command{
-		Use:   "zig",
-		Short: "Manage Zig compiler installations",
+		Use:               "zig",
+		Short:             "Manage Zig compiler installations",
+		PersistentPreRunE: useConfigCache,
 	}
 
 	zigUpdateCmd = &cobra.Command{
 		Use:   "update [version]",
 		Short: "Update or install a Zig version",
 		Long: `Download and install a Zig compiler version.
-If no version is specified, updates the 'master' version to latest.
-Use --force to re-download even if already installed.`,
+If no version is specified, updates the version in the project's
+.zig-version, or else 'master' to the latest snapshot.
+Use --force to re-download even if already installed.
+When gox.lock pins a master snapshot, --force also re-pins master to the
+latest snapshot.`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: runZigUpdate,
 	}
@@ -37,8 +41,9 @@ Use --force to re-download even if already installed.`,
 		Short: "Remove cached Zig installations",
 		Long: `Remove cached Zig compiler installations.
 If no version is specified, removes all cached versions.`,
-		Args: cobra.MaximumNArgs(1),
-		RunE: runZigClean,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeZigVersions,
+		RunE:              runZigClean,
 	}
 )
 
@@ -49,15 +54,27 @@ func init() {
 	rootCmd.AddCommand(zigCmd)
 }
 
-func runZigUpdate(cmd *cobra.Command, args []string) error {
-	version := "master"
+// zigUpdateVersion returns the version gox zig update installs: the one
+// given, else the one builds use.
+func zigUpdateVersion(args []string) string {
 	if len(args) > 0 {
-		version = args[0]
+		return args[0]
 	}
+	return zig.ResolveVersion("")
+}
+
+func runZigUpdate(cmd *cobra.Command, args []string) error {
+	version := zigUpdateVersion(args)
 	force, _ := cmd.Flags().GetBool("force")
+	if zig.System != "" {
+		ui.Info("zig-path is set; checking the system zig instead of downloading")
+	}
 
 	if force {
 		_ = zig.Remove(version)
+		if err := zig.Unpin(version); err != nil {
+			return err
+		}
 	}
 
 	path, err := zig.Ensure(cmd.Context(), version)
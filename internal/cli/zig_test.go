@@ -1,6 +1,12 @@
 package cli
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qntx/gox/internal/zig"
+)
 
 func TestZigCmd_Subcommands(t *testing.T) {
 	subcommands := []string{"update", "list", "clean"}
@@ -64,3 +70,23 @@ func TestZigListCmd_NoArgs(t *testing.T) {
 		t.Errorf("Use = %q, want 'list'", zigListCmd.Use)
 	}
 }
+
+func TestZigUpdateVersion(t *testing.T) {
+	proj := t.TempDir()
+	if err := os.WriteFile(filepath.Join(proj, "go.mod"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(proj)
+	if got := zigUpdateVersion(nil); got != "master" {
+		t.Errorf("zigUpdateVersion() = %q, want master", got)
+	}
+	if err := os.WriteFile(filepath.Join(proj, zig.VersionFile), []byte("0.14.1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := zigUpdateVersion(nil); got != "0.14.1" {
+		t.Errorf("zigUpdateVersion() = %q, want 0.14.1 from %s", got, zig.VersionFile)
+	}
+	if got := zigUpdateVersion([]string{"0.13.0"}); got != "0.13.0" {
+		t.Errorf("zigUpdateVersion(0.13.0) = %q, want 0.13.0", got)
+	}
+}
@@ -0,0 +1,278 @@
+package convert
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/qntx/gox/internal/build"
+)
+
+// ErrNoCgo is returned when a module has no #cgo directives.
+var ErrNoCgo = errors.New("no #cgo directives found")
+
+// knownOS and knownArch mirror the GOOS/GOARCH values accepted in file name
+// suffixes and #cgo constraints.
+var (
+	knownOS = []string{
+		"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos", "ios", "js",
+		"linux", "nacl", "netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows", "zos",
+	}
+	knownArch = []string{
+		"386", "amd64", "arm", "arm64", "loong64", "mips", "mips64", "mips64le", "mipsle",
+		"ppc64", "ppc64le", "riscv64", "s390x", "wasm",
+	}
+)
+
+// cgoScope is the os/arch a directive applies to; empty fields match any.
+type cgoScope struct{ os, arch string }
+
+// cgoNeeds collects the libraries and paths one scope asks for.
+type cgoNeeds struct {
+	link, include, lib []string
+}
+
+// FromCgo scans the Go files of the module at root for #cgo directives and
+// proposes the link, include and lib settings that satisfy them: global
+// ones in [default] and os- or os/arch-constrained ones as targets.
+// pkg-config names only produce a note, since module names are not library
+// names and the host pkg-config cannot describe cross targets.
+func FromCgo(root string) (*Result, error) {
+	res := &Result{Config: &build.Config{}}
+	needs := map[cgoScope]*cgoNeeds{}
+	found := false
+
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if p != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+				name == "vendor" || name == "testdata" || isModule(p)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(p, ".go") || strings.HasSuffix(p, "_test.go") {
+			return nil
+		}
+		f, err := parser.ParseFile(fset, p, nil, parser.ImportsOnly|parser.ParseComments)
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, filepath.Dir(p))
+		fileScope := scopeFromName(filepath.Base(p))
+		for _, imp := range f.Imports {
+			if imp.Path.Value != `"C"` {
+				continue
+			}
+			doc := imp.Doc
+			if doc == nil {
+				doc = importDeclDoc(f, imp.Pos())
+			}
+			if doc == nil {
+				continue
+			}
+			for line := range strings.SplitSeq(doc.Text(), "\n") {
+				if d, ok := strings.CutPrefix(strings.TrimSpace(line), "#cgo "); ok {
+					found = true
+					res.addDirective(needs, fileScope, rel, d, filepath.ToSlash(p))
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNoCgo
+	}
+
+	scopes := slices.SortedFunc(maps.Keys(needs), func(a, b cgoScope) int {
+		return strings.Compare(a.os+"/"+a.arch, b.os+"/"+b.arch)
+	})
+	for _, s := range scopes {
+		n := needs[s]
+		if s == (cgoScope{}) {
+			d := &res.Config.Default
+			d.Link, d.Include, d.Lib = n.link, n.include, n.lib
+			continue
+		}
+		name := s.os
+		if s.arch != "" {
+			name += "-" + s.arch
+		}
+		res.Config.Targets = append(res.Config.Targets, build.ConfigTarget{
+			Name: name, OS: s.os, Arch: s.arch,
+			Link: n.link, Include: n.include, Lib: n.lib,
+		})
+	}
+	return res, nil
+}
+
+// addDirective records one "#cgo [constraints] VAR: args" line.
+func (r *Result) addDirective(needs map[cgoScope]*cgoNeeds, file cgoScope, dir, directive, at string) {
+	head, args, ok := strings.Cut(directive, ":")
+	if !ok {
+		r.note("%s: malformed #cgo %s", at, directive)
+		return
+	}
+	fields := strings.Fields(head)
+	if len(fields) == 0 {
+		r.note("%s: malformed #cgo %s", at, directive)
+		return
+	}
+	verb := fields[len(fields)-1]
+	scopes, ok := parseConstraints(fields[:len(fields)-1], file)
+	if !ok {
+		r.note("%s: #cgo %s: constraint not translated, review by hand", at, strings.TrimSpace(head))
+		return
+	}
+
+	for _, s := range scopes {
+		n := needs[s]
+		if n == nil {
+			n = &cgoNeeds{}
+			needs[s] = n
+		}
+		for _, a := range cgoArgs(args) {
+			switch verb {
+			case "LDFLAGS":
+				switch {
+				case strings.HasPrefix(a, "-l"):
+					n.link = appendNew(n.link, a[2:])
+				case strings.HasPrefix(a, "-L"):
+					n.lib = appendNew(n.lib, srcdir(a[2:], dir))
+				default:
+					r.note("%s: LDFLAGS %s stays in the source", at, a)
+				}
+			case "CFLAGS", "CPPFLAGS", "CXXFLAGS":
+				if strings.HasPrefix(a, "-I") {
+					n.include = appendNew(n.include, srcdir(a[2:], dir))
+				}
+			case "pkg-config":
+				if strings.HasPrefix(a, "-") {
+					continue
+				}
+				r.note("%s: pkg-config %s: add a package providing it to packages (pkg-config does not see cross targets)", at, a)
+			}
+		}
+	}
+}
+
+// parseConstraints expands space-separated (OR) constraint terms, each an
+// os, arch or os,arch pair, intersected with the file's name constraint.
+// Negations and other tags are not translated.
+func parseConstraints(terms []string, file cgoScope) ([]cgoScope, bool) {
+	if len(terms) == 0 {
+		return []cgoScope{file}, true
+	}
+	var out []cgoScope
+	for _, term := range terms {
+		s := file
+		for part := range strings.SplitSeq(term, ",") {
+			switch {
+			case slices.Contains(knownOS, part) && (s.os == "" || s.os == part):
+				s.os = part
+			case slices.Contains(knownArch, part) && (s.arch == "" || s.arch == part):
+				s.arch = part
+			default:
+				return nil, false
+			}
+		}
+		if s.os == "" {
+			// Targets need an OS; arch-only constraints are left to the user.
+			return nil, false
+		}
+		out = append(out, s)
+	}
+	return out, true
+}
+
+// scopeFromName returns the constraint implied by a _<os>_<arch>.go suffix.
+func scopeFromName(name string) cgoScope {
+	parts := strings.Split(strings.TrimSuffix(name, ".go"), "_")
+	n := len(parts)
+	if n >= 3 && slices.Contains(knownOS, parts[n-2]) && slices.Contains(knownArch, parts[n-1]) {
+		return cgoScope{parts[n-2], parts[n-1]}
+	}
+	if n >= 2 && slices.Contains(knownOS, parts[n-1]) {
+		return cgoScope{os: parts[n-1]}
+	}
+	return cgoScope{}
+}
+
+// importDeclDoc returns the doc comment of the import declaration holding
+// pos. Like cgo, it only counts when "C" is the declaration's sole spec.
+func importDeclDoc(f *ast.File, pos token.Pos) *ast.CommentGroup {
+	for _, decl := range f.Decls {
+		if g, ok := decl.(*ast.GenDecl); ok && g.Tok == token.IMPORT && g.Pos() <= pos && pos <= g.End() && len(g.Specs) == 1 {
+			return g.Doc
+		}
+	}
+	return nil
+}
+
+// srcdir rewrites a ${SRCDIR}-relative path to one relative to the module.
+func srcdir(p, dir string) string {
+	rest, ok := strings.CutPrefix(p, "${SRCDIR}")
+	if !ok {
+		return p
+	}
+	return "./" + filepath.ToSlash(filepath.Join(dir, rest))
+}
+
+// cgoArgs splits directive arguments, honoring single and double quotes.
+func cgoArgs(s string) []string {
+	var (
+		out   []string
+		cur   strings.Builder
+		quote rune
+		inArg bool
+	)
+	for _, c := range s {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			cur.WriteRune(c)
+		case c == '\'' || c == '"':
+			quote, inArg = c, true
+		case c == ' ' || c == '\t':
+			if inArg {
+				out = append(out, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		out = append(out, cur.String())
+	}
+	return out
+}
+
+func appendNew(list []string, v string) []string {
+	if v == "" || slices.Contains(list, v) {
+		return list
+	}
+	return append(list, v)
+}
+
+func isModule(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "go.mod"))
+	return err == nil
+}
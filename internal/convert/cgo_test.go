@@ -0,0 +1,117 @@
+package convert
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFromCgo(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/m\n",
+		"cuda/cuda.go": `package cuda
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/include
+#cgo LDFLAGS: -L${SRCDIR}/lib -lcudart -lcublas
+#cgo linux,amd64 LDFLAGS: -lstdc++
+#cgo windows LDFLAGS: -lws2_32 -Wl,--no-insert-timestamp
+#cgo !windows LDFLAGS: -lm
+#cgo pkg-config: libpng
+#include <cuda.h>
+*/
+import "C"
+`,
+		"gl/gl_darwin.go": `package gl
+
+// #cgo LDFLAGS: -lGL
+import "C"
+`,
+		"plain/plain.go":     "package plain\n",
+		"vendor/x/x.go":      "package x\n\n// #cgo LDFLAGS: -lvendored\nimport \"C\"\n",
+		"nested/go.mod":      "module example.com/nested\n",
+		"nested/n.go":        "package n\n\n// #cgo LDFLAGS: -lnested\nimport \"C\"\n",
+		"cuda/cuda_test.go":  "package cuda\n\n// #cgo LDFLAGS: -ltestonly\nimport \"C\"\n",
+		"grouped/grouped.go": "package grouped\n\n/*\n#cgo LDFLAGS: -lz\n*/\nimport (\n\t\"C\"\n)\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := FromCgo(root)
+	if err != nil {
+		t.Fatalf("FromCgo() error = %v", err)
+	}
+	d := res.Config.Default
+	if want := []string{"cudart", "cublas", "z"}; !slices.Equal(d.Link, want) {
+		t.Errorf("default link = %v, want %v", d.Link, want)
+	}
+	if want := []string{"./cuda/include"}; !slices.Equal(d.Include, want) {
+		t.Errorf("default include = %v, want %v", d.Include, want)
+	}
+	if want := []string{"./cuda/lib"}; !slices.Equal(d.Lib, want) {
+		t.Errorf("default lib = %v, want %v", d.Lib, want)
+	}
+
+	targets := map[string][]string{}
+	for _, tg := range res.Config.Targets {
+		targets[tg.Name] = tg.Link
+	}
+	want := map[string][]string{
+		"darwin":      {"GL"},
+		"linux-amd64": {"stdc++"},
+		"windows":     {"ws2_32"},
+	}
+	if len(targets) != len(want) {
+		t.Errorf("targets = %v, want %v", targets, want)
+	}
+	for name, link := range want {
+		if !slices.Equal(targets[name], link) {
+			t.Errorf("target %s link = %v, want %v", name, targets[name], link)
+		}
+	}
+	notes := strings.Join(res.Notes, "\n")
+	for _, s := range []string{"-Wl,--no-insert-timestamp", "!windows", "pkg-config libpng"} {
+		if !strings.Contains(notes, s) {
+			t.Errorf("notes missing %q:\n%s", s, notes)
+		}
+	}
+}
+
+func TestFromCgo_None(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FromCgo(root); !errors.Is(err, ErrNoCgo) {
+		t.Errorf("FromCgo() error = %v, want ErrNoCgo", err)
+	}
+}
+
+func TestCgoArgs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{" -lfoo  -L/opt/lib", []string{"-lfoo", "-L/opt/lib"}},
+		{` -I"/path with space" -DX='a b'`, []string{"-I/path with space", "-DX=a b"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := cgoArgs(tt.in); !slices.Equal(got, tt.want) {
+				t.Errorf("cgoArgs(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
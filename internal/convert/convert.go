@@ -0,0 +1,288 @@
+// Package convert translates cross-build setups from other tools into gox.toml.
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/qntx/gox/internal/build"
+)
+
+// Source identifies the tool a configuration is imported from.
+type Source string
+
+const (
+	GoReleaser Source = "goreleaser"
+	Makefile   Source = "make"
+	Xgo        Source = "xgo"
+)
+
+// Result is a translated config plus notes about settings that could not be
+// carried over automatically.
+type Result struct {
+	Config *build.Config
+	Notes  []string
+}
+
+var (
+	ErrNoTargets = errors.New("no build targets found")
+
+	goosRE   = regexp.MustCompile(`\bGOOS=([a-z0-9]+)`)
+	goarchRE = regexp.MustCompile(`\bGOARCH=([a-z0-9]+)`)
+
+	// wildcardArchs expands xgo's os/* syntax to the architectures gox supports.
+	wildcardArchs = map[string][]string{
+		"linux":   {"amd64", "arm64", "386", "arm", "riscv64", "loong64", "ppc64le", "s390x"},
+		"windows": {"amd64", "arm64", "386"},
+		"darwin":  {"amd64"},
+		"freebsd": {"amd64", "386"},
+		"netbsd":  {"amd64", "arm64", "386", "arm"},
+	}
+)
+
+// DetectSource guesses the source tool from a file name.
+func DetectSource(path string) (Source, error) {
+	base := strings.ToLower(filepath.Base(path))
+	switch {
+	case strings.Contains(base, "goreleaser"):
+		return GoReleaser, nil
+	case base == "makefile" || base == "gnumakefile" || strings.HasSuffix(base, ".mk"):
+		return Makefile, nil
+	}
+	return "", fmt.Errorf("cannot detect format of %s (use --from)", path)
+}
+
+type grBuild struct {
+	ID      string         `yaml:"id"`
+	Main    string         `yaml:"main"`
+	Binary  string         `yaml:"binary"`
+	Goos    []string       `yaml:"goos"`
+	Goarch  []string       `yaml:"goarch"`
+	Flags   []string       `yaml:"flags"`
+	Ldflags yamlStrings    `yaml:"ldflags"`
+	Tags    []string       `yaml:"tags"`
+	Ignore  []grIgnore     `yaml:"ignore"`
+	Hooks   map[string]any `yaml:"hooks"`
+}
+
+type grIgnore struct {
+	Goos   string `yaml:"goos"`
+	Goarch string `yaml:"goarch"`
+}
+
+// yamlStrings accepts either a scalar or a sequence of strings.
+type yamlStrings []string
+
+func (s *yamlStrings) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*s = []string{n.Value}
+		return nil
+	}
+	var list []string
+	if err := n.Decode(&list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// FromGoReleaser translates the builds section of a .goreleaser.yaml.
+func FromGoReleaser(data []byte) (*Result, error) {
+	var doc struct {
+		Builds []grBuild `yaml:"builds"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	res := &Result{Config: &build.Config{}}
+	for i, b := range doc.Builds {
+		id := b.ID
+		if id == "" && len(doc.Builds) > 1 {
+			id = fmt.Sprintf("build%d", i)
+		}
+		binary := b.Binary
+		if binary == "" {
+			binary = "app"
+		}
+		if b.Main != "" && b.Main != "." {
+			res.note("build %q: pass %s as the package argument to gox build", b.ID, b.Main)
+		}
+		if len(b.Hooks) > 0 {
+			res.note("build %q: hooks are not supported and were dropped", b.ID)
+		}
+
+		strip, ldflags := splitLDFlags(b.Ldflags)
+		if ldflags != "" {
+			res.note("build %q: ldflags %q need manual migration", b.ID, ldflags)
+		}
+		var flags []string
+		flags = append(flags, b.Flags...)
+		if len(b.Tags) > 0 {
+			flags = append(flags, "-tags="+strings.Join(b.Tags, ","))
+		}
+
+		goos := b.Goos
+		if len(goos) == 0 {
+			goos = []string{"linux", "darwin", "windows"}
+		}
+		goarch := b.Goarch
+		if len(goarch) == 0 {
+			goarch = []string{"amd64", "arm64", "386"}
+		}
+		for _, os := range goos {
+			for _, arch := range goarch {
+				if ignored(b.Ignore, os, arch) {
+					continue
+				}
+				res.Config.Targets = append(res.Config.Targets, build.ConfigTarget{
+					Name:   targetName(id, os, arch),
+					OS:     os,
+					Arch:   arch,
+					Output: outputPath(binary, os, arch),
+					Flags:  slices.Clone(flags),
+					Strip:  strip,
+				})
+			}
+		}
+	}
+	return res.done()
+}
+
+// FromMakefile collects GOOS/GOARCH pairs assigned on the same line.
+func FromMakefile(data []byte) (*Result, error) {
+	res := &Result{Config: &build.Config{}}
+	seen := make(map[string]bool)
+	for line := range strings.Lines(string(data)) {
+		os, arch := goosRE.FindStringSubmatch(line), goarchRE.FindStringSubmatch(line)
+		if os == nil || arch == nil {
+			continue
+		}
+		res.addTarget(seen, os[1], arch[1], "app")
+	}
+	if strings.Contains(string(data), "$(GOOS)") || strings.Contains(string(data), "${GOOS}") {
+		res.note("variable-driven GOOS/GOARCH loops were not expanded; review the target list")
+	}
+	return res.done()
+}
+
+// FromXgo translates xgo command-line flags.
+func FromXgo(args []string) (*Result, error) {
+	res := &Result{Config: &build.Config{}}
+	var targets []string
+	out := "app"
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "-") {
+			continue
+		}
+		name, value, ok := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !ok && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			value = args[i+1]
+			i++
+		}
+		switch name {
+		case "targets":
+			targets = append(targets, strings.Split(value, ",")...)
+		case "out":
+			out = value
+		case "tags":
+			res.Config.Default.Flags = append(res.Config.Default.Flags, "-tags="+value)
+		case "ldflags":
+			strip, rest := splitLDFlags([]string{value})
+			res.Config.Default.Strip = strip
+			if rest != "" {
+				res.note("ldflags %q need manual migration", rest)
+			}
+		case "trimpath", "race", "v", "x":
+			res.Config.Default.Flags = append(res.Config.Default.Flags, "-"+name)
+		case "go", "image", "deps", "depsargs", "dest", "pkg", "remote", "branch":
+			res.note("xgo flag -%s has no gox equivalent and was dropped", name)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, t := range targets {
+		os, arch, _ := strings.Cut(strings.TrimSpace(t), "/")
+		if os == "" || os == "*" {
+			continue
+		}
+		if arch == "" || arch == "*" {
+			for _, a := range wildcardArchs[os] {
+				res.addTarget(seen, os, a, out)
+			}
+			continue
+		}
+		arch = strings.TrimSuffix(strings.TrimSuffix(arch, "-7"), "-6")
+		res.addTarget(seen, os, arch, out)
+	}
+	return res.done()
+}
+
+func (r *Result) addTarget(seen map[string]bool, os, arch, binary string) {
+	key := os + "/" + arch
+	if seen[key] {
+		return
+	}
+	seen[key] = true
+	r.Config.Targets = append(r.Config.Targets, build.ConfigTarget{
+		Name:   targetName("", os, arch),
+		OS:     os,
+		Arch:   arch,
+		Output: outputPath(binary, os, arch),
+	})
+}
+
+func (r *Result) note(format string, args ...any) {
+	r.Notes = append(r.Notes, fmt.Sprintf(format, args...))
+}
+
+func (r *Result) done() (*Result, error) {
+	if len(r.Config.Targets) == 0 {
+		return nil, ErrNoTargets
+	}
+	return r, nil
+}
+
+// splitLDFlags extracts -s -w into strip when both are given and returns
+// the remaining flags. A lone -s or -w stays in rest, as strip drops both.
+func splitLDFlags(list []string) (strip bool, rest string) {
+	var fields []string
+	for _, l := range list {
+		fields = append(fields, strings.Fields(l)...)
+	}
+	if !slices.Contains(fields, "-s") || !slices.Contains(fields, "-w") {
+		return false, strings.Join(fields, " ")
+	}
+	keep := slices.DeleteFunc(fields, func(f string) bool { return f == "-s" || f == "-w" })
+	return true, strings.Join(keep, " ")
+}
+
+func ignored(list []grIgnore, os, arch string) bool {
+	for _, ig := range list {
+		if (ig.Goos == "" || ig.Goos == os) && (ig.Goarch == "" || ig.Goarch == arch) {
+			return true
+		}
+	}
+	return false
+}
+
+func targetName(id, os, arch string) string {
+	if id == "" {
+		return os + "-" + arch
+	}
+	return id + "-" + os + "-" + arch
+}
+
+func outputPath(binary, os, arch string) string {
+	name := binary
+	if os == "windows" {
+		name += ".exe"
+	}
+	return fmt.Sprintf("dist/%s-%s/%s", os, arch, name)
+}
@@ -0,0 +1,176 @@
+package convert
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/qntx/gox/internal/build"
+)
+
+func TestDetectSource(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    Source
+		wantErr bool
+	}{
+		{".goreleaser.yaml", GoReleaser, false},
+		{"ci/.goreleaser.yml", GoReleaser, false},
+		{"Makefile", Makefile, false},
+		{"build/cross.mk", Makefile, false},
+		{"build.sh", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := DetectSource(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DetectSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DetectSource() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromGoReleaser(t *testing.T) {
+	data := []byte(`
+builds:
+  - id: app
+    main: ./cmd/app
+    binary: myapp
+    goos: [linux, windows]
+    goarch: [amd64, arm64]
+    ignore:
+      - goos: windows
+        goarch: arm64
+    flags: [-trimpath]
+    tags: [prod, cgo]
+    ldflags: -s -w -X main.version={{.Version}}
+`)
+	res, err := FromGoReleaser(data)
+	if err != nil {
+		t.Fatalf("FromGoReleaser() error = %v", err)
+	}
+
+	targets := res.Config.Targets
+	if len(targets) != 3 {
+		t.Fatalf("len(Targets) = %d, want 3", len(targets))
+	}
+	first := targets[0]
+	if first.Name != "app-linux-amd64" {
+		t.Errorf("Name = %q, want app-linux-amd64", first.Name)
+	}
+	if first.Output != "dist/linux-amd64/myapp" {
+		t.Errorf("Output = %q", first.Output)
+	}
+	if !first.Strip {
+		t.Error("Strip = false, want true")
+	}
+	if strings.Join(first.Flags, " ") != "-trimpath -tags=prod,cgo" {
+		t.Errorf("Flags = %v", first.Flags)
+	}
+	if targets[2].Output != "dist/windows-amd64/myapp.exe" {
+		t.Errorf("windows Output = %q", targets[2].Output)
+	}
+	if len(res.Notes) != 2 {
+		t.Errorf("Notes = %v, want main and ldflags notes", res.Notes)
+	}
+}
+
+func TestFromMakefile(t *testing.T) {
+	data := []byte(`
+build-all:
+	GOOS=linux GOARCH=amd64 go build -o dist/app-linux .
+	GOARCH=arm64 GOOS=linux go build -o dist/app-linux-arm64 .
+	GOOS=windows GOARCH=amd64 go build -o dist/app.exe .
+	GOOS=linux GOARCH=amd64 go test ./...
+`)
+	res, err := FromMakefile(data)
+	if err != nil {
+		t.Fatalf("FromMakefile() error = %v", err)
+	}
+	var names []string
+	for _, tg := range res.Config.Targets {
+		names = append(names, tg.Name)
+	}
+	if got := strings.Join(names, ","); got != "linux-amd64,linux-arm64,windows-amd64" {
+		t.Errorf("targets = %s", got)
+	}
+
+	if _, err := FromMakefile([]byte("all:\n\tgo build\n")); err != ErrNoTargets {
+		t.Errorf("error = %v, want ErrNoTargets", err)
+	}
+}
+
+func TestFromXgo(t *testing.T) {
+	res, err := FromXgo([]string{"-targets=linux/arm-7,windows/*", "-out", "tool", "-tags", "prod", "-ldflags=-s -w", "-go", "1.21", "."})
+	if err != nil {
+		t.Fatalf("FromXgo() error = %v", err)
+	}
+	if len(res.Config.Targets) != 4 {
+		t.Fatalf("len(Targets) = %d, want 4", len(res.Config.Targets))
+	}
+	if tg := res.Config.Targets[0]; tg.OS != "linux" || tg.Arch != "arm" || tg.Output != "dist/linux-arm/tool" {
+		t.Errorf("Targets[0] = %+v", tg)
+	}
+	if !res.Config.Default.Strip {
+		t.Error("Default.Strip = false, want true")
+	}
+	if len(res.Config.Default.Flags) != 1 || res.Config.Default.Flags[0] != "-tags=prod" {
+		t.Errorf("Default.Flags = %v", res.Config.Default.Flags)
+	}
+	if len(res.Notes) != 1 {
+		t.Errorf("Notes = %v, want one note for -go", res.Notes)
+	}
+}
+
+func TestResult_EncodeRoundTrip(t *testing.T) {
+	res, err := FromXgo([]string{"-targets=linux/amd64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := res.Config.Encode(&buf); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "strip") {
+		t.Errorf("unset fields should be omitted:\n%s", buf.String())
+	}
+
+	path := t.TempDir() + "/gox.toml"
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := build.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if len(cfg.Targets) != 1 || cfg.Targets[0].Name != "linux-amd64" {
+		t.Errorf("round trip Targets = %+v", cfg.Targets)
+	}
+}
+
+func TestSplitLDFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		list      []string
+		wantStrip bool
+		wantRest  string
+	}{
+		{"both", []string{"-s -w -X main.version=1.0"}, true, "-X main.version=1.0"},
+		{"both in separate entries", []string{"-s", "-w"}, true, ""},
+		{"-s alone", []string{"-s -X main.version=1.0"}, false, "-s -X main.version=1.0"},
+		{"-w alone", []string{"-w", "-X main.commit=abc"}, false, "-w -X main.commit=abc"},
+		{"neither", []string{"-X main.version=1.0"}, false, "-X main.version=1.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strip, rest := splitLDFlags(tt.list)
+			if strip != tt.wantStrip || rest != tt.wantRest {
+				t.Errorf("splitLDFlags(%q) = %v, %q, want %v, %q", tt.list, strip, rest, tt.wantStrip, tt.wantRest)
+			}
+		})
+	}
+}
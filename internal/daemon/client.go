@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Client talks to a running daemon.
+type Client struct {
+	Addr  string
+	Token string
+	HTTP  *http.Client
+}
+
+// NewClient returns a client for the daemon at addr (DefaultAddr if empty),
+// presenting the token from Token.
+func NewClient(addr string) *Client {
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	return &Client{Addr: addr, Token: Token(), HTTP: http.DefaultClient}
+}
+
+// Build runs a build on the daemon, copying its output to out.
+func (c *Client) Build(ctx context.Context, req BuildRequest, out io.Writer) error {
+	resp, err := c.post(ctx, "/v1/build", req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var ev Event
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			return fmt.Errorf("daemon: %w", err)
+		}
+		if ev.Done {
+			if ev.Error != "" {
+				return errors.New(ev.Error)
+			}
+			return nil
+		}
+		if _, err := io.WriteString(out, ev.Output); err != nil {
+			return err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("daemon: %w", err)
+	}
+	return errors.New("daemon: connection closed before build finished")
+}
+
+// Fetch warms the daemon's toolchain and package cache.
+func (c *Client) Fetch(ctx context.Context, req FetchRequest) (*FetchResponse, error) {
+	resp, err := c.post(ctx, "/v1/fetch", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var out FetchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("daemon: %w", err)
+	}
+	return &out, nil
+}
+
+// Status returns the daemon's queue and cache state.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url("/v1/status"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var st Status
+	if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+		return nil, fmt.Errorf("daemon: %w", err)
+	}
+	return &st, nil
+}
+
+func (c *Client) post(ctx context.Context, path string, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url(path), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.do(req)
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("daemon not reachable at %s (start it with `gox daemon`): %w", c.Addr, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if resp.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("daemon: %s: %s (set %s to the daemon's token)", resp.Status, strings.TrimSpace(string(msg)), TokenEnv)
+		}
+		return nil, fmt.Errorf("daemon: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+func (c *Client) url(path string) string {
+	if strings.Contains(c.Addr, "://") {
+		return strings.TrimSuffix(c.Addr, "/") + path
+	}
+	return "http://" + c.Addr + path
+}
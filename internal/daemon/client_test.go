@@ -0,0 +1,73 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestClient_Build(t *testing.T) {
+	dir := t.TempDir()
+	srv := testServer(t, fakeGox(t, 0), dir)
+
+	var out bytes.Buffer
+	err := NewClient(srv.URL).Build(context.Background(), BuildRequest{Dir: dir, Args: []string{"-t", "linux"}}, &out)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "build -t linux") {
+		t.Errorf("output = %q, want build args", out.String())
+	}
+	if !strings.Contains(out.String(), dir) {
+		t.Errorf("output = %q, want working dir %s", out.String(), dir)
+	}
+}
+
+func TestClient_BuildFailure(t *testing.T) {
+	dir := t.TempDir()
+	srv := testServer(t, fakeGox(t, 1), dir)
+
+	err := NewClient(srv.URL).Build(context.Background(), BuildRequest{Dir: dir}, &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("Build() should fail when gox exits non-zero")
+	}
+}
+
+func TestClient_Status(t *testing.T) {
+	srv := testServer(t, "gox", t.TempDir())
+
+	st, err := NewClient(srv.URL).Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if st.PID == 0 {
+		t.Error("PID = 0, want daemon pid")
+	}
+	if len(st.Jobs) != 0 {
+		t.Errorf("len(Jobs) = %d, want 0", len(st.Jobs))
+	}
+}
+
+func TestClient_Unreachable(t *testing.T) {
+	_, err := NewClient("127.0.0.1:1").Status(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "gox daemon") {
+		t.Errorf("Status() error = %v, want hint to start daemon", err)
+	}
+}
+
+func TestClient_URL(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"", "http://" + DefaultAddr + "/v1/status"},
+		{"localhost:9000", "http://localhost:9000/v1/status"},
+		{"http://host:1/", "http://host:1/v1/status"},
+	}
+	for _, tt := range tests {
+		if got := NewClient(tt.addr).url("/v1/status"); got != tt.want {
+			t.Errorf("url(%q) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
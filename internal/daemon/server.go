@@ -0,0 +1,299 @@
+// Package daemon implements a local HTTP build server and its client. The
+// server runs builds from all clients through a single machine-wide queue,
+// each in a fresh gox process, and can fetch zig and packages into the
+// cache ahead of them.
+package daemon
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qntx/gox/internal/build"
+	"github.com/qntx/gox/internal/zig"
+)
+
+// DefaultAddr is the address the daemon listens on and clients dial.
+const DefaultAddr = "127.0.0.1:7077"
+
+// BuildRequest asks the daemon to run `gox build Args` in Dir, an absolute
+// path inside the daemon's root.
+type BuildRequest struct {
+	Dir  string   `json:"dir"`
+	Args []string `json:"args"`
+}
+
+// FetchRequest asks the daemon to warm the toolchain and package cache.
+type FetchRequest struct {
+	Zig      string   `json:"zig,omitempty"`
+	Packages []string `json:"packages,omitempty"`
+}
+
+// FetchResponse reports the cached zig installation.
+type FetchResponse struct {
+	Zig string `json:"zig"`
+}
+
+// Event is one line of a streamed build response. Output events carry
+// build output; the final event has Done set.
+type Event struct {
+	Output string `json:"output,omitempty"`
+	Done   bool   `json:"done,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Job is a queued or running build.
+type Job struct {
+	ID      int       `json:"id"`
+	Dir     string    `json:"dir"`
+	Args    []string  `json:"args"`
+	State   string    `json:"state"` // queued, running
+	Created time.Time `json:"created"`
+}
+
+// Status describes the daemon.
+type Status struct {
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+	Built   int       `json:"built"`
+	Jobs    []Job     `json:"jobs"`
+	Zig     []string  `json:"zig"`
+}
+
+// Server runs builds one at a time for all clients.
+type Server struct {
+	exe     string // gox binary used to run builds
+	token   string
+	root    string // builds must run inside it
+	started time.Time
+	slot    chan struct{}
+
+	mu    sync.Mutex
+	jobs  []*Job
+	next  int
+	built int
+}
+
+// NewServer returns a server that runs builds with the gox binary at exe
+// in directories below root. Clients must present token as a bearer token.
+func NewServer(exe, token, root string) *Server {
+	return &Server{exe: exe, token: token, root: root, started: time.Now(), slot: make(chan struct{}, 1)}
+}
+
+// Handler returns the HTTP API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/build", s.handleBuild)
+	mux.HandleFunc("POST /v1/fetch", s.handleFetch)
+	mux.HandleFunc("GET /v1/status", s.handleStatus)
+	return s.guard(mux)
+}
+
+// guard rejects requests without the token and, so that web pages cannot
+// reach the daemon through the browser, any request a browser sends
+// cross-origin: those carrying an Origin header and POSTs of other content
+// types than JSON, which would need no CORS preflight.
+func (s *Server) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+			return
+		}
+		if !s.authorized(r) {
+			http.Error(w, "invalid or missing daemon token", http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodPost {
+			if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/json" {
+				http.Error(w, "content type must be application/json", http.StatusUnsupportedMediaType)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	got := r.Header.Get("Authorization")
+	return s.token != "" && subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+s.token)) == 1
+}
+
+// ListenAndServe serves the API on addr until ctx is canceled.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	}()
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) handleBuild(w http.ResponseWriter, r *http.Request) {
+	var req BuildRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Dir == "" {
+		http.Error(w, "dir is required", http.StatusBadRequest)
+		return
+	}
+	if err := s.inRoot(req.Dir); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	job := s.enqueue(req)
+	defer s.finish(job)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	out := &eventWriter{enc: json.NewEncoder(w), flusher: w.(http.Flusher)}
+
+	select {
+	case s.slot <- struct{}{}:
+		defer func() { <-s.slot }()
+	case <-r.Context().Done():
+		return
+	}
+	s.setState(job, "running")
+
+	err := s.run(r.Context(), req, out)
+	done := Event{Done: true}
+	if err != nil {
+		done.Error = err.Error()
+	}
+	out.send(done)
+}
+
+// inRoot checks that dir is an absolute path inside the server's root,
+// following symlinks.
+func (s *Server) inRoot(dir string) error {
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("dir %q is not absolute", dir)
+	}
+	root, err := filepath.EvalSymlinks(s.root)
+	if err != nil {
+		return err
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("dir %s is outside the daemon root %s", dir, s.root)
+	}
+	return nil
+}
+
+func (s *Server) run(ctx context.Context, req BuildRequest, out io.Writer) error {
+	cmd := exec.CommandContext(ctx, s.exe, append([]string{"build"}, req.Args...)...)
+	cmd.Dir = req.Dir
+	cmd.Env = os.Environ()
+	cmd.Stdout = out
+	cmd.Stderr = out
+	return cmd.Run()
+}
+
+func (s *Server) handleFetch(w http.ResponseWriter, r *http.Request) {
+	var req FetchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	path, err := zig.Ensure(r.Context(), req.Zig)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("zig: %v", err), http.StatusBadGateway)
+		return
+	}
+	if len(req.Packages) > 0 {
+		if _, err := build.EnsureAll(r.Context(), req.Packages); err != nil {
+			http.Error(w, fmt.Sprintf("packages: %v", err), http.StatusBadGateway)
+			return
+		}
+	}
+	writeJSON(w, FetchResponse{Zig: path})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	st := Status{PID: os.Getpid(), Started: s.started, Jobs: []Job{}}
+	st.Zig, _ = zig.Installed()
+
+	s.mu.Lock()
+	st.Built = s.built
+	for _, j := range s.jobs {
+		st.Jobs = append(st.Jobs, *j)
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, st)
+}
+
+func (s *Server) enqueue(req BuildRequest) *Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next++
+	j := &Job{ID: s.next, Dir: req.Dir, Args: req.Args, State: "queued", Created: time.Now()}
+	s.jobs = append(s.jobs, j)
+	return j
+}
+
+func (s *Server) setState(j *Job, state string) {
+	s.mu.Lock()
+	j.State = state
+	s.mu.Unlock()
+}
+
+func (s *Server) finish(j *Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, x := range s.jobs {
+		if x == j {
+			s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+			break
+		}
+	}
+	if j.State == "running" {
+		s.built++
+	}
+}
+
+// eventWriter streams written bytes as output events.
+type eventWriter struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	flusher http.Flusher
+}
+
+func (e *eventWriter) Write(p []byte) (int, error) {
+	e.send(Event{Output: string(p)})
+	return len(p), nil
+}
+
+func (e *eventWriter) send(ev Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_ = e.enc.Encode(ev)
+	e.flusher.Flush()
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
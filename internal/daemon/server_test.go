@@ -0,0 +1,146 @@
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeGox writes a script that echoes its arguments and working directory.
+func fakeGox(t *testing.T, exit int) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script fake requires unix")
+	}
+	path := filepath.Join(t.TempDir(), "gox")
+	script := "#!/bin/sh\necho \"$@\"\npwd\nexit " + strconv.Itoa(exit) + "\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testServer starts a server with token "secret" building below root.
+func testServer(t *testing.T, exe, root string) *httptest.Server {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv(TokenEnv, "secret")
+	srv := httptest.NewServer(NewServer(exe, "secret", root).Handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestServer_BuildValidation(t *testing.T) {
+	root := t.TempDir()
+	inside := filepath.Join(root, "app")
+	if err := os.Mkdir(inside, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+	escape := filepath.Join(root, "escape")
+	if err := os.Symlink(outside, escape); err != nil {
+		t.Fatal(err)
+	}
+	srv := testServer(t, fakeGox(t, 0), root)
+
+	body := func(dir string) string { return `{"dir":` + strconv.Quote(dir) + `}` }
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		token       string
+		origin      string
+		want        int
+	}{
+		{"ok", body(inside), "application/json", "secret", "", http.StatusOK},
+		{"json with charset", body(inside), "application/json; charset=utf-8", "secret", "", http.StatusOK},
+		{"bad json", "{", "application/json", "secret", "", http.StatusBadRequest},
+		{"missing dir", `{"args":["-t","x"]}`, "application/json", "secret", "", http.StatusBadRequest},
+		{"relative dir", body("app"), "application/json", "secret", "", http.StatusForbidden},
+		{"dir outside root", body(outside), "application/json", "secret", "", http.StatusForbidden},
+		{"dir escaping by symlink", body(escape), "application/json", "secret", "", http.StatusForbidden},
+		{"missing token", body(inside), "application/json", "", "", http.StatusUnauthorized},
+		{"wrong token", body(inside), "application/json", "wrong", "", http.StatusUnauthorized},
+		{"text/plain", body(inside), "text/plain", "secret", "", http.StatusUnsupportedMediaType},
+		{"no content type", body(inside), "", "secret", "", http.StatusUnsupportedMediaType},
+		{"cross-origin", body(inside), "application/json", "secret", "https://evil.example", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, srv.URL+"/v1/build", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_StatusRequiresToken(t *testing.T) {
+	srv := testServer(t, "gox", t.TempDir())
+
+	resp, err := http.Get(srv.URL + "/v1/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GET /v1/status without token status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestServer_Methods(t *testing.T) {
+	srv := testServer(t, "gox", t.TempDir())
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/v1/build", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /v1/build status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestServer_FinishCountsBuilds(t *testing.T) {
+	s := NewServer("gox", "secret", "/")
+	queued := s.enqueue(BuildRequest{Dir: "/a"})
+	ran := s.enqueue(BuildRequest{Dir: "/b"})
+	s.setState(ran, "running")
+
+	s.finish(queued)
+	s.finish(ran)
+
+	if s.built != 1 {
+		t.Errorf("built = %d, want 1", s.built)
+	}
+	if len(s.jobs) != 0 {
+		t.Errorf("len(jobs) = %d, want 0", len(s.jobs))
+	}
+}
@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TokenEnv holds the token clients present to the daemon. When it is not
+// set, the daemon makes up a token at startup and stores it in TokenFile,
+// where clients of the same user read it.
+const TokenEnv = "GOX_DAEMON_TOKEN"
+
+// TokenFile returns the file the daemon stores its token in. It does not
+// follow the cache scope of a project's config, so every client finds it.
+func TokenFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "gox", "daemon-token")
+}
+
+// NewToken returns the token a starting daemon requires: that in TokenEnv,
+// or a new random one written to TokenFile, readable only by the user.
+func NewToken() (string, error) {
+	if t := os.Getenv(TokenEnv); t != "" {
+		return t, nil
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	path := TokenFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	// Replace the file so an existing one with looser permissions goes.
+	_ = os.Remove(path)
+	if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Token returns the token clients present: that in TokenEnv, else the one
+// in TokenFile, else "".
+func Token() string {
+	if t := os.Getenv(TokenEnv); t != "" {
+		return t
+	}
+	data, err := os.ReadFile(TokenFile())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
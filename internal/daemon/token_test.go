@@ -0,0 +1,48 @@
+package daemon
+
+import (
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestNewToken(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("LocalAppData", t.TempDir())
+	t.Setenv(TokenEnv, "")
+
+	token, err := NewToken()
+	if err != nil {
+		t.Fatalf("NewToken() error = %v", err)
+	}
+	if len(token) != 64 {
+		t.Errorf("NewToken() = %q, want 64 hex digits", token)
+	}
+	if got := Token(); got != token {
+		t.Errorf("Token() = %q, want %q", got, token)
+	}
+	info, err := os.Stat(TokenFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Errorf("token file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	again, err := NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == token {
+		t.Error("NewToken() returned the same token twice")
+	}
+
+	t.Setenv(TokenEnv, "secret")
+	if got, _ := NewToken(); got != "secret" {
+		t.Errorf("NewToken() with %s = %q, want secret", TokenEnv, got)
+	}
+	if got := Token(); got != "secret" {
+		t.Errorf("Token() with %s = %q, want secret", TokenEnv, got)
+	}
+}
@@ -0,0 +1,100 @@
+package fakenet
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+)
+
+// Format is an archive format of canned archives.
+type Format string
+
+// Archive formats.
+const (
+	TarGz Format = "tar.gz"
+	TarXz Format = "tar.xz"
+	Zip   Format = "zip"
+)
+
+// Ext returns the file extension of f, with a leading dot.
+func (f Format) Ext() string {
+	return "." + string(f)
+}
+
+// FormatOf returns the format a file name implies, defaulting to TarGz.
+func FormatOf(name string) Format {
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return Zip
+	case strings.HasSuffix(name, ".tar.xz"), strings.HasSuffix(name, ".txz"):
+		return TarXz
+	}
+	return TarGz
+}
+
+// Archive returns an archive of files, keyed by slash-separated name. Files
+// starting with "#!" are executable. It panics on write errors, which an
+// in-memory archive does not have.
+func Archive(f Format, files map[string]string) []byte {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var buf bytes.Buffer
+	if f == Zip {
+		zw := zip.NewWriter(&buf)
+		for _, name := range names {
+			hdr := &zip.FileHeader{Name: name, Method: zip.Deflate}
+			hdr.SetMode(mode(files[name]))
+			w, err := zw.CreateHeader(hdr)
+			must(err)
+			_, err = io.WriteString(w, files[name])
+			must(err)
+		}
+		must(zw.Close())
+		return buf.Bytes()
+	}
+
+	var (
+		cw  io.WriteCloser
+		err error
+	)
+	if f == TarXz {
+		cw, err = xz.NewWriter(&buf)
+		must(err)
+	} else {
+		cw = gzip.NewWriter(&buf)
+	}
+	tw := tar.NewWriter(cw)
+	for _, name := range names {
+		data := files[name]
+		must(tw.WriteHeader(&tar.Header{Name: name, Mode: int64(mode(data)), Size: int64(len(data)), Typeflag: tar.TypeReg}))
+		_, err := io.WriteString(tw, data)
+		must(err)
+	}
+	must(tw.Close())
+	must(cw.Close())
+	return buf.Bytes()
+}
+
+func mode(data string) fs.FileMode {
+	if strings.HasPrefix(data, "#!") {
+		return 0o755
+	}
+	return 0o644
+}
+
+func must(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
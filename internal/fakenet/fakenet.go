@@ -0,0 +1,174 @@
+// Package fakenet serves stand-ins for the registries gox downloads from: a
+// ziglang.org release index with Zig tarballs, and GitHub releases with
+// their assets. Tests point gox at it through zig-mirror, [mirrors] and
+// GOX_GITHUB_API, so nothing reaches the real network.
+package fakenet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/qntx/gox/internal/zig"
+)
+
+// GitHub is the URL prefix of GitHub release downloads.
+const GitHub = "https://github.com/"
+
+// Server is a fake registry. Add content with AddZig and AddRelease before
+// gox requests it.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	files    map[string][]byte         // served content by URL path
+	index    map[string]map[string]any // zig index.json
+	releases map[string][]release      // GitHub releases by owner/repo
+	hits     map[string]int            // requests by URL path
+}
+
+// release is a GitHub release as the REST API lists it.
+type release struct {
+	Tag    string  `json:"tag_name"`
+	Assets []asset `json:"assets"`
+}
+
+type asset struct {
+	Name string `json:"name"`
+}
+
+// New starts a server that is closed when the test ends.
+func New(t testing.TB) *Server {
+	s := &Server{
+		files:    map[string][]byte{},
+		index:    map[string]map[string]any{},
+		releases: map[string][]release{},
+		hits:     map[string]int{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// ZigMirror is the zig-mirror value serving the fake index and tarballs.
+func (s *Server) ZigMirror() string {
+	return s.URL + "/zig"
+}
+
+// Mirrors is the [mirrors] table sending GitHub release downloads here.
+func (s *Server) Mirrors() map[string]string {
+	return map[string]string{GitHub: s.URL + "/github/"}
+}
+
+// GitHubAPI is the GOX_GITHUB_API value listing the added releases.
+func (s *Server) GitHubAPI() string {
+	return s.URL + "/api"
+}
+
+// AddZig publishes a fake Zig for the host platform as release name of
+// the index (a version or "master"). Its zig program is a shell script that
+// reports version and otherwise exits successfully, enough for builds that
+// compile no C.
+func (s *Server) AddZig(name, version string) {
+	platform := zig.HostPlatform()
+	base := fmt.Sprintf("zig-%s-%s", platform, version)
+	format := TarXz
+	if runtime.GOOS == "windows" {
+		format = Zip
+	}
+	script := fmt.Sprintf("#!/bin/sh\nif [ \"$1\" = version ]; then echo %s; fi\n", version)
+	data := Archive(format, map[string]string{base + "/zig": script})
+	file := base + format.Ext()
+
+	sum := sha256.Sum256(data)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files["/zig/"+file] = data
+	s.index[name] = map[string]any{
+		"version": version,
+		platform: map[string]string{
+			"tarball": "https://ziglang.org/builds/" + file,
+			"shasum":  hex.EncodeToString(sum[:]),
+			"size":    strconv.Itoa(len(data)),
+		},
+	}
+}
+
+// AddRelease publishes a GitHub release asset, an archive of files in the
+// format its name implies, and returns the archive's SHA-256.
+func (s *Server) AddRelease(owner, repo, tag, asset string, files map[string]string) string {
+	data := Archive(FormatOf(asset), files)
+	sum := sha256.Sum256(data)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[path.Join("/github", owner, repo, "releases/download", tag, asset)] = data
+	s.addAsset(owner+"/"+repo, tag, asset)
+	return hex.EncodeToString(sum[:])
+}
+
+// addAsset lists asset in release tag of repo, newest release first.
+func (s *Server) addAsset(repo, tag, name string) {
+	rs := s.releases[repo]
+	for i := range rs {
+		if rs[i].Tag == tag {
+			rs[i].Assets = append(rs[i].Assets, asset{name})
+			return
+		}
+	}
+	s.releases[repo] = append([]release{{Tag: tag, Assets: []asset{{name}}}}, rs...)
+}
+
+// Hits returns how often files whose URL path ends in suffix were
+// downloaded.
+func (s *Server) Hits(suffix string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for p, c := range s.hits {
+		if strings.HasSuffix(p, suffix) {
+			n += c
+		}
+	}
+	return n
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.URL.Path == "/zig/index.json" {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.index)
+		return
+	}
+	if repo, ok := strings.CutPrefix(r.URL.Path, "/api/repos/"); ok {
+		rs, ok := s.releases[strings.TrimSuffix(repo, "/releases")]
+		if !ok || !strings.HasSuffix(repo, "/releases") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(rs)
+		return
+	}
+	data, ok := s.files[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method == http.MethodGet {
+		s.hits[r.URL.Path]++
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(data)
+	}
+}
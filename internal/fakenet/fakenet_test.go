@@ -0,0 +1,93 @@
+package fakenet
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/qntx/gox/internal/archive"
+)
+
+func TestArchive(t *testing.T) {
+	files := map[string]string{
+		"pkg-1.0/include/a.h": "#define A 1\n",
+		"pkg-1.0/bin/tool":    "#!/bin/sh\n",
+	}
+	for _, f := range []Format{TarGz, TarXz, Zip} {
+		t.Run(string(f), func(t *testing.T) {
+			src := filepath.Join(t.TempDir(), "pkg"+f.Ext())
+			if err := os.WriteFile(src, Archive(f, files), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if got := FormatOf(src); got != f {
+				t.Errorf("FormatOf(%s) = %s", src, got)
+			}
+			dst := t.TempDir()
+			if err := archive.Extract(src, dst); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(filepath.Join(dst, "include", "a.h"))
+			if err != nil || string(data) != files["pkg-1.0/include/a.h"] {
+				t.Errorf("a.h = %q, %v", data, err)
+			}
+			info, err := os.Stat(filepath.Join(dst, "bin", "tool"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if runtime.GOOS != "windows" && info.Mode()&0o100 == 0 {
+				t.Errorf("tool mode = %v, want executable", info.Mode())
+			}
+		})
+	}
+}
+
+func TestServer(t *testing.T) {
+	s := New(t)
+	s.AddRelease("acme", "lib", "v1", "lib.zip", map[string]string{"lib/x": "x"})
+	s.AddZig("master", "0.16.0-dev.1")
+
+	get := func(path string) int {
+		t.Helper()
+		resp, err := http.Get(s.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode
+	}
+	if code := get("/github/acme/lib/releases/download/v1/lib.zip"); code != http.StatusOK {
+		t.Errorf("release asset: %d", code)
+	}
+	if code := get("/github/acme/lib/releases/download/v2/lib.zip"); code != http.StatusNotFound {
+		t.Errorf("missing asset: %d", code)
+	}
+	if code := get("/zig/index.json"); code != http.StatusOK {
+		t.Errorf("index: %d", code)
+	}
+	if n := s.Hits("lib.zip"); n != 1 {
+		t.Errorf("Hits = %d, want 1", n)
+	}
+
+	s.AddRelease("acme", "lib", "v2", "lib.zip", map[string]string{"lib/x": "y"})
+	s.AddRelease("acme", "lib", "v2", "lib.tar.gz", map[string]string{"lib/x": "y"})
+	resp, err := http.Get(s.GitHubAPI() + "/repos/acme/lib/releases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var releases []release
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		t.Fatal(err)
+	}
+	if len(releases) != 2 || releases[0].Tag != "v2" || len(releases[0].Assets) != 2 {
+		t.Errorf("releases = %+v, want v2 with 2 assets first", releases)
+	}
+	if code := get("/api/repos/acme/gone/releases"); code != http.StatusNotFound {
+		t.Errorf("missing repo: %d", code)
+	}
+}
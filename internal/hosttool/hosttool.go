@@ -0,0 +1,160 @@
+// Package hosttool locates the host programs gox runs (go, git, the
+// container runtimes and the sysroot runners), honoring paths configured in the [tools] section of
+// gox.toml, so missing tools are reported up front instead of midway
+// through a build.
+package hosttool
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Tool is a host program gox may run.
+type Tool struct {
+	Name     string
+	Purpose  string   // what gox needs it for, shown when it is missing
+	Version  []string // arguments printing its version
+	Optional bool     // only some features use it
+}
+
+// Known lists the tools gox runs, in the order gox doctor reports them.
+var Known = []Tool{
+	{Name: "go", Purpose: "compiles Go packages", Version: []string{"version"}},
+	{Name: "git", Purpose: "provides {{.Version}} and {{.Commit}} in output templates", Version: []string{"--version"}, Optional: true},
+	{Name: "pkg-config", Purpose: "resolves #cgo pkg-config directives against packages", Version: []string{"--version"}, Optional: true},
+	{Name: "vcpkg", Purpose: "installs the ports of vcpkg: packages", Version: []string{"version"}, Optional: true},
+	{Name: "docker", Purpose: "runs --container builds", Version: []string{"--version"}, Optional: true},
+	{Name: "podman", Purpose: "runs --container builds", Version: []string{"--version"}, Optional: true},
+	{Name: "wine", Purpose: "runs windows binaries in gox run and gox test", Version: []string{"--version"}, Optional: true},
+	{Name: "bwrap", Purpose: "runs host-architecture binaries in --sysroot", Version: []string{"--version"}, Optional: true},
+	{Name: "qemu-x86_64", Purpose: "runs linux/amd64 binaries in gox run, gox test and --sysroot", Version: []string{"--version"}, Optional: true},
+	{Name: "qemu-i386", Purpose: "runs linux/386 binaries in gox run, gox test and --sysroot", Version: []string{"--version"}, Optional: true},
+	{Name: "qemu-aarch64", Purpose: "runs linux/arm64 binaries in gox run, gox test and --sysroot", Version: []string{"--version"}, Optional: true},
+	{Name: "qemu-arm", Purpose: "runs linux/arm binaries in gox run, gox test and --sysroot", Version: []string{"--version"}, Optional: true},
+	{Name: "qemu-riscv64", Purpose: "runs linux/riscv64 binaries in gox run, gox test and --sysroot", Version: []string{"--version"}, Optional: true},
+	{Name: "qemu-loongarch64", Purpose: "runs linux/loong64 binaries in gox run, gox test and --sysroot", Version: []string{"--version"}, Optional: true},
+	{Name: "qemu-ppc64le", Purpose: "runs linux/ppc64le binaries in gox run, gox test and --sysroot", Version: []string{"--version"}, Optional: true},
+	{Name: "qemu-s390x", Purpose: "runs linux/s390x binaries in gox run, gox test and --sysroot", Version: []string{"--version"}, Optional: true},
+}
+
+var (
+	mu    sync.RWMutex
+	paths map[string]string
+)
+
+// Use sets explicit tool paths, e.g. from [tools], replacing earlier ones.
+// Unknown tool names are rejected.
+func Use(p map[string]string) error {
+	for name := range p {
+		if _, ok := lookup(name); !ok {
+			return fmt.Errorf("unknown tool %q in [tools] (known: %s)", name, strings.Join(names(), ", "))
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	paths = p
+	return nil
+}
+
+// Configured returns the path set for name with Use, or "".
+func Configured(name string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return paths[name]
+}
+
+// MissingError reports a tool that is neither configured nor in PATH, or
+// whose configured path does not work.
+type MissingError struct {
+	Tool Tool
+	Path string // configured path, if any
+	Err  error
+}
+
+func (e *MissingError) Error() string {
+	if e.Path != "" {
+		return fmt.Sprintf("%s not found at %s, set by [tools] %s in gox.toml", e.Tool.Name, e.Path, e.Tool.Name)
+	}
+	return fmt.Sprintf("%s not found (%s): install it or set [tools] %s = \"/path/to/%s\" in gox.toml",
+		e.Tool.Name, e.Tool.Purpose, e.Tool.Name, e.Tool.Name)
+}
+
+func (e *MissingError) Unwrap() error { return e.Err }
+
+// Path returns the executable for name: its configured path, or the first
+// match in PATH.
+func Path(name string) (string, error) {
+	t, ok := lookup(name)
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", name)
+	}
+	file := Configured(name)
+	p, err := exec.LookPath(cmp.Or(file, name))
+	if err != nil {
+		return "", &MissingError{Tool: t, Path: file, Err: err}
+	}
+	return p, nil
+}
+
+// Command returns an exec.Cmd running tool name. When the tool cannot be
+// found the command runs the configured path or bare name, so Run reports
+// the error.
+func Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	p, err := Path(name)
+	if err != nil {
+		p = cmp.Or(Configured(name), name)
+	}
+	return exec.CommandContext(ctx, p, args...)
+}
+
+// Version returns the first line the tool prints for its version.
+func Version(ctx context.Context, name string) (string, error) {
+	t, ok := lookup(name)
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", name)
+	}
+	p, err := Path(name)
+	if err != nil {
+		return "", err
+	}
+	out, err := exec.CommandContext(ctx, p, t.Version...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w", name, strings.Join(t.Version, " "), err)
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(line), nil
+}
+
+// Require checks that every named tool can be found and reports all missing
+// ones together.
+func Require(names ...string) error {
+	var errs []error
+	for _, name := range slices.Compact(slices.Sorted(slices.Values(names))) {
+		if _, err := Path(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func lookup(name string) (Tool, bool) {
+	i := slices.IndexFunc(Known, func(t Tool) bool { return t.Name == name })
+	if i < 0 {
+		return Tool{}, false
+	}
+	return Known[i], true
+}
+
+func names() []string {
+	out := make([]string, len(Known))
+	for i, t := range Known {
+		out[i] = t.Name
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package hosttool
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakeTool writes an executable script printing out for any arguments.
+func fakeTool(t *testing.T, name, out string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts")
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho '"+out+"'\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestUse(t *testing.T) {
+	t.Cleanup(func() { _ = Use(nil) })
+
+	if err := Use(map[string]string{"upx": "/usr/bin/upx"}); err == nil {
+		t.Error("Use() accepted an unknown tool")
+	}
+	if err := Use(map[string]string{"git": "/opt/git"}); err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+	if got := Configured("git"); got != "/opt/git" {
+		t.Errorf("Configured(git) = %q, want /opt/git", got)
+	}
+}
+
+func TestPath(t *testing.T) {
+	t.Cleanup(func() { _ = Use(nil) })
+	git := fakeTool(t, "git", "git version 2.99.0\nextra")
+
+	if err := Use(map[string]string{"git": git}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Path("git"); err != nil || got != git {
+		t.Errorf("Path(git) = %q, %v; want %q", got, err, git)
+	}
+	v, err := Version(context.Background(), "git")
+	if err != nil || v != "git version 2.99.0" {
+		t.Errorf("Version(git) = %q, %v", v, err)
+	}
+
+	if err := Use(map[string]string{"docker": filepath.Join(t.TempDir(), "docker")}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = Path("docker")
+	var missing *MissingError
+	if !errors.As(err, &missing) || missing.Tool.Name != "docker" {
+		t.Errorf("Path(docker) error = %v, want MissingError", err)
+	}
+	if _, err := Path("upx"); err == nil {
+		t.Error("Path(upx) of an unknown tool succeeded")
+	}
+}
+
+func TestRequire(t *testing.T) {
+	t.Cleanup(func() { _ = Use(nil) })
+	dir := t.TempDir()
+	err := Use(map[string]string{
+		"go":  fakeTool(t, "go", "go version go1.25.0"),
+		"git": filepath.Join(dir, "git"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Require("go", "go"); err != nil {
+		t.Errorf("Require(go) error = %v", err)
+	}
+	err = Require("go", "git")
+	var missing *MissingError
+	if !errors.As(err, &missing) || missing.Tool.Name != "git" {
+		t.Errorf("Require(go, git) error = %v, want git missing", err)
+	}
+}
@@ -0,0 +1,57 @@
+// Package httpclient provides the HTTP client shared by all gox network
+// access: pooled keep-alive connections, HTTP/2, sane dial and header
+// timeouts and a User-Agent identifying the gox version.
+package httpclient
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// Client is the shared client. It sets no overall timeout so large
+// downloads are bounded by their request context instead.
+var Client = New()
+
+// New returns a client configured like Client.
+func New() *http.Client {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	t.ForceAttemptHTTP2 = true
+	t.MaxIdleConns = 100
+	t.MaxIdleConnsPerHost = 16
+	t.IdleConnTimeout = 90 * time.Second
+	t.TLSHandshakeTimeout = 10 * time.Second
+	t.ResponseHeaderTimeout = 30 * time.Second
+	return &http.Client{Transport: &userAgent{base: t, value: UserAgent()}}
+}
+
+// Version returns the gox module version, or "devel" for local builds.
+func Version() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "devel"
+}
+
+// UserAgent returns the User-Agent sent with every request.
+func UserAgent() string {
+	return fmt.Sprintf("gox/%s (%s; %s/%s)", Version(), runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+// userAgent sets the User-Agent header unless the request already has one.
+type userAgent struct {
+	base  http.RoundTripper
+	value string
+}
+
+func (u *userAgent) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", u.value)
+	}
+	return u.base.RoundTrip(req)
+}
@@ -0,0 +1,50 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_UserAgent(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+	}))
+	defer srv.Close()
+
+	resp, err := Client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if !strings.HasPrefix(got, "gox/") {
+		t.Errorf("User-Agent = %q, want gox/ prefix", got)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("User-Agent", "custom")
+	resp, err = Client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got != "custom" {
+		t.Errorf("User-Agent = %q, want explicit header kept", got)
+	}
+}
+
+func TestNew_Transport(t *testing.T) {
+	ua, ok := New().Transport.(*userAgent)
+	if !ok {
+		t.Fatalf("Transport = %T, want *userAgent", New().Transport)
+	}
+	tr := ua.base.(*http.Transport)
+	if !tr.ForceAttemptHTTP2 {
+		t.Error("ForceAttemptHTTP2 = false, want true")
+	}
+	if tr.MaxIdleConnsPerHost < 2 {
+		t.Errorf("MaxIdleConnsPerHost = %d, want pooled connections", tr.MaxIdleConnsPerHost)
+	}
+}
@@ -0,0 +1,123 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Policy controls how failed requests are retried.
+type Policy struct {
+	Attempts   int           // total tries including the first; 1 disables retries
+	Backoff    time.Duration // delay before the first retry, doubled after each
+	MaxBackoff time.Duration // cap on a single delay
+}
+
+// DefaultPolicy is used unless gox.toml configures [default.retry].
+var DefaultPolicy = Policy{Attempts: 4, Backoff: 500 * time.Millisecond, MaxBackoff: 15 * time.Second}
+
+var policy = DefaultPolicy
+
+// SetPolicy replaces the retry policy for the rest of the process. Zero
+// fields keep their DefaultPolicy values.
+func SetPolicy(p Policy) {
+	if p.Attempts <= 0 {
+		p.Attempts = DefaultPolicy.Attempts
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = DefaultPolicy.Backoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = max(DefaultPolicy.MaxBackoff, p.Backoff)
+	}
+	policy = p
+}
+
+// CurrentPolicy returns the active retry policy.
+func CurrentPolicy() Policy {
+	return policy
+}
+
+// StatusError is an unexpected HTTP response status.
+type StatusError struct {
+	Code       int
+	RetryAfter time.Duration // from the Retry-After header, if any
+}
+
+// NewStatusError describes resp as an unexpected status.
+func NewStatusError(resp *http.Response) *StatusError {
+	e := &StatusError{Code: resp.StatusCode}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		e.RetryAfter = time.Duration(secs) * time.Second
+	}
+	return e
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP %d", e.Code)
+}
+
+// Retryable reports whether err is transient: a dropped or refused
+// connection, a timeout, a truncated body, or a 5xx/429 status. Context
+// cancellation is never retried.
+func Retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code >= 500 || se.Code == http.StatusTooManyRequests
+	}
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		err = ue.Err
+	}
+	var ne net.Error
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.As(err, &ne)
+}
+
+// Retry calls fn until it succeeds, returns a non-retryable error, or the
+// policy runs out of attempts, sleeping with exponential backoff and jitter
+// between tries.
+func Retry(ctx context.Context, fn func() error) error {
+	p := policy
+	var err error
+	for attempt := range p.Attempts {
+		if err = fn(); !Retryable(err) || attempt == p.Attempts-1 {
+			return err
+		}
+		select {
+		case <-time.After(p.delay(attempt, err)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+	return err
+}
+
+// delay returns the wait before retry attempt+1: a random point in the
+// upper half of the exponential backoff, or the server's Retry-After.
+func (p Policy) delay(attempt int, err error) time.Duration {
+	var se *StatusError
+	if errors.As(err, &se) && se.RetryAfter > 0 {
+		return min(se.RetryAfter, p.MaxBackoff)
+	}
+	d := min(p.Backoff<<attempt, p.MaxBackoff)
+	if d <= 0 {
+		d = p.MaxBackoff
+	}
+	return d/2 + rand.N(d/2+1)
+}
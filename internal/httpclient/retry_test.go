@@ -0,0 +1,104 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"canceled", context.Canceled, false},
+		{"5xx", &StatusError{Code: 503}, true},
+		{"429", &StatusError{Code: 429}, true},
+		{"404", &StatusError{Code: 404}, false},
+		{"reset", &url.Error{Op: "Get", URL: "x", Err: syscall.ECONNRESET}, true},
+		{"truncated body", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{"other", errors.New("checksum mismatch"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Retryable(tt.err); got != tt.want {
+				t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	old := CurrentPolicy()
+	t.Cleanup(func() { policy = old })
+	SetPolicy(Policy{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+	t.Run("succeeds after transient errors", func(t *testing.T) {
+		calls := 0
+		err := Retry(context.Background(), func() error {
+			if calls++; calls < 3 {
+				return &StatusError{Code: 502}
+			}
+			return nil
+		})
+		if err != nil || calls != 3 {
+			t.Errorf("Retry() = %v after %d calls, want nil after 3", err, calls)
+		}
+	})
+
+	t.Run("gives up after attempts", func(t *testing.T) {
+		calls := 0
+		err := Retry(context.Background(), func() error {
+			calls++
+			return &StatusError{Code: 500}
+		})
+		if err == nil || calls != 3 {
+			t.Errorf("Retry() = %v after %d calls, want error after 3", err, calls)
+		}
+	})
+
+	t.Run("stops on permanent error", func(t *testing.T) {
+		calls := 0
+		_ = Retry(context.Background(), func() error {
+			calls++
+			return &StatusError{Code: http.StatusNotFound}
+		})
+		if calls != 1 {
+			t.Errorf("calls = %d, want 1", calls)
+		}
+	})
+}
+
+func TestPolicyDelay(t *testing.T) {
+	p := Policy{Attempts: 5, Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for attempt, want := range []time.Duration{100, 200, 400, 800, 1000} {
+		want *= time.Millisecond
+		if attempt == 4 {
+			want = time.Second
+		}
+		d := p.delay(attempt, nil)
+		if d < want/2 || d > want {
+			t.Errorf("delay(%d) = %v, want in [%v, %v]", attempt, d, want/2, want)
+		}
+	}
+	if d := p.delay(0, &StatusError{Code: 503, RetryAfter: 5 * time.Second}); d != time.Second {
+		t.Errorf("delay(Retry-After 5s) = %v, want capped at 1s", d)
+	}
+}
+
+func TestSetPolicy_Defaults(t *testing.T) {
+	old := CurrentPolicy()
+	t.Cleanup(func() { policy = old })
+	SetPolicy(Policy{Attempts: 1})
+	if p := CurrentPolicy(); p.Attempts != 1 || p.Backoff != DefaultPolicy.Backoff || p.MaxBackoff != DefaultPolicy.MaxBackoff {
+		t.Errorf("CurrentPolicy() = %+v", p)
+	}
+}
@@ -0,0 +1,145 @@
+package linuxpkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// apkArchs maps GOARCH to Alpine architecture names.
+var apkArchs = map[string]string{
+	"386":     "x86",
+	"amd64":   "x86_64",
+	"arm":     "armv7",
+	"arm64":   "aarch64",
+	"loong64": "loongarch64",
+	"ppc64le": "ppc64le",
+	"riscv64": "riscv64",
+	"s390x":   "s390x",
+}
+
+// APKArch returns the Alpine architecture of goarch.
+func APKArch(goarch string) (string, bool) {
+	a, ok := apkArchs[goarch]
+	return a, ok
+}
+
+var (
+	apkBase = regexp.MustCompile(`^[0-9]+(\.[0-9]+)*[a-z]?$`)
+	apkPre  = regexp.MustCompile(`^(alpha|beta|pre|rc)\.?([0-9]*)`)
+)
+
+// APKVersion returns v as an Alpine package version with its release,
+// which apk only accepts as numbers with known suffixes: a leading "v" is
+// dropped, pre-releases (1.2.0-rc1) become 1.2.0_rc1 and the commits since
+// a tag in git describe output (1.2.0-3-gabc1234) a patch level,
+// 1.2.0_p3. Versions apk cannot order, such as bare commit hashes, are 0.
+func APKVersion(v string) string {
+	base, rest, _ := strings.Cut(strings.TrimPrefix(v, "v"), "-")
+	if !apkBase.MatchString(base) {
+		return "0-r0"
+	}
+	switch {
+	case rest == "":
+	case unicode.IsDigit(rune(rest[0])):
+		n, _, _ := strings.Cut(rest, "-")
+		base += "_p" + n
+	default:
+		if m := apkPre.FindStringSubmatch(rest); m != nil {
+			base += "_" + m[1] + m[2]
+		}
+	}
+	return base + "-r0"
+}
+
+// APKFile returns the conventional file name of p as an .apk:
+// <name>-<version>-r0.apk.
+func (p *Package) APKFile() string {
+	return fmt.Sprintf("%s-%s.apk", p.Name, APKVersion(p.Version))
+}
+
+// WriteAPK writes p to w as an unsigned apk v2 package: the gzip streams of
+// the control tarball, cut before its end so apk reads both as one, and the
+// data tarball. Alpine installs unsigned packages with --allow-untrusted,
+// or after abuild-sign adds a signature.
+func (p *Package) WriteAPK(w io.Writer) error {
+	arch, ok := APKArch(p.Arch)
+	if !ok {
+		return fmt.Errorf("apk: no Alpine architecture for %s", p.Arch)
+	}
+	var pl *payload
+	data, err := tgz(func(tw *tar.Writer) (err error) {
+		pl, err = p.writeTar(tw, "", true)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+
+	var info bytes.Buffer
+	info.WriteString("# Generated by gox\n")
+	fmt.Fprintf(&info, "pkgname = %s\n", p.Name)
+	fmt.Fprintf(&info, "pkgver = %s\n", APKVersion(p.Version))
+	fmt.Fprintf(&info, "pkgdesc = %s\n", p.summary())
+	if p.Homepage != "" {
+		fmt.Fprintf(&info, "url = %s\n", p.Homepage)
+	}
+	fmt.Fprintf(&info, "builddate = %d\n", p.ModTime.Unix())
+	if p.Maintainer != "" {
+		fmt.Fprintf(&info, "packager = %s\n", p.Maintainer)
+		fmt.Fprintf(&info, "maintainer = %s\n", p.Maintainer)
+	}
+	fmt.Fprintf(&info, "size = %d\n", pl.size)
+	fmt.Fprintf(&info, "arch = %s\n", arch)
+	fmt.Fprintf(&info, "origin = %s\n", p.Name)
+	if p.License != "" {
+		fmt.Fprintf(&info, "license = %s\n", p.License)
+	}
+	for _, d := range p.Depends {
+		// apk writes constraints without spaces: name>=1.0.
+		fmt.Fprintf(&info, "depend = %s\n", strings.Join(strings.Fields(strings.NewReplacer("(", " ", ")", " ").Replace(d)), ""))
+	}
+	fmt.Fprintf(&info, "datahash = %s\n", hex.EncodeToString(sum[:]))
+
+	files := []tarFile{{".PKGINFO", 0o644, info.Bytes()}}
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{
+		{".pre-install", p.Scripts.PreInstall},
+		{".post-install", p.Scripts.PostInstall},
+		{".pre-deinstall", p.Scripts.PreRemove},
+		{".post-deinstall", p.Scripts.PostRemove},
+	} {
+		if len(f.data) > 0 {
+			files = append(files, tarFile{f.name, 0o755, f.data})
+		}
+	}
+	var control bytes.Buffer
+	gw := gzip.NewWriter(&control)
+	tw := tar.NewWriter(gw)
+	if err := writeTarFiles(tw, p.ModTime, files...); err != nil {
+		return err
+	}
+	// Flush, not Close: the end-of-archive blocks would hide the data.
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(control.Bytes()); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
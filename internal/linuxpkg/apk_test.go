@@ -0,0 +1,117 @@
+package linuxpkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWriteAPK(t *testing.T) {
+	p := testPackage(t)
+	p.Arch = "amd64"
+	p.License = "MIT"
+	p.Depends = []string{"ca-certificates", "musl (>= 1.2)"}
+	p.Scripts.PostInstall = []byte("#!/bin/sh\necho hi\n")
+	var buf bytes.Buffer
+	if err := p.WriteAPK(&buf); err != nil {
+		t.Fatalf("WriteAPK() error = %v", err)
+	}
+
+	// apk reads the gzip streams as one tarball.
+	gr, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	var names []string
+	files := map[string]string{}
+	var appSum string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(tr)
+		names = append(names, hdr.Name)
+		files[hdr.Name] = string(b)
+		if hdr.Name == "usr/bin/app" {
+			appSum = hdr.PAXRecords["APK-TOOLS.checksum.SHA1"]
+		}
+	}
+	want := []string{
+		".PKGINFO", ".post-install",
+		"usr/", "usr/bin/", "usr/bin/app", "usr/lib/", "usr/lib/libfoo.so", "usr/lib/libfoo.so.1",
+		"usr/lib/systemd/", "usr/lib/systemd/system/", "usr/lib/systemd/system/app.service",
+	}
+	if !slices.Equal(names, want) {
+		t.Errorf("entries = %q, want %q", names, want)
+	}
+	if sum := sha1.Sum([]byte("binary")); appSum != hex.EncodeToString(sum[:]) {
+		t.Errorf("usr/bin/app checksum = %q", appSum)
+	}
+
+	// The data stream starts after the control stream.
+	br := bytes.NewReader(buf.Bytes())
+	gr, err = gzip.NewReader(br)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gr.Multistream(false)
+	if _, err := io.Copy(io.Discard, gr); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()[buf.Len()-br.Len():]
+	sum := sha256.Sum256(data)
+
+	info := files[".PKGINFO"]
+	for _, line := range []string{
+		"pkgname = app", "pkgver = 1.2.3-r0", "pkgdesc = An app", "arch = x86_64", "license = MIT",
+		"size = 19", "depend = ca-certificates", "depend = musl>=1.2", "builddate = 1700000000",
+		"datahash = " + hex.EncodeToString(sum[:]),
+	} {
+		if !strings.Contains(info, "\n"+line+"\n") {
+			t.Errorf(".PKGINFO missing %q:\n%s", line, info)
+		}
+	}
+}
+
+func TestWriteAPK_UnknownArch(t *testing.T) {
+	p := testPackage(t)
+	p.Arch = "wasm"
+	if err := p.WriteAPK(io.Discard); err == nil {
+		t.Error("WriteAPK() for wasm succeeded")
+	}
+}
+
+func TestAPKVersion(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"v1.2.3", "1.2.3-r0"},
+		{"1.2.3-rc1", "1.2.3_rc1-r0"},
+		{"1.2.3-beta.2", "1.2.3_beta2-r0"},
+		{"1.2.3-4-gabc1234-dirty", "1.2.3_p4-r0"},
+		{"abc1234", "0-r0"},
+		{"", "0-r0"},
+	}
+	for _, tt := range tests {
+		if got := APKVersion(tt.in); got != tt.want {
+			t.Errorf("APKVersion(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAPKFile(t *testing.T) {
+	p := &Package{Name: "app", Version: "v1.0.0", Arch: "arm64"}
+	if got, want := p.APKFile(), "app-1.0.0-r0.apk"; got != want {
+		t.Errorf("APKFile() = %q, want %q", got, want)
+	}
+}
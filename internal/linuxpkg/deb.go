@@ -0,0 +1,193 @@
+package linuxpkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// debArchs maps GOARCH to Debian architecture names.
+var debArchs = map[string]string{
+	"386":     "i386",
+	"amd64":   "amd64",
+	"arm":     "armhf",
+	"arm64":   "arm64",
+	"loong64": "loong64",
+	"ppc64le": "ppc64el",
+	"riscv64": "riscv64",
+	"s390x":   "s390x",
+}
+
+// DebArch returns the Debian architecture of goarch.
+func DebArch(goarch string) (string, bool) {
+	a, ok := debArchs[goarch]
+	return a, ok
+}
+
+// DebVersion returns v as a Debian version, which must start with a digit:
+// a leading "v" is dropped, a missing version is 0 and versions such as
+// bare commit hashes sort before any release.
+func DebVersion(v string) string {
+	switch v = strings.TrimPrefix(v, "v"); {
+	case v == "":
+		return "0"
+	case !unicode.IsDigit(rune(v[0])):
+		return "0~" + v
+	}
+	return v
+}
+
+// DebFile returns the conventional file name of p as a .deb:
+// <name>_<version>_<arch>.deb.
+func (p *Package) DebFile() string {
+	arch, _ := DebArch(p.Arch)
+	return fmt.Sprintf("%s_%s_%s.deb", p.Name, DebVersion(p.Version), arch)
+}
+
+// WriteDeb writes p to w as a Debian binary package: an ar archive of
+// debian-binary, control.tar.gz and data.tar.gz.
+func (p *Package) WriteDeb(w io.Writer) error {
+	if _, ok := DebArch(p.Arch); !ok {
+		return fmt.Errorf("deb: no Debian architecture for %s", p.Arch)
+	}
+	var pl *payload
+	data, err := tgz(func(tw *tar.Writer) (err error) {
+		pl, err = p.writeTar(tw, "./", false)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	control, err := tgz(func(tw *tar.Writer) error {
+		return writeTarFiles(tw, p.ModTime, p.debControl(pl)...)
+	})
+	if err != nil {
+		return err
+	}
+	return writeAr(w, p.ModTime,
+		arMember{"debian-binary", []byte("2.0\n")},
+		arMember{"control.tar.gz", control},
+		arMember{"data.tar.gz", data},
+	)
+}
+
+// debControl returns the files of control.tar.gz: the control file, the
+// md5sums of the payload and the maintainer scripts.
+func (p *Package) debControl(pl *payload) []tarFile {
+	arch, _ := DebArch(p.Arch)
+	var c bytes.Buffer
+	fmt.Fprintf(&c, "Package: %s\n", p.Name)
+	fmt.Fprintf(&c, "Version: %s\n", DebVersion(p.Version))
+	fmt.Fprintf(&c, "Architecture: %s\n", arch)
+	fmt.Fprintf(&c, "Maintainer: %s\n", p.Maintainer)
+	fmt.Fprintf(&c, "Installed-Size: %d\n", (pl.size+1023)/1024)
+	if len(p.Depends) > 0 {
+		fmt.Fprintf(&c, "Depends: %s\n", strings.Join(p.Depends, ", "))
+	}
+	c.WriteString("Priority: optional\n")
+	if p.Homepage != "" {
+		fmt.Fprintf(&c, "Homepage: %s\n", p.Homepage)
+	}
+	fmt.Fprintf(&c, "Description: %s\n", p.summary())
+	for line := range strings.Lines(p.details()) {
+		// Continuation lines are indented; "." stands for an empty one.
+		if line = strings.TrimRight(line, " \t\r\n"); line == "" {
+			line = "."
+		}
+		fmt.Fprintf(&c, " %s\n", line)
+	}
+
+	var sums bytes.Buffer
+	for _, name := range slices.Sorted(maps.Keys(pl.md5)) {
+		fmt.Fprintf(&sums, "%s  %s\n", pl.md5[name], name)
+	}
+
+	files := []tarFile{{"./control", 0o644, c.Bytes()}, {"./md5sums", 0o644, sums.Bytes()}}
+	s := p.debScripts()
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{
+		{"preinst", s.PreInstall},
+		{"postinst", s.PostInstall},
+		{"prerm", s.PreRemove},
+		{"postrm", s.PostRemove},
+	} {
+		if len(f.data) > 0 {
+			files = append(files, tarFile{"./" + f.name, 0o755, f.data})
+		}
+	}
+	return files
+}
+
+// debScripts returns the maintainer scripts of p. Scripts p leaves empty
+// enable and start its systemd units on install and stop them on removal.
+func (p *Package) debScripts() Scripts {
+	s := p.Scripts
+	if len(p.Units) == 0 {
+		return s
+	}
+	units := strings.Join(p.Units, " ")
+	if len(s.PostInstall) == 0 {
+		s.PostInstall = fmt.Appendf(nil, `#!/bin/sh
+set -e
+if [ "$1" = configure ] && [ -d /run/systemd/system ]; then
+	systemctl daemon-reload
+	systemctl enable --now %s
+fi
+`, units)
+	}
+	if len(s.PreRemove) == 0 {
+		s.PreRemove = fmt.Appendf(nil, `#!/bin/sh
+set -e
+if [ "$1" = remove ] && [ -d /run/systemd/system ]; then
+	systemctl disable --now %s || true
+fi
+`, units)
+	}
+	if len(s.PostRemove) == 0 {
+		s.PostRemove = []byte(`#!/bin/sh
+set -e
+if [ -d /run/systemd/system ]; then
+	systemctl daemon-reload || true
+fi
+`)
+	}
+	return s
+}
+
+// arMember is one file of an ar archive.
+type arMember struct {
+	name string
+	data []byte
+}
+
+// writeAr writes members to w as a common-format ar archive, the container
+// of a .deb, owned by root and dated mtime.
+func writeAr(w io.Writer, mtime time.Time, members ...arMember) error {
+	if _, err := io.WriteString(w, "!<arch>\n"); err != nil {
+		return err
+	}
+	for _, m := range members {
+		hdr := fmt.Sprintf("%-16s%-12d%-6d%-6d%-8o%-10d`\n", m.name, mtime.Unix(), 0, 0, 0o100644, len(m.data))
+		if _, err := io.WriteString(w, hdr); err != nil {
+			return err
+		}
+		if _, err := w.Write(m.data); err != nil {
+			return err
+		}
+		if len(m.data)%2 == 1 {
+			// Members start at even offsets.
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package linuxpkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readAr returns the members of the ar archive data by name, in order.
+func readAr(t *testing.T, data []byte) ([]string, map[string][]byte) {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("!<arch>\n")) {
+		t.Fatal("missing ar magic")
+	}
+	data = data[8:]
+	var names []string
+	members := map[string][]byte{}
+	for len(data) > 0 {
+		if len(data) < 60 || string(data[58:60]) != "`\n" {
+			t.Fatalf("bad ar header %q", data[:min(len(data), 60)])
+		}
+		name := strings.TrimSpace(string(data[:16]))
+		size, err := strconv.Atoi(strings.TrimSpace(string(data[48:58])))
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+		members[name] = data[60 : 60+size]
+		data = data[60+size+size%2:]
+	}
+	return names, members
+}
+
+// readTgz returns the headers and regular file contents of a tar.gz.
+func readTgz(t *testing.T, data []byte) ([]*tar.Header, map[string]string) {
+	t.Helper()
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	var hdrs []*tar.Header
+	files := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		hdrs = append(hdrs, hdr)
+		if hdr.Typeflag == tar.TypeReg {
+			b, _ := io.ReadAll(tr)
+			files[hdr.Name] = string(b)
+		}
+	}
+	return hdrs, files
+}
+
+func testPackage(t *testing.T) *Package {
+	t.Helper()
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	return &Package{
+		Name:        "app",
+		Version:     "v1.2.3",
+		Arch:        "arm64",
+		Maintainer:  "Jane Doe <jane@example.com>",
+		Description: "An app\nIt does things.\n\nWell.",
+		Homepage:    "https://example.com",
+		Depends:     []string{"libc6", "ca-certificates"},
+		Units:       []string{"app.service"},
+		Files: []File{
+			{Src: write("app", "binary"), Dst: "/usr/bin/app", Mode: 0o755},
+			{Src: write("libfoo.so.1", "lib"), Dst: "/usr/lib/libfoo.so.1", Mode: 0o644},
+			{Dst: "/usr/lib/libfoo.so", Mode: fs.ModeSymlink | 0o777, Link: "libfoo.so.1"},
+			{Src: write("app.service", "[Service]\n"), Dst: "/usr/lib/systemd/system/app.service", Mode: 0o644},
+		},
+		ModTime: time.Unix(1700000000, 0),
+	}
+}
+
+func TestWriteDeb(t *testing.T) {
+	p := testPackage(t)
+	var buf bytes.Buffer
+	if err := p.WriteDeb(&buf); err != nil {
+		t.Fatalf("WriteDeb() error = %v", err)
+	}
+	names, members := readAr(t, buf.Bytes())
+	if want := []string{"debian-binary", "control.tar.gz", "data.tar.gz"}; !slices.Equal(names, want) {
+		t.Fatalf("members = %q, want %q", names, want)
+	}
+	if got := string(members["debian-binary"]); got != "2.0\n" {
+		t.Errorf("debian-binary = %q", got)
+	}
+
+	_, control := readTgz(t, members["control.tar.gz"])
+	wantControl := `Package: app
+Version: 1.2.3
+Architecture: arm64
+Maintainer: Jane Doe <jane@example.com>
+Installed-Size: 1
+Depends: libc6, ca-certificates
+Priority: optional
+Homepage: https://example.com
+Description: An app
+ It does things.
+ .
+ Well.
+`
+	if got := control["./control"]; got != wantControl {
+		t.Errorf("control =\n%s\nwant\n%s", got, wantControl)
+	}
+	if got := control["./md5sums"]; !strings.Contains(got, "  usr/bin/app\n") || strings.Contains(got, "libfoo.so\n") {
+		t.Errorf("md5sums = %q, want regular files only", got)
+	}
+	if got := control["./postinst"]; !strings.Contains(got, "systemctl enable --now app.service") {
+		t.Errorf("postinst = %q, want the unit enabled", got)
+	}
+	if got := control["./prerm"]; !strings.Contains(got, "systemctl disable --now app.service") {
+		t.Errorf("prerm = %q, want the unit stopped", got)
+	}
+	if _, ok := control["./preinst"]; ok {
+		t.Error("preinst written without a script")
+	}
+
+	hdrs, data := readTgz(t, members["data.tar.gz"])
+	var entries []string
+	for _, h := range hdrs {
+		entries = append(entries, h.Name)
+		if h.Uname != "root" || h.Uid != 0 || !h.ModTime.Equal(p.ModTime) {
+			t.Errorf("%s: owner %s/%d, time %v", h.Name, h.Uname, h.Uid, h.ModTime)
+		}
+	}
+	wantEntries := []string{
+		"./usr/", "./usr/bin/", "./usr/bin/app", "./usr/lib/", "./usr/lib/libfoo.so", "./usr/lib/libfoo.so.1",
+		"./usr/lib/systemd/", "./usr/lib/systemd/system/", "./usr/lib/systemd/system/app.service",
+	}
+	if !slices.Equal(entries, wantEntries) {
+		t.Errorf("data entries = %q, want %q", entries, wantEntries)
+	}
+	if data["./usr/bin/app"] != "binary" {
+		t.Errorf("usr/bin/app = %q", data["./usr/bin/app"])
+	}
+	for _, h := range hdrs {
+		switch h.Name {
+		case "./usr/bin/app":
+			if h.Mode != 0o755 {
+				t.Errorf("app mode = %o, want 755", h.Mode)
+			}
+		case "./usr/lib/libfoo.so":
+			if h.Typeflag != tar.TypeSymlink || h.Linkname != "libfoo.so.1" {
+				t.Errorf("libfoo.so = %c -> %q, want a symlink to libfoo.so.1", h.Typeflag, h.Linkname)
+			}
+		}
+	}
+
+	var again bytes.Buffer
+	if err := p.WriteDeb(&again); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), again.Bytes()) {
+		t.Error("WriteDeb() is not deterministic")
+	}
+}
+
+func TestWriteDeb_Scripts(t *testing.T) {
+	p := testPackage(t)
+	p.Scripts = Scripts{PreInstall: []byte("#!/bin/sh\necho pre\n"), PostInstall: []byte("#!/bin/sh\necho post\n")}
+	var buf bytes.Buffer
+	if err := p.WriteDeb(&buf); err != nil {
+		t.Fatal(err)
+	}
+	_, members := readAr(t, buf.Bytes())
+	hdrs, control := readTgz(t, members["control.tar.gz"])
+	if got := control["./postinst"]; got != "#!/bin/sh\necho post\n" {
+		t.Errorf("postinst = %q, want the package's own", got)
+	}
+	if got := control["./preinst"]; got != "#!/bin/sh\necho pre\n" {
+		t.Errorf("preinst = %q", got)
+	}
+	for _, h := range hdrs {
+		if h.Name == "./preinst" && h.Mode != 0o755 {
+			t.Errorf("preinst mode = %o, want 755", h.Mode)
+		}
+	}
+}
+
+func TestWriteDeb_UnknownArch(t *testing.T) {
+	p := testPackage(t)
+	p.Arch = "wasm"
+	if err := p.WriteDeb(io.Discard); err == nil {
+		t.Error("WriteDeb() for wasm succeeded")
+	}
+}
+
+func TestDebVersion(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"v1.2.3", "1.2.3"},
+		{"1.2.3-4-gabc1234-dirty", "1.2.3-4-gabc1234-dirty"},
+		{"abc1234", "0~abc1234"},
+		{"", "0"},
+	}
+	for _, tt := range tests {
+		if got := DebVersion(tt.in); got != tt.want {
+			t.Errorf("DebVersion(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDebFile(t *testing.T) {
+	p := &Package{Name: "app", Version: "v1.0.0", Arch: "arm"}
+	if got, want := p.DebFile(), "app_1.0.0_armhf.deb"; got != want {
+		t.Errorf("DebFile() = %q, want %q", got, want)
+	}
+}
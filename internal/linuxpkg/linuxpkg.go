@@ -0,0 +1,193 @@
+// Package linuxpkg writes Linux distribution packages from a list of files
+// to install, so releases need no dpkg or other packaging tools.
+package linuxpkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Package describes a binary package: its metadata and the files it
+// installs.
+type Package struct {
+	Name        string
+	Version     string
+	Arch        string // GOARCH
+	Maintainer  string
+	Description string // the first line is the summary
+	Homepage    string
+	License     string
+	Depends     []string
+	Scripts     Scripts
+	Units       []string // systemd units among Files, enabled on install
+	Files       []File
+	ModTime     time.Time // time of every entry
+}
+
+// Scripts are the shell scripts run around installation and removal. Empty
+// scripts are left out, unless the package has systemd units to manage.
+type Scripts struct {
+	PreInstall  []byte
+	PostInstall []byte
+	PreRemove   []byte
+	PostRemove  []byte
+}
+
+// File is one file the package installs. Directories leading to it are
+// created as needed.
+type File struct {
+	Src  string      // file on disk; unused for symlinks
+	Dst  string      // absolute install path
+	Mode fs.FileMode // permissions, with fs.ModeSymlink for symlinks
+	Link string      // symlink target
+}
+
+// entry is a file or directory of a package payload.
+type entry struct {
+	name string // path without the leading slash, directories end in /
+	file *File  // nil for directories
+}
+
+// entries returns the files of p and the directories holding them, in
+// lexical order.
+func (p *Package) entries() []entry {
+	dirs := map[string]bool{}
+	var out []entry
+	for i := range p.Files {
+		f := &p.Files[i]
+		name := strings.TrimPrefix(path.Clean("/"+f.Dst), "/")
+		out = append(out, entry{name, f})
+		for d := path.Dir(name); d != "."; d = path.Dir(d) {
+			dirs[d+"/"] = true
+		}
+	}
+	for d := range dirs {
+		out = append(out, entry{name: d})
+	}
+	slices.SortFunc(out, func(a, b entry) int { return strings.Compare(a.name, b.name) })
+	return out
+}
+
+// payload holds what writing the files of a package found out about them.
+type payload struct {
+	size int64             // bytes of regular files
+	md5  map[string]string // hex MD5 of regular files, by entry name
+}
+
+// writeTar writes the entries of p to tw, each name prefixed with prefix,
+// owned by root and dated p.ModTime. With apkSums, regular files carry
+// their SHA-1 in the PAX record apk verifies.
+func (p *Package) writeTar(tw *tar.Writer, prefix string, apkSums bool) (*payload, error) {
+	pl := &payload{md5: map[string]string{}}
+	for _, e := range p.entries() {
+		hdr := &tar.Header{
+			Name:     prefix + e.name,
+			ModTime:  p.ModTime,
+			Typeflag: tar.TypeDir,
+			Mode:     0o755,
+			Uname:    "root",
+			Gname:    "root",
+		}
+		if e.file == nil {
+			if err := tw.WriteHeader(hdr); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		f := e.file
+		hdr.Mode = int64(f.Mode.Perm())
+		if f.Mode&fs.ModeSymlink != 0 {
+			hdr.Typeflag, hdr.Linkname, hdr.Mode = tar.TypeSymlink, f.Link, 0o777
+			if err := tw.WriteHeader(hdr); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		data, err := os.ReadFile(f.Src)
+		if err != nil {
+			return nil, err
+		}
+		hdr.Typeflag, hdr.Size = tar.TypeReg, int64(len(data))
+		if apkSums {
+			sum := sha1.Sum(data)
+			hdr.PAXRecords = map[string]string{"APK-TOOLS.checksum.SHA1": hex.EncodeToString(sum[:])}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, err
+		}
+		sum := md5.Sum(data)
+		pl.size += int64(len(data))
+		pl.md5[e.name] = hex.EncodeToString(sum[:])
+	}
+	return pl, nil
+}
+
+// tarFile is a file generated for a package's metadata.
+type tarFile struct {
+	name string
+	mode int64
+	data []byte
+}
+
+// writeTarFiles writes files to tw, owned by root and dated mtime.
+func writeTarFiles(tw *tar.Writer, mtime time.Time, files ...tarFile) error {
+	for _, f := range files {
+		hdr := &tar.Header{
+			Name:    f.name,
+			Mode:    f.mode,
+			Size:    int64(len(f.data)),
+			ModTime: mtime,
+			Uname:   "root",
+			Gname:   "root",
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tgz returns the gzip-compressed tarball fill writes.
+func tgz(fill func(*tar.Writer) error) ([]byte, error) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	if err := fill(tw); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// summary returns the first line of the description.
+func (p *Package) summary() string {
+	s, _, _ := strings.Cut(strings.TrimSpace(p.Description), "\n")
+	return strings.TrimSpace(s)
+}
+
+// details returns the description after its first line.
+func (p *Package) details() string {
+	_, s, _ := strings.Cut(strings.TrimSpace(p.Description), "\n")
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,533 @@
+package linuxpkg
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// rpmArchs maps GOARCH to RPM architecture names.
+var rpmArchs = map[string]string{
+	"386":     "i686",
+	"amd64":   "x86_64",
+	"arm":     "armv7hl",
+	"arm64":   "aarch64",
+	"loong64": "loongarch64",
+	"ppc64le": "ppc64le",
+	"riscv64": "riscv64",
+	"s390x":   "s390x",
+}
+
+// RPMArch returns the RPM architecture of goarch.
+func RPMArch(goarch string) (string, bool) {
+	a, ok := rpmArchs[goarch]
+	return a, ok
+}
+
+// rpmRelease is the release of every package: gox packages one build of
+// each version.
+const rpmRelease = "1"
+
+// RPMVersion returns v as an RPM version, which may not contain "-": a
+// leading "v" is dropped, a missing version is 0, and the suffix of a
+// pre-release (1.2.0-rc1) sorts before the release while that of a git
+// describe (1.2.0-3-gabc1234) sorts after it.
+func RPMVersion(v string) string {
+	switch v = strings.TrimPrefix(v, "v"); {
+	case v == "":
+		return "0"
+	case !unicode.IsDigit(rune(v[0])):
+		v = "0~" + v
+	}
+	v, suffix, ok := strings.Cut(v, "-")
+	if !ok {
+		return v
+	}
+	sep := "~"
+	if unicode.IsDigit(rune(suffix[0])) {
+		sep = "+"
+	}
+	return v + sep + strings.ReplaceAll(suffix, "-", ".")
+}
+
+// RPMFile returns the conventional file name of p as an .rpm:
+// <name>-<version>-<release>.<arch>.rpm.
+func (p *Package) RPMFile() string {
+	arch, _ := RPMArch(p.Arch)
+	return fmt.Sprintf("%s-%s-%s.%s.rpm", p.Name, RPMVersion(p.Version), rpmRelease, arch)
+}
+
+// RPM header data types.
+const (
+	rpmInt16       = 3
+	rpmInt32       = 4
+	rpmString      = 6
+	rpmBin         = 7
+	rpmStringArray = 8
+	rpmI18NString  = 9
+)
+
+// RPM header tags.
+const (
+	tagHeaderSignatures = 62
+	tagHeaderImmutable  = 63
+	tagI18NTable        = 100
+
+	sigSHA1        = 269
+	sigSHA256      = 273
+	sigSize        = 1000
+	sigMD5         = 1004
+	sigPayloadSize = 1007
+
+	tagName              = 1000
+	tagVersion           = 1001
+	tagRelease           = 1002
+	tagSummary           = 1004
+	tagDescription       = 1005
+	tagBuildTime         = 1006
+	tagBuildHost         = 1007
+	tagSize              = 1009
+	tagLicense           = 1014
+	tagPackager          = 1015
+	tagGroup             = 1016
+	tagURL               = 1020
+	tagOS                = 1021
+	tagArch              = 1022
+	tagPreIn             = 1023
+	tagPostIn            = 1024
+	tagPreUn             = 1025
+	tagPostUn            = 1026
+	tagFileSizes         = 1028
+	tagFileModes         = 1030
+	tagFileRDevs         = 1033
+	tagFileMTimes        = 1034
+	tagFileDigests       = 1035
+	tagFileLinkTos       = 1036
+	tagFileFlags         = 1037
+	tagFileUserName      = 1039
+	tagFileGroupName     = 1040
+	tagSourceRPM         = 1044
+	tagProvideName       = 1047
+	tagRequireFlags      = 1048
+	tagRequireName       = 1049
+	tagRequireVersion    = 1050
+	tagPreInProg         = 1085
+	tagPostInProg        = 1086
+	tagPreUnProg         = 1087
+	tagPostUnProg        = 1088
+	tagFileDevices       = 1095
+	tagFileInodes        = 1096
+	tagFileLangs         = 1097
+	tagProvideFlags      = 1112
+	tagProvideVersion    = 1113
+	tagDirIndexes        = 1116
+	tagBaseNames         = 1117
+	tagDirNames          = 1118
+	tagPayloadFormat     = 1124
+	tagPayloadCompressor = 1125
+	tagPayloadFlags      = 1126
+	tagFileDigestAlgo    = 5011
+	tagPayloadDigest     = 5092
+	tagPayloadDigestAlgo = 5093
+)
+
+// Dependency flags.
+const (
+	senseLess    = 1 << 1
+	senseGreater = 1 << 2
+	senseEqual   = 1 << 3
+	senseRPMLib  = 1 << 24
+)
+
+// rpmDigestSHA256 is the PGP hash algorithm number of SHA-256, used for
+// file and payload digests.
+const rpmDigestSHA256 = 8
+
+// WriteRPM writes p to w as an RPM binary package: a lead, a signature
+// header holding digests, the header holding the metadata and file list,
+// and the files as a gzip-compressed cpio archive.
+func (p *Package) WriteRPM(w io.Writer) error {
+	arch, ok := RPMArch(p.Arch)
+	if !ok {
+		return fmt.Errorf("rpm: no RPM architecture for %s", p.Arch)
+	}
+	files, cpio, err := p.rpmPayload()
+	if err != nil {
+		return err
+	}
+	var payload bytes.Buffer
+	gw, _ := gzip.NewWriterLevel(&payload, gzip.BestCompression)
+	if _, err := gw.Write(cpio); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	payloadSum := sha256.Sum256(payload.Bytes())
+	hdr := p.rpmHeader(arch, files, hex.EncodeToString(payloadSum[:])).marshal(tagHeaderImmutable)
+
+	var sig rpmHeader
+	sha1Sum, sha256Sum := sha1.Sum(hdr), sha256.Sum256(hdr)
+	md5Sum := md5.New()
+	md5Sum.Write(hdr)
+	md5Sum.Write(payload.Bytes())
+	sig.string(sigSHA1, hex.EncodeToString(sha1Sum[:]))
+	sig.string(sigSHA256, hex.EncodeToString(sha256Sum[:]))
+	sig.int32s(sigSize, int32(len(hdr)+payload.Len()))
+	sig.add(sigMD5, rpmBin, md5Sum.Size(), md5Sum.Sum(nil))
+	sig.int32s(sigPayloadSize, int32(len(cpio)))
+	sigData := sig.marshal(tagHeaderSignatures)
+	// The header starts 8-byte aligned.
+	sigData = append(sigData, make([]byte, (8-len(sigData)%8)%8)...)
+
+	for _, b := range [][]byte{p.rpmLead(), sigData, hdr, payload.Bytes()} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rpmLead returns the 96-byte lead of p, kept by rpm for compatibility.
+func (p *Package) rpmLead() []byte {
+	lead := make([]byte, 96)
+	copy(lead, []byte{0xed, 0xab, 0xee, 0xdb, 3, 0})
+	name := fmt.Sprintf("%s-%s-%s", p.Name, RPMVersion(p.Version), rpmRelease)
+	copy(lead[10:75], name)                  // NUL-terminated within 66 bytes
+	binary.BigEndian.PutUint16(lead[76:], 1) // linux
+	binary.BigEndian.PutUint16(lead[78:], 5) // header-style signature
+	return lead
+}
+
+// rpmFile is a payload entry as listed in the header.
+type rpmFile struct {
+	name   string // absolute path
+	mode   uint16 // type and permission bits
+	size   int32
+	digest string // hex SHA-256 of regular files
+	link   string
+}
+
+// rpmPayload returns the files of p and the cpio archive (newc format)
+// holding them. Directories are left to rpm to create; packages own only
+// their files.
+func (p *Package) rpmPayload() ([]rpmFile, []byte, error) {
+	var files []rpmFile
+	var buf bytes.Buffer
+	write := func(ino int, name string, mode uint32, data []byte) {
+		fmt.Fprintf(&buf, "070701%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X",
+			ino, mode, 0, 0, 1, p.ModTime.Unix(), len(data), 0, 0, 0, 0, len(name)+1, 0)
+		buf.WriteString(name)
+		buf.WriteByte(0)
+		pad4(&buf)
+		buf.Write(data)
+		pad4(&buf)
+	}
+	for _, e := range p.entries() {
+		f := e.file
+		if f == nil {
+			continue
+		}
+		rf := rpmFile{name: "/" + e.name, mode: 0o100000 | uint16(f.Mode.Perm())}
+		var data []byte
+		if f.Mode&fs.ModeSymlink != 0 {
+			rf.mode, rf.link = 0o120777, f.Link
+			data = []byte(f.Link)
+		} else {
+			var err error
+			if data, err = os.ReadFile(f.Src); err != nil {
+				return nil, nil, err
+			}
+			sum := sha256.Sum256(data)
+			rf.digest = hex.EncodeToString(sum[:])
+		}
+		rf.size = int32(len(data))
+		files = append(files, rf)
+		write(len(files), "."+rf.name, uint32(rf.mode), data)
+	}
+	write(0, "TRAILER!!!", 0, nil)
+	return files, buf.Bytes(), nil
+}
+
+// pad4 pads buf to a multiple of 4 bytes, as cpio aligns names and data.
+func pad4(buf *bytes.Buffer) {
+	buf.Write(make([]byte, (4-buf.Len()%4)%4))
+}
+
+// rpmHeader returns the main header of p.
+func (p *Package) rpmHeader(arch string, files []rpmFile, payloadDigest string) *rpmHeader {
+	version := RPMVersion(p.Version)
+	var h rpmHeader
+	h.add(tagI18NTable, rpmStringArray, 1, []byte("C\x00"))
+	h.string(tagName, p.Name)
+	h.string(tagVersion, version)
+	h.string(tagRelease, rpmRelease)
+	h.i18n(tagSummary, p.summary())
+	h.i18n(tagDescription, cmp.Or(p.details(), p.summary()))
+	h.int32s(tagBuildTime, int32(p.ModTime.Unix()))
+	h.string(tagBuildHost, "localhost")
+	h.string(tagLicense, p.License)
+	if p.Maintainer != "" {
+		h.string(tagPackager, p.Maintainer)
+	}
+	h.i18n(tagGroup, "Unspecified")
+	if p.Homepage != "" {
+		h.string(tagURL, p.Homepage)
+	}
+	h.string(tagOS, "linux")
+	h.string(tagArch, arch)
+	h.string(tagSourceRPM, fmt.Sprintf("%s-%s-%s.src.rpm", p.Name, version, rpmRelease))
+
+	s := p.rpmScripts()
+	for _, sc := range []struct {
+		tag, prog int
+		data      []byte
+	}{
+		{tagPreIn, tagPreInProg, s.PreInstall},
+		{tagPostIn, tagPostInProg, s.PostInstall},
+		{tagPreUn, tagPreUnProg, s.PreRemove},
+		{tagPostUn, tagPostUnProg, s.PostRemove},
+	} {
+		if len(sc.data) > 0 {
+			h.string(sc.tag, string(sc.data))
+			h.string(sc.prog, "/bin/sh")
+		}
+	}
+
+	var size int32
+	var sizes, mtimes, flags, devices, inodes, dirIndexes []int32
+	var modes, rdevs []int16
+	var digests, links, users, langs, baseNames, dirNames []string
+	for i, f := range files {
+		size += f.size
+		sizes = append(sizes, f.size)
+		mtimes = append(mtimes, int32(p.ModTime.Unix()))
+		flags = append(flags, 0)
+		devices = append(devices, 1)
+		inodes = append(inodes, int32(i+1))
+		modes = append(modes, int16(f.mode))
+		rdevs = append(rdevs, 0)
+		digests = append(digests, f.digest)
+		links = append(links, f.link)
+		users = append(users, "root")
+		langs = append(langs, "")
+		dir, base := path.Split(f.name)
+		j := slices.Index(dirNames, dir)
+		if j < 0 {
+			j = len(dirNames)
+			dirNames = append(dirNames, dir)
+		}
+		dirIndexes = append(dirIndexes, int32(j))
+		baseNames = append(baseNames, base)
+	}
+	h.int32s(tagSize, size)
+	if len(files) > 0 {
+		h.int32s(tagFileSizes, sizes...)
+		h.int16s(tagFileModes, modes...)
+		h.int16s(tagFileRDevs, rdevs...)
+		h.int32s(tagFileMTimes, mtimes...)
+		h.strings(tagFileDigests, digests...)
+		h.strings(tagFileLinkTos, links...)
+		h.int32s(tagFileFlags, flags...)
+		h.strings(tagFileUserName, users...)
+		h.strings(tagFileGroupName, users...)
+		h.int32s(tagFileDevices, devices...)
+		h.int32s(tagFileInodes, inodes...)
+		h.strings(tagFileLangs, langs...)
+		h.int32s(tagDirIndexes, dirIndexes...)
+		h.strings(tagBaseNames, baseNames...)
+		h.strings(tagDirNames, dirNames...)
+		h.int32s(tagFileDigestAlgo, rpmDigestSHA256)
+	}
+
+	h.strings(tagProvideName, p.Name)
+	h.int32s(tagProvideFlags, senseEqual)
+	h.strings(tagProvideVersion, version+"-"+rpmRelease)
+
+	reqs := []rpmDep{
+		{"rpmlib(CompressedFileNames)", senseRPMLib | senseLess | senseEqual, "3.0.4-1"},
+		{"rpmlib(FileDigests)", senseRPMLib | senseLess | senseEqual, "4.6.0-1"},
+		{"rpmlib(PayloadFilesHavePrefix)", senseRPMLib | senseLess | senseEqual, "4.0-1"},
+	}
+	if len(s.PreInstall)+len(s.PostInstall)+len(s.PreRemove)+len(s.PostRemove) > 0 {
+		reqs = append(reqs, rpmDep{"/bin/sh", 0, ""})
+	}
+	for _, d := range p.Depends {
+		reqs = append(reqs, rpmDependency(d))
+	}
+	var reqNames, reqVersions []string
+	var reqFlags []int32
+	for _, r := range reqs {
+		reqNames, reqFlags, reqVersions = append(reqNames, r.name), append(reqFlags, r.flags), append(reqVersions, r.version)
+	}
+	h.strings(tagRequireName, reqNames...)
+	h.int32s(tagRequireFlags, reqFlags...)
+	h.strings(tagRequireVersion, reqVersions...)
+
+	h.string(tagPayloadFormat, "cpio")
+	h.string(tagPayloadCompressor, "gzip")
+	h.string(tagPayloadFlags, "9")
+	h.strings(tagPayloadDigest, payloadDigest)
+	h.int32s(tagPayloadDigestAlgo, rpmDigestSHA256)
+	return &h
+}
+
+// rpmDep is a required package.
+type rpmDep struct {
+	name    string
+	flags   int32
+	version string
+}
+
+// rpmDependency parses a dependency written "name", "name >= 1.0" or, as
+// for deb, "name (>= 1.0)".
+func rpmDependency(dep string) rpmDep {
+	fields := strings.Fields(strings.NewReplacer("(", " ", ")", " ").Replace(dep))
+	if len(fields) != 3 {
+		return rpmDep{strings.Join(fields, " "), 0, ""}
+	}
+	var flags int32
+	switch fields[1] {
+	case "<", "<<":
+		flags = senseLess
+	case "<=":
+		flags = senseLess | senseEqual
+	case "=", "==":
+		flags = senseEqual
+	case ">=":
+		flags = senseGreater | senseEqual
+	case ">", ">>":
+		flags = senseGreater
+	default:
+		return rpmDep{dep, 0, ""}
+	}
+	return rpmDep{fields[0], flags, fields[2]}
+}
+
+// rpmScripts returns the scriptlets of p. Scripts p leaves empty enable and
+// start its systemd units on install and stop them on removal; rpm passes
+// the number of installed instances left as $1.
+func (p *Package) rpmScripts() Scripts {
+	s := p.Scripts
+	if len(p.Units) == 0 {
+		return s
+	}
+	units := strings.Join(p.Units, " ")
+	if len(s.PostInstall) == 0 {
+		s.PostInstall = fmt.Appendf(nil, `if [ -d /run/systemd/system ]; then
+	systemctl daemon-reload
+	if [ "$1" -eq 1 ]; then
+		systemctl enable --now %s
+	fi
+fi
+`, units)
+	}
+	if len(s.PreRemove) == 0 {
+		s.PreRemove = fmt.Appendf(nil, `if [ "$1" -eq 0 ] && [ -d /run/systemd/system ]; then
+	systemctl disable --now %s || true
+fi
+`, units)
+	}
+	if len(s.PostRemove) == 0 {
+		s.PostRemove = []byte(`if [ -d /run/systemd/system ]; then
+	systemctl daemon-reload || true
+fi
+`)
+	}
+	return s
+}
+
+// rpmHeader is an RPM header under construction.
+type rpmHeader struct {
+	entries []rpmEntry
+}
+
+// rpmEntry is one tag of a header with its encoded data.
+type rpmEntry struct {
+	tag, typ, count int
+	data            []byte
+}
+
+func (h *rpmHeader) add(tag, typ, count int, data []byte) {
+	h.entries = append(h.entries, rpmEntry{tag, typ, count, data})
+}
+
+func (h *rpmHeader) string(tag int, s string) {
+	h.add(tag, rpmString, 1, append([]byte(s), 0))
+}
+
+func (h *rpmHeader) i18n(tag int, s string) {
+	h.add(tag, rpmI18NString, 1, append([]byte(s), 0))
+}
+
+func (h *rpmHeader) strings(tag int, ss ...string) {
+	var data []byte
+	for _, s := range ss {
+		data = append(append(data, s...), 0)
+	}
+	h.add(tag, rpmStringArray, len(ss), data)
+}
+
+func (h *rpmHeader) int32s(tag int, vs ...int32) {
+	data := make([]byte, 0, 4*len(vs))
+	for _, v := range vs {
+		data = binary.BigEndian.AppendUint32(data, uint32(v))
+	}
+	h.add(tag, rpmInt32, len(vs), data)
+}
+
+func (h *rpmHeader) int16s(tag int, vs ...int16) {
+	data := make([]byte, 0, 2*len(vs))
+	for _, v := range vs {
+		data = binary.BigEndian.AppendUint16(data, uint16(v))
+	}
+	h.add(tag, rpmInt16, len(vs), data)
+}
+
+// marshal encodes h as a header region tagged region: the entries sorted
+// by tag, preceded by the region entry whose trailer closes the data.
+func (h *rpmHeader) marshal(region int) []byte {
+	entries := slices.Clone(h.entries)
+	slices.SortStableFunc(entries, func(a, b rpmEntry) int { return a.tag - b.tag })
+	var index, store []byte
+	entry := func(e rpmEntry, offset int) {
+		for _, v := range []int{e.tag, e.typ, offset, e.count} {
+			index = binary.BigEndian.AppendUint32(index, uint32(v))
+		}
+	}
+	for _, e := range entries {
+		align := map[int]int{rpmInt16: 2, rpmInt32: 4}[e.typ]
+		for align > 0 && len(store)%align != 0 {
+			store = append(store, 0)
+		}
+		entry(e, len(store))
+		store = append(store, e.data...)
+	}
+	trailer := rpmEntry{region, rpmBin, 16, nil}
+	for _, v := range []int{region, rpmBin, -16 * (len(entries) + 1), 16} {
+		trailer.data = binary.BigEndian.AppendUint32(trailer.data, uint32(v))
+	}
+	regionIndex := index
+	index = nil
+	entry(trailer, len(store))
+	index = append(index, regionIndex...)
+	store = append(store, trailer.data...)
+
+	out := []byte{0x8e, 0xad, 0xe8, 0x01, 0, 0, 0, 0}
+	out = binary.BigEndian.AppendUint32(out, uint32(len(entries)+1))
+	out = binary.BigEndian.AppendUint32(out, uint32(len(store)))
+	return append(append(out, index...), store...)
+}
@@ -0,0 +1,217 @@
+package linuxpkg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// readRPMHeader parses the header at data and returns its tags (strings,
+// string arrays and int32 arrays decoded) and the length it occupies.
+func readRPMHeader(t *testing.T, data []byte, region int) (map[int]any, int) {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte{0x8e, 0xad, 0xe8, 0x01}) {
+		t.Fatal("missing header magic")
+	}
+	n := int(binary.BigEndian.Uint32(data[8:]))
+	size := int(binary.BigEndian.Uint32(data[12:]))
+	index, store := data[16:16+16*n], data[16+16*n:16+16*n+size]
+	tags := map[int]any{}
+	for i := range n {
+		e := index[16*i:]
+		tag, typ := int(binary.BigEndian.Uint32(e)), int(binary.BigEndian.Uint32(e[4:]))
+		off, count := int(binary.BigEndian.Uint32(e[8:])), int(binary.BigEndian.Uint32(e[12:]))
+		if i == 0 {
+			trailer := store[off : off+16]
+			if tag != region || int(binary.BigEndian.Uint32(trailer)) != region || int32(binary.BigEndian.Uint32(trailer[8:])) != int32(-16*n) {
+				t.Fatalf("bad region entry %d at %d: %x", tag, off, trailer)
+			}
+			continue
+		}
+		switch typ {
+		case rpmString, rpmI18NString:
+			s, _, _ := strings.Cut(string(store[off:]), "\x00")
+			tags[tag] = s
+		case rpmStringArray:
+			tags[tag] = strings.Split(string(store[off:]), "\x00")[:count]
+		case rpmInt32:
+			if off%4 != 0 {
+				t.Errorf("tag %d misaligned at %d", tag, off)
+			}
+			var vs []int32
+			for j := range count {
+				vs = append(vs, int32(binary.BigEndian.Uint32(store[off+4*j:])))
+			}
+			tags[tag] = vs
+		case rpmInt16:
+			var vs []int16
+			for j := range count {
+				vs = append(vs, int16(binary.BigEndian.Uint16(store[off+2*j:])))
+			}
+			tags[tag] = vs
+		default:
+			tags[tag] = store[off : off+count]
+		}
+	}
+	return tags, 16 + 16*n + size
+}
+
+// readCpio returns the names and contents of a newc cpio archive.
+func readCpio(t *testing.T, data []byte) ([]string, map[string]string) {
+	t.Helper()
+	var names []string
+	files := map[string]string{}
+	for off := 0; ; {
+		hdr := string(data[off : off+110])
+		if !strings.HasPrefix(hdr, "070701") {
+			t.Fatalf("bad cpio header at %d", off)
+		}
+		field := func(i int) int {
+			v, _ := strconv.ParseUint(hdr[6+8*i:14+8*i], 16, 32)
+			return int(v)
+		}
+		size, nameSize := field(6), field(11)
+		name := string(data[off+110 : off+110+nameSize-1])
+		off = (off + 110 + nameSize + 3) &^ 3
+		if name == "TRAILER!!!" {
+			return names, files
+		}
+		names = append(names, name)
+		files[name] = string(data[off : off+size])
+		off = (off + size + 3) &^ 3
+	}
+}
+
+func TestWriteRPM(t *testing.T) {
+	p := testPackage(t)
+	p.License = "MIT"
+	p.Depends = []string{"glibc >= 2.28", "ca-certificates", "openssl-libs (>= 3.0)"}
+	var buf bytes.Buffer
+	if err := p.WriteRPM(&buf); err != nil {
+		t.Fatalf("WriteRPM() error = %v", err)
+	}
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, []byte{0xed, 0xab, 0xee, 0xdb}) {
+		t.Fatal("missing lead magic")
+	}
+	if name, _, _ := strings.Cut(string(data[10:76]), "\x00"); name != "app-1.2.3-1" {
+		t.Errorf("lead name = %q", name)
+	}
+
+	sig, n := readRPMHeader(t, data[96:], tagHeaderSignatures)
+	off := 96 + (n+7)&^7
+	tags, n := readRPMHeader(t, data[off:], tagHeaderImmutable)
+	hdr, payload := data[off:off+n], data[off+n:]
+	sum := sha256.Sum256(hdr)
+	if sig[sigSHA256] != hex.EncodeToString(sum[:]) {
+		t.Errorf("signature sha256 = %v, want the header's", sig[sigSHA256])
+	}
+	if got := sig[sigSize].([]int32)[0]; int(got) != len(hdr)+len(payload) {
+		t.Errorf("signature size = %d, want %d", got, len(hdr)+len(payload))
+	}
+
+	for tag, want := range map[int]string{
+		tagName: "app", tagVersion: "1.2.3", tagRelease: "1", tagArch: "aarch64", tagOS: "linux",
+		tagLicense: "MIT", tagSummary: "An app", tagDescription: "It does things.\n\nWell.",
+		tagURL: "https://example.com", tagPackager: "Jane Doe <jane@example.com>",
+		tagSourceRPM: "app-1.2.3-1.src.rpm", tagPayloadCompressor: "gzip",
+	} {
+		if tags[tag] != want {
+			t.Errorf("tag %d = %q, want %q", tag, tags[tag], want)
+		}
+	}
+	if got, want := tags[tagBaseNames], []string{"app", "libfoo.so", "libfoo.so.1", "app.service"}; !slices.Equal(got.([]string), want) {
+		t.Errorf("basenames = %q, want %q", got, want)
+	}
+	if got, want := tags[tagDirNames], []string{"/usr/bin/", "/usr/lib/", "/usr/lib/systemd/system/"}; !slices.Equal(got.([]string), want) {
+		t.Errorf("dirnames = %q, want %q", got, want)
+	}
+	if got, want := tags[tagFileModes], []int16{-32275, -24065, -32348, -32348}; !slices.Equal(got.([]int16), want) {
+		t.Errorf("modes = %o, want %o", got, want)
+	}
+	if got := tags[tagFileLinkTos].([]string); got[1] != "libfoo.so.1" {
+		t.Errorf("linktos = %q", got)
+	}
+	reqs := tags[tagRequireName].([]string)
+	flags := tags[tagRequireFlags].([]int32)
+	versions := tags[tagRequireVersion].([]string)
+	for _, want := range []rpmDep{
+		{"glibc", senseGreater | senseEqual, "2.28"},
+		{"ca-certificates", 0, ""},
+		{"openssl-libs", senseGreater | senseEqual, "3.0"},
+		{"/bin/sh", 0, ""},
+	} {
+		i := slices.Index(reqs, want.name)
+		if i < 0 || flags[i] != want.flags || versions[i] != want.version {
+			t.Errorf("requires = %q, want %v", reqs, want)
+		}
+	}
+	if got := tags[tagPostIn].(string); !strings.Contains(got, "systemctl enable --now app.service") {
+		t.Errorf("post = %q, want the unit enabled", got)
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpio, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sig[sigPayloadSize].([]int32)[0]; int(got) != len(cpio) {
+		t.Errorf("payload size = %d, want %d", got, len(cpio))
+	}
+	names, files := readCpio(t, cpio)
+	want := []string{"./usr/bin/app", "./usr/lib/libfoo.so", "./usr/lib/libfoo.so.1", "./usr/lib/systemd/system/app.service"}
+	if !slices.Equal(names, want) {
+		t.Errorf("payload = %q, want %q", names, want)
+	}
+	if files["./usr/bin/app"] != "binary" || files["./usr/lib/libfoo.so"] != "libfoo.so.1" {
+		t.Errorf("payload files = %q", files)
+	}
+
+	var again bytes.Buffer
+	if err := p.WriteRPM(&again); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, again.Bytes()) {
+		t.Error("WriteRPM() is not deterministic")
+	}
+}
+
+func TestWriteRPM_UnknownArch(t *testing.T) {
+	p := testPackage(t)
+	p.Arch = "mips"
+	if err := p.WriteRPM(io.Discard); err == nil {
+		t.Error("WriteRPM() for mips succeeded")
+	}
+}
+
+func TestRPMVersion(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"v1.2.3", "1.2.3"},
+		{"1.2.3-rc1", "1.2.3~rc1"},
+		{"1.2.3-4-gabc1234-dirty", "1.2.3+4.gabc1234.dirty"},
+		{"abc1234", "0~abc1234"},
+		{"", "0"},
+	}
+	for _, tt := range tests {
+		if got := RPMVersion(tt.in); got != tt.want {
+			t.Errorf("RPMVersion(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRPMFile(t *testing.T) {
+	p := &Package{Name: "app", Version: "v1.0.0", Arch: "amd64"}
+	if got, want := p.RPMFile(), "app-1.0.0-1.x86_64.rpm"; got != want {
+		t.Errorf("RPMFile() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,172 @@
+// Package lipo merges thin Mach-O files into a universal (fat) binary, as
+// Apple's lipo -create does, so universal darwin builds need no Xcode.
+package lipo
+
+import (
+	"cmp"
+	"debug/macho"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+const (
+	fatMagic      = 0xcafebabe
+	fatHeaderSize = 8
+	fatArchSize   = 20
+)
+
+// slice is one thin Mach-O file of a universal binary.
+type slice struct {
+	path   string
+	cpu    macho.Cpu
+	subCpu uint32
+	size   int64
+	align  uint32 // log2 of the slice's file offset alignment
+	offset int64
+}
+
+// Create writes the universal binary dst holding the thin Mach-O files
+// inputs, one per CPU type. dst gets the file mode of the first input.
+func Create(dst string, inputs ...string) error {
+	if len(inputs) == 0 {
+		return errors.New("lipo: no input files")
+	}
+	thin := make([]*slice, 0, len(inputs))
+	for _, in := range inputs {
+		s, err := open(in)
+		if err != nil {
+			return err
+		}
+		for _, o := range thin {
+			if o.cpu == s.cpu {
+				return fmt.Errorf("lipo: %s and %s have the same architecture (%v)", o.path, s.path, s.cpu)
+			}
+		}
+		thin = append(thin, s)
+	}
+	fi, err := os.Stat(inputs[0])
+	if err != nil {
+		return err
+	}
+
+	// Like lipo, order slices by alignment so x86_64 precedes arm64.
+	slices.SortStableFunc(thin, func(a, b *slice) int { return cmp.Compare(a.align, b.align) })
+	end := int64(fatHeaderSize + fatArchSize*len(thin))
+	for _, s := range thin {
+		s.offset = alignUp(end, int64(1)<<s.align)
+		end = s.offset + s.size
+	}
+	if end > math.MaxUint32 {
+		return fmt.Errorf("lipo: %s would exceed 4 GiB", dst)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".lipo-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := write(tmp, thin); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), fi.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// Archs returns the CPU types of the slices of a universal binary, or of a
+// thin Mach-O file.
+func Archs(path string) ([]macho.Cpu, error) {
+	if fat, err := macho.OpenFat(path); err == nil {
+		defer fat.Close()
+		out := make([]macho.Cpu, len(fat.Arches))
+		for i, a := range fat.Arches {
+			out[i] = a.Cpu
+		}
+		return out, nil
+	}
+	f, err := macho.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return []macho.Cpu{f.Cpu}, nil
+}
+
+// open reads the header of the thin Mach-O file path.
+func open(path string) (*slice, error) {
+	if fat, err := macho.OpenFat(path); err == nil {
+		fat.Close()
+		return nil, fmt.Errorf("lipo: %s is already a universal binary", path)
+	}
+	f, err := macho.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("lipo: %s: %w", path, err)
+	}
+	defer f.Close()
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &slice{path: path, cpu: f.Cpu, subCpu: f.SubCpu, size: fi.Size(), align: alignment(f.Cpu)}, nil
+}
+
+// alignment returns the log2 alignment lipo gives slices of cpu: the 16 KiB
+// pages of arm64 and 4 KiB elsewhere.
+func alignment(cpu macho.Cpu) uint32 {
+	if cpu == macho.CpuArm || cpu == macho.CpuArm64 {
+		return 14
+	}
+	return 12
+}
+
+// write writes the fat header and the slices to w.
+func write(w io.WriteSeeker, thin []*slice) error {
+	header := make([]byte, fatHeaderSize+fatArchSize*len(thin))
+	binary.BigEndian.PutUint32(header[0:], fatMagic)
+	binary.BigEndian.PutUint32(header[4:], uint32(len(thin)))
+	for i, s := range thin {
+		arch := header[fatHeaderSize+fatArchSize*i:]
+		binary.BigEndian.PutUint32(arch[0:], uint32(s.cpu))
+		binary.BigEndian.PutUint32(arch[4:], s.subCpu)
+		binary.BigEndian.PutUint32(arch[8:], uint32(s.offset))
+		binary.BigEndian.PutUint32(arch[12:], uint32(s.size))
+		binary.BigEndian.PutUint32(arch[16:], s.align)
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	for _, s := range thin {
+		if _, err := w.Seek(s.offset, io.SeekStart); err != nil {
+			return err
+		}
+		if err := copyFile(w, s.path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func alignUp(n, align int64) int64 {
+	return (n + align - 1) / align * align
+}
@@ -0,0 +1,103 @@
+package lipo
+
+import (
+	"bytes"
+	"debug/macho"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// thinMachO writes a minimal 64-bit Mach-O executable for cpu followed by
+// body.
+func thinMachO(t *testing.T, name string, cpu macho.Cpu, body string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	hdr := macho.FileHeader{Magic: macho.Magic64, Cpu: cpu, Type: macho.TypeExec}
+	if err := binary.Write(&buf, binary.LittleEndian, hdr); err != nil {
+		t.Fatal(err)
+	}
+	buf.Write(make([]byte, 4)) // reserved field of mach_header_64
+	buf.WriteString(body)
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, buf.Bytes(), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCreate(t *testing.T) {
+	arm := thinMachO(t, "arm64", macho.CpuArm64, "arm code")
+	amd := thinMachO(t, "amd64", macho.CpuAmd64, "x86 code")
+	dst := filepath.Join(t.TempDir(), "app")
+
+	if err := Create(dst, arm, amd); err != nil {
+		t.Fatal(err)
+	}
+	archs, err := Archs(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []macho.Cpu{macho.CpuAmd64, macho.CpuArm64}; !slices.Equal(archs, want) {
+		t.Errorf("Archs() = %v, want %v", archs, want)
+	}
+
+	fat, err := macho.OpenFat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fat.Close()
+	for _, a := range fat.Arches {
+		if a.Offset%(1<<a.Align) != 0 {
+			t.Errorf("%v slice at offset %d, want %d-aligned", a.Cpu, a.Offset, 1<<a.Align)
+		}
+		src := map[macho.Cpu]string{macho.CpuArm64: arm, macho.CpuAmd64: amd}[a.Cpu]
+		want, _ := os.ReadFile(src)
+		got := make([]byte, a.Size)
+		f, _ := os.Open(dst)
+		_, err := f.ReadAt(got, int64(a.Offset))
+		f.Close()
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("%v slice differs from %s", a.Cpu, filepath.Base(src))
+		}
+	}
+	if fi, err := os.Stat(dst); err != nil || fi.Mode().Perm() != 0o755 {
+		t.Errorf("mode = %v, %v, want 0755", fi.Mode(), err)
+	}
+}
+
+func TestCreate_Errors(t *testing.T) {
+	arm := thinMachO(t, "arm64", macho.CpuArm64, "")
+	arm2 := thinMachO(t, "arm64-2", macho.CpuArm64, "")
+	amd := thinMachO(t, "amd64", macho.CpuAmd64, "")
+	fat := filepath.Join(t.TempDir(), "fat")
+	if err := Create(fat, arm, amd); err != nil {
+		t.Fatal(err)
+	}
+	text := filepath.Join(t.TempDir(), "text")
+	if err := os.WriteFile(text, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		inputs  []string
+		wantErr string
+	}{
+		{"no inputs", nil, "no input files"},
+		{"same arch", []string{arm, arm2}, "same architecture"},
+		{"fat input", []string{fat, amd}, "already a universal binary"},
+		{"not mach-o", []string{text}, "text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Create(filepath.Join(t.TempDir(), "out"), tt.inputs...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Create() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
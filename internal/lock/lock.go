@@ -0,0 +1,186 @@
+// Package lock reads and writes gox.lock, which pins downloaded packages
+// and zig master snapshots so builds are reproducible across machines.
+package lock
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+)
+
+// File is the lockfile name, written next to gox.toml.
+const File = "gox.lock"
+
+const header = "# This file is generated by gox. Do not edit.\n\n"
+
+// Lock pins every package source to the URL, size and archive digest it
+// resolved to, and zig master to an exact dev snapshot per host platform.
+type Lock struct {
+	Zig      []Zig     `toml:"zig,omitempty"`
+	Packages []Package `toml:"package,omitempty"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// Package is a resolved package.
+type Package struct {
+	Source string `toml:"source"`
+	URL    string `toml:"url"`
+	Size   int64  `toml:"size"`
+	SHA256 string `toml:"sha256"`
+}
+
+// Zig is a resolved zig snapshot for one host platform.
+type Zig struct {
+	Version  string `toml:"version"`  // requested version, e.g. master
+	Resolved string `toml:"resolved"` // exact version, e.g. 0.16.0-dev.1+abc
+	Platform string `toml:"platform"` // zig host platform, e.g. x86_64-linux
+	Tarball  string `toml:"tarball"`
+	Shasum   string `toml:"shasum"`
+}
+
+// active is the lock consulted during downloads; nil disables locking.
+var active *Lock
+
+// Use loads the lockfile at path (empty if missing) and makes it the
+// active lock. An empty path disables locking.
+func Use(path string) error {
+	if path == "" {
+		active = nil
+		return nil
+	}
+	l, err := Load(path)
+	if err != nil {
+		return err
+	}
+	active = l
+	return nil
+}
+
+// Active returns the active lock, or nil when locking is disabled.
+func Active() *Lock {
+	return active
+}
+
+// Load reads a lockfile; a missing file yields an empty lock.
+func Load(path string) (*Lock, error) {
+	l := &Lock{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := toml.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Package returns the locked entry for source.
+func (l *Lock) Package(source string) (Package, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, p := range l.Packages {
+		if p.Source == source {
+			return p, true
+		}
+	}
+	return Package{}, false
+}
+
+// SetPackage records p, replacing any entry with the same source, and saves.
+func (l *Lock) SetPackage(p Package) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.Packages = slices.DeleteFunc(l.Packages, func(x Package) bool {
+		return x.Source == p.Source
+	})
+	l.Packages = append(l.Packages, p)
+	slices.SortFunc(l.Packages, func(a, b Package) int {
+		return cmp.Compare(a.Source, b.Source)
+	})
+	return l.save()
+}
+
+// RemovePackage drops the entry for source and saves.
+func (l *Lock) RemovePackage(source string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.Packages = slices.DeleteFunc(l.Packages, func(x Package) bool { return x.Source == source })
+	return l.save()
+}
+
+// RetainPackages drops the entries whose source is not in sources, saves,
+// and returns the dropped entries.
+func (l *Lock) RetainPackages(sources []string) ([]Package, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var dropped []Package
+	l.Packages = slices.DeleteFunc(l.Packages, func(x Package) bool {
+		if slices.Contains(sources, x.Source) {
+			return false
+		}
+		dropped = append(dropped, x)
+		return true
+	})
+	if len(dropped) == 0 {
+		return nil, nil
+	}
+	return dropped, l.save()
+}
+
+// ZigFor returns the locked snapshot of version for platform. With an empty
+// platform it returns any locked platform of version.
+func (l *Lock) ZigFor(version, platform string) (Zig, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, z := range l.Zig {
+		if z.Version == version && (platform == "" || z.Platform == platform) {
+			return z, true
+		}
+	}
+	return Zig{}, false
+}
+
+// SetZig records z, replacing the entry for the same version and platform,
+// and saves.
+func (l *Lock) SetZig(z Zig) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.Zig = slices.DeleteFunc(l.Zig, func(x Zig) bool {
+		return x.Version == z.Version && x.Platform == z.Platform
+	})
+	l.Zig = append(l.Zig, z)
+	slices.SortFunc(l.Zig, func(a, b Zig) int {
+		return cmp.Or(cmp.Compare(a.Version, b.Version), cmp.Compare(a.Platform, b.Platform))
+	})
+	return l.save()
+}
+
+// RemoveZig drops every platform entry of version and saves.
+func (l *Lock) RemoveZig(version string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.Zig = slices.DeleteFunc(l.Zig, func(x Zig) bool { return x.Version == version })
+	return l.save()
+}
+
+func (l *Lock) save() error {
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	enc := toml.NewEncoder(&buf)
+	enc.Indent = ""
+	if err := enc.Encode(l); err != nil {
+		return err
+	}
+	return os.WriteFile(l.path, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,108 @@
+package lock
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad_Missing(t *testing.T) {
+	l, err := Load(filepath.Join(t.TempDir(), File))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(l.Packages) != 0 || len(l.Zig) != 0 {
+		t.Errorf("Load() = %+v, want empty lock", l)
+	}
+}
+
+func TestLock_SetPackage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), File)
+	l, _ := Load(path)
+	for _, src := range []string{"b/b@1/b.tar.gz", "a/a@1/a.tar.gz", "b/b@1/b.tar.gz"} {
+		if err := l.SetPackage(Package{Source: src, URL: "https://x/" + src, Size: 1, SHA256: "ab"}); err != nil {
+			t.Fatalf("SetPackage() error = %v", err)
+		}
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(got.Packages) != 2 {
+		t.Fatalf("len(Packages) = %d, want 2", len(got.Packages))
+	}
+	if got.Packages[0].Source != "a/a@1/a.tar.gz" {
+		t.Errorf("Packages[0].Source = %q, want sorted by source", got.Packages[0].Source)
+	}
+	if p, ok := got.Package("b/b@1/b.tar.gz"); !ok || p.SHA256 != "ab" {
+		t.Errorf("Package() = %+v, %v", p, ok)
+	}
+
+	if err := l.RemovePackage("a/a@1/a.tar.gz"); err != nil {
+		t.Fatalf("RemovePackage() error = %v", err)
+	}
+	if got, _ = Load(path); len(got.Packages) != 1 || got.Packages[0].Source != "b/b@1/b.tar.gz" {
+		t.Errorf("after RemovePackage() Packages = %+v", got.Packages)
+	}
+}
+
+func TestLock_RetainPackages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), File)
+	l, _ := Load(path)
+	for _, src := range []string{"a/a@1/a.tar.gz", "b/b@1/b.tar.gz", "c/c@1/c.tar.gz"} {
+		if err := l.SetPackage(Package{Source: src, URL: "https://x/" + src}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dropped, err := l.RetainPackages([]string{"b/b@1/b.tar.gz", "d/d@1/d.tar.gz"})
+	if err != nil {
+		t.Fatalf("RetainPackages() error = %v", err)
+	}
+	if len(dropped) != 2 || dropped[0].Source != "a/a@1/a.tar.gz" || dropped[1].Source != "c/c@1/c.tar.gz" {
+		t.Errorf("RetainPackages() dropped %+v, want a and c", dropped)
+	}
+	if got, _ := Load(path); len(got.Packages) != 1 || got.Packages[0].Source != "b/b@1/b.tar.gz" {
+		t.Errorf("after RetainPackages() Packages = %+v", got.Packages)
+	}
+}
+
+func TestLock_Zig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), File)
+	l, _ := Load(path)
+	z := Zig{Version: "master", Resolved: "0.16.0-dev.1+abc", Platform: "x86_64-linux", Tarball: "https://x/zig.tar.xz", Shasum: "ff"}
+	if err := l.SetZig(z); err != nil {
+		t.Fatalf("SetZig() error = %v", err)
+	}
+
+	got, _ := Load(path)
+	if e, ok := got.ZigFor("master", "x86_64-linux"); !ok || e != z {
+		t.Errorf("ZigFor() = %+v, %v, want %+v", e, ok, z)
+	}
+	if _, ok := got.ZigFor("master", "aarch64-macos"); ok {
+		t.Error("ZigFor(other platform) should not match")
+	}
+	if e, ok := got.ZigFor("master", ""); !ok || e.Resolved != z.Resolved {
+		t.Errorf("ZigFor(any platform) = %+v, %v", e, ok)
+	}
+
+	if err := got.RemoveZig("master"); err != nil {
+		t.Fatalf("RemoveZig() error = %v", err)
+	}
+	if _, ok := got.ZigFor("master", ""); ok {
+		t.Error("RemoveZig() left an entry")
+	}
+}
+
+func TestUse(t *testing.T) {
+	t.Cleanup(func() { _ = Use("") })
+	if err := Use(filepath.Join(t.TempDir(), File)); err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+	if Active() == nil {
+		t.Error("Active() = nil after Use(path)")
+	}
+	_ = Use("")
+	if Active() != nil {
+		t.Error("Active() != nil after Use(\"\")")
+	}
+}
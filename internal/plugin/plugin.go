@@ -0,0 +1,174 @@
+// Package plugin runs the programs that extend gox builds. A plugin named
+// notify is the executable gox-notify found on PATH; once enabled in
+// gox.toml it is run at each hook of a build with the event, as JSON, on
+// its standard input.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Prefix starts the file name of every plugin executable.
+const Prefix = "gox-"
+
+// The hooks plugins are run at.
+const (
+	PreBuild  = "pre-build"  // before a target compiles
+	PostBuild = "post-build" // after a target's binary is built, signed and given its libs
+	PrePack   = "pre-pack"   // before a target's prefix or binary is archived
+	Publish   = "publish"    // once every target is built, with all artifacts
+)
+
+// Hooks lists the hooks in the order a build reaches them.
+var Hooks = []string{PreBuild, PostBuild, PrePack, Publish}
+
+// Event is the JSON document a plugin reads from its standard input.
+type Event struct {
+	Event     string   `json:"event"`
+	Target    string   `json:"target,omitempty"`
+	OS        string   `json:"os,omitempty"`
+	Arch      string   `json:"arch,omitempty"`
+	Output    string   `json:"output,omitempty"`    // the target's binary
+	Prefix    string   `json:"prefix,omitempty"`    // the target's install prefix
+	Archive   string   `json:"archive,omitempty"`   // pre-pack: the archive about to be written
+	Artifacts []string `json:"artifacts,omitempty"` // publish: binaries, archives, SHA256SUMS and the Scoop manifest
+}
+
+// Plugin is an executable run at every hook.
+type Plugin struct {
+	Name string
+	Path string
+}
+
+var (
+	mu      sync.RWMutex
+	enabled []Plugin
+)
+
+// Use enables the plugins names, replacing earlier ones. A name containing
+// a path separator is the path of the executable; any other resolves to
+// gox-<name> on PATH.
+func Use(names []string) error {
+	var out []Plugin
+	for _, name := range names {
+		p, err := Lookup(name)
+		if err != nil {
+			return err
+		}
+		out = append(out, p)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	enabled = out
+	return nil
+}
+
+// Enabled returns the plugins set with Use.
+func Enabled() []Plugin {
+	mu.RLock()
+	defer mu.RUnlock()
+	return slices.Clone(enabled)
+}
+
+// Lookup resolves the plugin name as Use does.
+func Lookup(name string) (Plugin, error) {
+	if name == "" {
+		return Plugin{}, errors.New("empty plugin name")
+	}
+	if strings.ContainsAny(name, `/\`) {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			return Plugin{}, fmt.Errorf("plugin %s: %w", name, err)
+		}
+		return Plugin{Name: strings.TrimPrefix(trimExt(filepath.Base(name)), Prefix), Path: path}, nil
+	}
+	path, err := exec.LookPath(Prefix + name)
+	if err != nil {
+		return Plugin{}, fmt.Errorf("plugin %s: %s%s not found in PATH", name, Prefix, name)
+	}
+	return Plugin{Name: name, Path: path}, nil
+}
+
+// Discover returns the gox-* executables on PATH, enabled or not, by name.
+// Like PATH lookup, the first directory holding a name wins.
+func Discover() []Plugin {
+	var out []Plugin
+	seen := map[string]bool{}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue // like exec.LookPath, never run programs from "."
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name := trimExt(e.Name())
+			if !strings.HasPrefix(name, Prefix) || len(name) == len(Prefix) || e.IsDir() {
+				continue
+			}
+			name = strings.TrimPrefix(name, Prefix)
+			if seen[name] {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			if _, err := exec.LookPath(path); err != nil {
+				continue
+			}
+			seen[name] = true
+			out = append(out, Plugin{Name: name, Path: path})
+		}
+	}
+	slices.SortFunc(out, func(a, b Plugin) int { return strings.Compare(a.Name, b.Name) })
+	return out
+}
+
+// Run runs every enabled plugin, in order, with e. Their output goes to
+// w. A plugin exiting with an error stops the build at that hook.
+func Run(ctx context.Context, e Event, w io.Writer) error {
+	plugins := Enabled()
+	if len(plugins) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	for _, p := range plugins {
+		cmd := exec.CommandContext(ctx, p.Path, e.Event)
+		cmd.Env = append(os.Environ(), "GOX_EVENT="+e.Event)
+		cmd.Stdin = bytes.NewReader(data)
+		cmd.Stdout = w
+		cmd.Stderr = w
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("plugin %s: %s: %w", p.Name, e.Event, err)
+		}
+	}
+	return nil
+}
+
+// trimExt strips the executable extension of name on windows.
+func trimExt(name string) string {
+	if runtime.GOOS != "windows" {
+		return name
+	}
+	ext := filepath.Ext(name)
+	for _, x := range filepath.SplitList(strings.ToLower(os.Getenv("PATHEXT"))) {
+		if strings.EqualFold(ext, x) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+	return name
+}
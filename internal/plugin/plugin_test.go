@@ -0,0 +1,131 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writePlugin writes the shell script gox-<name> to dir.
+func writePlugin(t *testing.T, dir, name, script string) string {
+	t.Helper()
+	path := filepath.Join(dir, Prefix+name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func skipWindows(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	skipWindows(t)
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+	path := writePlugin(t, dir, "notify", "")
+
+	tests := []struct {
+		name    string
+		want    Plugin
+		wantErr string
+	}{
+		{name: "notify", want: Plugin{Name: "notify", Path: path}},
+		{name: path, want: Plugin{Name: "notify", Path: path}},
+		{name: "upload", wantErr: "gox-upload not found in PATH"},
+		{name: filepath.Join(dir, "gox-upload"), wantErr: "plugin " + filepath.Join(dir, "gox-upload")},
+		{name: "", wantErr: "empty plugin name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Lookup(tt.name)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Lookup() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Lookup() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	skipWindows(t)
+	first, second := t.TempDir(), t.TempDir()
+	t.Setenv("PATH", first+string(os.PathListSeparator)+second)
+	upload := writePlugin(t, first, "upload", "")
+	writePlugin(t, second, "upload", "")
+	notify := writePlugin(t, second, "notify", "")
+	if err := os.WriteFile(filepath.Join(first, "gox-data"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(first, "gox-dir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writePlugin(t, first, "", "")
+
+	got := Discover()
+	want := []Plugin{{Name: "notify", Path: notify}, {Name: "upload", Path: upload}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Discover() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	skipWindows(t)
+	dir := t.TempDir()
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	log := filepath.Join(dir, "log")
+	writePlugin(t, dir, "log", `echo "$1 $GOX_EVENT" >> `+log+"\ncat >> "+log+"\necho logged\n")
+	writePlugin(t, dir, "veto", `[ "$1" != pre-pack ] || { echo no packing >&2; exit 3; }`+"\n")
+	t.Cleanup(func() { _ = Use(nil) })
+
+	if err := Run(context.Background(), Event{Event: PreBuild}, nil); err != nil {
+		t.Fatalf("Run() without plugins error = %v", err)
+	}
+	if err := Use([]string{"log", "veto"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	e := Event{Event: PreBuild, Target: "app", OS: "linux", Arch: "amd64", Output: "dist/app"}
+	if err := Run(context.Background(), e, &out); err != nil {
+		t.Fatalf("Run() error = %v\n%s", err, out.String())
+	}
+	if out.String() != "logged\n" {
+		t.Errorf("output = %q, want plugin output", out.String())
+	}
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, body, _ := strings.Cut(string(data), "\n")
+	if head != "pre-build pre-build" {
+		t.Errorf("arguments = %q, want the event name", head)
+	}
+	var got Event
+	if err := json.Unmarshal([]byte(body), &got); err != nil || got.Target != e.Target || got.Output != e.Output {
+		t.Errorf("stdin = %s (%v), want %+v", body, err, e)
+	}
+
+	out.Reset()
+	err = Run(context.Background(), Event{Event: PrePack}, &out)
+	if err == nil || !strings.Contains(err.Error(), "plugin veto: pre-pack") {
+		t.Errorf("Run() error = %v, want plugin veto failure", err)
+	}
+	if !strings.Contains(out.String(), "no packing") {
+		t.Errorf("output = %q, want plugin stderr", out.String())
+	}
+}
@@ -0,0 +1,249 @@
+// Package sdk manages the Apple SDKs darwin CGO targets compile against.
+// Zig ships the macOS libc headers but not the frameworks (CoreFoundation,
+// Security, ...) most cgo code for darwin needs, so those targets build
+// against a MacOSX.sdk: a local copy registered with Add, or an archive
+// downloaded from a URL the project configures.
+package sdk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/qntx/gox/internal/archive"
+	"github.com/qntx/gox/internal/cache"
+	"github.com/qntx/gox/internal/ui"
+	"github.com/qntx/gox/internal/zig"
+)
+
+// Env names the environment variable selecting an SDK directory when the
+// config names none, as with Apple's own tools.
+const Env = "SDKROOT"
+
+// linkExt marks a registration: a file holding the path of an SDK.
+const linkExt = ".path"
+
+// SDK is a registered macOS SDK.
+type SDK struct {
+	Version string // e.g. 14.5
+	Path    string // SDK root holding usr/include and System/Library/Frameworks
+}
+
+// Frameworks returns the directory holding the SDK's frameworks.
+func Frameworks(root string) string {
+	return filepath.Join(root, "System", "Library", "Frameworks")
+}
+
+// Add registers the SDK at src, a MacOSX.sdk directory or the URL of an
+// archive holding one, under its version. A URL is downloaded first.
+func Add(ctx context.Context, src string) (*SDK, error) {
+	path, err := filepath.Abs(src)
+	if isURL(src) {
+		path, err = download(ctx, src)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := check(path); err != nil {
+		return nil, err
+	}
+	version, err := Version(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir(), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir(), version+linkExt), []byte(path+"\n"), 0o644); err != nil {
+		return nil, err
+	}
+	return &SDK{Version: version, Path: path}, nil
+}
+
+// List returns the registered SDKs, newest first.
+func List() ([]SDK, error) {
+	entries, err := os.ReadDir(dir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []SDK
+	for _, e := range entries {
+		version, ok := strings.CutSuffix(e.Name(), linkExt)
+		if !ok || e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir(), e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, SDK{Version: version, Path: strings.TrimSpace(string(data))})
+	}
+	slices.SortFunc(out, func(a, b SDK) int { return zig.CompareVersions(b.Version, a.Version) })
+	return out, nil
+}
+
+// Remove unregisters version. Downloaded SDKs are deleted too; local copies
+// are left in place.
+func Remove(version string) error {
+	link := filepath.Join(dir(), version+linkExt)
+	data, err := os.ReadFile(link)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("macOS SDK %s is not registered", version)
+	}
+	if err != nil {
+		return err
+	}
+	if dl := downloaded(strings.TrimSpace(string(data))); dl != "" {
+		if err := os.RemoveAll(dl); err != nil {
+			return err
+		}
+	}
+	return os.Remove(link)
+}
+
+// Locate returns the SDK directory spec selects without downloading: spec
+// is a directory, a registered version or a URL, whose download location is
+// returned. An empty spec falls back to $SDKROOT, then to the newest
+// registered SDK, and returns "" when there is none.
+func Locate(spec string) (string, error) {
+	switch {
+	case spec == "" && os.Getenv(Env) != "":
+		return filepath.Abs(os.Getenv(Env))
+	case spec == "":
+		sdks, err := List()
+		if err != nil || len(sdks) == 0 {
+			return "", err
+		}
+		return sdks[0].Path, nil
+	case isURL(spec):
+		return sdkRoot(downloadDir(spec)), nil
+	case strings.ContainsAny(spec, `/\`) || strings.HasPrefix(spec, "."):
+		return filepath.Abs(spec)
+	}
+	data, err := os.ReadFile(filepath.Join(dir(), spec+linkExt))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("macOS SDK %s is not registered (run gox sdk add <path>)", spec)
+	}
+	return strings.TrimSpace(string(data)), err
+}
+
+// Resolve returns the SDK directory spec selects like Locate, downloading a
+// URL that is not cached yet, and checks that it holds an SDK.
+func Resolve(ctx context.Context, spec string) (string, error) {
+	path, err := Locate(spec)
+	if err != nil || path == "" {
+		return "", err
+	}
+	if isURL(spec) {
+		if path, err = download(ctx, spec); err != nil {
+			return "", err
+		}
+	}
+	if err := check(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Version reads an SDK's version from SDKSettings.json, falling back to a
+// MacOSX<version>.sdk directory name.
+func Version(root string) (string, error) {
+	var settings struct {
+		Version string `json:"Version"`
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "SDKSettings.json")); err == nil {
+		if json.Unmarshal(data, &settings) == nil && settings.Version != "" {
+			return settings.Version, nil
+		}
+	}
+	name := strings.TrimSuffix(filepath.Base(root), ".sdk")
+	if v, ok := strings.CutPrefix(name, "MacOSX"); ok && v != "" {
+		return v, nil
+	}
+	return "", fmt.Errorf("%s: no version in SDKSettings.json or a MacOSX<version>.sdk name", root)
+}
+
+// check fails unless root holds an SDK's headers and frameworks.
+func check(root string) error {
+	for _, d := range []string{filepath.Join(root, "usr", "include"), Frameworks(root)} {
+		if fi, err := os.Stat(d); err != nil || !fi.IsDir() {
+			return fmt.Errorf("%s is not a macOS SDK: %s is missing", root, d)
+		}
+	}
+	return nil
+}
+
+// download fetches the SDK archive at url into the cache, unless it is
+// there already, and returns the SDK root inside it.
+func download(ctx context.Context, url string) (string, error) {
+	dst := downloadDir(url)
+	if _, err := os.Stat(dst); err != nil {
+		size, _ := archive.ContentLength(ctx, url)
+		progress := ui.NewProgress()
+		bar := progress.AddBar("macOS SDK", size)
+		_, err := archive.DownloadWith(ctx, url, dst, archive.DownloadOptions{
+			Proxy:   bar.ProxyReader,
+			Resumed: bar.SetCurrent,
+		})
+		if err != nil {
+			bar.Abort(true)
+			progress.Wait()
+			return "", fmt.Errorf("macOS SDK: %w", err)
+		}
+		bar.Complete()
+		progress.Wait()
+		ui.Success("Downloaded macOS SDK from %s", url)
+	}
+	return sdkRoot(dst), nil
+}
+
+// sdkRoot returns dir, or the first *.sdk directory inside it holding an
+// SDK when the archive held more than the SDK itself, such as the
+// MacOSX.sdk symlinks Xcode ships next to versioned SDKs.
+func sdkRoot(dir string) string {
+	if check(dir) == nil {
+		return dir
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.sdk"))
+	for _, m := range matches {
+		if check(m) == nil {
+			return m
+		}
+	}
+	return dir
+}
+
+// downloadDir returns where the SDK archive at url is extracted.
+func downloadDir(url string) string {
+	h := sha256.Sum256([]byte(url))
+	return filepath.Join(dir(), "url-"+hex.EncodeToString(h[:8]))
+}
+
+// downloaded returns the download directory holding the SDK at path, or ""
+// for an SDK outside the cache.
+func downloaded(path string) string {
+	rel, err := filepath.Rel(dir(), path)
+	if err != nil || !filepath.IsLocal(rel) || !strings.HasPrefix(rel, "url-") {
+		return ""
+	}
+	top, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	return filepath.Join(dir(), top)
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://")
+}
+
+func dir() string {
+	return cache.Dir("sdk")
+}
@@ -0,0 +1,160 @@
+package sdk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qntx/gox/internal/fakenet"
+)
+
+// fakeSDK creates the skeleton of a macOS SDK named name under a temp dir.
+func fakeSDK(t *testing.T, name, settings string) string {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), name)
+	for _, d := range []string{"usr/include", "usr/lib", "System/Library/Frameworks/CoreFoundation.framework"} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if settings != "" {
+		if err := os.WriteFile(filepath.Join(root, "SDKSettings.json"), []byte(settings), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		name, dir, settings, want string
+	}{
+		{"settings", "MacOSX.sdk", `{"Version": "14.5", "DisplayName": "macOS 14.5"}`, "14.5"},
+		{"dir name", "MacOSX13.3.sdk", "", "13.3"},
+		{"unknown", "sdk", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Version(fakeSDK(t, tt.dir, tt.settings))
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("Version() = %q, want error", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Version() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddListRemove(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv(Env, "")
+
+	if got, err := Locate(""); err != nil || got != "" {
+		t.Fatalf("Locate() without SDKs = %q, %v", got, err)
+	}
+	old := fakeSDK(t, "MacOSX13.3.sdk", "")
+	newer := fakeSDK(t, "MacOSX.sdk", `{"Version": "14.5"}`)
+	for _, p := range []string{old, newer} {
+		if _, err := Add(t.Context(), p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Add(t.Context(), t.TempDir()); err == nil || !strings.Contains(err.Error(), "not a macOS SDK") {
+		t.Errorf("Add() of an empty dir = %v", err)
+	}
+
+	sdks, err := List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sdks) != 2 || sdks[0].Version != "14.5" || sdks[1].Path != old {
+		t.Fatalf("List() = %+v, want 14.5 then 13.3", sdks)
+	}
+
+	tests := []struct {
+		spec, env, want, wantErr string
+	}{
+		{"", "", newer, ""},
+		{"", old, old, ""},
+		{"13.3", "", old, ""},
+		{old, "", old, ""},
+		{"12.0", "", "", "not registered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec+"_"+tt.env, func(t *testing.T) {
+			t.Setenv(Env, tt.env)
+			got, err := Resolve(t.Context(), tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Resolve(%q) = %q, %v, want %q", tt.spec, got, err, tt.want)
+			}
+		})
+	}
+
+	if err := Remove("13.3"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(old); err != nil {
+		t.Errorf("Remove() deleted a registered directory: %v", err)
+	}
+	if sdks, _ := List(); len(sdks) != 1 {
+		t.Errorf("List() after Remove() = %+v", sdks)
+	}
+	if err := Remove("13.3"); err == nil {
+		t.Error("Remove() of an unregistered version succeeded")
+	}
+}
+
+func TestResolve_URL(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	data := fakenet.Archive(fakenet.TarGz, map[string]string{
+		"MacOSX14.5.sdk/usr/include/stdio.h": "",
+		"MacOSX14.5.sdk/System/Library/Frameworks/CoreFoundation.framework/Headers/CoreFoundation.h": "",
+		"MacOSX.sdk": "",
+	})
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			hits++
+		}
+		_, _ = w.Write(data)
+	}))
+	defer srv.Close()
+
+	url := srv.URL + "/MacOSX14.5.sdk.tar.gz"
+	for range 2 {
+		root, err := Resolve(t.Context(), url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Base(root) != "MacOSX14.5.sdk" {
+			t.Errorf("Resolve() = %s, want the SDK inside the archive", root)
+		}
+	}
+	if hits != 1 {
+		t.Errorf("downloaded %d times, want 1", hits)
+	}
+
+	s, err := Add(t.Context(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove(s.Version); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(downloadDir(url)); !os.IsNotExist(err) {
+		t.Errorf("Remove() kept the download: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		traces   string
+		disabled string
+		want     bool
+	}{
+		{"unset", "", "", "", false},
+		{"endpoint", "http://localhost:4318", "", "", true},
+		{"traces endpoint", "", "http://localhost:4318/v1/traces", "", true},
+		{"sdk disabled", "http://localhost:4318", "", "true", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", tt.endpoint)
+			t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", tt.traces)
+			t.Setenv("OTEL_SDK_DISABLED", tt.disabled)
+			if got := Enabled(); got != tt.want {
+				t.Errorf("Enabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetup_Disabled(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	shutdown, err := Setup(t.Context())
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	if err := shutdown(t.Context()); err != nil {
+		t.Errorf("shutdown() error = %v", err)
+	}
+}
+
+func TestPhase(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	old := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(old) })
+
+	ctx, root := Start(t.Context(), "gox build")
+	_ = Phase(ctx, "compile", func(context.Context) error { return nil })
+	want := errors.New("boom")
+	if err := Phase(ctx, "pack", func(context.Context) error { return want }); err != want {
+		t.Errorf("Phase() error = %v, want %v", err, want)
+	}
+	End(root, nil)
+
+	spans := rec.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	for _, s := range spans[:2] {
+		if s.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("%s parent = %v, want root", s.Name(), s.Parent().SpanID())
+		}
+	}
+	if got := spans[0].Status().Code; got != codes.Unset {
+		t.Errorf("compile status = %v, want Unset", got)
+	}
+	if got := spans[1].Status(); got.Code != codes.Error || got.Description != "boom" {
+		t.Errorf("pack status = %+v, want Error boom", got)
+	}
+}
@@ -0,0 +1,135 @@
+package ui
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/qntx/gox/uiutil"
+)
+
+// Event types of --json output.
+const (
+	EventMessage      = "message"
+	EventTargetStart  = "target-start"
+	EventTargetFinish = "target-finish"
+	EventDownload     = "download"
+	EventError        = "error"
+)
+
+// Event is one line of --json output.
+type Event struct {
+	Time       time.Time `json:"time"`
+	Type       string    `json:"type"`
+	Level      string    `json:"level,omitempty"`   // message: success, info, warn or error
+	Message    string    `json:"message,omitempty"` // message text
+	Target     string    `json:"target,omitempty"`
+	OS         string    `json:"os,omitempty"`
+	Arch       string    `json:"arch,omitempty"`
+	Name       string    `json:"name,omitempty"`   // download name
+	Status     string    `json:"status,omitempty"` // target or download outcome
+	Current    int64     `json:"current,omitempty"`
+	Total      int64     `json:"total,omitempty"`
+	DurationMS int64     `json:"duration_ms,omitempty"`
+	Artifacts  []string  `json:"artifacts,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// downloadEventInterval throttles download progress events per bar.
+const downloadEventInterval = 250 * time.Millisecond
+
+var (
+	jsonMode bool
+	emitMu   sync.Mutex
+)
+
+// SetJSON switches output to JSON events on Stdout, one object per line.
+// Messages become message events, build status lines are left to the
+// target events callers emit, and progress bars report download events.
+func SetJSON(on bool) {
+	jsonMode = on
+}
+
+// JSON reports whether output is JSON events.
+func JSON() bool {
+	return jsonMode
+}
+
+// Emit writes e to Stdout as one JSON line, stamping its time. It is a
+// no-op unless JSON output is on.
+func Emit(e Event) {
+	if !jsonMode {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	emitMu.Lock()
+	defer emitMu.Unlock()
+	_, _ = Stdout.Write(append(data, '\n'))
+}
+
+// message emits a message event at level.
+func message(level, msg string) {
+	Emit(Event{Type: EventMessage, Level: level, Message: msg})
+}
+
+// jsonBar counts a download and reports it as throttled download events.
+type jsonBar struct {
+	*uiutil.Counter
+	mu   sync.Mutex
+	last time.Time
+}
+
+func (b *jsonBar) Write(p []byte) (int, error) {
+	n, _ := b.Counter.Write(p)
+	b.tick()
+	return n, nil
+}
+
+func (b *jsonBar) SetCurrent(n int64) {
+	b.Counter.SetCurrent(n)
+	b.tick()
+}
+
+func (b *jsonBar) Complete() {
+	b.Counter.Complete()
+	b.emit("done")
+}
+
+func (b *jsonBar) Abort(drop bool) {
+	b.Counter.Abort(drop)
+	b.emit("aborted")
+}
+
+func (b *jsonBar) ProxyReader(r io.Reader) io.Reader {
+	return uiutil.ProxyReader(r, b)
+}
+
+func (b *jsonBar) tick() {
+	b.mu.Lock()
+	now := time.Now()
+	due := now.Sub(b.last) >= downloadEventInterval
+	if due {
+		b.last = now
+	}
+	b.mu.Unlock()
+	if due {
+		b.emit("running")
+	}
+}
+
+func (b *jsonBar) emit(status string) {
+	Emit(Event{
+		Type:    EventDownload,
+		Name:    b.Name,
+		Status:  status,
+		Current: b.Current(),
+		Total:   b.Total(),
+	})
+}
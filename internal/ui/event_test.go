@@ -0,0 +1,93 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// captureJSON turns on JSON output into a buffer for the rest of the test.
+func captureJSON(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	old := Stdout
+	Stdout = &buf
+	SetJSON(true)
+	t.Cleanup(func() {
+		Stdout = old
+		SetJSON(false)
+	})
+	return &buf
+}
+
+func decodeEvents(t *testing.T, buf *bytes.Buffer) []Event {
+	t.Helper()
+	var events []Event
+	for line := range strings.Lines(buf.String()) {
+		var e Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid event %q: %v", line, err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestEmit_Disabled(t *testing.T) {
+	var buf bytes.Buffer
+	old := Stdout
+	Stdout = &buf
+	t.Cleanup(func() { Stdout = old })
+
+	Emit(Event{Type: EventError, Error: "boom"})
+	if buf.Len() != 0 {
+		t.Errorf("Emit() without JSON wrote %q", buf.String())
+	}
+}
+
+func TestJSONMessages(t *testing.T) {
+	buf := captureJSON(t)
+
+	Success("built %d", 2)
+	Warn("careful")
+	Built("out/app", 0)
+	Label("zig", "/opt/zig")
+
+	events := decodeEvents(t, buf)
+	want := []struct{ level, msg string }{
+		{"success", "built 2"},
+		{"warn", "careful"},
+		{"info", "zig: /opt/zig"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %s", len(events), len(want), buf)
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.Type != EventMessage || e.Level != w.level || e.Message != w.msg || e.Time.IsZero() {
+			t.Errorf("event %d = %+v, want %s %q", i, e, w.level, w.msg)
+		}
+	}
+}
+
+func TestJSONProgress(t *testing.T) {
+	buf := captureJSON(t)
+
+	p := NewProgress()
+	bar := p.AddBar("lib.tar.gz", 10)
+	if _, err := bar.Write(make([]byte, 10)); err != nil {
+		t.Fatal(err)
+	}
+	bar.Complete()
+	p.Wait()
+
+	events := decodeEvents(t, buf)
+	if len(events) == 0 {
+		t.Fatal("no download events")
+	}
+	last := events[len(events)-1]
+	if last.Type != EventDownload || last.Name != "lib.tar.gz" || last.Status != "done" || last.Current != 10 || last.Total != 10 {
+		t.Errorf("last event = %+v", last)
+	}
+}
@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Stdout and Stderr are the streams gox and its subprocesses write to.
+// OpenLog tees both into a log file.
+var (
+	Stdout io.Writer = os.Stdout
+	Stderr io.Writer = os.Stderr
+)
+
+// logTimeFormat prefixes every line of the log file.
+const logTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// OpenLog appends an unstyled, timestamped copy of everything written to
+// Stdout and Stderr to the file at path. The returned func restores the
+// streams, flushes any partial line and closes the file. Progress bars
+// stay terminal-only.
+func OpenLog(path string) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	l := &logFile{w: f, now: time.Now}
+	stdout, stderr := Stdout, Stderr
+	Stdout = io.MultiWriter(stdout, l)
+	Stderr = io.MultiWriter(stderr, l)
+	return func() error {
+		Stdout, Stderr = stdout, stderr
+		l.flush()
+		return f.Close()
+	}, nil
+}
+
+// logFile strips ANSI sequences and writes complete lines with a timestamp.
+// A carriage return discards the pending line, so redrawn status lines only
+// log their final state.
+type logFile struct {
+	mu   sync.Mutex
+	w    io.Writer
+	now  func() time.Time
+	line []byte
+}
+
+func (l *logFile) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, c := range []byte(ansi.Strip(string(p))) {
+		switch c {
+		case '\n':
+			l.emit()
+		case '\r':
+			l.line = l.line[:0]
+		default:
+			l.line = append(l.line, c)
+		}
+	}
+	// A log write error must not fail the terminal output.
+	return len(p), nil
+}
+
+func (l *logFile) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.line) > 0 {
+		l.emit()
+	}
+}
+
+func (l *logFile) emit() {
+	var b bytes.Buffer
+	b.WriteString(l.now().Format(logTimeFormat))
+	if len(l.line) > 0 {
+		b.WriteByte(' ')
+		b.Write(l.line)
+	}
+	b.WriteByte('\n')
+	_, _ = l.w.Write(b.Bytes())
+	l.line = l.line[:0]
+}
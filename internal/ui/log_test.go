@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogFile(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 6e6, time.UTC)
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{"plain", []string{"hello\n"}, "2026-01-02T03:04:05.006Z hello\n"},
+		{"strips ansi", []string{"\x1b[1;32m✓\x1b[0m done\n"}, "2026-01-02T03:04:05.006Z ✓ done\n"},
+		{"joins partial writes", []string{"a", "b\nc\n"}, "2026-01-02T03:04:05.006Z ab\n2026-01-02T03:04:05.006Z c\n"},
+		{"carriage return keeps last frame", []string{"10%\r50%\r100%\n"}, "2026-01-02T03:04:05.006Z 100%\n"},
+		{"blank line", []string{"\n"}, "2026-01-02T03:04:05.006Z\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := &logFile{w: &buf, now: func() time.Time { return at }}
+			for _, w := range tt.writes {
+				if n, err := l.Write([]byte(w)); n != len(w) || err != nil {
+					t.Fatalf("Write() = %d, %v", n, err)
+				}
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("log = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gox.log")
+	var term bytes.Buffer
+	old := Stderr
+	Stderr = &term
+	t.Cleanup(func() { Stderr = old })
+
+	closeLog, err := OpenLog(path)
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v", err)
+	}
+	Info("building %s", "linux/amd64")
+	_, _ = Stderr.Write([]byte("partial"))
+	if err := closeLog(); err != nil {
+		t.Fatalf("close error = %v", err)
+	}
+	if Stderr != &term {
+		t.Error("close did not restore Stderr")
+	}
+
+	if !strings.Contains(term.String(), "building linux/amd64") {
+		t.Errorf("terminal = %q, want message", term.String())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], " building linux/amd64") || !strings.HasSuffix(lines[1], " partial") {
+		t.Errorf("log = %q, want timestamped message and flushed partial line", data)
+	}
+	if strings.Contains(string(data), "\x1b[") {
+		t.Errorf("log contains ANSI sequences: %q", data)
+	}
+}
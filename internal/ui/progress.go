@@ -7,15 +7,26 @@ import (
 
 	"github.com/vbauerster/mpb/v8"
 	"github.com/vbauerster/mpb/v8/decor"
+
+	"github.com/qntx/gox/uiutil"
+)
+
+var (
+	_ uiutil.Progress = (*Progress)(nil)
+	_ uiutil.Bar      = (*Bar)(nil)
 )
 
-// Progress manages concurrent progress bars.
+// Progress manages concurrent progress bars. With JSON output it reports
+// download events instead of drawing bars.
 type Progress struct {
 	p *mpb.Progress
 }
 
 // NewProgress creates a new progress container.
 func NewProgress() *Progress {
+	if jsonMode {
+		return &Progress{}
+	}
 	return &Progress{
 		p: mpb.New(
 			mpb.WithOutput(os.Stderr),
@@ -26,8 +37,11 @@ func NewProgress() *Progress {
 }
 
 // AddBar adds a new progress bar for a download task.
-// Returns an io.Writer that tracks bytes written.
-func (p *Progress) AddBar(name string, total int64) *Bar {
+// The bar is an io.Writer that tracks bytes written.
+func (p *Progress) AddBar(name string, total int64) uiutil.Bar {
+	if p.p == nil {
+		return &jsonBar{Counter: uiutil.NewCounter(name, total)}
+	}
 	// Truncate name if too long
 	displayName := filepath.Base(name)
 	if len(displayName) > 40 {
@@ -51,7 +65,9 @@ func (p *Progress) AddBar(name string, total int64) *Bar {
 
 // Wait waits for all bars to complete.
 func (p *Progress) Wait() {
-	p.p.Wait()
+	if p.p != nil {
+		p.p.Wait()
+	}
 }
 
 // Bar wraps an mpb.Bar and implements io.Writer.
@@ -71,6 +87,11 @@ func (b *Bar) SetTotal(total int64) {
 	b.bar.SetTotal(total, false)
 }
 
+// SetCurrent sets the progress, e.g. to the bytes of a resumed download.
+func (b *Bar) SetCurrent(n int64) {
+	b.bar.SetCurrent(n)
+}
+
 // Complete marks the bar as complete.
 func (b *Bar) Complete() {
 	b.bar.SetTotal(-1, true)
@@ -0,0 +1,32 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// Stdin is where Confirm reads answers from.
+var Stdin io.Reader = os.Stdin
+
+// Interactive reports whether stdin and stderr are terminals, so a prompt
+// can be seen and answered. JSON output is never interactive.
+func Interactive() bool {
+	return !jsonMode && term.IsTerminal(os.Stdin.Fd()) && term.IsTerminal(os.Stderr.Fd())
+}
+
+// Confirm asks a yes/no question and reports whether it was answered yes.
+// An empty answer or end of input counts as no.
+func Confirm(msg string, args ...any) bool {
+	fmt.Fprintf(Stderr, "%s %s [y/N] ", styleWarn.Render(iconWarning), fmt.Sprintf(msg, args...))
+	line, _ := bufio.NewReader(Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
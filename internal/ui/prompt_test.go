@@ -0,0 +1,32 @@
+package ui
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestConfirm(t *testing.T) {
+	old, oldErr := Stdin, Stderr
+	t.Cleanup(func() { Stdin, Stderr = old, oldErr })
+	Stderr = io.Discard
+
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"y\n", true},
+		{" Yes \n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			Stdin = strings.NewReader(tt.in)
+			if got := Confirm("Continue?"); got != tt.want {
+				t.Errorf("Confirm() with %q = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/term"
+)
+
+// Bytes is a table cell rendered with FormatSize and right-aligned.
+type Bytes int64
+
+// Status colors a table row.
+type Status int
+
+const (
+	StatusNone Status = iota
+	StatusOK
+	StatusFailed
+	StatusWarn
+	StatusMuted
+)
+
+// statusStyles color rows by status; set by the active theme. Color is
+// only a cue: tables should also carry the status as text.
+var statusStyles map[Status]lipgloss.Style
+
+// minColumn is the narrowest a column is truncated to when the table does
+// not fit the terminal.
+const minColumn = 8
+
+// Table renders a simple table. Widths are measured in terminal cells, so
+// wide (e.g. CJK) text stays aligned, and rows wider than the terminal are
+// truncated column by column, widest first.
+type Table struct {
+	headers []string
+	rows    []row
+	widths  []int
+	right   []bool
+	// MaxWidth caps the rendered width; 0 uses the terminal width of
+	// stderr, and a negative value disables truncation.
+	MaxWidth int
+}
+
+type row struct {
+	cols   []string
+	status Status
+}
+
+// NewTable creates a new table with headers.
+func NewTable(headers ...string) *Table {
+	widths := make([]int, len(headers))
+	for i, h := range headers {
+		widths[i] = ansi.StringWidth(h)
+	}
+	return &Table{headers: headers, widths: widths, right: make([]bool, len(headers))}
+}
+
+// AlignRight right-aligns the given columns.
+func (t *Table) AlignRight(cols ...int) {
+	for _, c := range cols {
+		if c >= 0 && c < len(t.right) {
+			t.right[c] = true
+		}
+	}
+}
+
+// AddRow adds a row to the table.
+func (t *Table) AddRow(cols ...string) {
+	t.add(StatusNone, cols)
+}
+
+// AddCells adds a row of typed cells: Bytes and time.Duration are formatted
+// with FormatSize and FormatDuration, and they and integers right-align
+// their column. Other values use fmt.Sprint.
+func (t *Table) AddCells(cells ...any) {
+	t.AddStatusRow(StatusNone, cells...)
+}
+
+// AddStatusRow adds a row of typed cells, as AddCells, colored by status.
+func (t *Table) AddStatusRow(status Status, cells ...any) {
+	cols := make([]string, len(cells))
+	for i, c := range cells {
+		var numeric bool
+		cols[i], numeric = formatCell(c)
+		if numeric {
+			t.AlignRight(i)
+		}
+	}
+	t.add(status, cols)
+}
+
+func (t *Table) add(status Status, cols []string) {
+	for i, c := range cols {
+		if w := ansi.StringWidth(c); i < len(t.widths) && w > t.widths[i] {
+			t.widths[i] = w
+		}
+	}
+	t.rows = append(t.rows, row{cols: cols, status: status})
+}
+
+func formatCell(c any) (string, bool) {
+	switch v := c.(type) {
+	case string:
+		return v, false
+	case Bytes:
+		return FormatSize(int64(v)), true
+	case time.Duration:
+		return FormatDuration(v), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	}
+	return fmt.Sprint(c), false
+}
+
+// Render prints the table.
+func (t *Table) Render() {
+	fmt.Fprint(Stderr, t.String())
+}
+
+// String returns the rendered table.
+func (t *Table) String() string {
+	widths := t.fit()
+	var out, sb strings.Builder
+
+	for i, h := range t.headers {
+		if i > 0 {
+			sb.WriteString("  ")
+		}
+		sb.WriteString(t.pad(i, h, widths[i]))
+	}
+	fmt.Fprintf(&out, "  %s\n", styleDim.Render(sb.String()))
+
+	sb.Reset()
+	for i, w := range widths {
+		if i > 0 {
+			sb.WriteString("  ")
+		}
+		sb.WriteString(strings.Repeat(ruleChar, w))
+	}
+	fmt.Fprintf(&out, "  %s\n", styleDim.Render(sb.String()))
+
+	for _, r := range t.rows {
+		sb.Reset()
+		for i, col := range r.cols {
+			if i > 0 {
+				sb.WriteString("  ")
+			}
+			if i < len(widths) {
+				sb.WriteString(t.pad(i, col, widths[i]))
+			} else {
+				sb.WriteString(col)
+			}
+		}
+		line := sb.String()
+		if style, ok := statusStyles[r.status]; ok {
+			line = style.Render(line)
+		}
+		fmt.Fprintf(&out, "  %s\n", line)
+	}
+	return out.String()
+}
+
+// pad truncates s to w cells and pads it according to the column alignment.
+func (t *Table) pad(col int, s string, w int) string {
+	if ansi.StringWidth(s) > w {
+		s = ansi.Truncate(s, w, ellipsis)
+	}
+	fill := strings.Repeat(" ", w-ansi.StringWidth(s))
+	if t.right[col] {
+		return fill + s
+	}
+	return s + fill
+}
+
+// fit returns column widths shrunk, widest column first, until the table
+// fits MaxWidth (or the terminal).
+func (t *Table) fit() []int {
+	widths := append([]int(nil), t.widths...)
+	limit := t.MaxWidth
+	if limit == 0 {
+		limit = termWidth()
+	}
+	if limit <= 0 {
+		return widths
+	}
+
+	// Leading indent plus two spaces between columns.
+	total := 2 + 2*max(len(widths)-1, 0)
+	for _, w := range widths {
+		total += w
+	}
+	for total > limit {
+		widest := 0
+		for i, w := range widths {
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= minColumn {
+			break
+		}
+		widths[widest]--
+		total--
+	}
+	return widths
+}
+
+// termWidth returns the width of the terminal on stderr, or 0 when stderr
+// is not a terminal.
+func termWidth() int {
+	if !term.IsTerminal(os.Stderr.Fd()) {
+		return 0
+	}
+	w, _, err := term.GetSize(os.Stderr.Fd())
+	if err != nil {
+		return 0
+	}
+	return w
+}
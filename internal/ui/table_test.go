@@ -0,0 +1,127 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestTable(t *testing.T) {
+	t.Run("basic table", func(t *testing.T) {
+		tbl := NewTable("NAME", "SIZE", "COUNT")
+
+		if len(tbl.headers) != 3 {
+			t.Errorf("len(headers) = %d, want 3", len(tbl.headers))
+		}
+		if len(tbl.widths) != 3 {
+			t.Errorf("len(widths) = %d, want 3", len(tbl.widths))
+		}
+
+		// Initial widths should match header lengths
+		if tbl.widths[0] != 4 { // "NAME"
+			t.Errorf("widths[0] = %d, want 4", tbl.widths[0])
+		}
+	})
+
+	t.Run("add row updates widths", func(t *testing.T) {
+		tbl := NewTable("A", "B")
+		tbl.AddRow("longer-value", "x")
+
+		if tbl.widths[0] != 12 { // "longer-value"
+			t.Errorf("widths[0] = %d, want 12", tbl.widths[0])
+		}
+		if tbl.widths[1] != 1 { // "B" is longer than "x"
+			t.Errorf("widths[1] = %d, want 1", tbl.widths[1])
+		}
+	})
+
+	t.Run("multiple rows", func(t *testing.T) {
+		tbl := NewTable("COL1", "COL2")
+		tbl.AddRow("a", "b")
+		tbl.AddRow("aa", "bb")
+		tbl.AddRow("aaa", "bbb")
+
+		if len(tbl.rows) != 3 {
+			t.Errorf("len(rows) = %d, want 3", len(tbl.rows))
+		}
+		if tbl.widths[0] != 4 { // "COL1" is still longest
+			t.Errorf("widths[0] = %d, want 4", tbl.widths[0])
+		}
+	})
+
+	t.Run("row longer than header", func(t *testing.T) {
+		tbl := NewTable("X")
+		tbl.AddRow("very-long-value")
+
+		if tbl.widths[0] != 15 {
+			t.Errorf("widths[0] = %d, want 15", tbl.widths[0])
+		}
+	})
+}
+
+func TestTable_Cells(t *testing.T) {
+	tbl := NewTable("NAME", "SIZE", "TIME", "N")
+	tbl.MaxWidth = -1
+	tbl.AddCells("a", Bytes(2048), 1500*time.Millisecond, 7)
+	tbl.AddCells("bb", Bytes(10), 20*time.Millisecond, 12)
+
+	lines := strings.Split(ansi.Strip(tbl.String()), "\n")
+	want := []string{
+		"  NAME    SIZE  TIME   N",
+		"  a     2.0 KB  1.5s   7",
+		"  bb      10 B  20ms  12",
+	}
+	for i, w := range want {
+		got := lines[i]
+		if i > 0 {
+			got = lines[i+1] // skip the rule under the header
+		}
+		if got != w {
+			t.Errorf("line %d = %q, want %q", i, got, w)
+		}
+	}
+}
+
+func TestTable_WideRunes(t *testing.T) {
+	tbl := NewTable("NAME", "X")
+	tbl.MaxWidth = -1
+	tbl.AddRow("日本語", "1")
+	tbl.AddRow("abc", "2")
+
+	if tbl.widths[0] != 6 {
+		t.Errorf("widths[0] = %d, want 6 (display cells)", tbl.widths[0])
+	}
+	lines := strings.Split(ansi.Strip(tbl.String()), "\n")
+	if ansi.StringWidth(lines[2]) != ansi.StringWidth(lines[3]) {
+		t.Errorf("rows misaligned: %q vs %q", lines[2], lines[3])
+	}
+}
+
+func TestTable_Truncate(t *testing.T) {
+	tbl := NewTable("PATH", "SIZE")
+	tbl.MaxWidth = 30
+	tbl.AddCells(strings.Repeat("x", 60), Bytes(1))
+
+	for _, line := range strings.Split(strings.TrimSuffix(ansi.Strip(tbl.String()), "\n"), "\n") {
+		if w := ansi.StringWidth(line); w > 30 {
+			t.Errorf("line width = %d, want <= 30: %q", w, line)
+		}
+	}
+	if !strings.Contains(tbl.String(), "…") {
+		t.Error("truncated cell should end with an ellipsis")
+	}
+}
+
+func TestTable_StatusRow(t *testing.T) {
+	tbl := NewTable("A")
+	tbl.MaxWidth = -1
+	tbl.AddStatusRow(StatusFailed, "boom")
+	if tbl.rows[0].status != StatusFailed {
+		t.Errorf("status = %v, want StatusFailed", tbl.rows[0].status)
+	}
+	if !strings.Contains(ansi.Strip(tbl.String()), "boom") {
+		t.Error("status row text missing")
+	}
+}
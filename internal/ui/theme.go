@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme names accepted by SetTheme.
+const (
+	ThemeDefault      = "default"
+	ThemeHighContrast = "high-contrast" // bright ANSI colors, no gray text
+	ThemeMono         = "mono"          // no color, ASCII icons spelled out as text
+)
+
+type theme struct {
+	colors palette
+	icons  iconSet
+}
+
+type palette struct {
+	primary, success, error, warning, info, muted, subtle, text lipgloss.TerminalColor
+}
+
+type iconSet struct {
+	success, error, warning, info, arrow, build string
+	rule, ellipsis                              string
+}
+
+var (
+	unicodeIcons = iconSet{
+		success: "✓", error: "✗", warning: "!", info: "●", arrow: "→", build: "⚙",
+		rule: "─", ellipsis: "…",
+	}
+	// asciiIcons spell out the status so it reads without color or glyphs.
+	asciiIcons = iconSet{
+		success: "[ok]", error: "[error]", warning: "[warn]", info: "[info]", arrow: "->", build: "[build]",
+		rule: "-", ellipsis: "...",
+	}
+	none = lipgloss.NoColor{}
+)
+
+var themes = map[string]theme{
+	ThemeDefault: {
+		colors: palette{
+			primary: lipgloss.Color("#7C3AED"),
+			success: lipgloss.Color("#10B981"),
+			error:   lipgloss.Color("#EF4444"),
+			warning: lipgloss.Color("#F59E0B"),
+			info:    lipgloss.Color("#3B82F6"),
+			muted:   lipgloss.Color("#6B7280"),
+			subtle:  lipgloss.Color("#9CA3AF"),
+			text:    lipgloss.Color("#F9FAFB"),
+		},
+		icons: unicodeIcons,
+	},
+	ThemeHighContrast: {
+		colors: palette{
+			primary: lipgloss.Color("13"), // bright magenta
+			success: lipgloss.Color("10"), // bright green
+			error:   lipgloss.Color("9"),  // bright red
+			warning: lipgloss.Color("11"), // bright yellow
+			info:    lipgloss.Color("14"), // bright cyan
+			muted:   none,
+			subtle:  none,
+			text:    none,
+		},
+		icons: unicodeIcons,
+	},
+	ThemeMono: {
+		colors: palette{none, none, none, none, none, none, none, none},
+		icons:  asciiIcons,
+	},
+}
+
+func init() {
+	apply(themes[ThemeDefault])
+}
+
+// Themes returns the names accepted by SetTheme.
+func Themes() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SetTheme switches the colors and icons of all later output. An empty
+// name selects ThemeDefault.
+func SetTheme(name string) error {
+	if name == "" {
+		name = ThemeDefault
+	}
+	t, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q (use %v)", name, Themes())
+	}
+	apply(t)
+	return nil
+}
+
+func apply(t theme) {
+	c := t.colors
+	colorPrimary, colorSuccess, colorError, colorWarning = c.primary, c.success, c.error, c.warning
+	colorInfo, colorMuted, colorSubtle, colorText = c.info, c.muted, c.subtle, c.text
+
+	styleSuccess = lipgloss.NewStyle().Foreground(colorSuccess).Bold(true)
+	styleError = lipgloss.NewStyle().Foreground(colorError).Bold(true)
+	styleWarn = lipgloss.NewStyle().Foreground(colorWarning).Bold(true)
+	styleInfo = lipgloss.NewStyle().Foreground(colorInfo).Bold(true)
+	styleBold = lipgloss.NewStyle().Bold(true)
+	styleDim = lipgloss.NewStyle().Foreground(colorMuted)
+	stylePrimary = lipgloss.NewStyle().Foreground(colorPrimary).Bold(true)
+	styleLabel = lipgloss.NewStyle().Foreground(colorSubtle).Width(12)
+	styleValue = lipgloss.NewStyle().Foreground(colorText)
+	styleHeader = lipgloss.NewStyle().Foreground(colorPrimary).Bold(true).MarginBottom(1)
+
+	statusStyles = map[Status]lipgloss.Style{
+		StatusOK:     lipgloss.NewStyle().Foreground(colorSuccess),
+		StatusFailed: lipgloss.NewStyle().Foreground(colorError),
+		StatusWarn:   lipgloss.NewStyle().Foreground(colorWarning),
+		StatusMuted:  styleDim,
+	}
+
+	i := t.icons
+	iconSuccess, iconError, iconWarning, iconInfo = i.success, i.error, i.warning, i.info
+	iconArrow, iconBuild = i.arrow, i.build
+	ruleChar, ellipsis = i.rule, i.ellipsis
+}
@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetTheme(t *testing.T) {
+	t.Cleanup(func() { _ = SetTheme(ThemeDefault) })
+
+	tests := []struct {
+		name    string
+		success string
+		rule    string
+		wantErr bool
+	}{
+		{"", "✓", "─", false},
+		{ThemeHighContrast, "✓", "─", false},
+		{ThemeMono, "[ok]", "-", false},
+		{"neon", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SetTheme(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetTheme(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if iconSuccess != tt.success || ruleChar != tt.rule {
+				t.Errorf("icons = %q, %q, want %q, %q", iconSuccess, ruleChar, tt.success, tt.rule)
+			}
+		})
+	}
+}
+
+func TestThemeMono_ASCII(t *testing.T) {
+	t.Cleanup(func() { _ = SetTheme(ThemeDefault) })
+	if err := SetTheme(ThemeMono); err != nil {
+		t.Fatal(err)
+	}
+
+	tbl := NewTable("NAME", "STATUS")
+	tbl.MaxWidth = 12
+	tbl.AddStatusRow(StatusFailed, "a-very-long-name", "failed")
+	out := tbl.String() + iconSuccess + iconError + iconWarning + iconInfo + iconArrow + iconBuild
+	for _, r := range out {
+		if r > 127 {
+			t.Fatalf("mono output contains non-ASCII %q: %q", r, out)
+		}
+	}
+	if strings.Contains(out, "\x1b[") {
+		t.Errorf("mono output contains color escapes: %q", out)
+	}
+}
+
+func TestThemes(t *testing.T) {
+	got := strings.Join(Themes(), ",")
+	if got != "default,high-contrast,mono" {
+		t.Errorf("Themes() = %q", got)
+	}
+}
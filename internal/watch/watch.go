@@ -0,0 +1,200 @@
+// Package watch re-runs a function when source files change. It polls file
+// sizes and modification times, which needs no platform notification API
+// and is cheap for module-sized trees.
+package watch
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/qntx/gox/internal/ui"
+)
+
+// DefaultIgnore lists paths never watched: VCS metadata, a project-scoped
+// gox cache and JavaScript dependencies.
+var DefaultIgnore = []string{".git", ".gox", "node_modules"}
+
+// sourceExts are the file extensions whose changes trigger a re-run.
+var sourceExts = []string{
+	".go", ".c", ".h", ".cc", ".cpp", ".cxx", ".hh", ".hpp", ".hxx",
+	".m", ".s", ".S", ".syso",
+}
+
+// sourceNames are files besides sources whose changes trigger a re-run.
+var sourceNames = []string{"go.mod", "go.sum", "gox.toml"}
+
+// Watcher polls a tree for source changes.
+type Watcher struct {
+	Root     string
+	Ignore   []string      // glob patterns matched against relative paths and base names
+	Interval time.Duration // time between polls (default: 500ms)
+	Debounce time.Duration // quiet period after a change before re-running (default: 300ms)
+	Restart  bool          // cancel a run still in progress when files change
+}
+
+// stamp identifies a file version cheaply.
+type stamp struct {
+	size  int64
+	mtime time.Time
+}
+
+// Run calls fn, then again each time a source file changes, until ctx is
+// canceled. Errors from fn are reported and do not stop watching. Without
+// Restart, changes made while fn runs trigger a new run once it returns.
+func (w *Watcher) Run(ctx context.Context, fn func(context.Context) error) error {
+	interval := cmpDuration(w.Interval, 500*time.Millisecond)
+	debounce := cmpDuration(w.Debounce, 300*time.Millisecond)
+
+	snap, err := w.scan()
+	if err != nil {
+		return err
+	}
+	for {
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() { done <- fn(runCtx) }()
+
+		var (
+			changed []string
+			last    time.Time
+			running = true
+		)
+		ticker := time.NewTicker(interval)
+	poll:
+		for {
+			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				cancel()
+				if running {
+					<-done
+				}
+				return nil
+			case err := <-done:
+				running = false
+				if err != nil && ctx.Err() == nil {
+					ui.Error("%v", err)
+				}
+				if len(changed) == 0 {
+					ui.Info("Watching %s for changes (Ctrl-C to stop)", w.Root)
+				}
+			case now := <-ticker.C:
+				next, err := w.scan()
+				if err != nil {
+					continue
+				}
+				if diff := compare(snap, next); len(diff) > 0 {
+					changed = append(changed, diff...)
+					last = now
+					snap = next
+				}
+				if len(changed) == 0 || now.Sub(last) < debounce {
+					continue
+				}
+				if running && !w.Restart {
+					continue
+				}
+				break poll
+			}
+		}
+		ticker.Stop()
+		cancel()
+		if running {
+			<-done
+		}
+		reportChange(changed)
+	}
+}
+
+func reportChange(changed []string) {
+	slices.Sort(changed)
+	changed = slices.Compact(changed)
+	if len(changed) == 1 {
+		ui.Info("Changed %s, re-running", changed[0])
+		return
+	}
+	ui.Info("Changed %s and %d more, re-running", changed[0], len(changed)-1)
+}
+
+// scan records the watched source files under Root.
+func (w *Watcher) scan() (map[string]stamp, error) {
+	files := map[string]stamp{}
+	err := filepath.WalkDir(w.Root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == w.Root {
+				return err
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(w.Root, p)
+		if err != nil || rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if w.ignored(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !Source(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files[rel] = stamp{info.Size(), info.ModTime()}
+		return nil
+	})
+	return files, err
+}
+
+// ignored reports whether rel, a slash-separated path under Root, matches
+// an ignore pattern by its full path or base name.
+func (w *Watcher) ignored(rel string) bool {
+	base := path.Base(rel)
+	for _, pat := range slices.Concat(DefaultIgnore, w.Ignore) {
+		pat = strings.TrimSuffix(filepath.ToSlash(pat), "/")
+		if ok, _ := path.Match(pat, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(pat, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Source reports whether a file named name is watched.
+func Source(name string) bool {
+	return slices.Contains(sourceNames, name) || slices.Contains(sourceExts, filepath.Ext(name))
+}
+
+// compare returns the files added, modified or removed between a and b.
+func compare(a, b map[string]stamp) []string {
+	var out []string
+	for name, s := range b {
+		if old, ok := a[name]; !ok || old != s {
+			out = append(out, name)
+		}
+	}
+	for name := range a {
+		if _, ok := b[name]; !ok {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func cmpDuration(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
@@ -0,0 +1,155 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"main.go", true},
+		{"lib.c", true},
+		{"lib.h", true},
+		{"impl.cpp", true},
+		{"go.mod", true},
+		{"gox.toml", true},
+		{"README.md", false},
+		{"app.exe", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Source(tt.name); got != tt.want {
+				t.Errorf("Source(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScan_Ignore(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{
+		"main.go",
+		"notes.txt",
+		"c/lib.c",
+		"c/gen.pb.go",
+		"testdata/x.go",
+		".git/hooks.go",
+		"node_modules/a/b.c",
+	} {
+		writeFile(t, filepath.Join(root, name), "x")
+	}
+
+	w := &Watcher{Root: root, Ignore: []string{"testdata/", "*.pb.go"}}
+	snap, err := w.scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for name := range snap {
+		got = append(got, name)
+	}
+	slices.Sort(got)
+	if want := []string{"c/lib.c", "main.go"}; !slices.Equal(got, want) {
+		t.Errorf("scan = %v, want %v", got, want)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	now := time.Now()
+	a := map[string]stamp{"a.go": {1, now}, "b.go": {1, now}, "c.go": {1, now}}
+	b := map[string]stamp{"a.go": {1, now}, "b.go": {2, now}, "d.go": {1, now}}
+	got := compare(a, b)
+	slices.Sort(got)
+	if want := []string{"b.go", "c.go", "d.go"}; !slices.Equal(got, want) {
+		t.Errorf("compare = %v, want %v", got, want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		restart bool
+	}{
+		{"wait", false},
+		{"restart", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			src := filepath.Join(root, "main.go")
+			writeFile(t, src, "package main")
+
+			w := &Watcher{Root: root, Interval: 5 * time.Millisecond, Debounce: 20 * time.Millisecond, Restart: tt.restart}
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			runs := make(chan int, 10)
+			n := 0
+			errc := make(chan error, 1)
+			go func() {
+				errc <- w.Run(ctx, func(ctx context.Context) error {
+					n++
+					runs <- n
+					if tt.restart {
+						<-ctx.Done() // a long-running program
+					}
+					return nil
+				})
+			}()
+
+			wantRun(t, runs, 1)
+			// Burst of writes within the debounce window: one re-run.
+			for i := range 3 {
+				writeFile(t, src, "package main // "+string(rune('a'+i)))
+				time.Sleep(2 * time.Millisecond)
+			}
+			wantRun(t, runs, 2)
+			select {
+			case got := <-runs:
+				t.Fatalf("unexpected run %d", got)
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			writeFile(t, filepath.Join(root, "notes.txt"), "x")
+			select {
+			case got := <-runs:
+				t.Fatalf("run %d after a non-source change", got)
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			cancel()
+			if err := <-errc; err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+		})
+	}
+}
+
+func wantRun(t *testing.T, runs <-chan int, want int) {
+	t.Helper()
+	select {
+	case got := <-runs:
+		if got != want {
+			t.Fatalf("run %d, want %d", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for run %d", want)
+	}
+}
+
+func writeFile(t *testing.T, name, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,344 @@
+// Package winres writes Windows resources (an icon, version information and
+// an application manifest) as a COFF object the go command links into
+// windows binaries when it finds it as a .syso file in the main package, as
+// goversioninfo and rsrc do.
+package winres
+
+import (
+	"bytes"
+	"cmp"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Resource types and IDs, as in winuser.h.
+const (
+	typeIcon      = 3
+	typeGroupIcon = 14
+	typeVersion   = 16
+	typeManifest  = 24
+
+	manifestID = 1 // CREATEPROCESS_MANIFEST_RESOURCE_ID
+	langEnUS   = 0x0409
+	codeUTF16  = 1200
+)
+
+// Resources are the resources of a windows binary. Unset fields are left
+// out.
+type Resources struct {
+	Icon     []byte // contents of a .ico file
+	Manifest []byte // application manifest XML
+	Version  *VersionInfo
+}
+
+// VersionInfo is the VERSIONINFO resource Explorer shows in a file's
+// Details tab.
+type VersionInfo struct {
+	FileVersion    [4]uint16
+	ProductVersion [4]uint16
+	Strings        map[string]string // e.g. CompanyName, FileDescription
+}
+
+// ParseVersion returns the numeric components of a version such as
+// "v1.2.3-rc.1", up to four, stopping at the first that does not start with
+// a digit.
+func ParseVersion(s string) [4]uint16 {
+	var v [4]uint16
+	for i, part := range strings.SplitN(strings.TrimPrefix(s, "v"), ".", 4) {
+		end := strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' })
+		if end < 0 {
+			end = len(part)
+		}
+		n, err := strconv.ParseUint(part[:end], 10, 16)
+		if err != nil {
+			break
+		}
+		v[i] = uint16(n)
+		if end < len(part) {
+			break
+		}
+	}
+	return v
+}
+
+// machines maps the GOARCHes the go linker reads resource objects for to
+// their COFF machine and image-relative relocation type.
+var machines = map[string]struct{ machine, reloc uint16 }{
+	"386":   {pe.IMAGE_FILE_MACHINE_I386, 0x0007},  // IMAGE_REL_I386_DIR32NB
+	"amd64": {pe.IMAGE_FILE_MACHINE_AMD64, 0x0003}, // IMAGE_REL_AMD64_ADDR32NB
+	"arm64": {pe.IMAGE_FILE_MACHINE_ARM64, 0x0002}, // IMAGE_REL_ARM64_ADDR32NB
+}
+
+// Syso returns r as a COFF object for goarch holding a .rsrc section.
+func (r *Resources) Syso(goarch string) ([]byte, error) {
+	m, ok := machines[goarch]
+	if !ok {
+		return nil, fmt.Errorf("windows resources are not supported on %s", goarch)
+	}
+	res, err := r.resources()
+	if err != nil {
+		return nil, err
+	}
+	section, relocs := rsrc(res)
+
+	const headers = 20 + 40 // file and section header
+	relocAt := headers + len(section)
+	symAt := relocAt + 10*len(relocs)
+	var buf bytes.Buffer
+	write := func(v any) { _ = binary.Write(&buf, binary.LittleEndian, v) }
+	write(pe.FileHeader{
+		Machine:              m.machine,
+		NumberOfSections:     1,
+		PointerToSymbolTable: uint32(symAt),
+		NumberOfSymbols:      1,
+	})
+	write(pe.SectionHeader32{
+		Name:                 [8]uint8{'.', 'r', 's', 'r', 'c'},
+		SizeOfRawData:        uint32(len(section)),
+		PointerToRawData:     headers,
+		PointerToRelocations: uint32(relocAt),
+		NumberOfRelocations:  uint16(len(relocs)),
+		Characteristics:      pe.IMAGE_SCN_CNT_INITIALIZED_DATA | pe.IMAGE_SCN_MEM_READ,
+	})
+	buf.Write(section)
+	for _, off := range relocs {
+		write(pe.Reloc{VirtualAddress: off, SymbolTableIndex: 0, Type: m.reloc})
+	}
+	// The relocations are against the section symbol, so each field holds
+	// its offset into the section as the addend.
+	write(pe.COFFSymbol{
+		Name:          [8]uint8{'.', 'r', 's', 'r', 'c'},
+		SectionNumber: 1,
+		StorageClass:  3, // IMAGE_SYM_CLASS_STATIC
+	})
+	write(uint32(4)) // empty string table
+	return buf.Bytes(), nil
+}
+
+// resource is one entry of the resource tree.
+type resource struct {
+	typ, id uint16
+	data    []byte
+}
+
+func (r *Resources) resources() ([]resource, error) {
+	var out []resource
+	if len(r.Icon) > 0 {
+		group, images, err := parseIcon(r.Icon)
+		if err != nil {
+			return nil, err
+		}
+		for i, img := range images {
+			out = append(out, resource{typeIcon, uint16(i + 1), img})
+		}
+		out = append(out, resource{typeGroupIcon, 1, group})
+	}
+	if r.Version != nil {
+		out = append(out, resource{typeVersion, 1, r.Version.bytes()})
+	}
+	if len(r.Manifest) > 0 {
+		out = append(out, resource{typeManifest, manifestID, r.Manifest})
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no windows resources")
+	}
+	slices.SortFunc(out, func(a, b resource) int {
+		return cmp.Or(cmp.Compare(a.typ, b.typ), cmp.Compare(a.id, b.id))
+	})
+	return out, nil
+}
+
+// rsrc lays out the resource section: the type, name and language
+// directories, then the data entries, then the data. It returns the section
+// and the offsets of the data entries' RVA fields, which need relocating.
+func rsrc(res []resource) ([]byte, []uint32) {
+	const dirSize, entrySize, dataEntrySize = 16, 8, 16
+	var types []uint16
+	for _, r := range res {
+		if !slices.Contains(types, r.typ) {
+			types = append(types, r.typ)
+		}
+	}
+
+	// Offsets of every directory and entry, in the order they are written.
+	typeDirs := make([]int, len(types))
+	off := dirSize + entrySize*len(types)
+	for i, t := range types {
+		typeDirs[i] = off
+		off += dirSize + entrySize*countType(res, t)
+	}
+	langDirs := make([]int, len(res))
+	for i := range res {
+		langDirs[i] = off
+		off += dirSize + entrySize
+	}
+	dataEntries := make([]int, len(res))
+	for i := range res {
+		dataEntries[i] = off
+		off += dataEntrySize
+	}
+	data := make([]int, len(res))
+	for i, r := range res {
+		off = alignUp(off, 8)
+		data[i] = off
+		off += len(r.data)
+	}
+
+	b := make([]byte, off)
+	dir := func(at, ids int) int {
+		binary.LittleEndian.PutUint16(b[at+14:], uint16(ids)) // NumberOfIdEntries
+		return at + dirSize
+	}
+	entry := func(at int, id uint16, target int, subdir bool) {
+		binary.LittleEndian.PutUint32(b[at:], uint32(id))
+		if subdir {
+			target |= 1 << 31
+		}
+		binary.LittleEndian.PutUint32(b[at+4:], uint32(target))
+	}
+
+	at := dir(0, len(types))
+	for i, t := range types {
+		entry(at+entrySize*i, t, typeDirs[i], true)
+	}
+	i := 0
+	for ti, t := range types {
+		at := dir(typeDirs[ti], countType(res, t))
+		for ; i < len(res) && res[i].typ == t; i++ {
+			entry(at, res[i].id, langDirs[i], true)
+			at += entrySize
+		}
+	}
+	relocs := make([]uint32, len(res))
+	for i, r := range res {
+		entry(dir(langDirs[i], 1), langEnUS, dataEntries[i], false)
+		binary.LittleEndian.PutUint32(b[dataEntries[i]:], uint32(data[i]))
+		binary.LittleEndian.PutUint32(b[dataEntries[i]+4:], uint32(len(r.data)))
+		relocs[i] = uint32(dataEntries[i])
+		copy(b[data[i]:], r.data)
+	}
+	return b, relocs
+}
+
+func countType(res []resource, typ uint16) int {
+	n := 0
+	for _, r := range res {
+		if r.typ == typ {
+			n++
+		}
+	}
+	return n
+}
+
+// parseIcon splits a .ico file into the images of its RT_ICON resources,
+// numbered from 1, and the RT_GROUP_ICON directory referring to them.
+func parseIcon(ico []byte) ([]byte, [][]byte, error) {
+	const headerSize, entrySize, groupEntrySize = 6, 16, 14
+	if len(ico) < headerSize || binary.LittleEndian.Uint16(ico[0:]) != 0 || binary.LittleEndian.Uint16(ico[2:]) != 1 {
+		return nil, nil, errors.New("icon: not a .ico file")
+	}
+	n := int(binary.LittleEndian.Uint16(ico[4:]))
+	if n == 0 || len(ico) < headerSize+entrySize*n {
+		return nil, nil, errors.New("icon: truncated .ico file")
+	}
+	group := make([]byte, headerSize+groupEntrySize*n)
+	copy(group, ico[:headerSize])
+	images := make([][]byte, n)
+	for i := range n {
+		e := ico[headerSize+entrySize*i:]
+		size := int(binary.LittleEndian.Uint32(e[8:]))
+		offset := int(binary.LittleEndian.Uint32(e[12:]))
+		if offset < 0 || size <= 0 || offset > len(ico) || size > len(ico)-offset {
+			return nil, nil, fmt.Errorf("icon: image %d lies outside the file", i+1)
+		}
+		images[i] = ico[offset : offset+size]
+		g := group[headerSize+groupEntrySize*i:]
+		copy(g, e[:12]) // size, colors, planes, bit count and byte count
+		binary.LittleEndian.PutUint16(g[12:], uint16(i+1))
+	}
+	return group, images, nil
+}
+
+// bytes encodes v as a VS_VERSIONINFO block.
+func (v *VersionInfo) bytes() []byte {
+	fixed := make([]byte, 52) // VS_FIXEDFILEINFO
+	for i, d := range []uint32{
+		0xfeef04bd, 0x00010000,
+		uint32(v.FileVersion[0])<<16 | uint32(v.FileVersion[1]), uint32(v.FileVersion[2])<<16 | uint32(v.FileVersion[3]),
+		uint32(v.ProductVersion[0])<<16 | uint32(v.ProductVersion[1]), uint32(v.ProductVersion[2])<<16 | uint32(v.ProductVersion[3]),
+		0x3f,    // file flags mask
+		0,       // file flags
+		0x40004, // VOS_NT_WINDOWS32
+		1,       // VFT_APP
+	} {
+		binary.LittleEndian.PutUint32(fixed[4*i:], d)
+	}
+
+	var strs []block
+	for _, k := range slices.Sorted(maps.Keys(v.Strings)) {
+		val := utf16z(v.Strings[k])
+		strs = append(strs, block{key: k, text: true, value: val, valueLen: len(val) / 2})
+	}
+	translation := binary.LittleEndian.AppendUint16(binary.LittleEndian.AppendUint16(nil, langEnUS), codeUTF16)
+	root := block{key: "VS_VERSION_INFO", value: fixed, valueLen: len(fixed), children: []block{
+		{key: "StringFileInfo", text: true, children: []block{
+			{key: fmt.Sprintf("%04X%04X", langEnUS, codeUTF16), text: true, children: strs},
+		}},
+		{key: "VarFileInfo", text: true, children: []block{
+			{key: "Translation", value: translation, valueLen: len(translation)},
+		}},
+	}}
+	return root.bytes()
+}
+
+// block is a node of a VS_VERSIONINFO tree.
+type block struct {
+	key      string
+	text     bool // value is a string; valueLen counts UTF-16 units
+	value    []byte
+	valueLen int
+	children []block
+}
+
+func (b *block) bytes() []byte {
+	out := make([]byte, 6, 64)
+	out = append(out, utf16z(b.key)...)
+	out = pad4(out)
+	out = append(out, b.value...)
+	for _, c := range b.children {
+		out = append(pad4(out), c.bytes()...)
+	}
+	binary.LittleEndian.PutUint16(out[0:], uint16(len(out)))
+	binary.LittleEndian.PutUint16(out[2:], uint16(b.valueLen))
+	if b.text {
+		binary.LittleEndian.PutUint16(out[4:], 1)
+	}
+	return out
+}
+
+// utf16z encodes s as NUL-terminated little-endian UTF-16.
+func utf16z(s string) []byte {
+	var out []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		out = binary.LittleEndian.AppendUint16(out, u)
+	}
+	return append(out, 0, 0)
+}
+
+func pad4(b []byte) []byte {
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func alignUp(n, align int) int {
+	return (n + align - 1) / align * align
+}
@@ -0,0 +1,167 @@
+package winres
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testIcon returns a .ico file holding the given images.
+func testIcon(images ...[]byte) []byte {
+	ico := binary.LittleEndian.AppendUint16(nil, 0)
+	ico = binary.LittleEndian.AppendUint16(ico, 1)
+	ico = binary.LittleEndian.AppendUint16(ico, uint16(len(images)))
+	offset := 6 + 16*len(images)
+	for i, img := range images {
+		ico = append(ico, byte(16*(i+1)), byte(16*(i+1)), 0, 0)
+		ico = binary.LittleEndian.AppendUint16(ico, 1)
+		ico = binary.LittleEndian.AppendUint16(ico, 32)
+		ico = binary.LittleEndian.AppendUint32(ico, uint32(len(img)))
+		ico = binary.LittleEndian.AppendUint32(ico, uint32(offset))
+		offset += len(img)
+	}
+	for _, img := range images {
+		ico = append(ico, img...)
+	}
+	return ico
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want [4]uint16
+	}{
+		{"1.2.3.4", [4]uint16{1, 2, 3, 4}},
+		{"v1.2.3", [4]uint16{1, 2, 3, 0}},
+		{"v1.2.3-rc.1", [4]uint16{1, 2, 3, 0}},
+		{"v0.4.1-3-gabcdef0", [4]uint16{0, 4, 1, 0}},
+		{"2024.10", [4]uint16{2024, 10, 0, 0}},
+		{"dev", [4]uint16{}},
+		{"", [4]uint16{}},
+		{"70000.1", [4]uint16{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := ParseVersion(tt.in); got != tt.want {
+				t.Errorf("ParseVersion(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyso(t *testing.T) {
+	r := &Resources{
+		Icon:     testIcon([]byte("small"), []byte("large image")),
+		Manifest: []byte("<assembly/>"),
+		Version: &VersionInfo{
+			FileVersion: [4]uint16{1, 2, 3, 0},
+			Strings:     map[string]string{"CompanyName": "Acme", "FileVersion": "1.2.3"},
+		},
+	}
+	for _, tt := range []struct {
+		arch    string
+		machine uint16
+	}{
+		{"amd64", pe.IMAGE_FILE_MACHINE_AMD64},
+		{"386", pe.IMAGE_FILE_MACHINE_I386},
+		{"arm64", pe.IMAGE_FILE_MACHINE_ARM64},
+	} {
+		t.Run(tt.arch, func(t *testing.T) {
+			data, err := r.Syso(tt.arch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := pe.NewFile(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Machine != tt.machine {
+				t.Errorf("machine = %#x, want %#x", f.Machine, tt.machine)
+			}
+			s := f.Section(".rsrc")
+			if s == nil {
+				t.Fatal("no .rsrc section")
+			}
+			section, err := s.Data()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Unlinked, each data entry holds the section offset of its
+			// resource: the addend of its relocation.
+			got := map[string]string{}
+			walk(t, section, 0, nil, got)
+			want := map[string]string{
+				"3/1":  "small",
+				"3/2":  "large image",
+				"24/1": "<assembly/>",
+			}
+			for k, v := range want {
+				if got[k] != v {
+					t.Errorf("resource %s = %q, want %q", k, got[k], v)
+				}
+			}
+			if group := got["14/1"]; len(group) != 6+14*2 || group[6+12] != 1 || group[6+14+12] != 2 {
+				t.Errorf("group icon = %x, want 2 entries naming icons 1 and 2", group)
+			}
+			if v := got["16/1"]; !strings.Contains(v, string(utf16z("Acme"))) || !strings.Contains(v, string(utf16z("VS_VERSION_INFO"))) {
+				t.Errorf("version info lacks its strings: %x", v)
+			}
+			if len(s.Relocs) != len(got) {
+				t.Errorf("%d relocations, want one per resource (%d)", len(s.Relocs), len(got))
+			}
+			if len(f.COFFSymbols) != 1 || f.COFFSymbols[0].SectionNumber != 1 {
+				t.Errorf("symbols = %+v, want the .rsrc section symbol", f.COFFSymbols)
+			}
+		})
+	}
+}
+
+// walk records the data of every resource under the directory at off by
+// its "type/id" path, leaving out the language.
+func walk(t *testing.T, section []byte, off uint32, path []string, out map[string]string) {
+	t.Helper()
+	n := int(binary.LittleEndian.Uint16(section[off+12:])) + int(binary.LittleEndian.Uint16(section[off+14:]))
+	for i := range n {
+		e := section[off+16+uint32(8*i):]
+		target := binary.LittleEndian.Uint32(e[4:])
+		if target&(1<<31) != 0 {
+			p := path
+			if len(path) < 2 {
+				p = append(slices.Clone(path), strconv.Itoa(int(binary.LittleEndian.Uint32(e))))
+			}
+			walk(t, section, target&^(1<<31), p, out)
+			continue
+		}
+		start := binary.LittleEndian.Uint32(section[target:])
+		size := binary.LittleEndian.Uint32(section[target+4:])
+		out[strings.Join(path, "/")] = string(section[start : start+size])
+	}
+}
+
+func TestSyso_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		r    Resources
+		arch string
+		want string
+	}{
+		{"arch", Resources{Manifest: []byte("<assembly/>")}, "riscv64", "not supported on riscv64"},
+		{"empty", Resources{}, "amd64", "no windows resources"},
+		{"not ico", Resources{Icon: []byte("PNG")}, "amd64", "not a .ico file"},
+		{"truncated", Resources{Icon: testIcon([]byte("img"))[:10]}, "amd64", "truncated"},
+		{"outside", Resources{Icon: testIcon([]byte("img"))[:22]}, "amd64", "outside the file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.r.Syso(tt.arch)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Syso() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package worker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/qntx/gox/internal/hosttool"
+)
+
+// ErrUnreachable wraps failures to reach a worker or keep its connection,
+// as opposed to builds that ran and failed.
+var ErrUnreachable = errors.New("worker unreachable")
+
+// Client dispatches jobs to one worker.
+type Client struct {
+	Addr  string
+	Token string
+	HTTP  *http.Client
+}
+
+// NewClient returns a client for the worker at addr, a host, host:port or
+// URL. Hosts without a port use DefaultPort.
+func NewClient(addr, token string) *Client {
+	return &Client{Addr: addr, Token: token, HTTP: http.DefaultClient}
+}
+
+// Run sends job with the tar.gz source tree at source, copies the build
+// output to out and unpacks the files the build wrote into dst, returning
+// their paths.
+func (c *Client) Run(ctx context.Context, job Job, source string, out io.Writer, dst string) ([]string, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() { pw.CloseWithError(writeJob(mw, job, source)) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/v1/jobs"), pr)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		pr.CloseWithError(err)
+		return nil, c.unreachable(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("worker %s: %s: %s", c.Addr, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64*1024), 1<<30)
+	for sc.Scan() {
+		var ev Event
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			return nil, fmt.Errorf("worker %s: %w", c.Addr, err)
+		}
+		if ev.Done {
+			if ev.Error != "" {
+				return nil, errors.New(ev.Error)
+			}
+			return Unpack(bytes.NewReader(ev.Artifacts), dst)
+		}
+		if _, err := io.WriteString(out, ev.Output); err != nil {
+			return nil, err
+		}
+	}
+	if err := sc.Err(); err != nil && ctx.Err() == nil {
+		return nil, c.unreachable(err)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return nil, c.unreachable(errors.New("connection closed before the build finished"))
+}
+
+func (c *Client) unreachable(err error) error {
+	return fmt.Errorf("%w: %s: %v", ErrUnreachable, c.Addr, err)
+}
+
+func (c *Client) url(path string) string {
+	addr := strings.TrimSuffix(c.Addr, "/")
+	if !strings.Contains(addr, "://") {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(strings.Trim(addr, "[]"), DefaultPort)
+		}
+		addr = "http://" + addr
+	}
+	return addr + path
+}
+
+func writeJob(mw *multipart.Writer, job Job, source string) error {
+	jw, err := mw.CreateFormField("job")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(jw).Encode(job); err != nil {
+		return err
+	}
+	sw, err := mw.CreateFormFile("source", "source.tar.gz")
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(sw, f); err != nil {
+		return err
+	}
+	return mw.Close()
+}
+
+// SourceFiles lists the files under root to ship to workers, relative to
+// root with forward slashes. In a git work tree these are the tracked and
+// untracked files git does not ignore; otherwise everything but VCS
+// metadata and a project-scoped gox cache.
+func SourceFiles(ctx context.Context, root string) ([]string, error) {
+	cmd := hosttool.Command(ctx, "git", "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	cmd.Dir = root
+	if out, err := cmd.Output(); err == nil {
+		var files []string
+		for name := range strings.SplitSeq(string(out), "\x00") {
+			if name != "" {
+				files = append(files, name)
+			}
+		}
+		slices.Sort(files)
+		return slices.Compact(files), nil
+	}
+
+	var files []string
+	err := walk(root, func(rel string, info fs.FileInfo) {
+		if info.Mode().IsRegular() || info.Mode()&fs.ModeSymlink != 0 {
+			files = append(files, rel)
+		}
+	})
+	return files, err
+}
+
+// RelDir returns dir relative to root with forward slashes, or "." when
+// dir is not inside root.
+func RelDir(root, dir string) string {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "."
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,225 @@
+// Package worker implements distributed builds. A worker runs gox build
+// for remote coordinators: the coordinator ships the build's source tree as
+// a tar.gz, the worker builds it in a scratch directory and streams back
+// the build output and every file the build wrote.
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/qntx/gox/internal/workspace"
+)
+
+// DefaultPort is the port workers listen on and coordinators dial when an
+// address has none.
+const DefaultPort = "7078"
+
+// TokenEnv holds the shared secret coordinators present to workers.
+const TokenEnv = "GOX_WORKER_TOKEN"
+
+// Job asks a worker to run `gox build Args` in Dir, a slash-separated path
+// inside the shipped source tree.
+type Job struct {
+	Dir  string   `json:"dir"`
+	Args []string `json:"args"`
+}
+
+// Event is one line of a streamed job response. Output events carry build
+// output; the final event has Done set and, on success, the files the
+// build wrote as a tar.gz.
+type Event struct {
+	Output    string `json:"output,omitempty"`
+	Done      bool   `json:"done,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Artifacts []byte `json:"artifacts,omitempty"`
+}
+
+// Server runs jobs, a bounded number at a time.
+type Server struct {
+	exe   string // gox binary used to run builds
+	token string
+	slot  chan struct{}
+}
+
+// NewServer returns a server running up to jobs builds at once with the
+// gox binary at exe. A non-empty token must be presented as a bearer token.
+func NewServer(exe, token string, jobs int) *Server {
+	return &Server{exe: exe, token: token, slot: make(chan struct{}, max(1, jobs))}
+}
+
+// Handler returns the HTTP API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/jobs", s.handleJob)
+	mux.HandleFunc("GET /v1/health", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// ListenAndServe serves the API on addr until ctx is canceled.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	}()
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// handleJob reads a multipart body of a "job" part holding the Job and a
+// "source" part holding the tar.gz source tree.
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	// Browsers send multipart forms cross-origin without a preflight; keep
+	// web pages from running builds on a worker without a token.
+	if r.Header.Get("Origin") != "" {
+		http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+		return
+	}
+	if !s.authorized(r) {
+		http.Error(w, "invalid or missing worker token", http.StatusUnauthorized)
+		return
+	}
+	mr, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dir, err := workspace.MkdirTemp("worker-*")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	job, err := readJob(mr, dir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	out := &eventWriter{enc: json.NewEncoder(w), flusher: w.(http.Flusher)}
+
+	select {
+	case s.slot <- struct{}{}:
+		defer func() { <-s.slot }()
+	case <-r.Context().Done():
+		return
+	}
+
+	arts, err := s.run(r.Context(), dir, job, out)
+	done := Event{Done: true, Artifacts: arts}
+	if err != nil {
+		done.Error = err.Error()
+	}
+	out.send(done)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+	got := r.Header.Get("Authorization")
+	return subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+s.token)) == 1
+}
+
+// readJob decodes the job part and unpacks the source part into dir.
+func readJob(mr *multipart.Reader, dir string) (Job, error) {
+	var (
+		job       Job
+		hasJob    bool
+		hasSource bool
+	)
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return job, err
+		}
+		switch p.FormName() {
+		case "job":
+			if err := json.NewDecoder(p).Decode(&job); err != nil {
+				return job, fmt.Errorf("job: %w", err)
+			}
+			hasJob = true
+		case "source":
+			if _, err := Unpack(p, dir); err != nil {
+				return job, fmt.Errorf("source: %w", err)
+			}
+			hasSource = true
+		}
+	}
+	if !hasJob || !hasSource {
+		return job, errors.New("job and source parts are required")
+	}
+	if _, err := within(dir, job.Dir); err != nil {
+		return job, fmt.Errorf("dir: %w", err)
+	}
+	return job, nil
+}
+
+// run builds job in dir and returns the files the build wrote, packed.
+func (s *Server) run(ctx context.Context, dir string, job Job, out io.Writer) ([]byte, error) {
+	before, err := snapshot(dir)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, s.exe, append([]string{"build"}, job.Args...)...)
+	cmd.Dir = filepath.Join(dir, filepath.FromSlash(job.Dir))
+	cmd.Env = os.Environ()
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	files, err := changed(dir, before)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := Pack(&buf, dir, files); err != nil {
+		return nil, fmt.Errorf("artifacts: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// eventWriter streams written bytes as output events.
+type eventWriter struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	flusher http.Flusher
+}
+
+func (e *eventWriter) Write(p []byte) (int, error) {
+	e.send(Event{Output: string(p)})
+	return len(p), nil
+}
+
+func (e *eventWriter) send(ev Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_ = e.enc.Encode(ev)
+	e.flusher.Flush()
+}
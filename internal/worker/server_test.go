@@ -0,0 +1,139 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeGox writes a script that echoes its arguments and writes dist/app
+// in its working directory, exiting with exit.
+func fakeGox(t *testing.T, exit string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script fake requires unix")
+	}
+	path := filepath.Join(t.TempDir(), "gox")
+	script := "#!/bin/sh\necho \"$@\"\nmkdir -p dist && echo built > dist/app\nexit " + exit + "\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testSource packs a small module into a tar.gz file.
+func testSource(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "go.mod"), "module x\n", 0o644)
+	writeTestFile(t, filepath.Join(root, "cmd", "x", "main.go"), "package main\n", 0o644)
+	var buf bytes.Buffer
+	if err := Pack(&buf, root, []string{"go.mod", "cmd/x/main.go"}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "source.tar.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestClient_Run(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	srv := httptest.NewServer(NewServer(fakeGox(t, "0"), "secret", 1).Handler())
+	defer srv.Close()
+
+	dst := t.TempDir()
+	var out bytes.Buffer
+	job := Job{Dir: "cmd/x", Args: []string{"--target", "linux-arm64"}}
+	paths, err := NewClient(srv.URL, "secret").Run(context.Background(), job, testSource(t), &out, dst)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "build --target linux-arm64") {
+		t.Errorf("output = %q, want build args", out.String())
+	}
+	want := filepath.Join(dst, "cmd", "x", "dist", "app")
+	if len(paths) != 1 || paths[0] != want {
+		t.Errorf("Run() = %q, want only %s", paths, want)
+	}
+}
+
+func TestClient_RunFailures(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	tests := []struct {
+		name    string
+		exit    string
+		token   string
+		job     Job
+		wantErr string
+	}{
+		{"build fails", "1", "secret", Job{Dir: "."}, "exit status 1"},
+		{"bad token", "0", "wrong", Job{Dir: "."}, "401"},
+		{"dir outside source", "0", "secret", Job{Dir: "../.."}, "unsafe path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(NewServer(fakeGox(t, tt.exit), "secret", 1).Handler())
+			defer srv.Close()
+			_, err := NewClient(srv.URL, tt.token).Run(context.Background(), tt.job, testSource(t), &bytes.Buffer{}, t.TempDir())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrUnreachable) {
+				t.Errorf("Run() error = %v, should not be unreachable", err)
+			}
+		})
+	}
+}
+
+func TestServer_RejectsCrossOrigin(t *testing.T) {
+	srv := httptest.NewServer(NewServer("gox", "", 1).Handler())
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/v1/jobs", strings.NewReader("--x--\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	req.Header.Set("Origin", "https://evil.example")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+func TestClient_Unreachable(t *testing.T) {
+	_, err := NewClient("127.0.0.1:1", "").Run(context.Background(), Job{Dir: "."}, testSource(t), &bytes.Buffer{}, t.TempDir())
+	if !errors.Is(err, ErrUnreachable) {
+		t.Errorf("Run() error = %v, want ErrUnreachable", err)
+	}
+}
+
+func TestClient_URL(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"build1", "http://build1:" + DefaultPort + "/v1/health"},
+		{"build1:9000", "http://build1:9000/v1/health"},
+		{"[::1]", "http://[::1]:" + DefaultPort + "/v1/health"},
+		{"https://farm.example/", "https://farm.example/v1/health"},
+	}
+	for _, tt := range tests {
+		if got := NewClient(tt.addr, "").url("/v1/health"); got != tt.want {
+			t.Errorf("url(%q) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package worker
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// skipDirs are never shipped in either direction: VCS metadata and a
+// project-scoped gox cache.
+var skipDirs = []string{".git", ".gox"}
+
+// Pack writes files, given relative to root, as a gzipped tar to w. Regular
+// files and symlinks are kept; files that no longer exist are skipped.
+func Pack(w io.Writer, root string, files []string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, name := range files {
+		if err := addFile(tw, root, name); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addFile(tw *tar.Writer, root, name string) error {
+	path := filepath.Join(root, filepath.FromSlash(name))
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var link string
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		if link, err = os.Readlink(path); err != nil {
+			return err
+		}
+	case !info.Mode().IsRegular():
+		return nil
+	}
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = filepath.ToSlash(name)
+	hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if link != "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Unpack extracts a gzipped tar from r into dst and returns the extracted
+// file paths. Entries escaping dst, by name or symlink target, are rejected.
+func Unpack(r io.Reader, dst string) ([]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	var paths []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return paths, nil
+		}
+		if err != nil {
+			return paths, err
+		}
+		path, err := within(dst, hdr.Name)
+		if err != nil {
+			return paths, err
+		}
+		if err := noLinkedParent(dst, path); err != nil {
+			return paths, fmt.Errorf("%s: %w", hdr.Name, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return paths, err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			if err := writeFile(path, tr, hdr.FileInfo().Mode().Perm(), hdr.ModTime); err != nil {
+				return paths, err
+			}
+		case tar.TypeSymlink:
+			target := filepath.FromSlash(hdr.Linkname)
+			if filepath.IsAbs(target) {
+				return paths, fmt.Errorf("%s: absolute symlink %s", hdr.Name, hdr.Linkname)
+			}
+			if _, err := within(dst, filepath.ToSlash(filepath.Join(filepath.Dir(filepath.FromSlash(hdr.Name)), target))); err != nil {
+				return paths, err
+			}
+			_ = os.Remove(path)
+			if err := os.Symlink(target, path); err != nil {
+				return paths, err
+			}
+		default:
+			continue
+		}
+		paths = append(paths, path)
+	}
+}
+
+// noLinkedParent fails when a directory between dst and path is a symlink,
+// such as one extracted earlier: each link's target is only checked from
+// where the archive names it, so a chain of them could reach out of dst.
+func noLinkedParent(dst, path string) error {
+	rel, err := filepath.Rel(dst, filepath.Dir(path))
+	if err != nil || rel == "." {
+		return err
+	}
+	dir := dst
+	for elem := range strings.SplitSeq(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, elem)
+		info, err := os.Lstat(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("unsafe path through symlink %s", dir)
+		}
+	}
+	return nil
+}
+
+// within returns name joined to dst, failing when it leaves dst.
+func within(dst, name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("unsafe path %q", name)
+	}
+	return filepath.Join(dst, clean), nil
+}
+
+func writeFile(path string, r io.Reader, perm fs.FileMode, mtime time.Time) error {
+	// Replace rather than truncate so a running binary can be overwritten.
+	_ = os.Remove(path)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(path, mtime, mtime)
+}
+
+// stamp identifies a file's content cheaply.
+type stamp struct {
+	size  int64
+	mtime time.Time
+}
+
+// snapshot records the files under root.
+func snapshot(root string) (map[string]stamp, error) {
+	files := map[string]stamp{}
+	err := walk(root, func(rel string, info fs.FileInfo) {
+		files[rel] = stamp{info.Size(), info.ModTime()}
+	})
+	return files, err
+}
+
+// changed returns the files under root that are new or modified since
+// before, relative to root with forward slashes.
+func changed(root string, before map[string]stamp) ([]string, error) {
+	var out []string
+	err := walk(root, func(rel string, info fs.FileInfo) {
+		if s, ok := before[rel]; !ok || s != (stamp{info.Size(), info.ModTime()}) {
+			out = append(out, rel)
+		}
+	})
+	slices.Sort(out)
+	return out, err
+}
+
+func walk(root string, fn func(rel string, info fs.FileInfo)) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && slices.Contains(skipDirs, d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		fn(filepath.ToSlash(rel), info)
+		return nil
+	})
+}
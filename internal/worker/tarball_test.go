@@ -0,0 +1,130 @@
+package worker
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPackUnpack(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "main.go"), "package main\n", 0o644)
+	writeTestFile(t, filepath.Join(src, "bin", "run.sh"), "#!/bin/sh\n", 0o755)
+	if err := os.Symlink("main.go", filepath.Join(src, "link.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Pack(&buf, src, []string{"main.go", "bin/run.sh", "link.go", "deleted.go"}); err != nil {
+		t.Fatal(err)
+	}
+	dst := t.TempDir()
+	paths, err := Unpack(&buf, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 3 {
+		t.Errorf("Unpack() = %q, want 3 paths", paths)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "link.go")); string(data) != "package main\n" {
+		t.Errorf("link.go = %q, want the linked file", data)
+	}
+	info, err := os.Stat(filepath.Join(dst, "bin", "run.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0o100 == 0 {
+		t.Errorf("run.sh mode = %v, want executable", info.Mode())
+	}
+}
+
+func TestUnpack_Unsafe(t *testing.T) {
+	tests := []struct {
+		name string
+		hdrs []tar.Header
+	}{
+		{"parent", []tar.Header{{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0o644}}},
+		{"absolute", []tar.Header{{Name: "/etc/evil", Typeflag: tar.TypeReg, Mode: 0o644}}},
+		{"symlink out", []tar.Header{{Name: "a/link", Typeflag: tar.TypeSymlink, Linkname: "../../etc"}}},
+		{"absolute symlink", []tar.Header{{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc"}}},
+		{"symlink chain", []tar.Header{
+			{Name: "x/y", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "x/y/z", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "x/y/z/evil", Typeflag: tar.TypeReg, Mode: 0o644},
+		}},
+		{"file through symlink", []tar.Header{
+			{Name: "x/y", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "x/y/evil", Typeflag: tar.TypeReg, Mode: 0o644},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			tw := tar.NewWriter(gz)
+			for _, hdr := range tt.hdrs {
+				if err := tw.WriteHeader(&hdr); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tw.Close()
+			gz.Close()
+			parent := t.TempDir()
+			dst := filepath.Join(parent, "a", "b")
+			if err := os.MkdirAll(dst, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Unpack(&buf, dst); err == nil {
+				t.Error("Unpack() succeeded, want unsafe path error")
+			}
+			for _, dir := range []string{parent, filepath.Join(parent, "a")} {
+				if _, err := os.Lstat(filepath.Join(dir, "evil")); err == nil {
+					t.Errorf("Unpack() wrote %s outside dst", filepath.Join(dir, "evil"))
+				}
+			}
+		})
+	}
+}
+
+func TestChanged(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	writeTestFile(t, filepath.Join(root, "keep.go"), "a", 0o644)
+	writeTestFile(t, filepath.Join(root, "edit.go"), "a", 0o644)
+	for _, f := range []string{"keep.go", "edit.go"} {
+		if err := os.Chtimes(filepath.Join(root, f), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, err := snapshot(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, filepath.Join(root, "edit.go"), "ab", 0o644)
+	writeTestFile(t, filepath.Join(root, "dist", "app"), "bin", 0o755)
+	writeTestFile(t, filepath.Join(root, ".gox", "pkg", "x"), "cache", 0o644)
+
+	got, err := changed(root, before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"dist/app", "edit.go"}; !slices.Equal(got, want) {
+		t.Errorf("changed() = %q, want %q", got, want)
+	}
+}
+
+func writeTestFile(t *testing.T, path, data string, perm os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), perm); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package workspace
+
+import "os"
+
+// Alive reports whether a process with pid exists. On Windows FindProcess
+// fails once the process has exited.
+func Alive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
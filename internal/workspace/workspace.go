@@ -0,0 +1,144 @@
+// Package workspace provides a per-invocation temp directory holding every
+// temporary file gox creates (downloads, run binaries, scripts), so a run
+// cleans up with a single RemoveAll.
+package workspace
+
+import (
+	"cmp"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const prefix = "run-"
+
+var (
+	mu  sync.Mutex
+	dir string
+)
+
+// Root returns the directory holding the workspaces of all runs.
+func Root() string {
+	return filepath.Join(os.TempDir(), "gox-tmp")
+}
+
+// Dir returns the workspace of this run, creating it on first use. Its name
+// embeds the process ID so leftovers of crashed runs can be detected.
+func Dir() (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if dir != "" {
+		return dir, nil
+	}
+	if err := os.MkdirAll(Root(), 0o755); err != nil {
+		return "", err
+	}
+	d, err := os.MkdirTemp(Root(), prefix+strconv.Itoa(os.Getpid())+"-*")
+	if err != nil {
+		return "", err
+	}
+	dir = d
+	return dir, nil
+}
+
+// MkdirTemp creates a new directory inside the run workspace.
+func MkdirTemp(pattern string) (string, error) {
+	d, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(d, pattern)
+}
+
+// Cleanup removes the run workspace. It is safe to call more than once.
+func Cleanup() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if dir == "" {
+		return nil
+	}
+	err := os.RemoveAll(dir)
+	dir = ""
+	return err
+}
+
+// Leftover is a workspace whose run is no longer alive.
+type Leftover struct {
+	Path    string
+	PID     int
+	Size    int64
+	ModTime time.Time
+}
+
+// Leftovers lists workspaces left behind by runs that have exited, oldest
+// first.
+func Leftovers() ([]Leftover, error) {
+	entries, err := os.ReadDir(Root())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Leftover
+	for _, e := range entries {
+		pid, ok := parsePID(e.Name())
+		if !e.IsDir() || !ok || Alive(pid) {
+			continue
+		}
+		path := filepath.Join(Root(), e.Name())
+		l := Leftover{Path: path, PID: pid, Size: dirSize(path)}
+		if info, err := e.Info(); err == nil {
+			l.ModTime = info.ModTime()
+		}
+		out = append(out, l)
+	}
+	slices.SortFunc(out, func(a, b Leftover) int {
+		return cmp.Compare(a.ModTime.UnixNano(), b.ModTime.UnixNano())
+	})
+	return out, nil
+}
+
+// Clean removes every leftover workspace and returns what was removed.
+func Clean() ([]Leftover, error) {
+	leftovers, err := Leftovers()
+	if err != nil {
+		return nil, err
+	}
+	for i, l := range leftovers {
+		if err := os.RemoveAll(l.Path); err != nil {
+			return leftovers[:i], err
+		}
+	}
+	return leftovers, nil
+}
+
+// parsePID extracts the process ID from a workspace name (run-<pid>-<rand>).
+func parsePID(name string) (int, bool) {
+	rest, ok := strings.CutPrefix(name, prefix)
+	if !ok {
+		return 0, false
+	}
+	s, _, _ := strings.Cut(rest, "-")
+	pid, err := strconv.Atoi(s)
+	return pid, err == nil && pid > 0
+}
+
+func dirSize(root string) int64 {
+	var size int64
+	_ = filepath.WalkDir(root, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,108 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func useTempRoot(t *testing.T) {
+	t.Helper()
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	t.Setenv("TMP", tmp)
+	t.Setenv("TEMP", tmp)
+	t.Cleanup(func() { _ = Cleanup() })
+}
+
+func TestDir(t *testing.T) {
+	useTempRoot(t)
+
+	d, err := Dir()
+	if err != nil {
+		t.Fatalf("Dir() error = %v", err)
+	}
+	if filepath.Dir(d) != Root() {
+		t.Errorf("Dir() = %q, want under %q", d, Root())
+	}
+	if again, _ := Dir(); again != d {
+		t.Errorf("Dir() = %q on second call, want %q", again, d)
+	}
+	if pid, ok := parsePID(filepath.Base(d)); !ok || pid != os.Getpid() {
+		t.Errorf("parsePID(%q) = %d, %v, want %d", filepath.Base(d), pid, ok, os.Getpid())
+	}
+
+	sub, err := MkdirTemp("download-*")
+	if err != nil {
+		t.Fatalf("MkdirTemp() error = %v", err)
+	}
+	if !strings.HasPrefix(sub, d) {
+		t.Errorf("MkdirTemp() = %q, want inside %q", sub, d)
+	}
+
+	if err := Cleanup(); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if _, err := os.Stat(d); !os.IsNotExist(err) {
+		t.Errorf("Cleanup() left %s", d)
+	}
+	if err := Cleanup(); err != nil {
+		t.Errorf("second Cleanup() error = %v", err)
+	}
+}
+
+func TestClean(t *testing.T) {
+	useTempRoot(t)
+
+	live, err := Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// PIDs this large are never assigned, so the run is treated as exited.
+	dead := filepath.Join(Root(), "run-2147483646-123")
+	if err := os.MkdirAll(dead, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dead, "archive.tar.gz"), []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(Root(), "unrelated")
+	if err := os.MkdirAll(other, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := Clean()
+	if err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+	if len(removed) != 1 || removed[0].Path != dead || removed[0].Size != 4 {
+		t.Fatalf("Clean() = %+v, want only %s (4 bytes)", removed, dead)
+	}
+	for _, p := range []string{live, other} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("Clean() removed %s", p)
+		}
+	}
+}
+
+func TestParsePID(t *testing.T) {
+	tests := []struct {
+		name string
+		want int
+		ok   bool
+	}{
+		{"run-42-abc", 42, true},
+		{"run-x-abc", 0, false},
+		{"run-0-abc", 0, false},
+		{"other-42-abc", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parsePID(tt.name)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parsePID(%q) = %d, %v, want %d, %v", tt.name, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
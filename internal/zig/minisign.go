@@ -0,0 +1,162 @@
+package zig
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/qntx/gox/internal/httpclient"
+)
+
+// PublicKey is the minisign key ziglang.org signs release and dev tarballs
+// with, as published on https://ziglang.org/download/.
+const PublicKey = "RWSGOq2NVecA2UPNdBUZykf1CCb147pkmdtYxgb3Ti+JO/wCYvhbAb/U"
+
+// VerifySignature checks downloaded tarballs against their .minisig
+// signature and PublicKey. Enabled by zig-minisign in gox.toml.
+var VerifySignature bool
+
+// ErrBadSignature is returned when a tarball fails minisign verification.
+var ErrBadSignature = errors.New("minisign verification failed")
+
+type minisignKey struct {
+	id  [8]byte
+	key ed25519.PublicKey
+}
+
+type minisignSig struct {
+	prehashed bool
+	id        [8]byte
+	sig       []byte
+	comment   string // trusted comment
+	global    []byte // signature over sig and comment
+}
+
+func parseMinisignKey(s string) (*minisignKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != "Ed" {
+		return nil, errors.New("invalid minisign public key")
+	}
+	k := &minisignKey{key: ed25519.PublicKey(raw[10:])}
+	copy(k.id[:], raw[2:10])
+	return k, nil
+}
+
+func parseMinisignSig(data []byte) (*minisignSig, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return nil, errors.New("invalid minisign signature file")
+	}
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(raw) != 2+8+ed25519.SignatureSize {
+		return nil, errors.New("invalid minisign signature")
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return nil, errors.New("invalid minisign global signature")
+	}
+
+	s := &minisignSig{
+		sig:     raw[10:],
+		comment: strings.TrimPrefix(lines[2], "trusted comment: "),
+		global:  global,
+	}
+	switch string(raw[:2]) {
+	case "Ed":
+	case "ED":
+		s.prehashed = true
+	default:
+		return nil, fmt.Errorf("unsupported minisign algorithm %q", raw[:2])
+	}
+	copy(s.id[:], raw[2:10])
+	return s, nil
+}
+
+// verify checks that s signs the contents of r with k. When the trusted
+// comment names a file, it must be name, so a validly signed but different
+// tarball cannot be substituted.
+func (k *minisignKey) verify(s *minisignSig, r io.Reader, name string) error {
+	if s.id != k.id {
+		return fmt.Errorf("%w: signed by key %X, want %X", ErrBadSignature, s.id, k.id)
+	}
+
+	var msg []byte
+	if s.prehashed {
+		h, _ := blake2b.New512(nil)
+		if _, err := io.Copy(h, r); err != nil {
+			return err
+		}
+		msg = h.Sum(nil)
+	} else {
+		var err error
+		if msg, err = io.ReadAll(r); err != nil {
+			return err
+		}
+	}
+	if !ed25519.Verify(k.key, msg, s.sig) {
+		return fmt.Errorf("%w: bad signature", ErrBadSignature)
+	}
+	if !ed25519.Verify(k.key, append(bytes.Clone(s.sig), s.comment...), s.global) {
+		return fmt.Errorf("%w: bad trusted comment", ErrBadSignature)
+	}
+
+	for _, f := range strings.Fields(s.comment) {
+		if file, ok := strings.CutPrefix(f, "file:"); ok && file != name {
+			return fmt.Errorf("%w: signature is for %s, not %s", ErrBadSignature, file, name)
+		}
+	}
+	return nil
+}
+
+// signatureCheck fetches the .minisig of tarball and returns a check for the
+// downloaded archive file.
+func signatureCheck(ctx context.Context, tarball string) (func(string) error, error) {
+	key, err := parseMinisignKey(PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	err = httpclient.Retry(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, tarball+".minisig", nil)
+		if err != nil {
+			return err
+		}
+		resp, err := httpclient.Client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s.minisig: %w", path.Base(tarball), httpclient.NewStatusError(resp))
+		}
+		data, err = io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sig, err := parseMinisignSig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(file string) error {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return key.verify(sig, f, path.Base(tarball))
+	}, nil
+}
@@ -0,0 +1,92 @@
+package zig
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// signMinisign produces a prehashed minisign signature file for data.
+func signMinisign(t *testing.T, priv ed25519.PrivateKey, id [8]byte, data []byte, comment string) []byte {
+	t.Helper()
+	h := blake2b.Sum512(data)
+	sig := ed25519.Sign(priv, h[:])
+	raw := append(append([]byte("ED"), id[:]...), sig...)
+	global := ed25519.Sign(priv, append(sig, comment...))
+	return fmt.Appendf(nil, "untrusted comment: test\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(raw), comment, base64.StdEncoding.EncodeToString(global))
+}
+
+func TestParseMinisignKey(t *testing.T) {
+	k, err := parseMinisignKey(PublicKey)
+	if err != nil {
+		t.Fatalf("parseMinisignKey(PublicKey) error = %v", err)
+	}
+	if len(k.key) != ed25519.PublicKeySize {
+		t.Errorf("key size = %d, want %d", len(k.key), ed25519.PublicKeySize)
+	}
+	if _, err := parseMinisignKey("not-a-key"); err == nil {
+		t.Error("parseMinisignKey(invalid) should fail")
+	}
+}
+
+func TestMinisignVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+	key := &minisignKey{id: id, key: pub}
+	data := []byte("zig tarball")
+	const name = "zig-x86_64-linux-0.15.1.tar.xz"
+	comment := "timestamp:1 file:" + name + " hashed"
+
+	tests := []struct {
+		name    string
+		sig     []byte
+		data    []byte
+		file    string
+		wantErr bool
+	}{
+		{"valid", signMinisign(t, priv, id, data, comment), data, name, false},
+		{"tampered data", signMinisign(t, priv, id, data, comment), []byte("evil"), name, true},
+		{"other file", signMinisign(t, priv, id, data, comment), data, "zig-x86_64-linux-0.14.0.tar.xz", true},
+		{"other key id", signMinisign(t, priv, [8]byte{9}, data, comment), data, name, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sig, err := parseMinisignSig(tt.sig)
+			if err != nil {
+				t.Fatalf("parseMinisignSig() error = %v", err)
+			}
+			err = key.verify(sig, strings.NewReader(string(tt.data)), tt.file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrBadSignature) {
+				t.Errorf("verify() error = %v, want ErrBadSignature", err)
+			}
+		})
+	}
+
+	t.Run("tampered comment", func(t *testing.T) {
+		sig, _ := parseMinisignSig(signMinisign(t, priv, id, data, comment))
+		sig.comment = "timestamp:2 file:" + name
+		if err := key.verify(sig, strings.NewReader(string(data)), name); !errors.Is(err, ErrBadSignature) {
+			t.Errorf("verify() error = %v, want ErrBadSignature", err)
+		}
+	})
+}
+
+func TestParseMinisignSig_Invalid(t *testing.T) {
+	for _, in := range []string{"", "untrusted comment: x\nAAAA\n", "a\nb\nc\nd\n"} {
+		if _, err := parseMinisignSig([]byte(in)); err == nil {
+			t.Errorf("parseMinisignSig(%q) should fail", in)
+		}
+	}
+}
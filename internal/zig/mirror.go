@@ -0,0 +1,31 @@
+package zig
+
+import (
+	"cmp"
+	"os"
+	"path"
+	"strings"
+)
+
+// MirrorEnv names the environment variable that overrides Mirror.
+const MirrorEnv = "GOX_ZIG_MIRROR"
+
+// Mirror is a base URL serving zig tarballs (and index.json) under their
+// upstream file names, used instead of ziglang.org. Set from zig-mirror in
+// gox.toml.
+var Mirror string
+
+// mirrorBase returns the active mirror without a trailing slash, or "".
+func mirrorBase() string {
+	return strings.TrimSuffix(cmp.Or(os.Getenv(MirrorEnv), Mirror), "/")
+}
+
+// mirrorURL rewrites an upstream zig download URL to <mirror>/<file>.
+// Locked and index URLs stay upstream so gox.lock is portable.
+func mirrorURL(u string) string {
+	base := mirrorBase()
+	if base == "" {
+		return u
+	}
+	return base + "/" + path.Base(u)
+}
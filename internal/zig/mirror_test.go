@@ -0,0 +1,50 @@
+package zig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMirrorURL(t *testing.T) {
+	const up = "https://ziglang.org/download/0.14.1/zig-x86_64-linux-0.14.1.tar.xz"
+	tests := []struct {
+		name, mirror, env, want string
+	}{
+		{"unset", "", "", up},
+		{"config", "https://m.example/zig", "", "https://m.example/zig/zig-x86_64-linux-0.14.1.tar.xz"},
+		{"trailing slash", "https://m.example/zig/", "", "https://m.example/zig/zig-x86_64-linux-0.14.1.tar.xz"},
+		{"env wins", "https://m.example/zig", "https://env.example", "https://env.example/zig-x86_64-linux-0.14.1.tar.xz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := Mirror
+			Mirror = tt.mirror
+			t.Cleanup(func() { Mirror = old })
+			t.Setenv(MirrorEnv, tt.env)
+			if got := mirrorURL(up); got != tt.want {
+				t.Errorf("mirrorURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchIndex_Mirror(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/zig/index.json" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"0.14.1":{"date":"2025-05-21"}}`))
+	}))
+	defer srv.Close()
+
+	t.Setenv(MirrorEnv, srv.URL+"/zig")
+	idx, err := fetchIndex(t.Context())
+	if err != nil {
+		t.Fatalf("fetchIndex() error = %v", err)
+	}
+	if _, ok := idx["0.14.1"]; !ok {
+		t.Errorf("fetchIndex() = %v, want mirror index", idx)
+	}
+}
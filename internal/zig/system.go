@@ -0,0 +1,72 @@
+package zig
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/qntx/gox/internal/ui"
+)
+
+// System is an existing Zig binary, or a directory holding one, used
+// instead of a downloaded toolchain. Set from --zig-path or zig-path.
+var System string
+
+var versionRE = regexp.MustCompile(`^\d+\.\d+\.\d+(-[0-9A-Za-z.+-]+)?$`)
+
+// useSystem resolves System and checks that it runs and reports a version.
+// A different version than the one requested is only warned about, since
+// the point of a system Zig is to build with what is installed.
+func useSystem(ctx context.Context, version string) (string, error) {
+	bin, err := systemBinary(System)
+	if err != nil {
+		return "", fmt.Errorf("zig-path %s: %w", System, err)
+	}
+	out, err := exec.CommandContext(ctx, bin, "version").Output()
+	if err != nil {
+		return "", fmt.Errorf("zig-path %s: zig version: %w", bin, err)
+	}
+	got := strings.TrimSpace(string(out))
+	if !versionRE.MatchString(got) {
+		return "", fmt.Errorf("zig-path %s: unexpected zig version output %q", bin, got)
+	}
+	if version != defaultVersion && version != got {
+		ui.Warn("zig-path %s is %s, config asks for %s", bin, got, version)
+	}
+	return filepath.Dir(bin), nil
+}
+
+// systemBinary returns the absolute path of the zig binary named by path: a
+// file, a directory containing zig, or a bare name looked up in PATH.
+func systemBinary(path string) (string, error) {
+	name := "zig"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	if !strings.ContainsAny(path, `/\`) {
+		p, err := exec.LookPath(path)
+		if err != nil {
+			return "", err
+		}
+		path = p
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if fi.IsDir() {
+		path = filepath.Join(path, name)
+	} else if filepath.Base(path) != name {
+		// Builder invokes <dir>/zig, so the binary must keep its name.
+		return "", fmt.Errorf("binary must be named %s", name)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return filepath.Abs(path)
+}
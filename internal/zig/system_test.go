@@ -0,0 +1,85 @@
+package zig
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakeZig writes a zig script into dir that prints version.
+func fakeZig(t *testing.T, dir, version string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script zig")
+	}
+	bin := filepath.Join(dir, "zig")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\necho "+version+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return bin
+}
+
+func TestSystemBinary(t *testing.T) {
+	dir := t.TempDir()
+	bin := fakeZig(t, dir, "0.15.2")
+	other := filepath.Join(dir, "zig-0.15")
+	if err := os.WriteFile(other, nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"binary", bin, bin, false},
+		{"directory", dir, bin, false},
+		{"in PATH", "zig", bin, false},
+		{"renamed binary", other, "", true},
+		{"missing", filepath.Join(dir, "nope", "zig"), "", true},
+		{"empty directory", t.TempDir(), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := systemBinary(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("systemBinary() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("systemBinary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsure_System(t *testing.T) {
+	dir := t.TempDir()
+	t.Cleanup(func() { System = "" })
+
+	tests := []struct {
+		name    string
+		output  string
+		version string
+		wantErr bool
+	}{
+		{"release", "0.15.2", "0.15.2", false},
+		{"dev build", "0.16.0-dev.1234+abcdef012", "", false},
+		{"version mismatch warns", "0.14.1", "0.15.2", false},
+		{"not zig", "hello", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			System = fakeZig(t, dir, tt.output)
+			got, err := Ensure(t.Context(), tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Ensure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != dir {
+				t.Errorf("Ensure() = %q, want %q", got, dir)
+			}
+		})
+	}
+}
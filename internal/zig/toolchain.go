@@ -1,6 +1,7 @@
 package zig
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
@@ -9,8 +10,16 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/qntx/gox/internal/archive"
+	"github.com/qntx/gox/internal/cache"
+	"github.com/qntx/gox/internal/httpclient"
+	"github.com/qntx/gox/internal/lock"
+	"github.com/qntx/gox/internal/telemetry"
 	"github.com/qntx/gox/internal/ui"
 )
 
@@ -36,6 +45,11 @@ const (
 	defaultVersion = "master"
 )
 
+// Verify checks downloaded tarballs against the shasum published in
+// index.json or pinned in gox.lock, and against their signature when
+// VerifySignature is set. Disabled by --no-verify.
+var Verify = true
+
 var (
 	archMap = map[string]string{
 		"386":   "x86",
@@ -57,9 +71,21 @@ var (
 )
 
 // Ensure downloads and caches a Zig version. Returns installation path.
-func Ensure(ctx context.Context, version string) (string, error) {
-	if version == "" {
-		version = defaultVersion
+// An empty version falls back to the project's .zig-version file, then to
+// master.
+//
+// With an active lockfile, master resolves to the exact dev snapshot pinned
+// there, and the first resolution on a machine is recorded. With System set,
+// nothing is downloaded and the system Zig's directory is returned.
+func Ensure(ctx context.Context, version string) (_ string, err error) {
+	version = ResolveVersion(version)
+	ctx, span := telemetry.Start(ctx, "zig.ensure", attribute.String("gox.zig.version", version))
+	defer func() { telemetry.End(span, err) }()
+	if System != "" {
+		return useSystem(ctx, version)
+	}
+	if l := lock.Active(); l != nil && version == defaultVersion {
+		return ensurePinned(ctx, l, version)
 	}
 
 	dir := Path(version)
@@ -77,27 +103,210 @@ func Ensure(ctx context.Context, version string) (string, error) {
 		return "", fmt.Errorf("version %q not found", version)
 	}
 
-	platform := hostPlatform()
+	platform := HostPlatform()
 	build, ok := rel.Builds[platform]
 	if !ok {
 		return "", fmt.Errorf("no build for %s", platform)
 	}
 
-	size, _ := archive.ContentLength(ctx, build.Tarball)
+	if _, err := install(ctx, version, build.Tarball, build.Shasum, dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// Locate returns the directory Ensure would return for version without
+// downloading or running anything. Nothing is checked to exist there.
+func Locate(version string) string {
+	version = ResolveVersion(version)
+	if System != "" {
+		if bin, err := systemBinary(System); err == nil {
+			return filepath.Dir(bin)
+		}
+		return System
+	}
+	if l := lock.Active(); l != nil && version == defaultVersion {
+		if pin, ok := l.ZigFor(version, HostPlatform()); ok {
+			return Path(pin.Resolved)
+		}
+	}
+	return Path(version)
+}
+
+// Unpin drops the locked snapshots of version so the next Ensure resolves
+// it afresh. It is a no-op without an active lockfile.
+func Unpin(version string) error {
+	if l := lock.Active(); l != nil {
+		return l.RemoveZig(version)
+	}
+	return nil
+}
+
+// Latest returns the newest tagged release listed in index.json.
+func Latest(ctx context.Context) (string, error) {
+	idx, err := fetchIndex(ctx)
+	if err != nil {
+		return "", err
+	}
+	var latest string
+	for v := range idx {
+		if IsRelease(v) && (latest == "" || CompareVersions(v, latest) > 0) {
+			latest = v
+		}
+	}
+	if latest == "" {
+		return "", errors.New("no releases in index.json")
+	}
+	return latest, nil
+}
+
+// IsRelease reports whether version names a tagged release such as 0.14.1,
+// rather than master or a dev snapshot.
+func IsRelease(version string) bool {
+	if version == "" {
+		return false
+	}
+	for part := range strings.SplitSeq(version, ".") {
+		if _, err := strconv.Atoi(part); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// CompareVersions orders release versions numerically, part by part.
+func CompareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range max(len(as), len(bs)) {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if c := cmp.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// ensurePinned installs the snapshot of version recorded in l for this host,
+// resolving and recording one first if needed.
+func ensurePinned(ctx context.Context, l *lock.Lock, version string) (string, error) {
+	platform := HostPlatform()
+	pin, ok := l.ZigFor(version, platform)
+	if !ok {
+		var err error
+		if pin, err = resolvePin(ctx, l, version, platform); err != nil {
+			return "", err
+		}
+	}
+
+	dir := Path(pin.Resolved)
+	if !isInstalled(dir) {
+		sum, err := install(ctx, pin.Resolved, pin.Tarball, pin.Shasum, dir)
+		if err != nil {
+			if errors.Is(err, archive.ErrChecksumMismatch) {
+				return "", fmt.Errorf("%s: %w", lock.File, err)
+			}
+			return "", err
+		}
+		pin.Shasum = cmp.Or(pin.Shasum, sum)
+	}
+	if ok {
+		return dir, nil
+	}
+	return dir, l.SetZig(pin)
+}
+
+// resolvePin picks the snapshot of version for platform. When another host
+// platform is already pinned, the same snapshot is used so every machine
+// builds with one compiler; otherwise the current index entry is taken.
+func resolvePin(ctx context.Context, l *lock.Lock, version, platform string) (lock.Zig, error) {
+	if other, ok := l.ZigFor(version, ""); ok {
+		url, err := snapshotURL(other, platform)
+		if err != nil {
+			return lock.Zig{}, err
+		}
+		return lock.Zig{Version: version, Resolved: other.Resolved, Platform: platform, Tarball: url}, nil
+	}
+
+	idx, err := fetchIndex(ctx)
+	if err != nil {
+		return lock.Zig{}, err
+	}
+	rel, ok := idx[version]
+	if !ok {
+		return lock.Zig{}, fmt.Errorf("version %q not found", version)
+	}
+	build, ok := rel.Builds[platform]
+	if !ok {
+		return lock.Zig{}, fmt.Errorf("no build for %s", platform)
+	}
+	return lock.Zig{
+		Version:  version,
+		Resolved: rel.Version,
+		Platform: platform,
+		Tarball:  build.Tarball,
+		Shasum:   build.Shasum,
+	}, nil
+}
+
+// snapshotURL derives the tarball of a pinned snapshot for another platform,
+// e.g. zig-x86_64-linux-<ver>.tar.xz -> zig-aarch64-macos-<ver>.tar.xz.
+func snapshotURL(pin lock.Zig, platform string) (string, error) {
+	if !strings.Contains(pin.Tarball, pin.Platform) {
+		return "", fmt.Errorf("%s: cannot derive zig %s for %s from %s", lock.File, pin.Resolved, platform, pin.Tarball)
+	}
+	url := strings.Replace(pin.Tarball, pin.Platform, platform, 1)
+	switch {
+	case strings.HasSuffix(platform, "-windows") && strings.HasSuffix(url, ".tar.xz"):
+		url = strings.TrimSuffix(url, ".tar.xz") + ".zip"
+	case !strings.HasSuffix(platform, "-windows") && strings.HasSuffix(url, ".zip"):
+		url = strings.TrimSuffix(url, ".zip") + ".tar.xz"
+	}
+	return url, nil
+}
+
+// install downloads tarball (from the mirror, if any) into dir with a
+// progress bar, verifying shasum when set and Verify is on (plus the
+// minisign signature with VerifySignature), and returns the archive digest.
+func install(ctx context.Context, version, tarball, shasum, dir string) (string, error) {
+	tarball = mirrorURL(tarball)
+	opts := archive.DownloadOptions{SHA256: shasum}
+	if !Verify {
+		opts.SHA256 = ""
+	} else if VerifySignature {
+		check, err := signatureCheck(ctx, tarball)
+		if err != nil {
+			return "", fmt.Errorf("zig %s: %w", version, err)
+		}
+		opts.Verify = check
+	}
+	platform := HostPlatform()
+	size, _ := archive.ContentLength(ctx, tarball)
 
 	progress := ui.NewProgress()
 	bar := progress.AddBar(fmt.Sprintf("zig %s (%s)", version, platform), size)
 
-	if err := archive.DownloadTo(ctx, build.Tarball, dir, bar.ProxyReader); err != nil {
+	opts.Proxy = bar.ProxyReader
+	opts.Resumed = bar.SetCurrent
+	res, err := archive.DownloadWith(ctx, tarball, dir, opts)
+	if err != nil {
 		bar.Abort(true)
 		progress.Wait()
+		if errors.Is(err, archive.ErrChecksumMismatch) || errors.Is(err, ErrBadSignature) {
+			return "", fmt.Errorf("zig %s: %w (use --no-verify to skip)", version, err)
+		}
 		return "", err
 	}
 	bar.Complete()
 	progress.Wait()
 
 	ui.Success("Installed zig %s", version)
-	return dir, nil
+	return res.SHA256, nil
 }
 
 // Path returns the installation path for a version.
@@ -156,27 +365,68 @@ func (r *Release) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// fetchIndex loads index.json from the mirror, falling back to ziglang.org
+// when the mirror does not serve one.
 func fetchIndex(ctx context.Context) (Index, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, indexURL, nil)
-	if err != nil {
-		return nil, err
+	if base := mirrorBase(); base != "" {
+		idx, err := fetchIndexFrom(ctx, base+"/index.json")
+		var se *httpclient.StatusError
+		if !errors.As(err, &se) || se.Code != http.StatusNotFound {
+			return idx, err
+		}
 	}
+	return fetchIndexFrom(ctx, indexURL)
+}
 
-	resp, err := http.DefaultClient.Do(req)
+// Reachable makes a single request for the release index, to the mirror
+// when one is set, and returns the URL it tried. Any answer below 500 from
+// a mirror counts, since mirrors need not serve index.json.
+func Reachable(ctx context.Context) (string, error) {
+	url := indexURL
+	if base := mirrorBase(); base != "" {
+		url = base + "/index.json"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, err
+		return url, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	resp, err := httpclient.Client.Do(req)
+	if err != nil {
+		return url, err
 	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK || (url != indexURL && resp.StatusCode < 500) {
+		return url, nil
+	}
+	return url, httpclient.NewStatusError(resp)
+}
 
+func fetchIndexFrom(ctx context.Context, url string) (Index, error) {
 	var idx Index
-	return idx, json.NewDecoder(resp.Body).Decode(&idx)
+	err := httpclient.Retry(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := httpclient.Client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return httpclient.NewStatusError(resp)
+		}
+		idx = nil
+		return json.NewDecoder(resp.Body).Decode(&idx)
+	})
+	return idx, err
 }
 
-func hostPlatform() string {
+// HostPlatform returns the index.json platform key of this host, e.g.
+// x86_64-linux.
+func HostPlatform() string {
 	arch := archMap[runtime.GOARCH]
 	if arch == "" {
 		arch = runtime.GOARCH
@@ -198,8 +448,5 @@ func isInstalled(dir string) bool {
 }
 
 func baseDir() string {
-	if dir, err := os.UserCacheDir(); err == nil {
-		return filepath.Join(dir, "gox")
-	}
-	return filepath.Join(os.TempDir(), "gox")
+	return cache.Root()
 }
@@ -2,15 +2,22 @@ package zig
 
 import (
 	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
+
+	"github.com/qntx/gox/internal/archive"
+	"github.com/qntx/gox/internal/cache"
+	"github.com/qntx/gox/internal/lock"
 )
 
 func TestHostPlatform(t *testing.T) {
-	platform := hostPlatform()
+	platform := HostPlatform()
 
 	// Should contain architecture
 	archFound := false
@@ -21,7 +28,7 @@ func TestHostPlatform(t *testing.T) {
 		}
 	}
 	if !archFound {
-		t.Errorf("hostPlatform() = %q, missing valid arch prefix", platform)
+		t.Errorf("HostPlatform() = %q, missing valid arch prefix", platform)
 	}
 
 	// Should contain OS
@@ -33,7 +40,7 @@ func TestHostPlatform(t *testing.T) {
 		}
 	}
 	if !osFound {
-		t.Errorf("hostPlatform() = %q, missing valid OS", platform)
+		t.Errorf("HostPlatform() = %q, missing valid OS", platform)
 	}
 }
 
@@ -48,6 +55,19 @@ func TestPath(t *testing.T) {
 	}
 }
 
+func TestLocate(t *testing.T) {
+	if got, want := Locate("0.15.0"), Path("0.15.0"); got != want {
+		t.Errorf("Locate() = %q, want %q", got, want)
+	}
+
+	dir := t.TempDir()
+	System = fakeZig(t, dir, "0.15.2")
+	t.Cleanup(func() { System = "" })
+	if got := Locate("0.15.0"); got != dir {
+		t.Errorf("Locate() with System = %q, want %q", got, dir)
+	}
+}
+
 func TestIsInstalled(t *testing.T) {
 	// Non-existent path
 	if isInstalled("/nonexistent/path") {
@@ -183,3 +203,146 @@ func TestSkipKeys(t *testing.T) {
 		t.Error("skipKeys[x86_64-linux] should be false")
 	}
 }
+
+func TestSnapshotURL(t *testing.T) {
+	const base = "https://ziglang.org/builds/zig-"
+	pin := lock.Zig{
+		Resolved: "0.16.0-dev.1+abc",
+		Platform: "x86_64-linux",
+		Tarball:  base + "x86_64-linux-0.16.0-dev.1+abc.tar.xz",
+	}
+	tests := []struct {
+		platform string
+		want     string
+	}{
+		{"aarch64-macos", base + "aarch64-macos-0.16.0-dev.1+abc.tar.xz"},
+		{"x86_64-windows", base + "x86_64-windows-0.16.0-dev.1+abc.zip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			got, err := snapshotURL(pin, tt.platform)
+			if err != nil {
+				t.Fatalf("snapshotURL() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("snapshotURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	win := lock.Zig{Platform: "x86_64-windows", Tarball: base + "x86_64-windows-0.16.0-dev.1+abc.zip"}
+	if got, _ := snapshotURL(win, "x86_64-linux"); got != base+"x86_64-linux-0.16.0-dev.1+abc.tar.xz" {
+		t.Errorf("snapshotURL(from windows) = %q", got)
+	}
+
+	if _, err := snapshotURL(lock.Zig{Platform: "x86_64-linux", Tarball: base + "linux-x86_64-0.11.0.tar.xz"}, "aarch64-macos"); err == nil {
+		t.Error("snapshotURL() should fail when the platform is not in the tarball name")
+	}
+}
+
+func TestEnsurePinned_Installed(t *testing.T) {
+	if err := cache.Use(cache.ScopeProject, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cache.Use(cache.ScopeUser, "") })
+	l, _ := lock.Load(filepath.Join(t.TempDir(), lock.File))
+	pin := lock.Zig{Version: "master", Resolved: "0.16.0-dev.1+abc", Platform: HostPlatform(), Tarball: "https://invalid.example/zig.tar.xz"}
+	if err := l.SetZig(pin); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := Path(pin.Resolved)
+	bin := filepath.Join(dir, "zig")
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bin, nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ensurePinned(t.Context(), l, "master")
+	if err != nil {
+		t.Fatalf("ensurePinned() error = %v", err)
+	}
+	if got != dir {
+		t.Errorf("ensurePinned() = %q, want %q", got, dir)
+	}
+}
+
+func TestInstall_ShasumMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("not zig"))
+	}))
+	defer srv.Close()
+
+	dir := filepath.Join(t.TempDir(), "zig")
+	_, err := install(t.Context(), "0.15.1", srv.URL+"/zig.tar.xz", strings.Repeat("0", 64), dir)
+	if !errors.Is(err, archive.ErrChecksumMismatch) {
+		t.Fatalf("install() error = %v, want ErrChecksumMismatch", err)
+	}
+	if !strings.Contains(err.Error(), "--no-verify") {
+		t.Errorf("install() error = %q, want --no-verify hint", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("install() left %s behind", dir)
+	}
+
+	Verify = false
+	t.Cleanup(func() { Verify = true })
+	_, err = install(t.Context(), "0.15.1", srv.URL+"/zig.tar.xz", strings.Repeat("0", 64), dir)
+	if errors.Is(err, archive.ErrChecksumMismatch) {
+		t.Errorf("install() with Verify=false error = %v, want no checksum check", err)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"master":{"version":"0.16.0-dev.1+abc"},"0.9.1":{},"0.14.1":{},"0.14.0":{}}`))
+	}))
+	defer srv.Close()
+
+	t.Setenv(MirrorEnv, srv.URL)
+	got, err := Latest(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "0.14.1" {
+		t.Errorf("Latest() = %q, want 0.14.1", got)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"0.14.1", "0.14.0", 1},
+		{"0.9.1", "0.14.0", -1},
+		{"0.14", "0.14.0", 0},
+		{"1.0.0", "0.99.9", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"_"+tt.b, func(t *testing.T) {
+			if got := CompareVersions(tt.a, tt.b); got != tt.want {
+				t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRelease(t *testing.T) {
+	for v, want := range map[string]bool{
+		"0.14.1":            true,
+		"master":            false,
+		"0.16.0-dev.1+abc":  false,
+		"":                  false,
+		"0.15.0-dev.94+123": false,
+	} {
+		if got := IsRelease(v); got != want {
+			t.Errorf("IsRelease(%q) = %v, want %v", v, got, want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package zig
+
+import (
+	"cmp"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// VersionFile pins a project's Zig version (the ZVM and zigup convention).
+const VersionFile = ".zig-version"
+
+// ResolveVersion returns the Zig version an empty version stands for: the
+// one in the project's VersionFile, else master. Other versions are
+// returned as they are.
+func ResolveVersion(version string) string {
+	return cmp.Or(version, fileVersion(), defaultVersion)
+}
+
+// fileVersion returns the version in the nearest VersionFile between the
+// working directory and the project root, the first directory holding
+// go.mod or .git. It returns "" when there is none.
+func fileVersion() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		if data, err := os.ReadFile(filepath.Join(dir, VersionFile)); err == nil {
+			line, _, _ := strings.Cut(string(data), "\n")
+			return strings.TrimSpace(line)
+		}
+		if isProjectRoot(dir) {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func isProjectRoot(dir string) bool {
+	for _, name := range []string{"go.mod", ".git"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
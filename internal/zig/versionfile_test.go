@@ -0,0 +1,94 @@
+package zig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileVersion(t *testing.T) {
+	write := func(t *testing.T, path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		cwd   string
+		want  string
+	}{
+		{"in cwd", map[string]string{"proj/go.mod": "", "proj/.zig-version": "0.14.1\n"}, "proj", "0.14.1"},
+		{"from subdirectory", map[string]string{"proj/go.mod": "", "proj/.zig-version": " 0.15.2 \n", "proj/cmd/x/main.go": ""}, "proj/cmd/x", "0.15.2"},
+		{"stops at project root", map[string]string{".zig-version": "0.13.0", "proj/go.mod": ""}, "proj", ""},
+		{"none", map[string]string{"proj/go.mod": ""}, "proj", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tt.files {
+				write(t, filepath.Join(root, name), content)
+			}
+			t.Chdir(filepath.Join(root, tt.cwd))
+			if got := fileVersion(); got != tt.want {
+				t.Errorf("fileVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveVersion(t *testing.T) {
+	proj := t.TempDir()
+	if err := os.WriteFile(filepath.Join(proj, "go.mod"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(proj)
+	if got := ResolveVersion(""); got != "master" {
+		t.Errorf("ResolveVersion(\"\") without %s = %q, want master", VersionFile, got)
+	}
+	if err := os.WriteFile(filepath.Join(proj, VersionFile), []byte("0.14.1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := ResolveVersion(""); got != "0.14.1" {
+		t.Errorf("ResolveVersion(\"\") = %q, want 0.14.1 from %s", got, VersionFile)
+	}
+	if got := ResolveVersion("0.15.2"); got != "0.15.2" {
+		t.Errorf("ResolveVersion(0.15.2) = %q, want 0.15.2", got)
+	}
+}
+
+func TestEnsure_VersionFile(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := Path("0.14.1")
+	fakeZig(t, mustMkdir(t, dir), "0.14.1")
+
+	proj := t.TempDir()
+	if err := os.WriteFile(filepath.Join(proj, "go.mod"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(proj, VersionFile), []byte("0.14.1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(proj)
+
+	got, err := Ensure(t.Context(), "")
+	if err != nil {
+		t.Fatalf("Ensure() error = %v", err)
+	}
+	if got != dir {
+		t.Errorf("Ensure() = %q, want installed %q", got, dir)
+	}
+}
+
+func mustMkdir(t *testing.T, dir string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
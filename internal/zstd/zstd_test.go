@@ -0,0 +1,64 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestFileSamples decompresses the files in testdata, named after the
+// first eight hexadecimal digits of the SHA-256 of their contents.
+func TestFileSamples(t *testing.T) {
+	samples, err := os.ReadDir("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sample := range samples {
+		name := sample.Name()
+		if !strings.HasSuffix(name, ".zst") {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			data, err := io.ReadAll(NewReader(f))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _, _ := strings.Cut(name, ".")
+			if got := fmt.Sprintf("%x", sha256.Sum256(data))[:8]; got != want {
+				t.Errorf("sha256 = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestReset(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "f2a8e35c.helloworld-11000x.zst"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewReader(bytes.NewReader(nil))
+	for range 2 {
+		r.Reset(bytes.NewReader(data))
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := strings.Repeat("hello world ", 11000); string(got) != want {
+			t.Errorf("got %d bytes, want %d", len(got), len(want))
+		}
+	}
+}
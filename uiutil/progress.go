@@ -0,0 +1,145 @@
+package uiutil
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Bar tracks the progress of one transfer. Writes and reads through
+// ProxyReader advance it by the bytes they carry.
+type Bar interface {
+	io.Writer
+	// SetTotal updates the expected size, e.g. once a server reports it.
+	SetTotal(total int64)
+	// SetCurrent sets the progress, e.g. to the bytes of a resumed download.
+	SetCurrent(n int64)
+	// Complete marks the transfer as finished.
+	Complete()
+	// Abort stops the bar after a failure, removing it when drop is set.
+	Abort(drop bool)
+	// ProxyReader returns a reader that advances the bar as r is read.
+	ProxyReader(r io.Reader) io.Reader
+}
+
+// Progress groups the bars of concurrent transfers.
+type Progress interface {
+	// AddBar starts a bar named name expecting total bytes (0 if unknown).
+	AddBar(name string, total int64) Bar
+	// Wait blocks until every bar has completed or aborted.
+	Wait()
+}
+
+// Counter is a Bar that only counts, for callers that render progress
+// themselves or not at all. It is safe for concurrent use.
+type Counter struct {
+	Name    string
+	current atomic.Int64
+	total   atomic.Int64
+	state   atomic.Int32
+}
+
+// Counter states reported by State.
+const (
+	Running int32 = iota
+	Completed
+	Aborted
+)
+
+// NewCounter returns a running Counter expecting total bytes.
+func NewCounter(name string, total int64) *Counter {
+	c := &Counter{Name: name}
+	c.total.Store(total)
+	return c
+}
+
+// Write implements io.Writer by counting len(p).
+func (c *Counter) Write(p []byte) (int, error) {
+	c.current.Add(int64(len(p)))
+	return len(p), nil
+}
+
+// SetTotal implements Bar.
+func (c *Counter) SetTotal(total int64) {
+	c.total.Store(total)
+}
+
+// SetCurrent implements Bar.
+func (c *Counter) SetCurrent(n int64) {
+	c.current.Store(n)
+}
+
+// Complete implements Bar.
+func (c *Counter) Complete() {
+	c.state.CompareAndSwap(Running, Completed)
+}
+
+// Abort implements Bar.
+func (c *Counter) Abort(bool) {
+	c.state.CompareAndSwap(Running, Aborted)
+}
+
+// ProxyReader implements Bar.
+func (c *Counter) ProxyReader(r io.Reader) io.Reader {
+	return ProxyReader(r, c)
+}
+
+// Current returns the bytes counted so far.
+func (c *Counter) Current() int64 {
+	return c.current.Load()
+}
+
+// Total returns the expected size, 0 if unknown.
+func (c *Counter) Total() int64 {
+	return c.total.Load()
+}
+
+// State returns Running, Completed or Aborted.
+func (c *Counter) State() int32 {
+	return c.state.Load()
+}
+
+// Counters is a Progress of Counter bars.
+type Counters struct {
+	mu   sync.Mutex
+	bars []*Counter
+}
+
+// AddBar implements Progress.
+func (p *Counters) AddBar(name string, total int64) Bar {
+	c := NewCounter(name, total)
+	p.mu.Lock()
+	p.bars = append(p.bars, c)
+	p.mu.Unlock()
+	return c
+}
+
+// Wait implements Progress. Counters never block: completing the bars is
+// the caller's job.
+func (p *Counters) Wait() {}
+
+// Bars returns the bars added so far.
+func (p *Counters) Bars() []*Counter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*Counter(nil), p.bars...)
+}
+
+// ProxyReader returns a reader that writes every byte read from r to w, so
+// a Bar (or any io.Writer) sees the transfer as it happens.
+func ProxyReader(r io.Reader, w io.Writer) io.Reader {
+	return &proxyReader{r: r, w: w}
+}
+
+type proxyReader struct {
+	r io.Reader
+	w io.Writer
+}
+
+func (p *proxyReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		_, _ = p.w.Write(b[:n])
+	}
+	return n, err
+}
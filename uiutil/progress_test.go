@@ -0,0 +1,47 @@
+package uiutil
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCounter(t *testing.T) {
+	var p Counters
+	bar := p.AddBar("pkg", 10)
+
+	n, err := io.Copy(io.Discard, bar.ProxyReader(strings.NewReader("hello")))
+	if err != nil || n != 5 {
+		t.Fatalf("io.Copy() = %d, %v", n, err)
+	}
+	if _, err := bar.Write([]byte("abc")); err != nil {
+		t.Fatal(err)
+	}
+	bar.SetTotal(20)
+	bar.Complete()
+	bar.Abort(true) // no effect once completed
+	p.Wait()
+
+	bars := p.Bars()
+	if len(bars) != 1 {
+		t.Fatalf("len(Bars()) = %d, want 1", len(bars))
+	}
+	c := bars[0]
+	if c.Name != "pkg" || c.Current() != 8 || c.Total() != 20 || c.State() != Completed {
+		t.Errorf("counter = %q %d/%d state %d", c.Name, c.Current(), c.Total(), c.State())
+	}
+
+	c.SetCurrent(2)
+	if c.Current() != 2 {
+		t.Errorf("Current() after SetCurrent = %d, want 2", c.Current())
+	}
+}
+
+func TestCounter_Abort(t *testing.T) {
+	c := NewCounter("zig", 0)
+	c.Abort(false)
+	c.Complete()
+	if c.State() != Aborted {
+		t.Errorf("State() = %d, want Aborted", c.State())
+	}
+}